@@ -0,0 +1,133 @@
+package actisense
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"time"
+)
+
+// BEMHeader is common header of all BEM (Binary Encoded Message) responses sent by Actisense device
+type BEMHeader struct {
+	// BEMID identifies response type (i.e. 0xF0 startup status)
+	BEMID uint8 `json:"bem_id"`
+	// SequenceID is sequence number of response
+	SequenceID uint8 `json:"sequence_id"`
+	// ModelID is Actisense model identifier
+	ModelID uint16 `json:"model_id"`
+	// SerialID is device serial number
+	SerialID uint32 `json:"serial_id"`
+	// ErrorCode is error code device reported. 0 means no error.
+	ErrorCode uint32 `json:"error_code"`
+}
+
+// StartupStatus is BEM message that device sends when it has (re)started
+type StartupStatus struct {
+	BEMHeader
+	// FirmwareVersion is firmware version (i.e. "2.210"). Empty when message is too short to contain version.
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+}
+
+// OperatingModeStatus is BEM response to operating mode get/set command
+type OperatingModeStatus struct {
+	BEMHeader
+	Mode OperatingMode `json:"mode"`
+}
+
+// SystemStatus is BEM message with CAN channel load and error counters that device sends periodically
+type SystemStatus struct {
+	BEMHeader
+	Channels []ChannelStatus `json:"channels"`
+}
+
+// HardwareInfo is BEM response to hardware info request
+type HardwareInfo struct {
+	BEMHeader
+}
+
+// UnknownBEMMessage is BEM message which layout is not known. Data contains bytes after common header.
+type UnknownBEMMessage struct {
+	BEMHeader
+	Data []byte `json:"data"`
+}
+
+// IsBEMMessage checks if message is Actisense device specific (BEM) message that BinaryFormatDevice outputs when
+// Config.OutputActisenseMessages is set. These messages have PGN in CanBoatFakePGNOffset range.
+func IsBEMMessage(msg nmea.RawMessage) bool {
+	return msg.Header.PGN&^0xFF == CanBoatFakePGNOffset
+}
+
+// DecodeBEMMessage decodes Actisense device specific (BEM) message into typed value. Returned value is one of
+// StartupStatus, OperatingModeStatus, SystemStatus, HardwareInfo or UnknownBEMMessage.
+func DecodeBEMMessage(msg nmea.RawMessage) (any, error) {
+	if !IsBEMMessage(msg) {
+		return nil, fmt.Errorf("message PGN %v is not actisense BEM message", msg.Header.PGN)
+	}
+	return ParseBEMPayload(msg.Data)
+}
+
+// ParseBEMPayload parses BEM response payload (bytes after command and length byte) into typed value. See
+// DecodeBEMMessage for returned types.
+func ParseBEMPayload(payload []byte) (any, error) {
+	header, err := parseBEMHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+	data := payload[bemHeaderLength:]
+
+	switch header.BEMID {
+	case bemStartupStatus:
+		status := StartupStatus{BEMHeader: header}
+		if len(data) >= 2 {
+			version := binary.LittleEndian.Uint16(data)
+			status.FirmwareVersion = fmt.Sprintf("%d.%03d", version/1000, version%1000)
+		}
+		return status, nil
+	case bemOperatingMode:
+		if len(data) < 2 {
+			return nil, errors.New("actisense operating mode response too short")
+		}
+		return OperatingModeStatus{BEMHeader: header, Mode: OperatingMode(binary.LittleEndian.Uint16(data))}, nil
+	case bemSystemStatus:
+		health, err := ParseSystemStatus(payload, GatewayHealth{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		return SystemStatus{BEMHeader: header, Channels: health.Channels}, nil
+	case bemHardwareInfo:
+		return HardwareInfo{BEMHeader: header}, nil
+	}
+	return UnknownBEMMessage{BEMHeader: header, Data: append([]byte(nil), data...)}, nil
+}
+
+func parseBEMHeader(payload []byte) (BEMHeader, error) {
+	if len(payload) < bemHeaderLength {
+		return BEMHeader{}, errors.New("actisense BEM response too short to contain header")
+	}
+	return BEMHeader{
+		BEMID:      payload[0],
+		SequenceID: payload[1],
+		ModelID:    binary.LittleEndian.Uint16(payload[2:4]),
+		SerialID:   binary.LittleEndian.Uint32(payload[4:8]),
+		ErrorCode:  binary.LittleEndian.Uint32(payload[8:12]),
+	}, nil
+}
+
+// Handshake initializes device to `receive all` operating mode, waits until device confirms it and queries hardware
+// info. Unlike Initialize, which only sends operating mode command, Handshake fails when device does not respond and
+// can be used to check that device on other end of serial port/connection is working Actisense device.
+//
+// Responses are processed by ReadRawMessage so reading must be in progress (in other goroutine). Use context with
+// timeout as device may not respond at all.
+func (d *BinaryFormatDevice) Handshake(ctx context.Context) (DeviceInfo, error) {
+	if err := d.SetOperatingMode(ctx, OperatingModeReceiveAll); err != nil {
+		return DeviceInfo{}, fmt.Errorf("actisense handshake, set operating mode: %w", err)
+	}
+	info, err := d.QueryDeviceInfo(ctx)
+	if err != nil {
+		return DeviceInfo{}, fmt.Errorf("actisense handshake, query device info: %w", err)
+	}
+	return info, nil
+}
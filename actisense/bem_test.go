@@ -0,0 +1,116 @@
+package actisense
+
+import (
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseBEMPayload(t *testing.T) {
+	header := []byte{0x01, 0x45, 0x00, 0x40, 0xE2, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00} // SID, model, serial, error code
+	expectHeader := func(bemID uint8) BEMHeader {
+		return BEMHeader{BEMID: bemID, SequenceID: 1, ModelID: 69, SerialID: 123456}
+	}
+	payload := func(bemID uint8, data ...byte) []byte {
+		return append(append([]byte{bemID}, header...), data...)
+	}
+
+	var testCases = []struct {
+		name        string
+		when        []byte
+		expect      any
+		expectError string
+	}{
+		{
+			name:   "ok, startup status",
+			when:   payload(0xF0, 0xA2, 0x08),
+			expect: StartupStatus{BEMHeader: expectHeader(0xF0), FirmwareVersion: "2.210"},
+		},
+		{
+			name:   "ok, operating mode",
+			when:   payload(0x11, 0x02, 0x00),
+			expect: OperatingModeStatus{BEMHeader: expectHeader(0x11), Mode: OperatingModeReceiveAll},
+		},
+		{
+			name: "ok, system status",
+			when: payload(0xF2, 0x01, 0x0A, 0x14, 0x00, 0x02, 0x05, 0x06),
+			expect: SystemStatus{BEMHeader: expectHeader(0xF2), Channels: []ChannelStatus{
+				{RxBandwidth: 10, RxLoad: 20, RxFiltered: 0, RxDropped: 2, TxBandwidth: 5, TxLoad: 6},
+			}},
+		},
+		{
+			name:   "ok, hardware info",
+			when:   payload(0x10),
+			expect: HardwareInfo{BEMHeader: expectHeader(0x10)},
+		},
+		{
+			name:   "ok, unknown",
+			when:   payload(0x99, 0x01, 0x02),
+			expect: UnknownBEMMessage{BEMHeader: expectHeader(0x99), Data: []byte{0x01, 0x02}},
+		},
+		{
+			name:        "nok, operating mode too short",
+			when:        payload(0x11, 0x02),
+			expectError: "actisense operating mode response too short",
+		},
+		{
+			name:        "nok, too short",
+			when:        []byte{0xF0, 0x01},
+			expectError: "actisense BEM response too short to contain header",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseBEMPayload(tc.when)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDecodeBEMMessage(t *testing.T) {
+	rw := &readWriteBuffer{Reader: bytes.NewReader(bemResponse(bemStartupStatus, 0, 0xA2, 0x08))}
+	device := NewBinaryDeviceWithConfig(rw, Config{OutputActisenseMessages: true})
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, IsBEMMessage(msg))
+
+	result, err := DecodeBEMMessage(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, StartupStatus{
+		BEMHeader:       BEMHeader{BEMID: 0xF0, SequenceID: 1, ModelID: 69, SerialID: 123456},
+		FirmwareVersion: "2.210",
+	}, result)
+
+	_, err = DecodeBEMMessage(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 130310}})
+	assert.EqualError(t, err, "message PGN 130310 is not actisense BEM message")
+}
+
+func TestBinaryFormatDevice_Handshake(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		switch written[4] {
+		case bemOperatingMode:
+			return bemResponse(bemOperatingMode, 0, 0x02, 0x00)
+		case bemHardwareInfo:
+			return bemResponse(bemHardwareInfo, 0)
+		}
+		return nil
+	})
+	device := NewBinaryDevice(rw)
+	defer startReading(t, device)()
+
+	ctx, cancel := testContext()
+	defer cancel()
+	info, err := device.Handshake(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(123456), info.SerialID)
+	assert.Len(t, rw.written, 2)
+}
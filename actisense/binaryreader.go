@@ -8,6 +8,8 @@ import (
 	"github.com/aldas/go-nmea-client"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -44,14 +46,71 @@ const (
 	CanBoatFakePGNOffset uint32 = 0x40000
 )
 
+// ErrInvalidCRC is returned when message checksum does not match message contents
+var ErrInvalidCRC = errors.New("raw message has invalid crc")
+
+// StreamStats holds counters of messages read from binary stream and corruption detected in it. Corrupted messages
+// are discarded and reading continues from next message start sequence (DLE+STX).
+type StreamStats struct {
+	// Messages is number of valid messages read from stream
+	Messages uint64 `json:"messages"`
+	// CRCErrors is number of messages discarded due to invalid checksum
+	CRCErrors uint64 `json:"crc_errors"`
+	// Malformed is number of messages discarded due to invalid structure (length byte value not matching actual
+	// length, unknown escape sequence, message exceeding maximum size)
+	Malformed uint64 `json:"malformed"`
+	// Resyncs is number of times start of next message was found before end of current message (message was
+	// truncated)
+	Resyncs uint64 `json:"resyncs"`
+	// DiscardedBytes is number of bytes that were not part of any valid message
+	DiscardedBytes uint64 `json:"discarded_bytes"`
+}
+
+type streamCounters struct {
+	messages       atomic.Uint64
+	crcErrors      atomic.Uint64
+	malformed      atomic.Uint64
+	resyncs        atomic.Uint64
+	discardedBytes atomic.Uint64
+}
+
 // BinaryFormatDevice is implementing Actisense device using binary formats (NGT1 and N2K binary)
 type BinaryFormatDevice struct {
 	device io.ReadWriter
+	// reader makes reads from device interruptible by context cancellation and Close
+	reader *contextReader
 
 	sleepFunc func(timeout time.Duration)
 	timeNow   func() time.Time
 
 	config Config
+
+	// busClock converts 32bit millisecond timestamps of NGT and N2K binary messages to bus times
+	busClock *nmea.DeviceClock
+	// rawBusClock converts 16bit millisecond timestamps of RAW (BST-95) messages to bus times
+	rawBusClock *nmea.DeviceClock
+
+	infoLock sync.Mutex
+	info     DeviceInfo
+	health   GatewayHealth
+	// commandWaiter is command waiting for BEM response. Guarded by infoLock.
+	commandWaiter *bemWaiter
+	// commandLock serializes commands sent over BEM command channel
+	commandLock chan struct{}
+
+	// message is reusable buffer for unescaped message bytes. Actisense N2K binary message can be up to ISOTP size 1785
+	message []byte
+	// chunk is buffer for bulk reads from device. Bytes read past end of current message are kept in chunk
+	// (chunk[chunkIndex:chunkLen]) for next read.
+	chunk      []byte
+	chunkIndex int
+	chunkLen   int
+	// chunkTime is time when chunk was read from device
+	chunkTime time.Time
+
+	stats streamCounters
+
+	closed atomic.Bool
 }
 
 // Config is configuration for Actisense NGT-1 device
@@ -70,15 +129,47 @@ type Config struct {
 	// OutputActisenseMessages instructs device to output Actisense own messages
 	OutputActisenseMessages bool
 
-	// LogFunc callback to output/print debug/log statements
+	// HealthMonitor is updated with gateway health from system status messages device sends periodically (even when
+	// OutputActisenseMessages is false).
+	// Optional: used only by BinaryFormatDevice
+	HealthMonitor *HealthMonitor
+
+	// OnEBLRecord is called with EBL records that are not CAN messages (file header, start time, text metadata, device
+	// info, error frames and records of unknown type). See ParseEBLRecord for record types.
+	// Optional: used only by EBLFormatDevice
+	OnEBLRecord func(record any)
+
+	// LogFunc callback to output/print debug/log statements. Used when Logger is not set.
 	LogFunc func(format string, a ...any)
+	// Logger outputs debug/log statements. Raw message bytes are logged on Debug level when DebugLogRawMessageBytes is
+	// set.
+	// Optional: when not set LogFunc is used
+	Logger nmea.Logger
 
 	// IsN2KWriter instructs device to write/send messages to NMEA200 bus as N2K binary format (used by Actisense W2K-1)
 	IsN2KWriter bool
 
-	// FastPacketAssembler assembles fast-packet PGN frames to complete messages.
+	// FastPacketAssembler assembles fast-packet PGN frames to complete messages. Use nmea.ISOTPAssembler wrapping
+	// nmea.FastPacketAssembler to assemble ISO 11783-3 transport protocol messages as well.
 	// Optional: if set is used by devices/format that do not do packet assembly inside hardware (i.e. W2K-1 Raw ASCII format)
 	FastPacketAssembler nmea.Assembler
+
+	// FastPacketSplitter splits messages to frames for devices/formats that send ordinary CAN frames (i.e. W2K-1 Raw
+	// ASCII format).
+	// Optional: defaults to splitter that splits messages longer than 8 bytes into fast-packet frames
+	FastPacketSplitter nmea.Splitter
+
+	// ISOTPSender sends long messages with ISO 11783-3 transport protocol for devices/formats that send ordinary CAN
+	// frames (i.e. W2K-1 Raw ASCII format). Handshake frames are received by reading device, so addressed transfers
+	// require reading device concurrently with writing.
+	// Optional: when not set messages are split into fast-packet frames
+	ISOTPSender *nmea.ISOTPSender
+
+	// DataPool is pool where Data slices for messages returned by ReadRawMessage are taken from. Application should
+	// return Data with DataPool.Put after message has been processed.
+	// Optional: when not set Data is allocated for each message. ReadRawMessageInto reuses Data of given message and
+	// uses pool only when its capacity is too small.
+	DataPool *nmea.BufferPool
 }
 
 // NewBinaryDevice creates new instance of Actisense device using binary formats (NGT1 and N2K binary)
@@ -91,14 +182,35 @@ func NewBinaryDeviceWithConfig(reader io.ReadWriter, config Config) *BinaryForma
 	if config.ReceiveDataTimeout > 0 {
 		config.ReceiveDataTimeout = 5 * time.Second
 	}
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
 	return &BinaryFormatDevice{
-		device:    reader,
-		sleepFunc: time.Sleep,
-		timeNow:   time.Now,
-		config:    config,
+		device:      reader,
+		reader:      newContextReader(reader),
+		sleepFunc:   time.Sleep,
+		timeNow:     time.Now,
+		config:      config,
+		busClock:    nmea.NewDeviceClock(counterWrapAround32bitMs),
+		rawBusClock: nmea.NewDeviceClock(counterWrapAround16bitMs),
+		message:     make([]byte, nmea.ISOTPDataMaxSize),
+		chunk:       make([]byte, binaryReadChunkSize),
+		commandLock: make(chan struct{}, 1),
+	}
+}
+
+// OpenBinaryDevice opens serial port (i.e. Actisense NGT-1 USB device) with given opener and creates BinaryFormatDevice
+// on top of it. Closing device closes serial port.
+func OpenBinaryDevice(opener nmea.SerialOpener, serialConfig nmea.SerialConfig, config Config) (*BinaryFormatDevice, error) {
+	port, err := opener.OpenSerial(serialConfig.WithDefaults())
+	if err != nil {
+		return nil, err
 	}
+	return NewBinaryDeviceWithConfig(port, config), nil
 }
 
+// binaryReadChunkSize is size of single read from device. Serial ports and TCP sockets return what is available so
+// this limits only how much is read with single syscall.
+const binaryReadChunkSize = 512
+
 type state uint8
 
 const (
@@ -110,85 +222,198 @@ const (
 // ReadRawMessage reads raw data and parses it to nmea.RawMessage. This method block until full RawMessage is read or
 // an error occurs (including context related errors).
 func (d *BinaryFormatDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
-	// Actisense N2K binary message can be up to ISOTP size 1785
-	message := make([]byte, nmea.ISOTPDataMaxSize)
+	msg := nmea.RawMessage{}
+	if err := d.ReadRawMessageInto(ctx, &msg); err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return msg, nil
+}
+
+// ReadRawMessageInto reads raw data and parses it into given message. Message Data is reused when it has enough
+// capacity. This method block until full RawMessage is read or an error occurs (including context related errors).
+func (d *BinaryFormatDevice) ReadRawMessageInto(ctx context.Context, to *nmea.RawMessage) error {
+	msg, _, err := d.readRawMessage(ctx)
+	if err != nil {
+		return err
+	}
+	setRawMessage(to, msg, d.config.DataPool)
+	return nil
+}
+
+// ReadRawFrame reads next CAN frame from device. Frames are available only in RAW Actisense (BST-95) format (i.e. W2K-1
+// configured to RAW Actisense mode). Messages assembled by device (NGT-1 and N2K binary formats) result
+// nmea.ErrNotRawFrame error.
+func (d *BinaryFormatDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	msg, isFrame, err := d.readRawMessage(ctx)
+	if err != nil {
+		return nmea.RawFrame{}, err
+	}
+	if !isFrame || len(msg.Data) > 8 {
+		return nmea.RawFrame{}, nmea.ErrNotRawFrame
+	}
+	frame := nmea.RawFrame{
+		Time:   msg.Time,
+		Header: msg.Header,
+		Length: uint8(len(msg.Data)),
+	}
+	copy(frame.Data[:], msg.Data)
+	return frame, nil
+}
+
+// readRawMessage reads next message from device. Returned message Data references device internal buffer and is
+// valid only until next read. Returns true when message is single CAN frame (RAW Actisense format) and not message
+// assembled by device.
+func (d *BinaryFormatDevice) readRawMessage(ctx context.Context) (nmea.RawMessage, bool, error) {
+	message := d.message
 	messageByteIndex := 0
 
-	buf := make([]byte, 1)
 	lastReadWithDataTime := d.timeNow()
 	var previousByte byte
 	var currentByte byte
 
 	state := waitingStartOfMessage
 	for {
-		select {
-		case <-ctx.Done():
-			return nmea.RawMessage{}, ctx.Err()
-		default:
-		}
+		if d.chunkIndex >= d.chunkLen {
+			select {
+			case <-ctx.Done():
+				return nmea.RawMessage{}, false, ctx.Err()
+			default:
+			}
+			if d.closed.Load() {
+				return nmea.RawMessage{}, false, nmea.ErrDeviceClosed
+			}
 
-		n, err := d.device.Read(buf)
-		// on read errors we do not return immediately as for:
-		// os.ErrDeadlineExceeded - we set new deadline on next iteration
-		// io.EOF - we check if already read + received is enough to form complete message
-		if err != nil && !(errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF)) {
-			return nmea.RawMessage{}, err
-		}
+			n, err := d.reader.Read(ctx, d.chunk)
+			// on read errors we do not return immediately as for:
+			// os.ErrDeadlineExceeded - we set new deadline on next iteration
+			// io.EOF - we check if already read + received is enough to form complete message
+			if err != nil && !(errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF)) {
+				if d.closed.Load() {
+					return nmea.RawMessage{}, false, nmea.ErrDeviceClosed
+				}
+				return nmea.RawMessage{}, false, err
+			}
 
-		now := d.timeNow()
-		if n == 0 {
-			if errors.Is(err, io.EOF) && now.Sub(lastReadWithDataTime) > d.config.ReceiveDataTimeout {
-				return nmea.RawMessage{}, err
+			now := d.timeNow()
+			if n == 0 {
+				if errors.Is(err, io.EOF) && now.Sub(lastReadWithDataTime) > d.config.ReceiveDataTimeout {
+					return nmea.RawMessage{}, false, err
+				}
+				continue
 			}
-			continue
+			lastReadWithDataTime = now
+			d.chunkIndex = 0
+			d.chunkLen = n
+			d.chunkTime = now
 		}
-		lastReadWithDataTime = now
 		previousByte = currentByte
-		currentByte = buf[0]
+		currentByte = d.chunk[d.chunkIndex]
+		d.chunkIndex++
 
 		switch state {
 		case waitingStartOfMessage:
 			if previousByte == DLE && currentByte == STX {
 				state = readingMessageData
+				break
+			}
+			if previousByte == DLE { // previous byte was not start of message after all
+				d.stats.discardedBytes.Add(1)
+			}
+			if currentByte != DLE {
+				d.stats.discardedBytes.Add(1)
 			}
 		case readingMessageData:
 			if currentByte == DLE {
 				state = processingEscapeSequence
 				break
 			}
+			if messageByteIndex == len(message) { // garbage without end sequence - discard and wait for next start sequence
+				d.discard(message[0:messageByteIndex], errors.New("message exceeds maximum size"))
+				state = waitingStartOfMessage
+				messageByteIndex = 0
+				break
+			}
 			message[messageByteIndex] = currentByte
 			messageByteIndex++
 		case processingEscapeSequence:
-			if currentByte == DLE { // any DLE characters are double escaped (DLE DLE)
+			if currentByte == DLE && messageByteIndex < len(message) { // any DLE characters are double escaped (DLE DLE)
 				state = readingMessageData
 				message[messageByteIndex] = currentByte
 				messageByteIndex++
 				break
 			}
-			if currentByte == ETX { // end of message sequence
+			if currentByte == STX { // start of next message before end of current - current message was truncated
+				d.stats.resyncs.Add(1)
+				d.stats.discardedBytes.Add(uint64(messageByteIndex) + 2)
+				state = readingMessageData
+				messageByteIndex = 0
+				break
+			}
+			if currentByte == ETX && messageByteIndex > 0 { // end of message sequence
 				msg := message[0:messageByteIndex]
-				if d.config.DebugLogRawMessageBytes && d.config.LogFunc != nil {
-					d.config.LogFunc("# DEBUG read raw actisense binary message: %x\n", msg)
+				now := d.chunkTime
+				if d.config.DebugLogRawMessageBytes {
+					d.config.Logger.Debug("read raw actisense binary message", "bytes", msg)
 				}
+				var m nmea.RawMessage
+				var err error
+				isFrame := false
+				isOutput := true
 				switch message[0] {
 				case cmdNGTMessageReceived, cmdNGTMessageSend:
-					return fromActisenseNGTBinaryMessage(msg, now)
+					m, err = fromActisenseNGTBinaryMessage(msg, now, d.busClock)
 				case cmdN2KMessageReceived, cmdN2KMessageSend:
-					return fromActisenseN2KBinaryMessage(msg, now)
+					m, err = fromActisenseN2KBinaryMessage(msg, now, d.busClock)
 				case cmdRAWActisenseMessageReceived, cmdRAWActisenseMessageSend:
-					return fromRawActisenseMessage(msg, now)
+					m, err = fromRawActisenseMessage(msg, now, d.rawBusClock)
+					isFrame = true
 				case cmdDeviceMessageReceived:
-					if d.config.OutputActisenseMessages {
-						return fromNGTMessage(msg, now)
+					d.updateDeviceInfo(msg, now)
+					isOutput = d.config.OutputActisenseMessages
+					if isOutput {
+						m, err = fromNGTMessage(msg, now)
+					}
+				default:
+					isOutput = false
+				}
+				if err != nil {
+					d.discard(msg, err)
+				} else {
+					d.stats.messages.Add(1)
+					if isOutput {
+						return m, isFrame, nil
 					}
 				}
+			} else {
+				// unknown DLE + ??? sequence - discard this current message and wait for next start sequence
+				d.discard(message[0:messageByteIndex], fmt.Errorf("unknown escape sequence, DLE+0x%02x", currentByte))
 			}
-			// when ignoreNGT1Messages or unknown DLE + ??? sequence - discard this current message and wait for next start sequence
 			state = waitingStartOfMessage
 			messageByteIndex = 0
 		}
 	}
+}
+
+// discard counts and logs corrupted message that is not returned to caller
+func (d *BinaryFormatDevice) discard(msg []byte, err error) {
+	if errors.Is(err, ErrInvalidCRC) {
+		d.stats.crcErrors.Add(1)
+	} else {
+		d.stats.malformed.Add(1)
+	}
+	d.stats.discardedBytes.Add(uint64(len(msg)) + 4) // + DLE,STX and DLE,ETX/??? sequences
+	d.config.Logger.Debug("discarded corrupted actisense binary message", "err", err, "bytes", msg)
+}
 
+// StreamStats returns counters of messages read and corruption detected in binary stream
+func (d *BinaryFormatDevice) StreamStats() StreamStats {
+	return StreamStats{
+		Messages:       d.stats.messages.Load(),
+		CRCErrors:      d.stats.crcErrors.Load(),
+		Malformed:      d.stats.malformed.Load(),
+		Resyncs:        d.stats.resyncs.Load(),
+		DiscardedBytes: d.stats.discardedBytes.Load(),
+	}
 }
 
 func fromNGTMessage(raw []byte, now time.Time) (nmea.RawMessage, error) {
@@ -197,12 +422,11 @@ func fromNGTMessage(raw []byte, now time.Time) (nmea.RawMessage, error) {
 		return nmea.RawMessage{}, errors.New("raw message length too short to be valid BinaryFormatDevice message")
 	}
 	payloadLen := int(raw[1])
-	if len(raw)-2 > payloadLen {
-		payloadLen = int(raw[1])
-		//return nmea.RawMessage{}, errors.New("raw message payload length does not match payload length")
+	if payloadLen < 1 || payloadLen > len(raw)-2 {
+		return nmea.RawMessage{}, fmt.Errorf("data length byte value is different from actual length, %v!=%v", payloadLen, len(raw)-2)
 	}
 	dataBytes := make([]byte, payloadLen)
-	copy(dataBytes, raw[2:payloadLen])
+	copy(dataBytes, raw[2:2+payloadLen])
 
 	return nmea.RawMessage{
 		Time: now,
@@ -216,12 +440,13 @@ func fromNGTMessage(raw []byte, now time.Time) (nmea.RawMessage, error) {
 	}, nil
 }
 
-func fromActisenseNGTBinaryMessage(raw []byte, now time.Time) (nmea.RawMessage, error) {
+// fromActisenseNGTBinaryMessage parses NGT binary message. Returned message Data references raw slice.
+func fromActisenseNGTBinaryMessage(raw []byte, now time.Time, clock *nmea.DeviceClock) (nmea.RawMessage, error) {
 	length := len(raw) - 2 // 2 bytes for: command(raw[0]) + len(raw[1])
-	data := raw[2:]
 	if length < 11 {
 		return nmea.RawMessage{}, errors.New("raw message length too short to be valid NMEA message")
 	}
+	data := raw[2:]
 
 	const dataPartIndex = int(11)
 	l := data[10]
@@ -235,26 +460,31 @@ func fromActisenseNGTBinaryMessage(raw []byte, now time.Time) (nmea.RawMessage,
 	}
 
 	pgn := uint32(data[1]) + uint32(data[2])<<8 + uint32(data[3])<<16
-	dataBytes := make([]byte, l)
-	copy(dataBytes, data[dataPartIndex:endIndex])
+	dataBytes := data[dataPartIndex:endIndex]
+
+	// NB: actisense ngt-1 has (four bytes) for timestamp in milliseconds
+	timestamp := binary.LittleEndian.Uint32(data[6:10])
 
 	return nmea.RawMessage{
-		Time: now,
+		Time:       now,
+		BusTime:    busTime(clock, time.Duration(timestamp)*time.Millisecond, now),
+		DeviceTime: time.Duration(timestamp) * time.Millisecond,
 		Header: nmea.CanBusHeader{
 			PGN:         pgn,
 			Source:      data[5],
 			Destination: data[4],
 			Priority:    data[0],
 		},
-		// NB: actisense ngt-1 seems to have some incrementing value for each message
-		// ala 0x46f1ba15 -> 1190246933 -> 2007-09-20T03:08:53+03:00
-		//Timestamp: uint32(data[6]) + uint32(data[7])<<8 + uint32(data[8])<<16 + uint32(data[9])<<24,
 		Data: dataBytes,
 	}, nil
 }
 
-func fromActisenseN2KBinaryMessage(raw []byte, now time.Time) (nmea.RawMessage, error) {
+// fromActisenseN2KBinaryMessage parses N2K binary message. Returned message Data references raw slice.
+func fromActisenseN2KBinaryMessage(raw []byte, now time.Time, clock *nmea.DeviceClock) (nmea.RawMessage, error) {
 	// first 3 bytes are: 1 byte for message type, 2 bytes for rest of message length
+	if len(raw) < 13 {
+		return nmea.RawMessage{}, errors.New("raw message length too short to be valid N2K message")
+	}
 	length := uint32(raw[1]) + uint32(raw[2])<<8
 	if int(length)+1 != len(raw) {
 		return nmea.RawMessage{}, errors.New("raw message length do not match actual data length")
@@ -267,27 +497,25 @@ func fromActisenseN2KBinaryMessage(raw []byte, now time.Time) (nmea.RawMessage,
 	prio := (dprp >> 2) & 7 // priority bits are 3,4,5th bit
 	rAndDP := dprp & 3      // data page + reserved is first 2 bits
 
-	pduFormat := raw[6] // PF (PDU Format)
-	pgn := uint32(rAndDP)<<16 + uint32(pduFormat)<<8
-	if pduFormat >= 240 { // message is broadcast, PS contains group extension
-		pgn += uint32(raw[5]) // +PS (PDU Specific)
-	}
+	pgn := nmea.PGNFromPDU(rAndDP, raw[6], raw[5]) // PF (PDU Format), PS (PDU Specific)
 	//control := raw[8] // `PGN control ID bits and 3-bit Fast-Packet sequence ID` I do not know where this is useful.
 
 	const dataPartIndex = int(13)
-	dataBytes := make([]byte, len(raw)-dataPartIndex)
-	copy(dataBytes, raw[dataPartIndex:])
+	dataBytes := raw[dataPartIndex:]
+
+	// NB: actisense n2k has (four bytes) for timestamp in milliseconds
+	timestamp := binary.LittleEndian.Uint32(raw[9:13])
 
 	return nmea.RawMessage{
-		Time: now,
+		Time:       now,
+		BusTime:    busTime(clock, time.Duration(timestamp)*time.Millisecond, now),
+		DeviceTime: time.Duration(timestamp) * time.Millisecond,
 		Header: nmea.CanBusHeader{
 			PGN:         pgn,
 			Source:      src,
 			Destination: dst,
 			Priority:    prio,
 		},
-		// NB: actisense n2k has (four bytes) for timestamp in milliseconds
-		//Timestamp: uint32(raw[9]) + uint32(raw[10])<<8 + uint32(raw[11])<<16 + uint32(raw[12])<<24,
 		Data: dataBytes,
 	}, nil
 }
@@ -302,7 +530,9 @@ func fromActisenseN2KBinaryMessage(raw []byte, now time.Time) (nmea.RawMessage,
 // byte 4,5,6,7: CanID (little endian)
 // byte 8 ... (N-1): data
 // byte N (last): CRC
-func fromRawActisenseMessage(raw []byte, now time.Time) (nmea.RawMessage, error) {
+//
+// Returned message Data references raw slice.
+func fromRawActisenseMessage(raw []byte, now time.Time, clock *nmea.DeviceClock) (nmea.RawMessage, error) {
 	if len(raw) < 8 {
 		return nmea.RawMessage{}, errors.New("raw actisense message length too short to be valid")
 	}
@@ -317,26 +547,63 @@ func fromRawActisenseMessage(raw []byte, now time.Time) (nmea.RawMessage, error)
 	}
 
 	CanID := nmea.ParseCANID(binary.LittleEndian.Uint32(raw[4:8]))
-	dataBytes := make([]byte, dLen-6)
-	copy(dataBytes, raw[8:len(raw)-1])
+	dataBytes := raw[8 : len(raw)-1]
+
+	// NB: RAW actisense has (two bytes) for timestamp in milliseconds
+	timestamp := binary.LittleEndian.Uint16(raw[2:4])
 
 	return nmea.RawMessage{
-		Time: now,
+		Time:       now,
+		BusTime:    busTime(clock, time.Duration(timestamp)*time.Millisecond, now),
+		DeviceTime: time.Duration(timestamp) * time.Millisecond,
 		Header: nmea.CanBusHeader{
 			PGN:         CanID.PGN,
 			Source:      CanID.Source,
 			Destination: CanID.Destination,
 			Priority:    CanID.Priority,
 		},
-		// NB: RAW actisense seems to have some incrementing value (2 bytes) for each message
 		Data: dataBytes,
 	}, nil
 }
 
+// setRawMessage copies message into given message. Data of given message is reused when it has enough capacity,
+// otherwise new slice is taken from pool (when set) or allocated.
+func setRawMessage(to *nmea.RawMessage, msg nmea.RawMessage, pool *nmea.BufferPool) {
+	data := to.Data
+	if cap(data) < len(msg.Data) {
+		if pool != nil {
+			data = pool.Get(len(msg.Data))
+		} else {
+			data = make([]byte, len(msg.Data))
+		}
+	}
+	data = data[:len(msg.Data)]
+	copy(data, msg.Data)
+
+	to.Time = msg.Time
+	to.BusTime = msg.BusTime
+	to.DeviceTime = msg.DeviceTime
+	to.Header = msg.Header
+	to.Data = data
+}
+
+const (
+	counterWrapAround32bitMs = time.Duration(1<<32) * time.Millisecond
+	counterWrapAround16bitMs = time.Duration(1<<16) * time.Millisecond
+)
+
+// busTime converts device timestamp to bus time. Returns zero time when clock is not set.
+func busTime(clock *nmea.DeviceClock, timestamp time.Duration, now time.Time) time.Time {
+	if clock == nil {
+		return time.Time{}
+	}
+	return clock.BusTime(timestamp, now)
+}
+
 // crcCheck calculates and checks message checksum.
 func crcCheck(data []byte) error {
 	if crc(data) != 0 {
-		return errors.New("raw message has invalid crc")
+		return ErrInvalidCRC
 	}
 	return nil
 }
@@ -366,19 +633,16 @@ func crc(data []byte) uint8 {
 // Page 14: ACommsCommand_SetOperatingMode
 // https://www.actisense.com/wp-content/uploads/2020/01/ActisenseComms-SDK-User-Manual-Issue-1.07-1.pdf
 func (d *BinaryFormatDevice) Initialize() error {
-	clearPGNFilter := []byte{ // `Receive All Transfer` Operating Mode
-		cmdDeviceMessageSend, // Op code (NGT specific message)
-		3,                    // length
-		0x11,                 // msg byte 1, command `operating mode`
-		0x02,                 // msg byte 2, argument 'receive all' (2 bytes)
-		0x00,                 // msg byte 3
-	}
-	return d.writeBstMessage(clearPGNFilter)
+	// `Receive All Transfer` Operating Mode
+	return d.writeBEMCommand(bemOperatingMode, uint8(OperatingModeReceiveAll), uint8(OperatingModeReceiveAll>>8))
 }
 
 func (d *BinaryFormatDevice) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
 	if d.config.DebugLogRawMessageBytes {
-		fmt.Printf("# DEBUG sending raw message: %+v\n", msg)
+		d.config.Logger.Debug("sending raw message", "message", msg)
 	}
 
 	header := msg.Header
@@ -421,7 +685,7 @@ func (d *BinaryFormatDevice) writeBstMessage(data []byte) error {
 	maxRetry := 5
 
 	if d.config.DebugLogRawMessageBytes {
-		fmt.Printf("# DEBUG sent raw actisense binary message: %x\n", packet)
+		d.config.Logger.Debug("sent raw actisense binary message", "bytes", packet)
 	}
 	for {
 		n, err := d.device.Write(packet)
@@ -444,7 +708,10 @@ func (d *BinaryFormatDevice) writeBstMessage(data []byte) error {
 	return nil
 }
 
+// Close closes device. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
 func (d *BinaryFormatDevice) Close() error {
+	d.closed.Store(true)
+	d.reader.Close()
 	if c, ok := d.device.(io.Closer); ok {
 		return c.Close()
 	}
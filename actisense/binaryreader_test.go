@@ -9,6 +9,7 @@ import (
 	test_test "github.com/aldas/go-nmea-client/test"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -25,7 +26,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129025, position rapid update",
 			when: "93130201f801ff7faf3a0a0908e715b322c318590dca",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 151665327 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x2,     // 2
 					PGN:         0x1f801, // 129025
@@ -40,7 +42,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 127250, vessel heading",
 			when: "93130212f101ff80af3a0a090800fde3ff7f3005fd41",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 151665327 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x2,     // 2
 					PGN:         0x1f112, // 127250
@@ -55,7 +58,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129029, GNSS Position Data",
 			when: "93360305f801ff7f083d0a092b004949d8343e0f00463eb928411408a064944bd69a1b03f0d8ffffffffffff12fc003c005a00ac08000000fd",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 151665928 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x3,     // 3
 					PGN:         0x1f805, // 129029
@@ -80,7 +84,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129026, COG & SOG, Rapid Update",
 			when: "93130202f801ff7f15baf1460800fcffff0000ffffd9",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190246933 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    2,
 					PGN:         129026,
@@ -99,7 +104,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129025, Position, Rapid Update",
 			when: "93130201f801ff7f15baf146081e17b3224919590d00",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190246933 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    2,
 					PGN:         129025,
@@ -118,7 +124,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 127250, Vessel Heading",
 			when: "93130212f101ff8016baf1460800bdeeff7f3105fd6a",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190246934 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    2,
 					PGN:         127250,
@@ -137,7 +144,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 127251, Rate of Turn",
 			when: "93130313f101ff8017baf1460800f2e61d0000ffffd0",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190246935 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    3,
 					PGN:         127251,
@@ -156,7 +164,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129029, GNSS Position Data",
 			when: "93360305f801ff7f0cbcf1462b005549b8d94e108032064a71411408009add56f59a1b03501517010000000012fc000e019a01ac08000000ce",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190247436 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    3,
 					PGN:         129029,
@@ -181,7 +190,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129540, GNSS Sats in View",
 			when: "93920604fa01ff7f10bcf1468700ff0b02961a72501c0c00000000f203d106ae00480d00000000f206e819c431740e00000000f20c39375b4cfc0800000000f213f40c7323d80e00000000f256d1060b116c0700000000f21d390af1936c0700000000f020c5131fba140500000000f046f40c58f16c0700000000f04d8b18cf51dc0500000000f05700000000f00a00000000f07a",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190247440 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    6,
 					PGN:         129540,
@@ -214,7 +224,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 126992, System Time",
 			when: "93130310f001ff7f1bbcf1460800f05549b8d94e1045",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190247451 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    3,
 					PGN:         126992,
@@ -233,7 +244,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 129539, GNSS DOPs",
 			when: "93130603fa01ff7f1cbcf1460800d30e013601ff7f2a",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190247452 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    6,
 					PGN:         129539,
@@ -252,7 +264,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 127258, Magnetic Variation",
 			when: "9313071af101ff7f1dbcf1460800f6ffff3105ffff89",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190247453 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    7,
 					PGN:         127258,
@@ -271,7 +284,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 127257, Attitude",
 			when: "93130319f101ff801dbcf1460800ff7f77fcecf9ffe0",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1190247453 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    3,
 					PGN:         127257,
@@ -286,7 +300,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 130827, Lowrance: unknown",
 			when: "9310070bff01ff08af172e00053f9f0200006b",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 3020719 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x7,
 					PGN:         130827, // 0x1ff0b
@@ -301,7 +316,8 @@ func TestParseRawMessages(t *testing.T) {
 			name: "ok, 126208",
 			when: "93110300ed01080353a07200060200ef01010002",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 7512147 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x3,
 					PGN:         126208, // 0x1ed00
@@ -325,7 +341,7 @@ func TestParseRawMessages(t *testing.T) {
 			raw, err := hex.DecodeString(tc.when)
 			assert.NoError(t, err)
 
-			result, err := fromActisenseNGTBinaryMessage(raw, now)
+			result, err := fromActisenseNGTBinaryMessage(raw, now, nil)
 
 			assert.Equal(t, tc.expect, result)
 			if tc.expectError != "" {
@@ -372,7 +388,8 @@ func TestFromActisenseN2KBinaryMessage(t *testing.T) {
 				"ffff7f014b1a1b4e5b5c" +
 				"12ffffff7f01c3",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 1696792 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					PGN:         130845,
 					Source:      11,
@@ -414,7 +431,7 @@ func TestFromActisenseN2KBinaryMessage(t *testing.T) {
 			raw, err := hex.DecodeString(tc.when)
 			assert.NoError(t, err)
 
-			result, err := fromActisenseN2KBinaryMessage(raw, now)
+			result, err := fromActisenseN2KBinaryMessage(raw, now, nil)
 
 			assert.Equal(t, tc.expect, result)
 			if tc.expectError != "" {
@@ -426,6 +443,26 @@ func TestFromActisenseN2KBinaryMessage(t *testing.T) {
 	}
 }
 
+func TestFromActisenseN2KBinaryMessage_busTime(t *testing.T) {
+	now := time.Unix(1623928400, 0)
+	clock := nmea.NewDeviceClock(counterWrapAround32bitMs)
+
+	// timestamp bytes 9-12 (little endian milliseconds): 0x000003e8 = 1000ms, 0x0000044c = 1100ms
+	first, err := hex.DecodeString("d01400ff0b01f80900e80300000001020304050607")
+	assert.NoError(t, err)
+	second, err := hex.DecodeString("d01400ff0b01f809004c0400000001020304050607")
+	assert.NoError(t, err)
+
+	result, err := fromActisenseN2KBinaryMessage(first, now, clock)
+	assert.NoError(t, err)
+	assert.Equal(t, now, result.BusTime)
+
+	result, err = fromActisenseN2KBinaryMessage(second, now.Add(130*time.Millisecond), clock)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(100*time.Millisecond), result.BusTime)
+	assert.Equal(t, uint32(129025), result.Header.PGN)
+}
+
 func TestNGT1Device_Read(t *testing.T) {
 	exampleData := test_test.LoadBytes(t, "actisense-serial-ng1-cat-usb-2021-05-14-1005.bin")
 	r := bytes.NewReader(exampleData)
@@ -460,7 +497,8 @@ func TestFromRawActisenseMessage(t *testing.T) {
 			name: "ok, ISORequest broadcast, address claim",
 			when: "95093eb7feffea1800ee0080",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 46910 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x6,
 					PGN:         uint32(nmea.PGNISORequest),
@@ -474,7 +512,8 @@ func TestFromRawActisenseMessage(t *testing.T) {
 			name: "ok, 130310",
 			when: "950ea57f1606fd1501c170ffffffffffde",
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 32677 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					Priority:    0x5,
 					PGN:         130310,
@@ -490,7 +529,7 @@ func TestFromRawActisenseMessage(t *testing.T) {
 			raw, err := hex.DecodeString(tc.when)
 			assert.NoError(t, err)
 
-			result, err := fromRawActisenseMessage(raw, now)
+			result, err := fromRawActisenseMessage(raw, now, nil)
 
 			assert.Equal(t, tc.expect, result)
 			if tc.expectError != "" {
@@ -501,3 +540,369 @@ func TestFromRawActisenseMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestBinaryFormatDevice_ReadRawMessageInto(t *testing.T) {
+	exampleData := test_test.LoadBytes(t, "actisense-serial-ng1-cat-usb-2021-05-14-1005.bin")
+	wr := bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(exampleData)), nil)
+	expectDevice := NewBinaryDevice(bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(exampleData)), nil))
+
+	device := NewBinaryDevice(wr)
+	msg := nmea.RawMessage{Data: make([]byte, 0, nmea.ISOTPDataMaxSize)}
+	dataPtr := &msg.Data[:1][0]
+	for i := 0; i < 20; i++ {
+		expect, err := expectDevice.ReadRawMessage(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		err = device.ReadRawMessageInto(context.Background(), &msg)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expect.Header, msg.Header)
+		assert.Equal(t, expect.Data, msg.Data)
+		assert.Same(t, dataPtr, &msg.Data[:1][0])
+	}
+}
+
+func bstPacket(t *testing.T, rawHex string) []byte {
+	raw, err := hex.DecodeString(rawHex)
+	assert.NoError(t, err)
+	packet := []byte{DLE, STX}
+	for _, b := range raw {
+		if b == DLE {
+			packet = append(packet, DLE)
+		}
+		packet = append(packet, b)
+	}
+	return append(packet, DLE, ETX)
+}
+
+func TestBinaryFormatDevice_ReadRawMessage_chunkedReads(t *testing.T) {
+	first := bstPacket(t, "95093eb7feffea1800ee0080")
+	second := bstPacket(t, "950ea57f1606fd1501c170ffffffffffde")
+
+	var testCases = []struct {
+		name  string
+		reads []test_test.ReadResult
+	}{
+		{
+			name: "ok, multiple messages in single read",
+			reads: []test_test.ReadResult{
+				{Read: append(append([]byte{0x00, 0x01}, first...), second...)},
+			},
+		},
+		{
+			name: "ok, message split over multiple reads",
+			reads: []test_test.ReadResult{
+				{Read: first[:1]},
+				{Read: first[1:5]},
+				{Read: append(append([]byte{}, first[5:]...), second[:3]...)},
+				{Read: second[3:]},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockReader := &test_test.MockReaderWriter{Reads: tc.reads}
+			device := NewBinaryDevice(mockReader)
+
+			msg, err := device.ReadRawMessage(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(nmea.PGNISORequest), msg.Header.PGN)
+			assert.Equal(t, nmea.RawData{0x0, 0xee, 0x0}, msg.Data)
+
+			msg, err = device.ReadRawMessage(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(130310), msg.Header.PGN)
+			assert.Equal(t, nmea.RawData{0x1, 0xc1, 0x70, 0xff, 0xff, 0xff, 0xff, 0xff}, msg.Data)
+		})
+	}
+}
+
+type countingReader struct {
+	io.Reader
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+func (r *countingReader) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestBinaryFormatDevice_ReadRawMessage_bulkReads(t *testing.T) {
+	exampleData := test_test.LoadBytes(t, "actisense-serial-ng1-cat-usb-2021-05-14-1005.bin")
+	reader := &countingReader{Reader: bytes.NewReader(exampleData)}
+
+	device := NewBinaryDevice(reader)
+	for i := 0; i < 20; i++ {
+		_, err := device.ReadRawMessage(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	assert.Less(t, reader.reads, 20) // single read contains multiple messages
+}
+
+func TestBinaryFormatDevice_ReadRawFrame(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	mockReader := &test_test.MockReaderWriter{Reads: []test_test.ReadResult{
+		{Read: bstPacket(t, "950ea57f1606fd1501c170ffffffffffde")},
+	}}
+	device := NewBinaryDevice(mockReader)
+	device.timeNow = func() time.Time { return now }
+
+	frame, err := device.ReadRawFrame(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawFrame{
+		Time:   now,
+		Header: nmea.CanBusHeader{Priority: 5, PGN: 130310, Destination: nmea.AddressGlobal, Source: 22},
+		Length: 8,
+		Data:   [8]byte{0x1, 0xc1, 0x70, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}, frame)
+
+	// NGT-1 assembles messages in device, original frames are not available
+	exampleData := test_test.LoadBytes(t, "actisense-serial-ng1-cat-usb-2021-05-14-1005.bin")
+	device = NewBinaryDevice(bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(exampleData)), nil))
+	_, err = device.ReadRawFrame(context.Background())
+	assert.ErrorIs(t, err, nmea.ErrNotRawFrame)
+}
+
+// newStreamTestDevice creates device reading given stream where each call to timeNow advances clock so device
+// returns io.EOF immediately at the end of stream instead of waiting for ReceiveDataTimeout.
+func newStreamTestDevice(stream []byte) *BinaryFormatDevice {
+	device := NewBinaryDevice(&countingReader{Reader: bytes.NewReader(stream)})
+	now := test_test.UTCTime(1665488842)
+	device.timeNow = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	return device
+}
+
+func TestBinaryFormatDevice_ReadRawMessage_resync(t *testing.T) {
+	valid := bstPacket(t, "950ea57f1606fd1501c170ffffffffffde")
+	invalidCRC := bstPacket(t, "950ea57f1606fd1501c170ffffffffffdf")
+
+	var testCases = []struct {
+		name   string
+		when   []byte
+		expect StreamStats
+	}{
+		{
+			name:   "ok, garbage before message",
+			when:   append([]byte{0x01, DLE, 0x05, 0xff}, valid...),
+			expect: StreamStats{Messages: 1, DiscardedBytes: 4},
+		},
+		{
+			name:   "ok, message with invalid crc is discarded",
+			when:   append(append([]byte{}, invalidCRC...), valid...),
+			expect: StreamStats{Messages: 1, CRCErrors: 1, DiscardedBytes: uint64(len(invalidCRC))},
+		},
+		{
+			name:   "ok, truncated message is discarded",
+			when:   append(append([]byte{}, valid[:8]...), valid...),
+			expect: StreamStats{Messages: 1, Resyncs: 1, DiscardedBytes: 8},
+		},
+		{
+			name:   "ok, message with unknown escape sequence is discarded",
+			when:   append(append([]byte{}, valid[:8]...), append([]byte{DLE, 0x55}, valid...)...),
+			expect: StreamStats{Messages: 1, Malformed: 1, DiscardedBytes: 10},
+		},
+		{
+			name:   "ok, message with invalid length is discarded",
+			when:   append(bstPacket(t, "9502ff"), valid...),
+			expect: StreamStats{Messages: 1, Malformed: 1, DiscardedBytes: 7},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := newStreamTestDevice(tc.when)
+
+			msg, err := device.ReadRawMessage(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(130310), msg.Header.PGN)
+			assert.Equal(t, nmea.RawData{0x1, 0xc1, 0x70, 0xff, 0xff, 0xff, 0xff, 0xff}, msg.Data)
+
+			_, err = device.ReadRawMessage(context.Background())
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Equal(t, tc.expect, device.StreamStats())
+		})
+	}
+}
+
+func TestBinaryFormatDevice_ReadRawMessage_corruptedStream(t *testing.T) {
+	exampleData := test_test.LoadBytes(t, "actisense-serial-ng1-cat-usb-2021-05-14-1005.bin")
+
+	expectDevice := newStreamTestDevice(exampleData)
+	expectCount := 0
+	for {
+		_, err := expectDevice.ReadRawMessage(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		expectCount++
+	}
+	expectStats := expectDevice.StreamStats()
+	assert.Zero(t, expectStats.CRCErrors+expectStats.Malformed+expectStats.Resyncs)
+
+	// seeded source makes corruption deterministic between runs
+	rnd := rand.New(rand.NewSource(1))
+	corrupted := append([]byte{}, exampleData...)
+	for i := 0; i < 200; i++ {
+		corrupted[rnd.Intn(len(corrupted))] = byte(rnd.Intn(256))
+	}
+
+	device := newStreamTestDevice(corrupted)
+	count := 0
+	for {
+		msg, err := device.ReadRawMessage(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotZero(t, msg.Header.PGN)
+		count++
+	}
+	stats := device.StreamStats()
+	assert.Less(t, stats.Messages, expectStats.Messages) // includes device messages that are not returned
+	assert.Greater(t, count, expectCount/2)
+	assert.Less(t, count, expectCount)
+	assert.NotZero(t, stats.CRCErrors+stats.Malformed)
+	assert.NotZero(t, stats.DiscardedBytes)
+}
+
+func FuzzBinaryFormatDevice_ReadRawMessage(f *testing.F) {
+	f.Add(bstPacket(&testing.T{}, "95093eb7feffea1800ee0080"))
+	f.Add(bstPacket(&testing.T{}, "93110300ed01080353a07200060200ef01010002"))
+	f.Add(bstPacket(&testing.T{}, "d01400ff0b01f80900e80300000001020304050607"))
+	f.Add([]byte{DLE, STX, DLE, STX, DLE, DLE, DLE, ETX})
+
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		device := newStreamTestDevice(stream)
+		for i := 0; ; i++ {
+			_, err := device.ReadRawMessage(context.Background())
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err) || !assert.LessOrEqual(t, i, len(stream)/4) {
+				return
+			}
+		}
+		stats := device.StreamStats()
+		assert.LessOrEqual(t, stats.DiscardedBytes, uint64(len(stream)))
+	})
+}
+
+func FuzzFromActisenseNGTBinaryMessage(f *testing.F) {
+	for _, seed := range []string{
+		"93110300ed01080353a07200060200ef01010002",
+		"9313020df101ff0c1f23d30908ff0700ff7f0000ffffa6",
+		"9300",
+	} {
+		raw, _ := hex.DecodeString(seed)
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		msg, err := fromActisenseNGTBinaryMessage(raw, time.Time{}, nil)
+		if err != nil {
+			return
+		}
+		assert.Zero(t, crc(raw))
+		assert.LessOrEqual(t, len(msg.Data), len(raw))
+	})
+}
+
+func FuzzFromActisenseN2KBinaryMessage(f *testing.F) {
+	for _, seed := range []string{
+		"d01400ff0b01f80900e80300000001020304050607",
+		"d00c00ff0b01f80900e803000000",
+		"d0",
+	} {
+		raw, _ := hex.DecodeString(seed)
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		msg, err := fromActisenseN2KBinaryMessage(raw, time.Time{}, nil)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, len(raw)-13, len(msg.Data))
+	})
+}
+
+func FuzzFromRawActisenseMessage(f *testing.F) {
+	for _, seed := range []string{
+		"95093eb7feffea1800ee0080",
+		"950ea57f1606fd1501c170ffffffffffde",
+		"9500",
+	} {
+		raw, _ := hex.DecodeString(seed)
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		msg, err := fromRawActisenseMessage(raw, time.Time{}, nil)
+		if err != nil {
+			return
+		}
+		assert.Zero(t, crc(raw))
+		assert.Equal(t, len(raw)-9, len(msg.Data))
+	})
+}
+
+func FuzzFromNGTMessage(f *testing.F) {
+	for _, seed := range []string{
+		"a00ff0010000000000000000000000000000",
+		"a0ff0000000000000000000000000000",
+		"a000",
+	} {
+		raw, _ := hex.DecodeString(seed)
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		msg, err := fromNGTMessage(raw, time.Time{})
+		if err != nil {
+			return
+		}
+		assert.GreaterOrEqual(t, msg.Header.PGN, CanBoatFakePGNOffset)
+	})
+}
+
+type nopCloser struct {
+	io.ReadWriter
+	closed bool
+}
+
+func (c *nopCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestOpenBinaryDevice(t *testing.T) {
+	port := &nopCloser{ReadWriter: &countingReader{Reader: bytes.NewReader(nil)}}
+	var given nmea.SerialConfig
+	opener := nmea.SerialOpenerFunc(func(config nmea.SerialConfig) (io.ReadWriteCloser, error) {
+		given = config
+		return port, nil
+	})
+
+	device, err := OpenBinaryDevice(opener, nmea.SerialConfig{Name: "/dev/ttyUSB0"}, Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.SerialConfig{Name: "/dev/ttyUSB0", Baud: 115200, ReadTimeout: 100 * time.Millisecond}, given)
+
+	assert.NoError(t, device.Close())
+	assert.True(t, port.closed)
+}
@@ -0,0 +1,119 @@
+package actisense
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// blockingReadWriter blocks reads until it is closed, similarly to TCP connection with no incoming data
+type blockingReadWriter struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+}
+
+func newBlockingReadWriter() *blockingReadWriter {
+	r, w := io.Pipe()
+	return &blockingReadWriter{reader: r, writer: w}
+}
+
+func (b *blockingReadWriter) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+func (b *blockingReadWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (b *blockingReadWriter) Close() error {
+	return b.reader.Close()
+}
+
+var deviceTestCases = []struct {
+	name      string
+	newDevice func(rw io.ReadWriter) nmea.RawMessageReaderWriter
+}{
+	{
+		name: "BinaryFormatDevice",
+		newDevice: func(rw io.ReadWriter) nmea.RawMessageReaderWriter {
+			return NewBinaryDevice(rw)
+		},
+	},
+	{
+		name: "EBLFormatDevice",
+		newDevice: func(rw io.ReadWriter) nmea.RawMessageReaderWriter {
+			return NewEBLFormatDevice(rw)
+		},
+	},
+	{
+		name: "N2kASCIIDevice",
+		newDevice: func(rw io.ReadWriter) nmea.RawMessageReaderWriter {
+			return NewN2kASCIIDevice(rw, Config{})
+		},
+	},
+	{
+		name: "RawASCIIDevice",
+		newDevice: func(rw io.ReadWriter) nmea.RawMessageReaderWriter {
+			return NewRawASCIIDevice(rw, Config{})
+		},
+	},
+}
+
+func TestDevices_CloseUnblocksRead(t *testing.T) {
+	for _, tc := range deviceTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := tc.newDevice(newBlockingReadWriter())
+
+			errCh := make(chan error, 1)
+			go func() {
+				_, err := device.ReadRawMessage(context.Background())
+				errCh <- err
+			}()
+
+			time.Sleep(10 * time.Millisecond) // give read time to block
+			assert.NoError(t, device.Close())
+
+			select {
+			case err := <-errCh:
+				assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+				assert.True(t, errors.Is(err, net.ErrClosed))
+			case <-time.After(1 * time.Second):
+				t.Fatal("read was not unblocked by Close")
+			}
+
+			_, err := device.ReadRawMessage(context.Background())
+			assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+		})
+	}
+}
+
+func TestDevices_ContextCancellationUnblocksRead(t *testing.T) {
+	for _, tc := range deviceTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := tc.newDevice(newBlockingReadWriter())
+			defer device.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errCh := make(chan error, 1)
+			go func() {
+				_, err := device.ReadRawMessage(ctx)
+				errCh <- err
+			}()
+
+			time.Sleep(10 * time.Millisecond) // give read time to block
+			cancel()
+
+			select {
+			case err := <-errCh:
+				assert.ErrorIs(t, err, context.Canceled)
+			case <-time.After(1 * time.Second):
+				t.Fatal("read was not unblocked by context cancellation")
+			}
+		})
+	}
+}
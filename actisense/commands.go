@@ -0,0 +1,247 @@
+package actisense
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+)
+
+// BEM (Binary Encoded Message) command IDs of ActisenseComms device management commands sent over A1 (request) and
+// answered over A0 (response) command channel.
+const (
+	// bemReInitMainApp is BEM command ID to reboot device
+	bemReInitMainApp = 0x00
+	// bemCommitToEEPROM is BEM command ID to store current settings (operating mode, PGN enable lists) to EEPROM
+	bemCommitToEEPROM = 0x01
+	// bemRxPGNEnable is BEM command ID to enable/disable single PGN in receive PGN enable list
+	bemRxPGNEnable = 0x46
+	// bemTxPGNEnable is BEM command ID to enable/disable single PGN in transmit PGN enable list
+	bemTxPGNEnable = 0x47
+	// bemRxPGNEnableList is BEM command ID to get receive PGN enable list
+	bemRxPGNEnableList = 0x48
+	// bemTxPGNEnableList is BEM command ID to get transmit PGN enable list
+	bemTxPGNEnableList = 0x49
+	// bemDeletePGNEnableList is BEM command ID to clear PGN enable lists
+	bemDeletePGNEnableList = 0x4A
+	// bemActivatePGNEnableLists is BEM command ID to make changes done to PGN enable lists active
+	bemActivatePGNEnableLists = 0x4B
+)
+
+// OperatingMode is Actisense device operating mode
+type OperatingMode uint16
+
+const (
+	// OperatingModeNormal passes only PGNs enabled in receive PGN enable list
+	OperatingModeNormal OperatingMode = 0x0001
+	// OperatingModeReceiveAll passes all PGNs received from bus regardless of receive PGN enable list
+	OperatingModeReceiveAll OperatingMode = 0x0002
+)
+
+func (m OperatingMode) String() string {
+	switch m {
+	case OperatingModeNormal:
+		return "normal"
+	case OperatingModeReceiveAll:
+		return "receive_all"
+	default:
+		return fmt.Sprintf("unknown(0x%04x)", uint16(m))
+	}
+}
+
+// PGNList identifies PGN enable list of device
+type PGNList uint8
+
+const (
+	// PGNListRx is list of PGNs that device passes from bus to host in OperatingModeNormal
+	PGNListRx PGNList = 1
+	// PGNListTx is list of PGNs that device allows host to transmit to bus
+	PGNListTx PGNList = 2
+	// PGNListBoth selects both receive and transmit PGN enable lists. Can be used only with DeletePGNEnableLists.
+	PGNListBoth PGNList = 3
+)
+
+// BEMError is returned when device responds to command with non-zero error code
+type BEMError struct {
+	// Command is BEM ID of command that failed
+	Command uint8
+	// Code is error code device responded with
+	Code uint32
+}
+
+func (e *BEMError) Error() string {
+	return fmt.Sprintf("actisense BEM command 0x%02x failed with error code %v", e.Command, e.Code)
+}
+
+// QueryDeviceInfo sends hardware info request and waits for response. Returned info contains firmware version when
+// device has sent startup status before.
+//
+// Responses are processed by ReadRawMessage so reading must be in progress (in other goroutine) for command methods to
+// return. Use context with timeout as device may not respond at all.
+func (d *BinaryFormatDevice) QueryDeviceInfo(ctx context.Context) (DeviceInfo, error) {
+	if _, err := d.command(ctx, bemHardwareInfo); err != nil {
+		return DeviceInfo{}, err
+	}
+	info, _ := d.DeviceInfo()
+	return info, nil
+}
+
+// OperatingMode queries current operating mode of device.
+func (d *BinaryFormatDevice) OperatingMode(ctx context.Context) (OperatingMode, error) {
+	data, err := d.command(ctx, bemOperatingMode)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 2 {
+		return 0, errors.New("actisense operating mode response too short")
+	}
+	return OperatingMode(binary.LittleEndian.Uint16(data)), nil
+}
+
+// SetOperatingMode sets operating mode of device. Mode is not persisted over reboot unless CommitToEEPROM is called.
+func (d *BinaryFormatDevice) SetOperatingMode(ctx context.Context, mode OperatingMode) error {
+	_, err := d.command(ctx, bemOperatingMode, uint8(mode), uint8(mode>>8))
+	return err
+}
+
+// SetPGNEnabled enables or disables PGN in receive or transmit PGN enable list. Changes take effect after
+// ActivatePGNEnableLists is called.
+func (d *BinaryFormatDevice) SetPGNEnabled(ctx context.Context, list PGNList, pgn uint32, enabled bool) error {
+	bemID, err := pgnListCommand(list, bemRxPGNEnable, bemTxPGNEnable)
+	if err != nil {
+		return err
+	}
+	enable := uint8(0)
+	if enabled {
+		enable = 1
+	}
+	_, err = d.command(ctx, bemID, uint8(pgn), uint8(pgn>>8), uint8(pgn>>16), uint8(pgn>>24), enable)
+	return err
+}
+
+// PGNEnableList returns PGNs in receive or transmit PGN enable list.
+//
+// Response data is PGN count (1 byte) followed by PGNs (4 bytes each, little endian).
+func (d *BinaryFormatDevice) PGNEnableList(ctx context.Context, list PGNList) ([]uint32, error) {
+	bemID, err := pgnListCommand(list, bemRxPGNEnableList, bemTxPGNEnableList)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.command(ctx, bemID)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 1 {
+		return nil, errors.New("actisense PGN enable list response too short")
+	}
+	count := int(data[0])
+	if len(data)-1 < count*4 {
+		return nil, fmt.Errorf("actisense PGN enable list response too short for %v PGNs", count)
+	}
+	pgns := make([]uint32, 0, count)
+	for i := 0; i < count; i++ {
+		pgns = append(pgns, binary.LittleEndian.Uint32(data[1+i*4:]))
+	}
+	return pgns, nil
+}
+
+// DeletePGNEnableLists removes all PGNs from given PGN enable list(s).
+func (d *BinaryFormatDevice) DeletePGNEnableLists(ctx context.Context, list PGNList) error {
+	if list != PGNListRx && list != PGNListTx && list != PGNListBoth {
+		return fmt.Errorf("unknown actisense PGN list: %v", list)
+	}
+	_, err := d.command(ctx, bemDeletePGNEnableList, uint8(list))
+	return err
+}
+
+// ActivatePGNEnableLists makes changes done to PGN enable lists active.
+func (d *BinaryFormatDevice) ActivatePGNEnableLists(ctx context.Context) error {
+	_, err := d.command(ctx, bemActivatePGNEnableLists)
+	return err
+}
+
+// CommitToEEPROM stores current settings (operating mode, PGN enable lists) to device EEPROM so they persist over
+// reboot.
+func (d *BinaryFormatDevice) CommitToEEPROM(ctx context.Context) error {
+	_, err := d.command(ctx, bemCommitToEEPROM)
+	return err
+}
+
+// Reboot sends reboot command to device. Device does not respond to this command, instead it sends startup status
+// when it has restarted. Device needs to be initialized again after reboot.
+func (d *BinaryFormatDevice) Reboot() error {
+	return d.writeBEMCommand(bemReInitMainApp)
+}
+
+func pgnListCommand(list PGNList, rx uint8, tx uint8) (uint8, error) {
+	switch list {
+	case PGNListRx:
+		return rx, nil
+	case PGNListTx:
+		return tx, nil
+	}
+	return 0, fmt.Errorf("unknown actisense PGN list: %v", list)
+}
+
+// writeBEMCommand writes BEM command with arguments to device over A1 command channel.
+func (d *BinaryFormatDevice) writeBEMCommand(bemID uint8, args ...uint8) error {
+	msg := make([]byte, 0, 3+len(args))
+	msg = append(msg, cmdDeviceMessageSend, uint8(1+len(args)), bemID)
+	return d.writeBstMessage(append(msg, args...))
+}
+
+// command writes BEM command and waits for response with same BEM ID. Returns response data after common BEM header.
+// Commands are serialized as device responses can not be matched to requests other way than by BEM ID.
+func (d *BinaryFormatDevice) command(ctx context.Context, bemID uint8, args ...uint8) ([]byte, error) {
+	if d.closed.Load() {
+		return nil, nmea.ErrDeviceClosed
+	}
+	select {
+	case d.commandLock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-d.commandLock }()
+
+	response := make(chan []byte, 1)
+	d.infoLock.Lock()
+	d.commandWaiter = &bemWaiter{bemID: bemID, response: response}
+	d.infoLock.Unlock()
+	defer func() {
+		d.infoLock.Lock()
+		d.commandWaiter = nil
+		d.infoLock.Unlock()
+	}()
+
+	if err := d.writeBEMCommand(bemID, args...); err != nil {
+		return nil, err
+	}
+	select {
+	case payload := <-response:
+		if code := binary.LittleEndian.Uint32(payload[8:12]); code != 0 {
+			return nil, &BEMError{Command: bemID, Code: code}
+		}
+		return payload[bemHeaderLength:], nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// bemWaiter waits for BEM response to command
+type bemWaiter struct {
+	bemID    uint8
+	response chan []byte
+}
+
+// deliverResponse passes copy of BEM response payload to command waiting for it. Must be called with infoLock held.
+func (d *BinaryFormatDevice) deliverResponse(payload []byte) {
+	w := d.commandWaiter
+	if w == nil || w.bemID != payload[0] {
+		return
+	}
+	select {
+	case w.response <- append([]byte(nil), payload...):
+	default:
+	}
+}
@@ -0,0 +1,184 @@
+package actisense
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// bemResponse creates A0 BEM response packet with common header (model 69, serial 123456) followed by data
+func bemResponse(bemID uint8, errorCode uint8, data ...byte) []byte {
+	payload := append([]byte{bemID, 0x01, 0x45, 0x00, 0x40, 0xE2, 0x01, 0x00, errorCode, 0x00, 0x00, 0x00}, data...)
+	raw := append([]byte{cmdDeviceMessageReceived, uint8(len(payload))}, payload...)
+	raw = append(raw, 0-crc(raw))
+
+	packet := []byte{DLE, STX}
+	for _, b := range raw {
+		if b == DLE {
+			packet = append(packet, DLE)
+		}
+		packet = append(packet, b)
+	}
+	return append(packet, DLE, ETX)
+}
+
+// respondingDevice responds to every written command with response created by respond function
+type respondingDevice struct {
+	reader  *io.PipeReader
+	writer  *io.PipeWriter
+	written [][]byte
+	respond func(written []byte) []byte
+}
+
+func newRespondingDevice(respond func(written []byte) []byte) *respondingDevice {
+	r, w := io.Pipe()
+	return &respondingDevice{reader: r, writer: w, respond: respond}
+}
+
+func (d *respondingDevice) Read(p []byte) (int, error) {
+	return d.reader.Read(p)
+}
+
+func (d *respondingDevice) Write(p []byte) (int, error) {
+	d.written = append(d.written, append([]byte(nil), p...))
+	if response := d.respond(p); response != nil {
+		go d.writer.Write(response)
+	}
+	return len(p), nil
+}
+
+func (d *respondingDevice) Close() error {
+	return d.writer.Close()
+}
+
+func startReading(t *testing.T, device *BinaryFormatDevice) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if _, err := device.ReadRawMessage(ctx); err != nil {
+				if !errors.Is(err, context.Canceled) && !errors.Is(err, io.EOF) {
+					t.Error(err)
+				}
+				return
+			}
+		}
+	}()
+	return cancel
+}
+
+func testContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 2*time.Second)
+}
+
+func TestBinaryFormatDevice_OperatingMode(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		return bemResponse(bemOperatingMode, 0, 0x01, 0x00)
+	})
+	device := NewBinaryDevice(rw)
+	defer startReading(t, device)()
+
+	ctx, cancel := testContext()
+	defer cancel()
+	mode, err := device.OperatingMode(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, OperatingModeNormal, mode)
+	assert.Equal(t, "normal", mode.String())
+	assert.Equal(t, []byte{DLE, STX, 0xa1, 0x01, 0x11, 0x4d, DLE, ETX}, rw.written[0])
+
+	err = device.SetOperatingMode(ctx, OperatingModeReceiveAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{DLE, STX, 0xa1, 0x03, 0x11, 0x02, 0x00, 0x49, DLE, ETX}, rw.written[1])
+}
+
+func TestBinaryFormatDevice_QueryDeviceInfo(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		return bemResponse(bemHardwareInfo, 0)
+	})
+	device := NewBinaryDevice(rw)
+	defer startReading(t, device)()
+
+	ctx, cancel := testContext()
+	defer cancel()
+	info, err := device.QueryDeviceInfo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(69), info.ModelID)
+	assert.Equal(t, uint32(123456), info.SerialID)
+}
+
+func TestBinaryFormatDevice_PGNEnableList(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		switch written[4] {
+		case bemRxPGNEnable, bemActivatePGNEnableLists, bemDeletePGNEnableList:
+			return bemResponse(written[4], 0)
+		case bemTxPGNEnableList:
+			return bemResponse(bemTxPGNEnableList, 0,
+				0x02,                   // count
+				0x00, 0xEA, 0x00, 0x00, // 59904
+				0x06, 0xFD, 0x01, 0x00, // 130310
+			)
+		}
+		return nil
+	})
+	device := NewBinaryDevice(rw)
+	defer startReading(t, device)()
+
+	ctx, cancel := testContext()
+	defer cancel()
+
+	err := device.SetPGNEnabled(ctx, PGNListRx, 130310, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{DLE, STX, 0xa1, 0x06, 0x46, 0x06, 0xFD, 0x01, 0x00, 0x01, 0x0e, DLE, ETX}, rw.written[0])
+
+	assert.NoError(t, device.ActivatePGNEnableLists(ctx))
+
+	pgns, err := device.PGNEnableList(ctx, PGNListTx)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{59904, 130310}, pgns)
+
+	assert.NoError(t, device.DeletePGNEnableLists(ctx, PGNListBoth))
+
+	_, err = device.PGNEnableList(ctx, PGNListBoth)
+	assert.EqualError(t, err, "unknown actisense PGN list: 3")
+}
+
+func TestBinaryFormatDevice_command_errorCode(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		return bemResponse(bemCommitToEEPROM, 5)
+	})
+	device := NewBinaryDevice(rw)
+	defer startReading(t, device)()
+
+	ctx, cancel := testContext()
+	defer cancel()
+	err := device.CommitToEEPROM(ctx)
+	assert.EqualError(t, err, "actisense BEM command 0x01 failed with error code 5")
+
+	var bemErr *BEMError
+	assert.True(t, errors.As(err, &bemErr))
+}
+
+func TestBinaryFormatDevice_command_noResponse(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		return nil
+	})
+	device := NewBinaryDevice(rw)
+	defer startReading(t, device)()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := device.OperatingMode(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestBinaryFormatDevice_Reboot(t *testing.T) {
+	rw := newRespondingDevice(func(written []byte) []byte {
+		return nil
+	})
+	device := NewBinaryDevice(rw)
+
+	assert.NoError(t, device.Reboot())
+	assert.Equal(t, []byte{DLE, STX, 0xa1, 0x01, 0x00, 0x5e, DLE, ETX}, rw.written[0])
+}
@@ -0,0 +1,147 @@
+package actisense
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// readPollInterval is read deadline set for transports supporting deadlines. After deadline passes without data
+// context is checked and read is continued.
+const readPollInterval = 250 * time.Millisecond
+
+type readDeadlineSetter interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// contextReader makes blocking reads from device interruptible by context cancellation and Close. When device
+// supports read deadlines (net.Conn, os.File for pipes/ttys) reads are done with short deadline and context is checked
+// between reads. Otherwise (i.e. serial port, bufio readers) reads with cancellable context are done in separate
+// goroutine and caller waits for result or context cancellation. Data read by goroutine after caller gave up is
+// returned by next read, so no data is lost.
+//
+// Note: is not go-routine safe, device must not be read concurrently.
+type contextReader struct {
+	reader   io.Reader
+	deadline readDeadlineSetter // nil when device does not support deadlines
+
+	closeOnce sync.Once
+	done      chan struct{}
+
+	started  bool
+	pending  bool // goroutine is reading or has unclaimed result
+	requests chan struct{}
+	results  chan readResult
+	buf      []byte
+	leftover []byte
+}
+
+func newContextReader(reader io.Reader) *contextReader {
+	r := &contextReader{
+		reader: reader,
+		done:   make(chan struct{}),
+	}
+	if ds, ok := reader.(readDeadlineSetter); ok {
+		// regular files and some other transports implement interface but do not support deadlines
+		if err := ds.SetReadDeadline(time.Time{}); err == nil {
+			r.deadline = ds
+		}
+	}
+	return r
+}
+
+// Read reads into p. Blocks until data is read, error occurs, context is cancelled or reader is closed.
+func (r *contextReader) Read(ctx context.Context, p []byte) (int, error) {
+	if r.deadline != nil {
+		return r.readWithDeadline(ctx, p)
+	}
+	if len(r.leftover) > 0 {
+		n := copy(p, r.leftover)
+		r.leftover = r.leftover[n:]
+		return n, nil
+	}
+	if !r.pending && ctx.Done() == nil { // context can not be cancelled, no need for goroutine
+		return r.reader.Read(p)
+	}
+	return r.readInGoroutine(ctx, p)
+}
+
+func (r *contextReader) readWithDeadline(ctx context.Context, p []byte) (int, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-r.done:
+			return 0, nmea.ErrDeviceClosed
+		default:
+		}
+		if err := r.deadline.SetReadDeadline(time.Now().Add(readPollInterval)); err != nil {
+			return 0, err
+		}
+		n, err := r.reader.Read(p)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *contextReader) readInGoroutine(ctx context.Context, p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		r.buf = make([]byte, len(p))
+		r.requests = make(chan struct{})
+		r.results = make(chan readResult, 1)
+		go r.readLoop()
+	}
+	if !r.pending {
+		select {
+		case r.requests <- struct{}{}:
+			r.pending = true
+		case <-r.done:
+			return 0, nmea.ErrDeviceClosed
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-r.done:
+		return 0, nmea.ErrDeviceClosed
+	case res := <-r.results:
+		r.pending = false
+		n := copy(p, r.buf[:res.n])
+		r.leftover = r.buf[n:res.n]
+		return n, res.err
+	}
+}
+
+func (r *contextReader) readLoop() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.requests:
+		}
+		n, err := r.reader.Read(r.buf)
+		r.results <- readResult{n: n, err: err}
+	}
+}
+
+// Close unblocks pending and future reads with nmea.ErrDeviceClosed. Device itself is not closed.
+func (r *contextReader) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+}
@@ -0,0 +1,58 @@
+package actisense
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestContextReader_Read_cancelledWithoutDeadlines(t *testing.T) {
+	pr, pw := io.Pipe()
+	reader := newContextReader(pr)
+	assert.Nil(t, reader.deadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	buf := make([]byte, 10)
+	_, err := reader.Read(ctx, buf)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// data read by goroutine after context was cancelled is returned by next read
+	go pw.Write([]byte("abcdef"))
+
+	buf = make([]byte, 4)
+	n, err := reader.Read(context.Background(), buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", string(buf[:n]))
+
+	n, err = reader.Read(context.Background(), buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ef", string(buf[:n]))
+
+	reader.Close()
+	_, err = reader.Read(ctx, buf)
+	assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+}
+
+func TestContextReader_Read_withDeadlines(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	reader := newContextReader(client)
+	assert.NotNil(t, reader.deadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	buf := make([]byte, 10)
+	_, err := reader.Read(ctx, buf)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go server.Write([]byte("abc"))
+	n, err := reader.Read(context.Background(), buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", string(buf[:n]))
+}
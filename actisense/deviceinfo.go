@@ -0,0 +1,109 @@
+package actisense
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// bemHardwareInfo is BEM (Binary Encoded Message) command ID to request hardware information from device
+	bemHardwareInfo = 0x10
+	// bemOperatingMode is BEM command ID to get/set device operating mode
+	bemOperatingMode = 0x11
+	// bemStartupStatus is BEM command ID of status message that device sends on startup. Contains firmware version.
+	bemStartupStatus = 0xF0
+)
+
+// DeviceInfo is information that Actisense device (NGT-1, W2K-1) reports about itself. All BEM responses start with
+// common header containing model and serial ID and startup status message additionally contains firmware version.
+//
+// Layout of the BEM messages is reverse engineered by Canboat project (`Actisense: Startup status` fake PGN 0x400F0).
+type DeviceInfo struct {
+	// ModelID is Actisense model identifier
+	ModelID uint16 `json:"model_id"`
+	// SerialID is device serial number
+	SerialID uint32 `json:"serial_id"`
+	// ErrorCode is last error code device reported. 0 means no error.
+	ErrorCode uint32 `json:"error_code"`
+	// FirmwareVersion is firmware version (i.e. "2.210"). Empty when device has not (yet) sent startup status.
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	// UpdatedAt is time when info was last updated from device response
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// bemHeaderLength is length of BEM response common header: BEM ID (1), SID (1), model ID (2), serial ID (4),
+// error code (4)
+const bemHeaderLength = 12
+
+// ParseDeviceInfo parses device information from BEM response payload (bytes after command and length byte). Fields
+// that are not included in given BEM response are copied from previous info.
+func ParseDeviceInfo(payload []byte, previous DeviceInfo, now time.Time) (DeviceInfo, error) {
+	if len(payload) < bemHeaderLength {
+		return previous, errors.New("actisense BEM response too short to contain device info")
+	}
+	info := previous
+	info.ModelID = binary.LittleEndian.Uint16(payload[2:4])
+	info.SerialID = binary.LittleEndian.Uint32(payload[4:8])
+	info.ErrorCode = binary.LittleEndian.Uint32(payload[8:12])
+	info.UpdatedAt = now
+
+	if payload[0] == bemStartupStatus && len(payload) >= bemHeaderLength+2 {
+		version := binary.LittleEndian.Uint16(payload[bemHeaderLength : bemHeaderLength+2])
+		info.FirmwareVersion = fmt.Sprintf("%d.%03d", version/1000, version%1000)
+	}
+	return info, nil
+}
+
+// DeviceInfo returns latest information that device has reported about itself. Information is collected from BEM
+// responses seen by ReadRawMessage (even when Config.OutputActisenseMessages is false), so reading must be in progress
+// for this to be updated. Returns false when device has not sent any BEM responses yet.
+func (d *BinaryFormatDevice) DeviceInfo() (DeviceInfo, bool) {
+	d.infoLock.Lock()
+	defer d.infoLock.Unlock()
+
+	return d.info, !d.info.UpdatedAt.IsZero()
+}
+
+// RequestDeviceInfo sends hardware info BEM request to device. Response is processed by ReadRawMessage and is
+// available through DeviceInfo method.
+func (d *BinaryFormatDevice) RequestDeviceInfo() error {
+	return d.writeBEMCommand(bemHardwareInfo)
+}
+
+func (d *BinaryFormatDevice) updateDeviceInfo(msg []byte, now time.Time) {
+	// first 2 bytes are command + length
+	if len(msg) < 2 {
+		return
+	}
+	payloadLen := int(msg[1])
+	if payloadLen > len(msg)-2 {
+		payloadLen = len(msg) - 2
+	}
+
+	d.infoLock.Lock()
+	payload := msg[2 : 2+payloadLen]
+	info, err := ParseDeviceInfo(payload, d.info, now)
+	if err != nil {
+		d.infoLock.Unlock()
+		return
+	}
+	d.info = info
+	d.deliverResponse(payload)
+
+	isHealthUpdated := false
+	if payload[0] == bemSystemStatus {
+		if health, err := ParseSystemStatus(payload, d.health, now); err == nil {
+			d.health = health
+			isHealthUpdated = true
+		}
+	}
+	health := d.health
+	d.infoLock.Unlock()
+
+	// monitor is called without lock as alert callback could query device info/health
+	if isHealthUpdated && d.config.HealthMonitor != nil {
+		d.config.HealthMonitor.Update(health)
+	}
+}
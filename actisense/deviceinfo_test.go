@@ -0,0 +1,113 @@
+package actisense
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseDeviceInfo(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+
+	var testCases = []struct {
+		name        string
+		givenPrev   DeviceInfo
+		whenPayload []byte
+		expect      DeviceInfo
+		expectError string
+	}{
+		{
+			name: "ok, startup status contains firmware version",
+			whenPayload: []byte{
+				0xF0,       // BEM ID
+				0x01,       // SID
+				0x45, 0x00, // model ID
+				0x40, 0xE2, 0x01, 0x00, // serial ID
+				0x00, 0x00, 0x00, 0x00, // error code
+				0xA2, 0x08, // firmware version 2210
+			},
+			expect: DeviceInfo{ModelID: 69, SerialID: 123456, FirmwareVersion: "2.210", UpdatedAt: now},
+		},
+		{
+			name:      "ok, other BEM responses keep previously seen firmware version",
+			givenPrev: DeviceInfo{FirmwareVersion: "2.210"},
+			whenPayload: []byte{
+				0x11,       // BEM ID, operating mode
+				0x02,       // SID
+				0x45, 0x00, // model ID
+				0x40, 0xE2, 0x01, 0x00, // serial ID
+				0x01, 0x00, 0x00, 0x00, // error code
+				0x02, 0x00, // operating mode
+			},
+			expect: DeviceInfo{ModelID: 69, SerialID: 123456, ErrorCode: 1, FirmwareVersion: "2.210", UpdatedAt: now},
+		},
+		{
+			name:        "nok, too short",
+			givenPrev:   DeviceInfo{FirmwareVersion: "2.210"},
+			whenPayload: []byte{0xF0, 0x01},
+			expect:      DeviceInfo{FirmwareVersion: "2.210"},
+			expectError: "actisense BEM response too short to contain device info",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseDeviceInfo(tc.whenPayload, tc.givenPrev, now)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type readWriteBuffer struct {
+	io.Reader
+	written bytes.Buffer
+}
+
+func (b *readWriteBuffer) Write(p []byte) (int, error) {
+	return b.written.Write(p)
+}
+
+func TestBinaryFormatDevice_DeviceInfo(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+	input := []byte{
+		DLE, STX,
+		cmdDeviceMessageReceived, 14, // command + length
+		0xF0, 0x01, 0x45, 0x00, 0x40, 0xE2, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xA2, 0x08,
+		0x00, // crc
+		DLE, ETX,
+	}
+	rw := &readWriteBuffer{Reader: bytes.NewReader(input)}
+	device := NewBinaryDeviceWithConfig(rw, Config{})
+	clock := now
+	device.timeNow = func() time.Time {
+		clock = clock.Add(1 * time.Millisecond)
+		return clock
+	}
+
+	_, ok := device.DeviceInfo()
+	assert.False(t, ok)
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF) // BEM messages are not output by default
+
+	info, ok := device.DeviceInfo()
+	assert.True(t, ok)
+	assert.Equal(t, DeviceInfo{ModelID: 69, SerialID: 123456, FirmwareVersion: "2.210", UpdatedAt: now.Add(2 * time.Millisecond)}, info) // 1 initial + 1 bulk read
+}
+
+func TestBinaryFormatDevice_RequestDeviceInfo(t *testing.T) {
+	rw := &readWriteBuffer{Reader: bytes.NewReader(nil)}
+	device := NewBinaryDeviceWithConfig(rw, Config{})
+
+	err := device.RequestDeviceInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{DLE, STX, 0xa1, 0x01, 0x10, 0x10, 0x4e, DLE, ETX}, rw.written.Bytes())
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/aldas/go-nmea-client"
 	"io"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,8 +29,11 @@ import (
 // Example: first frame in file:
 // 1B 01 03 00 10 E7 A7 84 83 D9 01 1B 0A
 //
-//	03 <--- "03" maybe frame type
-//	   00 10 E7 A7 84 83 D9 01 <-- 8 byte little endian unsigned number,
+//	03 <--- "03" frame type (start time)
+//	   00 10 E7 A7 84 83 D9 01 <-- 8 byte little endian Windows FILETIME (100ns intervals since 1601-01-01)
+//
+// Frames that are not BST-95 CAN messages (file header, start time, text metadata, device info, error frames) are
+// passed to Config.OnEBLRecord. See ParseEBLRecord.
 const (
 	// SOH is start of data frame byte for Actisense BST-95 (EBL file created by Actisense W2K-1 device)
 	SOH = 0x01
@@ -42,11 +46,18 @@ const (
 // EBLFormatDevice is implementing Actisense EBL file format
 type EBLFormatDevice struct {
 	device io.ReadWriter
+	// reader makes reads from device interruptible by context cancellation and Close
+	reader *contextReader
 
 	sleepFunc func(timeout time.Duration)
 	timeNow   func() time.Time
 
 	config Config
+
+	// busClock converts 16bit millisecond timestamps of BST-95 messages to bus times
+	busClock *nmea.DeviceClock
+
+	closed atomic.Bool
 }
 
 // NewEBLFormatDevice creates new instance of Actisense device using binary formats (NGT1 and N2K binary)
@@ -59,11 +70,14 @@ func NewEBLFormatDeviceWithConfig(reader io.ReadWriter, config Config) *EBLForma
 	if config.ReceiveDataTimeout > 0 {
 		config.ReceiveDataTimeout = 5 * time.Second
 	}
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
 	return &EBLFormatDevice{
 		device:    reader,
+		reader:    newContextReader(reader),
 		sleepFunc: time.Sleep,
 		timeNow:   time.Now,
 		config:    config,
+		busClock:  nmea.NewDeviceClock(counterWrapAround16bitMs),
 	}
 }
 
@@ -86,12 +100,18 @@ func (d *EBLFormatDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage,
 			return nmea.RawMessage{}, ctx.Err()
 		default:
 		}
+		if d.closed.Load() {
+			return nmea.RawMessage{}, nmea.ErrDeviceClosed
+		}
 
-		n, err := d.device.Read(buf)
+		n, err := d.reader.Read(ctx, buf)
 		// on read errors we do not return immediately as for:
 		// os.ErrDeadlineExceeded - we set new deadline on next iteration
 		// io.EOF - we check if already read + received is enough to form complete message
 		if err != nil && !(errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF)) {
+			if d.closed.Load() {
+				return nmea.RawMessage{}, nmea.ErrDeviceClosed
+			}
 			return nmea.RawMessage{}, err
 		}
 
@@ -116,35 +136,30 @@ func (d *EBLFormatDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage,
 				state = processingEscapeSequence
 				break
 			}
+			if messageByteIndex == len(message) { // no end sequence found, discard and wait for next start sequence
+				state = waitingStartOfMessage
+				messageByteIndex = 0
+				break
+			}
 			message[messageByteIndex] = currentByte
 			messageByteIndex++
 		case processingEscapeSequence:
-			if currentByte == ESC { // any ESC characters are double escaped (ESC ESC)
+			if currentByte == ESC && messageByteIndex < len(message) { // any ESC characters are double escaped (ESC ESC)
 				state = readingMessageData
 				message[messageByteIndex] = currentByte
 				messageByteIndex++
 				break
 			}
-			if currentByte == NL { // end of message sequence (ESC + NL)
-				if messageByteIndex-2 <= 2 {
-					return nmea.RawMessage{}, errors.New("message too short to be BST95 format")
-				}
+			if currentByte == NL && messageByteIndex > 0 { // end of message sequence (ESC + NL)
 				msg := message[0:messageByteIndex]
-				if d.config.DebugLogRawMessageBytes && d.config.LogFunc != nil {
-					d.config.LogFunc("# DEBUG read raw actisense ELB message: %x\n", msg)
+				if d.config.DebugLogRawMessageBytes {
+					d.config.Logger.Debug("read raw actisense EBL message", "bytes", msg)
 				}
-				//if msg[0] == 0x3 { // 0x03 seems to be time since start of day (8 bytes)
-				//	d.config.LogFunc("# TIME: %x\n", msg)
-				//}
-				if msg[0] == 0x7 && msg[1] == cmdRAWActisenseMessageReceived { // 0x07+0x95 seems to identify BST-95 message
-					return fromActisenseBST95Message(msg[2:], now)
-				}
-				//if msg[0] != 0x3 && msg[0] != 0x7 { // all other messages
-				//	d.config.LogFunc("# XXX: %x\n", msg)
-				//}
-				if d.config.LogFunc != nil {
-					d.config.LogFunc("# ERROR unknown message type read: %x\n", msg)
+				// 0x07+0x95 identifies BST-95 message
+				if len(msg) > 2 && msg[0] == eblFrameTypeBST95 && msg[1] == cmdRAWActisenseMessageReceived && !isBST95ErrorFrame(msg[2:]) {
+					return fromActisenseBST95Message(msg[2:], now, d.busClock)
 				}
+				d.handleRecord(msg)
 			}
 			// when unknown ESC + ??? sequence - discard this current message and wait for next start sequence
 			state = waitingStartOfMessage
@@ -154,7 +169,42 @@ func (d *EBLFormatDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage,
 
 }
 
-func fromActisenseBST95Message(raw []byte, now time.Time) (nmea.RawMessage, error) {
+// handleRecord parses EBL record that is not CAN message and passes it to Config.OnEBLRecord. Records that can not be
+// parsed are skipped.
+func (d *EBLFormatDevice) handleRecord(record []byte) {
+	r, err := ParseEBLRecord(record)
+	if err != nil {
+		d.config.Logger.Debug("skipping invalid actisense EBL record", "err", err, "bytes", record)
+		return
+	}
+	if u, ok := r.(EBLUnknownRecord); ok {
+		d.config.Logger.Debug("unknown actisense EBL record type read", "type", u.Type, "bytes", record)
+	}
+	if d.config.OnEBLRecord != nil {
+		d.config.OnEBLRecord(r)
+	}
+}
+
+// ReadRawFrame reads next CAN frame from EBL file. BST-95 messages are ordinary CAN frames so frames are returned
+// without fast-packet assembly.
+func (d *EBLFormatDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	msg, err := d.ReadRawMessage(ctx)
+	if err != nil {
+		return nmea.RawFrame{}, err
+	}
+	if len(msg.Data) > 8 {
+		return nmea.RawFrame{}, nmea.ErrNotRawFrame
+	}
+	frame := nmea.RawFrame{
+		Time:   msg.Time,
+		Header: msg.Header,
+		Length: uint8(len(msg.Data)),
+	}
+	copy(frame.Data[:], msg.Data)
+	return frame, nil
+}
+
+func fromActisenseBST95Message(raw []byte, now time.Time, clock *nmea.DeviceClock) (nmea.RawMessage, error) {
 	const startOfData = 7 // length(1) + timestamp(2) + canid(4) = 7
 	if len(raw) < 8 {     // startOfData + min length of data (1)
 		return nmea.RawMessage{}, errors.New("raw message actual length too short to be valid BST-95 message")
@@ -168,13 +218,16 @@ func fromActisenseBST95Message(raw []byte, now time.Time) (nmea.RawMessage, erro
 	dataBytes := make([]byte, len(raw)-startOfData)
 	copy(dataBytes, raw[startOfData:])
 
+	// W2K-1 seems to use some kind of (offset) counter in milliseconds for timestamp. Probably some other message type
+	// in beginning of the EBL file has "start" time for that file to which this timestamp offset should be added to.
+	timestamp := uint16(raw[1]) + uint16(raw[2])<<8
+
 	return nmea.RawMessage{
-		Time:   now,
-		Header: nmea.ParseCANID(canID),
-		// W2K-1 seems to use some kind of (offset) counter for timestamp. Probably some other message type in beginning
-		// of the EBL file has "start" time for that file to which this timestamp offset should be added to.
-		//Timestamp: uint16(raw[1]) + uint16(raw[2]) << 8,
-		Data: dataBytes,
+		Time:       now,
+		BusTime:    busTime(clock, time.Duration(timestamp)*time.Millisecond, now),
+		DeviceTime: time.Duration(timestamp) * time.Millisecond,
+		Header:     nmea.ParseCANID(canID),
+		Data:       dataBytes,
 	}, nil
 }
 
@@ -187,7 +240,10 @@ func (d *EBLFormatDevice) WriteRawMessage(ctx context.Context, msg nmea.RawMessa
 	return nil
 }
 
+// Close closes device. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
 func (d *EBLFormatDevice) Close() error {
+	d.closed.Store(true)
+	d.reader.Close()
 	if c, ok := d.device.(io.Closer); ok {
 		return c.Close()
 	}
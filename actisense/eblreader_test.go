@@ -29,7 +29,9 @@ func TestEBLFormatDevice_Read(t *testing.T) {
 	}
 
 	firstPacket := nmea.RawMessage{
-		Time: now,
+		Time:       now,
+		BusTime:    now,
+		DeviceTime: 39464 * time.Millisecond,
 		Header: nmea.CanBusHeader{
 			PGN:         129025,
 			Priority:    2,
@@ -47,7 +49,9 @@ func TestEBLFormatDevice_Read(t *testing.T) {
 	}
 
 	secondPacket := nmea.RawMessage{
-		Time: now,
+		Time:       now,
+		BusTime:    now,
+		DeviceTime: 39474 * time.Millisecond,
 		Header: nmea.CanBusHeader{
 			PGN:         130843,
 			Priority:    7,
@@ -59,6 +63,25 @@ func TestEBLFormatDevice_Read(t *testing.T) {
 	assert.Equal(t, secondPacket, packet)
 }
 
+func TestEBLFormatDevice_ReadRawFrame(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	exampleData := test_test.LoadBytes(t, "actisense_w2k1_bst95.ebl")
+	device := NewEBLFormatDevice(bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(exampleData)), nil))
+	device.timeNow = func() time.Time {
+		return now
+	}
+
+	frame, err := device.ReadRawFrame(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawFrame{
+		Time:   now,
+		Header: nmea.CanBusHeader{PGN: 129025, Priority: 2, Source: 0, Destination: 255},
+		Length: 8,
+		Data:   [8]byte{0x3d, 0x0d, 0xb3, 0x22, 0x48, 0x32, 0x59, 0x0d},
+	}, frame)
+}
+
 func TestFromActisenseBST95Message(t *testing.T) {
 	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
 
@@ -72,7 +95,8 @@ func TestFromActisenseBST95Message(t *testing.T) {
 			name:    "ok",
 			whenRaw: []byte{0x0e, 0x28, 0x9a, 0x00, 0x01, 0xf8, 0x09, 0x3d, 0x0d, 0xb3, 0x22, 0x48, 0x32, 0x59, 0x0d},
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 39464 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					PGN:         129025,
 					Priority:    2,
@@ -105,7 +129,131 @@ func TestFromActisenseBST95Message(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := fromActisenseBST95Message(tc.whenRaw, now)
+			result, err := fromActisenseBST95Message(tc.whenRaw, now, nil)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEBLFormatDevice_ReadRawMessage_records(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	exampleData := test_test.LoadBytes(t, "actisense_w2k1_bst95.ebl")
+	var records []any
+	device := NewEBLFormatDeviceWithConfig(bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(exampleData)), nil), Config{
+		OnEBLRecord: func(record any) { records = append(records, record) },
+	})
+	device.timeNow = func() time.Time {
+		return now
+	}
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(129025), msg.Header.PGN)
+	assert.Equal(t, []any{
+		EBLStartTime{Time: time.Date(2023, time.May, 10, 21, 16, 16, 0, time.UTC)},
+		EBLFileHeader{Version: 1002},
+	}, records)
+}
+
+func TestEBLFormatDevice_ReadRawMessage_extendedRecords(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var stream []byte
+	stream = appendEBLFrame(stream, append([]byte{eblFrameTypeText}, "engine room logger"...))
+	stream = appendEBLFrame(stream, append([]byte{eblFrameTypeBST95, cmdDeviceMessageReceived, 14},
+		bemStartupStatus, 0x01, 0x45, 0x00, 0x40, 0xE2, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xA2, 0x08))
+	stream = appendEBLFrame(stream, []byte{eblFrameTypeBST95, cmdRAWActisenseMessageReceived, 0x0e, 0x28, 0x9a,
+		0x04, 0x00, 0x00, 0x20, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00})
+	stream = appendEBLFrame(stream, []byte{0x42, 0x01, 0x02})
+	stream = appendEBLFrame(stream, []byte{eblFrameTypeTime, 0x01}) // too short, skipped
+	stream = append(stream, ESC, SOH, ESC, NL)                      // empty, skipped
+	stream = appendEBLFrame(stream, []byte{eblFrameTypeBST95, cmdRAWActisenseMessageReceived,
+		0x0e, 0x28, 0x9a, 0x00, 0x01, 0xf8, 0x09, 0x3d, 0x0d, 0xb3, 0x22, 0x48, 0x32, 0x59, 0x0d})
+
+	var records []any
+	device := NewEBLFormatDeviceWithConfig(bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(stream)), nil), Config{
+		OnEBLRecord: func(record any) { records = append(records, record) },
+	})
+	device.timeNow = func() time.Time {
+		return now
+	}
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(129025), msg.Header.PGN)
+
+	header := BEMHeader{BEMID: bemStartupStatus, SequenceID: 1, ModelID: 69, SerialID: 123456}
+	assert.Equal(t, []any{
+		EBLText{Text: "engine room logger"},
+		EBLDeviceInfo{Message: StartupStatus{BEMHeader: header, FirmwareVersion: "2.210"}},
+		EBLErrorFrame{DeviceTime: 39464 * time.Millisecond, Flags: 0x04, Data: []byte{0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		EBLUnknownRecord{Type: 0x42, Data: []byte{0x01, 0x02}},
+	}, records)
+}
+
+func TestParseEBLRecord(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        []byte
+		expect      any
+		expectError string
+	}{
+		{
+			name:   "ok, file header",
+			when:   []byte{0x01, 0xea, 0x03, 0x00, 0x00},
+			expect: EBLFileHeader{Version: 1002},
+		},
+		{
+			name:   "ok, start time",
+			when:   []byte{0x03, 0x00, 0x10, 0xe7, 0xa7, 0x84, 0x83, 0xd9, 0x01},
+			expect: EBLStartTime{Time: time.Date(2023, time.May, 10, 21, 16, 16, 0, time.UTC)},
+		},
+		{
+			name:   "ok, text with trailing NULs",
+			when:   []byte{0x02, 'h', 'i', 0x00, 0x00},
+			expect: EBLText{Text: "hi"},
+		},
+		{
+			name:   "ok, unknown BST ID",
+			when:   []byte{0x07, 0x93, 0x01},
+			expect: EBLUnknownRecord{Type: 0x07, Data: []byte{0x93, 0x01}},
+		},
+		{
+			name:        "nok, empty",
+			when:        []byte{},
+			expectError: "EBL record is empty",
+		},
+		{
+			name:        "nok, file header too short",
+			when:        []byte{0x01, 0xea},
+			expectError: "EBL file header record too short",
+		},
+		{
+			name:        "nok, BST-95 without error flag",
+			when:        []byte{0x07, 0x95, 0x06, 0x28, 0x9a, 0x00, 0x01, 0xf8, 0x09},
+			expectError: "EBL BST-95 record is not error frame",
+		},
+		{
+			name:        "nok, device info length too long",
+			when:        []byte{0x07, 0xa0, 0x0e, 0xf0},
+			expectError: "EBL device info record length does not match actual length",
+		},
+		{
+			name:        "nok, device info too short for BEM header",
+			when:        []byte{0x07, 0xa0, 0x01, 0xf0},
+			expectError: "EBL device info record: actisense BEM response too short to contain header",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseEBLRecord(tc.when)
 
 			assert.Equal(t, tc.expect, result)
 			if tc.expectError != "" {
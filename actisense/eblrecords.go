@@ -0,0 +1,140 @@
+package actisense
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EBL record types other than BST-95 CAN messages. Record type is first byte of EBL frame. Frames with type
+// eblFrameTypeBST95 contain BST message where second byte is BST ID (i.e. 0x95 CAN-Raw message).
+const (
+	// eblFrameTypeHeader identifies EBL frame written to start of log file containing log format version
+	eblFrameTypeHeader = 0x01
+	// eblFrameTypeText identifies EBL frame containing text metadata (i.e. note added by user or logger)
+	eblFrameTypeText = 0x02
+
+	// bst95ErrorFlag is bit in BST-95 message CAN ID marking CAN error frame (same as SocketCAN CAN_ERR_FLAG)
+	bst95ErrorFlag = uint32(0x20000000)
+)
+
+// EBLFileHeader is EBL record written to start of the log file by logging device
+type EBLFileHeader struct {
+	// Version is log format version (i.e. 1002)
+	Version uint32 `json:"version"`
+}
+
+// EBLStartTime is EBL record containing time when logging was started. BST-95 message timestamps are milliseconds
+// since that time.
+type EBLStartTime struct {
+	Time time.Time `json:"time"`
+}
+
+// EBLText is EBL record containing text metadata
+type EBLText struct {
+	Text string `json:"text"`
+}
+
+// EBLDeviceInfo is EBL record containing device (BEM) message of logging device. Message is one of the types
+// returned by ParseBEMPayload (i.e. StartupStatus, HardwareInfo).
+type EBLDeviceInfo struct {
+	Message any `json:"message"`
+}
+
+// EBLErrorFrame is EBL record containing CAN error frame logged by device
+type EBLErrorFrame struct {
+	// DeviceTime is device timestamp (milliseconds counter) of error frame
+	DeviceTime time.Duration `json:"device_time"`
+	// Flags is CAN ID of error frame without error flag bit. Contains error class bits.
+	Flags uint32 `json:"flags"`
+	// Data is error frame payload with error details
+	Data []byte `json:"data"`
+}
+
+// EBLUnknownRecord is EBL record which layout is not known. Data contains bytes after record type.
+type EBLUnknownRecord struct {
+	Type uint8  `json:"type"`
+	Data []byte `json:"data"`
+}
+
+// ParseEBLRecord parses EBL record (frame contents between ESC+SOH and ESC+NL, unescaped) that is not BST-95 CAN
+// message into typed value. Returned value is one of EBLFileHeader, EBLStartTime, EBLText, EBLDeviceInfo,
+// EBLErrorFrame or EBLUnknownRecord. Records with unknown type or BST ID are returned as EBLUnknownRecord.
+//
+// Layouts of file header and start time records are known from W2K-1 log files. Text, device info and error frame
+// records are laid out as their BST counterparts in Actisense binary protocol.
+func ParseEBLRecord(record []byte) (any, error) {
+	if len(record) == 0 {
+		return nil, errors.New("EBL record is empty")
+	}
+	data := record[1:]
+	switch record[0] {
+	case eblFrameTypeHeader:
+		if len(data) < 4 {
+			return nil, errors.New("EBL file header record too short")
+		}
+		return EBLFileHeader{Version: binary.LittleEndian.Uint32(data)}, nil
+	case eblFrameTypeText:
+		return EBLText{Text: strings.TrimRight(string(data), "\x00")}, nil
+	case eblFrameTypeTime:
+		if len(data) < 8 {
+			return nil, errors.New("EBL start time record too short")
+		}
+		fileTime := int64(binary.LittleEndian.Uint64(data) - fileTimeEpochOffset)
+		return EBLStartTime{Time: time.Unix(0, fileTime*100).UTC()}, nil
+	case eblFrameTypeBST95:
+		if len(data) < 1 {
+			return nil, errors.New("EBL BST record too short")
+		}
+		switch data[0] {
+		case cmdRAWActisenseMessageReceived:
+			frame, err := parseEBLErrorFrame(data[1:])
+			if err != nil {
+				return nil, err
+			}
+			return frame, nil
+		case cmdDeviceMessageReceived:
+			info, err := parseEBLDeviceInfo(data[1:])
+			if err != nil {
+				return nil, err
+			}
+			return info, nil
+		}
+	}
+	return EBLUnknownRecord{Type: record[0], Data: append([]byte(nil), data...)}, nil
+}
+
+// isBST95ErrorFrame checks if BST-95 message (bytes after BST ID) has error flag set in CAN ID
+func isBST95ErrorFrame(raw []byte) bool {
+	return len(raw) >= 7 && binary.LittleEndian.Uint32(raw[3:7])&bst95ErrorFlag != 0
+}
+
+func parseEBLErrorFrame(raw []byte) (EBLErrorFrame, error) {
+	// length(1) + timestamp(2) + canid(4)
+	if len(raw) < 7 || int(raw[0]) != len(raw)-1 {
+		return EBLErrorFrame{}, errors.New("EBL error frame record length does not match actual length")
+	}
+	canID := binary.LittleEndian.Uint32(raw[3:7])
+	if canID&bst95ErrorFlag == 0 {
+		return EBLErrorFrame{}, errors.New("EBL BST-95 record is not error frame")
+	}
+	return EBLErrorFrame{
+		DeviceTime: time.Duration(binary.LittleEndian.Uint16(raw[1:3])) * time.Millisecond,
+		Flags:      canID &^ bst95ErrorFlag,
+		Data:       append([]byte(nil), raw[7:]...),
+	}, nil
+}
+
+func parseEBLDeviceInfo(raw []byte) (EBLDeviceInfo, error) {
+	// length(1) + BEM payload
+	if len(raw) < 1 || int(raw[0]) > len(raw)-1 {
+		return EBLDeviceInfo{}, errors.New("EBL device info record length does not match actual length")
+	}
+	msg, err := ParseBEMPayload(raw[1 : 1+int(raw[0])])
+	if err != nil {
+		return EBLDeviceInfo{}, fmt.Errorf("EBL device info record: %w", err)
+	}
+	return EBLDeviceInfo{Message: msg}, nil
+}
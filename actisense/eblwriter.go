@@ -0,0 +1,107 @@
+package actisense
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// eblFrameTypeTime identifies EBL frame containing (start) time of log file as Windows FILETIME
+	eblFrameTypeTime = 0x03
+	// eblFrameTypeBST95 identifies EBL frame containing BST-95 (CAN-Raw) message
+	eblFrameTypeBST95 = 0x07
+
+	// bst95MaxDataLength is maximum data length that fits into BST-95 message. Length field (1 byte) includes
+	// timestamp (2) and CAN ID (4) bytes.
+	bst95MaxDataLength = 255 - 6
+
+	// fileTimeEpochOffset is number of 100ns intervals between Windows FILETIME epoch (1601-01-01) and unix epoch
+	fileTimeEpochOffset = 116444736000000000
+)
+
+// ErrEBLMessageTooLong is returned when message does not fit into single BST-95 message
+var ErrEBLMessageTooLong = errors.New("message data is too long for EBL BST-95 message")
+
+// EBLWriter writes messages to Actisense EBL log file format (BST-95 messages as created by W2K-1 device) so
+// recordings can be opened with Actisense EBL Reader software or read back with EBLFormatDevice.
+//
+// Time frame with time of first written message is written to start of the log and BST-95 message timestamps are
+// milliseconds since that time (wrapping around at 65536ms).
+//
+// Use nmea.Tee to record messages read from any nmea.RawMessageReader.
+type EBLWriter struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	timeNow func() time.Time
+
+	startTime time.Time
+}
+
+// NewEBLWriter creates new instance of EBLWriter
+func NewEBLWriter(writer io.Writer) *EBLWriter {
+	return &EBLWriter{
+		writer:  writer,
+		timeNow: time.Now,
+	}
+}
+
+// WriteRawMessage writes message as BST-95 EBL frame. Messages without time are written with current time.
+func (w *EBLWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	if len(msg.Data) > bst95MaxDataLength {
+		return ErrEBLMessageTooLong
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	t := msg.Time
+	if t.IsZero() {
+		t = w.timeNow()
+	}
+	b := make([]byte, 0, 2*(2+2+1+6+len(msg.Data)+2)+2*(2+1+8+2))
+	if w.startTime.IsZero() {
+		w.startTime = t
+		b = appendEBLFrame(b, appendFileTime([]byte{eblFrameTypeTime}, t))
+	}
+
+	timestamp := uint16(t.Sub(w.startTime).Milliseconds()) // wraps around same way as device counter does
+	payload := make([]byte, 0, 2+7+len(msg.Data))
+	payload = append(payload, eblFrameTypeBST95, cmdRAWActisenseMessageReceived, byte(6+len(msg.Data)))
+	payload = binary.LittleEndian.AppendUint16(payload, timestamp)
+	payload = binary.LittleEndian.AppendUint32(payload, msg.Header.Uint32())
+	payload = append(payload, msg.Data...)
+	b = appendEBLFrame(b, payload)
+
+	_, err := w.writer.Write(b)
+	return err
+}
+
+// Close closes underlying writer if it implements io.Closer
+func (w *EBLWriter) Close() error {
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// appendEBLFrame appends payload as EBL frame (ESC+SOH payload ESC+NL). ESC bytes in payload are escaped as ESC+ESC.
+func appendEBLFrame(b []byte, payload []byte) []byte {
+	b = append(b, ESC, SOH)
+	for _, c := range payload {
+		if c == ESC {
+			b = append(b, ESC)
+		}
+		b = append(b, c)
+	}
+	return append(b, ESC, NL)
+}
+
+// appendFileTime appends time as 8 byte little endian Windows FILETIME (100ns intervals since 1601-01-01)
+func appendFileTime(b []byte, t time.Time) []byte {
+	fileTime := uint64(t.UnixNano()/100) + fileTimeEpochOffset
+	return binary.LittleEndian.AppendUint64(b, fileTime)
+}
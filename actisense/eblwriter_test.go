@@ -0,0 +1,113 @@
+package actisense
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEBLWriter_WriteRawMessage(t *testing.T) {
+	start := time.Date(2023, 5, 10, 21, 16, 16, 0, time.UTC)
+
+	buf := bytes.NewBuffer(nil)
+	w := NewEBLWriter(buf)
+
+	err := w.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Time:   start,
+		Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0xfe, Destination: 0xff},
+		Data:   nmea.RawData{0x00, 0xee, 0x00},
+	})
+	assert.NoError(t, err)
+
+	err = w.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Time:   start.Add(1500 * time.Millisecond),
+		Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0x1b, Destination: 0xff},
+		Data:   nmea.RawData{0x1b, 0xee, 0x00},
+	})
+	assert.NoError(t, err)
+
+	expect := []byte{
+		0x1b, 0x01, 0x03, 0x00, 0x10, 0xe7, 0xa7, 0x84, 0x83, 0xd9, 0x01, 0x1b, 0x0a, // time frame
+		0x1b, 0x01, 0x07, 0x95, 0x09, 0x00, 0x00, 0xfe, 0xff, 0xea, 0x18, 0x00, 0xee, 0x00, 0x1b, 0x0a,
+		// timestamp 1500ms (0x05dc), ESC bytes in source and data are escaped
+		0x1b, 0x01, 0x07, 0x95, 0x09, 0xdc, 0x05, 0x1b, 0x1b, 0xff, 0xea, 0x18, 0x1b, 0x1b, 0xee, 0x00, 0x1b, 0x0a,
+	}
+	assert.Equal(t, expect, buf.Bytes())
+}
+
+func TestEBLWriter_WriteRawMessage_timestampWrapsAround(t *testing.T) {
+	start := time.Date(2023, 5, 10, 21, 16, 16, 0, time.UTC)
+
+	buf := bytes.NewBuffer(nil)
+	w := NewEBLWriter(buf)
+
+	assert.NoError(t, w.WriteRawMessage(context.Background(), nmea.RawMessage{Time: start, Data: nmea.RawData{0x01}}))
+	buf.Reset()
+
+	msg := nmea.RawMessage{Time: start.Add(65536*time.Millisecond + 2*time.Millisecond), Data: nmea.RawData{0x01}}
+	assert.NoError(t, w.WriteRawMessage(context.Background(), msg))
+	assert.Equal(t, []byte{0x02, 0x00}, buf.Bytes()[5:7])
+}
+
+func TestEBLWriter_WriteRawMessage_noTime(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	buf := bytes.NewBuffer(nil)
+	w := NewEBLWriter(buf)
+	w.timeNow = func() time.Time {
+		return now
+	}
+
+	assert.NoError(t, w.WriteRawMessage(context.Background(), nmea.RawMessage{Data: nmea.RawData{0x01}}))
+	assert.Equal(t, now, w.startTime)
+}
+
+func TestEBLWriter_WriteRawMessage_tooLong(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewEBLWriter(buf)
+
+	err := w.WriteRawMessage(context.Background(), nmea.RawMessage{Data: make(nmea.RawData, 250)})
+	assert.ErrorIs(t, err, ErrEBLMessageTooLong)
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestEBLWriter_roundTrip(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	buf := bytes.NewBuffer(nil)
+	w := NewEBLWriter(buf)
+
+	messages := []nmea.RawMessage{
+		{
+			Time:   now,
+			Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0xfe, Destination: 0xff},
+			Data:   nmea.RawData{0x00, 0xee, 0x00},
+		},
+		{
+			Time:   now.Add(10 * time.Millisecond),
+			Header: nmea.CanBusHeader{PGN: 126208, Priority: 3, Source: 0x1b, Destination: 0x23},
+			Data:   nmea.RawData{0x02, 0x1b, 0xff, 0x00, 0x1b, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07},
+		},
+	}
+	for _, m := range messages {
+		assert.NoError(t, w.WriteRawMessage(context.Background(), m))
+	}
+
+	device := NewEBLFormatDevice(bufio.NewReadWriter(bufio.NewReader(buf), nil))
+	device.timeNow = func() time.Time {
+		return now
+	}
+	for _, expect := range messages {
+		msg, err := device.ReadRawMessage(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expect.Header, msg.Header)
+		assert.Equal(t, expect.Data, msg.Data)
+	}
+}
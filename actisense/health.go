@@ -0,0 +1,275 @@
+package actisense
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"sync"
+	"time"
+)
+
+// bemSystemStatus is BEM command ID of status message that device sends periodically. Contains channel load and
+// dropped message counters.
+const bemSystemStatus = 0xF2
+
+// pgnDCVoltageCurrent is PGN 127751 DC Voltage/Current that HealthMonitor reads bus supply voltage from
+const pgnDCVoltageCurrent = uint32(127751)
+
+const (
+	// AlertSupplyVoltageLow is alert code for bus supply voltage below threshold
+	AlertSupplyVoltageLow = "supply_voltage_low"
+	// AlertBusLoadHigh is alert code for channel receive or transmit load above threshold
+	AlertBusLoadHigh = "bus_load_high"
+	// AlertMessagesDropped is alert code for gateway dropping received messages
+	AlertMessagesDropped = "messages_dropped"
+	// AlertGatewayError is alert code for gateway reporting non-zero error code
+	AlertGatewayError = "gateway_error"
+)
+
+// ChannelStatus is load and error statistics of single gateway channel
+type ChannelStatus struct {
+	RxBandwidth uint8 `json:"rx_bandwidth"`
+	// RxLoad is receive buffer load in percents
+	RxLoad     uint8 `json:"rx_load"`
+	RxFiltered uint8 `json:"rx_filtered"`
+	// RxDropped is number of dropped received messages since last status
+	RxDropped   uint8 `json:"rx_dropped"`
+	TxBandwidth uint8 `json:"tx_bandwidth"`
+	// TxLoad is transmit buffer load in percents
+	TxLoad uint8 `json:"tx_load"`
+}
+
+// GatewayHealth is health information of gateway device (NGT-1, W2K-1) itself
+type GatewayHealth struct {
+	// UpdatedAt is time when health was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+	// ErrorCode is last error code gateway reported. 0 means no error.
+	ErrorCode uint32          `json:"error_code"`
+	Channels  []ChannelStatus `json:"channels"`
+	// SupplyVoltage is bus supply voltage in volts. Value 0 means that voltage is unknown. Actisense devices do not report
+	// voltage in system status, so HealthMonitor takes it from PGN 127751 DC Voltage/Current messages seen on bus (sent
+	// by device connected to same bus power) or it can be set with HealthMonitor.SetSupplyVoltage.
+	SupplyVoltage float64 `json:"supply_voltage,omitempty"`
+}
+
+// ParseSystemStatus parses gateway health from system status BEM response payload (bytes after command and length
+// byte). Supply voltage is copied from previous health.
+//
+// Layout is reverse engineered by Canboat project (`Actisense: System status` fake PGN 0x400F2): common BEM header is
+// followed by count of individual channels and 6 status bytes for each of them.
+func ParseSystemStatus(payload []byte, previous GatewayHealth, now time.Time) (GatewayHealth, error) {
+	if len(payload) < bemHeaderLength+1 || payload[0] != bemSystemStatus {
+		return previous, errors.New("actisense BEM response is not valid system status")
+	}
+	info, err := ParseDeviceInfo(payload, DeviceInfo{}, now)
+	if err != nil {
+		return previous, err
+	}
+	channelCount := int(payload[bemHeaderLength])
+	const channelLength = 6
+	data := payload[bemHeaderLength+1:]
+	if len(data) < channelCount*channelLength {
+		return previous, fmt.Errorf("actisense system status too short for %v channels", channelCount)
+	}
+
+	health := GatewayHealth{
+		UpdatedAt:     now,
+		ErrorCode:     info.ErrorCode,
+		Channels:      make([]ChannelStatus, 0, channelCount),
+		SupplyVoltage: previous.SupplyVoltage,
+	}
+	for i := 0; i < channelCount; i++ {
+		c := data[i*channelLength : (i+1)*channelLength]
+		health.Channels = append(health.Channels, ChannelStatus{
+			RxBandwidth: c[0],
+			RxLoad:      c[1],
+			RxFiltered:  c[2],
+			RxDropped:   c[3],
+			TxBandwidth: c[4],
+			TxLoad:      c[5],
+		})
+	}
+	return health, nil
+}
+
+// GatewayHealth returns latest health information that device has reported. Information is collected from system
+// status BEM messages seen by ReadRawMessage. Returns false when device has not sent system status yet.
+func (d *BinaryFormatDevice) GatewayHealth() (GatewayHealth, bool) {
+	d.infoLock.Lock()
+	defer d.infoLock.Unlock()
+
+	return d.health, !d.health.UpdatedAt.IsZero()
+}
+
+// HealthThresholds are limits after which HealthMonitor raises alerts
+type HealthThresholds struct {
+	// MinSupplyVoltage is voltage below which bus power is considered marginal.
+	// Defaults to: 10.5 volts
+	MinSupplyVoltage float64
+	// MaxLoadPercent is channel receive or transmit load (percents) above which alert is raised.
+	// Defaults to: 80
+	MaxLoadPercent uint8
+	// MaxDropped is number of dropped messages (in single status) above which alert is raised.
+	// Defaults to: 0 (any dropped message raises alert)
+	MaxDropped uint8
+}
+
+// HealthAlert is raised when health value crosses threshold and cleared when value returns within limits
+type HealthAlert struct {
+	Code    string    `json:"code"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	// Active is true when alert is raised and false when alert is cleared
+	Active bool `json:"active"`
+}
+
+// HealthMonitor checks gateway health against thresholds and reports alert state changes. Health is updated from
+// BinaryFormatDevice system status (see Config.HealthMonitor) and from messages given to HandleRaw. HealthMonitor
+// implements pipeline.Handler so it can be added as pipeline stage.
+//
+// HealthMonitor is safe for concurrent use.
+type HealthMonitor struct {
+	thresholds HealthThresholds
+	onAlert    func(alert HealthAlert)
+
+	lock    sync.Mutex
+	health  GatewayHealth
+	active  map[string]bool
+	voltage float64
+}
+
+// NewHealthMonitor creates new instance of HealthMonitor. onAlert is called (synchronously) when alert is raised or
+// cleared.
+func NewHealthMonitor(thresholds HealthThresholds, onAlert func(alert HealthAlert)) *HealthMonitor {
+	if thresholds.MinSupplyVoltage <= 0 {
+		thresholds.MinSupplyVoltage = 10.5
+	}
+	if thresholds.MaxLoadPercent == 0 {
+		thresholds.MaxLoadPercent = 80
+	}
+	return &HealthMonitor{
+		thresholds: thresholds,
+		onAlert:    onAlert,
+		active:     map[string]bool{},
+	}
+}
+
+// SetSupplyVoltage sets bus supply voltage measured by other means and checks it against threshold.
+func (m *HealthMonitor) SetSupplyVoltage(voltage float64, now time.Time) {
+	m.lock.Lock()
+	m.voltage = voltage
+	health := m.health
+	m.lock.Unlock()
+
+	health.SupplyVoltage = voltage
+	if health.UpdatedAt.IsZero() {
+		health.UpdatedAt = now
+	}
+	m.Update(health)
+}
+
+// HandleRaw updates health from raw message. Supply voltage is taken from PGN 127751 DC Voltage/Current messages
+// and gateway health from Actisense system status messages (output by BinaryFormatDevice when
+// Config.OutputActisenseMessages is set or replayed from capture). Message is always passed on.
+func (m *HealthMonitor) HandleRaw(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+	switch {
+	case raw.Header.PGN == pgnDCVoltageCurrent:
+		if voltage, ok := parseDCVoltage(raw.Data); ok {
+			m.SetSupplyVoltage(voltage, raw.Time)
+		}
+	case IsBEMMessage(raw) && len(raw.Data) > 0 && raw.Data[0] == bemSystemStatus:
+		if health, err := ParseSystemStatus(raw.Data, m.Health(), raw.Time); err == nil {
+			m.Update(health)
+		}
+	}
+	return true, nil
+}
+
+// HandleDecoded passes message through
+func (m *HealthMonitor) HandleDecoded(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+	return true, nil
+}
+
+// parseDCVoltage parses voltage from PGN 127751 DC Voltage/Current data. Layout is: SID (1 byte), connection number
+// (1 byte), DC voltage (uint16, 0.1V resolution), DC current (int24, 0.01A resolution), reserved (1 byte).
+func parseDCVoltage(data []byte) (float64, bool) {
+	if len(data) < 4 {
+		return 0, false
+	}
+	v := binary.LittleEndian.Uint16(data[2:4])
+	if v >= 0xFFFD { // no data, out of range, reserved
+		return 0, false
+	}
+	return float64(v) * 0.1, true
+}
+
+// Update checks given health against thresholds and reports alerts that changed state.
+func (m *HealthMonitor) Update(health GatewayHealth) {
+	m.lock.Lock()
+	if health.SupplyVoltage == 0 {
+		health.SupplyVoltage = m.voltage
+	}
+	m.health = health
+
+	alerts := make([]HealthAlert, 0)
+	check := func(code string, isActive bool, message string) {
+		if m.active[code] == isActive {
+			return
+		}
+		m.active[code] = isActive
+		alerts = append(alerts, HealthAlert{Code: code, Message: message, Time: health.UpdatedAt, Active: isActive})
+	}
+
+	if health.SupplyVoltage > 0 {
+		check(
+			AlertSupplyVoltageLow,
+			health.SupplyVoltage < m.thresholds.MinSupplyVoltage,
+			fmt.Sprintf("supply voltage %.2fV (threshold %.2fV)", health.SupplyVoltage, m.thresholds.MinSupplyVoltage),
+		)
+	}
+	maxLoad := uint8(0)
+	maxDropped := uint8(0)
+	for _, c := range health.Channels {
+		if c.RxLoad > maxLoad {
+			maxLoad = c.RxLoad
+		}
+		if c.TxLoad > maxLoad {
+			maxLoad = c.TxLoad
+		}
+		if c.RxDropped > maxDropped {
+			maxDropped = c.RxDropped
+		}
+	}
+	check(
+		AlertBusLoadHigh,
+		maxLoad > m.thresholds.MaxLoadPercent,
+		fmt.Sprintf("channel load %v%% (threshold %v%%)", maxLoad, m.thresholds.MaxLoadPercent),
+	)
+	check(
+		AlertMessagesDropped,
+		maxDropped > m.thresholds.MaxDropped,
+		fmt.Sprintf("dropped messages %v (threshold %v)", maxDropped, m.thresholds.MaxDropped),
+	)
+	check(
+		AlertGatewayError,
+		health.ErrorCode != 0,
+		fmt.Sprintf("gateway error code %v", health.ErrorCode),
+	)
+	m.lock.Unlock()
+
+	if m.onAlert != nil {
+		for _, a := range alerts {
+			m.onAlert(a)
+		}
+	}
+}
+
+// Health returns health that was last checked
+func (m *HealthMonitor) Health() GatewayHealth {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.health
+}
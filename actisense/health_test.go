@@ -0,0 +1,247 @@
+package actisense
+
+import (
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+var exampleSystemStatusPayload = []byte{
+	0xF2,       // BEM ID, system status
+	0x01,       // SID
+	0x45, 0x00, // model ID
+	0x40, 0xE2, 0x01, 0x00, // serial ID
+	0x00, 0x00, 0x00, 0x00, // error code
+	0x02,                               // individual channel count
+	0x64, 0x05, 0x00, 0x00, 0x64, 0x01, // channel 1: rx bandwidth, rx load, rx filtered, rx dropped, tx bandwidth, tx load
+	0x64, 0x55, 0x00, 0x03, 0x64, 0x00, // channel 2
+}
+
+func TestParseSystemStatus(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+
+	var testCases = []struct {
+		name        string
+		givenPrev   GatewayHealth
+		whenPayload []byte
+		expect      GatewayHealth
+		expectError string
+	}{
+		{
+			name:        "ok",
+			givenPrev:   GatewayHealth{SupplyVoltage: 12.5},
+			whenPayload: exampleSystemStatusPayload,
+			expect: GatewayHealth{
+				UpdatedAt: now,
+				Channels: []ChannelStatus{
+					{RxBandwidth: 100, RxLoad: 5, TxBandwidth: 100, TxLoad: 1},
+					{RxBandwidth: 100, RxLoad: 85, RxDropped: 3, TxBandwidth: 100},
+				},
+				SupplyVoltage: 12.5,
+			},
+		},
+		{
+			name:        "nok, not system status",
+			whenPayload: []byte{0xF0, 0x01, 0x45, 0x00, 0x40, 0xE2, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			expect:      GatewayHealth{},
+			expectError: "actisense BEM response is not valid system status",
+		},
+		{
+			name:        "nok, too short for channels",
+			whenPayload: exampleSystemStatusPayload[0:20],
+			expect:      GatewayHealth{},
+			expectError: "actisense system status too short for 2 channels",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseSystemStatus(tc.whenPayload, tc.givenPrev, now)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBinaryFormatDevice_GatewayHealth(t *testing.T) {
+	input := []byte{DLE, STX, cmdDeviceMessageReceived, byte(len(exampleSystemStatusPayload))}
+	input = append(input, exampleSystemStatusPayload...)
+	input = append(input, 0x00, DLE, ETX) // crc + end
+
+	device := NewBinaryDeviceWithConfig(&readWriteBuffer{Reader: bytes.NewReader(input)}, Config{})
+	clock := time.Unix(1665488842, 0).UTC()
+	device.timeNow = func() time.Time {
+		clock = clock.Add(1 * time.Millisecond)
+		return clock
+	}
+
+	_, ok := device.GatewayHealth()
+	assert.False(t, ok)
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+
+	health, ok := device.GatewayHealth()
+	assert.True(t, ok)
+	assert.Len(t, health.Channels, 2)
+
+	info, ok := device.DeviceInfo()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(123456), info.SerialID)
+}
+
+func TestBinaryFormatDevice_HealthMonitor(t *testing.T) {
+	input := []byte{DLE, STX, cmdDeviceMessageReceived, byte(len(exampleSystemStatusPayload))}
+	input = append(input, exampleSystemStatusPayload...)
+	input = append(input, 0x00, DLE, ETX) // crc + end
+
+	var alerts []HealthAlert
+	monitor := NewHealthMonitor(HealthThresholds{}, func(alert HealthAlert) {
+		alerts = append(alerts, alert)
+	})
+	device := NewBinaryDeviceWithConfig(&readWriteBuffer{Reader: bytes.NewReader(input)}, Config{HealthMonitor: monitor})
+	clock := time.Unix(1665488842, 0).UTC()
+	device.timeNow = func() time.Time {
+		clock = clock.Add(1 * time.Millisecond)
+		return clock
+	}
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Len(t, monitor.Health().Channels, 2)
+	if assert.Len(t, alerts, 2) {
+		assert.Equal(t, AlertBusLoadHigh, alerts[0].Code)
+		assert.Equal(t, AlertMessagesDropped, alerts[1].Code)
+	}
+}
+
+func TestHealthMonitor_HandleRaw(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+
+	var testCases = []struct {
+		name         string
+		when         nmea.RawMessage
+		expectHealth GatewayHealth
+		expectAlerts []HealthAlert
+	}{
+		{
+			name: "ok, supply voltage from PGN 127751",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 127751, Source: 35},
+				Data:   []byte{0x00, 0x01, 0x66, 0x00, 0xe8, 0x03, 0x00, 0xff}, // sid, connection, 10.2V, 10A, reserved
+			},
+			expectHealth: GatewayHealth{UpdatedAt: now, SupplyVoltage: 10.200000000000001},
+			expectAlerts: []HealthAlert{
+				{Code: AlertSupplyVoltageLow, Message: "supply voltage 10.20V (threshold 10.50V)", Time: now, Active: true},
+			},
+		},
+		{
+			name: "ok, PGN 127751 voltage with no data is ignored",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 127751, Source: 35},
+				Data:   []byte{0x00, 0x01, 0xff, 0xff, 0xe8, 0x03, 0x00, 0xff},
+			},
+			expectHealth: GatewayHealth{},
+		},
+		{
+			name: "ok, system status BEM message",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: CanBoatFakePGNOffset + bemSystemStatus},
+				Data:   exampleSystemStatusPayload,
+			},
+			expectHealth: GatewayHealth{
+				UpdatedAt: now,
+				Channels: []ChannelStatus{
+					{RxBandwidth: 100, RxLoad: 5, TxBandwidth: 100, TxLoad: 1},
+					{RxBandwidth: 100, RxLoad: 85, RxDropped: 3, TxBandwidth: 100},
+				},
+			},
+			expectAlerts: []HealthAlert{
+				{Code: AlertBusLoadHigh, Message: "channel load 85% (threshold 80%)", Time: now, Active: true},
+				{Code: AlertMessagesDropped, Message: "dropped messages 3 (threshold 0)", Time: now, Active: true},
+			},
+		},
+		{
+			name: "ok, other messages are ignored",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 35},
+				Data:   []byte{0x00, 0x01, 0x66, 0x00, 0xe8, 0x03, 0x00, 0xff},
+			},
+			expectHealth: GatewayHealth{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var alerts []HealthAlert
+			monitor := NewHealthMonitor(HealthThresholds{}, func(alert HealthAlert) {
+				alerts = append(alerts, alert)
+			})
+
+			ok, err := monitor.HandleRaw(context.Background(), tc.when)
+
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectHealth, monitor.Health())
+			assert.Equal(t, tc.expectAlerts, alerts)
+		})
+	}
+}
+
+func TestHealthMonitor_Update(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+	var alerts []HealthAlert
+	monitor := NewHealthMonitor(HealthThresholds{}, func(alert HealthAlert) {
+		alerts = append(alerts, alert)
+	})
+
+	monitor.Update(GatewayHealth{UpdatedAt: now, Channels: []ChannelStatus{{RxLoad: 10}}})
+	assert.Empty(t, alerts)
+
+	monitor.Update(GatewayHealth{UpdatedAt: now.Add(1 * time.Second), Channels: []ChannelStatus{{RxLoad: 90, RxDropped: 2}}})
+	assert.Equal(t, []HealthAlert{
+		{Code: AlertBusLoadHigh, Message: "channel load 90% (threshold 80%)", Time: now.Add(1 * time.Second), Active: true},
+		{Code: AlertMessagesDropped, Message: "dropped messages 2 (threshold 0)", Time: now.Add(1 * time.Second), Active: true},
+	}, alerts)
+
+	alerts = nil
+	monitor.Update(GatewayHealth{UpdatedAt: now.Add(2 * time.Second), Channels: []ChannelStatus{{RxLoad: 95, RxDropped: 1}}})
+	assert.Empty(t, alerts) // already active alerts are not repeated
+
+	monitor.Update(GatewayHealth{UpdatedAt: now.Add(3 * time.Second), Channels: []ChannelStatus{{RxLoad: 20}}})
+	assert.Equal(t, []HealthAlert{
+		{Code: AlertBusLoadHigh, Message: "channel load 20% (threshold 80%)", Time: now.Add(3 * time.Second), Active: false},
+		{Code: AlertMessagesDropped, Message: "dropped messages 0 (threshold 0)", Time: now.Add(3 * time.Second), Active: false},
+	}, alerts)
+}
+
+func TestHealthMonitor_SetSupplyVoltage(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+	var alerts []HealthAlert
+	monitor := NewHealthMonitor(HealthThresholds{MinSupplyVoltage: 11}, func(alert HealthAlert) {
+		alerts = append(alerts, alert)
+	})
+
+	monitor.SetSupplyVoltage(10.2, now)
+	assert.Equal(t, []HealthAlert{
+		{Code: AlertSupplyVoltageLow, Message: "supply voltage 10.20V (threshold 11.00V)", Time: now, Active: true},
+	}, alerts)
+
+	// voltage is kept when health is updated from gateway status
+	monitor.Update(GatewayHealth{UpdatedAt: now.Add(1 * time.Second), ErrorCode: 5})
+	assert.Equal(t, 10.2, monitor.Health().SupplyVoltage)
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, AlertGatewayError, alerts[1].Code)
+}
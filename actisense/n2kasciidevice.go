@@ -8,7 +8,7 @@ import (
 	"fmt"
 	"github.com/aldas/go-nmea-client"
 	"io"
-	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,35 +17,69 @@ import (
 //
 // Note: is not go-routine safe
 type N2kASCIIDevice struct {
-	device  io.ReadWriter
+	device io.ReadWriter
+	// reader makes reads from device interruptible by context cancellation and Close
+	reader  *contextReader
 	timeNow func() time.Time
 
 	readBuffer []byte
 	readIndex  int
+	// chunk is reusable buffer for device reads
+	chunk []byte
+	// data is reusable buffer for decoded message data
+	data []byte
 
 	config Config
+
+	// busClock converts message time of day to bus times
+	busClock *nmea.DeviceClock
+
+	closed atomic.Bool
 }
 
 // NewN2kASCIIDevice creates new instance of Actisense W2K-1 device capable of decoding NMEA 2000 Ascii format
 func NewN2kASCIIDevice(reader io.ReadWriter, config Config) *N2kASCIIDevice {
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
 	return &N2kASCIIDevice{
 		device:     reader,
+		reader:     newContextReader(reader),
 		timeNow:    time.Now,
 		readBuffer: make([]byte, nmea.ISOTPDataMaxSize*2),
+		chunk:      make([]byte, nmea.FastRawPacketMaxSize+100),
+		data:       make([]byte, nmea.ISOTPDataMaxSize),
 
-		config: config,
+		config:   config,
+		busClock: nmea.NewDeviceClock(24 * time.Hour),
 	}
 }
 
+// Close closes device. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
 func (d *N2kASCIIDevice) Close() error {
+	d.closed.Store(true)
+	d.reader.Close()
 	if c, ok := d.device.(io.Closer); ok {
 		return c.Close()
 	}
 	return errors.New("device does not implement Closer interface")
 }
 
+// WriteRawMessage writes message to device as single N2K ASCII sentence. Device (W2K-1) splits messages longer than
+// 8 bytes into fast-packet or ISO TP frames itself so messages up to nmea.ISOTPDataMaxSize bytes can be written.
+// Messages without time are written with current time.
 func (d *N2kASCIIDevice) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
+	if len(msg.Data) == 0 || len(msg.Data) > nmea.ISOTPDataMaxSize {
+		return fmt.Errorf("N2K Ascii message data length must be 1-%v bytes, got %v", nmea.ISOTPDataMaxSize, len(msg.Data))
+	}
+	if msg.Time.IsZero() {
+		msg.Time = d.timeNow()
+	}
 	b := formatN2KASCII(msg)
+	if d.config.DebugLogRawMessageBytes {
+		d.config.Logger.Debug("writing Actisense N2K ASCII message", "line", string(bytes.TrimSpace(b)))
+	}
 	_, err := d.device.Write(b)
 	return err
 }
@@ -54,9 +88,31 @@ func (d *N2kASCIIDevice) Initialize() error {
 	return nil
 }
 
+// ReadRawMessage reads and parses next N2K ASCII sentence from device.
 func (d *N2kASCIIDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	msg := nmea.RawMessage{}
+	if err := d.ReadRawMessageInto(ctx, &msg); err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return msg, nil
+}
+
+// ReadRawMessageInto reads and parses next N2K ASCII sentence into given message. Message Data is reused when it has
+// enough capacity.
+func (d *N2kASCIIDevice) ReadRawMessageInto(ctx context.Context, to *nmea.RawMessage) error {
+	msg, err := d.readRawMessage(ctx)
+	if err != nil {
+		return err
+	}
+	setRawMessage(to, msg, d.config.DataPool)
+	return nil
+}
+
+// readRawMessage reads next message from device. Returned message Data references device internal buffer and is
+// valid only until next read.
+func (d *N2kASCIIDevice) readRawMessage(ctx context.Context) (nmea.RawMessage, error) {
 	// Example: 'A173321.107 23FF7 1F513 012F3070002F30709F  \n'
-	buf := make([]byte, nmea.FastRawPacketMaxSize+100)
+	buf := d.chunk
 
 	for {
 		select {
@@ -64,10 +120,16 @@ func (d *N2kASCIIDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, e
 			return nmea.RawMessage{}, ctx.Err()
 		default:
 		}
+		if d.closed.Load() {
+			return nmea.RawMessage{}, nmea.ErrDeviceClosed
+		}
 
-		n, err := d.device.Read(buf) // FIXME: read is blocking call. we need to set read timeouts to work with context cancellations
+		n, err := d.reader.Read(ctx, buf)
 
 		if err != nil {
+			if d.closed.Load() {
+				return nmea.RawMessage{}, nmea.ErrDeviceClosed
+			}
 			return nmea.RawMessage{}, err
 		}
 		if n == 0 {
@@ -87,11 +149,11 @@ func (d *N2kASCIIDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, e
 		d.readIndex += messageEndIndex
 
 		message := d.readBuffer[0:d.readIndex]
-		if d.config.DebugLogRawMessageBytes && d.config.LogFunc != nil {
-			d.config.LogFunc("# DEBUG Actisense N2K ASCII message: %x\n", message)
+		if d.config.DebugLogRawMessageBytes {
+			d.config.Logger.Debug("read Actisense N2K ASCII message", "bytes", message)
 		}
 		now := d.timeNow()
-		rawMessage, skip, err := parseN2KAscii(message, now)
+		rawMessage, skip, err := parseN2KAscii(message, now, d.busClock, d.data)
 
 		// reset read buffer to whatever we were able to read past current message end. probably nothing but could be
 		// start of next message etc
@@ -106,18 +168,20 @@ func (d *N2kASCIIDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, e
 	}
 }
 
+// formatN2KASCII formats message as N2K ASCII sentence `Ahhmmss.ddd <SS><DD><P> <PPPPP> b0b1...bn<CR>`. Format has
+// no checksum, CAN ID is sent as separate source, destination, priority and PGN fields and device composes CAN ID
+// from them.
 func formatN2KASCII(msg nmea.RawMessage) []byte {
 	// Example:
 	// cansend can0 18EAFFFE#00EE00
 	// echo -e "A173321.107 FEFF6 0EA00 00EE00\r" > /dev/tcp/192.168.1.194/60003
 
 	buf := new(bytes.Buffer)
+	buf.Grow(20 + 2*len(msg.Data))
 	buf.WriteString(msg.Time.Format("A150405.000 "))
 
-	buf.WriteString(strconv.FormatUint(uint64(msg.Header.Source), 16))
-	buf.WriteString(strconv.FormatUint(uint64(msg.Header.Destination), 16))
-	buf.WriteString(strconv.FormatUint(uint64(msg.Header.Priority), 10))
-	buf.WriteString(fmt.Sprintf(" %05x ", msg.Header.PGN))
+	buf.WriteString(fmt.Sprintf("%02x%02x%d", msg.Header.Source, msg.Header.Destination, msg.Header.Priority&0x7))
+	buf.WriteString(fmt.Sprintf(" %05x ", msg.Header.PGN&0x3FFFF))
 	enc := hex.NewEncoder(buf)
 	enc.Write(msg.Data)
 
@@ -126,7 +190,18 @@ func formatN2KASCII(msg nmea.RawMessage) []byte {
 	return buf.Bytes()
 }
 
-func parseN2KAscii(raw []byte, now time.Time) (nmea.RawMessage, bool, error) {
+// ParseN2KASCII parses single N2K ASCII sentence (without line ending). Sentence time of day is converted to bus time
+// with given clock. Returns true for sentences that should be skipped.
+func ParseN2KASCII(raw []byte, now time.Time, clock *nmea.DeviceClock) (nmea.RawMessage, bool, error) {
+	if len(raw) == 0 {
+		return nmea.RawMessage{}, true, errors.New("N2K Ascii message is empty")
+	}
+	return parseN2KAscii(raw, now, clock, nil)
+}
+
+// parseN2KAscii parses N2K ASCII sentence. Data is decoded into dataBuf when it is large enough, otherwise new slice
+// is allocated.
+func parseN2KAscii(raw []byte, now time.Time, clock *nmea.DeviceClock, dataBuf []byte) (nmea.RawMessage, bool, error) {
 	// Source: Actisense own documentation `NMEA 2000 ASCII Output format.docx`
 	//
 	// Ahhmmss.ddd <SS><DD><P> <PPPPP> b0b1b2b3b4b5b6b7.....bn<CR><LF>
@@ -159,6 +234,11 @@ func parseN2KAscii(raw []byte, now time.Time) (nmea.RawMessage, bool, error) {
 	if timePartEnd == 0 {
 		return nmea.RawMessage{}, false, errors.New("N2K Ascii message missing time block")
 	}
+	var bTime time.Time
+	timeOfDay, ok := parseN2KAsciiTime(raw[1 : timePartEnd+1])
+	if ok {
+		bTime = busTime(clock, timeOfDay, now)
+	}
 
 	headerPartStart, headerPartEnd := findNextNonHexBlock(raw, timePartEnd+1)
 	if headerPartEnd == -1 {
@@ -188,7 +268,11 @@ func parseN2KAscii(raw []byte, now time.Time) (nmea.RawMessage, bool, error) {
 	if dataPartEnd == -1 {
 		return nmea.RawMessage{}, false, errors.New("N2K Ascii message missing data block")
 	}
-	dataDecoded := make([]byte, (dataPartEnd+1-dataPartStart)/2)
+	dataLen := (dataPartEnd + 1 - dataPartStart) / 2
+	if cap(dataBuf) < dataLen {
+		dataBuf = make([]byte, dataLen)
+	}
+	dataDecoded := dataBuf[:dataLen]
 	n, err := hex.Decode(dataDecoded, raw[dataPartStart:dataPartEnd+1])
 	if err != nil {
 		return nmea.RawMessage{}, false, err
@@ -196,7 +280,9 @@ func parseN2KAscii(raw []byte, now time.Time) (nmea.RawMessage, bool, error) {
 	dataDecoded = dataDecoded[0:n]
 
 	return nmea.RawMessage{
-		Time: now,
+		Time:       now,
+		BusTime:    bTime,
+		DeviceTime: timeOfDay,
 		Header: nmea.CanBusHeader{
 			PGN:         pgn,
 			Source:      source,
@@ -207,6 +293,45 @@ func parseN2KAscii(raw []byte, now time.Time) (nmea.RawMessage, bool, error) {
 	}, false, nil
 }
 
+// parseN2KAsciiTime parses time block `hhmmss.ddd` (milliseconds part is optional) to time since start of the day
+func parseN2KAsciiTime(raw []byte) (time.Duration, bool) {
+	if len(raw) < 6 {
+		return 0, false
+	}
+	digits := func(b []byte) (int, bool) {
+		result := 0
+		for _, c := range b {
+			if c < '0' || c > '9' {
+				return 0, false
+			}
+			result = result*10 + int(c-'0')
+		}
+		return result, true
+	}
+	hhmmss, ok := digits(raw[0:6])
+	if !ok {
+		return 0, false
+	}
+	result := time.Duration(hhmmss/10000)*time.Hour +
+		time.Duration((hhmmss/100)%100)*time.Minute +
+		time.Duration(hhmmss%100)*time.Second
+	if len(raw) > 7 && raw[6] == '.' {
+		fraction := raw[7:]
+		if len(fraction) > 3 {
+			fraction = fraction[0:3]
+		}
+		ms, ok := digits(fraction)
+		if !ok {
+			return 0, false
+		}
+		for i := len(fraction); i < 3; i++ {
+			ms *= 10
+		}
+		result += time.Duration(ms) * time.Millisecond
+	}
+	return result, true
+}
+
 func findNextNonHexBlock(raw []byte, fromIndex int) (int, int) {
 	startIndex := -1
 	endIndex := -1
@@ -1,10 +1,13 @@
 package actisense
 
 import (
+	"bytes"
 	"context"
 	"github.com/aldas/go-nmea-client"
 	test_test "github.com/aldas/go-nmea-client/test"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,7 +31,9 @@ func TestN2kAsciiDevice_ReadRawMessage(t *testing.T) {
 				},
 			},
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				BusTime:    now,
+				DeviceTime: 63201107 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					PGN:         0x1F513, // 1F513 -> 128275 Distance Log
 					Source:      35,      // 0x23
@@ -46,7 +51,9 @@ func TestN2kAsciiDevice_ReadRawMessage(t *testing.T) {
 				{Read: []byte("1F513 012F3070002F30709F    \nAXXX"), Err: nil},
 			},
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				BusTime:    now,
+				DeviceTime: 63201107 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					PGN:         0x1F513, // 1F513 -> 128275 Distance Log
 					Source:      35,      // 0x23
@@ -64,7 +71,9 @@ func TestN2kAsciiDevice_ReadRawMessage(t *testing.T) {
 				{Read: []byte("1F513 012F3070002F30709F    \nAXXX"), Err: nil},
 			},
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				BusTime:    now,
+				DeviceTime: 63201107 * time.Millisecond,
 				Header: nmea.CanBusHeader{
 					PGN:         0x1F513, // 1F513 -> 128275 Distance Log
 					Source:      35,      // 0x23
@@ -137,6 +146,20 @@ func TestFormatN2KASCII(t *testing.T) {
 			},
 			expect: []byte("A114722.123 feff6 0ea00 00ee00\r"),
 		},
+		{
+			name: "ok, addresses are zero padded",
+			when: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					PGN:         uint32(nmea.PGNISOAddressClaim),
+					Source:      5,
+					Destination: 9,
+					Priority:    6,
+				},
+				Data: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			},
+			expect: []byte("A114722.123 05096 0ee00 0102030405060708\r"),
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -161,7 +184,8 @@ func TestParseN2KAscii(t *testing.T) {
 			name: "ok",
 			when: []byte("A173321.107 23FF7 1F513 012F3070002F30709F    \n"),
 			expect: nmea.RawMessage{
-				Time: now,
+				Time:       now,
+				DeviceTime: 17*time.Hour + 33*time.Minute + 21107*time.Millisecond,
 				Header: nmea.CanBusHeader{
 					PGN:         0x1F513, // 1F513 -> 128275 Distance Log
 					Source:      35,      // 0x23
@@ -176,7 +200,7 @@ func TestParseN2KAscii(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, skip, err := parseN2KAscii(tc.when, now)
+			result, skip, err := parseN2KAscii(tc.when, now, nil, nil)
 
 			assert.Equal(t, tc.expect, result)
 			assert.Equal(t, tc.expectSkip, skip)
@@ -188,3 +212,149 @@ func TestParseN2KAscii(t *testing.T) {
 		})
 	}
 }
+
+func TestParseN2KAsciiTime(t *testing.T) {
+	var testCases = []struct {
+		when       string
+		expect     time.Duration
+		expectFail bool
+	}{
+		{when: "173321.107", expect: 17*time.Hour + 33*time.Minute + 21*time.Second + 107*time.Millisecond},
+		{when: "173321.1", expect: 17*time.Hour + 33*time.Minute + 21*time.Second + 100*time.Millisecond},
+		{when: "000001", expect: 1 * time.Second},
+		{when: "1733", expectFail: true},
+		{when: "17332x.107", expectFail: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.when, func(t *testing.T) {
+			result, ok := parseN2KAsciiTime([]byte(tc.when))
+
+			assert.Equal(t, !tc.expectFail, ok)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestParseN2KAscii_busTime(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	clock := nmea.NewDeviceClock(24 * time.Hour)
+
+	first, _, err := parseN2KAscii([]byte("A173321.107 23FF7 1F513 012F3070002F30709F"), now, clock, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, now, first.BusTime)
+
+	second, _, err := parseN2KAscii([]byte("A173321.207 23FF7 1F513 012F3070002F30709F"), now.Add(150*time.Millisecond), clock, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(100*time.Millisecond), second.BusTime)
+}
+
+func TestN2kAsciiDevice_WriteRawMessage(t *testing.T) {
+	now := time.Unix(1665488842, 123999999).In(time.UTC) // Tue Oct 11 2022 11:47:22.123999999 GMT+0000
+
+	var testCases = []struct {
+		name        string
+		when        nmea.RawMessage
+		expect      string
+		expectError string
+	}{
+		{
+			name: "ok",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNISORequest), Source: 0x23, Destination: 0x09, Priority: 6},
+				Data:   []byte{0x00, 0xee, 0x00},
+			},
+			expect: "A114722.123 23096 0ea00 00ee00\r",
+		},
+		{
+			name: "ok, message without time is written with current time",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNISORequest), Source: 0x23, Destination: 0xff, Priority: 6},
+				Data:   []byte{0x00, 0xee, 0x00},
+			},
+			expect: "A114722.123 23ff6 0ea00 00ee00\r",
+		},
+		{
+			name: "ok, long message is written as single sentence",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 126996, Source: 0x23, Destination: 0xff, Priority: 6},
+				Data:   bytes.Repeat([]byte{0xab}, 134),
+			},
+			expect: "A114722.123 23ff6 1f014 " + strings.Repeat("ab", 134) + "\r",
+		},
+		{
+			name: "nok, empty data",
+			when: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNISORequest), Source: 0x23, Destination: 0xff, Priority: 6},
+			},
+			expectError: "N2K Ascii message data length must be 1-1785 bytes, got 0",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			device := NewN2kASCIIDevice(&struct {
+				io.Reader
+				io.Writer
+			}{Writer: buf}, Config{})
+			device.timeNow = func() time.Time {
+				return now
+			}
+
+			err := device.WriteRawMessage(context.Background(), tc.when)
+
+			assert.Equal(t, tc.expect, buf.String())
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestN2kAsciiDevice_WriteRawMessage_roundTrip(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	msg := nmea.RawMessage{
+		Time:       now,
+		DeviceTime: 11*time.Hour + 47*time.Minute + 22*time.Second, // time of day of `now`
+		Header:     nmea.CanBusHeader{PGN: uint32(nmea.PGNISOAddressClaim), Source: 0x0a, Destination: 0xff, Priority: 6},
+		Data:       []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+	}
+
+	line := formatN2KASCII(msg)
+	result, skip, err := parseN2KAscii(bytes.TrimRight(line, "\r"), now, nil, nil)
+
+	assert.NoError(t, err)
+	assert.False(t, skip)
+	assert.Equal(t, msg, result)
+}
+
+func TestN2kAsciiDevice_ReadRawMessageInto(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	mockReader := &test_test.MockReaderWriter{Reads: []test_test.ReadResult{
+		{Read: []byte("A173321.107 23FF7 1F513 012F3070002F30709F\nA173321.207 23FF7 1F513 0102\n")},
+		{Read: []byte("A173321.307 23FF7 1F513 0102\n")},
+	}}
+	device := NewN2kASCIIDevice(mockReader, Config{})
+	device.timeNow = func() time.Time {
+		return now
+	}
+
+	msg := nmea.RawMessage{Data: make([]byte, 0, 16)}
+	dataPtr := &msg.Data[:1][0]
+
+	err := device.ReadRawMessageInto(context.Background(), &msg)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawData{0x01, 0x2F, 0x30, 0x70, 0x00, 0x2F, 0x30, 0x70, 0x9F}, msg.Data)
+	assert.Equal(t, uint32(0x1F513), msg.Header.PGN)
+	assert.Same(t, dataPtr, &msg.Data[0]) // given slice was reused
+
+	err = device.ReadRawMessageInto(context.Background(), &msg)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawData{0x01, 0x02}, msg.Data)
+	assert.Same(t, dataPtr, &msg.Data[0])
+}
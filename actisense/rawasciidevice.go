@@ -6,13 +6,13 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"github.com/aldas/go-nmea-client"
 	"github.com/aldas/go-nmea-client/internal/utils"
 	"io"
 	"math"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,28 +20,43 @@ const rawASCIIDelimiter = ' '
 
 // RawASCIIDevice is implementing Actisense W2K-1 device capable of decoding RAW Ascii format
 type RawASCIIDevice struct {
-	device  io.ReadWriter
+	device io.ReadWriter
+	// reader makes reads from device interruptible by context cancellation and Close
+	reader  *contextReader
 	timeNow func() time.Time
 
 	readBuffer []byte
 	readIndex  int
+	// chunk is reusable buffer for device reads
+	chunk []byte
 
 	config Config
+
+	closed atomic.Bool
 }
 
 // NewRawASCIIDevice creates new instance of Actisense W2K-1 device capable of decoding RAW Ascii format. RAW ASCII
 // format is ordinary Canbus frame with 8 bytes of data so fast-packet and multi-packet (ISO TP) assembly must be done
 // separately.
 func NewRawASCIIDevice(reader io.ReadWriter, config Config) *RawASCIIDevice {
+	if config.FastPacketSplitter == nil {
+		config.FastPacketSplitter = nmea.NewFastPacketSplitter(nil)
+	}
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
 	return &RawASCIIDevice{
 		device:     reader,
+		reader:     newContextReader(reader),
 		timeNow:    time.Now,
 		readBuffer: make([]byte, 100),
+		chunk:      make([]byte, 50),
 		config:     config,
 	}
 }
 
+// Close closes device. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
 func (d *RawASCIIDevice) Close() error {
+	d.closed.Store(true)
+	d.reader.Close()
 	if c, ok := d.device.(io.Closer); ok {
 		return c.Close()
 	}
@@ -86,61 +101,85 @@ func toRawASCIIBytes(frame nmea.RawFrame) []byte {
 }
 
 func (d *RawASCIIDevice) WriteRawFrame(ctx context.Context, frame nmea.RawFrame) error {
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
 	rawB := toRawASCIIBytes(frame)
 	if d.config.DebugLogRawMessageBytes {
-		fmt.Printf("# DEBUG Writing Actisense N2K RAW ASCII bytes: `%v`\n", utils.FormatSpaces(rawB))
+		d.config.Logger.Debug("writing Actisense N2K RAW ASCII frame", "line", utils.FormatSpaces(rawB))
 	}
 	_, err := d.device.Write(rawB)
 	return err
 }
 
+// WriteRawMessage writes message to device. Messages longer than 8 bytes (and fast-packet PGNs known to
+// Config.FastPacketSplitter) are written as multiple fast-packet frames. Messages that Config.ISOTPSender considers
+// transport protocol messages are written with ISO 11783-3 transport protocol.
 func (d *RawASCIIDevice) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
-	dLen := uint8(len(msg.Data))
-	if len(msg.Data) > 8 {
-		panic("message longer than 8 bytes")
+	if d.config.ISOTPSender != nil && d.config.ISOTPSender.IsTransportMessage(msg) {
+		return d.config.ISOTPSender.Send(ctx, msg, func(frame nmea.RawFrame) error {
+			return d.WriteRawFrame(ctx, frame)
+		})
+	}
+	frames, err := d.config.FastPacketSplitter.Split(msg)
+	if err != nil {
+		return err
 	}
-	frame := nmea.RawFrame{
-		Time:   msg.Time,
-		Header: msg.Header,
-		Length: dLen,
-		Data:   [8]byte{},
+	for _, frame := range frames {
+		if err := d.WriteRawFrame(ctx, frame); err != nil {
+			return err
+		}
 	}
-	copy(frame.Data[0:], msg.Data)
-	return d.WriteRawFrame(ctx, frame)
+	return nil
 }
 
-func (d *RawASCIIDevice) assembleRawMessage(ctx context.Context) (nmea.RawMessage, error) {
-	msg := nmea.RawMessage{}
+func (d *RawASCIIDevice) assembleRawMessage(ctx context.Context, to *nmea.RawMessage) error {
+	if to.Data == nil && d.config.DataPool != nil {
+		to.Data = d.config.DataPool.Get(0)
+	}
 	for {
 		frame, err := d.ReadRawFrame(ctx)
 		if err != nil {
-			return nmea.RawMessage{}, err
+			return err
 		}
-		if d.config.FastPacketAssembler.Assemble(frame, &msg) {
-			return msg, nil
+		if d.config.FastPacketAssembler.Assemble(frame, to) {
+			return nil
 		}
 	}
 }
 
+// ReadRawMessage reads next frame from device. When Config.FastPacketAssembler is set frames are assembled to
+// complete messages.
 func (d *RawASCIIDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	msg := nmea.RawMessage{}
+	if err := d.ReadRawMessageInto(ctx, &msg); err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return msg, nil
+}
+
+// ReadRawMessageInto reads next frame from device into given message. When Config.FastPacketAssembler is set frames
+// are assembled to complete messages. Message Data is reused when it has enough capacity.
+func (d *RawASCIIDevice) ReadRawMessageInto(ctx context.Context, to *nmea.RawMessage) error {
 	if d.config.FastPacketAssembler != nil {
-		return d.assembleRawMessage(ctx)
+		return d.assembleRawMessage(ctx, to)
 	}
 
 	frame, err := d.ReadRawFrame(ctx)
 	if err != nil {
-		return nmea.RawMessage{}, err
+		return err
 	}
-	return nmea.RawMessage{
+	setRawMessage(to, nmea.RawMessage{
 		Time:   frame.Time,
 		Header: frame.Header,
 		Data:   frame.Data[:],
-	}, err
+	}, d.config.DataPool)
+	return nil
 }
 
 func (d *RawASCIIDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
 	// Example: '00:34:02.718 R 15FD0800 FF 00 01 CA 6F FF FF FF\n'
-	buf := make([]byte, 50)
+	buf := d.chunk
 
 	for {
 		select {
@@ -148,10 +187,16 @@ func (d *RawASCIIDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error
 			return nmea.RawFrame{}, ctx.Err()
 		default:
 		}
+		if d.closed.Load() {
+			return nmea.RawFrame{}, nmea.ErrDeviceClosed
+		}
 
-		n, err := d.device.Read(buf) // FIXME: read is blocking call. we need to set read timeouts to work with context cancellations
+		n, err := d.reader.Read(ctx, buf)
 
 		if err != nil {
+			if d.closed.Load() {
+				return nmea.RawFrame{}, nmea.ErrDeviceClosed
+			}
 			return nmea.RawFrame{}, err
 		}
 		if n == 0 {
@@ -172,8 +217,8 @@ func (d *RawASCIIDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error
 		d.readIndex += endIndex
 
 		frame := d.readBuffer[0:d.readIndex]
-		if d.config.DebugLogRawMessageBytes && d.config.LogFunc != nil {
-			d.config.LogFunc("# DEBUG Read Actisense RAW ASCII frame: %v\n", utils.FormatSpaces(frame))
+		if d.config.DebugLogRawMessageBytes {
+			d.config.Logger.Debug("read Actisense RAW ASCII frame", "line", utils.FormatSpaces(frame))
 		}
 		now := d.timeNow()
 		rawFrame, skip, err := parseRawASCII(frame, now)
@@ -186,6 +231,9 @@ func (d *RawASCIIDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error
 		if skip {
 			continue
 		}
+		if err == nil && d.config.ISOTPSender != nil {
+			d.config.ISOTPSender.HandleFrame(rawFrame)
+		}
 
 		return rawFrame, err
 	}
@@ -224,7 +272,7 @@ func parseRawASCII(raw []byte, now time.Time) (nmea.RawFrame, bool, error) {
 	}
 	canHeader := nmea.ParseCANID(CanID)
 
-	hexBytes := make([]byte, 16)
+	hexBytes := [16]byte{}
 	dstIndex := 0
 	for i := spaceIndex; i < len(raw); i++ {
 		b := raw[i]
@@ -237,13 +285,11 @@ func parseRawASCII(raw []byte, now time.Time) (nmea.RawFrame, bool, error) {
 		hexBytes[dstIndex] = b
 		dstIndex++
 	}
-	dataDecoded := make([]byte, dstIndex)
-	n, err := hex.Decode(dataDecoded, hexBytes)
+	data := [8]byte{}
+	n, err := hex.Decode(data[:], hexBytes[:])
 	if err != nil {
 		return nmea.RawFrame{}, false, err
 	}
-	data := [8]byte{}
-	copy(data[:], dataDecoded[:n])
 
 	return nmea.RawFrame{
 		Time:   now,
@@ -1,10 +1,13 @@
 package actisense
 
 import (
+	"bytes"
+	"context"
 	"github.com/aldas/go-nmea-client"
 	test_test "github.com/aldas/go-nmea-client/test"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestParseRawAscii(t *testing.T) {
@@ -126,7 +129,7 @@ func TestToRawASCIIBytes(t *testing.T) {
 				Length: 8,
 				Data:   [8]byte{0x3a, 0x9c, 0x63, 0x01, 0x00, 0xff, 0xff, 0xff},
 			},
-			expect: []byte("00:00:00.000 S 09F1FF23 3A 9C 63 01 00 FF FF FF\r\n"),
+			expect: []byte("00:00:00.000 S 09F11323 3A 9C 63 01 00 FF FF FF\r\n"),
 		},
 		{
 			name: "ok, shorter, 7 bytes",
@@ -140,7 +143,7 @@ func TestToRawASCIIBytes(t *testing.T) {
 				Length: 7,
 				Data:   [8]byte{0x3a, 0x9c, 0x63, 0x01, 0x00, 0xff, 0xff},
 			},
-			expect: []byte("00:00:00.000 S 09F1FF23 3A 9C 63 01 00 FF FF\r\n"),
+			expect: []byte("00:00:00.000 S 09F11323 3A 9C 63 01 00 FF FF\r\n"),
 		},
 		{
 			name: "ok, shorter, 1 byte",
@@ -154,7 +157,7 @@ func TestToRawASCIIBytes(t *testing.T) {
 				Length: 1,
 				Data:   [8]byte{0x3a},
 			},
-			expect: []byte("00:00:00.000 S 09F1FF23 3A\r\n"),
+			expect: []byte("00:00:00.000 S 09F11323 3A\r\n"),
 		},
 		{
 			name: "ok, ISORequest name",
@@ -178,3 +181,57 @@ func TestToRawASCIIBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestRawASCIIDevice_WriteRawMessage(t *testing.T) {
+	buf := &bytes.Buffer{}
+	device := NewRawASCIIDevice(buf, Config{})
+
+	header := nmea.CanBusHeader{PGN: 0x1F113, Source: 35, Destination: 255, Priority: 2}
+	err := device.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Header: header,
+		Data:   []byte{0x3a, 0x9c, 0x63, 0x01, 0x00, 0xff, 0xff, 0xff},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "00:00:00.000 S 09F11323 3A 9C 63 01 00 FF FF FF\r\n", buf.String())
+
+	buf.Reset()
+	err = device.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Header: header,
+		Data:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+	})
+	assert.NoError(t, err)
+	expect := "00:00:00.000 S 09F11323 00 0A 01 02 03 04 05 06\r\n" +
+		"00:00:00.000 S 09F11323 01 07 08 09 0A FF FF FF\r\n"
+	assert.Equal(t, expect, buf.String())
+
+	_, err = device.config.FastPacketSplitter.Split(nmea.RawMessage{Data: make([]byte, nmea.FastRawPacketMaxSize+1)})
+	assert.Error(t, err)
+}
+
+func TestRawASCIIDevice_ReadRawMessageInto(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	mockReader := &test_test.MockReaderWriter{Reads: []test_test.ReadResult{
+		{Read: []byte("00:34:02.718 R 15FD0800 FF 00 01 CA 6F FF FF FF\n")},
+	}}
+	pool := nmea.NewBufferPool(8)
+	device := NewRawASCIIDevice(mockReader, Config{DataPool: pool})
+	device.timeNow = func() time.Time {
+		return now
+	}
+
+	msg := nmea.RawMessage{}
+	err := device.ReadRawMessageInto(context.Background(), &msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time: now,
+		Header: nmea.CanBusHeader{
+			PGN:         0x1FD08,
+			Source:      0,
+			Destination: 255,
+			Priority:    5,
+		},
+		Data: []byte{0xFF, 0x0, 0x01, 0xCA, 0x6F, 0xFF, 0xFF, 0xFF},
+	}, msg)
+	pool.Put(msg.Data)
+}
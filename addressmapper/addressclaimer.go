@@ -0,0 +1,303 @@
+package addressmapper
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"sync"
+	"time"
+)
+
+// AddressClaimTimeout is time after sending address claim when claimed address can be used when no other node has
+// contested the claim (J1939-81).
+const AddressClaimTimeout = 250 * time.Millisecond
+
+const (
+	// arbitrary address capable nodes select new address from range 128 to 247 (J1939-81)
+	arbitraryAddressMin = uint8(128)
+	arbitraryAddressMax = uint8(247)
+)
+
+// ErrAddressNotClaimed is returned by ClaimedAddressWriter when address has not (yet) been successfully claimed.
+var ErrAddressNotClaimed = errors.New("address is not claimed")
+
+// AddressClaimState is state of address claim process
+type AddressClaimState uint8
+
+const (
+	// AddressClaimStateIdle means that claim process has not been started
+	AddressClaimStateIdle AddressClaimState = iota
+	// AddressClaimStateClaiming means that address claim has been sent and AddressClaimTimeout has not passed yet
+	AddressClaimStateClaiming
+	// AddressClaimStateClaimed means that address has been claimed (AddressClaimTimeout is checked by Address method)
+	AddressClaimStateClaimed
+	// AddressClaimStateCannotClaim means that node lost address claim and has no address available to claim
+	AddressClaimStateCannotClaim
+)
+
+// AddressClaimConfig configures how AddressClaimer instance behaves
+type AddressClaimConfig struct {
+	// Name is NAME of our node. Lower NAME wins address claim contention. When Name.ArbitraryAddressCapable is set to 1
+	// node selects new address from range 128-247 after losing claim, otherwise it sends "Cannot claim address".
+	Name NodeName
+	// PreferredAddress is source address that node tries to claim first
+	PreferredAddress uint8
+}
+
+// AddressClaimer implements J1939-81 address claim procedure so library can claim its own source address and act as
+// node in NMEA bus. Messages read from bus must be passed to Process method so claimer can respond to address claim
+// requests (PGN 59904) and competing address claims (PGN 60928).
+type AddressClaimer struct {
+	mutex sync.Mutex
+
+	writer nmea.RawMessageWriter
+
+	name      NodeName
+	nameData  []byte
+	nameValue uint64
+
+	preferredAddress uint8
+	address          uint8
+	state            AddressClaimState
+	claimedAt        time.Time
+
+	// otherNodes holds NAMEs of other nodes by their claimed addresses
+	otherNodes map[uint8]uint64
+
+	now func() time.Time
+}
+
+// NewAddressClaimer creates new instance of AddressClaimer
+func NewAddressClaimer(writer nmea.RawMessageWriter, config AddressClaimConfig) *AddressClaimer {
+	nameData := config.Name.Bytes()
+	return &AddressClaimer{
+		mutex:  sync.Mutex{},
+		writer: writer,
+
+		name:      config.Name,
+		nameData:  nameData,
+		nameValue: binary.LittleEndian.Uint64(nameData), // same as AddressMapper compares NAMEs
+
+		preferredAddress: config.PreferredAddress,
+		address:          nmea.AddressNull,
+		state:            AddressClaimStateIdle,
+
+		otherNodes: make(map[uint8]uint64),
+
+		now: time.Now,
+	}
+}
+
+// Claim starts address claim process by sending address claim (PGN 60928) for preferred address.
+func (c *AddressClaimer) Claim(ctx context.Context) error {
+	c.mutex.Lock()
+	address := c.preferredAddress
+	if otherNAME, ok := c.otherNodes[address]; ok && otherNAME < c.nameValue {
+		address = c.nextFreeAddress(address)
+	}
+	msg := c.claim(address)
+	c.mutex.Unlock()
+
+	return c.writer.WriteRawMessage(ctx, msg)
+}
+
+// Process handles address claims (PGN 60928), address claim requests (PGN 59904) and commanded addresses (PGN 65240)
+// read from bus. When response is needed (defending our claim, claiming new address or responding to request) it is
+// written to writer.
+func (c *AddressClaimer) Process(ctx context.Context, raw nmea.RawMessage) error {
+	var msg nmea.RawMessage
+	var respond bool
+	switch nmea.PGN(raw.Header.PGN) {
+	case nmea.PGNISOAddressClaim:
+		msg, respond = c.processAddressClaim(raw)
+	case nmea.PGNISORequest:
+		msg, respond = c.processISORequest(raw)
+	case nmea.PGNISOCommandedAddress:
+		msg, respond = c.processCommandedAddress(raw)
+	}
+	if !respond {
+		return nil
+	}
+	return c.writer.WriteRawMessage(ctx, msg)
+}
+
+func (c *AddressClaimer) processAddressClaim(raw nmea.RawMessage) (nmea.RawMessage, bool) {
+	if len(raw.Data) != 8 {
+		return nmea.RawMessage{}, false
+	}
+	source := raw.Header.Source
+	otherNAME := binary.LittleEndian.Uint64(raw.Data)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if otherNAME == c.nameValue {
+		return nmea.RawMessage{}, false // our own claim echoed back by gateway
+	}
+	for addr, name := range c.otherNodes {
+		if name == otherNAME {
+			delete(c.otherNodes, addr) // node moved to another address
+		}
+	}
+	if source >= nmea.AddressNull {
+		return nmea.RawMessage{}, false // "cannot claim address" from other node
+	}
+	c.otherNodes[source] = otherNAME
+
+	if c.state == AddressClaimStateIdle || c.state == AddressClaimStateCannotClaim || source != c.address {
+		return nmea.RawMessage{}, false
+	}
+	if c.nameValue < otherNAME {
+		// our NAME has higher priority so we defend our address by claiming it again
+		return c.claim(c.address), true
+	}
+	return c.claim(c.nextFreeAddress(c.address)), true
+}
+
+func (c *AddressClaimer) processISORequest(raw nmea.RawMessage) (nmea.RawMessage, bool) {
+	if len(raw.Data) < 3 {
+		return nmea.RawMessage{}, false
+	}
+	requestedPGN := uint32(raw.Data[0]) | uint32(raw.Data[1])<<8 | uint32(raw.Data[2])<<16
+	if requestedPGN != uint32(nmea.PGNISOAddressClaim) {
+		return nmea.RawMessage{}, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.state == AddressClaimStateIdle {
+		return nmea.RawMessage{}, false
+	}
+	destination := raw.Header.Destination
+	if destination != nmea.AddressGlobal && destination != c.address {
+		return nmea.RawMessage{}, false
+	}
+	if c.state == AddressClaimStateCannotClaim {
+		return c.claim(nmea.AddressNull), true
+	}
+	return c.createAddressClaim(c.address), true
+}
+
+// processCommandedAddress claims address our node is commanded to. Commanded address becomes our preferred address.
+func (c *AddressClaimer) processCommandedAddress(raw nmea.RawMessage) (nmea.RawMessage, bool) {
+	cmd, err := PGN65240ToCommandedAddress(raw)
+	if err != nil || cmd.NewAddress >= nmea.AddressNull {
+		return nmea.RawMessage{}, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cmd.NAME != c.nameValue || c.state == AddressClaimStateIdle {
+		return nmea.RawMessage{}, false
+	}
+	c.preferredAddress = cmd.NewAddress
+	address := cmd.NewAddress
+	if otherNAME, ok := c.otherNodes[address]; ok && otherNAME < c.nameValue {
+		address = c.nextFreeAddress(address)
+	}
+	return c.claim(address), true
+}
+
+// claim changes state to claiming given address and creates address claim message for it. When address is
+// AddressNull state is changed to "cannot claim".
+func (c *AddressClaimer) claim(address uint8) nmea.RawMessage {
+	c.address = address
+	c.claimedAt = c.now()
+	if address == nmea.AddressNull {
+		c.state = AddressClaimStateCannotClaim
+	} else {
+		c.state = AddressClaimStateClaiming
+	}
+	return c.createAddressClaim(address)
+}
+
+// nextFreeAddress returns address from arbitrary address range that is not claimed by other nodes. Returns AddressNull
+// when our node is not arbitrary address capable or all addresses are in use.
+func (c *AddressClaimer) nextFreeAddress(current uint8) uint8 {
+	if c.name.ArbitraryAddressCapable == 0 {
+		return nmea.AddressNull
+	}
+	candidate := current
+	for i := 0; i <= int(arbitraryAddressMax-arbitraryAddressMin); i++ {
+		candidate++
+		if candidate < arbitraryAddressMin || candidate > arbitraryAddressMax {
+			candidate = arbitraryAddressMin
+		}
+		if _, ok := c.otherNodes[candidate]; !ok {
+			return candidate
+		}
+	}
+	return nmea.AddressNull
+}
+
+func (c *AddressClaimer) createAddressClaim(source uint8) nmea.RawMessage {
+	data := make([]byte, len(c.nameData))
+	copy(data, c.nameData)
+	return nmea.RawMessage{
+		Time: c.now(),
+		Header: nmea.CanBusHeader{
+			PGN:         uint32(nmea.PGNISOAddressClaim),
+			Priority:    6,
+			Source:      source,
+			Destination: nmea.AddressGlobal,
+		},
+		Data: data,
+	}
+}
+
+// State returns current state of address claim process
+func (c *AddressClaimer) State() AddressClaimState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.state == AddressClaimStateClaiming && c.now().Sub(c.claimedAt) >= AddressClaimTimeout {
+		c.state = AddressClaimStateClaimed
+	}
+	return c.state
+}
+
+// Address returns claimed source address. Address is valid only when AddressClaimTimeout has passed from claim
+// without losing contention to other node.
+func (c *AddressClaimer) Address() (uint8, bool) {
+	if c.State() != AddressClaimStateClaimed {
+		return nmea.AddressNull, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.address, true
+}
+
+// ClaimedAddressWriter sets claimed address as source for all written messages so messages sent by library are from
+// valid bus node.
+type ClaimedAddressWriter struct {
+	claimer *AddressClaimer
+	writer  nmea.RawMessageWriter
+}
+
+// NewClaimedAddressWriter creates new instance of ClaimedAddressWriter
+func NewClaimedAddressWriter(claimer *AddressClaimer, writer nmea.RawMessageWriter) *ClaimedAddressWriter {
+	return &ClaimedAddressWriter{
+		claimer: claimer,
+		writer:  writer,
+	}
+}
+
+// WriteRawMessage writes message with claimed address as source. Returns ErrAddressNotClaimed when address has not
+// been claimed.
+func (w *ClaimedAddressWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	address, ok := w.claimer.Address()
+	if !ok {
+		return ErrAddressNotClaimed
+	}
+	msg.Header.Source = address
+	return w.writer.WriteRawMessage(ctx, msg)
+}
+
+// Close closes underlying writer
+func (w *ClaimedAddressWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,212 @@
+package addressmapper
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testWriter struct {
+	messages []nmea.RawMessage
+}
+
+func (w *testWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	w.messages = append(w.messages, msg)
+	return nil
+}
+
+func (w *testWriter) Close() error {
+	return nil
+}
+
+var testClaimerName = NodeName{
+	UniqueNumber:            1234,
+	Manufacturer:            2046,
+	DeviceFunction:          130, // PC Gateway
+	DeviceClass:             25,  // Internetwork device
+	IndustryGroup:           4,   // Marine
+	ArbitraryAddressCapable: 1,
+}
+
+func newTestClaimer(t *testing.T, name NodeName, preferredAddress uint8) (*AddressClaimer, *testWriter, *time.Time) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	w := &testWriter{}
+	c := NewAddressClaimer(w, AddressClaimConfig{Name: name, PreferredAddress: preferredAddress})
+	c.now = func() time.Time {
+		return now
+	}
+	assert.NoError(t, c.Claim(context.Background()))
+	return c, w, &now
+}
+
+func addressClaim(source uint8, data []byte) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: source, Destination: 255},
+		Data:   data,
+	}
+}
+
+func TestAddressClaimer_Claim(t *testing.T) {
+	c, w, now := newTestClaimer(t, testClaimerName, 100)
+
+	if assert.Len(t, w.messages, 1) {
+		assert.Equal(t, nmea.RawMessage{
+			Time:   *now,
+			Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: 100, Destination: 255},
+			Data:   nmea.RawData(testClaimerName.Bytes()),
+		}, w.messages[0])
+	}
+	assert.Equal(t, AddressClaimStateClaiming, c.State())
+	_, ok := c.Address()
+	assert.False(t, ok)
+
+	*now = now.Add(AddressClaimTimeout)
+
+	assert.Equal(t, AddressClaimStateClaimed, c.State())
+	address, ok := c.Address()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(100), address)
+}
+
+func TestAddressClaimer_Process_defendsAddress(t *testing.T) {
+	c, w, now := newTestClaimer(t, testClaimerName, 100)
+	*now = now.Add(AddressClaimTimeout)
+
+	otherName := testClaimerName
+	otherName.UniqueNumber = 9999 // higher NAME loses
+	err := c.Process(context.Background(), addressClaim(100, otherName.Bytes()))
+
+	assert.NoError(t, err)
+	if assert.Len(t, w.messages, 2) {
+		assert.Equal(t, uint8(100), w.messages[1].Header.Source)
+		assert.Equal(t, nmea.RawData(testClaimerName.Bytes()), w.messages[1].Data)
+	}
+	assert.Equal(t, AddressClaimStateClaiming, c.State())
+}
+
+func TestAddressClaimer_Process_losesAddress(t *testing.T) {
+	c, w, now := newTestClaimer(t, testClaimerName, 247)
+	*now = now.Add(AddressClaimTimeout)
+
+	assert.NoError(t, c.Process(context.Background(), addressClaim(128, []byte{0, 0, 0, 0, 0, 0, 0, 0})))
+	assert.Len(t, w.messages, 1) // claim for other address does not concern us
+
+	err := c.Process(context.Background(), addressClaim(247, []byte{0, 0, 0, 0, 0, 0, 0, 1}))
+
+	assert.NoError(t, err)
+	if assert.Len(t, w.messages, 2) {
+		assert.Equal(t, uint8(129), w.messages[1].Header.Source) // 128 is already used by other node
+		assert.Equal(t, nmea.RawData(testClaimerName.Bytes()), w.messages[1].Data)
+	}
+	_, ok := c.Address()
+	assert.False(t, ok)
+
+	*now = now.Add(AddressClaimTimeout)
+	address, ok := c.Address()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(129), address)
+}
+
+func TestAddressClaimer_Process_cannotClaim(t *testing.T) {
+	name := testClaimerName
+	name.ArbitraryAddressCapable = 0
+	c, w, _ := newTestClaimer(t, name, 100)
+
+	err := c.Process(context.Background(), addressClaim(100, []byte{0, 0, 0, 0, 0, 0, 0, 0}))
+
+	assert.NoError(t, err)
+	if assert.Len(t, w.messages, 2) {
+		assert.Equal(t, nmea.AddressNull, w.messages[1].Header.Source)
+	}
+	assert.Equal(t, AddressClaimStateCannotClaim, c.State())
+	_, ok := c.Address()
+	assert.False(t, ok)
+}
+
+func TestAddressClaimer_Process_ignoresOwnClaimEcho(t *testing.T) {
+	c, w, _ := newTestClaimer(t, testClaimerName, 100)
+
+	assert.NoError(t, c.Process(context.Background(), addressClaim(100, testClaimerName.Bytes())))
+
+	assert.Len(t, w.messages, 1)
+	assert.Equal(t, AddressClaimStateClaiming, c.State())
+}
+
+func TestAddressClaimer_Process_ISORequest(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		whenDestination  uint8
+		whenRequestedPGN []byte
+		expectResponse   bool
+	}{
+		{
+			name:             "ok, global request",
+			whenDestination:  255,
+			whenRequestedPGN: []byte{0x00, 0xee, 0x00},
+			expectResponse:   true,
+		},
+		{
+			name:             "ok, request to our address",
+			whenDestination:  100,
+			whenRequestedPGN: []byte{0x00, 0xee, 0x00},
+			expectResponse:   true,
+		},
+		{
+			name:             "ok, request to other address",
+			whenDestination:  101,
+			whenRequestedPGN: []byte{0x00, 0xee, 0x00},
+			expectResponse:   false,
+		},
+		{
+			name:             "ok, request for other PGN",
+			whenDestination:  255,
+			whenRequestedPGN: []byte{0x14, 0xf0, 0x01},
+			expectResponse:   false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w, _ := newTestClaimer(t, testClaimerName, 100)
+
+			err := c.Process(context.Background(), nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: tc.whenDestination},
+				Data:   tc.whenRequestedPGN,
+			})
+
+			assert.NoError(t, err)
+			if tc.expectResponse {
+				if assert.Len(t, w.messages, 2) {
+					assert.Equal(t, uint8(100), w.messages[1].Header.Source)
+					assert.Equal(t, nmea.RawData(testClaimerName.Bytes()), w.messages[1].Data)
+				}
+			} else {
+				assert.Len(t, w.messages, 1)
+			}
+		})
+	}
+}
+
+func TestClaimedAddressWriter_WriteRawMessage(t *testing.T) {
+	c, _, now := newTestClaimer(t, testClaimerName, 100)
+	w := &testWriter{}
+	writer := NewClaimedAddressWriter(c, w)
+
+	msg := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 126993, Priority: 7, Source: 254, Destination: 255},
+		Data:   []byte{0x60, 0xea, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	err := writer.WriteRawMessage(context.Background(), msg)
+	assert.ErrorIs(t, err, ErrAddressNotClaimed)
+	assert.Len(t, w.messages, 0)
+
+	*now = now.Add(AddressClaimTimeout)
+
+	err = writer.WriteRawMessage(context.Background(), msg)
+	assert.NoError(t, err)
+	if assert.Len(t, w.messages, 1) {
+		assert.Equal(t, uint8(100), w.messages[0].Header.Source)
+	}
+}
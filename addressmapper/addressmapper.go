@@ -6,12 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/aldas/go-nmea-client"
+	"sort"
 	"sync"
 	"time"
 )
 
 const addressMapperWriteChannelSize = 20
 
+// offlineCheckInterval is interval Run checks nodes for offline timeout
+const offlineCheckInterval = 1 * time.Second
+
 //func (p PGN) asBytes() []byte {
 //	return []byte{
 //		uint8(p & 0xff),
@@ -32,6 +36,32 @@ type Node struct {
 
 	ConfigurationInfo      ConfigurationInfo
 	ValidConfigurationInfo bool
+
+	// TransmitPGNs is list of PGNs node has reported (PGN 126464) to transmit
+	TransmitPGNs      []uint32
+	ValidTransmitPGNs bool
+	// ReceivePGNs is list of PGNs node has reported (PGN 126464) to receive
+	ReceivePGNs      []uint32
+	ValidReceivePGNs bool
+}
+
+// Transmits checks if node has reported transmitting given PGN
+func (n Node) Transmits(pgn uint32) bool {
+	return containsPGN(n.TransmitPGNs, pgn)
+}
+
+// Receives checks if node has reported receiving given PGN
+func (n Node) Receives(pgn uint32) bool {
+	return containsPGN(n.ReceivePGNs, pgn)
+}
+
+func containsPGN(pgns []uint32, pgn uint32) bool {
+	for _, p := range pgns {
+		if p == pgn {
+			return true
+		}
+	}
+	return false
 }
 
 type Nodes []Node
@@ -98,6 +128,22 @@ func PGN126996ToProductInfo(raw nmea.RawMessage) (ProductInfo, error) {
 	}, nil
 }
 
+// Bytes encodes product info to PGN 126996 data (134 bytes). Strings are truncated to 32 bytes and padded with 0xFF.
+func (p ProductInfo) Bytes() []byte {
+	b := make([]byte, 0, 134)
+	b = append(b, uint8(p.NMEA2000Version), uint8(p.NMEA2000Version>>8))
+	b = append(b, uint8(p.ProductCode), uint8(p.ProductCode>>8))
+	for _, s := range []string{p.ModelID, p.SoftwareVersionCode, p.ModelVersion, p.ModelSerialCode} {
+		field := [32]byte{}
+		n := copy(field[:], s)
+		for i := n; i < len(field); i++ {
+			field[i] = 0xFF
+		}
+		b = append(b, field[:]...)
+	}
+	return append(b, p.CertificationLevel, p.LoadEquivalency)
+}
+
 // NodeName holds information about node/device to identify it in the NMEA bus. Is acquired by requesting PGN 60928 (ISO Address Claim) from device.
 // Related info about SAE1939 Addresses https://embeddedflakes.com/network-management-in-sae-j1939/
 type NodeName struct { // PGN 60928 is actually 64 bits
@@ -172,6 +218,32 @@ type Config struct {
 	RequestConfigurationInformation bool
 	// RequestPGNList decides if PGN List (126464) is requested after processing Configuration Information (126998)
 	RequestPGNList bool
+
+	// Logger outputs log statements (i.e. failures to write requests to bus).
+	// Optional: when not set nothing is logged
+	Logger nmea.Logger
+
+	// OfflineTimeout is duration after which node is considered offline when nothing has been received from its
+	// address. Nodes with claimed address send Heartbeat (PGN 126993) at least every 60 seconds.
+	// Defaults to: 2 minutes
+	OfflineTimeout time.Duration
+	// OnNodeEvent is called when node comes online, goes offline or changes its address. OnNodeEvent is called outside
+	// of AddressMapper lock so AddressMapper methods can be called from callback. Offline nodes are detected by Run
+	// (or by calling DetectOffline).
+	// Optional
+	OnNodeEvent func(event NodeEvent)
+
+	// HistorySize is maximum number of NAME to source address claims kept in history (oldest are dropped).
+	// Defaults to: 256
+	HistorySize int
+
+	// AutoSavePath is file where Run periodically saves known nodes and address claim history (see SaveFile). Table
+	// is also saved when Run exits.
+	// Optional: when not set table is not saved automatically
+	AutoSavePath string
+	// AutoSaveInterval is interval of automatic saves. Table is saved only when it has changed since last save.
+	// Defaults to: 1 minute
+	AutoSaveInterval time.Duration
 }
 
 type AddressMapper struct {
@@ -191,6 +263,14 @@ type AddressMapper struct {
 
 	knownNodes   map[uint64]*Node
 	address2node [255]*busSlot
+	// commandedAddresses are addresses nodes (by NAME) have been commanded to (PGN 65240) but not yet claimed
+	commandedAddresses map[uint64]uint8
+	// events are node events waiting to be reported to Config.OnNodeEvent after lock is released
+	events []NodeEvent
+	// history contains address claims in order they were processed
+	history []SourceClaim
+	// isChanged is set when node table has changed since last save
+	isChanged bool
 
 	now func() time.Time
 }
@@ -209,6 +289,18 @@ func NewAddressMapper(nmeaDevice nmea.RawMessageWriter) *AddressMapper {
 
 // NewAddressMapperWithConfig creates new instance of AddressMapper with given configuration
 func NewAddressMapperWithConfig(nmeaDevice nmea.RawMessageWriter, config Config) *AddressMapper {
+	if config.Logger == nil {
+		config.Logger = nmea.NopLogger
+	}
+	if config.OfflineTimeout <= 0 {
+		config.OfflineTimeout = 2 * time.Minute
+	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = 256
+	}
+	if config.AutoSaveInterval <= 0 {
+		config.AutoSaveInterval = time.Minute
+	}
 	return &AddressMapper{
 		mutex: sync.Mutex{},
 		now:   time.Now,
@@ -218,8 +310,9 @@ func NewAddressMapperWithConfig(nmeaDevice nmea.RawMessageWriter, config Config)
 		nmeaDevice:      nmeaDevice,
 		config:          config,
 
-		knownNodes:   make(map[uint64]*Node),
-		address2node: [255]*busSlot{},
+		knownNodes:         make(map[uint64]*Node),
+		address2node:       [255]*busSlot{},
+		commandedAddresses: make(map[uint64]uint8),
 	}
 }
 
@@ -254,8 +347,24 @@ func (m *AddressMapper) Run(ctx context.Context) error {
 	if !enabled {
 		writeTimer.Stop()
 	}
+	offlineTicker := time.NewTicker(offlineCheckInterval)
+	defer offlineTicker.Stop()
+
+	var autoSave <-chan time.Time
+	if m.config.AutoSavePath != "" {
+		autoSaveTicker := time.NewTicker(m.config.AutoSaveInterval)
+		defer autoSaveTicker.Stop()
+		defer m.autoSave()
+		autoSave = autoSaveTicker.C
+	}
 	for {
 		select {
+		case <-autoSave:
+			m.autoSave()
+
+		case <-offlineTicker.C:
+			m.DetectOffline()
+
 		case writeEnabled := <-m.toggleWriteChan:
 			enabled = writeEnabled
 			if enabled {
@@ -278,7 +387,7 @@ func (m *AddressMapper) Run(ctx context.Context) error {
 				continue
 			}
 			if err := m.nmeaDevice.WriteRawMessage(ctx, msg); err != nil {
-				fmt.Printf("# address mapper writer (PGN: %v), err: %v\n", msg.Header.PGN, err)
+				m.config.Logger.Warn("address mapper failed to write request", "pgn", msg.Header.PGN, "err", err)
 			}
 
 		case <-ctx.Done():
@@ -290,12 +399,19 @@ func (m *AddressMapper) Run(ctx context.Context) error {
 type busSlot struct {
 	node    *Node
 	claimed time.Time
+	// isRestored is set for slots restored by Load. Restored owner of address may not be on the bus anymore, so first
+	// address claim for restored slot takes over the slot regardless of NAME priority.
+	isRestored bool
 
 	productInfoRequested time.Time
 	configInfoRequested  time.Time
 	pgnListRequested     time.Time
 
 	lastPacket time.Time
+	// lastSeen is local time when last packet from slot address was processed. Used for offline detection.
+	lastSeen time.Time
+	// isOffline is set when nothing has been received from slot address within offline timeout
+	isOffline bool
 }
 
 func (m *AddressMapper) BroadcastIsoAddressClaimRequest() {
@@ -305,10 +421,20 @@ func (m *AddressMapper) BroadcastIsoAddressClaimRequest() {
 	m.requestsChan <- createISORequest(nmea.PGNISOAddressClaim, nmea.AddressGlobal)
 }
 
+// Process updates node table from message. Returns true when node assigned to message source address changed.
+// Node events are reported to Config.OnNodeEvent after table is updated.
 func (m *AddressMapper) Process(raw nmea.RawMessage) (bool, error) {
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
+	isChanged, err := m.process(raw)
+	events := m.events
+	m.events = nil
+	m.mutex.Unlock()
+
+	m.notify(events)
+	return isChanged, err
+}
 
+func (m *AddressMapper) process(raw nmea.RawMessage) (bool, error) {
 	source := raw.Header.Source
 	var slot *busSlot
 	if source >= nmea.AddressNull { // addresses 254 and 255 have special meaning and does not represent actual address for node
@@ -320,6 +446,12 @@ func (m *AddressMapper) Process(raw nmea.RawMessage) (bool, error) {
 			m.address2node[source] = slot
 		}
 		slot.lastPacket = raw.Time
+		slot.lastSeen = m.now()
+		// address claim reports node events itself as claim can change node assigned to address
+		if slot.node != nil && slot.isOffline && raw.Header.PGN != uint32(nmea.PGNISOAddressClaim) {
+			slot.isOffline = false
+			m.addEvent(NodeOnline, slot.node, nmea.AddressNull)
+		}
 	}
 
 	isBusNodeChanged := false
@@ -330,6 +462,10 @@ func (m *AddressMapper) Process(raw nmea.RawMessage) (bool, error) {
 			return false, err
 		}
 		isBusNodeChanged = isChanged
+	case nmea.PGNISOCommandedAddress:
+		if err := m.processCommandedAddress(raw); err != nil {
+			return false, err
+		}
 	case nmea.PGNProductInfo:
 		if err := m.processProductInfo(slot, raw); err != nil {
 			return false, err
@@ -365,8 +501,20 @@ func (m *AddressMapper) processISOAddressClaim(slot *busSlot, raw nmea.RawMessag
 		m.knownNodes[NAME] = currentNode
 	}
 
+	previousSource := currentNode.Source
+	isOnline := slot.node == currentNode && !slot.isOffline
+	isCommanded := false
+	if address, ok := m.commandedAddresses[NAME]; ok && address == source {
+		delete(m.commandedAddresses, NAME)
+		isCommanded = true
+	}
 	isBusNodeChanged := false
-	if slot.node == nil {
+	// restored slot and address node was commanded to (PGN 65240) are taken over regardless of NAME priority
+	if slot.node == nil || slot.isRestored || (isCommanded && slot.node != currentNode) {
+		if slot.node != nil && slot.node != currentNode {
+			slot.node.Source = nmea.AddressNull
+		}
+		slot.isRestored = false
 		// a) in this case we probably started to listen already powered-up and claimed N2k network. assume
 		//    that this name is actually (settled by claim process) owner of this address
 		currentNode.Source = source
@@ -382,6 +530,23 @@ func (m *AddressMapper) processISOAddressClaim(slot *busSlot, raw nmea.RawMessag
 		slot.claimed = m.now()
 		isBusNodeChanged = true
 	}
+	if isBusNodeChanged && source < nmea.AddressNull {
+		m.addHistory(SourceClaim{NAME: NAME, Source: source, Time: slot.claimed})
+
+		if ok && previousSource != source && previousSource < nmea.AddressNull {
+			if old := m.address2node[previousSource]; old != nil && old.node == currentNode {
+				old.node = nil // node does not own its previous address anymore
+			}
+			slot.isOffline = false
+			isOnline = true
+			m.addEvent(NodeAddressChanged, currentNode, previousSource)
+		}
+	}
+	if slot.node == currentNode && source < nmea.AddressNull && !isOnline {
+		slot.isOffline = false
+		m.addEvent(NodeOnline, currentNode, nmea.AddressNull)
+	}
+	m.isChanged = m.isChanged || isBusNodeChanged || !ok
 
 	// if we already have not requested, then request product info for that device
 	if m.writeEnabled && m.config.RequestProductInfo && slot.productInfoRequested.IsZero() {
@@ -402,6 +567,7 @@ func (m *AddressMapper) processProductInfo(slot *busSlot, raw nmea.RawMessage) e
 	}
 	slot.node.ProductInfo = info
 	slot.node.ValidProductInfo = true
+	m.isChanged = true
 
 	// if we already have not requested, then request configuration info for that node
 	if m.writeEnabled && m.config.RequestConfigurationInformation && slot.configInfoRequested.IsZero() {
@@ -422,6 +588,7 @@ func (m *AddressMapper) processConfigurationInfo(slot *busSlot, raw nmea.RawMess
 	}
 	slot.node.ConfigurationInfo = ci
 	slot.node.ValidConfigurationInfo = true
+	m.isChanged = true
 
 	// if we already have not requested, then request PGN list for that node
 	if m.writeEnabled && m.config.RequestPGNList && slot.pgnListRequested.IsZero() {
@@ -435,14 +602,16 @@ func PGN126998ToConfigurationInfo(raw nmea.RawMessage) (ConfigurationInfo, error
 	if raw.Header.PGN != uint32(nmea.PGNConfigurationInformation) {
 		return ConfigurationInfo{}, errors.New("configuration info can only be created from rawMessage with PGN 126998")
 	}
+	// DecodeStringLAU returns number of read bits, not offset of next field
 	instDesc1, offset, err := raw.Data.DecodeStringLAU(0)
 	if err != nil {
 		return ConfigurationInfo{}, fmt.Errorf("failed to decode configuration info installation description 1, err: %w", err)
 	}
-	instDesc2, offset, err := raw.Data.DecodeStringLAU(offset)
+	instDesc2, readBits, err := raw.Data.DecodeStringLAU(offset)
 	if err != nil {
 		return ConfigurationInfo{}, fmt.Errorf("failed to decode configuration info installation description 2, err: %w", err)
 	}
+	offset += readBits
 	manufInfo, _, err := raw.Data.DecodeStringLAU(offset)
 	if err != nil {
 		return ConfigurationInfo{}, fmt.Errorf("failed to decode configuration info manufacturer info, err: %w", err)
@@ -454,11 +623,64 @@ func PGN126998ToConfigurationInfo(raw nmea.RawMessage) (ConfigurationInfo, error
 	}, nil
 }
 
+const (
+	// PGNListFunctionTransmit is PGN 126464 function code for list of PGNs node transmits
+	PGNListFunctionTransmit = uint8(0)
+	// PGNListFunctionReceive is PGN 126464 function code for list of PGNs node receives
+	PGNListFunctionReceive = uint8(1)
+)
+
+// PGNList is list of PGNs node transmits or receives. Is acquired by requesting PGN 126464 (PGN List) from device.
+type PGNList struct {
+	FunctionCode uint8 // 0 = transmit PGN list, 1 = receive PGN list (8 bits)
+	PGNs         []uint32
+}
+
+func PGN126464ToPGNList(raw nmea.RawMessage) (PGNList, error) {
+	if raw.Header.PGN != uint32(nmea.PGNPGNList) {
+		return PGNList{}, errors.New("pgn list can only be created from rawMessage with PGN 126464")
+	}
+	b := raw.Data
+	if len(b) < 1 {
+		return PGNList{}, errors.New("rawMessage has invalid length to be pgn list")
+	}
+	functionCode := b[0]
+	if functionCode != PGNListFunctionTransmit && functionCode != PGNListFunctionReceive {
+		return PGNList{}, fmt.Errorf("pgn list has unknown function code: %v", functionCode)
+	}
+
+	// each PGN is 3 bytes in little endian order. Incomplete PGN at the end (padding) is ignored
+	pgns := make([]uint32, 0, (len(b)-1)/3)
+	for i := 1; i+3 <= len(b); i += 3 {
+		pgn := uint32(b[i]) | uint32(b[i+1])<<8 | uint32(b[i+2])<<16
+		if pgn == 0xffffff { // no data / padding
+			continue
+		}
+		pgns = append(pgns, pgn)
+	}
+	return PGNList{
+		FunctionCode: functionCode,
+		PGNs:         pgns,
+	}, nil
+}
+
 func (m *AddressMapper) processPGNList(slot *busSlot, raw nmea.RawMessage) error {
-	if slot.node == nil || slot.node.ValidName {
+	if slot.node == nil || !slot.node.ValidName {
 		return nil
 	}
 
+	list, err := PGN126464ToPGNList(raw)
+	if err != nil {
+		return err
+	}
+	if list.FunctionCode == PGNListFunctionTransmit {
+		slot.node.TransmitPGNs = list.PGNs
+		slot.node.ValidTransmitPGNs = true
+	} else {
+		slot.node.ReceivePGNs = list.PGNs
+		slot.node.ValidReceivePGNs = true
+	}
+	m.isChanged = true
 	return nil
 }
 
@@ -490,6 +712,27 @@ func (m *AddressMapper) NodesInUseBySource() map[uint8]Node {
 	return result
 }
 
+// NodesSupportingPGN returns list of Nodes currently in use (assigned valid source address) that have reported
+// transmitting or receiving given PGN. Nodes are ordered by source address.
+func (m *AddressMapper) NodesSupportingPGN(pgn uint32) Nodes {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := make(Nodes, 0)
+	for _, n := range m.knownNodes {
+		if n.Source >= nmea.AddressNull || !n.ValidName {
+			continue
+		}
+		if n.Transmits(pgn) || n.Receives(pgn) {
+			result = append(result, *n)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
+
 func createISORequest(forPGN nmea.PGN, destination uint8) nmea.RawMessage {
 	return nmea.RawMessage{
 		Header: nmea.CanBusHeader{
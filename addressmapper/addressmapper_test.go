@@ -105,6 +105,28 @@ func TestPGN126996ToProductInfo(t *testing.T) {
 	}
 }
 
+func TestProductInfo_Bytes(t *testing.T) {
+	given := ProductInfo{
+		NMEA2000Version:     2100,
+		ProductCode:         2837,
+		ModelID:             "AP70 Mk2 Autopilot Controller",
+		SoftwareVersionCode: "01000_E 2.0.0.64.4.34",
+		ModelVersion:        "",
+		ModelSerialCode:     "128787093",
+		CertificationLevel:  0x2,
+		LoadEquivalency:     0x1,
+	}
+
+	data := given.Bytes()
+	assert.Len(t, data, 134)
+	assert.Equal(t, []byte{0x34, 0x08, 0x15, 0x0b, 'A'}, data[0:5])
+	assert.Equal(t, uint8(0xFF), data[4+len(given.ModelID)])
+
+	result, err := PGN126996ToProductInfo(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 126996}, Data: data})
+	assert.NoError(t, err)
+	assert.Equal(t, given, result)
+}
+
 func TestPGN60928ToDeviceName(t *testing.T) {
 	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
 
@@ -276,6 +298,134 @@ func TestCreateISORequest(t *testing.T) {
 	}
 }
 
+func TestPGN126464ToPGNList(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		given       nmea.RawMessage
+		expect      PGNList
+		expectError string
+	}{
+		{
+			name: "ok, transmit list",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 23, Destination: 255},
+				Data: []byte{
+					0x00,             // function code: transmit
+					0x00, 0xee, 0x00, // 60928
+					0x14, 0xf0, 0x01, // 126996
+					0x01, 0xf8, 0x01, // 129025
+				},
+			},
+			expect: PGNList{FunctionCode: PGNListFunctionTransmit, PGNs: []uint32{60928, 126996, 129025}},
+		},
+		{
+			name: "ok, receive list with padding",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 23, Destination: 255},
+				Data:   []byte{0x01, 0x00, 0xea, 0x00, 0xff, 0xff, 0xff, 0xff},
+			},
+			expect: PGNList{FunctionCode: PGNListFunctionReceive, PGNs: []uint32{59904}},
+		},
+		{
+			name: "nok, unknown function code",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126464},
+				Data:   []byte{0x02, 0x00, 0xea, 0x00},
+			},
+			expectError: "pgn list has unknown function code: 2",
+		},
+		{
+			name: "nok, no data",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126464},
+				Data:   []byte{},
+			},
+			expectError: "rawMessage has invalid length to be pgn list",
+		},
+		{
+			name: "nok, invalid PGN",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126996},
+				Data:   []byte{0x00},
+			},
+			expectError: "pgn list can only be created from rawMessage with PGN 126464",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := PGN126464ToPGNList(tc.given)
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAddressMapper_NodesSupportingPGN(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	m := NewAddressMapper(nil)
+	m.now = func() time.Time {
+		return now
+	}
+
+	messages := []nmea.RawMessage{
+		{
+			Time:   now,
+			Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: 23, Destination: 255},
+			Data:   []byte{0x1e, 0x7d, 0x3e, 0xe8, 0x00, 0x87, 0x32, 0xc0},
+		},
+		{
+			Time:   now,
+			Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: 10, Destination: 255},
+			Data:   []byte{0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0},
+		},
+		{
+			Time:   now,
+			Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 23, Destination: 255},
+			Data:   []byte{0x00, 0x00, 0xee, 0x00, 0x01, 0xf8, 0x01},
+		},
+		{
+			Time:   now,
+			Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 10, Destination: 255},
+			Data:   []byte{0x01, 0x01, 0xf8, 0x01},
+		},
+		{ // PGN list from unknown node is ignored
+			Time:   now,
+			Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 30, Destination: 255},
+			Data:   []byte{0x00, 0x01, 0xf8, 0x01},
+		},
+	}
+	for _, msg := range messages {
+		_, err := m.Process(msg)
+		assert.NoError(t, err)
+	}
+
+	result := m.NodesSupportingPGN(129025)
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, uint8(10), result[0].Source)
+		assert.False(t, result[0].ValidTransmitPGNs)
+		assert.True(t, result[0].ValidReceivePGNs)
+		assert.Equal(t, []uint32{129025}, result[0].ReceivePGNs)
+
+		assert.Equal(t, uint8(23), result[1].Source)
+		assert.True(t, result[1].ValidTransmitPGNs)
+		assert.Equal(t, []uint32{60928, 129025}, result[1].TransmitPGNs)
+		assert.True(t, result[1].Transmits(60928))
+		assert.False(t, result[1].Receives(60928))
+	}
+
+	result = m.NodesSupportingPGN(60928)
+	if assert.Len(t, result, 1) {
+		assert.Equal(t, uint8(23), result[0].Source)
+	}
+
+	assert.Len(t, m.NodesSupportingPGN(127250), 0)
+}
+
 func TestQueue(t *testing.T) {
 	q := newQueue[int](5)
 
@@ -0,0 +1,102 @@
+package addressmapper
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+)
+
+var (
+	// ErrInvalidAddress is returned when node is commanded to address that can not be claimed (254 or 255)
+	ErrInvalidAddress = errors.New("address mapper: invalid address to command")
+	// ErrNoWriter is returned when message needs to be sent but AddressMapper has no writer
+	ErrNoWriter = errors.New("address mapper: no writer to send message")
+)
+
+// CommandedAddress is ISO Commanded Address (PGN 65240) that commands node with given NAME to claim new address. Message
+// is 9 bytes and is sent with ISO transport protocol (BAM).
+type CommandedAddress struct {
+	// NAME is NAME of commanded node in same form as Node.NAME
+	NAME uint64
+	// NewAddress is source address node must claim
+	NewAddress uint8
+}
+
+// PGN65240ToCommandedAddress converts raw message to CommandedAddress
+func PGN65240ToCommandedAddress(raw nmea.RawMessage) (CommandedAddress, error) {
+	if raw.Header.PGN != uint32(nmea.PGNISOCommandedAddress) {
+		return CommandedAddress{}, errors.New("commanded address can only be created from rawMessage with PGN 65240")
+	}
+	if len(raw.Data) != 9 {
+		return CommandedAddress{}, errors.New("rawMessage has invalid length to be commanded address")
+	}
+	return CommandedAddress{
+		NAME:       binary.LittleEndian.Uint64(raw.Data),
+		NewAddress: raw.Data[8],
+	}, nil
+}
+
+// Bytes encodes commanded address to PGN 65240 data (9 bytes)
+func (c CommandedAddress) Bytes() []byte {
+	b := make([]byte, 9)
+	binary.LittleEndian.PutUint64(b, c.NAME)
+	b[8] = c.NewAddress
+	return b
+}
+
+// CreateCommandedAddress creates ISO Commanded Address (PGN 65240) message commanding node with given NAME to new
+// address. Message is broadcast as J1939-81 requires.
+func CreateCommandedAddress(NAME uint64, newAddress uint8, source uint8) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{
+			PGN:         uint32(nmea.PGNISOCommandedAddress),
+			Priority:    6,
+			Source:      source,
+			Destination: nmea.AddressGlobal,
+		},
+		Data: CommandedAddress{NAME: NAME, NewAddress: newAddress}.Bytes(),
+	}
+}
+
+// CommandAddress sends ISO Commanded Address (PGN 65240) to command node with given NAME to claim new address. Message
+// is written immediately (not throttled by Run) with AddressNull as source. Wrap writer with ClaimedAddressWriter
+// when devices require commands from node with claimed address. Node table is updated when commanded node claims
+// new address.
+func (m *AddressMapper) CommandAddress(ctx context.Context, NAME uint64, newAddress uint8) error {
+	if newAddress >= nmea.AddressNull {
+		return ErrInvalidAddress
+	}
+	if m.nmeaDevice == nil {
+		return ErrNoWriter
+	}
+	msg := CreateCommandedAddress(NAME, newAddress, nmea.AddressNull)
+	msg.Time = m.now()
+	if err := m.nmeaDevice.WriteRawMessage(ctx, msg); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.commandedAddresses[NAME] = newAddress
+	m.mutex.Unlock()
+	return nil
+}
+
+// processCommandedAddress remembers commanded address so claim from commanded node takes over new address even when
+// address is assigned to node with lower NAME in table (i.e. device that was removed from bus). When writing is
+// enabled address claim is requested from new address to confirm address change.
+func (m *AddressMapper) processCommandedAddress(raw nmea.RawMessage) error {
+	cmd, err := PGN65240ToCommandedAddress(raw)
+	if err != nil {
+		return err
+	}
+	if cmd.NewAddress >= nmea.AddressNull {
+		return nil
+	}
+	m.commandedAddresses[cmd.NAME] = cmd.NewAddress
+
+	if m.writeEnabled {
+		m.requestsChan <- createISORequest(nmea.PGNISOAddressClaim, cmd.NewAddress)
+	}
+	return nil
+}
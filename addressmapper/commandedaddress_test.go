@@ -0,0 +1,155 @@
+package addressmapper
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPGN65240ToCommandedAddress(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		given       nmea.RawMessage
+		expect      CommandedAddress
+		expectError string
+	}{
+		{
+			name: "ok",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 65240, Priority: 6, Source: 0, Destination: 255},
+				Data:   []byte{0x1e, 0x7d, 0x3e, 0xe8, 0x00, 0x87, 0x32, 0xc0, 0x2a},
+			},
+			expect: CommandedAddress{NAME: 0xc0328700e83e7d1e, NewAddress: 42},
+		},
+		{
+			name: "nok, invalid PGN",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 60928},
+				Data:   []byte{0x1e, 0x7d, 0x3e, 0xe8, 0x00, 0x87, 0x32, 0xc0, 0x2a},
+			},
+			expectError: "commanded address can only be created from rawMessage with PGN 65240",
+		},
+		{
+			name: "nok, invalid length",
+			given: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 65240},
+				Data:   []byte{0x1e, 0x7d, 0x3e, 0xe8, 0x00, 0x87, 0x32, 0xc0},
+			},
+			expectError: "rawMessage has invalid length to be commanded address",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := PGN65240ToCommandedAddress(tc.given)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateCommandedAddress(t *testing.T) {
+	result := CreateCommandedAddress(0xc0328700e83e7d1e, 42, 100)
+
+	expect := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 65240, Priority: 6, Source: 100, Destination: 255},
+		Data:   []byte{0x1e, 0x7d, 0x3e, 0xe8, 0x00, 0x87, 0x32, 0xc0, 0x2a},
+	}
+	assert.Equal(t, expect, result)
+}
+
+func TestAddressMapper_CommandAddress(t *testing.T) {
+	w := &testWriter{}
+	m := newTestMapper(Config{})
+	m.nmeaDevice = w
+
+	// address 23 is used by node with lower NAME that has been removed from bus
+	_, err := m.Process(claimSource23)
+	assert.NoError(t, err)
+	_, err = m.Process(claimSource10)
+	assert.NoError(t, err)
+
+	err = m.CommandAddress(context.Background(), 0xc064a0002222ad99, 23)
+	assert.NoError(t, err)
+	if assert.Len(t, w.messages, 1) {
+		assert.Equal(t, nmea.RawData{0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0, 0x17}, w.messages[0].Data)
+		assert.Equal(t, nmea.AddressNull, w.messages[0].Header.Source)
+	}
+
+	claim := claimSource10
+	claim.Header.Source = 23
+	isChanged, err := m.Process(claim)
+	assert.NoError(t, err)
+	assert.True(t, isChanged)
+
+	table := m.Table()
+	if assert.Len(t, table.Nodes, 2) {
+		assert.Equal(t, nmea.AddressNull, table.Nodes[0].Source)
+		assert.Equal(t, uint8(23), table.Nodes[1].Source)
+	}
+}
+
+func TestAddressMapper_CommandAddress_errors(t *testing.T) {
+	m := newTestMapper(Config{})
+	assert.ErrorIs(t, m.CommandAddress(context.Background(), 1, 10), ErrNoWriter)
+
+	m.nmeaDevice = &testWriter{}
+	assert.ErrorIs(t, m.CommandAddress(context.Background(), 1, nmea.AddressNull), ErrInvalidAddress)
+}
+
+func TestAddressMapper_Process_commandedAddress(t *testing.T) {
+	m := newTestMapper(Config{})
+	m.writeEnabled = true
+
+	_, err := m.Process(claimSource23)
+	assert.NoError(t, err)
+
+	// without command node with higher NAME can not take over address
+	claim := claimSource10
+	claim.Header.Source = 23
+	isChanged, err := m.Process(claim)
+	assert.NoError(t, err)
+	assert.False(t, isChanged)
+
+	_, err = m.Process(CreateCommandedAddress(0xc064a0002222ad99, 23, 0))
+	assert.NoError(t, err)
+	if assert.Len(t, m.requestsChan, 1) {
+		assert.Equal(t, createISORequest(nmea.PGNISOAddressClaim, 23), <-m.requestsChan)
+	}
+
+	isChanged, err = m.Process(claim)
+	assert.NoError(t, err)
+	assert.True(t, isChanged)
+	assert.Equal(t, uint64(0xc064a0002222ad99), m.NodesInUseBySource()[23].NAME)
+}
+
+func TestAddressClaimer_Process_commandedAddress(t *testing.T) {
+	c, w, now := newTestClaimer(t, testClaimerName, 100)
+	// command for other node is ignored
+	err := c.Process(context.Background(), CreateCommandedAddress(0xc0328700e83e7d1e, 50, 0))
+	assert.NoError(t, err)
+	assert.Len(t, w.messages, 1)
+
+	cmd := CreateCommandedAddress(0, 50, 0)
+	cmd.Data = append(nmea.RawData(testClaimerName.Bytes()), 50)
+	err = c.Process(context.Background(), cmd)
+	assert.NoError(t, err)
+	if assert.Len(t, w.messages, 2) {
+		assert.Equal(t, nmea.RawMessage{
+			Time:   *now,
+			Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: 50, Destination: 255},
+			Data:   nmea.RawData(testClaimerName.Bytes()),
+		}, w.messages[1])
+	}
+
+	*now = now.Add(AddressClaimTimeout)
+	address, ok := c.Address()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(50), address)
+}
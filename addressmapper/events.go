@@ -0,0 +1,95 @@
+package addressmapper
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"time"
+)
+
+// NodeEventType describes how node state on bus changed
+type NodeEventType uint8
+
+const (
+	// NodeOnline is event when node claims address or packet is received from address of offline node
+	NodeOnline NodeEventType = iota
+	// NodeOffline is event when nothing has been received from node address within Config.OfflineTimeout
+	NodeOffline
+	// NodeAddressChanged is event when node claims different address than it had before
+	NodeAddressChanged
+)
+
+func (t NodeEventType) String() string {
+	switch t {
+	case NodeOnline:
+		return "online"
+	case NodeOffline:
+		return "offline"
+	case NodeAddressChanged:
+		return "address_changed"
+	}
+	return "unknown"
+}
+
+// NodeEvent is change of node state on bus
+type NodeEvent struct {
+	Type NodeEventType
+	Node Node
+	// PreviousSource is address node had before NodeAddressChanged event. For other events it is nmea.AddressNull
+	PreviousSource uint8
+	Time           time.Time
+}
+
+func (m *AddressMapper) addEvent(eventType NodeEventType, node *Node, previousSource uint8) {
+	if m.config.OnNodeEvent == nil {
+		return
+	}
+	m.events = append(m.events, NodeEvent{
+		Type:           eventType,
+		Node:           *node,
+		PreviousSource: previousSource,
+		Time:           m.now(),
+	})
+}
+
+func (m *AddressMapper) notify(events []NodeEvent) {
+	for _, e := range events {
+		m.config.OnNodeEvent(e)
+	}
+}
+
+// DetectOffline marks nodes offline that have not sent anything within Config.OfflineTimeout and reports NodeOffline
+// events. Run calls DetectOffline every second, applications that do not use Run can call it periodically themselves.
+func (m *AddressMapper) DetectOffline() {
+	m.mutex.Lock()
+	now := m.now()
+	for source, slot := range m.address2node {
+		if slot == nil || slot.node == nil || slot.isOffline || slot.node.Source != uint8(source) {
+			continue
+		}
+		if now.Sub(slot.lastSeen) <= m.config.OfflineTimeout {
+			continue
+		}
+		slot.isOffline = true
+		m.addEvent(NodeOffline, slot.node, nmea.AddressNull)
+	}
+	events := m.events
+	m.events = nil
+	m.mutex.Unlock()
+
+	m.notify(events)
+}
+
+// IsOnline checks if node with given NAME has claimed address and has sent something within Config.OfflineTimeout
+func (m *AddressMapper) IsOnline(NAME uint64) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	node, ok := m.knownNodes[NAME]
+	if !ok || node.Source >= nmea.AddressNull {
+		return false
+	}
+	slot := m.address2node[node.Source]
+	if slot == nil || slot.node != node || slot.isOffline {
+		return false
+	}
+	return m.now().Sub(slot.lastSeen) <= m.config.OfflineTimeout
+}
@@ -0,0 +1,122 @@
+package addressmapper
+
+import (
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func heartbeat(source uint8) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 126993, Priority: 7, Source: source, Destination: 255},
+		Data:   []byte{0x60, 0xea, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+}
+
+type eventRecord struct {
+	Type           NodeEventType
+	Source         uint8
+	PreviousSource uint8
+}
+
+func newEventsTestMapper() (*AddressMapper, *time.Time, *[]eventRecord) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	events := make([]eventRecord, 0)
+
+	m := NewAddressMapperWithConfig(nil, Config{
+		OfflineTimeout: 10 * time.Second,
+		OnNodeEvent: func(event NodeEvent) {
+			events = append(events, eventRecord{
+				Type:           event.Type,
+				Source:         event.Node.Source,
+				PreviousSource: event.PreviousSource,
+			})
+		},
+	})
+	m.now = func() time.Time {
+		return now
+	}
+	return m, &now, &events
+}
+
+func TestAddressMapper_nodeEvents(t *testing.T) {
+	m, now, events := newEventsTestMapper()
+	const NAME = uint64(0xc0328700e83e7d1e)
+
+	_, err := m.Process(claimSource23)
+	assert.NoError(t, err)
+	_, err = m.Process(claimSource23) // repeated claim is not new event
+	assert.NoError(t, err)
+	assert.Equal(t, []eventRecord{{Type: NodeOnline, Source: 23, PreviousSource: nmea.AddressNull}}, *events)
+	assert.True(t, m.IsOnline(NAME))
+
+	*now = now.Add(9 * time.Second)
+	_, err = m.Process(heartbeat(23))
+	assert.NoError(t, err)
+	*now = now.Add(10 * time.Second)
+	m.DetectOffline()
+	assert.Len(t, *events, 1)
+
+	*now = now.Add(1 * time.Second)
+	assert.False(t, m.IsOnline(NAME))
+	m.DetectOffline()
+	m.DetectOffline() // offline is reported once
+	assert.Equal(t, eventRecord{Type: NodeOffline, Source: 23, PreviousSource: nmea.AddressNull}, (*events)[1])
+
+	_, err = m.Process(heartbeat(23))
+	assert.NoError(t, err)
+	assert.Equal(t, eventRecord{Type: NodeOnline, Source: 23, PreviousSource: nmea.AddressNull}, (*events)[2])
+	assert.True(t, m.IsOnline(NAME))
+
+	claim := claimSource23
+	claim.Header.Source = 30
+	isChanged, err := m.Process(claim)
+	assert.NoError(t, err)
+	assert.True(t, isChanged)
+	assert.Equal(t, eventRecord{Type: NodeAddressChanged, Source: 30, PreviousSource: 23}, (*events)[3])
+	assert.Len(t, *events, 4)
+
+	// previous address is not owned by node anymore, so it does not go offline there
+	*now = now.Add(5 * time.Second)
+	_, err = m.Process(heartbeat(30))
+	assert.NoError(t, err)
+	*now = now.Add(8 * time.Second)
+	m.DetectOffline()
+	assert.Len(t, *events, 4)
+	assert.True(t, m.IsOnline(NAME))
+}
+
+func TestAddressMapper_nodeEvents_restoredNode(t *testing.T) {
+	saved := newTestMapper(Config{})
+	_, err := saved.Process(claimSource10)
+	assert.NoError(t, err)
+	table := saved.Table()
+
+	m, _, events := newEventsTestMapper()
+	m.knownNodes = map[uint64]*Node{}
+	for _, n := range table.Nodes {
+		node := n
+		m.knownNodes[node.NAME] = &node
+		m.address2node[node.Source] = &busSlot{node: &node, isRestored: true, isOffline: true}
+	}
+	assert.False(t, m.IsOnline(0xc064a0002222ad99))
+
+	m.DetectOffline() // restored nodes have never been online
+	assert.Len(t, *events, 0)
+
+	_, err = m.Process(heartbeat(10))
+	assert.NoError(t, err)
+	_, err = m.Process(claimSource10)
+	assert.NoError(t, err)
+	assert.Equal(t, []eventRecord{{Type: NodeOnline, Source: 10, PreviousSource: nmea.AddressNull}}, *events)
+	assert.True(t, m.IsOnline(0xc064a0002222ad99))
+}
+
+func TestNodeEventType_String(t *testing.T) {
+	assert.Equal(t, "online", NodeOnline.String())
+	assert.Equal(t, "offline", NodeOffline.String())
+	assert.Equal(t, "address_changed", NodeAddressChanged.String())
+	assert.Equal(t, "unknown", NodeEventType(99).String())
+}
@@ -0,0 +1,152 @@
+package addressmapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SourceClaim is record of node (NAME) claiming source address on the bus
+type SourceClaim struct {
+	NAME   uint64    `json:"name"`
+	Source uint8     `json:"source"`
+	Time   time.Time `json:"time"`
+}
+
+// NodeTable is persisted state of AddressMapper
+type NodeTable struct {
+	SavedAt time.Time `json:"saved_at"`
+	// Nodes contains all known nodes ordered by NAME
+	Nodes Nodes `json:"nodes"`
+	// History contains address claims in order they were processed
+	History []SourceClaim `json:"history"`
+}
+
+func (m *AddressMapper) addHistory(claim SourceClaim) {
+	m.history = append(m.history, claim)
+	if over := len(m.history) - m.config.HistorySize; over > 0 {
+		m.history = append(m.history[:0], m.history[over:]...)
+	}
+}
+
+// History returns NAME to source address claims in order they were processed
+func (m *AddressMapper) History() []SourceClaim {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]SourceClaim(nil), m.history...)
+}
+
+// Table returns snapshot of known nodes and address claim history
+func (m *AddressMapper) Table() NodeTable {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.table()
+}
+
+func (m *AddressMapper) table() NodeTable {
+	nodes := make(Nodes, 0, len(m.knownNodes))
+	for _, n := range m.knownNodes {
+		nodes = append(nodes, *n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NAME < nodes[j].NAME
+	})
+	return NodeTable{
+		SavedAt: m.now(),
+		Nodes:   nodes,
+		History: append([]SourceClaim(nil), m.history...),
+	}
+}
+
+// Save writes known nodes and address claim history as JSON to writer
+func (m *AddressMapper) Save(w io.Writer) error {
+	m.mutex.Lock()
+	table := m.table()
+	m.isChanged = false
+	m.mutex.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(table)
+}
+
+// Load reads nodes and address claim history saved with Save. Loaded nodes are added to known nodes (nodes already
+// known take precedence) and nodes that had source address assigned are restored to that address, so restarted
+// application knows device NAMEs before next address claim cycle. Restored address is taken over by first address
+// claim from that address.
+func (m *AddressMapper) Load(r io.Reader) error {
+	table := NodeTable{}
+	if err := json.NewDecoder(r).Decode(&table); err != nil {
+		return fmt.Errorf("address mapper failed to decode node table: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, n := range table.Nodes {
+		if _, ok := m.knownNodes[n.NAME]; ok {
+			continue
+		}
+		node := n
+		m.knownNodes[node.NAME] = &node
+		if node.Source >= nmea.AddressNull || m.address2node[node.Source] != nil {
+			continue
+		}
+		// restored node is reported online when first packet from its address is processed
+		m.address2node[node.Source] = &busSlot{node: &node, isRestored: true, isOffline: true}
+	}
+	history := append(table.History, m.history...)
+	m.history = nil
+	for _, c := range history {
+		m.addHistory(c)
+	}
+	return nil
+}
+
+// SaveFile saves node table to file (see Save). File is replaced atomically.
+func (m *AddressMapper) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := m.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile loads node table from file saved with SaveFile (see Load). Returns error matching os.ErrNotExist when file
+// does not exist.
+func (m *AddressMapper) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Load(f)
+}
+
+func (m *AddressMapper) autoSave() {
+	m.mutex.Lock()
+	isChanged := m.isChanged
+	m.mutex.Unlock()
+	if !isChanged {
+		return
+	}
+	if err := m.SaveFile(m.config.AutoSavePath); err != nil {
+		m.config.Logger.Warn("address mapper failed to save node table", "path", m.config.AutoSavePath, "err", err)
+	}
+}
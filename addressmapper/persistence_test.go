@@ -0,0 +1,152 @@
+package addressmapper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestMapper(config Config) *AddressMapper {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	m := NewAddressMapperWithConfig(nil, config)
+	m.now = func() time.Time {
+		return now
+	}
+	return m
+}
+
+var (
+	claimSource23 = nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: 23, Destination: 255},
+		Data:   []byte{0x1e, 0x7d, 0x3e, 0xe8, 0x00, 0x87, 0x32, 0xc0},
+	}
+	claimSource10 = nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 60928, Priority: 6, Source: 10, Destination: 255},
+		Data:   []byte{0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0},
+	}
+)
+
+func TestAddressMapper_SaveLoad(t *testing.T) {
+	m := newTestMapper(Config{})
+	for _, msg := range []nmea.RawMessage{claimSource23, claimSource10} {
+		_, err := m.Process(msg)
+		assert.NoError(t, err)
+	}
+
+	buf := bytes.Buffer{}
+	err := m.Save(&buf)
+	assert.NoError(t, err)
+
+	restored := newTestMapper(Config{})
+	err = restored.Load(&buf)
+	assert.NoError(t, err)
+
+	assert.Equal(t, m.Table(), restored.Table())
+	inUse := restored.NodesInUseBySource()
+	assert.Len(t, inUse, 2)
+	assert.Equal(t, uint64(0xc0328700e83e7d1e), inUse[23].NAME)
+	assert.Equal(t, uint8(10), inUse[10].Source)
+
+	expectHistory := []SourceClaim{
+		{NAME: 0xc0328700e83e7d1e, Source: 23, Time: test_test.UTCTime(1665488842)},
+		{NAME: 0xc064a0002222ad99, Source: 10, Time: test_test.UTCTime(1665488842)},
+	}
+	assert.Equal(t, expectHistory, restored.History())
+}
+
+func TestAddressMapper_Load_restoredSlotIsTakenOver(t *testing.T) {
+	m := newTestMapper(Config{})
+	_, err := m.Process(claimSource10)
+	assert.NoError(t, err)
+	buf := bytes.Buffer{}
+	assert.NoError(t, m.Save(&buf))
+
+	restored := newTestMapper(Config{})
+	assert.NoError(t, restored.Load(&buf))
+
+	// node with higher NAME claims restored address. normally lower NAME keeps its address but restored owner may
+	// not be on bus anymore.
+	claim := claimSource23
+	claim.Header.Source = 10
+	isChanged, err := restored.Process(claim)
+	assert.NoError(t, err)
+	assert.True(t, isChanged)
+
+	inUse := restored.NodesInUseBySource()
+	assert.Equal(t, uint64(0xc0328700e83e7d1e), inUse[10].NAME)
+	table := restored.Table()
+	if assert.Len(t, table.Nodes, 2) {
+		assert.Equal(t, uint64(0xc0328700e83e7d1e), table.Nodes[0].NAME)
+		assert.Equal(t, uint8(10), table.Nodes[0].Source)
+		assert.Equal(t, uint64(0xc064a0002222ad99), table.Nodes[1].NAME)
+		assert.Equal(t, nmea.AddressNull, table.Nodes[1].Source)
+	}
+	assert.Len(t, restored.History(), 2)
+
+	// after take over, slot follows normal address claim rules
+	isChanged, err = restored.Process(claimSource10)
+	assert.NoError(t, err)
+	assert.False(t, isChanged)
+}
+
+func TestAddressMapper_Load_invalidJSON(t *testing.T) {
+	m := newTestMapper(Config{})
+	err := m.Load(bytes.NewBufferString("{"))
+	assert.EqualError(t, err, "address mapper failed to decode node table: unexpected EOF")
+}
+
+func TestAddressMapper_SaveFileLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.json")
+
+	m := newTestMapper(Config{})
+	err := m.LoadFile(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = m.Process(claimSource23)
+	assert.NoError(t, err)
+	assert.NoError(t, m.SaveFile(path))
+
+	restored := newTestMapper(Config{})
+	assert.NoError(t, restored.LoadFile(path))
+	assert.Equal(t, m.Nodes(), restored.Nodes())
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestAddressMapper_History_limitedBySize(t *testing.T) {
+	m := newTestMapper(Config{HistorySize: 1})
+	for _, msg := range []nmea.RawMessage{claimSource23, claimSource10} {
+		_, err := m.Process(msg)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []SourceClaim{
+		{NAME: 0xc064a0002222ad99, Source: 10, Time: test_test.UTCTime(1665488842)},
+	}, m.History())
+}
+
+func TestAddressMapper_Run_autoSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	m := newTestMapper(Config{AutoSavePath: path, AutoSaveInterval: 5 * time.Millisecond})
+
+	_, err := m.Process(claimSource23)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = m.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	restored := newTestMapper(Config{})
+	assert.NoError(t, restored.LoadFile(path))
+	assert.Len(t, restored.Nodes(), 1)
+}
@@ -0,0 +1,163 @@
+package addressmapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"sync"
+	"time"
+)
+
+// pgnISOAcknowledgement is PGN 59392 ISO Acknowledgement that node sends as negative response to ISO request
+const pgnISOAcknowledgement = uint32(59392) // 0xE800
+
+var (
+	// ErrRequestTimeout is returned when node did not respond to request within timeout
+	ErrRequestTimeout = errors.New("request timed out")
+	// ErrRequestNotAcknowledged is returned when node responded to request with ISO Acknowledgement (PGN 59392) NACK,
+	// access denied or cannot respond
+	ErrRequestNotAcknowledged = errors.New("request was not acknowledged")
+)
+
+// RequesterConfig configures how Requester instance behaves
+type RequesterConfig struct {
+	// Timeout is time waited for response after request has been sent. Timeout is also limited by request context.
+	// Defaults to: 2 seconds
+	Timeout time.Duration
+}
+
+type response struct {
+	msg nmea.RawMessage
+	err error
+}
+
+type requestWaiter struct {
+	pgn    uint32
+	source uint8
+	result chan response
+}
+
+// Requester sends ISO requests (PGN 59904) and waits for matching responses. Messages read from bus must be passed to
+// Process so responses can be routed to waiting requests. Requests are sent with AddressNull as source, wrap writer
+// with ClaimedAddressWriter when nodes respond only to nodes with claimed address.
+//
+// Requester is safe for concurrent use.
+type Requester struct {
+	mutex   sync.Mutex
+	writer  nmea.RawMessageWriter
+	config  RequesterConfig
+	waiters []*requestWaiter
+}
+
+// NewRequester creates new instance of Requester with default configuration
+func NewRequester(writer nmea.RawMessageWriter) *Requester {
+	return NewRequesterWithConfig(writer, RequesterConfig{})
+}
+
+// NewRequesterWithConfig creates new instance of Requester with given configuration
+func NewRequesterWithConfig(writer nmea.RawMessageWriter, config RequesterConfig) *Requester {
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Second
+	}
+	return &Requester{
+		writer: writer,
+		config: config,
+	}
+}
+
+// Process routes message to request waiting for it. Returns true when message was response to waiting request.
+func (r *Requester) Process(raw nmea.RawMessage) bool {
+	res := response{msg: raw}
+	pgn := raw.Header.PGN
+	if pgn == pgnISOAcknowledgement {
+		if len(raw.Data) < 8 || raw.Data[0] == 0 { // control 0 is positive acknowledgement
+			return false
+		}
+		pgn = uint32(raw.Data[5]) | uint32(raw.Data[6])<<8 | uint32(raw.Data[7])<<16
+		res.err = fmt.Errorf("%w, PGN: %v, source: %v, control: %v", ErrRequestNotAcknowledged, pgn, raw.Header.Source, raw.Data[0])
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	isDelivered := false
+	remaining := r.waiters[:0]
+	for _, w := range r.waiters {
+		if w.pgn != pgn || (w.source != nmea.AddressGlobal && w.source != raw.Header.Source) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.result <- res // buffered, every waiter receives only one response
+		isDelivered = true
+	}
+	for i := len(remaining); i < len(r.waiters); i++ {
+		r.waiters[i] = nil
+	}
+	r.waiters = remaining
+	return isDelivered
+}
+
+// Request sends ISO request for PGN to destination and waits for response. When destination is AddressGlobal first
+// response from any node is returned.
+func (r *Requester) Request(ctx context.Context, pgn nmea.PGN, destination uint8) (nmea.RawMessage, error) {
+	w := &requestWaiter{pgn: uint32(pgn), source: destination, result: make(chan response, 1)}
+	r.mutex.Lock()
+	r.waiters = append(r.waiters, w) // registered before sending so fast response is not missed
+	r.mutex.Unlock()
+	defer r.removeWaiter(w)
+
+	if err := r.writer.WriteRawMessage(ctx, createISORequest(pgn, destination)); err != nil {
+		return nmea.RawMessage{}, err
+	}
+
+	timer := time.NewTimer(r.config.Timeout)
+	defer timer.Stop()
+	select {
+	case res := <-w.result:
+		return res.msg, res.err
+	case <-timer.C:
+		return nmea.RawMessage{}, fmt.Errorf("%w, PGN: %v, destination: %v", ErrRequestTimeout, uint32(pgn), destination)
+	case <-ctx.Done():
+		return nmea.RawMessage{}, ctx.Err()
+	}
+}
+
+func (r *Requester) removeWaiter(waiter *requestWaiter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, w := range r.waiters {
+		if w == waiter {
+			r.waiters = append(r.waiters[:i], r.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// RequestProductInfo requests Product Info (PGN 126996) from node with given source address
+func (r *Requester) RequestProductInfo(ctx context.Context, source uint8) (ProductInfo, error) {
+	raw, err := r.Request(ctx, nmea.PGNProductInfo, source)
+	if err != nil {
+		return ProductInfo{}, err
+	}
+	return PGN126996ToProductInfo(raw)
+}
+
+// RequestConfigurationInformation requests Configuration Information (PGN 126998) from node with given source address
+func (r *Requester) RequestConfigurationInformation(ctx context.Context, source uint8) (ConfigurationInfo, error) {
+	raw, err := r.Request(ctx, nmea.PGNConfigurationInformation, source)
+	if err != nil {
+		return ConfigurationInfo{}, err
+	}
+	return PGN126998ToConfigurationInfo(raw)
+}
+
+// RequestAddressClaim requests ISO Address Claim (PGN 60928) from node with given source address
+func (r *Requester) RequestAddressClaim(ctx context.Context, source uint8) (NodeName, error) {
+	raw, err := r.Request(ctx, nmea.PGNISOAddressClaim, source)
+	if err != nil {
+		return NodeName{}, err
+	}
+	return PGN60928ToNodeName(raw)
+}
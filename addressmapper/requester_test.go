@@ -0,0 +1,159 @@
+package addressmapper
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// replyWriter responds to ISO requests by passing reply for requested PGN to requester
+type replyWriter struct {
+	requester *Requester
+	replies   map[uint32]nmea.RawMessage
+	err       error
+	written   []nmea.RawMessage
+}
+
+func (w *replyWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	w.written = append(w.written, msg)
+	if w.err != nil {
+		return w.err
+	}
+	requested := uint32(msg.Data[0]) | uint32(msg.Data[1])<<8 | uint32(msg.Data[2])<<16
+	if reply, ok := w.replies[requested]; ok {
+		w.requester.Process(reply)
+	}
+	return nil
+}
+
+func (w *replyWriter) Close() error {
+	return nil
+}
+
+func newTestRequester(replies ...nmea.RawMessage) (*Requester, *replyWriter) {
+	w := &replyWriter{replies: map[uint32]nmea.RawMessage{}}
+	for _, r := range replies {
+		pgn := r.Header.PGN
+		if pgn == pgnISOAcknowledgement {
+			pgn = uint32(r.Data[5]) | uint32(r.Data[6])<<8 | uint32(r.Data[7])<<16
+		}
+		w.replies[pgn] = r
+	}
+	r := NewRequesterWithConfig(w, RequesterConfig{Timeout: 20 * time.Millisecond})
+	w.requester = r
+	return r, w
+}
+
+func TestRequester_RequestAddressClaim(t *testing.T) {
+	r, w := newTestRequester(claimSource10, claimSource23)
+
+	name, err := r.RequestAddressClaim(context.Background(), 23)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1998110), name.UniqueNumber)
+	assert.Equal(t, []nmea.RawMessage{createISORequest(nmea.PGNISOAddressClaim, 23)}, w.written)
+	assert.Len(t, r.waiters, 0)
+}
+
+func TestRequester_RequestProductInfo(t *testing.T) {
+	data := ProductInfo{
+		NMEA2000Version:     2100,
+		ProductCode:         2837,
+		ModelID:             "AP70",
+		SoftwareVersionCode: "2.0",
+		CertificationLevel:  2,
+		LoadEquivalency:     1,
+	}.Bytes()
+	r, _ := newTestRequester(nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 126996, Priority: 6, Source: 51, Destination: 255},
+		Data:   data,
+	})
+
+	info, err := r.RequestProductInfo(context.Background(), 51)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2837), info.ProductCode)
+	assert.Equal(t, "AP70", info.ModelID)
+}
+
+func TestRequester_RequestConfigurationInformation(t *testing.T) {
+	r, _ := newTestRequester(nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 126998, Priority: 6, Source: 51, Destination: 255},
+		Data:   append([]byte{0x05, 0x01, 'A', 'B', 'C', 0x02, 0x01, 0x26, 0x01}, "Airmar 1-603-673-9570 www.airmar.com"...),
+	})
+
+	info, err := r.RequestConfigurationInformation(context.Background(), 51)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigurationInfo{
+		InstallationDesc1: "ABC",
+		ManufacturerInfo:  "Airmar 1-603-673-9570 www.airmar.com",
+	}, info)
+}
+
+func TestRequester_Request_errors(t *testing.T) {
+	t.Run("timeout, response from other node", func(t *testing.T) {
+		r, _ := newTestRequester(claimSource10)
+
+		_, err := r.RequestAddressClaim(context.Background(), 23)
+
+		assert.ErrorIs(t, err, ErrRequestTimeout)
+		assert.EqualError(t, err, "request timed out, PGN: 60928, destination: 23")
+		assert.Len(t, r.waiters, 0)
+	})
+
+	t.Run("NACK", func(t *testing.T) {
+		r, _ := newTestRequester(nmea.RawMessage{
+			Header: nmea.CanBusHeader{PGN: 59392, Priority: 6, Source: 51, Destination: 254},
+			Data:   []byte{0x01, 0xff, 0xff, 0xff, 0xff, 0x16, 0xf0, 0x01},
+		})
+
+		_, err := r.RequestConfigurationInformation(context.Background(), 51)
+
+		assert.ErrorIs(t, err, ErrRequestNotAcknowledged)
+		assert.EqualError(t, err, "request was not acknowledged, PGN: 126998, source: 51, control: 1")
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		r, w := newTestRequester()
+		w.err = errors.New("write failed")
+
+		_, err := r.RequestProductInfo(context.Background(), 51)
+
+		assert.EqualError(t, err, "write failed")
+		assert.Len(t, r.waiters, 0)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		r, _ := newTestRequester()
+		r.config.Timeout = time.Minute
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := r.RequestProductInfo(ctx, 51)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRequester_Process(t *testing.T) {
+	r := NewRequester(nil)
+	assert.False(t, r.Process(claimSource10))
+
+	global := &requestWaiter{pgn: 60928, source: nmea.AddressGlobal, result: make(chan response, 1)}
+	other := &requestWaiter{pgn: 60928, source: 23, result: make(chan response, 1)}
+	r.waiters = []*requestWaiter{global, other}
+
+	assert.True(t, r.Process(claimSource10))
+	assert.Equal(t, []*requestWaiter{other}, r.waiters)
+	assert.Equal(t, claimSource10, (<-global.result).msg)
+
+	// positive acknowledgement is not response
+	assert.False(t, r.Process(nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 59392, Source: 23},
+		Data:   []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0x00, 0xee, 0x00},
+	}))
+}
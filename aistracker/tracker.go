@@ -0,0 +1,346 @@
+// Package aistracker maintains table of AIS targets built from AIS PGNs. Dynamic (position reports) and static (name,
+// callsign, dimensions) data of same target are merged by MMSI, similar in spirit to addressmapper that does the same
+// for nodes on the bus.
+package aistracker
+
+import (
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PGNClassAPositionReport is PGN 129038 AIS Class A Position Report
+	PGNClassAPositionReport = uint32(129038)
+	// PGNClassBPositionReport is PGN 129039 AIS Class B Position Report
+	PGNClassBPositionReport = uint32(129039)
+	// PGNClassAStaticData is PGN 129794 AIS Class A Static and Voyage Related Data
+	PGNClassAStaticData = uint32(129794)
+	// PGNClassBStaticDataPartA is PGN 129809 AIS Class B static data (msg 24 Part A)
+	PGNClassBStaticDataPartA = uint32(129809)
+	// PGNClassBStaticDataPartB is PGN 129810 AIS Class B static data (msg 24 Part B)
+	PGNClassBStaticDataPartB = uint32(129810)
+)
+
+// ErrMissingMMSI is returned when AIS message does not have MMSI (userId field)
+var ErrMissingMMSI = errors.New("aistracker: message has no MMSI")
+
+// Class is AIS transponder class of target
+type Class string
+
+const (
+	// ClassA is AIS Class A transponder (commercial vessels)
+	ClassA Class = "A"
+	// ClassB is AIS Class B transponder (leisure vessels)
+	ClassB Class = "B"
+)
+
+// ChangeType describes how target in table changed
+type ChangeType uint8
+
+const (
+	// TargetAdded is change when message from target with new MMSI is processed
+	TargetAdded ChangeType = iota
+	// TargetUpdated is change when message from already known target is processed
+	TargetUpdated
+	// TargetExpired is change when target is removed from table as nothing has been received from it within expiry
+	TargetExpired
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case TargetAdded:
+		return "added"
+	case TargetUpdated:
+		return "updated"
+	case TargetExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// Target is AIS target with merged dynamic and static data. Values are in same (SI) units as canboat.Decoder outputs
+// them (degrees for position, radians for angles, m/s for speed, meters for dimensions). Fields that have not been
+// received (or were "no data") are nil or empty.
+type Target struct {
+	MMSI  uint32 `json:"mmsi"`
+	Class Class  `json:"class,omitempty"`
+
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+	COG        *float64 `json:"cog,omitempty"`
+	SOG        *float64 `json:"sog,omitempty"`
+	Heading    *float64 `json:"heading,omitempty"`
+	RateOfTurn *float64 `json:"rate_of_turn,omitempty"`
+	// NavStatus is NAV_STATUS lookup value (Class A only)
+	NavStatus *uint8 `json:"nav_status,omitempty"`
+
+	Name     string `json:"name,omitempty"`
+	Callsign string `json:"callsign,omitempty"`
+	// IMONumber is IMO ship identification number (Class A only)
+	IMONumber *uint32 `json:"imo_number,omitempty"`
+	// ShipType is SHIP_TYPE lookup value
+	ShipType    *uint8   `json:"ship_type,omitempty"`
+	Length      *float64 `json:"length,omitempty"`
+	Beam        *float64 `json:"beam,omitempty"`
+	Draft       *float64 `json:"draft,omitempty"`
+	Destination string   `json:"destination,omitempty"`
+	// VendorID is manufacturer ID of transponder (Class B only)
+	VendorID string `json:"vendor_id,omitempty"`
+
+	// Source is bus source address of AIS receiver that reported target last
+	Source uint8 `json:"source"`
+	// PositionUpdatedAt is time when last position report was processed
+	PositionUpdatedAt time.Time `json:"position_updated_at"`
+	// StaticUpdatedAt is time when last static data was processed
+	StaticUpdatedAt time.Time `json:"static_updated_at"`
+	// LastSeen is time when last message of target was processed
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Config configures Tracker instance
+type Config struct {
+	// Expiry is duration after which target is removed when no messages have been received from it. Class B static
+	// data is sent every 6 minutes and slow moving targets report position every 3 minutes.
+	// Defaults to: 10 minutes
+	Expiry time.Duration
+
+	// OnChange is called when target is added, updated or expired. OnChange is called outside of Tracker lock so
+	// Tracker methods can be called from callback.
+	// Optional
+	OnChange func(change ChangeType, target Target)
+}
+
+type change struct {
+	changeType ChangeType
+	target     Target
+}
+
+// Tracker maintains table of AIS targets keyed by MMSI from decoded AIS messages.
+//
+// Tracker is safe for concurrent use.
+type Tracker struct {
+	mutex  sync.Mutex
+	config Config
+
+	targets map[uint32]*Target
+
+	now func() time.Time
+}
+
+// NewTracker creates new instance of Tracker with default configuration
+func NewTracker() *Tracker {
+	return NewTrackerWithConfig(Config{})
+}
+
+// NewTrackerWithConfig creates new instance of Tracker with given configuration
+func NewTrackerWithConfig(config Config) *Tracker {
+	if config.Expiry <= 0 {
+		config.Expiry = 10 * time.Minute
+	}
+	return &Tracker{
+		config:  config,
+		targets: make(map[uint32]*Target),
+		now:     time.Now,
+	}
+}
+
+// IsAISPGN checks if PGN is one of the AIS PGNs Tracker processes
+func IsAISPGN(pgn uint32) bool {
+	switch pgn {
+	case PGNClassAPositionReport, PGNClassBPositionReport, PGNClassAStaticData, PGNClassBStaticDataPartA,
+		PGNClassBStaticDataPartB:
+		return true
+	}
+	return false
+}
+
+// Process updates target table from decoded AIS message. Returns false when message PGN is not AIS PGN processed by
+// Tracker. Expired targets are removed on every call.
+func (t *Tracker) Process(msg nmea.Message) (bool, error) {
+	if !IsAISPGN(msg.Header.PGN) {
+		return false, nil
+	}
+	fv, ok := msg.Fields.FindByID("userId")
+	if !ok {
+		return true, ErrMissingMMSI
+	}
+	mmsi, ok := fv.AsUint64()
+	if !ok || mmsi == 0 || mmsi > 999_999_999 {
+		return true, ErrMissingMMSI
+	}
+
+	t.mutex.Lock()
+	now := t.now()
+	changes := t.removeExpired(now)
+
+	target, ok := t.targets[uint32(mmsi)]
+	changeType := TargetUpdated
+	if !ok {
+		target = &Target{MMSI: uint32(mmsi)}
+		t.targets[uint32(mmsi)] = target
+		changeType = TargetAdded
+	}
+	target.Source = msg.Header.Source
+	target.LastSeen = now
+	applyMessage(target, msg, now)
+	changes = append(changes, change{changeType: changeType, target: *target})
+	t.mutex.Unlock()
+
+	t.notify(changes)
+	return true, nil
+}
+
+func applyMessage(target *Target, msg nmea.Message, now time.Time) {
+	r := fieldsReader{fields: msg.Fields}
+	switch msg.Header.PGN {
+	case PGNClassAPositionReport, PGNClassBPositionReport:
+		target.Class = ClassA
+		if msg.Header.PGN == PGNClassBPositionReport {
+			target.Class = ClassB
+		}
+		target.PositionUpdatedAt = now
+		target.Latitude = r.float64("latitude")
+		target.Longitude = r.float64("longitude")
+		target.COG = r.float64("cog")
+		target.SOG = r.float64("sog")
+		target.Heading = r.float64("heading")
+		if target.Class == ClassA {
+			target.RateOfTurn = r.float64("rateOfTurn")
+			target.NavStatus = r.uint8("navStatus")
+		}
+	case PGNClassAStaticData:
+		target.Class = ClassA
+		target.StaticUpdatedAt = now
+		target.IMONumber = r.uint32("imoNumber")
+		r.string("callsign", &target.Callsign)
+		r.string("name", &target.Name)
+		target.ShipType = r.uint8("typeOfShip")
+		target.Length = r.float64("length")
+		target.Beam = r.float64("beam")
+		target.Draft = r.float64("draft")
+		r.string("destination", &target.Destination)
+	case PGNClassBStaticDataPartA:
+		target.Class = ClassB
+		target.StaticUpdatedAt = now
+		r.string("name", &target.Name)
+	case PGNClassBStaticDataPartB:
+		target.Class = ClassB
+		target.StaticUpdatedAt = now
+		target.ShipType = r.uint8("typeOfShip")
+		r.string("vendorId", &target.VendorID)
+		r.string("callsign", &target.Callsign)
+		target.Length = r.float64("length")
+		target.Beam = r.float64("beam")
+	}
+}
+
+// Target returns target with given MMSI
+func (t *Tracker) Target(mmsi uint32) (Target, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	target, ok := t.targets[mmsi]
+	if !ok || t.now().Sub(target.LastSeen) > t.config.Expiry {
+		return Target{}, false
+	}
+	return *target, true
+}
+
+// Targets returns all targets that have not expired sorted by MMSI. Expired targets are removed.
+func (t *Tracker) Targets() []Target {
+	t.mutex.Lock()
+	changes := t.removeExpired(t.now())
+	result := make([]Target, 0, len(t.targets))
+	for _, target := range t.targets {
+		result = append(result, *target)
+	}
+	t.mutex.Unlock()
+
+	t.notify(changes)
+	sort.Slice(result, func(i, j int) bool { return result[i].MMSI < result[j].MMSI })
+	return result
+}
+
+func (t *Tracker) removeExpired(now time.Time) []change {
+	var changes []change
+	for mmsi, target := range t.targets {
+		if now.Sub(target.LastSeen) <= t.config.Expiry {
+			continue
+		}
+		delete(t.targets, mmsi)
+		changes = append(changes, change{changeType: TargetExpired, target: *target})
+	}
+	return changes
+}
+
+func (t *Tracker) notify(changes []change) {
+	if t.config.OnChange == nil {
+		return
+	}
+	for _, c := range changes {
+		t.config.OnChange(c.changeType, c.target)
+	}
+}
+
+// fieldsReader reads field values by ID. Fields that do not exist or have unexpected type are read as nil.
+type fieldsReader struct {
+	fields nmea.FieldValues
+}
+
+func (r fieldsReader) float64(ID string) *float64 {
+	fv, ok := r.fields.FindByID(ID)
+	if !ok {
+		return nil
+	}
+	switch fv.Value.(type) {
+	case float64, int64, uint64:
+		v, _ := fv.AsFloat64()
+		return &v
+	}
+	return nil
+}
+
+func (r fieldsReader) uint64(ID string, max uint64) *uint64 {
+	fv, ok := r.fields.FindByID(ID)
+	if !ok {
+		return nil
+	}
+	v, ok := fv.AsUint64()
+	if !ok || v > max {
+		return nil
+	}
+	return &v
+}
+
+func (r fieldsReader) uint8(ID string) *uint8 {
+	v := r.uint64(ID, 0xff)
+	if v == nil {
+		return nil
+	}
+	result := uint8(*v)
+	return &result
+}
+
+func (r fieldsReader) uint32(ID string) *uint32 {
+	v := r.uint64(ID, 0xffff_ffff)
+	if v == nil {
+		return nil
+	}
+	result := uint32(*v)
+	return &result
+}
+
+// string sets target string when field has value. AIS strings are padded with `@` or spaces.
+func (r fieldsReader) string(ID string, target *string) {
+	fv, ok := r.fields.FindByID(ID)
+	if !ok {
+		return
+	}
+	if s, ok := fv.Value.(string); ok {
+		*target = strings.TrimRight(s, " @")
+	}
+}
@@ -0,0 +1,242 @@
+package aistracker
+
+import (
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func float64p(v float64) *float64 { return &v }
+
+func uint8p(v uint8) *uint8 { return &v }
+
+func uint32p(v uint32) *uint32 { return &v }
+
+func TestTracker_Process(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var testCases = []struct {
+		name         string
+		given        []nmea.Message
+		when         nmea.Message
+		expect       []Target
+		expectResult bool
+		expectErr    string
+	}{
+		{
+			name: "ok, class A position and static data are merged",
+			given: []nmea.Message{
+				{
+					Header: nmea.CanBusHeader{PGN: PGNClassAStaticData, Source: 43},
+					Fields: nmea.FieldValues{
+						{ID: "userId", Value: uint64(276000001)},
+						{ID: "imoNumber", Value: uint64(9123456)},
+						{ID: "callsign", Value: "ESAB@@@"},
+						{ID: "name", Value: "SEA BREEZE   "},
+						{ID: "typeOfShip", Value: uint64(70)},
+						{ID: "length", Value: 120.5},
+						{ID: "beam", Value: 18.0},
+						{ID: "draft", Value: 6.2},
+						{ID: "destination", Value: "TALLINN@@@@"},
+					},
+				},
+			},
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: PGNClassAPositionReport, Source: 44},
+				Fields: nmea.FieldValues{
+					{ID: "userId", Value: uint64(276000001)},
+					{ID: "longitude", Value: 24.75},
+					{ID: "latitude", Value: 59.45},
+					{ID: "cog", Value: 1.5},
+					{ID: "sog", Value: 5.1},
+					{ID: "heading", Value: 1.52},
+					{ID: "rateOfTurn", Value: 0.001},
+					{ID: "navStatus", Value: nmea.EnumValue{Value: 0, Code: "Under way using engine"}},
+				},
+			},
+			expectResult: true,
+			expect: []Target{
+				{
+					MMSI:              276000001,
+					Class:             ClassA,
+					Latitude:          float64p(59.45),
+					Longitude:         float64p(24.75),
+					COG:               float64p(1.5),
+					SOG:               float64p(5.1),
+					Heading:           float64p(1.52),
+					RateOfTurn:        float64p(0.001),
+					NavStatus:         uint8p(0),
+					Name:              "SEA BREEZE",
+					Callsign:          "ESAB",
+					IMONumber:         uint32p(9123456),
+					ShipType:          uint8p(70),
+					Length:            float64p(120.5),
+					Beam:              float64p(18),
+					Draft:             float64p(6.2),
+					Destination:       "TALLINN",
+					Source:            44,
+					PositionUpdatedAt: now,
+					StaticUpdatedAt:   now,
+					LastSeen:          now,
+				},
+			},
+		},
+		{
+			name: "ok, class B static data parts are merged",
+			given: []nmea.Message{
+				{
+					Header: nmea.CanBusHeader{PGN: PGNClassBStaticDataPartA, Source: 43},
+					Fields: nmea.FieldValues{
+						{ID: "userId", Value: uint64(276000002)},
+						{ID: "name", Value: "LITTLE WING@@@@"},
+					},
+				},
+			},
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: PGNClassBStaticDataPartB, Source: 43},
+				Fields: nmea.FieldValues{
+					{ID: "userId", Value: uint64(276000002)},
+					{ID: "typeOfShip", Value: uint64(36)},
+					{ID: "vendorId", Value: "SRT"},
+					{ID: "callsign", Value: "ES1234"},
+					{ID: "length", Value: 9.0},
+					{ID: "beam", Value: 3.0},
+				},
+			},
+			expectResult: true,
+			expect: []Target{
+				{
+					MMSI:            276000002,
+					Class:           ClassB,
+					Name:            "LITTLE WING",
+					Callsign:        "ES1234",
+					ShipType:        uint8p(36),
+					Length:          float64p(9),
+					Beam:            float64p(3),
+					VendorID:        "SRT",
+					Source:          43,
+					StaticUpdatedAt: now,
+					LastSeen:        now,
+				},
+			},
+		},
+		{
+			name: "ok, position with no data fields",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: PGNClassBPositionReport, Source: 43},
+				Fields: nmea.FieldValues{
+					{ID: "userId", Value: uint64(276000003)},
+					{ID: "longitude", Value: 24.75},
+					{ID: "latitude", Value: 59.45},
+				},
+			},
+			expectResult: true,
+			expect: []Target{
+				{
+					MMSI:              276000003,
+					Class:             ClassB,
+					Latitude:          float64p(59.45),
+					Longitude:         float64p(24.75),
+					Source:            43,
+					PositionUpdatedAt: now,
+					LastSeen:          now,
+				},
+			},
+		},
+		{
+			name: "ok, not AIS PGN",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 129025, Source: 43},
+				Fields: nmea.FieldValues{{ID: "latitude", Value: 59.45}},
+			},
+			expectResult: false,
+			expect:       []Target{},
+		},
+		{
+			name: "nok, missing MMSI",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: PGNClassAPositionReport, Source: 43},
+				Fields: nmea.FieldValues{{ID: "latitude", Value: 59.45}},
+			},
+			expectResult: true,
+			expect:       []Target{},
+			expectErr:    "aistracker: message has no MMSI",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := NewTracker()
+			tracker.now = func() time.Time {
+				return now
+			}
+			for _, msg := range tc.given {
+				_, err := tracker.Process(msg)
+				assert.NoError(t, err)
+			}
+
+			ok, err := tracker.Process(tc.when)
+
+			assert.Equal(t, tc.expectResult, ok)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expect, tracker.Targets())
+		})
+	}
+}
+
+func TestTracker_Expiry(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	type event struct {
+		change ChangeType
+		mmsi   uint32
+	}
+	var events []event
+	tracker := NewTrackerWithConfig(Config{
+		Expiry: 1 * time.Minute,
+		OnChange: func(change ChangeType, target Target) {
+			events = append(events, event{change: change, mmsi: target.MMSI})
+		},
+	})
+	tracker.now = func() time.Time {
+		return now
+	}
+
+	position := func(mmsi uint64) nmea.Message {
+		return nmea.Message{
+			Header: nmea.CanBusHeader{PGN: PGNClassBPositionReport, Source: 43},
+			Fields: nmea.FieldValues{{ID: "userId", Value: mmsi}},
+		}
+	}
+	_, err := tracker.Process(position(276000001))
+	assert.NoError(t, err)
+	_, err = tracker.Process(position(276000002))
+	assert.NoError(t, err)
+
+	now = now.Add(50 * time.Second)
+	_, err = tracker.Process(position(276000002))
+	assert.NoError(t, err)
+
+	now = now.Add(20 * time.Second)
+	_, ok := tracker.Target(276000001)
+	assert.False(t, ok)
+	target, ok := tracker.Target(276000002)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(276000002), target.MMSI)
+
+	targets := tracker.Targets()
+	assert.Len(t, targets, 1)
+
+	assert.Equal(t, []event{
+		{change: TargetAdded, mmsi: 276000001},
+		{change: TargetAdded, mmsi: 276000002},
+		{change: TargetUpdated, mmsi: 276000002},
+		{change: TargetExpired, mmsi: 276000001},
+	}, events)
+}
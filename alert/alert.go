@@ -0,0 +1,252 @@
+// Package alert decodes NMEA2000 alert PGNs (126983 Alert, 126984 Alert Response, 126985 Alert Text) and tracks
+// active alerts with Manager.
+package alert
+
+import (
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"strings"
+)
+
+const (
+	// PGNAlert is PGN 126983 Alert
+	PGNAlert = uint32(126983)
+	// PGNAlertResponse is PGN 126984 Alert Response
+	PGNAlertResponse = uint32(126984)
+	// PGNAlertText is PGN 126985 Alert Text
+	PGNAlertText = uint32(126985)
+)
+
+// State is alert state (Canboat ALERT_STATE lookup)
+type State uint8
+
+const (
+	// StateDisabled is alert that is disabled
+	StateDisabled State = 0
+	// StateNormal is alert that is not active (condition has cleared)
+	StateNormal State = 1
+	// StateActive is active alert
+	StateActive State = 2
+	// StateSilenced is active alert that has been temporarily silenced
+	StateSilenced State = 3
+	// StateAcknowledged is active alert that has been acknowledged
+	StateAcknowledged State = 4
+	// StateAwaitingAcknowledge is alert whose condition has cleared but that has not been acknowledged yet
+	StateAwaitingAcknowledge State = 5
+)
+
+// IsActive checks if alert in this state is still active (needs attention or acknowledgement)
+func (s State) IsActive() bool {
+	return s != StateDisabled && s != StateNormal
+}
+
+func (s State) String() string {
+	switch s {
+	case StateDisabled:
+		return "Disabled"
+	case StateNormal:
+		return "Normal"
+	case StateActive:
+		return "Active"
+	case StateSilenced:
+		return "Silenced"
+	case StateAcknowledged:
+		return "Acknowledged"
+	case StateAwaitingAcknowledge:
+		return "Awaiting Acknowledge"
+	}
+	return fmt.Sprintf("State(%d)", uint8(s))
+}
+
+// ResponseCommand is command sent with Alert Response (Canboat ALERT_RESPONSE_COMMAND lookup)
+type ResponseCommand uint8
+
+const (
+	// ResponseAcknowledge acknowledges alert
+	ResponseAcknowledge ResponseCommand = 0
+	// ResponseTemporarySilence temporarily silences alert
+	ResponseTemporarySilence ResponseCommand = 1
+	// ResponseTestCommandOff ends alert test
+	ResponseTestCommandOff ResponseCommand = 2
+	// ResponseTestCommandOn starts alert test
+	ResponseTestCommandOn ResponseCommand = 3
+)
+
+// Key identifies single alert occurrence. Same alert (ID from same data source) gets new occurrence number every time
+// it is raised again.
+type Key struct {
+	// ID is alert ID unique within data source
+	ID uint16 `json:"id"`
+	// DataSourceNAME is ISO NAME of node that raised alert
+	DataSourceNAME uint64 `json:"data_source_name"`
+	// DataSourceInstance is instance of data source (i.e. engine instance)
+	DataSourceInstance uint8 `json:"data_source_instance"`
+	// DataSourceIndex is index of source within data source instance
+	DataSourceIndex uint8 `json:"data_source_index"`
+	// Occurrence is alert occurrence number
+	Occurrence uint8 `json:"occurrence"`
+}
+
+// Header contains fields that are common to all alert PGNs
+type Header struct {
+	Key
+	// Type is alert type (ALERT_TYPE lookup: 1 = Emergency Alarm, 2 = Alarm, 5 = Warning, 8 = Caution)
+	Type uint8 `json:"type"`
+	// Category is alert category (ALERT_CATEGORY lookup: 0 = Navigational, 1 = Technical)
+	Category  uint8 `json:"category"`
+	System    uint8 `json:"system"`
+	SubSystem uint8 `json:"sub_system"`
+}
+
+// Alert is PGN 126983 Alert
+type Alert struct {
+	Header
+	TemporarySilenceStatus  bool `json:"temporary_silence_status"`
+	AcknowledgeStatus       bool `json:"acknowledge_status"`
+	EscalationStatus        bool `json:"escalation_status"`
+	TemporarySilenceSupport bool `json:"temporary_silence_support"`
+	AcknowledgeSupport      bool `json:"acknowledge_support"`
+	EscalationSupport       bool `json:"escalation_support"`
+	// AcknowledgeSourceNAME is ISO NAME of node that acknowledged alert
+	AcknowledgeSourceNAME uint64 `json:"acknowledge_source_name"`
+	// TriggerCondition is ALERT_TRIGGER_CONDITION lookup (0 = Manual, 1 = Auto, 2 = Test, 3 = Disabled)
+	TriggerCondition uint8 `json:"trigger_condition"`
+	// ThresholdStatus is ALERT_THRESHOLD_STATUS lookup (0 = Normal, 1 = Threshold Exceeded, ...)
+	ThresholdStatus uint8 `json:"threshold_status"`
+	Priority        uint8 `json:"priority"`
+	State           State `json:"state"`
+}
+
+// Response is PGN 126984 Alert Response
+type Response struct {
+	Header
+	// AcknowledgeSourceNAME is ISO NAME of node that sent response
+	AcknowledgeSourceNAME uint64          `json:"acknowledge_source_name"`
+	Command               ResponseCommand `json:"command"`
+}
+
+// Text is PGN 126985 Alert Text
+type Text struct {
+	Header
+	LanguageID   uint8  `json:"language_id"`
+	Description  string `json:"description"`
+	LocationText string `json:"location_text"`
+}
+
+// ParseAlert converts decoded PGN 126983 message to Alert
+func ParseAlert(msg nmea.Message) (Alert, error) {
+	r := fieldsReader{fields: msg.Fields}
+	a := Alert{
+		Header:                  r.header(msg.Header.PGN, PGNAlert),
+		TemporarySilenceStatus:  r.bool("temporarySilenceStatus"),
+		AcknowledgeStatus:       r.bool("acknowledgeStatus"),
+		EscalationStatus:        r.bool("escalationStatus"),
+		TemporarySilenceSupport: r.bool("temporarySilenceSupport"),
+		AcknowledgeSupport:      r.bool("acknowledgeSupport"),
+		EscalationSupport:       r.bool("escalationSupport"),
+		AcknowledgeSourceNAME:   r.uint("acknowledgeSourceNetworkIdName", false),
+		TriggerCondition:        uint8(r.uint("triggerCondition", false)),
+		ThresholdStatus:         uint8(r.uint("thresholdStatus", false)),
+		Priority:                uint8(r.uint("alertPriority", false)),
+		State:                   State(r.uint("alertState", true)),
+	}
+	return a, r.err
+}
+
+// ParseResponse converts decoded PGN 126984 message to Response
+func ParseResponse(msg nmea.Message) (Response, error) {
+	r := fieldsReader{fields: msg.Fields}
+	resp := Response{
+		Header:                r.header(msg.Header.PGN, PGNAlertResponse),
+		AcknowledgeSourceNAME: r.uint("acknowledgeSourceNetworkIdName", false),
+		Command:               ResponseCommand(r.uint("responseCommand", true)),
+	}
+	return resp, r.err
+}
+
+// ParseText converts decoded PGN 126985 message to Text
+func ParseText(msg nmea.Message) (Text, error) {
+	r := fieldsReader{fields: msg.Fields}
+	t := Text{
+		Header:       r.header(msg.Header.PGN, PGNAlertText),
+		LanguageID:   uint8(r.uint("languageId", false)),
+		Description:  r.string("alertTextDescription"),
+		LocationText: r.string("alertLocationTextDescription"),
+	}
+	return t, r.err
+}
+
+// MarshalResponse encodes Alert Response to PGN 126984 data. Response is 25 bytes so it must be sent as fast-packet.
+func MarshalResponse(resp Response) []byte {
+	b := make([]byte, 25)
+	b[0] = resp.Type&0x0f | resp.Category<<4
+	b[1] = resp.System
+	b[2] = resp.SubSystem
+	b[3] = uint8(resp.ID)
+	b[4] = uint8(resp.ID >> 8)
+	putUint64(b[5:13], resp.DataSourceNAME)
+	b[13] = resp.DataSourceInstance
+	b[14] = resp.DataSourceIndex
+	b[15] = resp.Occurrence
+	putUint64(b[16:24], resp.AcknowledgeSourceNAME)
+	b[24] = uint8(resp.Command)&0x03 | 0xfc // reserved bits are set to 1
+	return b
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := 0; i < 8; i++ {
+		b[i] = uint8(v >> (8 * i))
+	}
+}
+
+// fieldsReader reads alert fields by ID. First missing required field is stored as error.
+type fieldsReader struct {
+	fields nmea.FieldValues
+	err    error
+}
+
+func (r *fieldsReader) header(pgn uint32, expect uint32) Header {
+	if pgn != expect {
+		r.err = fmt.Errorf("alert: message PGN %v is not %v", pgn, expect)
+		return Header{}
+	}
+	return Header{
+		Key: Key{
+			ID:                 uint16(r.uint("alertId", true)),
+			DataSourceNAME:     r.uint("dataSourceNetworkIdName", true),
+			DataSourceInstance: uint8(r.uint("dataSourceInstance", true)),
+			DataSourceIndex:    uint8(r.uint("dataSourceIndexSource", true)),
+			Occurrence:         uint8(r.uint("alertOccurrenceNumber", true)),
+		},
+		Type:      uint8(r.uint("alertType", false)),
+		Category:  uint8(r.uint("alertCategory", false)),
+		System:    uint8(r.uint("alertSystem", false)),
+		SubSystem: uint8(r.uint("alertSubSystem", false)),
+	}
+}
+
+func (r *fieldsReader) uint(ID string, required bool) uint64 {
+	fv, ok := r.fields.FindByID(ID)
+	if ok {
+		if v, ok := fv.AsUint64(); ok {
+			return v
+		}
+	}
+	if required && r.err == nil {
+		r.err = fmt.Errorf("alert: message has no value for field %v", ID)
+	}
+	return 0
+}
+
+func (r *fieldsReader) bool(ID string) bool {
+	return r.uint(ID, false) == 1
+}
+
+func (r *fieldsReader) string(ID string) string {
+	fv, ok := r.fields.FindByID(ID)
+	if !ok {
+		return ""
+	}
+	s, _ := fv.AsString()
+	return strings.TrimSpace(s)
+}
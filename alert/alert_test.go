@@ -0,0 +1,143 @@
+package alert
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func alertFields(occurrence uint64, state uint64) nmea.FieldValues {
+	return nmea.FieldValues{
+		{ID: "alertType", Value: nmea.EnumValue{Value: 5, Code: "Warning"}},
+		{ID: "alertCategory", Value: uint64(1)},
+		{ID: "alertSystem", Value: uint64(5)},
+		{ID: "alertSubSystem", Value: uint64(0)},
+		{ID: "alertId", Value: uint64(1001)},
+		{ID: "dataSourceNetworkIdName", Value: uint64(0xc0a1b2c3d4e5f601)},
+		{ID: "dataSourceInstance", Value: uint64(0)},
+		{ID: "dataSourceIndexSource", Value: uint64(1)},
+		{ID: "alertOccurrenceNumber", Value: occurrence},
+		{ID: "temporarySilenceStatus", Value: uint64(0)},
+		{ID: "acknowledgeStatus", Value: uint64(0)},
+		{ID: "escalationStatus", Value: uint64(0)},
+		{ID: "temporarySilenceSupport", Value: uint64(1)},
+		{ID: "acknowledgeSupport", Value: uint64(1)},
+		{ID: "escalationSupport", Value: uint64(0)},
+		{ID: "acknowledgeSourceNetworkIdName", Value: uint64(0)},
+		{ID: "triggerCondition", Value: uint64(1)},
+		{ID: "thresholdStatus", Value: uint64(1)},
+		{ID: "alertPriority", Value: uint64(3)},
+		{ID: "alertState", Value: nmea.EnumValue{Value: uint32(state), Code: State(state).String()}},
+	}
+}
+
+func TestParseAlert(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		when      nmea.Message
+		expect    Alert
+		expectErr string
+	}{
+		{
+			name: "ok",
+			when: nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert}, Fields: alertFields(2, 2)},
+			expect: Alert{
+				Header: Header{
+					Key: Key{
+						ID:                 1001,
+						DataSourceNAME:     0xc0a1b2c3d4e5f601,
+						DataSourceInstance: 0,
+						DataSourceIndex:    1,
+						Occurrence:         2,
+					},
+					Type:      5,
+					Category:  1,
+					System:    5,
+					SubSystem: 0,
+				},
+				TemporarySilenceSupport: true,
+				AcknowledgeSupport:      true,
+				TriggerCondition:        1,
+				ThresholdStatus:         1,
+				Priority:                3,
+				State:                   StateActive,
+			},
+		},
+		{
+			name:      "nok, wrong PGN",
+			when:      nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlertText}, Fields: alertFields(2, 2)},
+			expectErr: "alert: message PGN 126985 is not 126983",
+		},
+		{
+			name: "nok, missing alert ID",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: PGNAlert},
+				Fields: nmea.FieldValues{{ID: "alertState", Value: uint64(2)}},
+			},
+			expectErr: "alert: message has no value for field alertId",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseAlert(tc.when)
+
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestParseText(t *testing.T) {
+	fields := alertFields(2, 2)[:9]
+	fields = append(fields,
+		nmea.FieldValue{ID: "languageId", Value: uint64(0)},
+		nmea.FieldValue{ID: "alertTextDescription", Value: "Low oil pressure"},
+		nmea.FieldValue{ID: "alertLocationTextDescription", Value: "Engine room "},
+	)
+
+	result, err := ParseText(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlertText}, Fields: fields})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Low oil pressure", result.Description)
+	assert.Equal(t, "Engine room", result.LocationText)
+	assert.Equal(t, uint16(1001), result.ID)
+}
+
+func TestMarshalResponse(t *testing.T) {
+	result := MarshalResponse(Response{
+		Header: Header{
+			Key: Key{
+				ID:                 1001,
+				DataSourceNAME:     0xc0a1b2c3d4e5f601,
+				DataSourceInstance: 0,
+				DataSourceIndex:    1,
+				Occurrence:         2,
+			},
+			Type:      5,
+			Category:  1,
+			System:    5,
+			SubSystem: 0,
+		},
+		AcknowledgeSourceNAME: 0x0102030405060708,
+		Command:               ResponseTemporarySilence,
+	})
+
+	expect := []byte{
+		0x15,       // type 5, category 1
+		0x05,       // system
+		0x00,       // sub system
+		0xe9, 0x03, // alert ID 1001
+		0x01, 0xf6, 0xe5, 0xd4, 0xc3, 0xb2, 0xa1, 0xc0, // data source NAME
+		0x00,                                           // data source instance
+		0x01,                                           // data source index
+		0x02,                                           // occurrence
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // acknowledge source NAME
+		0xfd, // command 1 + reserved bits
+	}
+	assert.Equal(t, expect, result)
+}
@@ -0,0 +1,312 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownAlert is returned when responding to alert that Manager does not know
+var ErrUnknownAlert = errors.New("alert: unknown alert")
+
+// ErrNoWriter is returned when responding to alert and Manager has no writer configured
+var ErrNoWriter = errors.New("alert: writer is not configured")
+
+// ChangeType describes how active alert changed
+type ChangeType uint8
+
+const (
+	// AlertRaised is change when alert with new key becomes active
+	AlertRaised ChangeType = iota
+	// AlertUpdated is change when state, acknowledge status or text of active alert changes
+	AlertUpdated
+	// AlertCleared is change when alert becomes normal or disabled
+	AlertCleared
+	// AlertExpired is change when nothing has been received about alert within expiry
+	AlertExpired
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case AlertRaised:
+		return "raised"
+	case AlertUpdated:
+		return "updated"
+	case AlertCleared:
+		return "cleared"
+	case AlertExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// ActiveAlert is active alert with its latest text and response
+type ActiveAlert struct {
+	Alert
+	// Source is bus source address of node that sent alert
+	Source uint8 `json:"source"`
+	// Text is latest Alert Text of alert. Nil when text has not been received.
+	Text *Text `json:"text,omitempty"`
+	// LastResponse is latest Alert Response sent by any node on bus for this alert. Nil when no responses have been
+	// seen.
+	LastResponse *Response `json:"last_response,omitempty"`
+	// RaisedAt is time when alert was first seen as active
+	RaisedAt time.Time `json:"raised_at"`
+	// UpdatedAt is time when alert was last received
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Config configures Manager instance
+type Config struct {
+	// Writer is used to send Alert Responses with Acknowledge and Silence.
+	// Optional: when not set responses can not be sent
+	Writer nmea.RawMessageWriter
+	// Source is source address Alert Responses are sent with
+	Source uint8
+	// NAME is ISO NAME of this node sent as acknowledge source in Alert Responses
+	NAME uint64
+
+	// Expiry is duration after which active alert is removed when it has not been received again. Active alerts are
+	// retransmitted periodically by the node that raised them.
+	// Defaults to: 1 minute
+	Expiry time.Duration
+
+	// OnChange is called when alert is raised, updated, cleared or expired. OnChange is called outside of Manager
+	// lock.
+	// Optional
+	OnChange func(change ChangeType, alert ActiveAlert)
+}
+
+type change struct {
+	changeType ChangeType
+	alert      ActiveAlert
+}
+
+// Manager tracks active alerts from PGNs 126983 (Alert), 126984 (Alert Response) and 126985 (Alert Text) and
+// sends Alert Responses to acknowledge or silence them.
+//
+// Manager is safe for concurrent use.
+type Manager struct {
+	mutex  sync.Mutex
+	config Config
+
+	alerts map[Key]*ActiveAlert
+	// texts holds texts that were received before alert itself
+	texts map[Key]Text
+
+	now func() time.Time
+}
+
+// NewManager creates new instance of Manager with given configuration
+func NewManager(config Config) *Manager {
+	if config.Expiry <= 0 {
+		config.Expiry = 1 * time.Minute
+	}
+	return &Manager{
+		config: config,
+		alerts: make(map[Key]*ActiveAlert),
+		texts:  make(map[Key]Text),
+		now:    time.Now,
+	}
+}
+
+// Process updates alerts from decoded alert message. Returns false when message PGN is not alert PGN.
+func (m *Manager) Process(msg nmea.Message) (bool, error) {
+	switch msg.Header.PGN {
+	case PGNAlert:
+		a, err := ParseAlert(msg)
+		if err != nil {
+			return true, err
+		}
+		m.processAlert(a, msg.Header.Source)
+	case PGNAlertResponse:
+		resp, err := ParseResponse(msg)
+		if err != nil {
+			return true, err
+		}
+		m.processResponse(resp)
+	case PGNAlertText:
+		text, err := ParseText(msg)
+		if err != nil {
+			return true, err
+		}
+		m.processText(text)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *Manager) processAlert(a Alert, source uint8) {
+	m.mutex.Lock()
+	now := m.now()
+	changes := m.removeExpired(now)
+
+	active, ok := m.alerts[a.Key]
+	switch {
+	case !a.State.IsActive():
+		if ok {
+			delete(m.alerts, a.Key)
+			active.Alert = a
+			active.UpdatedAt = now
+			changes = append(changes, change{changeType: AlertCleared, alert: copyAlert(active)})
+		}
+		delete(m.texts, a.Key)
+	case !ok:
+		active = &ActiveAlert{Alert: a, Source: source, RaisedAt: now, UpdatedAt: now}
+		if text, ok := m.texts[a.Key]; ok {
+			active.Text = &text
+			delete(m.texts, a.Key)
+		}
+		m.alerts[a.Key] = active
+		changes = append(changes, change{changeType: AlertRaised, alert: copyAlert(active)})
+	default:
+		isChanged := active.Alert != a
+		active.Alert = a
+		active.Source = source
+		active.UpdatedAt = now
+		if isChanged {
+			changes = append(changes, change{changeType: AlertUpdated, alert: copyAlert(active)})
+		}
+	}
+	m.mutex.Unlock()
+
+	m.notify(changes)
+}
+
+func (m *Manager) processResponse(resp Response) {
+	m.mutex.Lock()
+	var changes []change
+	if active, ok := m.alerts[resp.Key]; ok {
+		active.LastResponse = &resp
+		changes = append(changes, change{changeType: AlertUpdated, alert: copyAlert(active)})
+	}
+	m.mutex.Unlock()
+
+	m.notify(changes)
+}
+
+func (m *Manager) processText(text Text) {
+	m.mutex.Lock()
+	var changes []change
+	active, ok := m.alerts[text.Key]
+	if !ok {
+		m.texts[text.Key] = text
+	} else if active.Text == nil || *active.Text != text {
+		active.Text = &text
+		changes = append(changes, change{changeType: AlertUpdated, alert: copyAlert(active)})
+	}
+	m.mutex.Unlock()
+
+	m.notify(changes)
+}
+
+// Alerts returns active alerts ordered by time they were raised
+func (m *Manager) Alerts() []ActiveAlert {
+	m.mutex.Lock()
+	changes := m.removeExpired(m.now())
+	result := make([]ActiveAlert, 0, len(m.alerts))
+	for _, a := range m.alerts {
+		result = append(result, copyAlert(a))
+	}
+	m.mutex.Unlock()
+
+	m.notify(changes)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RaisedAt.Equal(result[j].RaisedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].RaisedAt.Before(result[j].RaisedAt)
+	})
+	return result
+}
+
+// Alert returns active alert with given key
+func (m *Manager) Alert(key Key) (ActiveAlert, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	a, ok := m.alerts[key]
+	if !ok {
+		return ActiveAlert{}, false
+	}
+	return copyAlert(a), true
+}
+
+// Acknowledge sends Alert Response with acknowledge command for active alert
+func (m *Manager) Acknowledge(ctx context.Context, key Key) error {
+	return m.Respond(ctx, key, ResponseAcknowledge)
+}
+
+// Silence sends Alert Response with temporary silence command for active alert
+func (m *Manager) Silence(ctx context.Context, key Key) error {
+	return m.Respond(ctx, key, ResponseTemporarySilence)
+}
+
+// Respond sends Alert Response with given command to node that raised the alert
+func (m *Manager) Respond(ctx context.Context, key Key, command ResponseCommand) error {
+	if m.config.Writer == nil {
+		return ErrNoWriter
+	}
+	m.mutex.Lock()
+	a, ok := m.alerts[key]
+	if !ok {
+		m.mutex.Unlock()
+		return ErrUnknownAlert
+	}
+	header := a.Header
+	destination := a.Source
+	m.mutex.Unlock()
+
+	return m.config.Writer.WriteRawMessage(ctx, nmea.RawMessage{
+		Header: nmea.CanBusHeader{
+			PGN:         PGNAlertResponse,
+			Priority:    2,
+			Source:      m.config.Source,
+			Destination: destination,
+		},
+		Data: MarshalResponse(Response{
+			Header:                header,
+			AcknowledgeSourceNAME: m.config.NAME,
+			Command:               command,
+		}),
+	})
+}
+
+func (m *Manager) removeExpired(now time.Time) []change {
+	var changes []change
+	for key, a := range m.alerts {
+		if now.Sub(a.UpdatedAt) <= m.config.Expiry {
+			continue
+		}
+		delete(m.alerts, key)
+		changes = append(changes, change{changeType: AlertExpired, alert: copyAlert(a)})
+	}
+	return changes
+}
+
+func (m *Manager) notify(changes []change) {
+	if m.config.OnChange == nil {
+		return
+	}
+	for _, c := range changes {
+		m.config.OnChange(c.changeType, c.alert)
+	}
+}
+
+// copyAlert copies alert so that returned value does not share Text and LastResponse with alert in Manager
+func copyAlert(a *ActiveAlert) ActiveAlert {
+	result := *a
+	if a.Text != nil {
+		text := *a.Text
+		result.Text = &text
+	}
+	if a.LastResponse != nil {
+		resp := *a.LastResponse
+		result.LastResponse = &resp
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestManager_Process(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	type event struct {
+		change ChangeType
+		state  State
+		text   string
+	}
+	var events []event
+	manager := NewManager(Config{
+		OnChange: func(change ChangeType, alert ActiveAlert) {
+			e := event{change: change, state: alert.State}
+			if alert.Text != nil {
+				e.text = alert.Text.Description
+			}
+			events = append(events, e)
+		},
+	})
+	manager.now = func() time.Time {
+		return now
+	}
+
+	textFields := append(alertFields(2, 2)[:9:9], nmea.FieldValue{ID: "alertTextDescription", Value: "Low oil pressure"})
+	ok, err := manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlertText, Source: 35}, Fields: textFields})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	ok, err = manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert, Source: 35}, Fields: alertFields(2, 2)})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	// periodic retransmission without changes
+	_, err = manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert, Source: 35}, Fields: alertFields(2, 2)})
+	assert.NoError(t, err)
+
+	alerts := manager.Alerts()
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, uint8(35), alerts[0].Source)
+	assert.Equal(t, "Low oil pressure", alerts[0].Text.Description)
+	assert.Equal(t, now, alerts[0].RaisedAt)
+
+	_, err = manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert, Source: 35}, Fields: alertFields(2, 4)})
+	assert.NoError(t, err)
+	_, err = manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert, Source: 35}, Fields: alertFields(2, 1)})
+	assert.NoError(t, err)
+	assert.Len(t, manager.Alerts(), 0)
+
+	ok, err = manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: 129025}})
+	assert.False(t, ok)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []event{
+		{change: AlertRaised, state: StateActive, text: "Low oil pressure"},
+		{change: AlertUpdated, state: StateAcknowledged, text: "Low oil pressure"},
+		{change: AlertCleared, state: StateNormal, text: "Low oil pressure"},
+	}, events)
+}
+
+func TestManager_Expiry(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var changes []ChangeType
+	manager := NewManager(Config{
+		Expiry:   10 * time.Second,
+		OnChange: func(change ChangeType, alert ActiveAlert) { changes = append(changes, change) },
+	})
+	manager.now = func() time.Time {
+		return now
+	}
+
+	_, err := manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert, Source: 35}, Fields: alertFields(2, 2)})
+	assert.NoError(t, err)
+
+	now = now.Add(11 * time.Second)
+	assert.Len(t, manager.Alerts(), 0)
+	assert.Equal(t, []ChangeType{AlertRaised, AlertExpired}, changes)
+}
+
+func TestManager_Acknowledge(t *testing.T) {
+	device := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	manager := NewManager(Config{Writer: device, Source: 100, NAME: 0x0102030405060708})
+
+	key := Key{ID: 1001, DataSourceNAME: 0xc0a1b2c3d4e5f601, DataSourceIndex: 1, Occurrence: 2}
+	err := manager.Acknowledge(context.Background(), key)
+	assert.ErrorIs(t, err, ErrUnknownAlert)
+
+	_, err = manager.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: PGNAlert, Source: 35}, Fields: alertFields(2, 2)})
+	assert.NoError(t, err)
+
+	err = manager.Acknowledge(context.Background(), key)
+	assert.NoError(t, err)
+
+	written := device.Written()
+	assert.Len(t, written, 1)
+	assert.Equal(t, nmea.CanBusHeader{PGN: PGNAlertResponse, Priority: 2, Source: 100, Destination: 35}, written[0].Header)
+	assert.Equal(t, nmea.RawData{
+		0x15, 0x05, 0x00, 0xe9, 0x03,
+		0x01, 0xf6, 0xe5, 0xd4, 0xc3, 0xb2, 0xa1, 0xc0,
+		0x00, 0x01, 0x02,
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+		0xfc,
+	}, written[0].Data)
+}
+
+func TestManager_Respond_noWriter(t *testing.T) {
+	manager := NewManager(Config{})
+
+	err := manager.Silence(context.Background(), Key{ID: 1})
+
+	assert.ErrorIs(t, err, ErrNoWriter)
+}
@@ -0,0 +1,286 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// AsyncReaderConfig configures AsyncReader
+type AsyncReaderConfig struct {
+	// Size is number of messages buffer can hold.
+	// Defaults to: 1024
+	Size int
+	// DropPolicy decides which message is discarded when buffer is full. All policies (DropNewest, DropOldest,
+	// DropLowestPriority, Block) are supported. Block stops reading until consumer makes room in buffer.
+	// Defaults to: DropNewest
+	DropPolicy DropPolicy
+	// OnDrop is called for every discarded message. Called from reading goroutine so it must not block. Optional.
+	OnDrop func(msg RawMessage)
+}
+
+// AsyncReaderStats holds AsyncReader buffer counters
+type AsyncReaderStats struct {
+	// Read is number of messages read from wrapped reader
+	Read uint64 `json:"read"`
+	// Dropped is number of messages discarded because buffer was full. Discarded read errors are not counted.
+	Dropped uint64 `json:"dropped"`
+	// DroppedByPriority is number of discarded messages by message priority (0-7)
+	DroppedByPriority [8]uint64 `json:"dropped_by_priority"`
+	// Buffered is number of messages currently in buffer
+	Buffered int `json:"buffered"`
+	// MaxBuffered is highest number of messages that have been in buffer at once
+	MaxBuffered int `json:"max_buffered"`
+}
+
+type asyncEntry struct {
+	msg RawMessage
+	err error
+}
+
+// AsyncReader reads messages from wrapped reader continuously in separate goroutine into fixed size ring buffer and
+// returns them from buffer to caller. Slow consumer does not stall reading from device (which would overflow serial
+// port or socket buffers silently) - when buffer is full messages are discarded by DropPolicy and counted.
+//
+// Reading goroutine is started on first ReadRawMessage call. Read errors are buffered in order with messages.
+// Reading stops after io.EOF, closed device or context error and that error is returned after buffered messages have
+// been read.
+//
+// AsyncReader is safe for concurrent use.
+type AsyncReader struct {
+	reader RawMessageReader
+	config AsyncReaderConfig
+
+	startOnce sync.Once
+	cancel    context.CancelFunc
+
+	mu      sync.Mutex
+	buffer  []asyncEntry
+	head    int
+	count   int
+	doneErr error
+	closed  bool
+	notify  chan struct{}
+	stats   AsyncReaderStats
+}
+
+// NewAsyncReader creates new instance of AsyncReader with default configuration
+func NewAsyncReader(reader RawMessageReader) *AsyncReader {
+	return NewAsyncReaderWithConfig(reader, AsyncReaderConfig{})
+}
+
+// NewAsyncReaderWithConfig creates new instance of AsyncReader with given configuration
+func NewAsyncReaderWithConfig(reader RawMessageReader, config AsyncReaderConfig) *AsyncReader {
+	if config.Size <= 0 {
+		config.Size = 1024
+	}
+	return &AsyncReader{
+		reader: reader,
+		config: config,
+		buffer: make([]asyncEntry, config.Size),
+		notify: make(chan struct{}),
+	}
+}
+
+// ReadRawMessage returns next buffered message. Blocks until message is read from wrapped reader or context is done.
+func (r *AsyncReader) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	r.startOnce.Do(r.start)
+	for {
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return RawMessage{}, ErrDeviceClosed
+		}
+		if r.count > 0 {
+			e := r.buffer[r.head]
+			r.buffer[r.head] = asyncEntry{}
+			r.head = (r.head + 1) % len(r.buffer)
+			r.count--
+			if r.config.DropPolicy == Block {
+				r.signal() // wake up reading goroutine waiting for room in buffer
+			}
+			r.mu.Unlock()
+			return e.msg, e.err
+		}
+		if r.doneErr != nil {
+			r.mu.Unlock()
+			return RawMessage{}, r.doneErr
+		}
+		notify := r.notify
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return RawMessage{}, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+func (r *AsyncReader) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	r.cancel = cancel
+	closed := r.closed
+	r.mu.Unlock()
+	if closed {
+		cancel()
+		return
+	}
+	go r.readLoop(ctx)
+}
+
+func (r *AsyncReader) readLoop(ctx context.Context) {
+	for {
+		msg, err := r.reader.ReadRawMessage(ctx)
+		if err != nil && isTerminalReadError(err) {
+			r.mu.Lock()
+			r.doneErr = err
+			r.signal()
+			r.mu.Unlock()
+			return
+		}
+		r.push(asyncEntry{msg: msg, err: err})
+	}
+}
+
+func isTerminalReadError(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+func (r *AsyncReader) push(e asyncEntry) {
+	var dropped *RawMessage
+
+	r.mu.Lock()
+	if e.err == nil {
+		r.stats.Read++
+	}
+	for r.config.DropPolicy == Block && r.count == len(r.buffer) && !r.closed {
+		notify := r.notify
+		r.mu.Unlock()
+		<-notify
+		r.mu.Lock()
+	}
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	if r.count == len(r.buffer) {
+		drop, keepNew := r.dropIndex(e)
+		if !keepNew {
+			r.countDrop(e)
+			r.mu.Unlock()
+			if r.config.OnDrop != nil {
+				r.config.OnDrop(e.msg)
+			}
+			return
+		}
+		d := r.remove(drop)
+		r.countDrop(d)
+		if d.err == nil {
+			dropped = &d.msg
+		}
+	}
+	r.buffer[(r.head+r.count)%len(r.buffer)] = e
+	r.count++
+	if r.count > r.stats.MaxBuffered {
+		r.stats.MaxBuffered = r.count
+	}
+	r.signal()
+	r.mu.Unlock()
+
+	if dropped != nil && r.config.OnDrop != nil {
+		r.config.OnDrop(*dropped)
+	}
+}
+
+// dropIndex returns offset (from head) of buffered entry to discard or false when new entry should be discarded.
+// Errors are never discarded by priority.
+func (r *AsyncReader) dropIndex(e asyncEntry) (int, bool) {
+	switch r.config.DropPolicy {
+	case DropOldest:
+		return 0, true
+	case DropLowestPriority:
+		drop := -1
+		lowest := uint8(0)
+		for i := 0; i < r.count; i++ {
+			b := r.buffer[(r.head+i)%len(r.buffer)]
+			if b.err == nil && (drop == -1 || b.msg.Header.Priority > lowest) {
+				drop = i
+				lowest = b.msg.Header.Priority
+			}
+		}
+		if drop == -1 { // buffer is full of errors
+			return 0, true
+		}
+		if e.err == nil && e.msg.Header.Priority > lowest {
+			return 0, false
+		}
+		return drop, true
+	default:
+		return 0, e.err != nil
+	}
+}
+
+func (r *AsyncReader) remove(offset int) asyncEntry {
+	size := len(r.buffer)
+	removed := r.buffer[(r.head+offset)%size]
+	for i := offset; i > 0; i-- { // shift older entries towards removed slot
+		r.buffer[(r.head+i)%size] = r.buffer[(r.head+i-1)%size]
+	}
+	r.buffer[r.head] = asyncEntry{}
+	r.head = (r.head + 1) % size
+	r.count--
+	return removed
+}
+
+func (r *AsyncReader) countDrop(e asyncEntry) {
+	if e.err != nil {
+		return
+	}
+	r.stats.Dropped++
+	r.stats.DroppedByPriority[e.msg.Header.Priority&0x7]++
+}
+
+// signal wakes up waiting readers. Must be called with lock held.
+func (r *AsyncReader) signal() {
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// Stats returns buffer counters
+func (r *AsyncReader) Stats() AsyncReaderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.stats
+	s.Buffered = r.count
+	return s
+}
+
+// Initialize initializes wrapped reader
+func (r *AsyncReader) Initialize() error {
+	return r.reader.Initialize()
+}
+
+// Close stops reading goroutine and closes wrapped reader. Buffered messages are discarded.
+func (r *AsyncReader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	cancel := r.cancel
+	r.signal()
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	return r.reader.Close()
+}
@@ -0,0 +1,187 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type errorReader struct {
+	sliceReader
+	errAt int
+	err   error
+}
+
+func (r *errorReader) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	if r.index == r.errAt {
+		r.errAt = -1
+		return RawMessage{}, r.err
+	}
+	return r.sliceReader.ReadRawMessage(ctx)
+}
+
+func msgWithPriority(pgn uint32, priority uint8) RawMessage {
+	return RawMessage{Header: CanBusHeader{PGN: pgn, Priority: priority}}
+}
+
+func readAll(t *testing.T, r RawMessageReader) ([]RawMessage, []error) {
+	var messages []RawMessage
+	var errs []error
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		msg, err := r.ReadRawMessage(ctx)
+		cancel()
+		if errors.Is(err, io.EOF) {
+			return messages, errs
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal("read timed out")
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func TestAsyncReader_ReadRawMessage(t *testing.T) {
+	given := []RawMessage{
+		msgWithPriority(127250, 2),
+		msgWithPriority(129025, 2),
+		msgWithPriority(130312, 5),
+	}
+	r := NewAsyncReader(&errorReader{sliceReader: sliceReader{messages: given}, errAt: 1, err: errors.New("checksum")})
+
+	messages, errs := readAll(t, r)
+
+	assert.Equal(t, given, messages)
+	assert.Equal(t, []error{errors.New("checksum")}, errs)
+	assert.Equal(t, uint64(3), r.Stats().Read)
+	assert.Equal(t, uint64(0), r.Stats().Dropped)
+
+	_, err := r.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF) // terminal error is sticky
+}
+
+func TestAsyncReader_push(t *testing.T) {
+	given := []RawMessage{
+		msgWithPriority(1, 3),
+		msgWithPriority(2, 6),
+		msgWithPriority(3, 2),
+		msgWithPriority(4, 6),
+	}
+	var testCases = []struct {
+		name          string
+		givenPolicy   DropPolicy
+		whenPush      RawMessage
+		expect        []RawMessage
+		expectDropped RawMessage
+	}{
+		{
+			name:          "DropNewest",
+			givenPolicy:   DropNewest,
+			whenPush:      msgWithPriority(5, 2),
+			expect:        given,
+			expectDropped: msgWithPriority(5, 2),
+		},
+		{
+			name:          "DropOldest",
+			givenPolicy:   DropOldest,
+			whenPush:      msgWithPriority(5, 2),
+			expect:        []RawMessage{given[1], given[2], given[3], msgWithPriority(5, 2)},
+			expectDropped: given[0],
+		},
+		{
+			name:          "DropLowestPriority, oldest lowest priority message is dropped",
+			givenPolicy:   DropLowestPriority,
+			whenPush:      msgWithPriority(5, 2),
+			expect:        []RawMessage{given[0], given[2], given[3], msgWithPriority(5, 2)},
+			expectDropped: given[1],
+		},
+		{
+			name:          "DropLowestPriority, same priority replaces buffered message",
+			givenPolicy:   DropLowestPriority,
+			whenPush:      msgWithPriority(5, 6),
+			expect:        []RawMessage{given[0], given[2], given[3], msgWithPriority(5, 6)},
+			expectDropped: given[1],
+		},
+		{
+			name:          "DropLowestPriority, new message with lower priority is dropped",
+			givenPolicy:   DropLowestPriority,
+			whenPush:      msgWithPriority(5, 7),
+			expect:        given,
+			expectDropped: msgWithPriority(5, 7),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var dropped []RawMessage
+			r := NewAsyncReaderWithConfig(nil, AsyncReaderConfig{
+				Size:       len(given),
+				DropPolicy: tc.givenPolicy,
+				OnDrop: func(msg RawMessage) {
+					dropped = append(dropped, msg)
+				},
+			})
+			r.head = 2 // ring wraps around end of buffer
+			for _, m := range given {
+				r.push(asyncEntry{msg: m})
+			}
+
+			r.push(asyncEntry{msg: tc.whenPush})
+
+			r.doneErr = io.EOF
+			r.startOnce.Do(func() {})
+			messages, _ := readAll(t, r)
+			assert.Equal(t, tc.expect, messages)
+			assert.Equal(t, []RawMessage{tc.expectDropped}, dropped)
+
+			stats := r.Stats()
+			assert.Equal(t, uint64(5), stats.Read)
+			assert.Equal(t, uint64(1), stats.Dropped)
+			assert.Equal(t, uint64(1), stats.DroppedByPriority[tc.expectDropped.Header.Priority])
+			assert.Equal(t, 4, stats.MaxBuffered)
+			assert.Equal(t, 0, stats.Buffered)
+		})
+	}
+}
+
+func TestAsyncReader_push_errorsAreKept(t *testing.T) {
+	r := NewAsyncReaderWithConfig(nil, AsyncReaderConfig{Size: 1, DropPolicy: DropLowestPriority})
+	r.push(asyncEntry{err: errors.New("checksum")})
+	r.push(asyncEntry{msg: msgWithPriority(1, 0)}) // buffer is full of errors, oldest is dropped
+
+	assert.Equal(t, 1, r.count)
+	assert.Equal(t, msgWithPriority(1, 0), r.buffer[r.head].msg)
+	assert.Equal(t, uint64(0), r.Stats().Dropped)
+}
+
+func TestAsyncReader_Block(t *testing.T) {
+	given := []RawMessage{msgWithPriority(1, 3), msgWithPriority(2, 3), msgWithPriority(3, 3)}
+	r := NewAsyncReaderWithConfig(&sliceReader{messages: given}, AsyncReaderConfig{Size: 1, DropPolicy: Block})
+
+	messages, _ := readAll(t, r)
+
+	assert.Equal(t, given, messages)
+	assert.Equal(t, uint64(0), r.Stats().Dropped)
+}
+
+func TestAsyncReader_Close(t *testing.T) {
+	device := NewMockDevice(MockDeviceConfig{})
+	r := NewAsyncReader(device)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := r.ReadRawMessage(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NoError(t, r.Close())
+	_, err = r.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, ErrDeviceClosed)
+	assert.NoError(t, r.Close())
+}
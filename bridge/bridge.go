@@ -0,0 +1,336 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"sync"
+	"time"
+)
+
+// Direction is direction messages are forwarded in
+type Direction uint8
+
+const (
+	// AToB is direction from device A to device B
+	AToB Direction = iota
+	// BToA is direction from device B to device A
+	BToA
+)
+
+func (d Direction) String() string {
+	if d == AToB {
+		return "A->B"
+	}
+	return "B->A"
+}
+
+// Rule matches messages by PGN and source address.
+type Rule struct {
+	// PGNs lists PGNs rule matches.
+	// Optional: when empty rule matches any PGN
+	PGNs []uint32
+	// Sources lists source addresses rule matches.
+	// Optional: when empty rule matches any source address
+	Sources []uint8
+}
+
+// Matches checks if message header matches rule
+func (r Rule) Matches(header nmea.CanBusHeader) bool {
+	if len(r.PGNs) > 0 && !contains(r.PGNs, header.PGN) {
+		return false
+	}
+	return len(r.Sources) == 0 || contains(r.Sources, header.Source)
+}
+
+// Filter decides which messages are forwarded in one direction.
+type Filter struct {
+	// Allow lists rules of messages that are forwarded. Message is forwarded when it matches any of the rules.
+	// Optional: when empty all messages (that are not denied) are forwarded
+	Allow []Rule
+	// Deny lists rules of messages that are never forwarded. Deny rules take precedence over Allow rules.
+	Deny []Rule
+}
+
+// Forwards checks if message with given header is forwarded by filter
+func (f Filter) Forwards(header nmea.CanBusHeader) bool {
+	for _, r := range f.Deny {
+		if r.Matches(header) {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, r := range f.Allow {
+		if r.Matches(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is configuration for Bridge
+type Config struct {
+	// AToB filters messages forwarded from device A to device B
+	AToB Filter
+	// BToA filters messages forwarded from device B to device A
+	BToA Filter
+
+	// LoopWindow is time window in which message read from device that is identical (PGN, source, destination and
+	// data) to the message we just forwarded to that device is considered as echo of our own transmission and is not
+	// forwarded back. This prevents endless loops when devices are connected to same bus or gateway echoes written
+	// messages back. Negative value disables loop prevention.
+	// Defaults to: 1 second
+	LoopWindow time.Duration
+
+	// OnError is called when forwarding message fails.
+	// Optional
+	OnError func(direction Direction, msg nmea.RawMessage, err error)
+}
+
+// DirectionStats contains statistics of messages forwarded in one direction
+type DirectionStats struct {
+	// Received is number of messages read from source device
+	Received uint64 `json:"received"`
+	// Forwarded is number of messages successfully written to destination device
+	Forwarded uint64 `json:"forwarded"`
+	// Filtered is number of messages not forwarded due filter rules
+	Filtered uint64 `json:"filtered"`
+	// LoopDropped is number of messages not forwarded as they were considered as echo of our own transmission
+	LoopDropped uint64 `json:"loop_dropped"`
+	// Errors is number of messages that failed to be written to destination device
+	Errors uint64 `json:"errors"`
+}
+
+// Stats contains statistics of Bridge
+type Stats struct {
+	AToB DirectionStats `json:"a_to_b"`
+	BToA DirectionStats `json:"b_to_a"`
+}
+
+type messageKey struct {
+	pgn         uint32
+	source      uint8
+	destination uint8
+	data        string
+}
+
+func newMessageKey(msg nmea.RawMessage) messageKey {
+	return messageKey{
+		pgn:         msg.Header.PGN,
+		source:      msg.Header.Source,
+		destination: msg.Header.Destination,
+		data:        string(msg.Data),
+	}
+}
+
+type direction struct {
+	direction Direction
+	to        nmea.RawMessageReaderWriter
+	toMu      *sync.Mutex
+	filter    Filter
+	stats     DirectionStats
+	reverse   *direction
+	// sent holds times when messages were last forwarded to destination device. Used to detect echoes coming back.
+	sent map[messageKey]time.Time
+}
+
+// Bridge connects two devices and forwards messages between them in both directions, effectively acting as software
+// NMEA2000 gateway/filter (i.e. between SocketCAN interface and Actisense W2K-1).
+//
+// Bridge itself is a nmea.RawMessageReaderWriter for device A. Messages read with ReadRawMessage are read from device A
+// and forwarded to device B. Messages from device B are read by background goroutine (started by Initialize) and
+// forwarded to device A. Messages written with WriteRawMessage are written to device A.
+//
+// Note: ReadRawMessage is not go-routine safe
+type Bridge struct {
+	config  Config
+	timeNow func() time.Time
+
+	a   nmea.RawMessageReaderWriter
+	b   nmea.RawMessageReaderWriter
+	aMu sync.Mutex
+	bMu sync.Mutex
+
+	mu   sync.Mutex
+	aToB *direction
+	bToA *direction
+	// errB is error that ended reading device B
+	errB error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewBridge creates new instance of Bridge between devices A and B.
+func NewBridge(a nmea.RawMessageReaderWriter, b nmea.RawMessageReaderWriter, config Config) *Bridge {
+	if config.LoopWindow == 0 {
+		config.LoopWindow = 1 * time.Second
+	}
+	br := &Bridge{
+		config:  config,
+		timeNow: time.Now,
+		a:       a,
+		b:       b,
+	}
+	br.aToB = &direction{direction: AToB, to: b, toMu: &br.bMu, filter: config.AToB, sent: map[messageKey]time.Time{}}
+	br.bToA = &direction{direction: BToA, to: a, toMu: &br.aMu, filter: config.BToA, sent: map[messageKey]time.Time{}}
+	br.aToB.reverse = br.bToA
+	br.bToA.reverse = br.aToB
+	return br
+}
+
+// Initialize initializes both devices and starts forwarding messages from device B to device A.
+func (b *Bridge) Initialize() error {
+	if err := b.a.Initialize(); err != nil {
+		return fmt.Errorf("bridge device A initialization failed: %w", err)
+	}
+	if err := b.b.Initialize(); err != nil {
+		return fmt.Errorf("bridge device B initialization failed: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.mu.Lock()
+	b.cancel = cancel
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			msg, err := b.b.ReadRawMessage(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					b.mu.Lock()
+					b.errB = fmt.Errorf("bridge device B read failed: %w", err)
+					b.mu.Unlock()
+				}
+				return
+			}
+			b.forward(ctx, b.bToA, msg)
+		}
+	}()
+	return nil
+}
+
+// ReadRawMessage reads next message from device A and forwards it to device B. Returns error when reading device B
+// has failed.
+func (b *Bridge) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	b.mu.Lock()
+	errB := b.errB
+	b.mu.Unlock()
+	if errB != nil {
+		return nmea.RawMessage{}, errB
+	}
+
+	msg, err := b.a.ReadRawMessage(ctx)
+	if err != nil {
+		return nmea.RawMessage{}, err
+	}
+	b.forward(ctx, b.aToB, msg)
+	return msg, nil
+}
+
+// Run reads messages from device A and forwards them to device B until context is cancelled or reading fails. Use Run
+// when messages read from device A are not processed by application itself.
+func (b *Bridge) Run(ctx context.Context) error {
+	for {
+		if _, err := b.ReadRawMessage(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteRawMessage writes message to device A. Message is not forwarded to device B.
+func (b *Bridge) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	b.aMu.Lock()
+	defer b.aMu.Unlock()
+	return b.a.WriteRawMessage(ctx, msg)
+}
+
+// Close stops forwarding and closes both devices.
+func (b *Bridge) Close() error {
+	b.mu.Lock()
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.mu.Unlock()
+
+	err := b.a.Close()
+	if bErr := b.b.Close(); err == nil {
+		err = bErr
+	}
+	b.wg.Wait()
+	return err
+}
+
+// Stats returns current bridge statistics.
+func (b *Bridge) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{AToB: b.aToB.stats, BToA: b.bToA.stats}
+}
+
+func (b *Bridge) forward(ctx context.Context, d *direction, msg nmea.RawMessage) {
+	now := b.timeNow()
+	key := newMessageKey(msg)
+
+	b.mu.Lock()
+	d.stats.Received++
+	if b.config.LoopWindow > 0 {
+		// message coming from device we just forwarded same message to, is echo of our own transmission
+		if sentAt, ok := d.reverse.sent[key]; ok && now.Sub(sentAt) <= b.config.LoopWindow {
+			delete(d.reverse.sent, key)
+			d.stats.LoopDropped++
+			b.mu.Unlock()
+			return
+		}
+	}
+	if !d.filter.Forwards(msg.Header) {
+		d.stats.Filtered++
+		b.mu.Unlock()
+		return
+	}
+	if b.config.LoopWindow > 0 {
+		d.sent[key] = now
+		if len(d.sent) > 1024 {
+			b.cleanupSent(d, now)
+		}
+	}
+	b.mu.Unlock()
+
+	d.toMu.Lock()
+	err := d.to.WriteRawMessage(ctx, msg)
+	d.toMu.Unlock()
+
+	b.mu.Lock()
+	if err != nil {
+		d.stats.Errors++
+	} else {
+		d.stats.Forwarded++
+	}
+	b.mu.Unlock()
+
+	if err != nil && b.config.OnError != nil && !errors.Is(err, context.Canceled) {
+		b.config.OnError(d.direction, msg, err)
+	}
+}
+
+func (b *Bridge) cleanupSent(d *direction, now time.Time) {
+	for k, t := range d.sent {
+		if now.Sub(t) > b.config.LoopWindow {
+			delete(d.sent, k)
+		}
+	}
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func testMessage(pgn uint32, source uint8, data ...byte) nmea.RawMessage {
+	return nmea.RawMessage{
+		Time:   test_test.UTCTime(1665488842),
+		Header: nmea.CanBusHeader{PGN: pgn, Source: source, Destination: 255, Priority: 2},
+		Data:   data,
+	}
+}
+
+func TestFilter_Forwards(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		given  Filter
+		when   nmea.CanBusHeader
+		expect bool
+	}{
+		{
+			name:   "ok, empty filter forwards everything",
+			given:  Filter{},
+			when:   nmea.CanBusHeader{PGN: 127250, Source: 1},
+			expect: true,
+		},
+		{
+			name:   "ok, allowed PGN",
+			given:  Filter{Allow: []Rule{{PGNs: []uint32{129025, 127250}}}},
+			when:   nmea.CanBusHeader{PGN: 127250, Source: 1},
+			expect: true,
+		},
+		{
+			name:   "nok, PGN is not allowed",
+			given:  Filter{Allow: []Rule{{PGNs: []uint32{129025}}}},
+			when:   nmea.CanBusHeader{PGN: 127250, Source: 1},
+			expect: false,
+		},
+		{
+			name:   "nok, allowed PGN from other source",
+			given:  Filter{Allow: []Rule{{PGNs: []uint32{127250}, Sources: []uint8{2}}}},
+			when:   nmea.CanBusHeader{PGN: 127250, Source: 1},
+			expect: false,
+		},
+		{
+			name: "nok, deny takes precedence",
+			given: Filter{
+				Allow: []Rule{{PGNs: []uint32{127250}}},
+				Deny:  []Rule{{Sources: []uint8{1}}},
+			},
+			when:   nmea.CanBusHeader{PGN: 127250, Source: 1},
+			expect: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.given.Forwards(tc.when))
+		})
+	}
+}
+
+func TestBridge_forwarding(t *testing.T) {
+	a := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	b := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	bridge := NewBridge(a, b, Config{
+		AToB: Filter{Deny: []Rule{{PGNs: []uint32{129025}}}},
+	})
+	assert.NoError(t, bridge.Initialize())
+	defer bridge.Close()
+
+	a.Emit(testMessage(127250, 1, 0x01), testMessage(129025, 1, 0x02))
+	b.Emit(testMessage(130306, 2, 0x03))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := bridge.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage(127250, 1, 0x01), msg)
+	msg, err = bridge.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage(129025, 1, 0x02), msg) // filtered messages are still returned to reader
+
+	assert.Equal(t, []nmea.RawMessage{testMessage(127250, 1, 0x01)}, b.Written())
+	assert.Eventually(t, func() bool { return len(a.Written()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []nmea.RawMessage{testMessage(130306, 2, 0x03)}, a.Written())
+
+	assert.NoError(t, bridge.WriteRawMessage(ctx, testMessage(59904, 3, 0x04)))
+	assert.Len(t, a.Written(), 2)
+	assert.Len(t, b.Written(), 1)
+
+	assert.Equal(t, Stats{
+		AToB: DirectionStats{Received: 2, Forwarded: 1, Filtered: 1},
+		BToA: DirectionStats{Received: 1, Forwarded: 1},
+	}, bridge.Stats())
+}
+
+func TestBridge_loopPrevention(t *testing.T) {
+	a := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	b := nmea.NewMockDevice(nmea.MockDeviceConfig{
+		OnWrite: func(device *nmea.MockDevice, msg nmea.RawMessage) {
+			device.Emit(msg) // device B echoes every written message back
+		},
+	})
+	bridge := NewBridge(a, b, Config{})
+	assert.NoError(t, bridge.Initialize())
+	defer bridge.Close()
+
+	a.Emit(testMessage(127250, 1, 0x01))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := bridge.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return bridge.Stats().BToA.LoopDropped == 1 }, time.Second, time.Millisecond)
+	assert.Len(t, a.Written(), 0)
+	assert.Equal(t, Stats{
+		AToB: DirectionStats{Received: 1, Forwarded: 1},
+		BToA: DirectionStats{Received: 1, LoopDropped: 1},
+	}, bridge.Stats())
+}
+
+func TestBridge_forwardError(t *testing.T) {
+	a := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	b := nmea.NewMockDevice(nmea.MockDeviceConfig{WriteError: errors.New("bus off")})
+	var errs []error
+	bridge := NewBridge(a, b, Config{
+		OnError: func(direction Direction, msg nmea.RawMessage, err error) {
+			assert.Equal(t, AToB, direction)
+			errs = append(errs, err)
+		},
+	})
+	assert.NoError(t, bridge.Initialize())
+	defer bridge.Close()
+
+	a.Emit(testMessage(127250, 1, 0x01))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := bridge.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []error{errors.New("bus off")}, errs)
+	assert.Equal(t, DirectionStats{Received: 1, Errors: 1}, bridge.Stats().AToB)
+}
+
+func TestBridge_deviceBReadError(t *testing.T) {
+	a := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	b := nmea.NewMockDevice(nmea.MockDeviceConfig{EOFWhenDone: true})
+	bridge := NewBridge(a, b, Config{})
+	assert.NoError(t, bridge.Initialize())
+	defer bridge.Close()
+
+	var err error
+	assert.Eventually(t, func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		_, err = bridge.ReadRawMessage(ctx)
+		return errors.Is(err, io.EOF)
+	}, time.Second, time.Millisecond)
+	assert.EqualError(t, err, "bridge device B read failed: EOF")
+}
+
+func TestBridge_Close(t *testing.T) {
+	a := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	b := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+	bridge := NewBridge(a, b, Config{})
+	assert.NoError(t, bridge.Initialize())
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- bridge.Run(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, bridge.Close())
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("run was not unblocked by Close")
+	}
+}
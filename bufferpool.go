@@ -0,0 +1,50 @@
+package nmea
+
+import (
+	"context"
+	"sync"
+)
+
+// RawMessageIntoReader is implemented by devices that can read raw message into existing message. Message Data slice
+// is reused when it has enough capacity so read loops that reuse same message do not allocate for every message.
+type RawMessageIntoReader interface {
+	ReadRawMessageInto(ctx context.Context, msg *RawMessage) error
+}
+
+// BufferPool is sync.Pool backed pool of byte slices for RawMessage.Data. Devices configured with pool take data
+// slices for read messages from pool and application returns them with Put after message has been processed. This
+// reduces GC pressure on small devices reading busy bus.
+type BufferPool struct {
+	pool sync.Pool
+	size int
+}
+
+// NewBufferPool creates new BufferPool. Size is capacity of newly allocated slices (i.e. 8 for single frame messages
+// or FastRawPacketMaxSize for fast-packet messages). Larger slices are allocated when requested length exceeds size.
+func NewBufferPool(size int) *BufferPool {
+	return &BufferPool{size: size}
+}
+
+// Get returns slice with given length from pool. Slice contents are not zeroed.
+func (p *BufferPool) Get(length int) []byte {
+	if b, ok := p.pool.Get().(*[]byte); ok {
+		if cap(*b) >= length {
+			return (*b)[:length]
+		}
+		p.pool.Put(b)
+	}
+	size := p.size
+	if length > size {
+		size = length
+	}
+	return make([]byte, length, size)
+}
+
+// Put returns slice to pool. Slice must not be used after it has been returned.
+func (p *BufferPool) Put(b []byte) {
+	if cap(b) == 0 {
+		return
+	}
+	b = b[:0]
+	p.pool.Put(&b)
+}
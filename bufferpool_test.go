@@ -0,0 +1,32 @@
+package nmea
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBufferPool_Get(t *testing.T) {
+	pool := NewBufferPool(8)
+
+	b := pool.Get(3)
+	assert.Len(t, b, 3)
+	assert.Equal(t, 8, cap(b))
+
+	large := pool.Get(20)
+	assert.Len(t, large, 20)
+	assert.Equal(t, 20, cap(large))
+}
+
+func TestBufferPool_Put(t *testing.T) {
+	pool := NewBufferPool(8)
+
+	b := pool.Get(8)
+	b[0] = 0xAA
+	pool.Put(b)
+	pool.Put(nil) // is ignored
+
+	// sync.Pool does not guarantee that same slice is returned, so we can only check length and capacity
+	result := pool.Get(5)
+	assert.Len(t, result, 5)
+	assert.GreaterOrEqual(t, cap(result), 8)
+}
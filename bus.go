@@ -0,0 +1,210 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MessageFilter selects messages by header fields. Empty lists match all values.
+type MessageFilter struct {
+	PGNs         []uint32
+	Sources      []uint8
+	Destinations []uint8
+}
+
+// Matches checks if message header matches filter
+func (f MessageFilter) Matches(header CanBusHeader) bool {
+	return containsValue(f.PGNs, header.PGN) &&
+		containsValue(f.Sources, header.Source) &&
+		containsValue(f.Destinations, header.Destination)
+}
+
+func containsValue[T comparable](values []T, value T) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// BusConfig configures Bus
+type BusConfig struct {
+	// Decoder is used to decode messages for SubscribeDecoded subscriptions. Messages are decoded in subscription own
+	// goroutine so decoder must be safe for concurrent use (canboat.Decoder is). Optional when only raw messages are
+	// subscribed.
+	Decoder MessageDecoder
+}
+
+// BusSubscriptionConfig configures single Bus subscription
+type BusSubscriptionConfig struct {
+	// Name identifies subscription in errors and statistics. Optional.
+	Name string
+	// Filter decides which messages are delivered to this subscription. Zero value means all messages.
+	Filter MessageFilter
+
+	// BufferSize is number of messages that can wait for consumer to read them from subscription channel.
+	// Defaults to: 100
+	BufferSize int
+	// DropPolicy determines what to do when consumer is slow and buffer is full.
+	// Defaults to: DropNewest
+	DropPolicy DropPolicy
+}
+
+// BusSubscription delivers messages to consumer through channel C. Channel is closed when subscription or Bus is
+// closed.
+type BusSubscription[T any] struct {
+	// C is channel where subscribed messages are delivered
+	C <-chan T
+
+	bus          *Bus
+	subscription *Subscription
+	out          chan T
+	closing      chan struct{}
+	closeOnce    sync.Once
+}
+
+// Errors returns channel where delivery errors (i.e. decode errors for decoded subscriptions) are reported.
+func (s *BusSubscription[T]) Errors() <-chan SubscriptionError {
+	return s.subscription.Errors()
+}
+
+// Stats returns delivery statistics of subscription. Dropped counts messages discarded due to slow consumer.
+func (s *BusSubscription[T]) Stats() SubscriptionStats {
+	return s.subscription.Stats()
+}
+
+// Close removes subscription from Bus and closes channel C. Messages still in buffer are discarded.
+func (s *BusSubscription[T]) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closing) // unblocks delivery to consumer that is not reading anymore
+		s.subscription.Close()
+		close(s.out)
+		s.bus.remove(s)
+	})
+	return nil
+}
+
+func (s *BusSubscription[T]) deliver(v T) {
+	select {
+	case s.out <- v:
+	case <-s.closing:
+	}
+}
+
+// Bus runs read loop and delivers (fans out) read messages to multiple subscribers through channels so every consumer
+// does not need its own read loop. Subscribers can receive raw messages (Subscribe) or decoded messages
+// (SubscribeDecoded). Each subscription has its own buffer and drop policy so slow consumer does not affect others
+// (unless Block policy is used). Bus is built on top of Router.
+//
+// Bus is safe for concurrent use.
+type Bus struct {
+	reader  RawMessageReader
+	decoder MessageDecoder
+	router  *Router
+
+	lock          sync.Mutex
+	subscriptions map[closer]struct{}
+}
+
+type closer interface {
+	Close() error
+}
+
+// NewBus creates new instance of Bus reading messages from given reader
+func NewBus(reader RawMessageReader, config BusConfig) *Bus {
+	return &Bus{
+		reader:        reader,
+		decoder:       config.Decoder,
+		router:        NewRouter(),
+		subscriptions: map[closer]struct{}{},
+	}
+}
+
+// Run reads messages and delivers them to subscribers until reader returns io.EOF, context is cancelled or read
+// fails. Subscriptions are not closed when Run returns - call Close to close subscription channels.
+func (b *Bus) Run(ctx context.Context) error {
+	return b.router.Run(ctx, b.reader)
+}
+
+// Subscribe creates subscription that receives raw messages matching filter
+func (b *Bus) Subscribe(config BusSubscriptionConfig) (*BusSubscription[RawMessage], error) {
+	return subscribe(b, config, func(s *BusSubscription[RawMessage], raw RawMessage) error {
+		s.deliver(raw)
+		return nil
+	})
+}
+
+// SubscribeDecoded creates subscription that receives decoded messages matching filter. Messages that fail to decode
+// are reported through subscription Errors channel.
+func (b *Bus) SubscribeDecoded(config BusSubscriptionConfig) (*BusSubscription[Message], error) {
+	if b.decoder == nil {
+		return nil, errors.New("bus has no decoder to subscribe decoded messages")
+	}
+	return subscribe(b, config, func(s *BusSubscription[Message], raw RawMessage) error {
+		msg, err := b.decoder.Decode(raw)
+		if err != nil {
+			return err
+		}
+		s.deliver(msg)
+		return nil
+	})
+}
+
+func subscribe[T any](b *Bus, config BusSubscriptionConfig, handler func(s *BusSubscription[T], raw RawMessage) error) (*BusSubscription[T], error) {
+	out := make(chan T)
+	s := &BusSubscription[T]{
+		C:       out,
+		bus:     b,
+		out:     out,
+		closing: make(chan struct{}),
+	}
+	filter := config.Filter
+	sub, err := b.router.Subscribe(SubscriptionConfig{
+		Name: config.Name,
+		Filter: func(msg RawMessage) bool {
+			return filter.Matches(msg.Header)
+		},
+		Handler: func(msg RawMessage) error {
+			return handler(s, msg)
+		},
+		// channel is unbuffered so router subscription buffer holds messages that consumer has not read yet and
+		// drop policy is applied to it
+		BufferSize: config.BufferSize,
+		DropPolicy: config.DropPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.subscription = sub
+
+	b.lock.Lock()
+	b.subscriptions[s] = struct{}{}
+	b.lock.Unlock()
+	return s, nil
+}
+
+func (b *Bus) remove(s closer) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subscriptions, s)
+}
+
+// Close closes all subscriptions and their channels. Subscribing to closed Bus fails with ErrRouterClosed.
+func (b *Bus) Close() error {
+	b.lock.Lock()
+	subscriptions := make([]closer, 0, len(b.subscriptions))
+	for s := range b.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	b.lock.Unlock()
+
+	for _, s := range subscriptions {
+		s.Close()
+	}
+	return b.router.Close()
+}
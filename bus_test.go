@@ -0,0 +1,168 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type decoderFunc func(raw RawMessage) (Message, error)
+
+func (f decoderFunc) Decode(raw RawMessage) (Message, error) {
+	return f(raw)
+}
+
+func TestMessageFilter_Matches(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		filter MessageFilter
+		when   CanBusHeader
+		expect bool
+	}{
+		{
+			name:   "ok, empty filter matches all",
+			filter: MessageFilter{},
+			when:   CanBusHeader{PGN: 129025, Source: 1, Destination: 255},
+			expect: true,
+		},
+		{
+			name:   "ok, all lists match",
+			filter: MessageFilter{PGNs: []uint32{129026, 129025}, Sources: []uint8{1}, Destinations: []uint8{255}},
+			when:   CanBusHeader{PGN: 129025, Source: 1, Destination: 255},
+			expect: true,
+		},
+		{
+			name:   "nok, PGN does not match",
+			filter: MessageFilter{PGNs: []uint32{129026}},
+			when:   CanBusHeader{PGN: 129025, Source: 1, Destination: 255},
+			expect: false,
+		},
+		{
+			name:   "nok, source does not match",
+			filter: MessageFilter{PGNs: []uint32{129025}, Sources: []uint8{2, 3}},
+			when:   CanBusHeader{PGN: 129025, Source: 1, Destination: 255},
+			expect: false,
+		},
+		{
+			name:   "nok, destination does not match",
+			filter: MessageFilter{Destinations: []uint8{10}},
+			when:   CanBusHeader{PGN: 129025, Source: 1, Destination: 255},
+			expect: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.filter.Matches(tc.when))
+		})
+	}
+}
+
+func TestBus_Subscribe_fanOut(t *testing.T) {
+	reader := &sliceReader{messages: []RawMessage{
+		{Header: CanBusHeader{PGN: 129025, Source: 1}},
+		{Header: CanBusHeader{PGN: 129026, Source: 1}},
+		{Header: CanBusHeader{PGN: 129025, Source: 2}},
+	}}
+	bus := NewBus(reader, BusConfig{})
+	defer bus.Close()
+
+	all, err := bus.Subscribe(BusSubscriptionConfig{Name: "all", DropPolicy: Block})
+	assert.NoError(t, err)
+	positions, err := bus.Subscribe(BusSubscriptionConfig{
+		Name:       "positions",
+		Filter:     MessageFilter{PGNs: []uint32{129025}, Sources: []uint8{2}},
+		DropPolicy: Block,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, bus.Run(context.Background()))
+
+	for _, expect := range []uint32{129025, 129026, 129025} {
+		msg := <-all.C
+		assert.Equal(t, expect, msg.Header.PGN)
+	}
+	msg := <-positions.C
+	assert.Equal(t, CanBusHeader{PGN: 129025, Source: 2}, msg.Header)
+
+	assert.NoError(t, bus.Close())
+	_, ok := <-all.C
+	assert.False(t, ok)
+	_, ok = <-positions.C
+	assert.False(t, ok)
+
+	_, err = bus.Subscribe(BusSubscriptionConfig{})
+	assert.ErrorIs(t, err, ErrRouterClosed)
+}
+
+func TestBus_SubscribeDecoded(t *testing.T) {
+	reader := &sliceReader{messages: []RawMessage{
+		{Header: CanBusHeader{PGN: 1}, Data: RawData{0x01}},
+		{Header: CanBusHeader{PGN: 2}, Data: RawData{0x02}},
+	}}
+	decoder := decoderFunc(func(raw RawMessage) (Message, error) {
+		if raw.Header.PGN == 2 {
+			return Message{}, errors.New("decode failed")
+		}
+		return Message{Header: raw.Header, Fields: FieldValues{{ID: "value", Value: uint64(raw.Data[0])}}}, nil
+	})
+	bus := NewBus(reader, BusConfig{Decoder: decoder})
+	defer bus.Close()
+
+	sub, err := bus.SubscribeDecoded(BusSubscriptionConfig{DropPolicy: Block})
+	assert.NoError(t, err)
+
+	assert.NoError(t, bus.Run(context.Background()))
+
+	msg := <-sub.C
+	assert.Equal(t, Message{Header: CanBusHeader{PGN: 1}, Fields: FieldValues{{ID: "value", Value: uint64(1)}}}, msg)
+
+	e := <-sub.Errors()
+	assert.EqualError(t, e.Err, "decode failed")
+	assert.Equal(t, uint32(2), e.Message.Header.PGN)
+}
+
+func TestBus_SubscribeDecoded_withoutDecoder(t *testing.T) {
+	bus := NewBus(&sliceReader{}, BusConfig{})
+	defer bus.Close()
+
+	_, err := bus.SubscribeDecoded(BusSubscriptionConfig{})
+	assert.EqualError(t, err, "bus has no decoder to subscribe decoded messages")
+}
+
+func TestBus_Subscribe_slowConsumer(t *testing.T) {
+	reader := &sliceReader{messages: []RawMessage{
+		{Header: CanBusHeader{PGN: 1}},
+		{Header: CanBusHeader{PGN: 2}},
+		{Header: CanBusHeader{PGN: 3}},
+		{Header: CanBusHeader{PGN: 4}},
+	}}
+	bus := NewBus(reader, BusConfig{})
+	defer bus.Close()
+
+	slow, err := bus.Subscribe(BusSubscriptionConfig{BufferSize: 1, DropPolicy: DropNewest})
+	assert.NoError(t, err)
+	fast, err := bus.Subscribe(BusSubscriptionConfig{BufferSize: 10, DropPolicy: Block})
+	assert.NoError(t, err)
+
+	assert.NoError(t, bus.Run(context.Background()))
+
+	for i := 1; i <= 4; i++ {
+		msg := <-fast.C
+		assert.Equal(t, uint32(i), msg.Header.PGN)
+	}
+
+	// slow consumer has not read anything: at most one message is waiting for channel read and one in buffer, rest
+	// are dropped without blocking other subscribers
+	assert.Eventually(t, func() bool {
+		stats := slow.Stats()
+		return stats.Dropped+stats.Delivered+uint64(stats.Queued) == 4
+	}, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, slow.Stats().Dropped, uint64(2))
+
+	assert.NoError(t, slow.Close())
+	assert.NoError(t, slow.Close())
+	_, ok := <-slow.C
+	assert.False(t, ok)
+}
@@ -0,0 +1,122 @@
+package nmea
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BusTimeStrategy determines how BusTimeReader assigns bus time to messages
+type BusTimeStrategy uint8
+
+const (
+	// BusTimeDevice uses bus time calculated from device timestamps. Messages without device timestamps get local
+	// receive time as bus time.
+	BusTimeDevice BusTimeStrategy = iota
+	// BusTimeReceive uses local receive time (RawMessage.Time) as bus time and ignores device timestamps.
+	BusTimeReceive
+	// BusTimeMonotonic is same as BusTimeDevice but bus times of messages from same source never go backwards. Useful
+	// for replay and latency analysis where reordered messages or device clock jumps would produce negative intervals.
+	BusTimeMonotonic
+)
+
+// BusTimeConfig configures BusTimeReader
+type BusTimeConfig struct {
+	Strategy BusTimeStrategy
+}
+
+// ClockSkew is estimated skew between local receive time and device bus time of messages from single source.
+// Consists of transport latency (serial/network buffers) and clock drift between device and local clock.
+type ClockSkew struct {
+	// Samples is count of messages with device timestamps skew was estimated from
+	Samples uint64
+	Min     time.Duration
+	Max     time.Duration
+	Mean    time.Duration
+}
+
+// BusTimeReader wraps RawMessageReader, fills message bus time according to configured strategy and estimates clock
+// skew between local and device clock for each message source.
+type BusTimeReader struct {
+	reader RawMessageReader
+	config BusTimeConfig
+
+	mu sync.Mutex
+	// lastBusTime is last bus time assigned to message from source. Indexed by source address.
+	lastBusTime [256]time.Time
+	skews       [256]ClockSkew
+}
+
+// NewBusTimeReader creates new instance of BusTimeReader
+func NewBusTimeReader(reader RawMessageReader, config BusTimeConfig) *BusTimeReader {
+	return &BusTimeReader{
+		reader: reader,
+		config: config,
+	}
+}
+
+// ReadRawMessage reads message from wrapped reader and assigns bus time to it.
+func (r *BusTimeReader) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	msg, err := r.reader.ReadRawMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	msg.BusTime = r.busTime(msg)
+	return msg, nil
+}
+
+func (r *BusTimeReader) busTime(msg RawMessage) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source := msg.Header.Source
+	if !msg.BusTime.IsZero() {
+		r.observeSkew(source, msg.Time.Sub(msg.BusTime))
+	}
+
+	result := msg.BusTime
+	if result.IsZero() || r.config.Strategy == BusTimeReceive {
+		result = msg.Time
+	}
+	if r.config.Strategy == BusTimeMonotonic && result.Before(r.lastBusTime[source]) {
+		result = r.lastBusTime[source]
+	}
+	r.lastBusTime[source] = result
+	return result
+}
+
+func (r *BusTimeReader) observeSkew(source uint8, skew time.Duration) {
+	s := &r.skews[source]
+	s.Samples++
+	if s.Samples == 1 {
+		s.Min, s.Max, s.Mean = skew, skew, skew
+		return
+	}
+	if skew < s.Min {
+		s.Min = skew
+	}
+	if skew > s.Max {
+		s.Max = skew
+	}
+	s.Mean += (skew - s.Mean) / time.Duration(s.Samples)
+}
+
+// Skew returns estimated clock skew for given source. Returns false when no messages with device timestamps have been
+// read from that source.
+func (r *BusTimeReader) Skew(source uint8) (ClockSkew, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.skews[source]
+	return s, s.Samples > 0
+}
+
+// Initialize initializes wrapped reader
+func (r *BusTimeReader) Initialize() error {
+	return r.reader.Initialize()
+}
+
+// Close closes wrapped reader
+func (r *BusTimeReader) Close() error {
+	return r.reader.Close()
+}
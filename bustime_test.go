@@ -0,0 +1,103 @@
+package nmea
+
+import (
+	"context"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBusTimeReader_ReadRawMessage(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	messages := []RawMessage{
+		{Time: now, BusTime: now.Add(-10 * time.Millisecond), Header: CanBusHeader{Source: 1}},
+		{Time: now.Add(20 * time.Millisecond), Header: CanBusHeader{Source: 2}},
+		{Time: now.Add(30 * time.Millisecond), BusTime: now.Add(-20 * time.Millisecond), Header: CanBusHeader{Source: 1}}, // clock jump back
+		{Time: now.Add(40 * time.Millisecond), BusTime: now.Add(20 * time.Millisecond), Header: CanBusHeader{Source: 1}},
+	}
+
+	var testCases = []struct {
+		name        string
+		givenConfig BusTimeConfig
+		expect      []time.Time
+	}{
+		{
+			name:        "ok, device",
+			givenConfig: BusTimeConfig{Strategy: BusTimeDevice},
+			expect: []time.Time{
+				now.Add(-10 * time.Millisecond),
+				now.Add(20 * time.Millisecond),
+				now.Add(-20 * time.Millisecond),
+				now.Add(20 * time.Millisecond),
+			},
+		},
+		{
+			name:        "ok, receive",
+			givenConfig: BusTimeConfig{Strategy: BusTimeReceive},
+			expect: []time.Time{
+				now,
+				now.Add(20 * time.Millisecond),
+				now.Add(30 * time.Millisecond),
+				now.Add(40 * time.Millisecond),
+			},
+		},
+		{
+			name:        "ok, monotonic per source",
+			givenConfig: BusTimeConfig{Strategy: BusTimeMonotonic},
+			expect: []time.Time{
+				now.Add(-10 * time.Millisecond),
+				now.Add(20 * time.Millisecond),
+				now.Add(-10 * time.Millisecond),
+				now.Add(20 * time.Millisecond),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewBusTimeReader(&sliceReader{messages: messages}, tc.givenConfig)
+
+			result := make([]time.Time, 0, len(messages))
+			for {
+				msg, err := r.ReadRawMessage(context.Background())
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				result = append(result, msg.BusTime)
+			}
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestBusTimeReader_Skew(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	r := NewBusTimeReader(&sliceReader{messages: []RawMessage{
+		{Time: now, BusTime: now.Add(-10 * time.Millisecond), Header: CanBusHeader{Source: 1}},
+		{Time: now, Header: CanBusHeader{Source: 2}},
+		{Time: now, BusTime: now.Add(-30 * time.Millisecond), Header: CanBusHeader{Source: 1}},
+		{Time: now, BusTime: now.Add(-20 * time.Millisecond), Header: CanBusHeader{Source: 1}},
+	}}, BusTimeConfig{})
+	for {
+		if _, err := r.ReadRawMessage(context.Background()); err != nil {
+			break
+		}
+	}
+
+	skew, ok := r.Skew(1)
+	assert.True(t, ok)
+	assert.Equal(t, ClockSkew{
+		Samples: 3,
+		Min:     10 * time.Millisecond,
+		Max:     30 * time.Millisecond,
+		Mean:    20 * time.Millisecond,
+	}, skew)
+
+	_, ok = r.Skew(2)
+	assert.False(t, ok)
+}
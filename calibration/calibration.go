@@ -0,0 +1,230 @@
+package calibration
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// AdjustmentHeadingDeviation marks heading that was corrected with deviation table
+	AdjustmentHeadingDeviation = "heading_deviation"
+	// AdjustmentPitchOffset marks pitch that was corrected with zero offset
+	AdjustmentPitchOffset = "pitch_offset"
+	// AdjustmentRollOffset marks roll that was corrected with zero offset
+	AdjustmentRollOffset = "roll_offset"
+	// AdjustmentDepthOffset marks depth that was corrected with transducer offset
+	AdjustmentDepthOffset = "depth_offset"
+)
+
+const (
+	pgnVesselHeading = uint32(127250)
+	pgnAttitude      = uint32(127257)
+	pgnWaterDepth    = uint32(128267)
+
+	headingReferenceTrue = uint64(0)
+)
+
+// DeviationPoint is single entry of compass deviation table
+type DeviationPoint struct {
+	// HeadingDegrees is compass heading (0-360) where deviation was measured
+	HeadingDegrees float64 `json:"heading_degrees"`
+	// DeviationDegrees is deviation at that heading. Easterly deviation is positive and westerly negative.
+	DeviationDegrees float64 `json:"deviation_degrees"`
+}
+
+// DeviationTable is compass deviation table. Deviation between table entries is linearly interpolated and table wraps
+// around at 360 degrees.
+type DeviationTable []DeviationPoint
+
+// SourceCalibration is set of calibration offsets for single source
+type SourceCalibration struct {
+	// HeadingDeviation is applied to heading field of PGN 127250 (Vessel Heading) when heading reference is not true
+	// (i.e. magnetic heading from compass). Corrected heading = compass heading + deviation.
+	HeadingDeviation DeviationTable `json:"heading_deviation,omitempty"`
+	// PitchOffsetDegrees is pitch value that sensor reports when vessel is level. It is subtracted from pitch field
+	// of PGN 127257 (Attitude).
+	PitchOffsetDegrees float64 `json:"pitch_offset_degrees,omitempty"`
+	// RollOffsetDegrees is roll value that sensor reports when vessel is level. It is subtracted from roll field
+	// of PGN 127257 (Attitude).
+	RollOffsetDegrees float64 `json:"roll_offset_degrees,omitempty"`
+	// DepthOffset (meters) is added to depth field of PGN 128267 (Water Depth). Positive value is distance from
+	// transducer to waterline, negative value is distance from transducer to keel.
+	DepthOffset float64 `json:"depth_offset,omitempty"`
+}
+
+// Config is configuration for Calibrator. Calibrations are keyed by source address.
+type Config struct {
+	Sources map[uint8]SourceCalibration `json:"sources"`
+}
+
+// Calibrator applies per source calibration offsets to decoded messages. Every adjusted field is recorded in
+// Message.Adjustments with its original value, so consumers can tell corrected values apart from raw sensor values.
+//
+// Calibrator is safe for concurrent use.
+type Calibrator struct {
+	mutex   sync.RWMutex
+	sources map[uint8]SourceCalibration
+}
+
+// NewCalibrator creates new instance of Calibrator
+func NewCalibrator(config Config) *Calibrator {
+	c := &Calibrator{sources: map[uint8]SourceCalibration{}}
+	for source, sc := range config.Sources {
+		c.SetSourceCalibration(source, sc)
+	}
+	return c
+}
+
+// SetSourceCalibration sets (replaces) calibration for given source
+func (c *Calibrator) SetSourceCalibration(source uint8, sc SourceCalibration) {
+	table := make(DeviationTable, len(sc.HeadingDeviation))
+	copy(table, sc.HeadingDeviation)
+	for i, p := range table {
+		table[i].HeadingDegrees = normalizeDegrees(p.HeadingDegrees)
+	}
+	sort.Slice(table, func(i, j int) bool { return table[i].HeadingDegrees < table[j].HeadingDegrees })
+	sc.HeadingDeviation = table
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sources[source] = sc
+}
+
+// Apply applies calibration of message source to message fields and returns adjusted message. Fields that have no
+// data or are not numeric are left untouched.
+func (c *Calibrator) Apply(msg nmea.Message) nmea.Message {
+	c.mutex.RLock()
+	sc, ok := c.sources[msg.Header.Source]
+	c.mutex.RUnlock()
+	if !ok {
+		return msg
+	}
+
+	switch msg.Header.PGN {
+	case pgnVesselHeading:
+		if len(sc.HeadingDeviation) == 0 {
+			return msg
+		}
+		if ref, ok := msg.Fields.FindByID("reference"); ok {
+			// reference is decoded as EnumValue or plain uint64 depending on decoder configuration
+			if v, ok := ref.AsUint64(); ok && v == headingReferenceTrue {
+				return msg
+			}
+		}
+		return adjust(msg, "heading", AdjustmentHeadingDeviation, func(heading float64) float64 {
+			deviation := sc.HeadingDeviation.Deviation(heading * 180 / math.Pi)
+			return normalizeRadians(heading + deviation*math.Pi/180)
+		})
+	case pgnAttitude:
+		if sc.PitchOffsetDegrees != 0 {
+			msg = adjust(msg, "pitch", AdjustmentPitchOffset, func(pitch float64) float64 {
+				return pitch - sc.PitchOffsetDegrees*math.Pi/180
+			})
+		}
+		if sc.RollOffsetDegrees != 0 {
+			msg = adjust(msg, "roll", AdjustmentRollOffset, func(roll float64) float64 {
+				return roll - sc.RollOffsetDegrees*math.Pi/180
+			})
+		}
+	case pgnWaterDepth:
+		if sc.DepthOffset != 0 {
+			msg = adjust(msg, "depth", AdjustmentDepthOffset, func(depth float64) float64 {
+				return depth + sc.DepthOffset
+			})
+		}
+	}
+	return msg
+}
+
+func adjust(msg nmea.Message, fieldID string, kind string, fn func(v float64) float64) nmea.Message {
+	for i, f := range msg.Fields {
+		if f.ID != fieldID {
+			continue
+		}
+		v, ok := f.Value.(float64)
+		if !ok {
+			return msg
+		}
+		fields := make(nmea.FieldValues, len(msg.Fields))
+		copy(fields, msg.Fields)
+		fields[i].Value = fn(v)
+		msg.Fields = fields
+		msg.Adjustments = append(msg.Adjustments, nmea.FieldAdjustment{
+			FieldID:  fieldID,
+			Kind:     kind,
+			Original: v,
+		})
+		return msg
+	}
+	return msg
+}
+
+// Deviation returns (linearly interpolated) deviation in degrees for given compass heading in degrees
+func (t DeviationTable) Deviation(headingDegrees float64) float64 {
+	switch len(t) {
+	case 0:
+		return 0
+	case 1:
+		return t[0].DeviationDegrees
+	}
+	heading := normalizeDegrees(headingDegrees)
+
+	// find first entry that is after given heading. Entries before first and after last are interpolated over 360.
+	idx := sort.Search(len(t), func(i int) bool { return t[i].HeadingDegrees >= heading })
+	var from, to DeviationPoint
+	switch idx {
+	case 0, len(t):
+		from = t[len(t)-1]
+		to = t[0]
+		from.HeadingDegrees -= 360
+		if heading > to.HeadingDegrees {
+			heading -= 360
+		}
+	default:
+		from = t[idx-1]
+		to = t[idx]
+	}
+	span := to.HeadingDegrees - from.HeadingDegrees
+	if span == 0 {
+		return to.DeviationDegrees
+	}
+	return from.DeviationDegrees + (heading-from.HeadingDegrees)/span*(to.DeviationDegrees-from.DeviationDegrees)
+}
+
+func normalizeDegrees(v float64) float64 {
+	v = math.Mod(v, 360)
+	if v < 0 {
+		v += 360
+	}
+	return v
+}
+
+func normalizeRadians(v float64) float64 {
+	v = math.Mod(v, 2*math.Pi)
+	if v < 0 {
+		v += 2 * math.Pi
+	}
+	return v
+}
+
+// Decoder decorates nmea.MessageDecoder and applies calibration to every successfully decoded message.
+type Decoder struct {
+	decoder    nmea.MessageDecoder
+	calibrator *Calibrator
+}
+
+// NewDecoder creates new instance of calibrating Decoder
+func NewDecoder(decoder nmea.MessageDecoder, calibrator *Calibrator) *Decoder {
+	return &Decoder{decoder: decoder, calibrator: calibrator}
+}
+
+// Decode decodes raw message with wrapped decoder and applies calibration to the result
+func (d *Decoder) Decode(raw nmea.RawMessage) (nmea.Message, error) {
+	msg, err := d.decoder.Decode(raw)
+	if err != nil {
+		return msg, err
+	}
+	return d.calibrator.Apply(msg), nil
+}
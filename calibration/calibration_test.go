@@ -0,0 +1,252 @@
+package calibration
+
+import (
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func deg2rad(v float64) float64 {
+	return v * math.Pi / 180
+}
+
+func TestDeviationTable_Deviation(t *testing.T) {
+	table := DeviationTable{
+		{HeadingDegrees: 0, DeviationDegrees: 2},
+		{HeadingDegrees: 90, DeviationDegrees: -2},
+		{HeadingDegrees: 180, DeviationDegrees: 4},
+		{HeadingDegrees: 270, DeviationDegrees: 0},
+	}
+
+	var testCases = []struct {
+		name    string
+		heading float64
+		expect  float64
+	}{
+		{name: "ok, exact entry", heading: 90, expect: -2},
+		{name: "ok, between entries", heading: 45, expect: 0},
+		{name: "ok, between entries 2", heading: 135, expect: 1},
+		{name: "ok, wraps around after last entry", heading: 315, expect: 1},
+		{name: "ok, negative heading is normalized", heading: -45, expect: 1},
+		{name: "ok, heading over 360 is normalized", heading: 405, expect: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expect, table.Deviation(tc.heading), 0.000001)
+		})
+	}
+}
+
+func TestDeviationTable_DeviationWrapBeforeFirst(t *testing.T) {
+	table := DeviationTable{
+		{HeadingDegrees: 10, DeviationDegrees: 2},
+		{HeadingDegrees: 350, DeviationDegrees: 0},
+	}
+	assert.InDelta(t, 1.0, table.Deviation(0), 0.000001)
+	assert.InDelta(t, 2.0, DeviationTable{{HeadingDegrees: 10, DeviationDegrees: 2}}.Deviation(100), 0.000001)
+	assert.Equal(t, 0.0, DeviationTable{}.Deviation(100))
+}
+
+func TestCalibrator_Apply(t *testing.T) {
+	calibrator := NewCalibrator(Config{
+		Sources: map[uint8]SourceCalibration{
+			1: {
+				HeadingDeviation: DeviationTable{
+					{HeadingDegrees: 180, DeviationDegrees: 10},
+					{HeadingDegrees: 0, DeviationDegrees: 10},
+				},
+			},
+			2: {
+				PitchOffsetDegrees: 1,
+				RollOffsetDegrees:  -2,
+			},
+			3: {
+				DepthOffset: 0.5,
+			},
+		},
+	})
+
+	var testCases = []struct {
+		name   string
+		when   nmea.Message
+		expect nmea.Message
+	}{
+		{
+			name: "ok, magnetic heading corrected with deviation",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "heading", Value: deg2rad(355)},
+					{ID: "reference", Value: nmea.EnumValue{Value: 1, Code: "Magnetic"}},
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "heading", Value: deg2rad(5)},
+					{ID: "reference", Value: nmea.EnumValue{Value: 1, Code: "Magnetic"}},
+				},
+				Adjustments: []nmea.FieldAdjustment{
+					{FieldID: "heading", Kind: AdjustmentHeadingDeviation, Original: deg2rad(355)},
+				},
+			},
+		},
+		{
+			name: "ok, true heading is not corrected",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "heading", Value: deg2rad(355)},
+					{ID: "reference", Value: nmea.EnumValue{Value: 0, Code: "True"}},
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "heading", Value: deg2rad(355)},
+					{ID: "reference", Value: nmea.EnumValue{Value: 0, Code: "True"}},
+				},
+			},
+		},
+		{
+			name: "ok, true heading with numeric reference is not corrected",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "heading", Value: deg2rad(355)},
+					{ID: "reference", Value: uint64(0)},
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "heading", Value: deg2rad(355)},
+					{ID: "reference", Value: uint64(0)},
+				},
+			},
+		},
+		{
+			name: "ok, pitch and roll zero offsets",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127257, Source: 2},
+				Fields: nmea.FieldValues{
+					{ID: "yaw", Value: deg2rad(10)},
+					{ID: "pitch", Value: deg2rad(3)},
+					{ID: "roll", Value: deg2rad(-3)},
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127257, Source: 2},
+				Fields: nmea.FieldValues{
+					{ID: "yaw", Value: deg2rad(10)},
+					{ID: "pitch", Value: deg2rad(2)},
+					{ID: "roll", Value: deg2rad(-1)},
+				},
+				Adjustments: []nmea.FieldAdjustment{
+					{FieldID: "pitch", Kind: AdjustmentPitchOffset, Original: deg2rad(3)},
+					{FieldID: "roll", Kind: AdjustmentRollOffset, Original: deg2rad(-3)},
+				},
+			},
+		},
+		{
+			name: "ok, depth offset",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "depth", Value: 10.0},
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "depth", Value: 10.5},
+				},
+				Adjustments: []nmea.FieldAdjustment{
+					{FieldID: "depth", Kind: AdjustmentDepthOffset, Original: 10.0},
+				},
+			},
+		},
+		{
+			name: "ok, depth with no data is not adjusted",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 3},
+				Fields: nmea.FieldValues{},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 3},
+				Fields: nmea.FieldValues{},
+			},
+		},
+		{
+			name: "ok, source without calibration",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 4},
+				Fields: nmea.FieldValues{{ID: "depth", Value: 10.0}},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 4},
+				Fields: nmea.FieldValues{{ID: "depth", Value: 10.0}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := calibrator.Apply(tc.when)
+
+			assert.Equal(t, tc.expect.Header, result.Header)
+			assert.Equal(t, tc.expect.Adjustments, result.Adjustments)
+			if assert.Len(t, result.Fields, len(tc.expect.Fields)) {
+				for i, f := range tc.expect.Fields {
+					assert.Equal(t, f.ID, result.Fields[i].ID)
+					if expected, ok := f.Value.(float64); ok {
+						assert.InDelta(t, expected, result.Fields[i].Value, 0.000001)
+					} else {
+						assert.Equal(t, f.Value, result.Fields[i].Value)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCalibrator_ApplyDoesNotModifyOriginalFields(t *testing.T) {
+	calibrator := NewCalibrator(Config{Sources: map[uint8]SourceCalibration{3: {DepthOffset: 0.5}}})
+	msg := nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 128267, Source: 3},
+		Fields: nmea.FieldValues{{ID: "depth", Value: 10.0}},
+	}
+
+	result := calibrator.Apply(msg)
+
+	assert.Equal(t, 10.5, result.Fields[0].Value)
+	assert.Equal(t, 10.0, msg.Fields[0].Value)
+}
+
+type decoderFunc func(raw nmea.RawMessage) (nmea.Message, error)
+
+func (f decoderFunc) Decode(raw nmea.RawMessage) (nmea.Message, error) {
+	return f(raw)
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	calibrator := NewCalibrator(Config{Sources: map[uint8]SourceCalibration{3: {DepthOffset: -1}}})
+	decoder := NewDecoder(decoderFunc(func(raw nmea.RawMessage) (nmea.Message, error) {
+		if raw.Header.PGN != 128267 {
+			return nmea.Message{}, errors.New("unknown PGN")
+		}
+		return nmea.Message{
+			Header: raw.Header,
+			Fields: nmea.FieldValues{{ID: "depth", Value: 10.0}},
+		}, nil
+	}), calibrator)
+
+	msg, err := decoder.Decode(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 128267, Source: 3}})
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.FieldValues{{ID: "depth", Value: 9.0}}, msg.Fields)
+	assert.Len(t, msg.Adjustments, 1)
+
+	_, err = decoder.Decode(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1, Source: 3}})
+	assert.EqualError(t, err, "unknown PGN")
+}
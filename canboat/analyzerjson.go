@@ -0,0 +1,309 @@
+package canboat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAnalyzerJSONUnknownPGN is returned when schema does not have definition for marshalled message PGN
+var ErrAnalyzerJSONUnknownPGN = errors.New("analyzer JSON marshal failed, unknown PGN given")
+
+// AnalyzerJSONMarshaller marshals decoded messages to same JSON format as Canboat `analyzer -json -si` outputs so
+// existing Canboat tooling can consume the output. Example:
+// `{"timestamp":"2016-04-09T16:41:18.104Z","prio":6,"src":16,"dst":255,"pgn":60928,"description":"ISO Address Claim","fields":{"Unique Number":175513,"Manufacturer Code":"Actisense"}}`
+//
+// Fields are keyed by field names (not IDs) in schema order and values are formatted as analyzer formats them:
+// * numbers are in SI units (as Decoder produces them) with precision of field resolution,
+// * lookups are resolved to their names (unknown lookup values are output as numbers), bit lookups as list of names,
+// * times as `hh:mm:ss.sss`, dates as `YYYY.MM.DD`, MMSI as 9-digit string and binary data as hex bytes `01 AB`,
+// * repeating field sets as list of objects in `list` (and `list2` for second set).
+//
+// Lookups are resolved from schema so messages can be decoded with or without DecoderConfig.DecodeLookupsToEnumType.
+type AnalyzerJSONMarshaller struct {
+	uniquePGNs  map[uint32]PGN
+	nonUniqPGNs map[uint32]PGNs
+
+	lookups         LookupEnumerations
+	indirectLookups LookupIndirectEnumerations
+	bitLookups      LookupBitEnumerations
+}
+
+// NewAnalyzerJSONMarshaller creates new instance of AnalyzerJSONMarshaller
+func NewAnalyzerJSONMarshaller(schema CanboatSchema) *AnalyzerJSONMarshaller {
+	s := newDecoderSchema(schema)
+	return &AnalyzerJSONMarshaller{
+		uniquePGNs:  s.uniquePGNs,
+		nonUniqPGNs: s.nonUniqPGNs,
+
+		lookups:         schema.Enums,
+		indirectLookups: schema.IndirectEnums,
+		bitLookups:      schema.BitEnums,
+	}
+}
+
+// Marshal marshals decoded message to Canboat analyzer JSON. Timestamp is taken from raw message time.
+func (m *AnalyzerJSONMarshaller) Marshal(msg nmea.Message, raw nmea.RawMessage) ([]byte, error) {
+	pgn, ok := m.findPGN(msg)
+	if !ok {
+		return nil, ErrAnalyzerJSONUnknownPGN
+	}
+
+	b := new(bytes.Buffer)
+	b.WriteString(`{"timestamp":"`)
+	b.WriteString(raw.Time.UTC().Format("2006-01-02T15:04:05.000Z"))
+	b.WriteString(`","prio":`)
+	b.WriteString(strconv.Itoa(int(msg.Header.Priority)))
+	b.WriteString(`,"src":`)
+	b.WriteString(strconv.Itoa(int(msg.Header.Source)))
+	b.WriteString(`,"dst":`)
+	b.WriteString(strconv.Itoa(int(msg.Header.Destination)))
+	b.WriteString(`,"pgn":`)
+	b.WriteString(strconv.FormatUint(uint64(msg.Header.PGN), 10))
+	b.WriteString(`,"description":`)
+	writeJSONString(b, pgn.Description)
+	b.WriteString(`,"fields":`)
+	if err := m.writeFields(b, pgn, msg.Fields); err != nil {
+		return nil, err
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (m *AnalyzerJSONMarshaller) findPGN(msg nmea.Message) (PGN, bool) {
+	if pgn, ok := m.uniquePGNs[msg.Header.PGN]; ok {
+		return pgn, true
+	}
+	for _, pgn := range m.nonUniqPGNs[msg.Header.PGN] {
+		if hasAllFields(pgn, msg.Fields) {
+			return pgn, true
+		}
+	}
+	return PGN{}, false
+}
+
+func (m *AnalyzerJSONMarshaller) writeFields(b *bytes.Buffer, pgn PGN, fields nmea.FieldValues) error {
+	b.WriteByte('{')
+	first := true
+	for _, f := range pgn.Fields {
+		fv, ok := fields.FindByID(f.ID)
+		if !ok {
+			continue
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		writeJSONString(b, f.Name)
+		b.WriteByte(':')
+		if err := m.writeValue(b, pgn, f, fv.Value, fields); err != nil {
+			return err
+		}
+	}
+	for _, set := range []struct {
+		id  string
+		key string
+	}{{id: "FIELDSET_1", key: "list"}, {id: "FIELDSET_2", key: "list2"}} {
+		fv, ok := fields.FindByID(set.id)
+		if !ok {
+			continue
+		}
+		groups, ok := fv.Value.([][]nmea.FieldValue)
+		if !ok {
+			return fmt.Errorf("analyzer JSON marshal failed, field %v value type %T is not supported", set.id, fv.Value)
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		b.WriteString(`"` + set.key + `":[`)
+		for i, group := range groups {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := m.writeFields(b, pgn, group); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func (m *AnalyzerJSONMarshaller) writeValue(b *bytes.Buffer, pgn PGN, f Field, value interface{}, fields nmea.FieldValues) error {
+	switch v := value.(type) {
+	case nmea.EnumValue:
+		writeJSONString(b, v.Code)
+		return nil
+	case []nmea.EnumValue:
+		if len(v) == 0 {
+			b.WriteString("null") // no bits set
+			return nil
+		}
+		names := make([]string, 0, len(v))
+		for _, ev := range v {
+			names = append(names, ev.Code)
+		}
+		writeJSONStrings(b, names)
+		return nil
+	case string:
+		writeJSONString(b, v)
+		return nil
+	case []byte:
+		writeJSONString(b, formatHexBytes(v))
+		return nil
+	case time.Duration:
+		writeJSONString(b, formatAnalyzerTime(v, f.Resolution))
+		return nil
+	case nmea.TimeOfDay:
+		writeJSONString(b, formatAnalyzerTime(v.Duration(), f.Resolution))
+		return nil
+	case time.Time:
+		writeJSONString(b, v.UTC().Format("2006.01.02"))
+		return nil
+	case float64:
+		b.WriteString(formatAnalyzerFloat(v, f.Resolution))
+		return nil
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+		return nil
+	case uint64:
+		return m.writeUint(b, pgn, f, v, fields)
+	}
+	return fmt.Errorf("analyzer JSON marshal failed, field %v value type %T is not supported", f.ID, value)
+}
+
+func (m *AnalyzerJSONMarshaller) writeUint(b *bytes.Buffer, pgn PGN, f Field, v uint64, fields nmea.FieldValues) error {
+	switch f.FieldType {
+	case FieldTypeMMSI:
+		writeJSONString(b, fmt.Sprintf("%09d", v))
+		return nil
+	case FieldTypeLookup:
+		if ev, err := m.lookups.FindValue(f.LookupEnumeration, uint32(v)); err == nil {
+			writeJSONString(b, ev.Name)
+			return nil
+		}
+	case FieldTypeBitLookup:
+		if evs, err := m.bitLookups.FindValue(f.LookupBitEnumeration, uint32(v)); err == nil {
+			if len(evs) == 0 {
+				b.WriteString("null") // no bits set
+				return nil
+			}
+			names := make([]string, 0, len(evs))
+			for _, ev := range evs {
+				names = append(names, ev.Name)
+			}
+			writeJSONStrings(b, names)
+			return nil
+		}
+	case FieldTypeIndirectLookup:
+		if indirect, ok := indirectValue(pgn, f, fields); ok {
+			if ev, err := m.indirectLookups.FindValue(f.LookupIndirectEnumeration, uint32(v), indirect); err == nil {
+				writeJSONString(b, ev.Name)
+				return nil
+			}
+		}
+	}
+	b.WriteString(strconv.FormatUint(v, 10))
+	return nil
+}
+
+// indirectValue finds value of field that indirect lookup field refers to by field order
+func indirectValue(pgn PGN, f Field, fields nmea.FieldValues) (uint32, bool) {
+	for _, pf := range pgn.Fields {
+		if pf.Order != f.LookupIndirectEnumerationFieldOrder {
+			continue
+		}
+		fv, ok := fields.FindByID(pf.ID)
+		if !ok {
+			return 0, false
+		}
+		switch v := fv.Value.(type) {
+		case uint64:
+			return uint32(v), true
+		case nmea.EnumValue:
+			return v.Value, true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	enc, _ := json.Marshal(s) // marshalling string never fails
+	b.Write(enc)
+}
+
+func writeJSONStrings(b *bytes.Buffer, s []string) {
+	enc, _ := json.Marshal(s)
+	b.Write(enc)
+}
+
+// maxFloatDecimals limits decimals of very small resolutions (i.e. 64bit latitude 1e-16) to avoid printing float64
+// representation noise
+const maxFloatDecimals = 10
+
+// formatAnalyzerFloat formats value with number of decimals that field resolution has (i.e. resolution 0.01 => 2)
+func formatAnalyzerFloat(v float64, resolution float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "null"
+	}
+	return strconv.FormatFloat(v, 'f', resolutionDecimals(resolution), 64)
+}
+
+func resolutionDecimals(resolution float64) int {
+	if resolution <= 0 || resolution >= 1 {
+		return -1
+	}
+	r := strconv.FormatFloat(resolution, 'f', -1, 64)
+	decimals := len(r) - strings.IndexByte(r, '.') - 1
+	if decimals > maxFloatDecimals {
+		return maxFloatDecimals
+	}
+	return decimals
+}
+
+// formatAnalyzerTime formats duration as `hh:mm:ss` with fractional seconds when resolution is less than second.
+// Hours are not limited to 24 as TIME fields are used for durations as well (i.e. engine hours).
+func formatAnalyzerTime(d time.Duration, resolution float64) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	h := d / time.Hour
+	mins := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	result := fmt.Sprintf("%s%02d:%02d:%02d", sign, h, mins, s)
+	if decimals := resolutionDecimals(resolution); decimals > 0 {
+		if decimals > 9 {
+			decimals = 9
+		}
+		frac := int64(d%time.Second) / int64(math.Pow10(9-decimals))
+		result += fmt.Sprintf(".%0*d", decimals, frac)
+	}
+	return result
+}
+
+// formatHexBytes formats bytes as uppercase hex separated by spaces (i.e. `01 AB FF`)
+func formatHexBytes(data []byte) string {
+	const hextable = "0123456789ABCDEF"
+	if len(data) == 0 {
+		return ""
+	}
+	b := make([]byte, 0, len(data)*3-1)
+	for i, v := range data {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, hextable[v>>4], hextable[v&0x0f])
+	}
+	return string(b)
+}
@@ -0,0 +1,177 @@
+package canboat
+
+import (
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAnalyzerJSONMarshaller_Marshal(t *testing.T) {
+	now := time.Date(2016, 4, 9, 16, 41, 18, 104_000_000, time.UTC)
+
+	schema := CanboatSchema{
+		PGNs: PGNs{
+			*loadPGN(t, "canboat_pgn_60928.json"),
+			*loadPGN(t, "canboat_pgn_126464.json"),
+			*loadPGN(t, "canboat_pgn_127489.json"),
+			*loadPGN(t, "canboat_pgn_129029.json"),
+		},
+		Enums: LookupEnumerations{
+			{Name: "MANUFACTURER_CODE", Values: []EnumValue{{Name: "Actisense", Value: 273}}},
+			{Name: "DEVICE_CLASS", Values: []EnumValue{{Name: "Propulsion", Value: 50}}},
+			{Name: "INDUSTRY_CODE", Values: []EnumValue{{Name: "Marine", Value: 4}}},
+			{Name: "ENGINE_INSTANCE", Values: []EnumValue{{Name: "Single Engine or Dual Engine Port", Value: 0}}},
+			{Name: "PGN_LIST_FUNCTION", Values: []EnumValue{{Name: "Receive PGN list", Value: 1}}},
+		},
+		BitEnums: LookupBitEnumerations{
+			{Name: "ENGINE_STATUS_1", Values: []BitEnumValue{{Name: "Low System Voltage", Bit: 5}}},
+			{Name: "ENGINE_STATUS_2", Values: []BitEnumValue{{Name: "Warning Level 1", Bit: 0}}},
+		},
+		IndirectEnums: LookupIndirectEnumerations{
+			{
+				Name:   "DEVICE_FUNCTION",
+				Values: []IndirectEnumValue{{Name: "Engine Gateway", IndirectValue: 50, Value: 160}},
+			},
+		},
+	}
+
+	var testCases = []struct {
+		name        string
+		givenConfig DecoderConfig
+		whenRaw     nmea.RawMessage
+		expect      string
+	}{
+		{
+			// echo "2016-04-09T16:41:18.104Z,6,60928,16,255,8,99,ad,22,22,00,a0,64,c0" | analyzer -json -si
+			name: "ok, PGN 60928 with lookups and indirect lookup",
+			whenRaw: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{Priority: 6, PGN: 60928, Destination: 255, Source: 16},
+				Data:   []byte{0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0},
+			},
+			expect: `{"timestamp":"2016-04-09T16:41:18.104Z","prio":6,"src":16,"dst":255,"pgn":60928,` +
+				`"description":"ISO Address Claim","fields":{"Unique Number":175513,"Manufacturer Code":"Actisense",` +
+				`"Device Instance Lower":0,"Device Instance Upper":0,"Device Function":"Engine Gateway",` +
+				`"Device Class":"Propulsion","System Instance":0,"Industry Group":"Marine"}}`,
+		},
+		{
+			name:        "ok, PGN 60928 decoded with lookups as enums",
+			givenConfig: DecoderConfig{DecodeLookupsToEnumType: true},
+			whenRaw: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{Priority: 6, PGN: 60928, Destination: 255, Source: 16},
+				Data:   []byte{0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0},
+			},
+			expect: `{"timestamp":"2016-04-09T16:41:18.104Z","prio":6,"src":16,"dst":255,"pgn":60928,` +
+				`"description":"ISO Address Claim","fields":{"Unique Number":175513,"Manufacturer Code":"Actisense",` +
+				`"Device Instance Lower":0,"Device Instance Upper":0,"Device Function":"Engine Gateway",` +
+				`"Device Class":"Propulsion","System Instance":0,"Industry Group":"Marine"}}`,
+		},
+		{
+			// echo "2016-04-09T16:41:18.104Z,3,126464,127,255,7,01,04,ff,01,11,fb,01" | analyzer -json -si
+			// analyzer outputs every repetition of set without count field as separate object in list.
+			name: "ok, PGN 126464 with repeating field set without count field",
+			whenRaw: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{Priority: 3, PGN: 126464, Destination: 255, Source: 127},
+				Data:   []byte{0x01, 0x04, 0xff, 0x01, 0x11, 0xfb, 0x01},
+			},
+			expect: `{"timestamp":"2016-04-09T16:41:18.104Z","prio":3,"src":127,"dst":255,"pgn":126464,` +
+				`"description":"PGN List (Transmit and Receive)","fields":{"Function Code":"Receive PGN list",` +
+				`"list":[{"PGN":130820},{"PGN":129809}]}}`,
+		},
+		{
+			// echo "2022-09-23T11:05:05.383Z,2,127489,236,255,26,00,28,00,ff,ff,bb,71,57,03,00,00,e0,b0,05,00,ff,ff,ff,ff,ff,20,00,00,00,7e,ff" | analyzer -json -si
+			name: "ok, PGN 127489 with bit lookups, time and resolution",
+			whenRaw: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{Priority: 2, PGN: 127489, Destination: 255, Source: 236},
+				Data: []byte{
+					0x00, 0x28, 0x00, 0xff, 0xff, 0xbb, 0x71, 0x57, 0x03, 0x00,
+					0x00, 0xe0, 0xb0, 0x05, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff,
+					0x20, 0x00, 0x00, 0x00, 0x7e, 0xff,
+				},
+			},
+			expect: `{"timestamp":"2016-04-09T16:41:18.104Z","prio":2,"src":236,"dst":255,"pgn":127489,` +
+				`"description":"Engine Parameters, Dynamic","fields":{"Instance":"Single Engine or Dual Engine Port",` +
+				`"Oil pressure":4000,"Temperature":291.15,"Alternator Potential":8.55,"Fuel Rate":0.0,` +
+				`"Total Engine hours":"103:36:00","Discrete Status 1":["Low System Voltage"],"Discrete Status 2":null,` +
+				`"Engine Torque":-1}}`,
+		},
+		{
+			name: "ok, PGN 129029 with date, time, unknown lookup and repeating field set",
+			whenRaw: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{Priority: 3, PGN: 129029, Destination: 255, Source: 127},
+				Data: []byte{
+					0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+					0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+					0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+					0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			expect: `{"timestamp":"2016-04-09T16:41:18.104Z","prio":3,"src":127,"dst":255,"pgn":129029,` +
+				`"description":"GNSS Position Data","fields":{"SID":0,"Date":"2021.05.14","Time":"07:06:40.5000",` +
+				`"Latitude":58.2161881667,"Longitude":22.3942873333,"Altitude":0.140000,` +
+				`"GNSS type":2,"Method":1,"Integrity":0,"Number of SVs":0,"HDOP":0.60,` +
+				`"PDOP":0.90,"Geoidal Separation":22.20,"Reference Stations":1,` +
+				`"list":[{"Reference Station Type":0,"Reference Station ID":0,"Age of DGNSS Corrections":"00:00:00.00"}]}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewDecoderWithConfig(schema, tc.givenConfig)
+			msg, err := decoder.Decode(tc.whenRaw)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			result, err := NewAnalyzerJSONMarshaller(schema).Marshal(msg, tc.whenRaw)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, string(result))
+		})
+	}
+}
+
+func TestAnalyzerJSONMarshaller_Marshal_unknownPGN(t *testing.T) {
+	m := NewAnalyzerJSONMarshaller(CanboatSchema{})
+
+	_, err := m.Marshal(nmea.Message{Header: nmea.CanBusHeader{PGN: 60928}}, nmea.RawMessage{})
+
+	assert.ErrorIs(t, err, ErrAnalyzerJSONUnknownPGN)
+}
+
+func TestAnalyzerJSONMarshaller_Marshal_values(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	pgn := PGN{
+		PGN:         65000,
+		Description: "Test",
+		Fields: []Field{
+			{ID: "mmsi", Name: "User ID", Order: 1, FieldType: FieldTypeMMSI},
+			{ID: "data", Name: "Data", Order: 2, FieldType: FieldTypeBinary},
+			{ID: "name", Name: "Name", Order: 3, FieldType: FieldTypeStringLAU},
+			{ID: "unknown", Name: "Unknown Lookup", Order: 4, FieldType: FieldTypeLookup, LookupEnumeration: "X"},
+		},
+	}
+	m := NewAnalyzerJSONMarshaller(CanboatSchema{PGNs: PGNs{pgn}})
+
+	result, err := m.Marshal(nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 65000, Priority: 7, Source: 1, Destination: 255},
+		Fields: nmea.FieldValues{
+			{ID: "mmsi", Value: uint64(2761000)},
+			{ID: "data", Value: []byte{0x01, 0xab}},
+			{ID: "name", Value: `"quoted"`},
+			{ID: "unknown", Value: uint64(9)},
+		},
+	}, nmea.RawMessage{Time: now})
+
+	assert.NoError(t, err)
+	expect := `{"timestamp":"2022-10-11T11:47:22.000Z","prio":7,"src":1,"dst":255,"pgn":65000,"description":"Test",` +
+		`"fields":{"User ID":"002761000","Data":"01 AB","Name":"\"quoted\"","Unknown Lookup":9}}`
+	assert.Equal(t, expect, string(result))
+}
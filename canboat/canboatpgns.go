@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"github.com/aldas/go-nmea-client"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 )
 
 // FieldType is type Canboat type field values
@@ -50,6 +53,10 @@ const (
 	// byte and terminating zero. The length of the string is determined by a starting length byte. The 2nd byte
 	// contains 0 for UNICODE or 1 for ASCII.
 	FieldTypeStringLAU FieldType = "STRING_LAU"
+	// FieldTypeStringVar - A varying length string containing single byte codepoints. The length of the string is
+	// determined either with a start (0x02) and stop (0x01) byte, or with a starting length byte (> 0x02), or an
+	// indication that the string is empty which is encoded by either 0x01 or 0x00 as the first byte.
+	FieldTypeStringVar FieldType = "STRING_VAR"
 	// FieldTypeBinary - Unspecified content consisting of any number of bits.
 	FieldTypeBinary FieldType = "BINARY"
 	// FieldTypeReserved - Reserved field. All reserved bits shall be 1
@@ -63,6 +70,14 @@ const (
 	// FieldTypeVariable - Variable. The definition of the field is that of the reference PGN and reference field,
 	// this is totally variable.
 	FieldTypeVariable FieldType = "VARIABLE"
+	// FieldTypePGN - PGN number. VARIABLE and KEY_VALUE fields in the same message refer to fields of this PGN.
+	FieldTypePGN FieldType = "PGN"
+	// FieldTypeFieldIndex - Index (order) of the field in the referenced PGN. Is key for VARIABLE and KEY_VALUE field
+	// that follows it.
+	FieldTypeFieldIndex FieldType = "FIELD_INDEX"
+	// FieldTypeKeyValue - Key/value. The definition of the field is that of the referenced PGN field that preceding
+	// FIELD_INDEX field (key) points to.
+	FieldTypeKeyValue FieldType = "KEY_VALUE"
 )
 
 var (
@@ -81,9 +96,9 @@ func (bv *FieldType) UnmarshalJSON(b []byte) error {
 	case string(FieldTypeNumber), string(FieldTypeFloat), string(FieldTypeDecimal), string(FieldTypeLookup),
 		string(FieldTypeIndirectLookup),
 		string(FieldTypeBitLookup), string(FieldTypeTime), string(FieldTypeDate), string(FieldTypeStringFix),
-		string(FieldTypeStringLz), string(FieldTypeStringLAU), string(FieldTypeBinary),
+		string(FieldTypeStringLz), string(FieldTypeStringLAU), string(FieldTypeStringVar), string(FieldTypeBinary),
 		string(FieldTypeReserved), string(FieldTypeSpare), string(FieldTypeMMSI),
-		string(FieldTypeVariable):
+		string(FieldTypeVariable), string(FieldTypePGN), string(FieldTypeFieldIndex), string(FieldTypeKeyValue):
 		tmp = FieldType(t)
 	default:
 		return fmt.Errorf("unknown FieldType value: `%v`", t)
@@ -147,6 +162,72 @@ func LoadCANBoatSchema(filesystem fs.FS, path string) (CanboatSchema, error) {
 	return schema, err
 }
 
+// LoadCANBoatSchemaFile loads CANBoat PGN schema from JSON file in local filesystem. Unlike LoadCANBoatSchema with
+// os.DirFS(".") path can be absolute or contain "..".
+func LoadCANBoatSchemaFile(path string) (CanboatSchema, error) {
+	return LoadCANBoatSchema(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+// LoadCANBoatSchemaFiles loads CANBoat PGN schemas from JSON files in local filesystem and merges them into single
+// schema. Definitions in later files override definitions in earlier files (see CanboatSchema.Merge). Useful to keep
+// proprietary vendor PGNs or local corrections in separate file from Canboat schema.
+func LoadCANBoatSchemaFiles(paths ...string) (CanboatSchema, error) {
+	if len(paths) == 0 {
+		return CanboatSchema{}, errors.New("no schema files given")
+	}
+	result := CanboatSchema{}
+	for i, path := range paths {
+		schema, err := LoadCANBoatSchemaFile(path)
+		if err != nil {
+			return CanboatSchema{}, fmt.Errorf("failed to load schema file %v, err: %w", path, err)
+		}
+		if i == 0 {
+			result = schema
+			continue
+		}
+		result = result.Merge(schema)
+	}
+	return result, nil
+}
+
+// Merge returns new schema with definitions from other schemas merged into this schema. PGN definition with same PGN
+// number and ID as existing definition replaces it, other definitions are added. Lookup enumerations with same name
+// replace existing ones. Schema version and metadata are kept from this schema.
+func (s CanboatSchema) Merge(others ...CanboatSchema) CanboatSchema {
+	result := s
+	result.PGNs = append(PGNs(nil), s.PGNs...)
+	result.Enums = append(LookupEnumerations(nil), s.Enums...)
+	result.IndirectEnums = append(LookupIndirectEnumerations(nil), s.IndirectEnums...)
+	result.BitEnums = append(LookupBitEnumerations(nil), s.BitEnums...)
+
+	for _, o := range others {
+		for _, pgn := range o.PGNs {
+			result.PGNs = replaceOrAddPGN(result.PGNs, pgn)
+		}
+		result.Enums = mergeByName(result.Enums, o.Enums, func(e Enum) string { return e.Name })
+		result.IndirectEnums = mergeByName(result.IndirectEnums, o.IndirectEnums, func(e IndirectEnum) string { return e.Name })
+		result.BitEnums = mergeByName(result.BitEnums, o.BitEnums, func(e BitEnum) string { return e.Name })
+	}
+	return result
+}
+
+func mergeByName[T any](existing []T, other []T, name func(T) string) []T {
+	for _, o := range other {
+		replaced := false
+		for i, e := range existing {
+			if name(e) == name(o) {
+				existing[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			existing = append(existing, o)
+		}
+	}
+	return existing
+}
+
 // PGNs is list of PNG instances
 type PGNs []PGN
 
@@ -278,57 +359,70 @@ func (f *Field) Validate() error {
 	return nil
 }
 
+// IsTimeOfDay checks if TIME field is time of day (seconds since midnight, i.e. PGN 129029 `time`) and not duration
+// (i.e. PGN 127506 `timeRemaining`). Canboat defines time of day fields as 32 bits with 0.0001s resolution and range
+// up to 24 hours.
+func (f *Field) IsTimeOfDay() bool {
+	return f.FieldType == FieldTypeTime && f.BitLength == 32 && f.Resolution == 0.0001 &&
+		(f.RangeMax == 0 || f.RangeMax <= 86402)
+}
+
 func (f *Field) IsMatch(rawData nmea.RawData) bool {
 	// we deliberately consider errors here as no match
-	value, err := rawData.DecodeVariableUint(f.BitOffset, f.BitLength)
+	value, err := rawData.DecodeVariableUint(uint32(f.BitOffset), uint32(f.BitLength))
 	return err == nil && uint64(f.Match) == value
 }
 
-func (f *Field) Decode(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, uint16, error) {
+func (f *Field) Decode(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, uint32, error) {
 	switch f.FieldType {
 	case FieldTypeNumber:
 		value, err := f.decodeNumber(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	case FieldTypeLookup, FieldTypeIndirectLookup, FieldTypeBitLookup:
 		// Decoder will convert them to other Enum types if needed
 		value, err := f.decodeNumber(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
+	case FieldTypePGN, FieldTypeFieldIndex:
+		value, err := f.decodeUint(rawData, bitOffset)
+		return value, uint32(f.BitLength), err
 	case FieldTypeReserved, FieldTypeSpare, FieldTypeBinary:
 		return f.decodeBytes(rawData, bitOffset)
 	case FieldTypeTime:
 		value, err := f.decodeTime(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	case FieldTypeMMSI:
 		value, err := f.decodeMMSI(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	case FieldTypeStringFix:
 		value, err := f.decodeStringFIX(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	case FieldTypeStringLz:
 		return f.decodeStringLZ(rawData, bitOffset)
 	case FieldTypeStringLAU:
 		return f.decodeStringLAU(rawData, bitOffset)
+	case FieldTypeStringVar:
+		return f.decodeStringVar(rawData, bitOffset)
 	case FieldTypeDate:
 		value, err := f.decodeDate(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	case FieldTypeDecimal:
 		value, err := f.decodeDecimal(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	case FieldTypeFloat:
 		value, err := f.decodeFloat(rawData, bitOffset)
-		return value, f.BitLength, err
+		return value, uint32(f.BitLength), err
 	}
 	return nmea.FieldValue{}, 0, fmt.Errorf("field type: %v, err: %w", f.FieldType, ErrUnsupportedFieldType)
 }
 
-func (f *Field) decodeNumber(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
+func (f *Field) decodeNumber(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
 	var tmpIntValue int64
 	var tmpUIntValue uint64
 	var err error
 	if f.Signed {
-		tmpIntValue, err = rawData.DecodeVariableInt(bitOffset, f.BitLength)
+		tmpIntValue, err = rawData.DecodeVariableInt(bitOffset, uint32(f.BitLength))
 	} else {
-		tmpUIntValue, err = rawData.DecodeVariableUint(bitOffset, f.BitLength)
+		tmpUIntValue, err = rawData.DecodeVariableUint(bitOffset, uint32(f.BitLength))
 	}
 	if err != nil {
 		return nmea.FieldValue{}, err
@@ -351,8 +445,17 @@ func (f *Field) decodeNumber(rawData nmea.RawData, bitOffset uint16) (nmea.Field
 	return nmea.FieldValue{ID: f.ID, Value: value}, nil
 }
 
-func (f *Field) decodeBytes(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, uint16, error) {
-	value, bits, err := rawData.DecodeBytes(bitOffset, f.BitLength, f.BitLengthVariable)
+// decodeUint decodes field value as is (without resolution and offset). Used for PGN numbers and field indexes.
+func (f *Field) decodeUint(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	value, err := rawData.DecodeVariableUint(bitOffset, uint32(f.BitLength))
+	if err != nil {
+		return nmea.FieldValue{}, err
+	}
+	return nmea.FieldValue{ID: f.ID, Value: value}, nil
+}
+
+func (f *Field) decodeBytes(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, uint32, error) {
+	value, bits, err := rawData.DecodeBytes(bitOffset, uint32(f.BitLength), f.BitLengthVariable)
 	if err != nil {
 		return nmea.FieldValue{}, 0, err
 	}
@@ -362,8 +465,8 @@ func (f *Field) decodeBytes(rawData nmea.RawData, bitOffset uint16) (nmea.FieldV
 	}, bits, nil
 }
 
-func (f *Field) decodeTime(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
-	value, err := rawData.DecodeTime(bitOffset, f.BitLength, f.Resolution)
+func (f *Field) decodeTime(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	value, err := rawData.DecodeTime(bitOffset, uint32(f.BitLength), f.Resolution)
 	if err != nil {
 		return nmea.FieldValue{}, err
 	}
@@ -373,8 +476,8 @@ func (f *Field) decodeTime(rawData nmea.RawData, bitOffset uint16) (nmea.FieldVa
 	}, nil
 }
 
-func (f *Field) decodeDate(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
-	str, err := rawData.DecodeDate(bitOffset, f.BitLength)
+func (f *Field) decodeDate(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	str, err := rawData.DecodeDate(bitOffset, uint32(f.BitLength))
 	if err != nil {
 		return nmea.FieldValue{}, err
 	}
@@ -384,8 +487,8 @@ func (f *Field) decodeDate(rawData nmea.RawData, bitOffset uint16) (nmea.FieldVa
 	}, nil
 }
 
-func (f *Field) decodeMMSI(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
-	mmsi, err := rawData.DecodeVariableUint(bitOffset, f.BitLength)
+func (f *Field) decodeMMSI(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	mmsi, err := rawData.DecodeVariableUint(bitOffset, uint32(f.BitLength))
 	if err != nil {
 		return nmea.FieldValue{}, err
 	}
@@ -396,8 +499,8 @@ func (f *Field) decodeMMSI(rawData nmea.RawData, bitOffset uint16) (nmea.FieldVa
 	}, nil
 }
 
-func (f *Field) decodeStringFIX(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
-	str, err := rawData.DecodeStringFix(bitOffset, f.BitLength)
+func (f *Field) decodeStringFIX(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	str, err := rawData.DecodeStringFix(bitOffset, uint32(f.BitLength))
 	if err != nil {
 		return nmea.FieldValue{}, err
 	}
@@ -407,8 +510,8 @@ func (f *Field) decodeStringFIX(rawData nmea.RawData, bitOffset uint16) (nmea.Fi
 	}, nil
 }
 
-func (f *Field) decodeStringLZ(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, uint16, error) {
-	str, readBits, err := rawData.DecodeStringLZ(bitOffset, f.BitLength)
+func (f *Field) decodeStringLZ(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, uint32, error) {
+	str, readBits, err := rawData.DecodeStringLZ(bitOffset, uint32(f.BitLength))
 	if err != nil {
 		return nmea.FieldValue{}, 0, err
 	}
@@ -418,7 +521,7 @@ func (f *Field) decodeStringLZ(rawData nmea.RawData, bitOffset uint16) (nmea.Fie
 	}, readBits, nil
 }
 
-func (f *Field) decodeStringLAU(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, uint16, error) {
+func (f *Field) decodeStringLAU(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, uint32, error) {
 	str, readBits, err := rawData.DecodeStringLAU(bitOffset)
 	if err != nil {
 		return nmea.FieldValue{}, 0, err
@@ -429,8 +532,19 @@ func (f *Field) decodeStringLAU(rawData nmea.RawData, bitOffset uint16) (nmea.Fi
 	}, readBits, nil
 }
 
-func (f *Field) decodeDecimal(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
-	decimal, err := rawData.DecodeDecimal(bitOffset, f.BitLength)
+func (f *Field) decodeStringVar(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, uint32, error) {
+	str, readBits, err := rawData.DecodeStringVar(bitOffset)
+	if err != nil {
+		return nmea.FieldValue{}, 0, err
+	}
+	return nmea.FieldValue{
+		ID:    f.ID,
+		Value: str,
+	}, readBits, nil
+}
+
+func (f *Field) decodeDecimal(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	decimal, err := rawData.DecodeDecimal(bitOffset, uint32(f.BitLength))
 	if err != nil {
 		return nmea.FieldValue{}, err
 	}
@@ -440,8 +554,8 @@ func (f *Field) decodeDecimal(rawData nmea.RawData, bitOffset uint16) (nmea.Fiel
 	}, nil
 }
 
-func (f *Field) decodeFloat(rawData nmea.RawData, bitOffset uint16) (nmea.FieldValue, error) {
-	float, err := rawData.DecodeFloat(bitOffset, f.BitLength)
+func (f *Field) decodeFloat(rawData nmea.RawData, bitOffset uint32) (nmea.FieldValue, error) {
+	float, err := rawData.DecodeFloat(bitOffset, uint32(f.BitLength))
 	if err != nil {
 		return nmea.FieldValue{}, err
 	}
@@ -488,6 +602,21 @@ func (pgns *PGNs) FastPacketPGNs() []uint32 {
 	return result
 }
 
+// TransmissionIntervals returns map of PGNs and their transmission intervals for PGNs that are sent periodically.
+func (pgns *PGNs) TransmissionIntervals() map[uint32]time.Duration {
+	result := make(map[uint32]time.Duration)
+	if pgns == nil {
+		return result
+	}
+	for _, pgn := range *pgns {
+		if pgn.TransmissionIrregular || pgn.TransmissionInterval <= 0 {
+			continue
+		}
+		result[pgn.PGN] = time.Duration(pgn.TransmissionInterval) * time.Millisecond
+	}
+	return result
+}
+
 // FilterByPGN returns list of matching PGN objects that match by PGN value
 func (pgns *PGNs) FilterByPGN(pgn uint32) PGNs {
 	result := PGNs{}
@@ -499,6 +628,17 @@ func (pgns *PGNs) FilterByPGN(pgn uint32) PGNs {
 	return result
 }
 
+// replaceOrAddPGN replaces definition with same PGN number and ID or adds definition to the end of list
+func replaceOrAddPGN(pgns PGNs, pgn PGN) PGNs {
+	for i, p := range pgns {
+		if p.PGN == pgn.PGN && p.ID == pgn.ID {
+			pgns[i] = pgn
+			return pgns
+		}
+	}
+	return append(pgns, pgn)
+}
+
 func (pgns *PGNs) Match(rawData []byte) (PGN, bool) {
 	for _, pgn := range *pgns {
 		if !pgn.IsMatchable {
@@ -6,7 +6,10 @@ import (
 	test_test "github.com/aldas/go-nmea-client/test"
 	"github.com/aldas/go-nmea-client/test/message_test"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestPGNs_validate(t *testing.T) {
@@ -38,6 +41,93 @@ func TestPGNs_validate(t *testing.T) {
 	//}
 }
 
+func TestLoadCANBoatSchemaFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema", "..", "pgns.json")
+	err := os.WriteFile(filepath.Clean(path), []byte(`{"Version":"1.0","PGNs":[{"PGN":127250,"Id":"vesselHeading"}]}`), 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema, err := LoadCANBoatSchemaFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", schema.Version)
+	if assert.Len(t, schema.PGNs, 1) {
+		assert.Equal(t, uint32(127250), schema.PGNs[0].PGN)
+	}
+
+	_, err = LoadCANBoatSchemaFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestLoadCANBoatSchemaFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "pgns.json")
+	vendor := filepath.Join(dir, "vendor.json")
+	err := os.WriteFile(base, []byte(`{"Version":"1.0","PGNs":[{"PGN":127250,"Id":"vesselHeading"}],`+
+		`"LookupEnumerations":[{"Name":"MODE","EnumValues":[{"Name":"Standby","Value":1}]}]}`), 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = os.WriteFile(vendor, []byte(`{"Version":"0.1","PGNs":[{"PGN":130845,"Id":"vendorPGN","Fields":[{"Id":"a","Match":1857}]}],`+
+		`"LookupEnumerations":[{"Name":"MODE","EnumValues":[{"Name":"Auto","Value":1}]}]}`), 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema, err := LoadCANBoatSchemaFiles(base, vendor)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", schema.Version)
+	if assert.Len(t, schema.PGNs, 2) {
+		assert.Equal(t, uint32(130845), schema.PGNs[1].PGN)
+		assert.True(t, schema.PGNs[1].IsMatchable)
+	}
+	assert.Equal(t, LookupEnumerations{{Name: "MODE", Values: []EnumValue{{Name: "Auto", Value: 1}}}}, schema.Enums)
+
+	_, err = LoadCANBoatSchemaFiles(base, filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "failed to load schema file")
+
+	_, err = LoadCANBoatSchemaFiles()
+	assert.EqualError(t, err, "no schema files given")
+}
+
+func TestCanboatSchema_Merge(t *testing.T) {
+	base := CanboatSchema{
+		Version: "5.0.0",
+		PGNs: PGNs{
+			{PGN: 127250, ID: "vesselHeading", Description: "original"},
+			{PGN: 130845, ID: "simnetCompassHeadingOffset"},
+		},
+		Enums:         LookupEnumerations{{Name: "A"}, {Name: "B"}},
+		IndirectEnums: LookupIndirectEnumerations{{Name: "I"}},
+		BitEnums:      LookupBitEnumerations{{Name: "BITS"}},
+	}
+	override := CanboatSchema{
+		Version: "0.0.1",
+		PGNs: PGNs{
+			{PGN: 127250, ID: "vesselHeading", Description: "corrected"},
+			{PGN: 130845, ID: "vendorHeadingOffset"},
+		},
+		Enums:    LookupEnumerations{{Name: "B", Values: []EnumValue{{Name: "x", Value: 1}}}, {Name: "C"}},
+		BitEnums: LookupBitEnumerations{{Name: "BITS2"}},
+	}
+
+	result := base.Merge(override)
+
+	assert.Equal(t, "5.0.0", result.Version)
+	assert.Equal(t, PGNs{
+		{PGN: 127250, ID: "vesselHeading", Description: "corrected"},
+		{PGN: 130845, ID: "simnetCompassHeadingOffset"},
+		{PGN: 130845, ID: "vendorHeadingOffset"},
+	}, result.PGNs)
+	assert.Equal(t, LookupEnumerations{{Name: "A"}, {Name: "B", Values: []EnumValue{{Name: "x", Value: 1}}}, {Name: "C"}}, result.Enums)
+	assert.Equal(t, LookupIndirectEnumerations{{Name: "I"}}, result.IndirectEnums)
+	assert.Equal(t, LookupBitEnumerations{{Name: "BITS"}, {Name: "BITS2"}}, result.BitEnums)
+
+	// original schema is not modified
+	assert.Equal(t, "original", base.PGNs[0].Description)
+	assert.Len(t, base.Enums, 2)
+}
+
 func TestPGN_Unmarshal(t *testing.T) {
 	var testCases = []struct {
 		name        string
@@ -210,7 +300,7 @@ func TestField_Decode(t *testing.T) {
 		givenRawData   []byte
 		when           Field
 		expect         nmea.FieldValue
-		expectReadBits uint16
+		expectReadBits uint32
 		expectError    string
 	}{
 		{
@@ -288,11 +378,24 @@ func TestField_Decode(t *testing.T) {
 			expect:         nmea.FieldValue{ID: "reserved", Value: []byte{3}},
 			expectReadBits: 2,
 		},
+		{
+			name:         "string var type with STX and ETX",
+			givenRawData: []uint8{0x01, 0x02, 0x41, 0x42, 0x01, 0xff},
+			when: Field{
+				ID:                "text",
+				Name:              "Text",
+				BitOffset:         8,
+				BitLengthVariable: true,
+				FieldType:         FieldTypeStringVar,
+			},
+			expect:         nmea.FieldValue{ID: "text", Value: "AB"},
+			expectReadBits: 32,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, readBits, err := tc.when.Decode(tc.givenRawData, tc.when.BitOffset)
+			result, readBits, err := tc.when.Decode(tc.givenRawData, uint32(tc.when.BitOffset))
 
 			assert.Equal(t, tc.expectReadBits, readBits)
 			message_test.AssertFieldValue(t, tc.expect, result, 0.00000_00001)
@@ -304,3 +407,19 @@ func TestField_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestPGNs_TransmissionIntervals(t *testing.T) {
+	pgns := PGNs{
+		{PGN: 129025, TransmissionInterval: 100},
+		{PGN: 127250, TransmissionInterval: 100},
+		{PGN: 126996, TransmissionIrregular: true},
+		{PGN: 129029, TransmissionInterval: 1000},
+		{PGN: 130845},
+	}
+
+	assert.Equal(t, map[uint32]time.Duration{
+		129025: 100 * time.Millisecond,
+		127250: 100 * time.Millisecond,
+		129029: 1 * time.Second,
+	}, pgns.TransmissionIntervals())
+}
@@ -0,0 +1,214 @@
+package canboat
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// WriteCANBoatConstantsGoSource writes Go source file with typed constants generated from schema:
+//   - PGN numbers (`PGNVesselHeading = uint32(127250)`)
+//   - field IDs of PGNs (`FieldVesselHeadingHeading = "heading"`)
+//   - lookup enumerations as types with named values and String method (`type DirectionReference uint8` and
+//     `DirectionReferenceMagnetic DirectionReference = 1`)
+//   - bit lookup enumerations as types with bit mask values (`EngineStatus1CheckEngine EngineStatus1 = 1 << 0`)
+//
+// Constants give compile-time safety instead of field ID and lookup name strings scattered in user code. Indirect
+// lookup enumerations are not generated as their values depend on other field value.
+func WriteCANBoatConstantsGoSource(w io.Writer, schema CanboatSchema, packageName string) error {
+	if packageName == "" {
+		return fmt.Errorf("package name is required")
+	}
+	g := constantsGenerator{used: map[string]bool{}}
+	if schema.Version != "" {
+		fmt.Fprintf(&g.src, "// SchemaVersion is version of Canboat schema constants were generated from\n")
+		fmt.Fprintf(&g.src, "const SchemaVersion = %q\n\n", schema.Version)
+	}
+	g.writePGNs(schema.PGNs)
+	g.writeFields(schema.PGNs)
+	for _, e := range schema.Enums {
+		g.writeEnum(e)
+	}
+	for _, e := range schema.BitEnums {
+		g.writeBitEnum(e)
+	}
+
+	src := bytes.Buffer{}
+	src.WriteString("// Code generated by canboatgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %v\n\n", packageName)
+	if g.importStrconv {
+		src.WriteString("import \"strconv\"\n\n")
+	}
+	src.Write(g.src.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated constants source, err: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+type constantsGenerator struct {
+	// src is generated source without package clause and imports
+	src bytes.Buffer
+	// used holds identifiers already declared in generated file
+	used map[string]bool
+	// importStrconv is set when generated source uses strconv package
+	importStrconv bool
+}
+
+// ident returns unique Go identifier. Identifier that is already used gets numeric suffix.
+func (g *constantsGenerator) ident(name string) string {
+	result := name
+	for i := 2; g.used[result]; i++ {
+		result = name + "_" + strconv.Itoa(i)
+	}
+	g.used[result] = true
+	return result
+}
+
+func (g *constantsGenerator) writePGNs(pgns PGNs) {
+	if len(pgns) == 0 {
+		return
+	}
+	seen := map[string]bool{}
+	g.src.WriteString("// PGN numbers\nconst (\n")
+	for _, pgn := range pgns {
+		key := fmt.Sprintf("%v/%v", pgn.PGN, pgn.ID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		name := g.ident("PGN" + goIdentifier(pgn.ID))
+		fmt.Fprintf(&g.src, "// %v is PGN %v %v\n", name, pgn.PGN, commentText(pgn.Description))
+		fmt.Fprintf(&g.src, "%v = uint32(%v)\n", name, pgn.PGN)
+	}
+	g.src.WriteString(")\n\n")
+}
+
+func (g *constantsGenerator) writeFields(pgns PGNs) {
+	seen := map[string]bool{}
+	for _, pgn := range pgns {
+		key := fmt.Sprintf("%v/%v", pgn.PGN, pgn.ID)
+		if seen[key] || len(pgn.Fields) == 0 {
+			continue
+		}
+		seen[key] = true
+
+		prefix := "Field" + goIdentifier(pgn.ID)
+		fmt.Fprintf(&g.src, "// Field IDs of PGN %v %v\nconst (\n", pgn.PGN, commentText(pgn.Description))
+		fieldIDs := map[string]bool{}
+		for _, f := range pgn.Fields {
+			if f.FieldType == FieldTypeReserved || f.FieldType == FieldTypeSpare || fieldIDs[f.ID] {
+				continue
+			}
+			fieldIDs[f.ID] = true
+			fmt.Fprintf(&g.src, "%v = %q\n", g.ident(prefix+goIdentifier(f.ID)), f.ID)
+		}
+		g.src.WriteString(")\n\n")
+	}
+}
+
+func (g *constantsGenerator) writeEnum(e Enum) {
+	if len(e.Values) == 0 {
+		return
+	}
+	typeName := g.ident(goIdentifier(e.Name))
+	maxValue := uint32(0)
+	for _, v := range e.Values {
+		if v.Value > maxValue {
+			maxValue = v.Value
+		}
+	}
+	fmt.Fprintf(&g.src, "// %v is %v lookup enumeration\ntype %v %v\n\n", typeName, e.Name, typeName, uintType(uint64(maxValue)))
+
+	names := make([]string, len(e.Values))
+	g.src.WriteString("const (\n")
+	for i, v := range e.Values {
+		names[i] = g.ident(typeName + goIdentifier(v.Name))
+		fmt.Fprintf(&g.src, "%v %v = %v\n", names[i], typeName, v.Value)
+	}
+	g.src.WriteString(")\n\n")
+
+	fmt.Fprintf(&g.src, "func (v %v) String() string {\nswitch v {\n", typeName)
+	seen := map[uint32]bool{}
+	for i, v := range e.Values {
+		if seen[v.Value] {
+			continue
+		}
+		seen[v.Value] = true
+		fmt.Fprintf(&g.src, "case %v:\nreturn %q\n", names[i], v.Name)
+	}
+	fmt.Fprintf(&g.src, "}\nreturn \"%v(\" + strconv.FormatUint(uint64(v), 10) + \")\"\n}\n\n", typeName)
+	g.importStrconv = true
+}
+
+func (g *constantsGenerator) writeBitEnum(e BitEnum) {
+	if len(e.Values) == 0 {
+		return
+	}
+	typeName := g.ident(goIdentifier(e.Name))
+	maxBit := uint32(0)
+	for _, v := range e.Values {
+		if v.Bit > maxBit {
+			maxBit = v.Bit
+		}
+	}
+	if maxBit > 63 {
+		return
+	}
+	fmt.Fprintf(&g.src, "// %v is %v bit lookup enumeration. Values are bit masks.\ntype %v %v\n\n", typeName, e.Name, typeName, uintType(uint64(1)<<maxBit))
+	g.src.WriteString("const (\n")
+	for _, v := range e.Values {
+		fmt.Fprintf(&g.src, "%v %v = 1 << %v\n", g.ident(typeName+goIdentifier(v.Name)), typeName, v.Bit)
+	}
+	g.src.WriteString(")\n\n")
+}
+
+func uintType(max uint64) string {
+	switch {
+	case max <= 0xff:
+		return "uint8"
+	case max <= 0xffff:
+		return "uint16"
+	case max <= 0xffff_ffff:
+		return "uint32"
+	}
+	return "uint64"
+}
+
+// goIdentifier converts Canboat ID or name (`vesselHeading`, `DIRECTION_REFERENCE`, `Under way using engine`) to
+// exported Go identifier part (`VesselHeading`, `DirectionReference`, `UnderWayUsingEngine`).
+func goIdentifier(name string) string {
+	isUpper := strings.ToUpper(name) == name
+	var sb strings.Builder
+	startWord := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			startWord = true
+			continue
+		}
+		switch {
+		case startWord:
+			sb.WriteRune(unicode.ToUpper(r))
+		case isUpper:
+			sb.WriteRune(unicode.ToLower(r))
+		default:
+			sb.WriteRune(r)
+		}
+		startWord = false
+	}
+	if sb.Len() == 0 {
+		return "Unknown"
+	}
+	return sb.String()
+}
+
+func commentText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,121 @@
+package canboat
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriteCANBoatConstantsGoSource(t *testing.T) {
+	schema := CanboatSchema{
+		PGNs: PGNs{
+			{PGN: 127488, ID: "engineParametersRapidUpdate", Description: "Engine Parameters, Rapid Update", Fields: []Field{
+				{ID: "instance", FieldType: FieldTypeLookup, LookupEnumeration: "ENGINE_INSTANCE"},
+				{ID: "speed", FieldType: FieldTypeNumber},
+				{ID: "reserved", FieldType: FieldTypeReserved},
+			}},
+		},
+		Enums: LookupEnumerations{
+			{Name: "ENGINE_INSTANCE", Values: []EnumValue{
+				{Name: "Single Engine or Dual Engine Port", Value: 0},
+				{Name: "Dual Engine Starboard", Value: 1},
+			}},
+			{Name: "MANUFACTURER_CODE", Values: []EnumValue{
+				{Name: "B&G", Value: 381},
+				{Name: "Airmar", Value: 135},
+			}},
+		},
+		BitEnums: LookupBitEnumerations{
+			{Name: "ENGINE_STATUS_1", Values: []BitEnumValue{
+				{Name: "Check Engine", Bit: 0},
+				{Name: "Over Temperature", Bit: 1},
+			}},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := WriteCANBoatConstantsGoSource(&buf, schema, "n2k")
+
+	assert.NoError(t, err)
+	expect := `// Code generated by canboatgen. DO NOT EDIT.
+
+package n2k
+
+import "strconv"
+
+// PGN numbers
+const (
+	// PGNEngineParametersRapidUpdate is PGN 127488 Engine Parameters, Rapid Update
+	PGNEngineParametersRapidUpdate = uint32(127488)
+)
+
+// Field IDs of PGN 127488 Engine Parameters, Rapid Update
+const (
+	FieldEngineParametersRapidUpdateInstance = "instance"
+	FieldEngineParametersRapidUpdateSpeed    = "speed"
+)
+
+// EngineInstance is ENGINE_INSTANCE lookup enumeration
+type EngineInstance uint8
+
+const (
+	EngineInstanceSingleEngineOrDualEnginePort EngineInstance = 0
+	EngineInstanceDualEngineStarboard          EngineInstance = 1
+)
+
+func (v EngineInstance) String() string {
+	switch v {
+	case EngineInstanceSingleEngineOrDualEnginePort:
+		return "Single Engine or Dual Engine Port"
+	case EngineInstanceDualEngineStarboard:
+		return "Dual Engine Starboard"
+	}
+	return "EngineInstance(" + strconv.FormatUint(uint64(v), 10) + ")"
+}
+
+// ManufacturerCode is MANUFACTURER_CODE lookup enumeration
+type ManufacturerCode uint16
+
+const (
+	ManufacturerCodeBG     ManufacturerCode = 381
+	ManufacturerCodeAirmar ManufacturerCode = 135
+)
+
+func (v ManufacturerCode) String() string {
+	switch v {
+	case ManufacturerCodeBG:
+		return "B&G"
+	case ManufacturerCodeAirmar:
+		return "Airmar"
+	}
+	return "ManufacturerCode(" + strconv.FormatUint(uint64(v), 10) + ")"
+}
+
+// EngineStatus1 is ENGINE_STATUS_1 bit lookup enumeration. Values are bit masks.
+type EngineStatus1 uint8
+
+const (
+	EngineStatus1CheckEngine     EngineStatus1 = 1 << 0
+	EngineStatus1OverTemperature EngineStatus1 = 1 << 1
+)
+`
+	assert.Equal(t, expect, buf.String())
+}
+
+func TestGoIdentifier(t *testing.T) {
+	var testCases = []struct {
+		when   string
+		expect string
+	}{
+		{when: "vesselHeading", expect: "VesselHeading"},
+		{when: "DIRECTION_REFERENCE", expect: "DirectionReference"},
+		{when: "Under way using engine", expect: "UnderWayUsingEngine"},
+		{when: "1/2 kts", expect: "12Kts"},
+		{when: "°", expect: "Unknown"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.when, func(t *testing.T) {
+			assert.Equal(t, tc.expect, goIdentifier(tc.when))
+		})
+	}
+}
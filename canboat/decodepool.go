@@ -0,0 +1,118 @@
+package canboat
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"runtime"
+	"sync"
+)
+
+// DecodeResult is result of decoding raw message with DecodePool
+type DecodeResult struct {
+	Raw     nmea.RawMessage
+	Message nmea.Message
+	Err     error
+}
+
+// DecodePoolConfig configures DecodePool instance
+type DecodePoolConfig struct {
+	// Workers is number of goroutines decoding messages.
+	// Defaults to: runtime.GOMAXPROCS(0)
+	Workers int
+	// QueueSize is maximum number of messages being decoded or waiting to be written to output. When queue is full
+	// reading input blocks until oldest result is written to output.
+	// Defaults to: 4 * Workers
+	QueueSize int
+}
+
+// DecodePool decodes raw messages with multiple goroutines sharing single Decoder. Results are output in same order
+// as messages were read from input so messages of same PGN and source stay in order.
+type DecodePool struct {
+	decoder *Decoder
+	config  DecodePoolConfig
+}
+
+type decodeJob struct {
+	raw    nmea.RawMessage
+	result chan DecodeResult
+}
+
+// NewDecodePool creates new instance of DecodePool with default configuration
+func NewDecodePool(decoder *Decoder) *DecodePool {
+	return NewDecodePoolWithConfig(decoder, DecodePoolConfig{})
+}
+
+// NewDecodePoolWithConfig creates new instance of DecodePool with given configuration
+func NewDecodePoolWithConfig(decoder *Decoder, config DecodePoolConfig) *DecodePool {
+	if config.Workers <= 0 {
+		config.Workers = runtime.GOMAXPROCS(0)
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 4 * config.Workers
+	}
+	return &DecodePool{
+		decoder: decoder,
+		config:  config,
+	}
+}
+
+// Run decodes messages read from `in` and writes results to `out` in input order. Run blocks until `in` is closed and
+// all results have been written (returns nil) or context is cancelled (returns context error). Run does not close
+// `out`.
+func (p *DecodePool) Run(ctx context.Context, in <-chan nmea.RawMessage, out chan<- DecodeResult) error {
+	jobs := make(chan *decodeJob, p.config.QueueSize)
+	ordered := make(chan *decodeJob, p.config.QueueSize)
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	wg.Add(p.config.Workers)
+	for i := 0; i < p.config.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				msg, err := p.decoder.Decode(job.raw)
+				job.result <- DecodeResult{Raw: job.raw, Message: msg, Err: err}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(ordered)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok := <-in:
+				if !ok {
+					return
+				}
+				job := &decodeJob{raw: raw, result: make(chan DecodeResult, 1)}
+				select { // ordered queue limits how many messages are in flight
+				case ordered <- job:
+				case <-ctx.Done():
+					return
+				}
+				jobs <- job
+			}
+		}
+	}()
+
+	for job := range ordered {
+		var result DecodeResult
+		select {
+		case result = <-job.result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case out <- result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ctx.Err()
+}
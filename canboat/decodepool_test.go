@@ -0,0 +1,162 @@
+package canboat
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestDecodePool_Run(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{*loadPGN(t, "canboat_pgn_127257.json")}})
+	pool := NewDecodePoolWithConfig(decoder, DecodePoolConfig{Workers: 4, QueueSize: 2})
+
+	in := make(chan nmea.RawMessage)
+	out := make(chan DecodeResult, 100)
+	go func() {
+		for i := 0; i < 100; i++ {
+			pgn := uint32(127257)
+			if i%10 == 0 {
+				pgn = 1 // unknown to schema
+			}
+			in <- nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: pgn, Source: uint8(i)},
+				Data:   []byte{0x0, 0x01, 0x00, 0x77, 0xfc, 0xec, 0xf9, 0xff},
+			}
+		}
+		close(in)
+	}()
+
+	err := pool.Run(context.Background(), in, out)
+	assert.NoError(t, err)
+	close(out)
+
+	i := 0
+	for result := range out {
+		assert.Equal(t, uint8(i), result.Raw.Header.Source)
+		if i%10 == 0 {
+			assert.ErrorIs(t, result.Err, ErrDecodeUnknownPGN)
+		} else {
+			assert.NoError(t, result.Err)
+			assert.Equal(t, uint8(i), result.Message.Header.Source)
+			assert.Len(t, result.Message.Fields, 4)
+		}
+		i++
+	}
+	assert.Equal(t, 100, i)
+}
+
+func TestDecodePool_Run_contextCancelled(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{})
+	pool := NewDecodePool(decoder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan nmea.RawMessage, 1)
+	in <- nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}}
+	out := make(chan DecodeResult) // nobody reads output
+	go cancel()
+
+	err := pool.Run(ctx, in, out)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDecoder_Decode_concurrentSchemaChanges(t *testing.T) {
+	pgn := *loadPGN(t, "canboat_pgn_127257.json")
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{pgn}})
+	raw := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 127257},
+		Data:   []byte{0x0, 0x01, 0x00, 0x77, 0xfc, 0xec, 0xf9, 0xff},
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				msg, err := decoder.Decode(raw)
+				if err == nil {
+					assert.Len(t, msg.Fields, 4)
+				} else {
+					assert.ErrorIs(t, err, ErrDecodeUnknownPGN)
+				}
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		decoder.RemovePGN(127257)
+		assert.NoError(t, decoder.AddPGN(pgn))
+		decoder.Reload(CanboatSchema{PGNs: PGNs{pgn}})
+	}
+	wg.Wait()
+}
+
+func loadBenchmarkDecoder(b *testing.B) *Decoder {
+	content, err := os.ReadFile("testdata/canboat_pgn_127257.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	pgn := PGN{}
+	if err := json.Unmarshal(content, &pgn); err != nil {
+		b.Fatal(err)
+	}
+	return NewDecoder(CanboatSchema{PGNs: PGNs{pgn}})
+}
+
+var benchmarkRaw = nmea.RawMessage{
+	Header: nmea.CanBusHeader{PGN: 127257},
+	Data:   []byte{0x0, 0x01, 0x00, 0x77, 0xfc, 0xec, 0xf9, 0xff},
+}
+
+func BenchmarkDecoder_Decode(b *testing.B) {
+	decoder := loadBenchmarkDecoder(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decoder.Decode(benchmarkRaw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder_Decode_parallel(b *testing.B) {
+	decoder := loadBenchmarkDecoder(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := decoder.Decode(benchmarkRaw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodePool_Run(b *testing.B) {
+	pool := NewDecodePool(loadBenchmarkDecoder(b))
+	in := make(chan nmea.RawMessage, 64)
+	out := make(chan DecodeResult, 64)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			in <- benchmarkRaw
+		}
+		close(in)
+	}()
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if err := pool.Run(context.Background(), in, out); err != nil {
+		b.Fatal(err)
+	}
+	close(out)
+	<-done
+}
@@ -4,11 +4,39 @@ import (
 	"errors"
 	"fmt"
 	"github.com/aldas/go-nmea-client"
-	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
 	ErrDecodeUnknownPGN = errors.New("decode failed, unknown PGN seen")
+	// ErrUnresolvedVariableField is returned when definition of VARIABLE or KEY_VALUE type field can not be resolved as
+	// referenced PGN or field is unknown to schema.
+	ErrUnresolvedVariableField = errors.New("variable field definition could not be resolved")
+	// ErrInvalidDataLength is returned with LengthValidationStrict when message data length does not match PGN
+	// definition Length.
+	ErrInvalidDataLength = errors.New("decode failed, message data length does not match PGN definition")
+)
+
+const (
+	// WarningRepetitionCountOutOfRange is warning code for repeating field set count that was larger than allowed by
+	// schema or DecoderConfig.MaxRepetitionCount and was capped.
+	WarningRepetitionCountOutOfRange = "repetition_count_out_of_range"
+	// WarningRepetitionCountExceedsData is warning code for repeating field set count that was larger than remaining
+	// message data could hold and was capped.
+	WarningRepetitionCountExceedsData = "repetition_count_exceeds_data"
+	// WarningPartialPGNMatch is warning code for message that was decoded with best partially matching PGN definition
+	// as none of the PGN definitions matched message data fully (DecoderConfig.PartialMatchFallback).
+	WarningPartialPGNMatch = "partial_pgn_match"
+	// WarningDataTooShort is warning code for message that has less data than PGN definition Length (or MinLength)
+	// requires (DecoderConfig.LengthValidation).
+	WarningDataTooShort = "data_too_short"
+	// WarningDataTooLong is warning code for message that has more data than PGN definition Length
+	// (DecoderConfig.LengthValidation). Trailing 0xFF padding bytes are not counted.
+	WarningDataTooLong = "data_too_long"
 )
 
 type DecoderConfig struct {
@@ -18,10 +46,86 @@ type DecoderConfig struct {
 	DecodeSpareFields bool
 	// DecodeLookupsToEnumType instructs Decoder to convert lookup number to actual enum text+value pair
 	DecodeLookupsToEnumType bool
+	// RepeatCountNoData decides how repeating field set is decoded when its count field has no data (i.e. 0xFF).
+	// Defaults to: RepeatCountNoDataAsZero
+	RepeatCountNoData RepeatCountNoDataMode
+	// MaxRepetitionCount limits how many times repeating field set is decoded. Value of 0 means that there is no
+	// limit besides count field RangeMax from schema and remaining data length.
+	MaxRepetitionCount int
+	// DecodeAbsentFields instructs Decoder to fill Message.Absent with fields that have no value (no data, out of range,
+	// reserved value or not transmitted). Reserved and spare type fields are never reported as absent. Fields inside
+	// repeating field sets are not reported.
+	DecodeAbsentFields bool
+	// IncludeRawBits instructs Decoder to fill nmea.FieldValue.Raw with bit offset, bit length and message data bytes
+	// that field value was decoded from. Useful for debugging device firmware or schema (similar to Canboat
+	// analyzer `-debug` output).
+	IncludeRawBits bool
+	// Units determines in which units numeric field values are output and if values are annotated with unit
+	// (nmea.FieldValue.Unit). Defaults to: UnitSystemDefault (SI units without annotation)
+	//
+	// Note: Encoder, calibration and Signal K conversion expect values in SI units.
+	Units UnitSystem
+	// PartialMatchFallback instructs Decoder to decode message with best partially matching definition when PGN has
+	// multiple definitions (i.e. proprietary PGNs 130845, 130824) and none of them matches message data fully (i.e.
+	// manufacturer code is unknown to schema). Message gets WarningPartialPGNMatch warning listing candidates. When
+	// false, or when best candidate matches none of its match fields, ErrDecodeUnknownPGN is returned for such
+	// messages. See Decoder.Candidates.
+	PartialMatchFallback bool
+	// DecodeTimeOfDay instructs Decoder to decode TIME fields that are time of day (i.e. PGN 129029 `time`, see
+	// Field.IsTimeOfDay) as nmea.TimeOfDay instead of time.Duration.
+	DecodeTimeOfDay bool
+	// LengthValidation determines how messages with data length different from PGN definition Length are handled.
+	// Only fixed length PGNs (definitions with Length and without repeating field sets) are validated.
+	// Defaults to: LengthValidationPermissive
+	LengthValidation LengthValidationMode
 }
 
+// LengthValidationMode determines how Decoder handles messages with data length different from PGN definition.
+// Real devices send PGNs with shorter (older firmware) or longer (newer PGN revision) data than schema defines.
+type LengthValidationMode uint8
+
+const (
+	// LengthValidationPermissive decodes fields that fit into message data. Fields that do not fit are not decoded and
+	// extra data is ignored.
+	LengthValidationPermissive LengthValidationMode = iota
+	// LengthValidationReport decodes message as LengthValidationPermissive but adds WarningDataTooShort or
+	// WarningDataTooLong warning to message so data quality problems are visible.
+	LengthValidationReport
+	// LengthValidationStrict returns ErrInvalidDataLength for messages with data length different from PGN definition.
+	LengthValidationStrict
+)
+
+// RepeatCountNoDataMode determines how Decoder handles repeating field set when its count field value has no data
+type RepeatCountNoDataMode uint8
+
+const (
+	// RepeatCountNoDataAsZero treats no data count field as zero repetitions. Data following the count field
+	// that would belong to repeating field set is not decoded.
+	RepeatCountNoDataAsZero RepeatCountNoDataMode = iota
+	// RepeatCountNoDataUntilEnd treats no data count field as unknown count and repeating field set is decoded until
+	// the end of the message data.
+	RepeatCountNoDataUntilEnd
+)
+
+// Decoder decodes raw messages to fields using Canboat schema. PGN definitions and lookups can be changed at runtime
+// with AddPGN, RemovePGN and Reload.
+//
+// Decoder is safe for concurrent use. Decode does not lock nor mutate decoder state: PGN definitions and lookups are
+// immutable snapshot that AddPGN, RemovePGN and Reload replace as whole (copy-on-write), so single Decoder instance
+// can be shared by any number of goroutines (see DecodePool). Schema given to decoder must not be modified afterwards.
 type Decoder struct {
-	config DecoderConfig
+	config  DecoderConfig
+	timeNow func() time.Time
+
+	// mutex serializes schema changes. Decoding reads schema snapshot without locking.
+	mutex  sync.Mutex
+	schema atomic.Pointer[decoderSchema]
+}
+
+// decoderSchema is immutable snapshot of PGN definitions and lookups used for decoding. Snapshot is never modified
+// after it has been stored to Decoder.
+type decoderSchema struct {
+	version string
 
 	uniquePGNs  map[uint32]PGN
 	nonUniqPGNs map[uint32]PGNs
@@ -31,6 +135,43 @@ type Decoder struct {
 	bitLookups      LookupBitEnumerations
 }
 
+func newDecoderSchema(schema CanboatSchema) *decoderSchema {
+	uniq, nonUniq := indexPGNs(schema.PGNs)
+	return &decoderSchema{
+		version: schema.Version,
+
+		uniquePGNs:  uniq,
+		nonUniqPGNs: nonUniq,
+
+		lookups:         schema.Enums,
+		indirectLookups: schema.IndirectEnums,
+		bitLookups:      schema.BitEnums,
+	}
+}
+
+// withPGNs creates copy of snapshot with PGN definitions of given PGN number replaced with given definitions
+func (s *decoderSchema) withPGNs(pgn uint32, definitions PGNs) *decoderSchema {
+	result := *s
+	result.uniquePGNs = make(map[uint32]PGN, len(s.uniquePGNs)+1)
+	for k, v := range s.uniquePGNs {
+		if k != pgn {
+			result.uniquePGNs[k] = v
+		}
+	}
+	result.nonUniqPGNs = make(map[uint32]PGNs, len(s.nonUniqPGNs)+1)
+	for k, v := range s.nonUniqPGNs {
+		if k != pgn {
+			result.nonUniqPGNs[k] = v
+		}
+	}
+	if len(definitions) == 1 {
+		result.uniquePGNs[pgn] = definitions[0]
+	} else if len(definitions) > 1 {
+		result.nonUniqPGNs[pgn] = definitions
+	}
+	return &result
+}
+
 // NewDecoderWithConfig creates new instance of Canboat PGN decoder with given config
 func NewDecoderWithConfig(schema CanboatSchema, config DecoderConfig) *Decoder {
 	d := NewDecoder(schema)
@@ -40,148 +181,389 @@ func NewDecoderWithConfig(schema CanboatSchema, config DecoderConfig) *Decoder {
 
 // NewDecoder creates new instance of Canboat PGN decoder
 func NewDecoder(schema CanboatSchema) *Decoder {
+	d := &Decoder{
+		timeNow: time.Now,
+	}
+	d.schema.Store(newDecoderSchema(schema))
+	return d
+}
+
+// indexPGNs splits PGN definitions to PGNs that have single definition and PGNs that have multiple definitions (need
+// matching by field values).
+func indexPGNs(pgns PGNs) (map[uint32]PGN, map[uint32]PGNs) {
 	uniq := map[uint32]PGN{}
 	nonUniq := map[uint32]PGNs{}
-	for _, pgn := range schema.PGNs {
+	for _, pgn := range pgns {
 		existing, ok := uniq[pgn.PGN]
 		if !ok {
+			if group, isNonUniq := nonUniq[pgn.PGN]; isNonUniq {
+				nonUniq[pgn.PGN] = append(group, pgn)
+				continue
+			}
 			uniq[pgn.PGN] = pgn
 			continue
 		}
 
 		delete(uniq, pgn.PGN)
-		group, ok := nonUniq[pgn.PGN]
-		if !ok {
-			group = PGNs{existing}
+		nonUniq[pgn.PGN] = PGNs{existing, pgn}
+	}
+	return uniq, nonUniq
+}
+
+// Reload replaces PGN definitions and lookups of decoder with given schema. Decode calls in progress finish with
+// previous schema. Useful to hot-reload schema file without recreating decoder.
+func (d *Decoder) Reload(schema CanboatSchema) {
+	s := newDecoderSchema(schema)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.schema.Store(s)
+}
+
+// AddPGN adds PGN definition to decoder. Definition with same PGN number and ID as existing definition replaces it.
+// Lookups referenced by definition fields must exist in decoder schema.
+func (d *Decoder) AddPGN(pgn PGN) error {
+	if len(pgn.Fields) == 0 {
+		return fmt.Errorf("PGN %v definition has no fields", pgn.PGN)
+	}
+	if errs := (&PGNs{pgn}).Validate(); len(errs) > 0 {
+		return errs[0]
+	}
+	pgn.IsMatchable = false
+	for _, f := range pgn.Fields {
+		if f.Match != 0 {
+			pgn.IsMatchable = true
+			break
 		}
-		group = append(group, pgn)
-		nonUniq[pgn.PGN] = group
 	}
-	return &Decoder{
-		uniquePGNs:  uniq,
-		nonUniqPGNs: nonUniq,
 
-		lookups:         schema.Enums,
-		indirectLookups: schema.IndirectEnums,
-		bitLookups:      schema.BitEnums,
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s := d.schema.Load()
+	definitions := PGNs{}
+	if existing, ok := s.uniquePGNs[pgn.PGN]; ok {
+		definitions = append(definitions, existing)
 	}
+	definitions = append(definitions, s.nonUniqPGNs[pgn.PGN]...)
+	definitions = replaceOrAddPGN(definitions, pgn)
+
+	d.schema.Store(s.withPGNs(pgn.PGN, definitions))
+	return nil
+}
+
+// RemovePGN removes all definitions of given PGN number from decoder. Returns false when decoder had no definition
+// for PGN.
+func (d *Decoder) RemovePGN(pgn uint32) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s := d.schema.Load()
+	_, uniqOK := s.uniquePGNs[pgn]
+	_, nonUniqOK := s.nonUniqPGNs[pgn]
+	if !uniqOK && !nonUniqOK {
+		return false
+	}
+	d.schema.Store(s.withPGNs(pgn, nil))
+	return true
+}
+
+// SchemaVersion returns version of Canboat schema that decoder was created with
+func (d *Decoder) SchemaVersion() string {
+	return d.schema.Load().version
 }
 
 type decoded struct {
 	Field    Field
 	Value    nmea.FieldValue
 	ValueSet [][]decoded
+	// absence is reason why field has no value. Set only with errValueIgnored error.
+	absence nmea.AbsenceReason
 }
 
+// Decode decodes raw message to fields. Decode calls running concurrently with AddPGN, RemovePGN or Reload use schema
+// that was current when call started.
 func (d *Decoder) Decode(raw nmea.RawMessage) (nmea.Message, error) {
-	pgn, err := d.findPGN(raw)
+	s := d.schema.Load()
+
+	var warnings []nmea.DecodeWarning
+	pgn, err := s.findPGN(raw)
+	if err == ErrDecodeUnknownPGN && d.config.PartialMatchFallback {
+		if candidates := s.candidates(raw); len(candidates) > 0 && candidates[0].Score > 0 {
+			pgn = candidates[0].Definition
+			warnings = append(warnings, partialMatchWarning(candidates))
+			err = nil
+		}
+	}
 	if err != nil {
 		return nmea.Message{}, err
 	}
+	if d.config.LengthValidation != LengthValidationPermissive {
+		if warning := validateLength(pgn, raw.Data); warning != nil {
+			if d.config.LengthValidation == LengthValidationStrict {
+				return nmea.Message{}, fmt.Errorf("%w: %v", ErrInvalidDataLength, warning.Message)
+			}
+			warnings = append(warnings, *warning)
+		}
+	}
 	var decodedFields []decoded
+	var repetitionWarnings []nmea.DecodeWarning
+	var absent []nmea.AbsentField
 	if pgn.RepeatingFieldSet1StartField > 0 || pgn.RepeatingFieldSet2StartField > 0 {
-		decodedFields, err = d.decodeWithRepeatedFields(pgn, raw)
+		decodedFields, repetitionWarnings, absent, err = d.decodeWithRepeatedFields(s, pgn, raw)
+		warnings = append(warnings, repetitionWarnings...)
 	} else {
-		decodedFields, err = d.decode(pgn, raw)
+		decodedFields, absent, err = d.decode(s, pgn, raw)
 	}
 	if err != nil {
 		return nmea.Message{}, err
 	}
 
-	fields, err := d.postProcessFields(decodedFields)
+	fields, err := d.postProcessFields(s, decodedFields)
 	if err != nil {
 		return nmea.Message{}, err
 	}
 
+	var timing *nmea.MessageTiming
+	if !raw.BusTime.IsZero() {
+		timing = nmea.NewMessageTiming(raw, d.timeNow())
+	}
+
 	return nmea.Message{
-		Header: raw.Header,
-		Fields: fields,
+		Header:   raw.Header,
+		Fields:   fields,
+		Warnings: warnings,
+		Absent:   absent,
+		Timing:   timing,
 	}, nil
 }
 
 var errValueIgnored = errors.New("field value ignored")
 
-func (d *Decoder) decodeSingleField(raw nmea.RawMessage, f Field, bitOffset uint16) (decoded, uint16, error) {
+// validateLength checks message data length against fixed length PGN definition. Single frame messages are padded to
+// 8 bytes with 0xFF so trailing 0xFF bytes are not considered as extra data.
+func validateLength(pgn PGN, data nmea.RawData) *nmea.DecodeWarning {
+	if pgn.Length <= 0 || pgn.RepeatingFieldSet1StartField > 0 || pgn.RepeatingFieldSet2StartField > 0 {
+		return nil
+	}
+	minLength := int(pgn.Length)
+	if pgn.MinLength > 0 && int(pgn.MinLength) < minLength {
+		minLength = int(pgn.MinLength)
+	}
+	if len(data) < minLength {
+		return &nmea.DecodeWarning{
+			Code:    WarningDataTooShort,
+			Message: fmt.Sprintf("PGN %v data length %v is less than %v", pgn.PGN, len(data), minLength),
+		}
+	}
+	length := len(data)
+	for length > int(pgn.Length) && data[length-1] == 0xff {
+		length--
+	}
+	if length > int(pgn.Length) {
+		return &nmea.DecodeWarning{
+			Code:    WarningDataTooLong,
+			Message: fmt.Sprintf("PGN %v data length %v is more than %v", pgn.PGN, len(data), pgn.Length),
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeSingleField(s *decoderSchema, raw nmea.RawMessage, f Field, bitOffset uint32, ref *variableReference) (decoded, uint32, error) {
 	if (f.FieldType == FieldTypeReserved && !d.config.DecodeReservedFields) ||
 		(f.FieldType == FieldTypeSpare && !d.config.DecodeSpareFields) {
-		return decoded{}, f.BitLength, errValueIgnored
+		return decoded{}, uint32(f.BitLength), errValueIgnored
+	}
+	if f.FieldType == FieldTypeVariable || f.FieldType == FieldTypeKeyValue {
+		return d.decodeVariableField(s, raw, f, bitOffset, ref)
 	}
 
 	fv, readBits, err := f.Decode(raw.Data, bitOffset)
+	ref.update(f, fv, err)
 	if err != nil {
-		if err == nmea.ErrValueNoData || err == nmea.ErrValueOutOfRange || err == nmea.ErrValueReserved {
-			return decoded{}, readBits, errValueIgnored
+		switch err {
+		case nmea.ErrValueNoData:
+			return decoded{Field: f, absence: nmea.AbsenceNoData}, readBits, errValueIgnored
+		case nmea.ErrValueOutOfRange:
+			return decoded{Field: f, absence: nmea.AbsenceOutOfRange}, readBits, errValueIgnored
+		case nmea.ErrValueReserved:
+			return decoded{Field: f, absence: nmea.AbsenceReserved}, readBits, errValueIgnored
 		}
 		return decoded{}, 0, fmt.Errorf("decoder failed to decode field: %v, err: %w", f.ID, err)
 	}
+	if d.config.DecodeTimeOfDay && f.IsTimeOfDay() {
+		if v, ok := fv.Value.(time.Duration); ok {
+			fv.Value = nmea.TimeOfDay(v)
+		}
+	}
+	if d.config.Units != UnitSystemDefault {
+		fv = convertUnit(f, fv, d.config.Units)
+	}
+	if d.config.IncludeRawBits {
+		fv.Raw = fieldRaw(raw.Data, bitOffset, readBits)
+	}
 	return decoded{
 		Field: f,
 		Value: fv,
 	}, readBits, nil
 }
 
+// variableReference holds values of already decoded fields that VARIABLE and KEY_VALUE type field definition is
+// resolved from. Similarly to Canboat analyzer, referenced PGN is value of PGN type field (or field with ID `pgn` in
+// older schemas) and referenced field order is value of the field preceding VARIABLE field (FIELD_INDEX key, i.e.
+// PGN 126208 `parameter` + `value` pairs).
+type variableReference struct {
+	pgn         uint64
+	hasPGN      bool
+	fieldOrder  uint64
+	hasPrevious bool
+}
+
+func (r *variableReference) update(f Field, fv nmea.FieldValue, err error) {
+	if r == nil {
+		return
+	}
+	value, ok := fv.Value.(uint64)
+	r.fieldOrder, r.hasPrevious = value, err == nil && ok
+	if r.hasPrevious && (f.FieldType == FieldTypePGN || f.ID == "pgn") {
+		r.pgn, r.hasPGN = value, true
+	}
+}
+
+// decodeVariableField decodes VARIABLE and KEY_VALUE type field with definition of the referenced PGN field. Field keeps ID of the
+// VARIABLE field. Fixed length referenced fields are rounded up to whole bytes.
+func (d *Decoder) decodeVariableField(s *decoderSchema, raw nmea.RawMessage, f Field, bitOffset uint32, ref *variableReference) (decoded, uint32, error) {
+	refField, err := s.resolveVariableField(ref)
+	if err != nil {
+		return decoded{}, 0, fmt.Errorf("decoder failed to decode field: %v, err: %w", f.ID, err)
+	}
+	refField.ID = f.ID
+	refField.Name = f.Name
+	refField.Order = f.Order
+	refField.Match = 0
+
+	dfv, readBits, err := d.decodeSingleField(s, raw, refField, bitOffset, nil)
+	if !refField.BitLengthVariable {
+		readBits = (uint32(refField.BitLength) + 7) &^ 7
+	}
+	if err == nil && dfv.Value.Raw != nil {
+		dfv.Value.Raw = fieldRaw(raw.Data, bitOffset, readBits)
+	}
+	ref.update(f, nmea.FieldValue{}, errValueIgnored)
+	return dfv, readBits, err
+}
+
+func (s *decoderSchema) resolveVariableField(ref *variableReference) (Field, error) {
+	if ref == nil || !ref.hasPGN || !ref.hasPrevious {
+		return Field{}, ErrUnresolvedVariableField
+	}
+	pgn, ok := s.uniquePGNs[uint32(ref.pgn)]
+	if !ok {
+		pgns := s.nonUniqPGNs[uint32(ref.pgn)]
+		if len(pgns) == 0 {
+			return Field{}, fmt.Errorf("referenced PGN %v is unknown: %w", ref.pgn, ErrUnresolvedVariableField)
+		}
+		pgn = pgns[0]
+	}
+	if ref.fieldOrder < 1 || ref.fieldOrder > uint64(len(pgn.Fields)) {
+		return Field{}, fmt.Errorf("referenced PGN %v has no field %v: %w", ref.pgn, ref.fieldOrder, ErrUnresolvedVariableField)
+	}
+	refField := pgn.Fields[ref.fieldOrder-1]
+	if refField.FieldType == FieldTypeVariable || refField.FieldType == FieldTypeKeyValue {
+		return Field{}, fmt.Errorf("referenced PGN %v field %v is variable: %w", ref.pgn, ref.fieldOrder, ErrUnresolvedVariableField)
+	}
+	return refField, nil
+}
+
+// fieldRaw copies message data bytes containing given bit range
+func fieldRaw(data nmea.RawData, bitOffset uint32, bitLength uint32) *nmea.FieldRaw {
+	start := int(bitOffset / 8)
+	end := (int(bitOffset) + int(bitLength) + 7) / 8
+	if end > len(data) {
+		end = len(data)
+	}
+	b := make(nmea.RawData, 0, end-start)
+	if start < end {
+		b = append(b, data[start:end]...)
+	}
+	return &nmea.FieldRaw{BitOffset: bitOffset, BitLength: bitLength, Bytes: b}
+}
+
 // for the sake of simplicity decoding PGN with repeated fields has different decoding methods as simple PGN
-func (d *Decoder) decode(pgn PGN, raw nmea.RawMessage) ([]decoded, error) {
+func (d *Decoder) decode(s *decoderSchema, pgn PGN, raw nmea.RawMessage) ([]decoded, []nmea.AbsentField, error) {
 	decodedFields := make([]decoded, 0, len(pgn.Fields))
-	messageBitCount := uint16(len(raw.Data) * 8)
-	bitOffset := pgn.Fields[0].BitOffset
+	messageBitCount := uint32(len(raw.Data) * 8)
+	bitOffset := uint32(pgn.Fields[0].BitOffset)
 
+	var absent []nmea.AbsentField
+	ref := &variableReference{}
 	// we decode until we reach at the end of the message. This means that some fields may be left out (be optional)
-	for i := 0; bitOffset < messageBitCount; i++ {
-		if i >= len(pgn.Fields) {
-			break
-		}
+	i := 0
+	for ; bitOffset < messageBitCount && i < len(pgn.Fields); i++ {
 		f := pgn.Fields[i]
 
-		dfv, readBits, err := d.decodeSingleField(raw, f, bitOffset)
+		dfv, readBits, err := d.decodeSingleField(s, raw, f, bitOffset, ref)
 		bitOffset += readBits
 
 		if err == errValueIgnored {
+			absent = d.appendAbsent(absent, f, dfv.absence)
 			continue
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		decodedFields = append(decodedFields, dfv)
 	}
-	return decodedFields, nil
+	for ; i < len(pgn.Fields); i++ {
+		absent = d.appendAbsent(absent, pgn.Fields[i], nmea.AbsenceNotTransmitted)
+	}
+	return decodedFields, absent, nil
 }
 
-func (d *Decoder) decodeWithRepeatedFields(pgn PGN, raw nmea.RawMessage) ([]decoded, error) {
-	decodedFields := make([]decoded, 0, len(pgn.Fields))
-	messageBitCount := uint16(len(raw.Data) * 8)
-	bitOffset := pgn.Fields[0].BitOffset
-
-	neededRepetitionCountFields := 0
-	currentFieldOrder := 1
-	currentRepFieldOrder := 0
-	currentRepGroupIndex := 0
-
-	var rep1Values [][]decoded
-	rep1StartIndex := math.MaxInt // index of first decoded field over all rep groups
-	if pgn.RepeatingFieldSet1StartField > 0 {
-		rep1StartIndex = int(pgn.RepeatingFieldSet1StartField)
-	}
-	rep1EndIndex := 0 // index of last decoded field over all rep groups
-	if pgn.RepeatingFieldSet1CountField == 0 {
-		// Not all PGNs have `RepeatingFieldSet1CountField`. In that case field group repeats till the end of the message (PGN 126464)
-		rep1EndIndex = math.MaxInt
-		rep1Values = make([][]decoded, 0, 1)
-	} else {
-		neededRepetitionCountFields++
+func (d *Decoder) appendAbsent(absent []nmea.AbsentField, f Field, reason nmea.AbsenceReason) []nmea.AbsentField {
+	if !d.config.DecodeAbsentFields || reason == "" || f.FieldType == FieldTypeReserved || f.FieldType == FieldTypeSpare {
+		return absent
 	}
+	return append(absent, nmea.AbsentField{FieldID: f.ID, Reason: reason})
+}
+
+// repeatingFieldSet holds state of single repeating field set (group of fields that can repeat multiple times in message)
+type repeatingFieldSet struct {
+	id         string
+	startField int
+	size       int
+	countField int
+	// count is number of repetitions. Negative value means that set repeats until the end of the message.
+	count  int
+	values [][]decoded
+}
 
-	var rep2Values [][]decoded
-	rep2StartIndex := math.MaxInt // index of first decoded field over all rep groups
-	if pgn.RepeatingFieldSet2StartField > 0 {
-		rep2StartIndex = int(pgn.RepeatingFieldSet2StartField)
+func (d *Decoder) decodeWithRepeatedFields(s *decoderSchema, pgn PGN, raw nmea.RawMessage) ([]decoded, []nmea.DecodeWarning, []nmea.AbsentField, error) {
+	decodedFields := make([]decoded, 0, len(pgn.Fields))
+	messageBitCount := uint32(len(raw.Data) * 8)
+	bitOffset := uint32(pgn.Fields[0].BitOffset)
+
+	var warnings []nmea.DecodeWarning
+	var absent []nmea.AbsentField
+	ref := &variableReference{}
+	sets := make([]*repeatingFieldSet, 0, 2)
+	if pgn.RepeatingFieldSet1StartField > 0 && pgn.RepeatingFieldSet1Size > 0 { // set of size 0 is invalid schema
+		sets = append(sets, &repeatingFieldSet{
+			id:         "FIELDSET_1",
+			startField: int(pgn.RepeatingFieldSet1StartField),
+			size:       int(pgn.RepeatingFieldSet1Size),
+			countField: int(pgn.RepeatingFieldSet1CountField),
+			count:      -1,
+		})
 	}
-	rep2EndIndex := 0 // index of last decoded field over all rep groups
-	if pgn.RepeatingFieldSet2CountField == 0 {
-		rep2EndIndex = math.MaxInt
-		rep2Values = make([][]decoded, 0, 1)
-	} else {
-		neededRepetitionCountFields++
+	if pgn.RepeatingFieldSet2StartField > 0 && pgn.RepeatingFieldSet2Size > 0 { // set of size 0 is invalid schema
+		sets = append(sets, &repeatingFieldSet{
+			id:         "FIELDSET_2",
+			startField: int(pgn.RepeatingFieldSet2StartField),
+			size:       int(pgn.RepeatingFieldSet2Size),
+			countField: int(pgn.RepeatingFieldSet2CountField),
+			count:      -1,
+		})
 	}
 
 	// due to the repeating fields we can not just range over fields. Repeating fields are group of fields that can repeat
@@ -189,102 +571,167 @@ func (d *Decoder) decodeWithRepeatedFields(pgn PGN, raw nmea.RawMessage) ([]deco
 	// Note:
 	// * Repeating fields are optional, so we break out of decoding loop when we reach at the end of data with our bitOffset
 	// * Not all PGNs have `RepeatingFieldSet1CountField`. In that case field group repeats till the end of the message (PGN 126464).
-	for i := 0; bitOffset < messageBitCount; i++ {
-		if currentFieldOrder > len(pgn.Fields) {
-			break
-		}
-		f := pgn.Fields[currentFieldOrder-1]
-
-		isWithinRep1 := currentFieldOrder >= rep1StartIndex && currentFieldOrder <= rep1EndIndex
-		isWithinRep2 := !isWithinRep1 && currentFieldOrder >= rep2StartIndex && currentFieldOrder <= rep2EndIndex
-		if isWithinRep1 {
-			if currentFieldOrder == rep1StartIndex {
-				currentRepFieldOrder = 1
-			} else {
-				currentRepFieldOrder++
+	fieldOrder := 1
+	for fieldOrder <= len(pgn.Fields) && bitOffset < messageBitCount {
+		var set *repeatingFieldSet
+		for _, fs := range sets {
+			if fs.startField == fieldOrder {
+				set = fs
+				break
 			}
-			currentFieldOrder = rep1StartIndex + (currentRepFieldOrder % int(pgn.RepeatingFieldSet1Size))
-			currentRepGroupIndex = (currentRepFieldOrder - 1) / int(pgn.RepeatingFieldSet1Size)
-		} else if isWithinRep2 {
-			if currentFieldOrder == rep2StartIndex {
-				currentRepFieldOrder = 1
-			} else {
-				currentRepFieldOrder++
+		}
+		if set != nil {
+			for rep := 0; (set.count < 0 || rep < set.count) && bitOffset < messageBitCount; rep++ {
+				// repeat-until-end mode has no count to limit so configured maximum is applied here
+				if set.count < 0 && d.config.MaxRepetitionCount > 0 && rep >= d.config.MaxRepetitionCount {
+					warnings = append(warnings, nmea.DecodeWarning{
+						Code:    WarningRepetitionCountOutOfRange,
+						FieldID: countFieldID(pgn, set),
+						Message: fmt.Sprintf("PGN %v %v repeats more than allowed %v times", pgn.PGN, set.id, d.config.MaxRepetitionCount),
+					})
+					break
+				}
+				repetitionStart := bitOffset
+				group := make([]decoded, 0, set.size)
+				for i := 0; i < set.size && bitOffset < messageBitCount; i++ {
+					dfv, readBits, err := d.decodeSingleField(s, raw, pgn.Fields[set.startField-1+i], bitOffset, ref)
+					bitOffset += readBits
+					if err == errValueIgnored {
+						continue
+					}
+					if err != nil {
+						return nil, nil, nil, err
+					}
+					group = append(group, dfv)
+				}
+				if bitOffset == repetitionStart { // fields took no bits, repeating would never reach end of data
+					break
+				}
+				set.values = append(set.values, group)
 			}
-			currentFieldOrder = rep2StartIndex + (currentRepFieldOrder % int(pgn.RepeatingFieldSet2Size))
-			currentRepGroupIndex = (currentRepFieldOrder - 1) / int(pgn.RepeatingFieldSet2Size)
-		} else {
-			currentFieldOrder++
+			fieldOrder = set.startField + set.size
+			continue
 		}
 
-		dfv, readBits, err := d.decodeSingleField(raw, f, bitOffset)
+		f := pgn.Fields[fieldOrder-1]
+		dfv, readBits, err := d.decodeSingleField(s, raw, f, bitOffset, ref)
 		bitOffset += readBits
+		if err != nil && err != errValueIgnored {
+			return nil, nil, nil, err
+		}
+
+		for _, fs := range sets {
+			if fs.countField != fieldOrder {
+				continue
+			}
+			if err == errValueIgnored { // count field has no data (or is out of range / reserved)
+				fs.count = 0
+				if d.config.RepeatCountNoData == RepeatCountNoDataUntilEnd {
+					fs.count = -1
+				}
+			} else if count, ok := dfv.Value.Value.(uint64); ok {
+				var warning *nmea.DecodeWarning
+				fs.count, warning = d.limitRepetitionCount(pgn, fs, f, int(count), messageBitCount-bitOffset)
+				if warning != nil {
+					warnings = append(warnings, *warning)
+				}
+			}
+		}
+		fieldOrder++
 
 		if err == errValueIgnored {
+			absent = d.appendAbsent(absent, f, dfv.absence)
 			continue
 		}
-		if err != nil {
-			return nil, err
+		decodedFields = append(decodedFields, dfv)
+	}
+	for ; fieldOrder <= len(pgn.Fields); fieldOrder++ {
+		inSet := false
+		for _, fs := range sets {
+			if fieldOrder >= fs.startField && fieldOrder < fs.startField+fs.size {
+				inSet = true
+				break
+			}
+		}
+		if !inSet {
+			absent = d.appendAbsent(absent, pgn.Fields[fieldOrder-1], nmea.AbsenceNotTransmitted)
 		}
+	}
 
-		if neededRepetitionCountFields > 0 {
-			// when we reach field count field we can calculate end index for that repetition group
-			if currentFieldOrder-1 == int(pgn.RepeatingFieldSet1CountField) {
-				rep1Count := int(dfv.Value.Value.(uint64))
-				rep1Values = make([][]decoded, 0, rep1Count)
+	for _, fs := range sets {
+		if len(fs.values) > 0 {
+			decodedFields = append(decodedFields, decoded{
+				Field:    Field{ID: fs.id},
+				ValueSet: fs.values,
+			})
+		}
+	}
+	return decodedFields, warnings, absent, nil
+}
 
-				rep1EndIndex = rep1Count*int(pgn.RepeatingFieldSet1Size) + int(pgn.RepeatingFieldSet1StartField)
-				neededRepetitionCountFields--
-			} else if currentFieldOrder-1 == int(pgn.RepeatingFieldSet2CountField) {
-				rep2Count := int(dfv.Value.Value.(uint64))
-				rep2Values = make([][]decoded, 0, rep2Count)
+// countFieldID returns ID of repeating field set count field. Empty when set repeats until end of data without count
+// field.
+func countFieldID(pgn PGN, set *repeatingFieldSet) string {
+	if set.countField <= 0 || set.countField > len(pgn.Fields) {
+		return ""
+	}
+	return pgn.Fields[set.countField-1].ID
+}
 
-				rep2EndIndex = rep2Count*int(pgn.RepeatingFieldSet2Size) + int(pgn.RepeatingFieldSet2StartField)
-				neededRepetitionCountFields--
-			}
+// limitRepetitionCount caps repetition count read from (possibly corrupted) message data to values allowed by schema,
+// decoder configuration and by the amount of data remaining in the message.
+func (d *Decoder) limitRepetitionCount(
+	pgn PGN,
+	set *repeatingFieldSet,
+	countField Field,
+	count int,
+	remainingBits uint32,
+) (int, *nmea.DecodeWarning) {
+	maxCount := count
+	if countField.RangeMax > 0 && float64(maxCount) > countField.RangeMax {
+		maxCount = int(countField.RangeMax)
+	}
+	if d.config.MaxRepetitionCount > 0 && maxCount > d.config.MaxRepetitionCount {
+		maxCount = d.config.MaxRepetitionCount
+	}
+	if maxCount != count {
+		return maxCount, &nmea.DecodeWarning{
+			Code:    WarningRepetitionCountOutOfRange,
+			FieldID: countField.ID,
+			Message: fmt.Sprintf("PGN %v %v count %v is larger than allowed %v", pgn.PGN, set.id, count, maxCount),
 		}
+	}
 
-		if isWithinRep1 {
-			if currentRepGroupIndex+1 != len(rep1Values) {
-				rep1Values = append(rep1Values, make([]decoded, 0, pgn.RepeatingFieldSet1Size))
-			}
-			grp := rep1Values[currentRepGroupIndex]
-			grp = append(grp, dfv)
-			rep1Values[currentRepGroupIndex] = grp
-		} else if isWithinRep2 {
-			if currentRepGroupIndex+1 != len(rep2Values) {
-				rep2Values = append(rep2Values, make([]decoded, 0, pgn.RepeatingFieldSet2Size))
-			}
-			grp := rep2Values[currentRepGroupIndex]
-			grp = append(grp, dfv)
-			rep2Values[currentRepGroupIndex] = grp
-		} else {
-			decodedFields = append(decodedFields, dfv)
+	// variable length fields take at least 0 bits, so we check how many repetitions of fixed length fields fit in data
+	minBitsPerRepetition := 0
+	for i := 0; i < set.size && set.startField-1+i < len(pgn.Fields); i++ {
+		f := pgn.Fields[set.startField-1+i]
+		if !f.BitLengthVariable {
+			minBitsPerRepetition += int(f.BitLength)
 		}
 	}
-	if len(rep1Values) > 0 {
-		decodedFields = append(decodedFields, decoded{
-			Field:    Field{ID: "FIELDSET_1"},
-			ValueSet: rep1Values,
-		})
+	if minBitsPerRepetition == 0 {
+		return count, nil
 	}
-	if len(rep2Values) > 0 {
-		decodedFields = append(decodedFields, decoded{
-			Field:    Field{ID: "FIELDSET_2"},
-			ValueSet: rep2Values,
-		})
+	// last repetition may be truncated as trailing fields are optional
+	fitsCount := (int(remainingBits) + minBitsPerRepetition - 1) / minBitsPerRepetition
+	if count > fitsCount {
+		return fitsCount, &nmea.DecodeWarning{
+			Code:    WarningRepetitionCountExceedsData,
+			FieldID: countField.ID,
+			Message: fmt.Sprintf("PGN %v %v count %v is larger than remaining data can hold %v", pgn.PGN, set.id, count, fitsCount),
+		}
 	}
-
-	return decodedFields, nil
+	return count, nil
 }
 
-func (d *Decoder) postProcessFields(decodedFields []decoded) (nmea.FieldValues, error) {
+func (d *Decoder) postProcessFields(s *decoderSchema, decodedFields []decoded) (nmea.FieldValues, error) {
 	fields := make([]nmea.FieldValue, 0)
 	for _, f := range decodedFields {
 		if f.ValueSet != nil {
 			fieldsets := make([][]nmea.FieldValue, 0, len(f.ValueSet))
 			for _, fs := range f.ValueSet {
-				tmp, err := d.postProcessFields(fs)
+				tmp, err := d.postProcessFields(s, fs)
 				if err != nil {
 					return nil, err
 				}
@@ -299,10 +746,11 @@ func (d *Decoder) postProcessFields(decodedFields []decoded) (nmea.FieldValues,
 		fv := f.Value
 		if d.config.DecodeLookupsToEnumType && (f.Field.FieldType == FieldTypeLookup ||
 			f.Field.FieldType == FieldTypeIndirectLookup || f.Field.FieldType == FieldTypeBitLookup) {
-			tmpFv, err := d.decodeToEnum(f, decodedFields)
+			tmpFv, err := s.decodeToEnum(f, decodedFields)
 			if err != nil {
 				return nil, err
 			}
+			tmpFv.Raw = fv.Raw
 			fv = tmpFv
 		}
 		fields = append(fields, fv)
@@ -310,7 +758,7 @@ func (d *Decoder) postProcessFields(decodedFields []decoded) (nmea.FieldValues,
 	return fields, nil
 }
 
-func (d *Decoder) decodeToEnum(df decoded, decodedFields []decoded) (nmea.FieldValue, error) {
+func (s *decoderSchema) decodeToEnum(df decoded, decodedFields []decoded) (nmea.FieldValue, error) {
 	val, ok := df.Value.Value.(uint64)
 	if !ok {
 		return nmea.FieldValue{}, fmt.Errorf("decoder failed to convert enum value to uint64. field: %v", df.Field.ID)
@@ -321,7 +769,7 @@ func (d *Decoder) decodeToEnum(df decoded, decodedFields []decoded) (nmea.FieldV
 
 	switch f.FieldType {
 	case FieldTypeLookup:
-		ev, err := d.lookups.FindValue(f.LookupEnumeration, val32)
+		ev, err := s.lookups.FindValue(f.LookupEnumeration, val32)
 		if err == nil {
 			fv.Value = nmea.EnumValue{
 				Value: ev.Value,
@@ -333,7 +781,7 @@ func (d *Decoder) decodeToEnum(df decoded, decodedFields []decoded) (nmea.FieldV
 			return nmea.FieldValue{}, fmt.Errorf("enum field decoding failure, field: %v, err: %w", f.ID, err)
 		}
 	case FieldTypeBitLookup:
-		evBits, err := d.bitLookups.FindValue(f.LookupBitEnumeration, val32)
+		evBits, err := s.bitLookups.FindValue(f.LookupBitEnumeration, val32)
 		if err == nil {
 			evs := make([]nmea.EnumValue, 0, len(evBits))
 			for _, ev := range evBits {
@@ -367,7 +815,7 @@ func (d *Decoder) decodeToEnum(df decoded, decodedFields []decoded) (nmea.FieldV
 			return nmea.FieldValue{}, fmt.Errorf("decoder failed to convert indirect enum value to uint64. field: %v", indirectField.Field.ID)
 		}
 
-		ev, err := d.indirectLookups.FindValue(f.LookupIndirectEnumeration, val32, uint32(indirectValue))
+		ev, err := s.indirectLookups.FindValue(f.LookupIndirectEnumeration, val32, uint32(indirectValue))
 		if err == nil {
 			fv.Value = nmea.EnumValue{
 				Value: val32,
@@ -383,13 +831,13 @@ func (d *Decoder) decodeToEnum(df decoded, decodedFields []decoded) (nmea.FieldV
 	return fv, nil
 }
 
-func (d *Decoder) findPGN(raw nmea.RawMessage) (PGN, error) {
-	pgn, ok := d.uniquePGNs[raw.Header.PGN]
+func (s *decoderSchema) findPGN(raw nmea.RawMessage) (PGN, error) {
+	pgn, ok := s.uniquePGNs[raw.Header.PGN]
 	if ok {
 		return pgn, nil
 	}
 
-	pgns, ok := d.nonUniqPGNs[raw.Header.PGN]
+	pgns, ok := s.nonUniqPGNs[raw.Header.PGN]
 	if !ok || len(pgns) == 0 {
 		return PGN{}, ErrDecodeUnknownPGN
 	}
@@ -399,3 +847,85 @@ func (d *Decoder) findPGN(raw nmea.RawMessage) (PGN, error) {
 	}
 	return pgn, nil
 }
+
+// Candidate is PGN definition that could describe raw message and how well its match fields match message data.
+type Candidate struct {
+	// Definition is PGN definition from schema
+	Definition PGN `json:"-"`
+	// ID is ID of PGN definition
+	ID string `json:"id"`
+	// MatchFields is number of fields with match value in definition
+	MatchFields int `json:"match_fields"`
+	// Matched is number of match fields that matched message data
+	Matched int `json:"matched"`
+	// Mismatched lists IDs of match fields that did not match message data
+	Mismatched []string `json:"mismatched,omitempty"`
+	// Score is ratio of matched fields to match fields. 1 means that definition matches message fully. Definitions
+	// without match fields have score of 1 when PGN has single definition and 0 otherwise.
+	Score float64 `json:"score"`
+}
+
+// Candidates returns all definitions of raw message PGN with their match scores, best match first. Useful to debug
+// why message of PGN with multiple definitions (i.e. proprietary PGNs 130845, 130824) fails to decode with
+// ErrDecodeUnknownPGN. Returns nil for PGNs unknown to schema.
+func (d *Decoder) Candidates(raw nmea.RawMessage) []Candidate {
+	return d.schema.Load().candidates(raw)
+}
+
+func (s *decoderSchema) candidates(raw nmea.RawMessage) []Candidate {
+	if pgn, ok := s.uniquePGNs[raw.Header.PGN]; ok {
+		c := newCandidate(pgn, raw.Data)
+		if c.MatchFields == 0 {
+			c.Score = 1
+		}
+		return []Candidate{c}
+	}
+	pgns := s.nonUniqPGNs[raw.Header.PGN]
+	if len(pgns) == 0 {
+		return nil
+	}
+	result := make([]Candidate, 0, len(pgns))
+	for _, pgn := range pgns {
+		result = append(result, newCandidate(pgn, raw.Data))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Matched > result[j].Matched
+	})
+	return result
+}
+
+func newCandidate(pgn PGN, data nmea.RawData) Candidate {
+	c := Candidate{Definition: pgn, ID: pgn.ID}
+	for _, f := range pgn.Fields {
+		if f.Match == 0 {
+			continue
+		}
+		c.MatchFields++
+		if f.IsMatch(data) {
+			c.Matched++
+		} else {
+			c.Mismatched = append(c.Mismatched, f.ID)
+		}
+	}
+	if c.MatchFields > 0 {
+		c.Score = float64(c.Matched) / float64(c.MatchFields)
+	}
+	return c
+}
+
+func partialMatchWarning(candidates []Candidate) nmea.DecodeWarning {
+	best := candidates[0]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "no PGN definition matched fully, decoded as %v (%v/%v match fields, mismatched: %v), candidates:",
+		best.ID, best.Matched, best.MatchFields, strings.Join(best.Mismatched, ","))
+	for _, c := range candidates {
+		fmt.Fprintf(&sb, " %v=%.2f", c.ID, c.Score)
+	}
+	return nmea.DecodeWarning{
+		Code:    WarningPartialPGNMatch,
+		Message: sb.String(),
+	}
+}
@@ -500,6 +500,74 @@ func TestDecoder_Decode(t *testing.T) {
 			},
 			expectError: "",
 		},
+		{
+			name:        "ok, PGN 60928 with raw bits included",
+			givenPGN:    pgn60928,
+			givenConfig: DecoderConfig{DecodeLookupsToEnumType: true, IncludeRawBits: true},
+			whenRaw: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					Priority:    6,
+					PGN:         60928,
+					Destination: 255,
+					Source:      16,
+				},
+				Data: []byte{
+					0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0,
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{
+					Priority:    6,
+					PGN:         60928,
+					Destination: 255,
+					Source:      16,
+				},
+				Fields: []nmea.FieldValue{
+					{
+						ID:    "uniqueNumber",
+						Value: uint64(175513),
+						Raw:   &nmea.FieldRaw{BitOffset: 0, BitLength: 21, Bytes: nmea.RawData{0x99, 0xad, 0x22}},
+					},
+					{
+						ID:    "manufacturerCode",
+						Value: nmea.EnumValue{Value: 273, Code: "Actisense"},
+						Raw:   &nmea.FieldRaw{BitOffset: 21, BitLength: 11, Bytes: nmea.RawData{0x22, 0x22}},
+					},
+					{
+						ID:    "deviceInstanceLower",
+						Value: uint64(0),
+						Raw:   &nmea.FieldRaw{BitOffset: 32, BitLength: 3, Bytes: nmea.RawData{0x00}},
+					},
+					{
+						ID:    "deviceInstanceUpper",
+						Value: uint64(0),
+						Raw:   &nmea.FieldRaw{BitOffset: 35, BitLength: 5, Bytes: nmea.RawData{0x00}},
+					},
+					{
+						ID:    "deviceFunction",
+						Value: nmea.EnumValue{Value: 160, Code: "Engine Gateway"},
+						Raw:   &nmea.FieldRaw{BitOffset: 40, BitLength: 8, Bytes: nmea.RawData{0xa0}},
+					},
+					{
+						ID:    "deviceClass",
+						Value: nmea.EnumValue{Value: 50, Code: "Propulsion"},
+						Raw:   &nmea.FieldRaw{BitOffset: 49, BitLength: 7, Bytes: nmea.RawData{0x64}},
+					},
+					{
+						ID:    "systemInstance",
+						Value: uint64(0),
+						Raw:   &nmea.FieldRaw{BitOffset: 56, BitLength: 4, Bytes: nmea.RawData{0xc0}},
+					},
+					{
+						ID:    "industryGroup",
+						Value: nmea.EnumValue{Value: 4, Code: "Marine"},
+						Raw:   &nmea.FieldRaw{BitOffset: 60, BitLength: 3, Bytes: nmea.RawData{0xc0}},
+					},
+				},
+			},
+			expectError: "",
+		},
 		{
 			// echo "2022-09-23T11:05:05.383Z,2,127489,236,255,26,00,28,00,ff,ff,bb,71,57,03,00,00,e0,b0,05,00,ff,ff,ff,ff,ff,20,00,00,00,7e,ff" | ./rel/linux-x86_64/analyzer -json -debug -raw -si
 			// {"timestamp":"2022-09-23T11:05:05.383Z","prio":2,"src":236,"dst":255,"pgn":127489,"description":"Engine Parameters, Dynamic",
@@ -734,6 +802,164 @@ func TestDecoder_Decode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "ok, PGN 129029 with RepeatingFields count as no data, defaults to zero repetitions",
+			givenPGN: loadPGN(t, "canboat_pgn_129029.json"),
+			whenRaw: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					Priority:    3,
+					PGN:         129029,
+					Destination: 255,
+					Source:      127,
+				},
+				Data: []byte{
+					0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+					0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+					0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+					0x00, 0x00,
+					0xff,                   // referenceStations = no data
+					0x10, 0x00, 0x64, 0x00, // reference station 1
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{
+					Priority:    3,
+					PGN:         129029,
+					Destination: 255,
+					Source:      127,
+				},
+				Fields: []nmea.FieldValue{
+					{ID: "sid", Value: uint64(0)},
+					{ID: "date", Value: time.Date(2021, time.May, 14, 0, 0, 0, 0, time.UTC)},
+					{ID: "time", Value: time.Duration(25600500000000)},
+					{ID: "latitude", Value: 58.2161881666666},
+					{ID: "longitude", Value: 22.39428733333333},
+					{ID: "altitude", Value: 0.14},
+					{ID: "gnssType", Value: uint64(2)},
+					{ID: "method", Value: uint64(1)},
+					{ID: "integrity", Value: uint64(0)},
+					{ID: "numberOfSvs", Value: uint64(0)},
+					{ID: "hdop", Value: 0.6},
+					{ID: "pdop", Value: 0.9},
+					{ID: "geoidalSeparation", Value: 22.2},
+				},
+			},
+		},
+		{
+			name:        "ok, PGN 129029 with RepeatingFields count as no data, repeat until end",
+			givenPGN:    loadPGN(t, "canboat_pgn_129029.json"),
+			givenConfig: DecoderConfig{RepeatCountNoData: RepeatCountNoDataUntilEnd},
+			whenRaw: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					Priority:    3,
+					PGN:         129029,
+					Destination: 255,
+					Source:      127,
+				},
+				Data: []byte{
+					0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+					0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+					0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+					0x00, 0x00,
+					0xff,                   // referenceStations = no data
+					0x10, 0x00, 0x64, 0x00, // reference station 1
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{
+					Priority:    3,
+					PGN:         129029,
+					Destination: 255,
+					Source:      127,
+				},
+				Fields: []nmea.FieldValue{
+					{ID: "sid", Value: uint64(0)},
+					{ID: "date", Value: time.Date(2021, time.May, 14, 0, 0, 0, 0, time.UTC)},
+					{ID: "time", Value: time.Duration(25600500000000)},
+					{ID: "latitude", Value: 58.2161881666666},
+					{ID: "longitude", Value: 22.39428733333333},
+					{ID: "altitude", Value: 0.14},
+					{ID: "gnssType", Value: uint64(2)},
+					{ID: "method", Value: uint64(1)},
+					{ID: "integrity", Value: uint64(0)},
+					{ID: "numberOfSvs", Value: uint64(0)},
+					{ID: "hdop", Value: 0.6},
+					{ID: "pdop", Value: 0.9},
+					{ID: "geoidalSeparation", Value: 22.2},
+					{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+						{
+							{ID: "referenceStationType", Value: uint64(0)},
+							{ID: "referenceStationId", Value: uint64(1)},
+							{ID: "ageOfDgnssCorrections", Value: 1 * time.Second},
+						},
+					}},
+				},
+			},
+		},
+		{
+			name:     "ok, PGN 129029 with RepeatingFields count limits repetitions",
+			givenPGN: loadPGN(t, "canboat_pgn_129029.json"),
+			whenRaw: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					Priority:    3,
+					PGN:         129029,
+					Destination: 255,
+					Source:      127,
+				},
+				Data: []byte{
+					0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+					0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+					0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+					0x00, 0x00,
+					0x02,                   // referenceStations = 2
+					0x10, 0x00, 0x64, 0x00, // reference station 1
+					0x21, 0x00, 0xc8, 0x00, // reference station 2
+					0x31, 0x00, 0xc8, 0x00, // garbage past count
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{
+					Priority:    3,
+					PGN:         129029,
+					Destination: 255,
+					Source:      127,
+				},
+				Fields: []nmea.FieldValue{
+					{ID: "sid", Value: uint64(0)},
+					{ID: "date", Value: time.Date(2021, time.May, 14, 0, 0, 0, 0, time.UTC)},
+					{ID: "time", Value: time.Duration(25600500000000)},
+					{ID: "latitude", Value: 58.2161881666666},
+					{ID: "longitude", Value: 22.39428733333333},
+					{ID: "altitude", Value: 0.14},
+					{ID: "gnssType", Value: uint64(2)},
+					{ID: "method", Value: uint64(1)},
+					{ID: "integrity", Value: uint64(0)},
+					{ID: "numberOfSvs", Value: uint64(0)},
+					{ID: "hdop", Value: 0.6},
+					{ID: "pdop", Value: 0.9},
+					{ID: "geoidalSeparation", Value: 22.2},
+					{ID: "referenceStations", Value: uint64(2)},
+					{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+						{
+							{ID: "referenceStationType", Value: uint64(0)},
+							{ID: "referenceStationId", Value: uint64(1)},
+							{ID: "ageOfDgnssCorrections", Value: 1 * time.Second},
+						},
+						{
+							{ID: "referenceStationType", Value: uint64(1)},
+							{ID: "referenceStationId", Value: uint64(2)},
+							{ID: "ageOfDgnssCorrections", Value: 2 * time.Second},
+						},
+					}},
+				},
+			},
+		},
 		{
 			name:     "ok, PGN 126464 with RepeatingFields but without count field",
 			givenPGN: loadPGN(t, "canboat_pgn_126464.json"),
@@ -760,10 +986,95 @@ func TestDecoder_Decode(t *testing.T) {
 				},
 				Fields: []nmea.FieldValue{
 					{ID: "functionCode", Value: uint64(1)},
+					// set without count field repeats until end of data. Each repetition of set (size 1) is its own
+					// group, same as repetitions of sets with count field.
+					{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+						{{ID: "pgn", Value: uint64(130820)}},
+						{{ID: "pgn", Value: uint64(129809)}},
+					}},
+				},
+			},
+		},
+		{
+			name:     "ok, PGN 129540 synthetic, satsInView as no data, defaults to zero repetitions",
+			givenPGN: loadPGN(t, "canboat_pgn_129540.json"),
+			whenRaw: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					Priority:    6,
+					PGN:         129540,
+					Destination: 255,
+					Source:      0,
+				},
+				// synthetic data: first 2 satellites from actisense/testdata/actisense-w2k1-raw-ascii-fast-packet-pgn129540.txt
+				// with satsInView (14 in capture) replaced by 0xFF
+				Data: []byte{
+					0xff, 0xff, // sid, mode
+					0xff, // satsInView = no data
+
+					0x20, 0x2c, 0x22, 0x88, 0x37, 0x88, 0x13, 0xff, 0xff, 0xff, 0xff, 0xf2, // satellite 1
+					0x18, 0xd6, 0x05, 0x6b, 0x0a, 0x04, 0x10, 0xff, 0xff, 0xff, 0xff, 0xf2, // satellite 2
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{
+					Priority:    6,
+					PGN:         129540,
+					Destination: 255,
+					Source:      0,
+				},
+				Fields: []nmea.FieldValue{
+					{ID: "mode", Value: uint64(3)},
+				},
+			},
+		},
+		{
+			name:        "ok, PGN 129540 synthetic, satsInView as no data, repeat until end",
+			givenPGN:    loadPGN(t, "canboat_pgn_129540.json"),
+			givenConfig: DecoderConfig{RepeatCountNoData: RepeatCountNoDataUntilEnd},
+			whenRaw: nmea.RawMessage{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					Priority:    6,
+					PGN:         129540,
+					Destination: 255,
+					Source:      0,
+				},
+				// synthetic data: first 2 satellites from actisense/testdata/actisense-w2k1-raw-ascii-fast-packet-pgn129540.txt
+				// with satsInView (14 in capture) replaced by 0xFF
+				Data: []byte{
+					0xff, 0xff, // sid, mode
+					0xff, // satsInView = no data
+
+					0x20, 0x2c, 0x22, 0x88, 0x37, 0x88, 0x13, 0xff, 0xff, 0xff, 0xff, 0xf2, // satellite 1
+					0x18, 0xd6, 0x05, 0x6b, 0x0a, 0x04, 0x10, 0xff, 0xff, 0xff, 0xff, 0xf2, // satellite 2
+				},
+			},
+			expect: nmea.Message{
+				Header: nmea.CanBusHeader{
+					Priority:    6,
+					PGN:         129540,
+					Destination: 255,
+					Source:      0,
+				},
+				Fields: []nmea.FieldValue{
+					{ID: "mode", Value: uint64(3)},
 					{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
 						{
-							{ID: "pgn", Value: uint64(130820)},
-							{ID: "pgn", Value: uint64(129809)},
+							{ID: "prn", Value: uint64(32)},
+							{ID: "elevation", Value: 0.8748},
+							{ID: "azimuth", Value: 1.4216},
+							{ID: "snr", Value: float64(50)},
+							{ID: "rangeResiduals", Value: -0.00001},
+							{ID: "status", Value: uint64(2)},
+						},
+						{
+							{ID: "prn", Value: uint64(24)},
+							{ID: "elevation", Value: 0.1494},
+							{ID: "azimuth", Value: 0.2667},
+							{ID: "snr", Value: float64(41)},
+							{ID: "rangeResiduals", Value: -0.00001},
+							{ID: "status", Value: uint64(2)},
 						},
 					}},
 				},
@@ -798,7 +1109,7 @@ func TestDecoder_Decode(t *testing.T) {
 				Header: nmea.CanBusHeader{},
 				Fields: []nmea.FieldValue{},
 			},
-			expectError: "decoder failed to decode field: selectionValue, err: field type: VARIABLE, err: unsupported field type",
+			expectError: "decoder failed to decode field: selectionValue, err: referenced PGN 130820 is unknown: variable field definition could not be resolved",
 		},
 	}
 
@@ -832,3 +1143,767 @@ func TestDecoder_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoder_Decode_variableField(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var testCases = []struct {
+		name        string
+		givenConfig DecoderConfig
+		whenData    []byte
+		expect      []nmea.FieldValue
+		expectError string
+	}{
+		{
+			name: "ok, selection value is decoded with referenced PGN field definition",
+			whenData: []byte{
+				0x03,             // 1) Function Code = Read Fields
+				0x04, 0xFF, 0x01, // 2) PGN = 130820
+				0xa3, 0x99, // 3) Manufacturer Code = 419 4) reserved 5) Industry Code = 4
+				0x01,       // 6) Unique ID = 1
+				0x01,       // 7) Number of Selection Pairs = 1
+				0x02,       // 8) Number of Parameters = 2
+				0x06,       // 9) Selection Parameter = field 6 of PGN 130820 (amFm, 8 bit LOOKUP)
+				0x01,       // 10) Selection Value = 1
+				0x08, 0x0a, // 11) Parameters = fields 8 and 10
+			},
+			expect: []nmea.FieldValue{
+				{ID: "functionCode", Value: uint64(3)},
+				{ID: "pgn", Value: uint64(130820)},
+				{ID: "manufacturerCode", Value: uint64(419)},
+				{ID: "industryCode", Value: uint64(4)},
+				{ID: "uniqueId", Value: uint64(1)},
+				{ID: "numberOfSelectionPairs", Value: uint64(1)},
+				{ID: "numberOfParameters", Value: uint64(2)},
+				{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+					{
+						{ID: "selectionParameter", Value: uint64(6)},
+						{ID: "selectionValue", Value: uint64(1)},
+					},
+				}},
+				{ID: "FIELDSET_2", Value: [][]nmea.FieldValue{
+					{{ID: "parameter", Value: uint64(8)}},
+					{{ID: "parameter", Value: uint64(10)}},
+				}},
+			},
+		},
+		{
+			name:        "ok, selection value with referenced lookup field is decoded to enum",
+			givenConfig: DecoderConfig{DecodeLookupsToEnumType: true},
+			whenData: []byte{
+				0x03, 0x04, 0xFF, 0x01, 0xa3, 0x99, 0x01, 0x01, 0x00,
+				0x06, 0x01, // amFm = 1 (FM)
+			},
+			expect: []nmea.FieldValue{
+				{ID: "functionCode", Value: nmea.EnumValue{Value: 3, Code: "Read Fields"}},
+				{ID: "pgn", Value: uint64(130820)},
+				{ID: "manufacturerCode", Value: nmea.EnumValue{Value: 419, Code: "Fusion Electronics"}},
+				{ID: "industryCode", Value: nmea.EnumValue{Value: 4, Code: "Marine Industry"}},
+				{ID: "uniqueId", Value: uint64(1)},
+				{ID: "numberOfSelectionPairs", Value: uint64(1)},
+				{ID: "numberOfParameters", Value: uint64(0)},
+				{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+					{
+						{ID: "selectionParameter", Value: uint64(6)},
+						{ID: "selectionValue", Value: nmea.EnumValue{Value: 1, Code: "FM"}},
+					},
+				}},
+			},
+		},
+		{
+			name: "nok, referenced field does not exist",
+			whenData: []byte{
+				0x03, 0x04, 0xFF, 0x01, 0xa3, 0x99, 0x01, 0x01, 0x00,
+				0x20, 0x01, // field 32 of PGN 130820
+			},
+			expectError: "decoder failed to decode field: selectionValue, err: referenced PGN 130820 has no field 32: variable field definition could not be resolved",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewDecoderWithConfig(CanboatSchema{
+				PGNs: PGNs{
+					*loadPGN(t, "canboat_pgn_126208_3.json"),
+					*loadPGN(t, "canboat_pgn_130820.json"),
+				},
+				Enums: LookupEnumerations{
+					{Name: "GROUP_FUNCTION", Values: []EnumValue{{Name: "Read Fields", Value: 3}}},
+					{Name: "MANUFACTURER_CODE", Values: []EnumValue{{Name: "Fusion Electronics", Value: 419}}},
+					{Name: "INDUSTRY_CODE", Values: []EnumValue{{Name: "Marine Industry", Value: 4}}},
+					{Name: "FUSION_RADIO_SOURCE", Values: []EnumValue{{Name: "AM", Value: 0}, {Name: "FM", Value: 1}}},
+				},
+			}, tc.givenConfig)
+
+			result, err := decoder.Decode(nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 126208, Priority: 3, Source: 4, Destination: 5},
+				Data:   tc.whenData,
+			})
+
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				assert.ErrorIs(t, err, ErrUnresolvedVariableField)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, nmea.FieldValues(tc.expect), result.Fields)
+		})
+	}
+}
+
+func TestDecoder_Decode_groupFunctionParameters(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var testCases = []struct {
+		name     string
+		whenData []byte
+		expect   []nmea.FieldValue
+	}{
+		{
+			name: "ok, command parameters are decoded with commanded PGN field definitions",
+			whenData: []byte{
+				0x01,             // Function Code = Command
+				0x12, 0xF2, 0x01, // PGN = 127506
+				0xF8,       // Priority = 8 (no change), reserved
+				0x02,       // Number of Parameters = 2
+				0x02, 0x03, // instance (field 2) = 3
+				0x07, 0x05, 0x00, // rippleVoltage (field 7) = 0.05
+			},
+			expect: []nmea.FieldValue{
+				{ID: "functionCode", Value: nmea.EnumValue{Value: 1, Code: "Command"}},
+				{ID: "pgn", Value: uint64(127506)},
+				{ID: "priority", Value: nmea.EnumValue{Value: 8, Code: "Leave unchanged"}},
+				{ID: "numberOfParameters", Value: uint64(2)},
+				{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+					{
+						{ID: "parameter", Value: uint64(2)},
+						{ID: "value", Value: uint64(3)},
+					},
+					{
+						{ID: "parameter", Value: uint64(7)},
+						{ID: "value", Value: 0.05},
+					},
+				}},
+			},
+		},
+		{
+			name: "ok, acknowledge parameter error codes",
+			whenData: []byte{
+				0x02,             // Function Code = Acknowledge
+				0x12, 0xF2, 0x01, // PGN = 127506
+				0x00, // PGN error code = 0, transmission interval/priority error code = 0
+				0x02, // Number of Parameters = 2
+				0x30, // parameter 1 = 0 (Acknowledge), parameter 2 = 3 (Requested or command parameter out-of-range)
+			},
+			expect: []nmea.FieldValue{
+				{ID: "functionCode", Value: nmea.EnumValue{Value: 2, Code: "Acknowledge"}},
+				{ID: "pgn", Value: uint64(127506)},
+				{ID: "pgnErrorCode", Value: nmea.EnumValue{Value: 0, Code: "Acknowledge"}},
+				{ID: "transmissionIntervalPriorityErrorCode", Value: nmea.EnumValue{Value: 0, Code: "Acknowledge"}},
+				{ID: "numberOfParameters", Value: uint64(2)},
+				{ID: "FIELDSET_1", Value: [][]nmea.FieldValue{
+					{{ID: "parameter", Value: nmea.EnumValue{Value: 0, Code: "Acknowledge"}}},
+					{{ID: "parameter", Value: nmea.EnumValue{Value: 3, Code: "Requested or command parameter out-of-range"}}},
+				}},
+			},
+		},
+	}
+
+	decoder := NewDecoderWithConfig(CanboatSchema{
+		PGNs: PGNs{
+			*loadPGN(t, "canboat_pgn_126208_1.json"),
+			*loadPGN(t, "canboat_pgn_126208_2.json"),
+			*loadPGN(t, "canboat_pgn_127506.json"),
+		},
+		Enums: LookupEnumerations{
+			{Name: "GROUP_FUNCTION", Values: []EnumValue{{Name: "Command", Value: 1}, {Name: "Acknowledge", Value: 2}}},
+			{Name: "PRIORITY", Values: []EnumValue{{Name: "Leave unchanged", Value: 8}}},
+			{Name: "PGN_ERROR_CODE", Values: []EnumValue{{Name: "Acknowledge", Value: 0}}},
+			{Name: "TRANSMISSION_INTERVAL", Values: []EnumValue{{Name: "Acknowledge", Value: 0}}},
+			{Name: "PARAMETER_FIELD", Values: []EnumValue{
+				{Name: "Acknowledge", Value: 0},
+				{Name: "Requested or command parameter out-of-range", Value: 3},
+			}},
+		},
+	}, DecoderConfig{DecodeLookupsToEnumType: true})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := decoder.Decode(nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 126208, Priority: 3, Source: 4, Destination: 5},
+				Data:   tc.whenData,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, nmea.FieldValues(tc.expect), result.Fields)
+		})
+	}
+}
+
+func TestDecoder_Decode_timeOfDay(t *testing.T) {
+	pgn := loadPGN(t, "canboat_pgn_129029.json")
+	raw := nmea.RawMessage{
+		Time:   test_test.UTCTime(1665488842),
+		Header: nmea.CanBusHeader{PGN: 129029, Priority: 3, Source: 127, Destination: 255},
+		Data: []byte{
+			0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+			0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+			0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+			0x00, 0x00,
+			0x01,                   // referenceStations = 1
+			0x10, 0x00, 0x64, 0x00, // reference station 1
+		},
+	}
+
+	msg, err := NewDecoderWithConfig(CanboatSchema{PGNs: PGNs{*pgn}}, DecoderConfig{DecodeTimeOfDay: true}).Decode(raw)
+	assert.NoError(t, err)
+
+	timeOfDay, _ := msg.Fields.FindByID("time")
+	assert.Equal(t, nmea.TimeOfDay(7*time.Hour+6*time.Minute+40*time.Second+500*time.Millisecond), timeOfDay.Value)
+	// durations are not affected
+	set, _ := msg.Fields.FindByID("FIELDSET_1")
+	assert.Equal(t, nmea.FieldValue{ID: "ageOfDgnssCorrections", Value: 1 * time.Second}, set.Value.([][]nmea.FieldValue)[0][2])
+
+	msg, err = NewDecoder(CanboatSchema{PGNs: PGNs{*pgn}}).Decode(raw)
+	assert.NoError(t, err)
+	timeOfDay, _ = msg.Fields.FindByID("time")
+	assert.Equal(t, 7*time.Hour+6*time.Minute+40*time.Second+500*time.Millisecond, timeOfDay.Value)
+}
+
+func TestDecoder_Decode_repetitionCountWarnings(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var testCases = []struct {
+		name           string
+		givenConfig    DecoderConfig
+		whenCountAndFS []byte
+		expectFSLen    int
+		expectWarnings []nmea.DecodeWarning
+	}{
+		{
+			name:           "ok, count exceeds remaining data",
+			whenCountAndFS: []byte{0xfa, 0x10, 0x00, 0x64, 0x00}, // 250 reference stations with 1 actually sent
+			expectFSLen:    1,
+			expectWarnings: []nmea.DecodeWarning{
+				{
+					Code:    WarningRepetitionCountExceedsData,
+					FieldID: "referenceStations",
+					Message: "PGN 129029 FIELDSET_1 count 250 is larger than remaining data can hold 1",
+				},
+			},
+		},
+		{
+			name:           "ok, count capped by config",
+			givenConfig:    DecoderConfig{MaxRepetitionCount: 1},
+			whenCountAndFS: []byte{0x02, 0x10, 0x00, 0x64, 0x00, 0x21, 0x00, 0xc8, 0x00},
+			expectFSLen:    1,
+			expectWarnings: []nmea.DecodeWarning{
+				{
+					Code:    WarningRepetitionCountOutOfRange,
+					FieldID: "referenceStations",
+					Message: "PGN 129029 FIELDSET_1 count 2 is larger than allowed 1",
+				},
+			},
+		},
+		{
+			name:           "ok, count within limits",
+			givenConfig:    DecoderConfig{MaxRepetitionCount: 2},
+			whenCountAndFS: []byte{0x02, 0x10, 0x00, 0x64, 0x00, 0x21, 0x00, 0xc8, 0x00},
+			expectFSLen:    2,
+			expectWarnings: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewDecoderWithConfig(CanboatSchema{
+				PGNs: PGNs{*loadPGN(t, "canboat_pgn_129029.json")},
+			}, tc.givenConfig)
+
+			data := []byte{
+				0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+				0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+				0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+				0x00, 0x00,
+			}
+			result, err := decoder.Decode(nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 129029, Priority: 3, Source: 127, Destination: 255},
+				Data:   append(data, tc.whenCountAndFS...),
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectWarnings, result.Warnings)
+
+			fs, ok := result.Fields.FindByID("FIELDSET_1")
+			assert.True(t, ok)
+			assert.Len(t, fs.Value, tc.expectFSLen)
+		})
+	}
+}
+
+func TestDecoder_Decode_repetitionUntilEndCapped(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	decoder := NewDecoderWithConfig(CanboatSchema{
+		PGNs: PGNs{*loadPGN(t, "canboat_pgn_126464.json")},
+	}, DecoderConfig{MaxRepetitionCount: 2})
+
+	// set without count field repeats until end of data: 3 PGNs sent, 2 allowed
+	result, err := decoder.Decode(nmea.RawMessage{
+		Time:   now,
+		Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 35, Destination: 255},
+		Data:   []byte{0x00, 0x00, 0xEE, 0x00, 0x00, 0xEA, 0x00, 0x13, 0xF5, 0x01},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []nmea.DecodeWarning{
+		{
+			Code:    WarningRepetitionCountOutOfRange,
+			Message: "PGN 126464 FIELDSET_1 repeats more than allowed 2 times",
+		},
+	}, result.Warnings)
+
+	fs, ok := result.Fields.FindByID("FIELDSET_1")
+	assert.True(t, ok)
+	assert.Len(t, fs.Value, 2)
+}
+
+func TestDecoder_Decode_repetitionNoProgress(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var testCases = []struct {
+		name     string
+		givenPGN func(pgn *PGN)
+	}{
+		{
+			name: "ok, set of size 0 is decoded as ordinary fields",
+			givenPGN: func(pgn *PGN) {
+				pgn.RepeatingFieldSet1Size = 0
+			},
+		},
+		{
+			name: "ok, set fields taking no bits do not repeat forever",
+			givenPGN: func(pgn *PGN) {
+				pgn.Fields[1].FieldType = "BINARY"
+				pgn.Fields[1].BitLength = 0
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pgn := loadPGN(t, "canboat_pgn_126464.json")
+			tc.givenPGN(pgn)
+			decoder := NewDecoder(CanboatSchema{PGNs: PGNs{*pgn}})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = decoder.Decode(nmea.RawMessage{
+					Time:   now,
+					Header: nmea.CanBusHeader{PGN: 126464, Priority: 6, Source: 35, Destination: 255},
+					Data:   []byte{0x00, 0x00, 0xEE, 0x00},
+				})
+			}()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("decoding did not finish")
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_absentFields(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	var testCases = []struct {
+		name         string
+		givenPGN     *PGN
+		givenConfig  DecoderConfig
+		whenData     []byte
+		expectAbsent []nmea.AbsentField
+	}{
+		{
+			name:        "ok, no data and not transmitted fields",
+			givenPGN:    loadPGN(t, "canboat_pgn_127257.json"),
+			givenConfig: DecoderConfig{DecodeAbsentFields: true},
+			whenData:    []byte{0x0, 0xff, 0x7f, 0x77, 0xfc}, // yaw = no data, roll is not sent
+			expectAbsent: []nmea.AbsentField{
+				{FieldID: "yaw", Reason: nmea.AbsenceNoData},
+				{FieldID: "roll", Reason: nmea.AbsenceNotTransmitted},
+			},
+		},
+		{
+			name:         "ok, all fields present",
+			givenPGN:     loadPGN(t, "canboat_pgn_127257.json"),
+			givenConfig:  DecoderConfig{DecodeAbsentFields: true},
+			whenData:     []byte{0x0, 0x01, 0x00, 0x77, 0xfc, 0xec, 0xf9, 0xff},
+			expectAbsent: nil,
+		},
+		{
+			name:         "ok, absent fields are not recorded by default",
+			givenPGN:     loadPGN(t, "canboat_pgn_127257.json"),
+			whenData:     []byte{0x0, 0xff, 0x7f, 0x77, 0xfc},
+			expectAbsent: nil,
+		},
+		{
+			name:        "ok, repeating field set is not reported as absent",
+			givenPGN:    loadPGN(t, "canboat_pgn_129029.json"),
+			givenConfig: DecoderConfig{DecodeAbsentFields: true},
+			whenData: []byte{
+				0x00, 0x49, 0x49, 0x88, 0x53, 0x42, 0x0f, 0x80, 0xc0, 0x83,
+				0x9e, 0x25, 0x41, 0x14, 0x08, 0x60, 0x7d, 0x03, 0x57, 0xdb,
+				0x9a, 0x1b, 0x03, 0xe0, 0x22, 0x02, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x12, 0xfc, 0x00, 0x3c, 0x00, 0x5a, 0x00, 0xac, 0x08,
+				0x00, 0x00,
+				0xff, // referenceStations = no data
+			},
+			expectAbsent: []nmea.AbsentField{
+				{FieldID: "referenceStations", Reason: nmea.AbsenceNoData},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewDecoderWithConfig(CanboatSchema{PGNs: PGNs{*tc.givenPGN}}, tc.givenConfig)
+
+			result, err := decoder.Decode(nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: tc.givenPGN.PGN, Priority: 3, Source: 127, Destination: 255},
+				Data:   tc.whenData,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectAbsent, result.Absent)
+		})
+	}
+}
+
+func TestDecoder_Decode_timing(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	pgn := loadPGN(t, "canboat_pgn_127257.json")
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{*pgn}})
+	decoder.timeNow = func() time.Time {
+		return now.Add(2 * time.Millisecond)
+	}
+
+	raw := nmea.RawMessage{
+		Time:   now,
+		Header: nmea.CanBusHeader{PGN: pgn.PGN, Priority: 3, Source: 127, Destination: 255},
+		Data:   []byte{0x0, 0x01, 0x00, 0x77, 0xfc, 0xec, 0xf9, 0xff},
+	}
+	result, err := decoder.Decode(raw)
+	assert.NoError(t, err)
+	assert.Nil(t, result.Timing)
+
+	raw.BusTime = now.Add(-30 * time.Millisecond)
+	result, err = decoder.Decode(raw)
+	assert.NoError(t, err)
+	expect := &nmea.MessageTiming{
+		BusTime:         now.Add(-30 * time.Millisecond),
+		ReceivedTime:    now,
+		ProcessedTime:   now.Add(2 * time.Millisecond),
+		Latency:         30 * time.Millisecond,
+		ProcessingDelay: 2 * time.Millisecond,
+	}
+	assert.Equal(t, expect, result.Timing)
+}
+
+func testProprietaryPGN(id string, manufacturer int32) PGN {
+	return PGN{
+		PGN:         130845,
+		ID:          id,
+		IsMatchable: true,
+		Fields: []Field{
+			{ID: "manufacturerCode", Order: 1, BitLength: 11, BitOffset: 0, Match: manufacturer, Resolution: 1, FieldType: FieldTypeNumber},
+			{ID: "reserved", Order: 2, BitLength: 2, BitOffset: 11, FieldType: FieldTypeReserved},
+			{ID: "industryCode", Order: 3, BitLength: 3, BitOffset: 13, Match: 4, Resolution: 1, FieldType: FieldTypeNumber},
+			{ID: id, Order: 4, BitLength: 16, BitOffset: 16, Resolution: 1, FieldType: FieldTypeNumber},
+		},
+	}
+}
+
+func proprietaryRaw(manufacturer uint16, value uint16) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 130845, Priority: 7, Source: 1, Destination: 255},
+		Data:   []byte{uint8(manufacturer), uint8(manufacturer>>8) | 0x98, uint8(value), uint8(value >> 8)},
+	}
+}
+
+func TestDecoder_nonUniquePGNWithMultipleDefinitions(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{
+		testProprietaryPGN("first", 1857),
+		testProprietaryPGN("second", 1855),
+		testProprietaryPGN("third", 135),
+	}})
+
+	for _, tc := range []struct {
+		manufacturer uint16
+		expectField  string
+	}{{1857, "first"}, {1855, "second"}, {135, "third"}} {
+		msg, err := decoder.Decode(proprietaryRaw(tc.manufacturer, 42))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		_, ok := msg.Fields.FindByID(tc.expectField)
+		assert.True(t, ok, tc.expectField)
+	}
+}
+
+func TestDecoder_AddPGN(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{})
+
+	_, err := decoder.Decode(proprietaryRaw(1857, 42))
+	assert.ErrorIs(t, err, ErrDecodeUnknownPGN)
+
+	assert.NoError(t, decoder.AddPGN(testProprietaryPGN("heading", 1857)))
+	msg, err := decoder.Decode(proprietaryRaw(1857, 42))
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.FieldValues{
+		{ID: "manufacturerCode", Value: uint64(1857)},
+		{ID: "industryCode", Value: uint64(4)},
+		{ID: "heading", Value: uint64(42)},
+	}, msg.Fields)
+
+	// second definition with different ID makes PGN non-unique, definitions are selected by match fields
+	assert.NoError(t, decoder.AddPGN(testProprietaryPGN("satellites", 1855)))
+	msg, err = decoder.Decode(proprietaryRaw(1855, 7))
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.FieldValue{ID: "satellites", Value: uint64(7)}, msg.Fields[2])
+
+	// definition with same ID replaces existing one
+	override := testProprietaryPGN("heading", 1857)
+	override.Fields[3].ID = "headingOffset"
+	assert.NoError(t, decoder.AddPGN(override))
+	msg, err = decoder.Decode(proprietaryRaw(1857, 42))
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.FieldValue{ID: "headingOffset", Value: uint64(42)}, msg.Fields[2])
+	assert.Len(t, decoder.schema.Load().nonUniqPGNs[130845], 2)
+}
+
+func TestDecoder_AddPGN_invalid(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{})
+
+	err := decoder.AddPGN(PGN{PGN: 130845})
+	assert.EqualError(t, err, "PGN 130845 definition has no fields")
+
+	err = decoder.AddPGN(PGN{PGN: 130845, Fields: []Field{{ID: "date", BitLength: 8, FieldType: FieldTypeDate}}})
+	assert.EqualError(t, err, "field id: date of type DATE bit length is not 16 is 8")
+}
+
+func TestDecoder_RemovePGN(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{*loadPGN(t, "canboat_pgn_127257.json")}})
+
+	assert.True(t, decoder.RemovePGN(127257))
+	assert.False(t, decoder.RemovePGN(127257))
+
+	_, err := decoder.Decode(nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 127257},
+		Data:   []byte{0x00, 0xfd, 0x7f, 0x44, 0x00, 0x3d, 0x00, 0xff},
+	})
+	assert.ErrorIs(t, err, ErrDecodeUnknownPGN)
+}
+
+func TestDecoder_Reload(t *testing.T) {
+	pgn := testProprietaryPGN("mode", 1857)
+	pgn.Fields[3].FieldType = FieldTypeLookup
+	pgn.Fields[3].LookupEnumeration = "MODE"
+	schema := CanboatSchema{
+		Version: "1.0.0",
+		PGNs:    PGNs{pgn},
+		Enums:   LookupEnumerations{{Name: "MODE", Values: []EnumValue{{Name: "Standby", Value: 1}}}},
+	}
+	decoder := NewDecoderWithConfig(schema, DecoderConfig{DecodeLookupsToEnumType: true})
+
+	msg, err := decoder.Decode(proprietaryRaw(1857, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.EnumValue{Value: 1, Code: "Standby"}, msg.Fields[2].Value)
+
+	schema.Version = "1.0.1"
+	schema.Enums = LookupEnumerations{{Name: "MODE", Values: []EnumValue{{Name: "Auto", Value: 1}}}}
+	decoder.Reload(schema)
+
+	assert.Equal(t, "1.0.1", decoder.SchemaVersion())
+	msg, err = decoder.Decode(proprietaryRaw(1857, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.EnumValue{Value: 1, Code: "Auto"}, msg.Fields[2].Value)
+}
+
+func TestDecoder_AddPGN_concurrentWithDecode(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{testProprietaryPGN("heading", 1857)}})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_, err := decoder.Decode(proprietaryRaw(1857, 42))
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, decoder.AddPGN(testProprietaryPGN("heading", 1857)))
+	}
+	<-done
+}
+
+func TestDecoder_Candidates(t *testing.T) {
+	pgns130845 := PGNs{}
+	test_test.LoadJSON(t, "canboat_nonuniqpgn_130845.json", &pgns130845)
+	pgn127257 := loadPGN(t, "canboat_pgn_127257.json")
+	decoder := NewDecoder(CanboatSchema{PGNs: PGNs{pgns130845[0], pgns130845[1], *pgn127257}})
+
+	// manufacturerCode 1855 (Furuno), industryCode 0 (should be 4 - Marine)
+	raw := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 130845},
+		Data:   []byte{0x3f, 0x07, 0x14, 0x22, 0xff, 0x13, 0x1b, 0x57},
+	}
+	candidates := decoder.Candidates(raw)
+	if !assert.Len(t, candidates, 2) {
+		return
+	}
+	assert.Equal(t, "furunoMultiSatsInViewExtended", candidates[0].ID)
+	assert.Equal(t, 2, candidates[0].MatchFields)
+	assert.Equal(t, 1, candidates[0].Matched)
+	assert.Equal(t, []string{"industryCode"}, candidates[0].Mismatched)
+	assert.Equal(t, 0.5, candidates[0].Score)
+	assert.Equal(t, "simnetCompassHeadingOffset", candidates[1].ID)
+	assert.Equal(t, 0.0, candidates[1].Score)
+
+	candidates = decoder.Candidates(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 127257}})
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, 1.0, candidates[0].Score)
+
+	assert.Nil(t, decoder.Candidates(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}}))
+}
+
+func TestDecoder_Decode_partialMatchFallback(t *testing.T) {
+	pgns130845 := PGNs{}
+	test_test.LoadJSON(t, "canboat_nonuniqpgn_130845.json", &pgns130845)
+	schema := CanboatSchema{PGNs: PGNs{pgns130845[0], pgns130845[1]}}
+
+	raw := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 130845, Priority: 7, Source: 1, Destination: 255},
+		Data:   []byte{0x3f, 0x07, 0x14, 0x22, 0xff, 0x13, 0x1b, 0x57, 0x00, 0x21, 0x57, 0xab, 0xf8, 0x11},
+	}
+
+	_, err := NewDecoder(schema).Decode(raw)
+	assert.ErrorIs(t, err, ErrDecodeUnknownPGN)
+
+	result, err := NewDecoderWithConfig(schema, DecoderConfig{PartialMatchFallback: true}).Decode(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []nmea.DecodeWarning{
+		{
+			Code: WarningPartialPGNMatch,
+			Message: "no PGN definition matched fully, decoded as furunoMultiSatsInViewExtended (1/2 match fields, " +
+				"mismatched: industryCode), candidates: furunoMultiSatsInViewExtended=0.50 simnetCompassHeadingOffset=0.00",
+		},
+	}, result.Warnings)
+	manufacturerCode, ok := result.Fields.FindByID("manufacturerCode")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1855), manufacturerCode.Value)
+}
+
+func TestDecoder_Decode_partialMatchFallbackAllMismatched(t *testing.T) {
+	pgns130845 := PGNs{}
+	test_test.LoadJSON(t, "canboat_nonuniqpgn_130845.json", &pgns130845)
+	schema := CanboatSchema{PGNs: PGNs{pgns130845[0], pgns130845[1]}}
+
+	// manufacturerCode 2047 and industryCode 7 do not match any definition
+	raw := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 130845, Priority: 7, Source: 1, Destination: 255},
+		Data:   []byte{0xff, 0xff, 0x14, 0x22, 0xff, 0x13, 0x1b, 0x57, 0x00, 0x21, 0x57, 0xab, 0xf8, 0x11},
+	}
+	decoder := NewDecoderWithConfig(schema, DecoderConfig{PartialMatchFallback: true})
+	candidates := decoder.Candidates(raw)
+	assert.Len(t, candidates, 2)
+	for _, c := range candidates {
+		assert.Equal(t, 0.0, c.Score)
+	}
+
+	_, err := decoder.Decode(raw)
+	assert.ErrorIs(t, err, ErrDecodeUnknownPGN)
+}
+
+func TestDecoder_Decode_lengthValidation(t *testing.T) {
+	pgn := loadPGN(t, "canboat_pgn_127257.json") // Attitude, fixed length 7 bytes
+
+	tooShortWarning := nmea.DecodeWarning{Code: WarningDataTooShort, Message: "PGN 127257 data length 5 is less than 7"}
+	tooLongWarning := nmea.DecodeWarning{Code: WarningDataTooLong, Message: "PGN 127257 data length 8 is more than 7"}
+
+	var testCases = []struct {
+		name           string
+		givenMode      LengthValidationMode
+		whenData       []byte
+		expectFields   int
+		expectWarnings []nmea.DecodeWarning
+		expectErr      string
+	}{
+		{
+			name:         "ok, permissive, too short",
+			givenMode:    LengthValidationPermissive,
+			whenData:     []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+			expectFields: 3,
+		},
+		{
+			name:           "ok, report, too short",
+			givenMode:      LengthValidationReport,
+			whenData:       []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+			expectFields:   3,
+			expectWarnings: []nmea.DecodeWarning{tooShortWarning},
+		},
+		{
+			name:           "ok, report, too long",
+			givenMode:      LengthValidationReport,
+			whenData:       []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			expectFields:   4,
+			expectWarnings: []nmea.DecodeWarning{tooLongWarning},
+		},
+		{
+			name:         "ok, report, single frame padding is not extra data",
+			givenMode:    LengthValidationReport,
+			whenData:     []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0xff},
+			expectFields: 4,
+		},
+		{
+			name:         "ok, strict, exact length",
+			givenMode:    LengthValidationStrict,
+			whenData:     []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07},
+			expectFields: 4,
+		},
+		{
+			name:      "nok, strict, too short",
+			givenMode: LengthValidationStrict,
+			whenData:  []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+			expectErr: "decode failed, message data length does not match PGN definition: PGN 127257 data length 5 is less than 7",
+		},
+		{
+			name:      "nok, strict, too long",
+			givenMode: LengthValidationStrict,
+			whenData:  []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			expectErr: "decode failed, message data length does not match PGN definition: PGN 127257 data length 8 is more than 7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewDecoderWithConfig(CanboatSchema{PGNs: PGNs{*pgn}}, DecoderConfig{LengthValidation: tc.givenMode})
+
+			msg, err := decoder.Decode(nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 127257, Priority: 2, Source: 1, Destination: 255},
+				Data:   tc.whenData,
+			})
+
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.ErrorIs(t, err, ErrInvalidDataLength)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, msg.Fields, tc.expectFields)
+			assert.Equal(t, tc.expectWarnings, msg.Warnings)
+		})
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"github.com/aldas/go-nmea-client"
 	"io"
 	"strings"
+	"sync/atomic"
 )
 
 type Device struct {
 	reader  io.Reader
 	scanner *bufio.Scanner
+
+	closed atomic.Bool
 }
 
 func NewCanBoatReader(reader io.Reader) *Device {
@@ -25,6 +28,9 @@ func (d *Device) Initialize() error {
 }
 
 func (d *Device) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if d.closed.Load() {
+		return nmea.RawMessage{}, nmea.ErrDeviceClosed
+	}
 	for d.scanner.Scan() {
 		line := strings.TrimSpace(d.scanner.Text())
 		if line == "" || line[0] == '#' {
@@ -32,6 +38,9 @@ func (d *Device) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
 		}
 		return UnmarshalString(line)
 	}
+	if d.closed.Load() {
+		return nmea.RawMessage{}, nmea.ErrDeviceClosed
+	}
 	if err := d.scanner.Err(); err != nil {
 		return nmea.RawMessage{}, err
 	}
@@ -42,10 +51,42 @@ func (d *Device) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error
 	return nil // do nothing
 }
 
+// Close closes underlying reader. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
 func (d *Device) Close() error {
+	d.closed.Store(true)
 	closer, ok := d.reader.(io.Closer)
 	if ok {
 		return closer.Close()
 	}
 	return nil
 }
+
+// Writer writes raw messages to underlying writer in Canboat (actisense-serial/analyzer) plain format, one message per
+// line. Useful for recording raw traffic to file.
+type Writer struct {
+	writer io.Writer
+}
+
+// NewCanBoatWriter creates new instance of Writer
+func NewCanBoatWriter(writer io.Writer) *Writer {
+	return &Writer{writer: writer}
+}
+
+// WriteRawMessage writes message as single line in Canboat format
+func (w *Writer) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	b, err := MarshalRawMessage(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes underlying writer if it implements io.Closer
+func (w *Writer) Close() error {
+	closer, ok := w.writer.(io.Closer)
+	if ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package canboat
+
+import (
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDevice_ReadRawMessage(t *testing.T) {
+	input := "# comment\n" +
+		"\n" +
+		"2022-10-11T11:47:22Z,6,60928,16,255,8,99,ad,22,22,00,a0,64,c0\n"
+	device := NewCanBoatReader(strings.NewReader(input))
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(60928), msg.Header.PGN)
+
+	_, err = device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWriter_WriteRawMessage(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	buf := new(bytes.Buffer)
+	writer := NewCanBoatWriter(buf)
+
+	msg := nmea.RawMessage{
+		Time:   now,
+		Header: nmea.CanBusHeader{Priority: 6, PGN: 60928, Destination: 255, Source: 16},
+		Data:   []byte{0x99, 0xad, 0x22, 0x22, 0x00, 0xa0, 0x64, 0xc0},
+	}
+	assert.NoError(t, writer.WriteRawMessage(context.Background(), msg))
+	assert.NoError(t, writer.WriteRawMessage(context.Background(), msg))
+
+	expect := "2022-10-11T11:47:22Z,6,60928,16,255,8,99,ad,22,22,00,a0,64,c0\n"
+	assert.Equal(t, expect+expect, buf.String())
+
+	// written lines can be read back
+	device := NewCanBoatReader(buf)
+	read, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, msg.Header, read.Header)
+	assert.Equal(t, msg.Data, read.Data)
+
+	assert.NoError(t, writer.Close())
+}
+
+func TestDevice_CloseUnblocksRead(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	device := NewCanBoatReader(r)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := device.ReadRawMessage(context.Background())
+		errCh <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond) // give read time to block
+	assert.NoError(t, device.Close())
+
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+	case <-time.After(1 * time.Second):
+		t.Fatal("read was not unblocked by Close")
+	}
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+}
@@ -0,0 +1,681 @@
+package canboat
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	// ErrEncodeUnknownPGN is returned when schema does not have definition for encoded message PGN
+	ErrEncodeUnknownPGN = errors.New("encode failed, unknown PGN given")
+	// ErrEncodeValueOutOfRange is returned when field value does not fit into field bits
+	ErrEncodeValueOutOfRange = errors.New("encode failed, value out of range")
+)
+
+// Encoder encodes field values to PGN data bytes. Encoder is counterpart of Decoder - values are given in the same
+// types as Decoder produces them (including nmea.EnumValue for lookups) so decoded message can be encoded back to
+// same data.
+//
+// Fields that have no value in given field values are encoded as "no data" (all bits set), reserved fields with all
+// bits set, spare fields with zeros and match fields with their match value. Count fields of repeating field sets are
+// always set to number of given repetitions.
+type Encoder struct {
+	uniquePGNs  map[uint32]PGN
+	nonUniqPGNs map[uint32]PGNs
+
+	lookups         LookupEnumerations
+	indirectLookups LookupIndirectEnumerations
+}
+
+// NewEncoder creates new instance of Canboat PGN encoder
+func NewEncoder(schema CanboatSchema) *Encoder {
+	s := newDecoderSchema(schema)
+	return &Encoder{
+		uniquePGNs:  s.uniquePGNs,
+		nonUniqPGNs: s.nonUniqPGNs,
+
+		lookups:         schema.Enums,
+		indirectLookups: schema.IndirectEnums,
+	}
+}
+
+// Encode encodes message fields to raw message data. PGN definition is found by message header PGN. For PGNs with
+// multiple definitions the first definition that has all given fields and matches encoded data is used.
+func (e *Encoder) Encode(msg nmea.Message) (nmea.RawMessage, error) {
+	if pgn, ok := e.uniquePGNs[msg.Header.PGN]; ok {
+		data, err := e.EncodeFields(pgn, msg.Fields)
+		if err != nil {
+			return nmea.RawMessage{}, err
+		}
+		return nmea.RawMessage{Header: msg.Header, Data: data}, nil
+	}
+
+	var lastErr error = ErrEncodeUnknownPGN
+	for _, pgn := range e.nonUniqPGNs[msg.Header.PGN] {
+		if !hasAllFields(pgn, msg.Fields) {
+			continue
+		}
+		data, err := e.EncodeFields(pgn, msg.Fields)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if pgn.IsMatchable && !pgn.IsMatch(data) {
+			continue
+		}
+		return nmea.RawMessage{Header: msg.Header, Data: data}, nil
+	}
+	return nmea.RawMessage{}, lastErr
+}
+
+func hasAllFields(pgn PGN, fields nmea.FieldValues) bool {
+	for _, fv := range fields {
+		if fv.ID == "FIELDSET_1" || fv.ID == "FIELDSET_2" {
+			continue
+		}
+		found := false
+		for _, f := range pgn.Fields {
+			if f.ID == fv.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type encoderSet struct {
+	id         string
+	startField int
+	size       int
+	countField int
+	values     [][]nmea.FieldValue
+}
+
+// EncodeFields encodes field values to data bytes according to given PGN definition. Single frame PGNs are padded
+// to 8 bytes with 0xFF.
+func (e *Encoder) EncodeFields(pgn PGN, fields nmea.FieldValues) (nmea.RawData, error) {
+	if len(pgn.Fields) == 0 {
+		return nil, fmt.Errorf("encode failed, PGN %v has no fields", pgn.PGN)
+	}
+	w := &bitWriter{data: make([]byte, 0, 8), offset: pgn.Fields[0].BitOffset}
+
+	sets := make([]*encoderSet, 0, 2)
+	if pgn.RepeatingFieldSet1StartField > 0 {
+		sets = append(sets, &encoderSet{
+			id:         "FIELDSET_1",
+			startField: int(pgn.RepeatingFieldSet1StartField),
+			size:       int(pgn.RepeatingFieldSet1Size),
+			countField: int(pgn.RepeatingFieldSet1CountField),
+		})
+	}
+	if pgn.RepeatingFieldSet2StartField > 0 {
+		sets = append(sets, &encoderSet{
+			id:         "FIELDSET_2",
+			startField: int(pgn.RepeatingFieldSet2StartField),
+			size:       int(pgn.RepeatingFieldSet2Size),
+			countField: int(pgn.RepeatingFieldSet2CountField),
+		})
+	}
+	for _, s := range sets {
+		fv, ok := fields.FindByID(s.id)
+		if !ok {
+			continue
+		}
+		values, ok := fv.Value.([][]nmea.FieldValue)
+		if !ok {
+			return nil, fmt.Errorf("encode failed, repeating field set %v value is not [][]nmea.FieldValue", s.id)
+		}
+		s.values = values
+	}
+
+	lastField := len(pgn.Fields)
+	if pgn.Type == PacketTypeFast {
+		lastField = lastEncodedField(pgn, fields, sets)
+	}
+
+	rawValues := e.indirectLookupValues(pgn, fields)
+	fieldOrder := 1
+	for fieldOrder <= lastField {
+		var set *encoderSet
+		for _, s := range sets {
+			if s.startField == fieldOrder {
+				set = s
+				break
+			}
+		}
+		if set != nil {
+			for _, group := range set.values {
+				groupRawValues := map[int8]uint64{}
+				for i := 0; i < set.size && set.startField-1+i < len(pgn.Fields); i++ {
+					f := pgn.Fields[set.startField-1+i]
+					if err := e.encodeField(w, f, fieldValue(group, f.ID), groupRawValues); err != nil {
+						return nil, err
+					}
+				}
+			}
+			fieldOrder = set.startField + set.size
+			continue
+		}
+
+		f := pgn.Fields[fieldOrder-1]
+		value := fieldValue(fields, f.ID)
+		for _, s := range sets {
+			if s.countField == fieldOrder {
+				value = uint64(len(s.values))
+			}
+		}
+		if err := e.encodeField(w, f, value, rawValues); err != nil {
+			return nil, err
+		}
+		fieldOrder++
+	}
+
+	data := w.data
+	if pgn.Type == PacketTypeSingle && len(sets) == 0 {
+		for len(data) < 8 { // single frame is always 8 bytes, unused bytes are filled with 0xFF
+			data = append(data, 0xFF)
+		}
+	}
+	return data, nil
+}
+
+// EncodeField encodes single field value to data bytes. Field is encoded starting from first bit and unused bits of the
+// last byte are set to 1. Used to encode field values outside PGN data (i.e. Group Function (126208) parameters).
+func (e *Encoder) EncodeField(f Field, value interface{}) (nmea.RawData, error) {
+	w := &bitWriter{data: make([]byte, 0, 8)}
+	if err := e.encodeField(w, f, value, map[int8]uint64{}); err != nil {
+		return nil, err
+	}
+	if rem := w.offset % 8; rem != 0 {
+		w.put(w.offset, 8-rem, math.MaxUint64)
+	}
+	return w.data, nil
+}
+
+// indirectLookupValues returns raw values of fields that indirect lookups refer to. Referred field can come after
+// indirect lookup field (i.e. PGN 60928 device function and device class) so these are encoded beforehand.
+func (e *Encoder) indirectLookupValues(pgn PGN, fields nmea.FieldValues) map[int8]uint64 {
+	rawValues := map[int8]uint64{}
+	for _, f := range pgn.Fields {
+		if f.FieldType != FieldTypeIndirectLookup {
+			continue
+		}
+		for _, ref := range pgn.Fields {
+			if ref.Order != f.LookupIndirectEnumerationFieldOrder {
+				continue
+			}
+			if value := fieldValue(fields, ref.ID); value != nil {
+				if raw, err := e.rawValue(ref, value, rawValues); err == nil { // errors are reported when field is encoded
+					rawValues[ref.Order] = raw
+				}
+			}
+		}
+	}
+	return rawValues
+}
+
+// lastEncodedField returns order of the last field that has value to encode. Decoder treats fields after the end of
+// fast packet data as absent so trailing fields without values are not encoded at all.
+func lastEncodedField(pgn PGN, fields nmea.FieldValues, sets []*encoderSet) int {
+	last := 0
+	for i, f := range pgn.Fields {
+		if f.Match != 0 || fieldValue(fields, f.ID) != nil {
+			last = i + 1
+		}
+	}
+	for _, s := range sets {
+		if len(s.values) > 0 {
+			if end := s.startField - 1 + s.size; end > last {
+				last = end
+			}
+		} else if s.countField > last {
+			last = s.countField
+		}
+	}
+	if last > len(pgn.Fields) {
+		last = len(pgn.Fields)
+	}
+	return last
+}
+
+// fieldValue returns value of field with given ID or nil when fields do not have it
+func fieldValue(fields []nmea.FieldValue, ID string) interface{} {
+	for _, fv := range fields {
+		if fv.ID == ID {
+			return fv.Value
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) encodeField(w *bitWriter, f Field, value interface{}, rawValues map[int8]uint64) error {
+	switch f.FieldType {
+	case FieldTypeReserved, FieldTypeSpare, FieldTypeBinary:
+		fill := byte(0xFF)
+		if f.FieldType == FieldTypeSpare {
+			fill = 0x00
+		}
+		return e.encodeBytes(w, f, value, fill)
+	case FieldTypeStringFix:
+		return e.encodeStringFix(w, f, value)
+	case FieldTypeStringLz:
+		return e.encodeStringLZ(w, f, value)
+	case FieldTypeStringLAU:
+		return e.encodeStringLAU(w, f, value)
+	case FieldTypeDecimal:
+		return e.encodeDecimal(w, f, value)
+	case FieldTypeVariable, FieldTypeKeyValue, FieldTypeStringVar:
+		return fmt.Errorf("field type: %v, err: %w", f.FieldType, ErrUnsupportedFieldType)
+	}
+
+	var raw uint64
+	if value == nil {
+		raw = noDataValue(f)
+		if f.Match != 0 {
+			raw = uint64(f.Match)
+		}
+	} else {
+		var err error
+		raw, err = e.rawValue(f, value, rawValues)
+		if err != nil {
+			return err
+		}
+		if f.Match != 0 && raw != uint64(f.Match) {
+			return fmt.Errorf("encode failed, field %v value %v does not match PGN definition value %v", f.ID, raw, f.Match)
+		}
+	}
+	if f.Order != 0 {
+		rawValues[f.Order] = raw
+	}
+	w.put(w.offset, f.BitLength, raw)
+	w.offset += f.BitLength
+	return nil
+}
+
+// noDataValue returns raw value that Decoder decodes as nmea.ErrValueNoData
+func noDataValue(f Field) uint64 {
+	mask := bitMask(f.BitLength)
+	if f.Signed && (f.FieldType == FieldTypeNumber) {
+		return mask >> 1
+	}
+	return mask
+}
+
+func bitMask(bitLength uint16) uint64 {
+	if bitLength >= 64 {
+		return math.MaxUint64
+	}
+	return (uint64(1) << bitLength) - 1
+}
+
+func (e *Encoder) rawValue(f Field, value interface{}, rawValues map[int8]uint64) (uint64, error) {
+	if f.BitLength == 0 || f.BitLength > 64 {
+		return 0, fmt.Errorf("encode failed, field %v has invalid bit length %v", f.ID, f.BitLength)
+	}
+	switch f.FieldType {
+	case FieldTypeNumber, FieldTypeMMSI:
+		if s, ok := value.(string); ok && f.FieldType == FieldTypeMMSI {
+			mmsi, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return 0, fmt.Errorf("encode failed, field %v has invalid MMSI: %w", f.ID, err)
+			}
+			value = mmsi
+		}
+		return encodeNumber(f, value)
+	case FieldTypePGN, FieldTypeFieldIndex:
+		return encodeNumber(Field{ID: f.ID, BitLength: f.BitLength, Resolution: 1}, value)
+	case FieldTypeLookup, FieldTypeIndirectLookup, FieldTypeBitLookup:
+		return e.lookupValue(f, value, rawValues)
+	case FieldTypeTime:
+		return encodeTime(f, value)
+	case FieldTypeDate:
+		switch v := value.(type) {
+		case time.Time:
+			days := int64(math.Floor(v.Sub(epoch).Hours() / 24))
+			return checkRange(f, false, days)
+		default:
+			return encodeNumber(Field{ID: f.ID, BitLength: f.BitLength, Resolution: 1}, value)
+		}
+	case FieldTypeFloat:
+		if f.BitLength != 32 {
+			return 0, fmt.Errorf("encode failed, can only encode float with 32 bits. field: %v", f.ID)
+		}
+		n, ok := toNumber(value)
+		if !ok {
+			return 0, fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+		}
+		return uint64(math.Float32bits(float32(n.float()))), nil
+	}
+	return 0, fmt.Errorf("field type: %v, err: %w", f.FieldType, ErrUnsupportedFieldType)
+}
+
+var epoch = time.Unix(0, 0).UTC()
+
+// number is numeric field value in its original (integer or float) form
+type number struct {
+	isFloat bool
+	isUint  bool
+	f       float64
+	i       int64
+	u       uint64
+}
+
+func (n number) float() float64 {
+	switch {
+	case n.isFloat:
+		return n.f
+	case n.isUint:
+		return float64(n.u)
+	}
+	return float64(n.i)
+}
+
+func toNumber(value interface{}) (number, bool) {
+	switch v := value.(type) {
+	case float64:
+		return number{isFloat: true, f: v}, true
+	case float32:
+		return number{isFloat: true, f: float64(v)}, true
+	case int:
+		return number{i: int64(v)}, true
+	case int8:
+		return number{i: int64(v)}, true
+	case int16:
+		return number{i: int64(v)}, true
+	case int32:
+		return number{i: int64(v)}, true
+	case int64:
+		return number{i: v}, true
+	case uint:
+		return number{isUint: true, u: uint64(v)}, true
+	case uint8:
+		return number{isUint: true, u: uint64(v)}, true
+	case uint16:
+		return number{isUint: true, u: uint64(v)}, true
+	case uint32:
+		return number{isUint: true, u: uint64(v)}, true
+	case uint64:
+		return number{isUint: true, u: v}, true
+	case nmea.EnumValue:
+		return number{isUint: true, u: uint64(v.Value)}, true
+	}
+	return number{}, false
+}
+
+// encodeNumber converts value to raw value. Conversion is reverse of decoding: `raw = value / Resolution - Offset`
+func encodeNumber(f Field, value interface{}) (uint64, error) {
+	n, ok := toNumber(value)
+	if !ok {
+		return 0, fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+	}
+	resolution := f.Resolution
+	if resolution == 0 {
+		resolution = 1
+	}
+
+	if !n.isFloat && resolution == 1 {
+		if n.isUint && n.u > math.MaxInt64 {
+			if f.Signed || f.Offset != 0 || n.u > maxRawValue(f) {
+				return 0, fmt.Errorf("%w, field: %v, value: %v", ErrEncodeValueOutOfRange, f.ID, n.u)
+			}
+			return n.u, nil
+		}
+		v := n.i
+		if n.isUint {
+			v = int64(n.u)
+		}
+		return checkRange(f, f.Signed, v-int64(f.Offset))
+	}
+
+	raw := math.Round(n.float()/resolution) - float64(f.Offset)
+	if math.IsNaN(raw) || raw < math.MinInt64 || raw >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w, field: %v, value: %v", ErrEncodeValueOutOfRange, f.ID, n.float())
+	}
+	return checkRange(f, f.Signed, int64(raw))
+}
+
+// maxRawValue returns the largest raw value that does not collide with special values (no data, out of range,
+// reserved). Decoder treats special values only for fields with 8 or more bits.
+func maxRawValue(f Field) uint64 {
+	mask := bitMask(f.BitLength)
+	if f.Signed {
+		mask >>= 1
+	}
+	if f.BitLength >= 8 {
+		mask -= 3
+	}
+	return mask
+}
+
+func checkRange(f Field, signed bool, raw int64) (uint64, error) {
+	tmp := f
+	tmp.Signed = signed
+	max := maxRawValue(tmp)
+	if raw >= 0 && uint64(raw) > max {
+		return 0, fmt.Errorf("%w, field: %v, raw value: %v", ErrEncodeValueOutOfRange, f.ID, raw)
+	}
+	if raw < 0 {
+		if !signed || f.BitLength < 64 && raw < -(int64(1)<<(f.BitLength-1)) {
+			return 0, fmt.Errorf("%w, field: %v, raw value: %v", ErrEncodeValueOutOfRange, f.ID, raw)
+		}
+	}
+	return uint64(raw) & bitMask(f.BitLength), nil
+}
+
+func encodeTime(f Field, value interface{}) (uint64, error) {
+	resolution := f.Resolution
+	if resolution == 0 {
+		resolution = 1
+	}
+	var seconds float64
+	if d, ok := value.(time.Duration); ok {
+		seconds = d.Seconds()
+	} else if t, ok := value.(nmea.TimeOfDay); ok {
+		seconds = t.Duration().Seconds()
+	} else {
+		n, ok := toNumber(value)
+		if !ok {
+			return 0, fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+		}
+		seconds = n.float()
+	}
+	raw := math.Round(seconds / resolution)
+	if raw < 0 || raw >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w, field: %v, value: %v", ErrEncodeValueOutOfRange, f.ID, seconds)
+	}
+	return checkRange(f, false, int64(raw))
+}
+
+func (e *Encoder) lookupValue(f Field, value interface{}, rawValues map[int8]uint64) (uint64, error) {
+	switch v := value.(type) {
+	case string:
+		switch f.FieldType {
+		case FieldTypeLookup:
+			ev, err := e.lookups.FindByName(f.LookupEnumeration, v)
+			if err != nil {
+				return 0, fmt.Errorf("encode failed, field: %v, value: %v, err: %w", f.ID, v, err)
+			}
+			return checkRange(f, false, int64(ev.Value))
+		case FieldTypeIndirectLookup:
+			indirectValue, ok := rawValues[f.LookupIndirectEnumerationFieldOrder]
+			if !ok {
+				return 0, fmt.Errorf("encode failed, field: %v, indirect field with order %v is not encoded", f.ID, f.LookupIndirectEnumerationFieldOrder)
+			}
+			ev, err := e.indirectLookups.FindByName(f.LookupIndirectEnumeration, v, uint32(indirectValue))
+			if err != nil {
+				return 0, fmt.Errorf("encode failed, field: %v, value: %v, err: %w", f.ID, v, err)
+			}
+			return checkRange(f, false, int64(ev.Value))
+		}
+	case []nmea.EnumValue: // bit lookup values are bit numbers
+		raw := uint64(0)
+		for _, ev := range v {
+			if uint16(ev.Value) >= f.BitLength {
+				return 0, fmt.Errorf("%w, field: %v, bit: %v", ErrEncodeValueOutOfRange, f.ID, ev.Value)
+			}
+			raw |= 1 << ev.Value
+		}
+		return raw, nil
+	case nmea.EnumValue:
+		return checkRange(f, false, int64(v.Value))
+	}
+	return encodeNumber(f, value)
+}
+
+func (e *Encoder) encodeBytes(w *bitWriter, f Field, value interface{}, fill byte) error {
+	if value == nil {
+		if f.BitLengthVariable {
+			return nil
+		}
+		w.fill(f.BitLength, fill)
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+	}
+	bitLength := f.BitLength
+	if f.BitLengthVariable {
+		bitLength = uint16(len(b) * 8)
+	} else if len(b)*8 < int(bitLength)-7 || len(b)*8 > int(bitLength)+7 {
+		return fmt.Errorf("%w, field: %v, bytes length: %v", ErrEncodeValueOutOfRange, f.ID, len(b))
+	}
+	for i := uint16(0); i < bitLength; i += 8 {
+		n := uint16(8)
+		if bitLength-i < n {
+			n = bitLength - i
+		}
+		v := fill
+		if int(i/8) < len(b) {
+			v = b[i/8]
+		}
+		w.put(w.offset, n, uint64(v))
+		w.offset += n
+	}
+	return nil
+}
+
+// encodeStringFix encodes string to fixed length field. Unused bytes are filled with 0xFF.
+func (e *Encoder) encodeStringFix(w *bitWriter, f Field, value interface{}) error {
+	s := ""
+	if value != nil {
+		tmp, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+		}
+		s = tmp
+	}
+	if len(s)*8 > int(f.BitLength) {
+		return fmt.Errorf("%w, field: %v, string is longer than %v bytes", ErrEncodeValueOutOfRange, f.ID, f.BitLength/8)
+	}
+	for i := uint16(0); i < f.BitLength/8; i++ {
+		b := byte(0xFF)
+		if int(i) < len(s) {
+			b = s[i]
+		}
+		w.put(w.offset, 8, uint64(b))
+		w.offset += 8
+	}
+	return nil
+}
+
+func (e *Encoder) encodeStringLZ(w *bitWriter, f Field, value interface{}) error {
+	s := ""
+	if value != nil {
+		tmp, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+		}
+		s = tmp
+	}
+	if len(s) > 255 || (f.BitLength > 0 && len(s)*8 > int(f.BitLength)) {
+		return fmt.Errorf("%w, field: %v, string is too long", ErrEncodeValueOutOfRange, f.ID)
+	}
+	w.put(w.offset, 8, uint64(len(s)))
+	w.offset += 8
+	for i := 0; i < len(s); i++ {
+		w.put(w.offset, 8, uint64(s[i]))
+		w.offset += 8
+	}
+	return nil
+}
+
+func (e *Encoder) encodeStringLAU(w *bitWriter, f Field, value interface{}) error {
+	s := ""
+	if value != nil {
+		tmp, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("encode failed, field %v value type %T is not supported", f.ID, value)
+		}
+		s = tmp
+	}
+
+	encoding := byte(1) // ASCII
+	payload := []byte(s)
+	for _, c := range payload {
+		if c == 0 || c >= 0x80 {
+			encoding = 0 // UTF-16 little endian
+			break
+		}
+	}
+	if encoding == 0 {
+		codes := utf16.Encode([]rune(s))
+		payload = make([]byte, 0, len(codes)*2)
+		for _, c := range codes {
+			payload = append(payload, byte(c), byte(c>>8))
+		}
+	}
+	if len(payload)+2 > 255 {
+		return fmt.Errorf("%w, field: %v, string is too long", ErrEncodeValueOutOfRange, f.ID)
+	}
+	w.put(w.offset, 8, uint64(len(payload)+2))
+	w.put(w.offset+8, 8, uint64(encoding))
+	w.offset += 16
+	for _, b := range payload {
+		w.put(w.offset, 8, uint64(b))
+		w.offset += 8
+	}
+	return nil
+}
+
+// encodeDecimal encodes value so that each byte holds 2 decimal digits (value 0-99). Least significant digits are in
+// the last byte.
+func (e *Encoder) encodeDecimal(w *bitWriter, f Field, value interface{}) error {
+	byteCount := int(f.BitLength / 8)
+	data := make([]byte, byteCount)
+	if value == nil {
+		for i := range data {
+			data[i] = 0xFF
+		}
+	} else {
+		n, ok := toNumber(value)
+		if !ok || n.isFloat || (!n.isUint && n.i < 0) {
+			return fmt.Errorf("encode failed, field %v value %v is not supported", f.ID, value)
+		}
+		v := n.u
+		if !n.isUint {
+			v = uint64(n.i)
+		}
+		for i := byteCount - 1; i >= 0; i-- {
+			data[i] = byte(v % 100)
+			v /= 100
+		}
+		if v != 0 {
+			return fmt.Errorf("%w, field: %v, value: %v", ErrEncodeValueOutOfRange, f.ID, value)
+		}
+	}
+	for _, b := range data {
+		w.put(w.offset, 8, uint64(b))
+		w.offset += 8
+	}
+	return nil
+}
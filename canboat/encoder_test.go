@@ -0,0 +1,318 @@
+package canboat
+
+import (
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncoder_Encode_roundTrip(t *testing.T) {
+	files := []string{
+		"canboat_pgn_126208_3.json",
+		"canboat_pgn_126464.json",
+		"canboat_pgn_126998.json",
+		"canboat_pgn_127257.json",
+		"canboat_pgn_127489.json",
+		"canboat_pgn_127506.json",
+		"canboat_pgn_129029.json",
+		"canboat_pgn_129045.json",
+		"canboat_pgn_129808.json",
+		"canboat_pgn_129809.json",
+		"canboat_pgn_130820.json",
+		"canboat_pgn_60928.json",
+	}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			pgn := loadPGN(t, file)
+			schema := CanboatSchema{PGNs: PGNs{*pgn}}
+			generator, err := NewGenerator(schema, GeneratorConfig{Seed: 1})
+			if !assert.NoError(t, err) {
+				return
+			}
+			decoder := NewDecoder(schema)
+			encoder := NewEncoder(schema)
+
+			for i := 0; i < 100; i++ {
+				raw, err := generator.Next()
+				if !assert.NoError(t, err) {
+					return
+				}
+				msg, err := decoder.Decode(raw)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				encoded, err := encoder.Encode(msg)
+				if !assert.NoError(t, err, "data: %x", []byte(raw.Data)) {
+					return
+				}
+				assert.Equal(t, raw.Header, encoded.Header)
+
+				result, err := decoder.Decode(encoded)
+				if !assert.NoError(t, err, "data: %x", []byte(encoded.Data)) {
+					return
+				}
+				if !assert.Equal(t, msg.Fields, result.Fields, "data: %x, encoded: %x", []byte(raw.Data), []byte(encoded.Data)) {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	pgn127257 := loadPGN(t, "canboat_pgn_127257.json")
+	pgn129809 := loadPGN(t, "canboat_pgn_129809.json")
+	pgn126998 := loadPGN(t, "canboat_pgn_126998.json")
+	pgn60928 := loadPGN(t, "canboat_pgn_60928.json")
+	var pgns130845 []PGN
+	test_test.LoadJSON(t, "canboat_nonuniqpgn_130845.json", &pgns130845)
+
+	var testCases = []struct {
+		name        string
+		givenPGNs   PGNs
+		whenMessage nmea.Message
+		expect      nmea.RawData
+		expectError string
+	}{
+		{
+			name:      "ok, 127257, Attitude, missing yaw is encoded as no data",
+			givenPGNs: PGNs{*pgn127257},
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127257, Priority: 3, Destination: 255, Source: 128},
+				Fields: nmea.FieldValues{
+					{ID: "sid", Value: uint64(0)},
+					{ID: "pitch", Value: -0.0905},
+					{ID: "roll", Value: -0.1556},
+				},
+			},
+			expect: nmea.RawData{0x0, 0xff, 0x7f, 0x77, 0xfc, 0xec, 0xf9, 0xff},
+		},
+		{
+			name:      "ok, 129809 with MMSI and STRING_FIX fields",
+			givenPGNs: PGNs{*pgn129809},
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 129809, Priority: 6, Destination: 255, Source: 23},
+				Fields: nmea.FieldValues{
+					{ID: "messageId", Value: uint64(24)},
+					{ID: "repeatIndicator", Value: uint64(0)},
+					{ID: "userId", Value: uint64(244810069)},
+					{ID: "name", Value: "WITTE RAAF"},
+					{ID: "aisTransceiverInformation", Value: uint64(1)},
+				},
+			},
+			expect: nmea.RawData{
+				// STRING_FIX is padded with 0xFF and fields after last given field are not encoded
+				0x18, 0x55, 0x81, 0x97, 0x0e, 0x57, 0x49, 0x54, 0x54, 0x45,
+				0x20, 0x52, 0x41, 0x41, 0x46, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+			},
+		},
+		{
+			name:      "ok, 126998 with STRING_LAU fields",
+			givenPGNs: PGNs{*pgn126998},
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 126998},
+				Fields: nmea.FieldValues{
+					{ID: "installationDescription1", Value: ""},
+					{ID: "installationDescription2", Value: ""},
+					{ID: "manufacturerInformation", Value: "Airmar 1-603-673-9570 www.airmar.com"},
+				},
+			},
+			expect: nmea.RawData{
+				0x02, 0x01, 0x02, 0x01, 0x26, 0x01, 0x41, 0x69, 0x72, 0x6d, 0x61, 0x72, 0x20, 0x31, 0x2d, 0x36,
+				0x30, 0x33, 0x2d, 0x36, 0x37, 0x33, 0x2d, 0x39, 0x35, 0x37, 0x30, 0x20, 0x77, 0x77, 0x77, 0x2e,
+				0x61, 0x69, 0x72, 0x6d, 0x61, 0x72, 0x2e, 0x63, 0x6f, 0x6d,
+			},
+		},
+		{
+			name:      "ok, match from multiple, 130845",
+			givenPGNs: pgns130845,
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 130845},
+				Fields: nmea.FieldValues{
+					{ID: "manufacturerCode", Value: uint64(1855)},
+					{ID: "industryCode", Value: uint64(4)},
+				},
+			},
+			expect: nmea.RawData{0x3f, 0x9f},
+		},
+		{
+			name:      "nok, value out of range",
+			givenPGNs: PGNs{*pgn127257},
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127257},
+				Fields: nmea.FieldValues{
+					{ID: "sid", Value: uint64(256)},
+				},
+			},
+			expectError: "encode failed, value out of range",
+		},
+		{
+			name:      "nok, unknown lookup name",
+			givenPGNs: PGNs{*pgn60928},
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 60928},
+				Fields: nmea.FieldValues{
+					{ID: "deviceFunction", Value: "not existing function"},
+					{ID: "deviceClass", Value: uint64(25)},
+				},
+			},
+			expectError: "not existing function",
+		},
+		{
+			name:      "nok, unknown PGN",
+			givenPGNs: PGNs{*pgn127257},
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 1},
+			},
+			expectError: "encode failed, unknown PGN given",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schema := CanboatSchema{PGNs: tc.givenPGNs}
+			encoder := NewEncoder(schema)
+
+			raw, err := encoder.Encode(tc.whenMessage)
+			if tc.expectError != "" {
+				assert.ErrorContains(t, err, tc.expectError)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.whenMessage.Header, raw.Header)
+			if tc.expect != nil {
+				assert.Equal(t, tc.expect, raw.Data)
+			}
+
+			msg, err := NewDecoder(schema).Decode(raw)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, fv := range tc.whenMessage.Fields {
+				decoded, ok := msg.Fields.FindByID(fv.ID)
+				if !assert.True(t, ok, fv.ID) {
+					continue
+				}
+				if f, isFloat := fv.Value.(float64); isFloat {
+					assert.InDelta(t, f, decoded.Value, 0.000001, fv.ID)
+				} else {
+					assert.Equal(t, fv.Value, decoded.Value, fv.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_EncodeFields_resolutionOffsetAndLookups(t *testing.T) {
+	schema := CanboatSchema{
+		Enums: LookupEnumerations{
+			{Name: "MODE", Values: []EnumValue{{Name: "Off", Value: 0}, {Name: "Auto", Value: 2}}},
+		},
+		PGNs: PGNs{
+			{
+				PGN:  65000,
+				Type: PacketTypeSingle,
+				Fields: []Field{
+					{ID: "temperature", Order: 1, BitOffset: 0, BitLength: 16, Resolution: 0.01, Offset: 0, FieldType: FieldTypeNumber},
+					{ID: "depth", Order: 2, BitOffset: 16, BitLength: 16, Resolution: 0.1, Offset: -100, FieldType: FieldTypeNumber},
+					{ID: "trim", Order: 3, BitOffset: 32, BitLength: 8, Resolution: 1, Signed: true, FieldType: FieldTypeNumber},
+					{ID: "mode", Order: 4, BitOffset: 40, BitLength: 2, Resolution: 1, FieldType: FieldTypeLookup, LookupEnumeration: "MODE"},
+					{ID: "reserved", Order: 5, BitOffset: 42, BitLength: 6, FieldType: FieldTypeReserved},
+				},
+			},
+		},
+	}
+	pgn := schema.PGNs[0]
+	encoder := NewEncoder(schema)
+
+	data, err := encoder.EncodeFields(pgn, nmea.FieldValues{
+		{ID: "temperature", Value: 12.34},
+		{ID: "depth", Value: 2.5},
+		{ID: "trim", Value: int64(-5)},
+		{ID: "mode", Value: "Auto"},
+	})
+	assert.NoError(t, err)
+	// temperature 1234=0x04d2, depth (2.5 / 0.1) - (-100) = 125 = 0x007d, trim -5 = 0xfb, mode 2 + reserved bits set
+	assert.Equal(t, nmea.RawData{0xd2, 0x04, 0x7d, 0x00, 0xfb, 0xfe, 0xff, 0xff}, data)
+
+	msg, err := NewDecoderWithConfig(schema, DecoderConfig{DecodeLookupsToEnumType: true}).
+		Decode(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 65000}, Data: data})
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.FieldValues{
+		{ID: "temperature", Value: 12.34},
+		{ID: "depth", Value: 2.5},
+		{ID: "trim", Value: int64(-5)},
+		{ID: "mode", Value: nmea.EnumValue{Value: 2, Code: "Auto"}},
+	}, msg.Fields)
+
+	_, err = encoder.EncodeFields(pgn, nmea.FieldValues{{ID: "trim", Value: int64(-129)}})
+	assert.True(t, errors.Is(err, ErrEncodeValueOutOfRange))
+
+	_, err = encoder.EncodeFields(pgn, nmea.FieldValues{{ID: "temperature", Value: "hot"}})
+	assert.Error(t, err)
+}
+
+func TestEncoder_EncodeField(t *testing.T) {
+	encoder := NewEncoder(CanboatSchema{
+		Enums: LookupEnumerations{
+			{Name: "MODE", Values: []EnumValue{{Name: "Off", Value: 0}, {Name: "Auto", Value: 2}}},
+		},
+	})
+
+	var testCases = []struct {
+		name        string
+		givenField  Field
+		whenValue   interface{}
+		expect      nmea.RawData
+		expectError string
+	}{
+		{
+			name:       "ok, number",
+			givenField: Field{ID: "depth", Order: 2, BitOffset: 16, BitLength: 16, Resolution: 0.1, FieldType: FieldTypeNumber},
+			whenValue:  12.5,
+			expect:     nmea.RawData{0x7d, 0x00},
+		},
+		{
+			name:       "ok, lookup with unused bits",
+			givenField: Field{ID: "mode", Order: 4, BitOffset: 40, BitLength: 2, Resolution: 1, FieldType: FieldTypeLookup, LookupEnumeration: "MODE"},
+			whenValue:  "Auto",
+			expect:     nmea.RawData{0xfe},
+		},
+		{
+			name:       "ok, no data",
+			givenField: Field{ID: "depth", Order: 2, BitLength: 16, Resolution: 0.1, FieldType: FieldTypeNumber},
+			whenValue:  nil,
+			expect:     nmea.RawData{0xff, 0xff},
+		},
+		{
+			name:       "ok, string LAU",
+			givenField: Field{ID: "name", Order: 1, BitLengthVariable: true, FieldType: FieldTypeStringLAU},
+			whenValue:  "AB",
+			expect:     nmea.RawData{0x04, 0x01, 'A', 'B'},
+		},
+		{
+			name:        "nok, out of range",
+			givenField:  Field{ID: "trim", Order: 3, BitLength: 8, Resolution: 1, Signed: true, FieldType: FieldTypeNumber},
+			whenValue:   int64(-129),
+			expectError: "encode failed, value out of range",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := encoder.EncodeField(tc.givenField, tc.whenValue)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.ErrorContains(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -24,6 +24,22 @@ func (le LookupEnumerations) FindValue(enum string, value uint32) (EnumValue, er
 	return EnumValue{}, ErrUnknownEnumType
 }
 
+// FindByName finds enum value by its name
+func (le LookupEnumerations) FindByName(enum string, name string) (EnumValue, error) {
+	for _, e := range le {
+		if e.Name != enum {
+			continue
+		}
+		for _, v := range e.Values {
+			if v.Name == name {
+				return v, nil
+			}
+		}
+		return EnumValue{}, ErrUnknownEnumValue
+	}
+	return EnumValue{}, ErrUnknownEnumType
+}
+
 func (le LookupEnumerations) Exists(enum string) bool {
 	for _, e := range le {
 		if e.Name == enum {
@@ -106,6 +122,22 @@ func (le LookupIndirectEnumerations) FindValue(enum string, value uint32, indire
 	return IndirectEnumValue{}, ErrUnknownEnumType
 }
 
+// FindByName finds indirect enum value by its name and indirect (other field) value
+func (le LookupIndirectEnumerations) FindByName(enum string, name string, indirectValue uint32) (IndirectEnumValue, error) {
+	for _, e := range le {
+		if e.Name != enum {
+			continue
+		}
+		for _, v := range e.Values {
+			if v.Name == name && v.IndirectValue == indirectValue {
+				return v, nil
+			}
+		}
+		return IndirectEnumValue{}, ErrUnknownEnumValue
+	}
+	return IndirectEnumValue{}, ErrUnknownEnumType
+}
+
 func (le LookupIndirectEnumerations) Exists(enum string) bool {
 	for _, e := range le {
 		if e.Name == enum {
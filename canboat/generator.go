@@ -0,0 +1,544 @@
+package canboat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrGeneratorNoPGNs is returned when generator has no PGNs to generate messages for
+var ErrGeneratorNoPGNs = errors.New("generator has no PGNs to generate messages for")
+
+// errGeneratorStop signals that field can not be generated and message ends before that field
+var errGeneratorStop = errors.New("generator can not generate field")
+
+// GeneratorConfig configures Generator
+type GeneratorConfig struct {
+	// PGNs limits generated messages to given PGNs. Empty means all PGNs from schema that have fields.
+	PGNs []uint32
+	// Sources are source addresses that generated messages are randomly assigned.
+	// Defaults to: [1]
+	Sources []uint8
+	// Priority is priority of generated messages.
+	// Defaults to: 3
+	Priority uint8
+	// Rate is number of messages per second that ReadRawMessage produces. Zero means as fast as possible.
+	Rate float64
+	// Seed is seed for random number generator. Same seed and schema produce same messages.
+	Seed int64
+	// MaxRepetitions is maximum number of times repeating field set is generated.
+	// Defaults to: 3
+	MaxRepetitions int
+}
+
+// Generator produces syntactically valid random RawMessages from Canboat PGN definitions. Numeric fields are generated
+// within schema ranges (excluding "no data" and other special values), lookup fields use values from schema enums and
+// messages fit into PGN packet type (single frame messages are 8 bytes, fast-packet messages are up to 223 bytes).
+// Messages decode without errors with Decoder created from the same schema so Generator is suitable for load-testing
+// consumers, sinks and decoder itself.
+//
+// Generator is not safe for concurrent use.
+type Generator struct {
+	config GeneratorConfig
+	pgns   PGNs
+	rand   *rand.Rand
+
+	lookups         LookupEnumerations
+	indirectLookups LookupIndirectEnumerations
+	bitLookups      LookupBitEnumerations
+
+	next     time.Time
+	timeNow  func() time.Time
+	waitTill func(ctx context.Context, t time.Time) error
+}
+
+// NewGenerator creates new instance of Generator
+func NewGenerator(schema CanboatSchema, config GeneratorConfig) (*Generator, error) {
+	if len(config.Sources) == 0 {
+		config.Sources = []uint8{1}
+	}
+	if config.Priority == 0 {
+		config.Priority = 3
+	}
+	if config.MaxRepetitions <= 0 {
+		config.MaxRepetitions = 3
+	}
+
+	pgns := make(PGNs, 0, len(schema.PGNs))
+	for _, pgn := range schema.PGNs {
+		if len(pgn.Fields) == 0 {
+			continue
+		}
+		if len(config.PGNs) > 0 && !containsPGN(config.PGNs, pgn.PGN) {
+			continue
+		}
+		pgns = append(pgns, pgn)
+	}
+	if len(pgns) == 0 {
+		return nil, ErrGeneratorNoPGNs
+	}
+
+	return &Generator{
+		config: config,
+		pgns:   pgns,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+
+		lookups:         schema.Enums,
+		indirectLookups: schema.IndirectEnums,
+		bitLookups:      schema.BitEnums,
+
+		timeNow:  time.Now,
+		waitTill: waitTill,
+	}, nil
+}
+
+func containsPGN(pgns []uint32, pgn uint32) bool {
+	for _, p := range pgns {
+		if p == pgn {
+			return true
+		}
+	}
+	return false
+}
+
+func waitTill(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ReadRawMessage returns next randomly generated message. When Rate is set, calls are paced to produce given number of
+// messages per second.
+func (g *Generator) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nmea.RawMessage{}, err
+	}
+	if g.config.Rate > 0 {
+		now := g.timeNow()
+		if g.next.IsZero() {
+			g.next = now
+		}
+		if err := g.waitTill(ctx, g.next); err != nil {
+			return nmea.RawMessage{}, err
+		}
+		g.next = g.next.Add(time.Duration(float64(time.Second) / g.config.Rate))
+	}
+	return g.Next()
+}
+
+// Initialize does nothing. Exists to implement nmea.RawMessageReader interface.
+func (g *Generator) Initialize() error {
+	return nil
+}
+
+// Close does nothing. Exists to implement nmea.RawMessageReader interface.
+func (g *Generator) Close() error {
+	return nil
+}
+
+// Next returns message for randomly chosen PGN
+func (g *Generator) Next() (nmea.RawMessage, error) {
+	return g.Generate(g.pgns[g.rand.Intn(len(g.pgns))])
+}
+
+// Generate returns random message for given PGN definition
+func (g *Generator) Generate(pgn PGN) (nmea.RawMessage, error) {
+	maxBytes := nmea.FastRawPacketMaxSize
+	if pgn.Type != PacketTypeFast {
+		maxBytes = 8
+		if pgn.Length > 8 {
+			maxBytes = int(pgn.Length)
+		}
+	}
+	w := &bitWriter{data: make([]byte, 0, 8), maxBits: uint16(maxBytes * 8)}
+	if err := g.generateFields(pgn, w); err != nil {
+		return nmea.RawMessage{}, err
+	}
+
+	data := w.data
+	if pgn.Type != PacketTypeFast {
+		for len(data) < 8 { // single frame is always 8 bytes, unused bytes are filled with 0xFF
+			data = append(data, 0xFF)
+		}
+	}
+	return nmea.RawMessage{
+		Time: g.timeNow(),
+		Header: nmea.CanBusHeader{
+			PGN:         pgn.PGN,
+			Priority:    g.config.Priority,
+			Source:      g.config.Sources[g.rand.Intn(len(g.config.Sources))],
+			Destination: nmea.AddressGlobal,
+		},
+		Data: data,
+	}, nil
+}
+
+type generatorSet struct {
+	startField int
+	size       int
+	countField int
+	count      int
+	// countOffset is bit offset of count field in data so count can be corrected when not all repetitions fit
+	countOffset int
+	started     bool
+	done        bool
+}
+
+// finishSets corrects already generated count fields when message generation ends early. Sets that were not (fully)
+// generated must not claim more repetitions than message contains.
+func (g *Generator) finishSets(pgn PGN, sets []*generatorSet, w *bitWriter) {
+	for _, s := range sets {
+		if s.done || s.countOffset < 0 {
+			continue
+		}
+		if !s.started {
+			s.count = 0
+		}
+		w.put(uint16(s.countOffset), pgn.Fields[s.countField-1].BitLength, uint64(s.count))
+	}
+}
+
+func (g *Generator) generateFields(pgn PGN, w *bitWriter) error {
+	sets := make([]*generatorSet, 0, 2)
+	if pgn.RepeatingFieldSet1StartField > 0 {
+		sets = append(sets, &generatorSet{
+			startField:  int(pgn.RepeatingFieldSet1StartField),
+			size:        int(pgn.RepeatingFieldSet1Size),
+			countField:  int(pgn.RepeatingFieldSet1CountField),
+			count:       g.rand.Intn(g.config.MaxRepetitions + 1),
+			countOffset: -1,
+		})
+	}
+	if pgn.RepeatingFieldSet2StartField > 0 {
+		sets = append(sets, &generatorSet{
+			startField:  int(pgn.RepeatingFieldSet2StartField),
+			size:        int(pgn.RepeatingFieldSet2Size),
+			countField:  int(pgn.RepeatingFieldSet2CountField),
+			count:       g.rand.Intn(g.config.MaxRepetitions + 1),
+			countOffset: -1,
+		})
+	}
+
+	values := map[int8]uint64{}
+	w.offset = pgn.Fields[0].BitOffset
+	for fieldOrder := 1; fieldOrder <= len(pgn.Fields); {
+		var set *generatorSet
+		for _, s := range sets {
+			if s.startField == fieldOrder {
+				set = s
+				break
+			}
+		}
+		if set != nil {
+			if set.countField == 0 && set.count == 0 {
+				set.count = 1 // set without count field repeats till the end of data, so we always generate something
+			}
+			set.started = true
+			rep := 0
+			for ; rep < set.count; rep++ {
+				start := w.offset
+				startLen := len(w.data)
+				err := g.generateRange(pgn.Fields[set.startField-1:set.startField-1+set.size], w, values)
+				if err != nil && err != errGeneratorStop {
+					return err
+				}
+				if err != nil {
+					w.offset = start // repetition did not fit, remove partially generated repetition
+					w.data = w.data[:startLen]
+					break
+				}
+			}
+			if rep != set.count {
+				set.count = rep
+				g.finishSets(pgn, sets, w)
+				return nil
+			}
+			set.done = true
+			fieldOrder = set.startField + set.size
+			continue
+		}
+
+		f := pgn.Fields[fieldOrder-1]
+		var countSet *generatorSet
+		for _, s := range sets {
+			if s.countField == fieldOrder {
+				countSet = s
+				break
+			}
+		}
+		if countSet != nil {
+			if !w.fits(f.BitLength) {
+				g.finishSets(pgn, sets, w)
+				return nil
+			}
+			if maxCount := int(f.RangeMax); f.RangeMax > 0 && countSet.count > maxCount {
+				countSet.count = maxCount
+			}
+			countSet.countOffset = int(w.offset)
+			values[f.Order] = uint64(countSet.count)
+			w.put(w.offset, f.BitLength, uint64(countSet.count))
+			w.offset += f.BitLength
+			fieldOrder++
+			continue
+		}
+		if err := g.generateField(f, w, values); err != nil {
+			if err == errGeneratorStop {
+				g.finishSets(pgn, sets, w)
+				return nil
+			}
+			return err
+		}
+		fieldOrder++
+	}
+	return nil
+}
+
+func (g *Generator) generateRange(fields []Field, w *bitWriter, values map[int8]uint64) error {
+	for _, f := range fields {
+		if err := g.generateField(f, w, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) generateField(f Field, w *bitWriter, values map[int8]uint64) error {
+	if (f.BitLengthVariable && f.FieldType != FieldTypeStringLAU) || f.FieldType == FieldTypeStringLz || f.FieldType == FieldTypeStringVar ||
+		f.FieldType == FieldTypeVariable || f.FieldType == FieldTypeKeyValue {
+		// variable length fields (binary data, STRING_LZ, STRING_VAR, VARIABLE, KEY_VALUE) end generated message
+		return errGeneratorStop
+	}
+	if f.FieldType == FieldTypeStringLAU {
+		return g.generateStringLAU(w)
+	}
+	if f.BitLength == 0 || !w.fits(f.BitLength) {
+		return errGeneratorStop
+	}
+
+	var value uint64
+	switch f.FieldType {
+	case FieldTypeReserved:
+		w.fill(f.BitLength, 0xFF)
+		return nil
+	case FieldTypeSpare:
+		w.fill(f.BitLength, 0x00)
+		return nil
+	case FieldTypeStringFix:
+		return g.generateStringFix(f, w)
+	case FieldTypeBinary:
+		w.random(f.BitLength, g.rand)
+		return nil
+	case FieldTypeDecimal:
+		for i := uint16(0); i < f.BitLength/8; i++ {
+			w.put(w.offset, 8, uint64(g.rand.Intn(100)))
+			w.offset += 8
+		}
+		return nil
+	case FieldTypeFloat:
+		min, max := -1000.0, 1000.0
+		if f.RangeMin < f.RangeMax {
+			min, max = f.RangeMin, f.RangeMax
+		}
+		value = uint64(math.Float32bits(float32(min + g.rand.Float64()*(max-min))))
+	case FieldTypeLookup:
+		value = g.lookupValue(f)
+	case FieldTypeIndirectLookup:
+		value = g.indirectLookupValue(f, values)
+	case FieldTypeBitLookup:
+		value = g.bitLookupValue(f)
+	case FieldTypeMMSI:
+		value = uint64(g.rand.Int63n(1_000_000_000))
+	case FieldTypeNumber, FieldTypeTime, FieldTypeDate, FieldTypePGN, FieldTypeFieldIndex:
+		value = g.numberValue(f)
+	default:
+		return fmt.Errorf("generator does not support field type: %v, field: %v", f.FieldType, f.ID)
+	}
+	if f.Match != 0 {
+		value = uint64(f.Match)
+	}
+	if f.Order != 0 {
+		values[f.Order] = value
+	}
+	w.put(w.offset, f.BitLength, value)
+	w.offset += f.BitLength
+	return nil
+}
+
+// numberValue returns random raw value for field within schema range. Special values (no data, out of range, reserved)
+// are never returned.
+func (g *Generator) numberValue(f Field) uint64 {
+	bits := f.BitLength
+	if bits > 62 { // keeps math below from overflowing, such ranges are not practically used
+		bits = 62
+	}
+	var minRaw, maxRaw int64
+	if f.Signed {
+		minRaw = -(1 << (bits - 1))
+		maxRaw = (1 << (bits - 1)) - 1
+	} else {
+		maxRaw = (1 << bits) - 1
+	}
+	if f.BitLength >= 8 {
+		maxRaw -= 3
+	}
+	if f.RangeMin < f.RangeMax && f.Resolution > 0 {
+		lo := int64(math.Ceil(f.RangeMin/f.Resolution)) - int64(f.Offset)
+		hi := int64(math.Floor(f.RangeMax/f.Resolution)) - int64(f.Offset)
+		if lo > minRaw {
+			minRaw = lo
+		}
+		if hi < maxRaw {
+			maxRaw = hi
+		}
+	}
+	if maxRaw < minRaw {
+		maxRaw = minRaw
+	}
+	v := minRaw + g.rand.Int63n(maxRaw-minRaw+1)
+	return uint64(v) & (^uint64(0) >> (64 - f.BitLength))
+}
+
+func (g *Generator) lookupValue(f Field) uint64 {
+	for _, e := range g.lookups {
+		if e.Name == f.LookupEnumeration && len(e.Values) > 0 {
+			return uint64(e.Values[g.rand.Intn(len(e.Values))].Value)
+		}
+	}
+	return g.numberValue(f)
+}
+
+func (g *Generator) indirectLookupValue(f Field, values map[int8]uint64) uint64 {
+	indirect, ok := values[f.LookupIndirectEnumerationFieldOrder]
+	if !ok {
+		return g.numberValue(f)
+	}
+	candidates := make([]uint32, 0)
+	for _, e := range g.indirectLookups {
+		if e.Name != f.LookupIndirectEnumeration {
+			continue
+		}
+		for _, v := range e.Values {
+			if uint64(v.IndirectValue) == indirect {
+				candidates = append(candidates, v.Value)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return g.numberValue(f)
+	}
+	return uint64(candidates[g.rand.Intn(len(candidates))])
+}
+
+func (g *Generator) bitLookupValue(f Field) uint64 {
+	for _, e := range g.bitLookups {
+		if e.Name != f.LookupBitEnumeration {
+			continue
+		}
+		value := uint64(0)
+		for _, v := range e.Values {
+			if uint16(v.Bit) < f.BitLength && g.rand.Intn(2) == 1 {
+				value |= 1 << v.Bit
+			}
+		}
+		return value
+	}
+	return 0
+}
+
+const generatorLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func (g *Generator) generateStringFix(f Field, w *bitWriter) error {
+	length := int(f.BitLength / 8)
+	n := g.rand.Intn(length + 1)
+	for i := 0; i < length; i++ {
+		c := byte(0xFF) // unused bytes of fixed string are filled with 0xFF
+		if i < n {
+			c = generatorLetters[g.rand.Intn(len(generatorLetters))]
+		}
+		w.put(w.offset, 8, uint64(c))
+		w.offset += 8
+	}
+	return nil
+}
+
+func (g *Generator) generateStringLAU(w *bitWriter) error {
+	if !w.fits(16) {
+		return errGeneratorStop
+	}
+	n := g.rand.Intn(11)
+	for n > 0 && !w.fits(uint16(16+n*8)) {
+		n--
+	}
+	w.put(w.offset, 8, uint64(n+2)) // length includes length and encoding bytes
+	w.put(w.offset+8, 8, 1)         // 1 = ASCII
+	w.offset += 16
+	for i := 0; i < n; i++ {
+		w.put(w.offset, 8, uint64(generatorLetters[g.rand.Intn(len(generatorLetters))]))
+		w.offset += 8
+	}
+	return nil
+}
+
+// bitWriter writes little endian values at arbitrary bit offsets into growing byte slice
+type bitWriter struct {
+	data    []byte
+	offset  uint16
+	maxBits uint16
+}
+
+func (w *bitWriter) fits(bitLength uint16) bool {
+	return uint32(w.offset)+uint32(bitLength) <= uint32(w.maxBits)
+}
+
+func (w *bitWriter) put(bitOffset uint16, bitLength uint16, value uint64) {
+	end := int(bitOffset+bitLength+7) / 8
+	for len(w.data) < end {
+		w.data = append(w.data, 0x00)
+	}
+	for i := uint16(0); i < bitLength; i++ {
+		pos := bitOffset + i
+		mask := byte(1) << (pos % 8)
+		if i < 64 && value&(1<<i) != 0 {
+			w.data[pos/8] |= mask
+		} else {
+			w.data[pos/8] &^= mask
+		}
+	}
+}
+
+func (w *bitWriter) fill(bitLength uint16, b byte) {
+	for bitLength > 0 {
+		n := uint16(8)
+		if bitLength < n {
+			n = bitLength
+		}
+		w.put(w.offset, n, uint64(b))
+		w.offset += n
+		bitLength -= n
+	}
+}
+
+func (w *bitWriter) random(bitLength uint16, r *rand.Rand) {
+	for bitLength > 0 {
+		n := uint16(8)
+		if bitLength < n {
+			n = bitLength
+		}
+		w.put(w.offset, n, uint64(r.Intn(256)))
+		w.offset += n
+		bitLength -= n
+	}
+}
@@ -0,0 +1,160 @@
+package canboat
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Generate_decodes(t *testing.T) {
+	files := []string{
+		"canboat_pgn_126208_3.json",
+		"canboat_pgn_126464.json",
+		"canboat_pgn_126998.json",
+		"canboat_pgn_127257.json",
+		"canboat_pgn_127489.json",
+		"canboat_pgn_127506.json",
+		"canboat_pgn_129029.json",
+		"canboat_pgn_129045.json",
+		"canboat_pgn_129808.json",
+		"canboat_pgn_129809.json",
+		"canboat_pgn_130820.json",
+		"canboat_pgn_60928.json",
+	}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			pgn := loadPGN(t, file)
+			schema := CanboatSchema{PGNs: PGNs{*pgn}}
+			generator, err := NewGenerator(schema, GeneratorConfig{Seed: 1})
+			if !assert.NoError(t, err) {
+				return
+			}
+			decoder := NewDecoder(schema)
+
+			for i := 0; i < 200; i++ {
+				raw, err := generator.Next()
+				if !assert.NoError(t, err) {
+					return
+				}
+				if pgn.Type == PacketTypeFast {
+					assert.LessOrEqual(t, len(raw.Data), nmea.FastRawPacketMaxSize)
+				} else {
+					assert.Len(t, raw.Data, 8)
+				}
+
+				msg, err := decoder.Decode(raw)
+				if !assert.NoError(t, err, "data: %x", []byte(raw.Data)) {
+					return
+				}
+				assert.Empty(t, msg.Warnings, "data: %x", []byte(raw.Data))
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_rangesAndLookups(t *testing.T) {
+	schema := CanboatSchema{
+		PGNs: PGNs{
+			{
+				PGN:  65000,
+				Type: PacketTypeSingle,
+				Fields: []Field{
+					{ID: "mode", Order: 1, BitLength: 8, FieldType: FieldTypeLookup, LookupEnumeration: "MODE", Resolution: 1},
+					{ID: "flags", Order: 2, BitLength: 8, BitOffset: 8, FieldType: FieldTypeBitLookup, LookupBitEnumeration: "FLAGS", Resolution: 1},
+					{ID: "speed", Order: 3, BitLength: 16, BitOffset: 16, FieldType: FieldTypeNumber, Resolution: 0.01, RangeMin: 1, RangeMax: 2},
+					{ID: "trim", Order: 4, BitLength: 16, BitOffset: 32, FieldType: FieldTypeNumber, Signed: true, Resolution: 1, RangeMin: -5, RangeMax: -3},
+					{ID: "reserved", Order: 5, BitLength: 16, BitOffset: 48, FieldType: FieldTypeReserved},
+				},
+			},
+		},
+		Enums: LookupEnumerations{
+			{Name: "MODE", Values: []EnumValue{{Name: "A", Value: 3}, {Name: "B", Value: 7}}},
+		},
+		BitEnums: LookupBitEnumerations{
+			{Name: "FLAGS", Values: []BitEnumValue{{Name: "X", Bit: 1}, {Name: "Y", Bit: 4}}},
+		},
+	}
+	generator, err := NewGenerator(schema, GeneratorConfig{Seed: 42, Sources: []uint8{10, 20}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	decoder := NewDecoder(schema)
+
+	for i := 0; i < 100; i++ {
+		raw, err := generator.Next()
+		assert.NoError(t, err)
+		assert.Contains(t, []uint8{10, 20}, raw.Header.Source)
+		assert.Equal(t, uint8(3), raw.Header.Priority)
+		assert.Equal(t, uint8(0xFF), raw.Data[6])
+		assert.Equal(t, uint8(0xFF), raw.Data[7])
+
+		msg, err := decoder.Decode(raw)
+		if !assert.NoError(t, err) {
+			return
+		}
+		mode, _ := msg.Fields.FindByID("mode")
+		assert.Contains(t, []uint64{3, 7}, mode.Value)
+
+		flags, _ := msg.Fields.FindByID("flags")
+		assert.Zero(t, flags.Value.(uint64)&^uint64(0b10010))
+
+		speed, _ := msg.Fields.FindByID("speed")
+		assert.GreaterOrEqual(t, speed.Value, 1.0)
+		assert.LessOrEqual(t, speed.Value, 2.0)
+
+		trim, _ := msg.Fields.FindByID("trim")
+		assert.GreaterOrEqual(t, trim.Value, int64(-5))
+		assert.LessOrEqual(t, trim.Value, int64(-3))
+	}
+}
+
+func TestGenerator_Generate_sameSeedSameMessages(t *testing.T) {
+	schema := CanboatSchema{PGNs: PGNs{*loadPGN(t, "canboat_pgn_129029.json"), *loadPGN(t, "canboat_pgn_127257.json")}}
+	g1, err := NewGenerator(schema, GeneratorConfig{Seed: 7})
+	assert.NoError(t, err)
+	g2, err := NewGenerator(schema, GeneratorConfig{Seed: 7})
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		m1, _ := g1.Next()
+		m2, _ := g2.Next()
+		assert.Equal(t, m1.Header, m2.Header)
+		assert.Equal(t, m1.Data, m2.Data)
+	}
+}
+
+func TestNewGenerator_noPGNs(t *testing.T) {
+	schema := CanboatSchema{PGNs: PGNs{*loadPGN(t, "canboat_pgn_127257.json")}}
+	_, err := NewGenerator(schema, GeneratorConfig{PGNs: []uint32{129029}})
+	assert.ErrorIs(t, err, ErrGeneratorNoPGNs)
+}
+
+func TestGenerator_ReadRawMessage_rate(t *testing.T) {
+	schema := CanboatSchema{PGNs: PGNs{*loadPGN(t, "canboat_pgn_127257.json")}}
+	generator, err := NewGenerator(schema, GeneratorConfig{Rate: 4})
+	if !assert.NoError(t, err) {
+		return
+	}
+	now := test_test.UTCTime(1665488842)
+	generator.timeNow = func() time.Time { return now }
+	var waits []time.Time
+	generator.waitTill = func(ctx context.Context, t time.Time) error {
+		waits = append(waits, t)
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		msg, err := generator.ReadRawMessage(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(127257), msg.Header.PGN)
+	}
+	assert.Equal(t, []time.Time{now, now.Add(250 * time.Millisecond), now.Add(500 * time.Millisecond)}, waits)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = generator.ReadRawMessage(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -11,10 +11,41 @@ import (
 	"time"
 )
 
+// PlainTimeFormat is time format Canboat tools (i.e. actisense-serial, candump2analyzer) use in plain format lines
+const PlainTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// plainLegacyTimeFormats are time formats older Canboat tools and logs use in plain format lines (time is in UTC)
+var plainLegacyTimeFormats = []string{
+	"2006-01-02-15:04:05.000",
+	"2006-01-02-15:04:05",
+	"2006-01-02T15:04:05.000",
+}
+
+// MarshalRawMessage marshals raw message to Canboat plain format line (without line ending). Time is written with
+// nanosecond precision (RFC3339Nano). Use MarshalPlain to write lines exactly as Canboat tools do.
 func MarshalRawMessage(v nmea.RawMessage) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	buf.WriteString(v.Time.Format(time.RFC3339Nano))
 	buf.WriteByte(',')
+	writePlainMessage(buf, v)
+	return buf.Bytes(), nil
+}
+
+// MarshalPlain marshals raw message to Canboat plain format line (without line ending) i.e.
+// `2023-01-01T00:00:00.000Z,6,59904,0,255,3,00,ee,00`. Time is written in UTC with millisecond precision
+// (PlainTimeFormat). Message with zero time is written without time column (`6,59904,0,255,3,00,ee,00`) which is
+// format Canboat actisense-serial and n2k-reader accept as input for sending messages.
+func MarshalPlain(v nmea.RawMessage) []byte {
+	buf := new(bytes.Buffer)
+	if !v.Time.IsZero() {
+		buf.WriteString(v.Time.UTC().Format(PlainTimeFormat))
+		buf.WriteByte(',')
+	}
+	writePlainMessage(buf, v)
+	return buf.Bytes()
+}
+
+func writePlainMessage(buf *bytes.Buffer, v nmea.RawMessage) {
 	buf.WriteString(strconv.Itoa(int(v.Header.Priority)))
 	buf.WriteByte(',')
 	buf.WriteString(strconv.Itoa(int(v.Header.PGN)))
@@ -24,58 +55,75 @@ func MarshalRawMessage(v nmea.RawMessage) ([]byte, error) {
 	buf.WriteString(strconv.Itoa(int(v.Header.Destination)))
 	buf.WriteByte(',')
 	buf.WriteString(strconv.Itoa(len(v.Data)))
+	var hexByte [2]byte
 	for _, b := range v.Data {
-		if _, err := fmt.Fprintf(buf, ",%02x", b); err != nil {
-			return nil, fmt.Errorf("MarshalRawMessage failure, err: %w", err)
-		}
+		hex.Encode(hexByte[:], []byte{b})
+		buf.WriteByte(',')
+		buf.Write(hexByte[:])
 	}
-	return buf.Bytes(), nil
 }
 
+// UnmarshalString parses Canboat plain format line. Line may start with time in RFC3339 format (as written by
+// MarshalRawMessage and MarshalPlain) or older Canboat `2006-01-02-15:04:05.000` format or have no time column at all
+// (time is left zero). Leading and trailing whitespace is ignored.
 func UnmarshalString(raw string) (nmea.RawMessage, error) {
 	// 2021-07-29T10:18:31.758Z,6,126208,36,0,7,02,82,ff,00,10,02,00
 	// 2023-02-07T11:55:11.002803898+02:00,2,127245,13,255,8,ff,07,ff,7f,00,00,ff,ff
+	// 2011-11-24-22:42:04.388,2,127250,7,255,8,ff,7d,94,ff,7f,ff,7f,fd
+	// 6,59904,0,255,3,14,f0,01
 	// time                               ,prio,pgn,src,dst,len,data...
-	parts := strings.Split(raw, ",")
-	if len(parts) < 7 {
+	parts := strings.Split(strings.TrimSpace(raw), ",")
+	hasTime := strings.ContainsAny(parts[0], "-:T")
+	if (hasTime && len(parts) < 7) || len(parts) < 6 {
 		return nmea.RawMessage{}, errors.New("canboat input has fewer components than expected")
 	}
-	dLen, err := strconv.ParseUint(parts[5], 10, 16)
+	var t time.Time
+	if hasTime {
+		var err error
+		if t, err = parsePlainTime(parts[0]); err != nil {
+			return nmea.RawMessage{}, err
+		}
+		parts = parts[1:]
+	}
+
+	dLen, err := strconv.ParseUint(parts[4], 10, 16)
 	if err != nil {
 		return nmea.RawMessage{}, fmt.Errorf("canboat input invalid data length, err: %w", err)
 	}
-	if len(parts)-6 != int(dLen) {
+	if len(parts)-5 != int(dLen) {
 		return nmea.RawMessage{}, errors.New("canboat input data length does not match bytes count")
 	}
-
-	t, err := time.Parse(time.RFC3339Nano, parts[0])
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("canboat input invalid time format, err: %w", err)
-	}
-	prio, err := strconv.ParseUint(parts[1], 10, 8)
+	prio, err := strconv.ParseUint(parts[0], 10, 3)
 	if err != nil {
 		return nmea.RawMessage{}, fmt.Errorf("canboat input invalid priority, err: %w", err)
 	}
-	pgn, err := strconv.ParseUint(parts[2], 10, 32)
+	pgn, err := strconv.ParseUint(parts[1], 10, 18)
 	if err != nil {
 		return nmea.RawMessage{}, fmt.Errorf("canboat input invalid PGN, err: %w", err)
 	}
-	source, err := strconv.ParseUint(parts[3], 10, 8)
+	source, err := strconv.ParseUint(parts[2], 10, 8)
 	if err != nil {
 		return nmea.RawMessage{}, fmt.Errorf("canboat input invalid source, err: %w", err)
 	}
-	destination, err := strconv.ParseUint(parts[4], 10, 8)
+	destination, err := strconv.ParseUint(parts[3], 10, 8)
 	if err != nil {
 		return nmea.RawMessage{}, fmt.Errorf("canboat input invalid destination, err: %w", err)
 	}
 
-	data, err := hex.DecodeString(strings.Join(parts[6:], ""))
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("canboat input failure to convert hex into bytes, err: %w", err)
+	data := make([]byte, 0, dLen)
+	for _, p := range parts[5:] {
+		if len(p) != 2 {
+			return nmea.RawMessage{}, fmt.Errorf("canboat input invalid data byte: %q", p)
+		}
+		b, err := hex.DecodeString(p)
+		if err != nil {
+			return nmea.RawMessage{}, fmt.Errorf("canboat input failure to convert hex into bytes, err: %w", err)
+		}
+		data = append(data, b[0])
 	}
 
 	return nmea.RawMessage{
-		Time: t.UTC(),
+		Time: t,
 		Header: nmea.CanBusHeader{
 			PGN:         uint32(pgn),
 			Priority:    uint8(prio),
@@ -85,3 +133,16 @@ func UnmarshalString(raw string) (nmea.RawMessage, error) {
 		Data: data,
 	}, nil
 }
+
+func parsePlainTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range plainLegacyTimeFormats {
+		if legacy, legacyErr := time.Parse(layout, value); legacyErr == nil {
+			return legacy, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("canboat input invalid time format, err: %w", err)
+}
@@ -49,6 +49,59 @@ func TestMarshalRawMessage(t *testing.T) {
 	}
 }
 
+func TestMarshalPlain(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		when   nmea.RawMessage
+		expect string
+	}{
+		{
+			name: "ok",
+			when: nmea.RawMessage{
+				Time:   time.Unix(0, 1675763711002803898).In(time.FixedZone("EET", 2*60*60)),
+				Header: nmea.CanBusHeader{Priority: 2, PGN: 127245, Destination: 255, Source: 13},
+				Data:   []byte{0xff, 0x07, 0xff, 0x7f, 0x0, 0x0, 0xff, 0xff},
+			},
+			expect: "2023-02-07T09:55:11.002Z,2,127245,13,255,8,ff,07,ff,7f,00,00,ff,ff",
+		},
+		{
+			name: "ok, without time",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{Priority: 6, PGN: 59904, Destination: 255, Source: 0},
+				Data:   []byte{0x14, 0xf0, 0x01},
+			},
+			expect: "6,59904,0,255,3,14,f0,01",
+		},
+		{
+			name: "ok, without data",
+			when: nmea.RawMessage{
+				Time:   test_test.UTCTime(1665488842),
+				Header: nmea.CanBusHeader{Priority: 6, PGN: 59904, Destination: 255, Source: 0},
+			},
+			expect: "2022-10-11T11:47:22.000Z,6,59904,0,255,0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, string(MarshalPlain(tc.when)))
+		})
+	}
+}
+
+func TestMarshalPlain_roundTrip(t *testing.T) {
+	msg := nmea.RawMessage{
+		Time:   time.Unix(0, 1627553911758000000).In(time.UTC),
+		Header: nmea.CanBusHeader{Priority: 6, PGN: 126208, Destination: 0, Source: 36},
+		Data:   []byte{0x02, 0x82, 0xff, 0x00, 0x10, 0x02, 0x00},
+	}
+
+	result, err := UnmarshalString(string(MarshalPlain(msg)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, msg, result)
+}
+
 func TestUnmarshalString(t *testing.T) {
 	var testCases = []struct {
 		name        string
@@ -99,11 +152,46 @@ func TestUnmarshalString(t *testing.T) {
 			},
 		},
 		{
-			name:        "nok, too few parts",
-			when:        "2023-02-07T11:55:11.x,2,127245,13,255,8",
+			name: "ok, without time",
+			when: "6,59904,0,255,3,14,f0,01",
+			expect: nmea.RawMessage{
+				Header: nmea.CanBusHeader{Priority: 6, PGN: 59904, Destination: 255, Source: 0},
+				Data:   []byte{0x14, 0xf0, 0x01},
+			},
+		},
+		{
+			name: "ok, legacy canboat time format and surrounding whitespace",
+			when: " 2011-11-24-22:42:04.388,2,127250,7,255,8,ff,7d,94,ff,7f,ff,7f,fd\r\n",
+			expect: nmea.RawMessage{
+				Time:   time.Date(2011, 11, 24, 22, 42, 4, 388000000, time.UTC),
+				Header: nmea.CanBusHeader{Priority: 2, PGN: 127250, Destination: 255, Source: 7},
+				Data:   []byte{0xff, 0x7d, 0x94, 0xff, 0x7f, 0xff, 0x7f, 0xfd},
+			},
+		},
+		{
+			name:        "nok, too few parts without time",
+			when:        "6,59904,0,255,0",
 			expect:      nmea.RawMessage{},
 			expectError: "canboat input has fewer components than expected",
 		},
+		{
+			name:        "nok, priority out of range",
+			when:        "9,59904,0,255,3,14,f0,01",
+			expect:      nmea.RawMessage{},
+			expectError: "canboat input invalid priority, err: strconv.ParseUint: parsing \"9\": value out of range",
+		},
+		{
+			name:        "nok, source out of range",
+			when:        "6,59904,256,255,3,14,f0,01",
+			expect:      nmea.RawMessage{},
+			expectError: "canboat input invalid source, err: strconv.ParseUint: parsing \"256\": value out of range",
+		},
+		{
+			name:        "nok, data byte is not 2 hex characters",
+			when:        "6,59904,0,255,3,14,f0,1",
+			expect:      nmea.RawMessage{},
+			expectError: "canboat input invalid data byte: \"1\"",
+		},
 		{
 			name:        "nok, too few parts",
 			when:        "2023-02-07T11:55:11.x,2,127245,13,255,8",
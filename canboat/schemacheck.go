@@ -0,0 +1,141 @@
+package canboat
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SchemaWarningVersionMissing is warning code for schema that has no Version set
+	SchemaWarningVersionMissing = "version_missing"
+	// SchemaWarningVersionOlder is warning code for schema that is older than reference version
+	SchemaWarningVersionOlder = "version_older"
+	// SchemaWarningMissingLookup is warning code for PGN field referencing lookup enumeration that does not exist in schema
+	SchemaWarningMissingLookup = "missing_lookup"
+)
+
+// ErrSchemaCheckFailed is returned when schema check with SchemaCheckFail mode produced warnings
+var ErrSchemaCheckFailed = errors.New("canboat schema check failed")
+
+// SchemaCheckMode determines what to do when schema check produces warnings
+type SchemaCheckMode uint8
+
+const (
+	// SchemaCheckWarn reports warnings but schema is still used
+	SchemaCheckWarn SchemaCheckMode = iota
+	// SchemaCheckFail treats warnings as errors
+	SchemaCheckFail
+	// SchemaCheckIgnore does not check schema at all
+	SchemaCheckIgnore
+)
+
+// SchemaWarning describes difference in schema that could cause different decoding results compared to reference
+// schema (i.e. schema that is embedded into application).
+type SchemaWarning struct {
+	// Code is machine-readable identifier of warning kind
+	Code string `json:"code"`
+	// PGN is PGN that this warning relates to. Zero for schema level warnings.
+	PGN uint32 `json:"pgn,omitempty"`
+	// FieldID is ID of field that this warning relates to
+	FieldID string `json:"field_id,omitempty"`
+	// Message is human-readable description of the problem
+	Message string `json:"message"`
+}
+
+func (w SchemaWarning) String() string {
+	return w.Message
+}
+
+// CheckSchema checks schema against reference version according to mode. With SchemaCheckFail mode any warning results
+// an error wrapping ErrSchemaCheckFailed.
+func CheckSchema(schema CanboatSchema, referenceVersion string, mode SchemaCheckMode) ([]SchemaWarning, error) {
+	if mode == SchemaCheckIgnore {
+		return nil, nil
+	}
+	warnings := schema.Check(referenceVersion)
+	if mode == SchemaCheckFail && len(warnings) > 0 {
+		return warnings, fmt.Errorf("%w: %v (total warnings: %v)", ErrSchemaCheckFailed, warnings[0].Message, len(warnings))
+	}
+	return warnings, nil
+}
+
+// Check compares schema against reference version and checks that all lookups referenced by PGN fields exist in
+// schema. Reference version is usually version of the schema embedded into application. Empty reference version skips
+// version comparison.
+func (s CanboatSchema) Check(referenceVersion string) []SchemaWarning {
+	result := make([]SchemaWarning, 0)
+	if s.Version == "" {
+		result = append(result, SchemaWarning{
+			Code:    SchemaWarningVersionMissing,
+			Message: "schema has no version",
+		})
+	} else if referenceVersion != "" && CompareSchemaVersions(s.Version, referenceVersion) < 0 {
+		result = append(result, SchemaWarning{
+			Code:    SchemaWarningVersionOlder,
+			Message: fmt.Sprintf("schema version %v is older than expected version %v", s.Version, referenceVersion),
+		})
+	}
+
+	for _, pgn := range s.PGNs {
+		for _, f := range pgn.Fields {
+			lookup := ""
+			exists := true
+			switch {
+			case f.LookupEnumeration != "":
+				lookup = f.LookupEnumeration
+				exists = s.Enums.Exists(lookup)
+			case f.LookupBitEnumeration != "":
+				lookup = f.LookupBitEnumeration
+				exists = s.BitEnums.Exists(lookup)
+			case f.LookupIndirectEnumeration != "":
+				lookup = f.LookupIndirectEnumeration
+				exists = s.IndirectEnums.Exists(lookup)
+			}
+			if exists {
+				continue
+			}
+			result = append(result, SchemaWarning{
+				Code:    SchemaWarningMissingLookup,
+				PGN:     pgn.PGN,
+				FieldID: f.ID,
+				Message: fmt.Sprintf("PGN %v field %v references missing lookup %v", pgn.PGN, f.ID, lookup),
+			})
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// CompareSchemaVersions compares two dotted version strings (i.e. `4.10.0` or `v4.10.0`). Returns -1 when a is older
+// than b, 1 when a is newer than b and 0 when versions are equal. Non-numeric parts are compared as strings.
+func CompareSchemaVersions(a string, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		aNum, aErr := strconv.Atoi(aPart)
+		bNum, bErr := strconv.Atoi(bPart)
+		if aErr != nil || bErr != nil {
+			if c := strings.Compare(aPart, bPart); c != 0 {
+				return c
+			}
+			continue
+		}
+		if aNum < bNum {
+			return -1
+		} else if aNum > bNum {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package canboat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCanboatSchema_Check(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		givenSchema   CanboatSchema
+		whenReference string
+		expect        []SchemaWarning
+	}{
+		{
+			name: "ok, same version and all lookups exist",
+			givenSchema: CanboatSchema{
+				Version: "4.10.0",
+				PGNs: PGNs{
+					{PGN: 127250, Fields: []Field{{ID: "reference", LookupEnumeration: "DIRECTION_REFERENCE"}}},
+				},
+				Enums: LookupEnumerations{{Name: "DIRECTION_REFERENCE"}},
+			},
+			whenReference: "4.10.0",
+			expect:        nil,
+		},
+		{
+			name:          "nok, version is missing",
+			givenSchema:   CanboatSchema{},
+			whenReference: "4.10.0",
+			expect: []SchemaWarning{
+				{Code: SchemaWarningVersionMissing, Message: "schema has no version"},
+			},
+		},
+		{
+			name:          "nok, version is older than reference",
+			givenSchema:   CanboatSchema{Version: "4.9.1"},
+			whenReference: "v4.10.0",
+			expect: []SchemaWarning{
+				{Code: SchemaWarningVersionOlder, Message: "schema version 4.9.1 is older than expected version v4.10.0"},
+			},
+		},
+		{
+			name:          "ok, newer version than reference",
+			givenSchema:   CanboatSchema{Version: "5.0.0"},
+			whenReference: "4.10.0",
+			expect:        nil,
+		},
+		{
+			name: "nok, missing lookups",
+			givenSchema: CanboatSchema{
+				Version: "4.10.0",
+				PGNs: PGNs{
+					{PGN: 127250, Fields: []Field{{ID: "reference", LookupEnumeration: "DIRECTION_REFERENCE"}}},
+					{PGN: 127489, Fields: []Field{{ID: "discreteStatus1", LookupBitEnumeration: "ENGINE_STATUS_1"}}},
+					{PGN: 126208, Fields: []Field{{ID: "value", LookupIndirectEnumeration: "PGN_FIELDS"}}},
+				},
+			},
+			expect: []SchemaWarning{
+				{Code: SchemaWarningMissingLookup, PGN: 127250, FieldID: "reference", Message: "PGN 127250 field reference references missing lookup DIRECTION_REFERENCE"},
+				{Code: SchemaWarningMissingLookup, PGN: 127489, FieldID: "discreteStatus1", Message: "PGN 127489 field discreteStatus1 references missing lookup ENGINE_STATUS_1"},
+				{Code: SchemaWarningMissingLookup, PGN: 126208, FieldID: "value", Message: "PGN 126208 field value references missing lookup PGN_FIELDS"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := tc.givenSchema.Check(tc.whenReference)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	schema := CanboatSchema{Version: "4.9.0"}
+
+	warnings, err := CheckSchema(schema, "4.10.0", SchemaCheckWarn)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	warnings, err = CheckSchema(schema, "4.10.0", SchemaCheckFail)
+	assert.ErrorIs(t, err, ErrSchemaCheckFailed)
+	assert.EqualError(t, err, "canboat schema check failed: schema version 4.9.0 is older than expected version 4.10.0 (total warnings: 1)")
+	assert.Len(t, warnings, 1)
+
+	warnings, err = CheckSchema(schema, "4.10.0", SchemaCheckIgnore)
+	assert.NoError(t, err)
+	assert.Nil(t, warnings)
+}
+
+func TestCompareSchemaVersions(t *testing.T) {
+	var testCases = []struct {
+		a, b   string
+		expect int
+	}{
+		{a: "4.10.0", b: "4.10.0", expect: 0},
+		{a: "4.9.0", b: "4.10.0", expect: -1},
+		{a: "v4.10.1", b: "4.10.0", expect: 1},
+		{a: "4.10", b: "4.10.0", expect: 0},
+		{a: "5.0.0-rc1", b: "5.0.0-rc2", expect: -1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.a+" vs "+tc.b, func(t *testing.T) {
+			assert.Equal(t, tc.expect, CompareSchemaVersions(tc.a, tc.b))
+		})
+	}
+}
+
+func TestDecoder_SchemaVersion(t *testing.T) {
+	decoder := NewDecoder(CanboatSchema{Version: "4.10.0"})
+	assert.Equal(t, "4.10.0", decoder.SchemaVersion())
+}
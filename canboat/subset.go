@@ -0,0 +1,169 @@
+package canboat
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Subset returns schema with only definitions of given PGNs and lookup enumerations referenced by their fields. Useful
+// to reduce binary size and decoder initialization time on embedded targets that care only about few PGNs.
+func (s CanboatSchema) Subset(pgns ...uint32) CanboatSchema {
+	wanted := make(map[uint32]bool, len(pgns))
+	for _, pgn := range pgns {
+		wanted[pgn] = true
+	}
+
+	result := CanboatSchema{
+		Comment:     s.Comment,
+		CreatorCode: s.CreatorCode,
+		License:     s.License,
+		Version:     s.Version,
+		PGNs:        PGNs{},
+	}
+	enums := map[string]bool{}
+	indirectEnums := map[string]bool{}
+	bitEnums := map[string]bool{}
+	for _, pgn := range s.PGNs {
+		if !wanted[pgn.PGN] {
+			continue
+		}
+		result.PGNs = append(result.PGNs, pgn)
+		for _, f := range pgn.Fields {
+			if f.LookupEnumeration != "" {
+				enums[f.LookupEnumeration] = true
+			}
+			if f.LookupIndirectEnumeration != "" {
+				indirectEnums[f.LookupIndirectEnumeration] = true
+			}
+			if f.LookupBitEnumeration != "" {
+				bitEnums[f.LookupBitEnumeration] = true
+			}
+		}
+	}
+	for _, e := range s.Enums {
+		if enums[e.Name] {
+			result.Enums = append(result.Enums, e)
+		}
+	}
+	for _, e := range s.IndirectEnums {
+		if indirectEnums[e.Name] {
+			result.IndirectEnums = append(result.IndirectEnums, e)
+		}
+	}
+	for _, e := range s.BitEnums {
+		if bitEnums[e.Name] {
+			result.BitEnums = append(result.BitEnums, e)
+		}
+	}
+	return result
+}
+
+// WriteCANBoatSchemaGob serializes schema as gob blob. Gob is smaller and faster to load than JSON schema. Load it with
+// LoadCANBoatSchemaGob (i.e. from file embedded with go:embed).
+func WriteCANBoatSchemaGob(w io.Writer, schema CanboatSchema) error {
+	return gob.NewEncoder(w).Encode(schema)
+}
+
+// LoadCANBoatSchemaGob loads schema serialized with WriteCANBoatSchemaGob
+func LoadCANBoatSchemaGob(r io.Reader) (CanboatSchema, error) {
+	schema := CanboatSchema{}
+	if err := gob.NewDecoder(r).Decode(&schema); err != nil {
+		return CanboatSchema{}, err
+	}
+	return schema, nil
+}
+
+// WriteCANBoatSchemaGoSource writes schema as Go source file with schema as variable of type canboat.CanboatSchema.
+// Schema in Go source needs no parsing at runtime so decoder can be created without loading files.
+func WriteCANBoatSchemaGoSource(w io.Writer, schema CanboatSchema, packageName string, varName string) error {
+	if packageName == "" || varName == "" {
+		return fmt.Errorf("package and variable name are required")
+	}
+	pgns := make([]string, 0, len(schema.PGNs))
+	seen := map[uint32]bool{}
+	for _, pgn := range schema.PGNs {
+		if !seen[pgn.PGN] {
+			seen[pgn.PGN] = true
+			pgns = append(pgns, fmt.Sprintf("%v", pgn.PGN))
+		}
+	}
+	sort.Strings(pgns)
+
+	src := bytes.Buffer{}
+	src.WriteString("// Code generated by n2kschema. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %v\n\n", packageName)
+	src.WriteString("import \"github.com/aldas/go-nmea-client/canboat\"\n\n")
+	fmt.Fprintf(&src, "// %v is Canboat schema (version: %v) subset with PGNs: %v\n", varName, schema.Version, strings.Join(pgns, ", "))
+	fmt.Fprintf(&src, "var %v = ", varName)
+	writeGoLiteral(&src, reflect.ValueOf(schema), true)
+	src.WriteString("\n")
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated schema source, err: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// writeGoLiteral writes value as Go composite literal. Zero valued struct fields are omitted and element types are
+// elided inside slices to keep generated source compact.
+func writeGoLiteral(buf *bytes.Buffer, v reflect.Value, withType bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if withType {
+			buf.WriteString(goTypeName(v.Type()))
+		}
+		buf.WriteString("{\n")
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if f.IsZero() || !v.Type().Field(i).IsExported() {
+				continue
+			}
+			buf.WriteString(v.Type().Field(i).Name)
+			buf.WriteString(": ")
+			writeGoLiteral(buf, f, true)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString("}")
+	case reflect.Slice:
+		if withType {
+			buf.WriteString(goTypeName(v.Type()))
+		}
+		buf.WriteString("{\n")
+		for i := 0; i < v.Len(); i++ {
+			writeGoLiteral(buf, v.Index(i), false)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString("}")
+	case reflect.String:
+		buf.WriteString(strconv.Quote(v.String()))
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	default:
+		panic(fmt.Sprintf("unsupported kind in schema: %v", v.Kind()))
+	}
+}
+
+func goTypeName(t reflect.Type) string {
+	if t.Name() == "" && t.Kind() == reflect.Slice {
+		return "[]" + goTypeName(t.Elem())
+	}
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+	return "canboat." + t.Name()
+}
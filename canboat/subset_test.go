@@ -0,0 +1,102 @@
+package canboat
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func testSubsetSchema() CanboatSchema {
+	return CanboatSchema{
+		Version: "1.0.0",
+		PGNs: PGNs{
+			{PGN: 127488, ID: "engineParametersRapidUpdate", Fields: []Field{
+				{ID: "instance", FieldType: FieldTypeLookup, LookupEnumeration: "ENGINE_INSTANCE"},
+			}},
+			{PGN: 60928, ID: "isoAddressClaim", Fields: []Field{
+				{ID: "deviceFunction", FieldType: FieldTypeIndirectLookup, LookupIndirectEnumeration: "DEVICE_FUNCTION"},
+			}},
+			{PGN: 130845, ID: "simnetCompassHeadingOffset", Fields: []Field{
+				{ID: "manufacturerCode", FieldType: FieldTypeLookup, LookupEnumeration: "MANUFACTURER_CODE"},
+			}},
+			{PGN: 130845, ID: "furunoMultiSatsInViewExtended", Fields: []Field{
+				{ID: "status", FieldType: FieldTypeBitLookup, LookupBitEnumeration: "ENGINE_STATUS_1"},
+			}},
+		},
+		Enums: LookupEnumerations{
+			{Name: "ENGINE_INSTANCE"},
+			{Name: "MANUFACTURER_CODE"},
+			{Name: "YES_NO"},
+		},
+		IndirectEnums: LookupIndirectEnumerations{{Name: "DEVICE_FUNCTION"}},
+		BitEnums:      LookupBitEnumerations{{Name: "ENGINE_STATUS_1"}, {Name: "ENGINE_STATUS_2"}},
+	}
+}
+
+func TestCanboatSchema_Subset(t *testing.T) {
+	schema := testSubsetSchema()
+
+	result := schema.Subset(127488, 130845)
+
+	assert.Equal(t, "1.0.0", result.Version)
+	assert.Equal(t, PGNs{schema.PGNs[0], schema.PGNs[2], schema.PGNs[3]}, result.PGNs)
+	assert.Equal(t, LookupEnumerations{{Name: "ENGINE_INSTANCE"}, {Name: "MANUFACTURER_CODE"}}, result.Enums)
+	assert.Empty(t, result.IndirectEnums)
+	assert.Equal(t, LookupBitEnumerations{{Name: "ENGINE_STATUS_1"}}, result.BitEnums)
+}
+
+func TestCanboatSchema_Subset_unknownPGN(t *testing.T) {
+	result := testSubsetSchema().Subset(1)
+
+	assert.Empty(t, result.PGNs)
+	assert.Empty(t, result.Enums)
+	assert.Empty(t, result.IndirectEnums)
+	assert.Empty(t, result.BitEnums)
+}
+
+func TestCANBoatSchemaGob_roundtrip(t *testing.T) {
+	schema, err := LoadCANBoatSchemaFile("testdata/canboat.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	subset := schema.Subset(127250, 127489)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, WriteCANBoatSchemaGob(&buf, subset))
+
+	result, err := LoadCANBoatSchemaGob(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, subset, result)
+
+	decoder := NewDecoder(result)
+	assert.NotNil(t, decoder)
+}
+
+func TestWriteCANBoatSchemaGoSource(t *testing.T) {
+	schema, err := LoadCANBoatSchemaFile("testdata/canboat.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	buf := bytes.Buffer{}
+	err = WriteCANBoatSchemaGoSource(&buf, schema.Subset(127489, 127250), "schema", "EngineSchema")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	src := buf.String()
+	assert.Contains(t, src, "// Code generated by n2kschema. DO NOT EDIT.")
+	assert.Contains(t, src, "// EngineSchema is Canboat schema (version: "+schema.Version+") subset with PGNs: 127250, 127489")
+	assert.Contains(t, src, "var EngineSchema = canboat.CanboatSchema{")
+	assert.Contains(t, src, `ID:                   "engineParametersDynamic",`)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "schema.go", src, 0)
+	assert.NoError(t, err)
+}
+
+func TestWriteCANBoatSchemaGoSource_requiresNames(t *testing.T) {
+	err := WriteCANBoatSchemaGoSource(&bytes.Buffer{}, CanboatSchema{}, "", "schema")
+	assert.EqualError(t, err, "package and variable name are required")
+}
@@ -0,0 +1,76 @@
+package canboat
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"math"
+)
+
+// UnitSystem determines in which units Decoder outputs numeric field values
+type UnitSystem uint8
+
+const (
+	// UnitSystemDefault outputs values in SI units (as Canboat schema defines them) without unit annotation
+	UnitSystemDefault UnitSystem = iota
+	// UnitSystemSI outputs values in SI units and annotates nmea.FieldValue.Unit with field unit
+	UnitSystemSI
+	// UnitSystemDisplay converts values to units commonly used on displays (same as Canboat analyzer without `-si`
+	// flag) and annotates nmea.FieldValue.Unit with converted unit:
+	// * radians to degrees (`rad` -> `deg`, `rad/s` -> `deg/s`)
+	// * Kelvins to Celsius (`K` -> `C`)
+	// * meters per second to knots (`m/s` -> `kn`)
+	// * Pascals to bars (`Pa` -> `bar`)
+	UnitSystemDisplay
+)
+
+// unitConversion converts value from SI unit to display unit: result = value*scale + offset
+type unitConversion struct {
+	unit   string
+	scale  float64
+	offset float64
+	// physicalQuantity limits conversion to fields with given physical quantity. Empty value or field without
+	// physical quantity matches always.
+	physicalQuantity string
+}
+
+var displayUnitConversions = map[string]unitConversion{
+	"rad":   {unit: "deg", scale: 180 / math.Pi},
+	"rad/s": {unit: "deg/s", scale: 180 / math.Pi},
+	// Kelvins are converted only for absolute temperatures. Offset must not be added to temperature differences.
+	"K":   {unit: "C", scale: 1, offset: -273.15, physicalQuantity: "TEMPERATURE"},
+	"m/s": {unit: "kn", scale: 3600.0 / 1852},
+	"Pa":  {unit: "bar", scale: 1.0 / 100_000},
+}
+
+// convertUnit converts numeric field value to given unit system and annotates value with resulting unit. Integer
+// values are converted to float64 when unit conversion is applied.
+func convertUnit(f Field, fv nmea.FieldValue, units UnitSystem) nmea.FieldValue {
+	if units == UnitSystemDefault || f.Unit == "" {
+		return fv
+	}
+	fv.Unit = f.Unit
+	if units != UnitSystemDisplay || (f.FieldType != FieldTypeNumber && f.FieldType != FieldTypeFloat) {
+		return fv
+	}
+	conversion, ok := displayUnitConversions[f.Unit]
+	if !ok {
+		return fv
+	}
+	if conversion.physicalQuantity != "" && f.PhysicalQuantity != "" && conversion.physicalQuantity != f.PhysicalQuantity {
+		return fv
+	}
+
+	var value float64
+	switch v := fv.Value.(type) {
+	case float64:
+		value = v
+	case int64:
+		value = float64(v)
+	case uint64:
+		value = float64(v)
+	default:
+		return fv
+	}
+	fv.Value = value*conversion.scale + conversion.offset
+	fv.Unit = conversion.unit
+	return fv
+}
@@ -0,0 +1,136 @@
+package canboat
+
+import (
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/aldas/go-nmea-client/test/message_test"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConvertUnit(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		givenField Field
+		givenUnits UnitSystem
+		when       nmea.FieldValue
+		expect     nmea.FieldValue
+	}{
+		{
+			name:       "ok, default units are not annotated",
+			givenField: Field{ID: "heading", Unit: "rad", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDefault,
+			when:       nmea.FieldValue{ID: "heading", Value: math.Pi},
+			expect:     nmea.FieldValue{ID: "heading", Value: math.Pi},
+		},
+		{
+			name:       "ok, SI units are annotated",
+			givenField: Field{ID: "heading", Unit: "rad", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemSI,
+			when:       nmea.FieldValue{ID: "heading", Value: math.Pi},
+			expect:     nmea.FieldValue{ID: "heading", Value: math.Pi, Unit: "rad"},
+		},
+		{
+			name:       "ok, radians to degrees",
+			givenField: Field{ID: "heading", Unit: "rad", PhysicalQuantity: "ANGLE", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "heading", Value: math.Pi},
+			expect:     nmea.FieldValue{ID: "heading", Value: 180.0, Unit: "deg"},
+		},
+		{
+			name:       "ok, radians per second to degrees per second",
+			givenField: Field{ID: "rate", Unit: "rad/s", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "rate", Value: -math.Pi / 2},
+			expect:     nmea.FieldValue{ID: "rate", Value: -90.0, Unit: "deg/s"},
+		},
+		{
+			name:       "ok, Kelvins to Celsius",
+			givenField: Field{ID: "temperature", Unit: "K", PhysicalQuantity: "TEMPERATURE", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "temperature", Value: 291.15},
+			expect:     nmea.FieldValue{ID: "temperature", Value: 18.0, Unit: "C"},
+		},
+		{
+			name:       "ok, Kelvins of other physical quantity are not converted",
+			givenField: Field{ID: "delta", Unit: "K", PhysicalQuantity: "TEMPERATURE_DIFFERENCE", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "delta", Value: 1.5},
+			expect:     nmea.FieldValue{ID: "delta", Value: 1.5, Unit: "K"},
+		},
+		{
+			name:       "ok, meters per second to knots",
+			givenField: Field{ID: "sog", Unit: "m/s", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "sog", Value: 1852.0 / 3600},
+			expect:     nmea.FieldValue{ID: "sog", Value: 1.0, Unit: "kn"},
+		},
+		{
+			name:       "ok, integer Pascals to bars",
+			givenField: Field{ID: "pressure", Unit: "Pa", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "pressure", Value: uint64(101325)},
+			expect:     nmea.FieldValue{ID: "pressure", Value: 1.01325, Unit: "bar"},
+		},
+		{
+			name:       "ok, unit without conversion is annotated",
+			givenField: Field{ID: "voltage", Unit: "V", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "voltage", Value: 12.5},
+			expect:     nmea.FieldValue{ID: "voltage", Value: 12.5, Unit: "V"},
+		},
+		{
+			name:       "ok, time field is annotated but not converted",
+			givenField: Field{ID: "hours", Unit: "s", FieldType: FieldTypeTime},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "hours", Value: time.Hour},
+			expect:     nmea.FieldValue{ID: "hours", Value: time.Hour, Unit: "s"},
+		},
+		{
+			name:       "ok, field without unit",
+			givenField: Field{ID: "instance", FieldType: FieldTypeNumber},
+			givenUnits: UnitSystemDisplay,
+			when:       nmea.FieldValue{ID: "instance", Value: uint64(1)},
+			expect:     nmea.FieldValue{ID: "instance", Value: uint64(1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := convertUnit(tc.givenField, tc.when, tc.givenUnits)
+
+			message_test.AssertFieldValue(t, tc.expect, result, 0.000_000_1)
+		})
+	}
+}
+
+func TestDecoder_Decode_units(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	pgn := loadPGN(t, "canboat_pgn_127489.json")
+
+	decoder := NewDecoderWithConfig(CanboatSchema{PGNs: PGNs{*pgn}}, DecoderConfig{Units: UnitSystemDisplay})
+	result, err := decoder.Decode(nmea.RawMessage{
+		Time:   now,
+		Header: nmea.CanBusHeader{Priority: 2, PGN: 127489, Destination: 255, Source: 236},
+		Data: []byte{
+			0x00, 0x28, 0x00, 0xff, 0xff, 0xbb, 0x71, 0x57, 0x03, 0x00,
+			0x00, 0xe0, 0xb0, 0x05, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0x20, 0x00, 0x00, 0x00, 0x7e, 0xff,
+		},
+	})
+
+	assert.NoError(t, err)
+	message_test.AssertFieldValues(t, nmea.FieldValues{
+		{ID: "instance", Value: uint64(0)},
+		{ID: "oilPressure", Value: 0.04, Unit: "bar"},
+		{ID: "temperature", Value: 18.0, Unit: "C"},
+		{ID: "alternatorPotential", Value: 8.55, Unit: "V"},
+		{ID: "fuelRate", Value: float64(0), Unit: "L/h"},
+		{ID: "totalEngineHours", Value: 103*time.Hour + 36*time.Minute, Unit: "s"},
+		{ID: "discreteStatus1", Value: uint64(32)},
+		{ID: "discreteStatus2", Value: uint64(0)},
+		{ID: "engineTorque", Value: int64(-1), Unit: "%"},
+	}, result.Fields, 0.000_000_1)
+}
@@ -0,0 +1,233 @@
+package canboat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Severity is severity of schema validation issue
+type Severity string
+
+const (
+	// SeverityError is issue that makes PGN decode incorrectly or fail to decode
+	SeverityError Severity = "error"
+	// SeverityWarning is suspicious definition that is probably a mistake but does not break decoding
+	SeverityWarning Severity = "warning"
+)
+
+const (
+	// IssueDuplicatePGN is issue code for PGN definition that has same PGN and ID as other definition
+	IssueDuplicatePGN = "duplicate_pgn"
+	// IssueUnmatchableDefinition is issue code for PGN with multiple definitions where definition has no match fields
+	IssueUnmatchableDefinition = "unmatchable_definition"
+	// IssueDuplicateFieldID is issue code for field ID that is not unique within PGN
+	IssueDuplicateFieldID = "duplicate_field_id"
+	// IssueFieldOrder is issue code for field whose Order does not match its position in fields list
+	IssueFieldOrder = "field_order"
+	// IssueInvalidField is issue code for field definition that is invalid for its type (see Field.Validate)
+	IssueInvalidField = "invalid_field"
+	// IssueUnknownLookup is issue code for field that references lookup enumeration that does not exist in schema
+	IssueUnknownLookup = "unknown_lookup"
+	// IssueFieldOverlap is issue code for field whose bits overlap with previous field
+	IssueFieldOverlap = "field_overlap"
+	// IssueFieldGap is issue code for field that does not start where previous field ended
+	IssueFieldGap = "field_gap"
+	// IssueLengthMismatch is issue code for fixed length PGN whose Length does not match length of its fields
+	IssueLengthMismatch = "length_mismatch"
+	// IssueInvalidRepeatingFieldSet is issue code for repeating field set with invalid start, size or count field
+	IssueInvalidRepeatingFieldSet = "invalid_repeating_field_set"
+)
+
+// ValidationIssue is single problem found in schema
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	// Code is machine-readable identifier of issue kind (i.e. IssueUnknownLookup)
+	Code  string `json:"code"`
+	PGN   uint32 `json:"pgn"`
+	PGNID string `json:"pgn_id"`
+	// FieldID is ID of field the issue relates to. Empty for PGN level issues.
+	FieldID string `json:"field_id,omitempty"`
+	// FieldOrder is order (1 based) of field the issue relates to. Zero for PGN level issues.
+	FieldOrder int `json:"field_order,omitempty"`
+	// Message is human-readable description of issue
+	Message string `json:"message"`
+}
+
+func (i ValidationIssue) String() string {
+	if i.FieldID != "" {
+		return fmt.Sprintf("%v: PGN %v (%v) field %v (%v): %v", i.Severity, i.PGN, i.PGNID, i.FieldOrder, i.FieldID, i.Message)
+	}
+	return fmt.Sprintf("%v: PGN %v (%v): %v", i.Severity, i.PGN, i.PGNID, i.Message)
+}
+
+// ValidationReport is result of schema validation
+type ValidationReport struct {
+	// PGNs is number of validated PGN definitions
+	PGNs     int `json:"pgns"`
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	// Issues are ordered by PGN. Issues of same PGN are in order of definitions and fields.
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// HasErrors checks if report contains issues with error severity
+func (r ValidationReport) HasErrors() bool {
+	return r.Errors > 0
+}
+
+// WriteText writes report as text, one issue per line, followed by summary line
+func (r ValidationReport) WriteText(w io.Writer) error {
+	for _, i := range r.Issues {
+		if _, err := fmt.Fprintln(w, i.String()); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "validated %v PGNs: %v errors, %v warnings\n", r.PGNs, r.Errors, r.Warnings)
+	return err
+}
+
+func (r *ValidationReport) add(severity Severity, code string, pgn PGN, f *Field, fieldOrder int, format string, a ...any) {
+	issue := ValidationIssue{
+		Severity: severity,
+		Code:     code,
+		PGN:      pgn.PGN,
+		PGNID:    pgn.ID,
+		Message:  fmt.Sprintf(format, a...),
+	}
+	if f != nil {
+		issue.FieldID = f.ID
+		issue.FieldOrder = fieldOrder
+	}
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// Validate checks schema for problems that PGNs.Validate checks and additionally cross-checks lookup enumeration
+// references, field bit offsets (overlaps and gaps), fixed PGN lengths and repeating field set indices. Useful when
+// maintaining locally patched schemas.
+func (s CanboatSchema) Validate() ValidationReport {
+	report := ValidationReport{PGNs: len(s.PGNs), Issues: make([]ValidationIssue, 0)}
+
+	definitions := map[uint32]int{}
+	seen := map[string]bool{}
+	for _, pgn := range s.PGNs {
+		definitions[pgn.PGN]++
+		key := fmt.Sprintf("%v/%v", pgn.PGN, pgn.ID)
+		if seen[key] {
+			report.add(SeverityError, IssueDuplicatePGN, pgn, nil, 0, "PGN has multiple definitions with same ID")
+		}
+		seen[key] = true
+	}
+
+	for _, pgn := range s.PGNs {
+		if definitions[pgn.PGN] > 1 && !hasMatchFields(pgn) {
+			report.add(SeverityWarning, IssueUnmatchableDefinition, pgn, nil, 0,
+				"PGN has %v definitions but this definition has no match fields", definitions[pgn.PGN])
+		}
+		s.validateFields(&report, pgn)
+		validateRepeatingFieldSet(&report, pgn, 1, pgn.RepeatingFieldSet1StartField, pgn.RepeatingFieldSet1Size, pgn.RepeatingFieldSet1CountField)
+		validateRepeatingFieldSet(&report, pgn, 2, pgn.RepeatingFieldSet2StartField, pgn.RepeatingFieldSet2Size, pgn.RepeatingFieldSet2CountField)
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].PGN < report.Issues[j].PGN
+	})
+	return report
+}
+
+func hasMatchFields(pgn PGN) bool {
+	for _, f := range pgn.Fields {
+		if f.Match != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s CanboatSchema) validateFields(report *ValidationReport, pgn PGN) {
+	ids := map[string]bool{}
+	// offsets of fields after variable length field are not known, so they are not checked
+	checkOffsets := true
+	bitEnd := uint32(0)
+	if len(pgn.Fields) > 0 {
+		bitEnd = uint32(pgn.Fields[0].BitOffset)
+	}
+	for i := range pgn.Fields {
+		f := &pgn.Fields[i]
+		order := i + 1
+		if ids[f.ID] {
+			report.add(SeverityError, IssueDuplicateFieldID, pgn, f, order, "duplicate field ID")
+		}
+		ids[f.ID] = true
+
+		if int(f.Order) != order {
+			report.add(SeverityWarning, IssueFieldOrder, pgn, f, order, "field Order is %v", f.Order)
+		}
+		if err := f.Validate(); err != nil {
+			report.add(SeverityError, IssueInvalidField, pgn, f, order, "%v", err)
+		}
+		if f.LookupEnumeration != "" && !s.Enums.Exists(f.LookupEnumeration) {
+			report.add(SeverityError, IssueUnknownLookup, pgn, f, order, "unknown lookup enumeration %v", f.LookupEnumeration)
+		}
+		if f.LookupBitEnumeration != "" && !s.BitEnums.Exists(f.LookupBitEnumeration) {
+			report.add(SeverityError, IssueUnknownLookup, pgn, f, order, "unknown bit lookup enumeration %v", f.LookupBitEnumeration)
+		}
+		if f.LookupIndirectEnumeration != "" && !s.IndirectEnums.Exists(f.LookupIndirectEnumeration) {
+			report.add(SeverityError, IssueUnknownLookup, pgn, f, order, "unknown indirect lookup enumeration %v", f.LookupIndirectEnumeration)
+		}
+
+		if !checkOffsets {
+			continue
+		}
+		// Canboat omits BitOffset of fields whose offset is not known (after variable length fields)
+		if f.BitLengthVariable || f.FieldType == FieldTypeVariable || f.FieldType == FieldTypeKeyValue ||
+			(i > 0 && f.BitOffset == 0) {
+			checkOffsets = false
+			continue
+		}
+		offset := uint32(f.BitOffset)
+		if offset < bitEnd {
+			report.add(SeverityError, IssueFieldOverlap, pgn, f, order,
+				"field bit offset %v overlaps with previous field ending at bit %v", offset, bitEnd)
+		} else if offset > bitEnd {
+			report.add(SeverityWarning, IssueFieldGap, pgn, f, order,
+				"field bit offset %v leaves gap after previous field ending at bit %v", offset, bitEnd)
+		}
+		bitEnd = offset + uint32(f.BitLength)
+	}
+
+	isRepeating := pgn.RepeatingFieldSet1StartField > 0 || pgn.RepeatingFieldSet2StartField > 0
+	if checkOffsets && !isRepeating && pgn.Length > 0 && (bitEnd+7)/8 != uint32(pgn.Length) {
+		report.add(SeverityWarning, IssueLengthMismatch, pgn, nil, 0,
+			"PGN Length is %v bytes but fields take %v bits", pgn.Length, bitEnd)
+	}
+}
+
+func validateRepeatingFieldSet(report *ValidationReport, pgn PGN, set int, start int8, size int8, count int8) {
+	if start == 0 && size == 0 && count == 0 {
+		return
+	}
+	fieldCount := len(pgn.Fields)
+	switch {
+	case start <= 0 || size <= 0:
+		report.add(SeverityError, IssueInvalidRepeatingFieldSet, pgn, nil, 0,
+			"RepeatingFieldSet%v has start field %v and size %v", set, start, size)
+	case int(start)+int(size)-1 > fieldCount:
+		report.add(SeverityError, IssueInvalidRepeatingFieldSet, pgn, nil, 0,
+			"RepeatingFieldSet%v fields %v-%v are out of fields list (%v fields)", set, start, int(start)+int(size)-1, fieldCount)
+	case count > 0 && int(count) > fieldCount:
+		report.add(SeverityError, IssueInvalidRepeatingFieldSet, pgn, nil, 0,
+			"RepeatingFieldSet%v count field %v is out of fields list (%v fields)", set, count, fieldCount)
+	case count > 0 && count >= start:
+		report.add(SeverityError, IssueInvalidRepeatingFieldSet, pgn, nil, 0,
+			"RepeatingFieldSet%v count field %v is not before start field %v", set, count, start)
+	case count > 0 && pgn.Fields[count-1].FieldType != FieldTypeNumber:
+		report.add(SeverityError, IssueInvalidRepeatingFieldSet, pgn, nil, 0,
+			"RepeatingFieldSet%v count field %v is not NUMBER type", set, count)
+	}
+}
@@ -0,0 +1,72 @@
+package canboat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCanboatSchema_Validate(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		given  CanboatSchema
+		expect []ValidationIssue
+	}{
+		{
+			name: "ok, valid schema",
+			given: CanboatSchema{
+				PGNs: PGNs{
+					{PGN: 127245, ID: "rudder", Length: 2, Fields: []Field{
+						{ID: "instance", Order: 1, BitLength: 8, BitOffset: 0, FieldType: FieldTypeNumber},
+						{ID: "directionOrder", Order: 2, BitLength: 2, BitOffset: 8, FieldType: FieldTypeLookup, LookupEnumeration: "DIRECTION_RUDDER"},
+						{ID: "reserved", Order: 3, BitLength: 6, BitOffset: 10, FieldType: FieldTypeReserved},
+					}},
+				},
+				Enums: LookupEnumerations{{Name: "DIRECTION_RUDDER"}},
+			},
+			expect: []ValidationIssue{},
+		},
+		{
+			name: "nok, duplicate definitions and field IDs",
+			given: CanboatSchema{
+				PGNs: PGNs{
+					{PGN: 130845, ID: "first", Fields: []Field{
+						{ID: "a", Order: 1, BitLength: 8, FieldType: FieldTypeNumber},
+						{ID: "a", Order: 2, BitLength: 8, BitOffset: 8, FieldType: FieldTypeNumber},
+					}},
+					{PGN: 130845, ID: "first", Fields: []Field{
+						{ID: "a", Order: 1, BitLength: 8, FieldType: FieldTypeNumber, Match: 1},
+					}},
+				},
+			},
+			expect: []ValidationIssue{
+				{Severity: SeverityError, Code: IssueDuplicatePGN, PGN: 130845, PGNID: "first", Message: "PGN has multiple definitions with same ID"},
+				{Severity: SeverityWarning, Code: IssueUnmatchableDefinition, PGN: 130845, PGNID: "first", Message: "PGN has 2 definitions but this definition has no match fields"},
+				{Severity: SeverityError, Code: IssueDuplicateFieldID, PGN: 130845, PGNID: "first", FieldID: "a", FieldOrder: 2, Message: "duplicate field ID"},
+			},
+		},
+		{
+			name: "nok, field gap, order and count field type",
+			given: CanboatSchema{
+				PGNs: PGNs{
+					{PGN: 129540, ID: "sats", RepeatingFieldSet1Size: 1, RepeatingFieldSet1StartField: 2, RepeatingFieldSet1CountField: 1, Fields: []Field{
+						{ID: "count", Order: 1, BitLength: 8, FieldType: FieldTypeBinary},
+						{ID: "prn", Order: 3, BitLength: 8, BitOffset: 16, FieldType: FieldTypeNumber},
+					}},
+				},
+			},
+			expect: []ValidationIssue{
+				{Severity: SeverityWarning, Code: IssueFieldOrder, PGN: 129540, PGNID: "sats", FieldID: "prn", FieldOrder: 2, Message: "field Order is 3"},
+				{Severity: SeverityWarning, Code: IssueFieldGap, PGN: 129540, PGNID: "sats", FieldID: "prn", FieldOrder: 2, Message: "field bit offset 16 leaves gap after previous field ending at bit 8"},
+				{Severity: SeverityError, Code: IssueInvalidRepeatingFieldSet, PGN: 129540, PGNID: "sats", Message: "RepeatingFieldSet1 count field 1 is not NUMBER type"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := tc.given.Validate()
+
+			assert.Equal(t, tc.expect, report.Issues)
+			assert.Equal(t, len(tc.given.PGNs), report.PGNs)
+		})
+	}
+}
@@ -1,5 +1,16 @@
 package nmea
 
+import "fmt"
+
+const (
+	// PGNMax is largest valid PGN. PGN is 18 bits: reserved (1bit) + data page (1bit) + PDU format (8bits) + PDU
+	// specific (8bits).
+	PGNMax = uint32(0x3FFFF)
+	// PDU2FormatMin is smallest PDU format (PF) value of PDU2 messages. Messages with smaller PF value are PDU1
+	// messages where PDU specific (PS) byte is destination address and not part of PGN.
+	PDU2FormatMin = uint8(240)
+)
+
 type CanBusHeader struct {
 	PGN         uint32 `json:"pgn"`
 	Priority    uint8  `json:"priority"`
@@ -7,19 +18,64 @@ type CanBusHeader struct {
 	Destination uint8  `json:"destination"`
 }
 
+// Uint32 returns 29 bit CAN ID for header. Is inverse of ParseCANID.
 func (h CanBusHeader) Uint32() uint32 {
 	canID := uint32(h.Source) // bit 0-7
 
-	pf := uint8(h.PGN)
-	if pf < 240 {
+	pgn := h.PGN & PGNMax
+	if IsPDU1PGN(pgn) {
+		pgn &^= 0xFF                        // PS byte of PDU1 PGN is always 0
 		canID |= uint32(h.Destination) << 8 // bits 8-15
 	}
-	canID |= h.PGN << 8                        // bits 16-24
+	canID |= pgn << 8                          // bits 8-25
 	canID = canID | uint32(h.Priority&0x7)<<26 // bit 26,27,28
 	return canID                               // this need to be turned to big endian when written to the wire
 }
 
-// ParseCANID parses can bus header fields from CANID (29 bits of 32 bit).
+// IsPDU1 returns true when header PGN is PDU1 format (addressable) message.
+func (h CanBusHeader) IsPDU1() bool {
+	return IsPDU1PGN(h.PGN)
+}
+
+// IsPDU2 returns true when header PGN is PDU2 format (broadcast only) message.
+func (h CanBusHeader) IsPDU2() bool {
+	return !IsPDU1PGN(h.PGN)
+}
+
+// IsBroadcast returns true when message is meant for all nodes on the bus. PDU2 messages are always broadcast and PDU1
+// messages are broadcast when sent to global address.
+func (h CanBusHeader) IsBroadcast() bool {
+	return h.IsPDU2() || h.Destination == AddressGlobal
+}
+
+// IsPDU1PGN returns true when PGN is PDU1 format (addressable) PGN. PDU1 PGNs have PDU format (PF) byte value less
+// than 240 and their PDU specific (PS) byte is used for destination address.
+func IsPDU1PGN(pgn uint32) bool {
+	return uint8(pgn>>8) < PDU2FormatMin
+}
+
+// PGNFromPDU creates PGN from CAN ID fields. dataPage contains reserved and data page bits (bits 24,25 of CAN ID).
+// PDU specific byte is part of PGN only for PDU2 messages, for PDU1 messages it is destination address.
+func PGNFromPDU(dataPage uint8, pduFormat uint8, pduSpecific uint8) uint32 {
+	pgn := uint32(dataPage&3)<<16 + uint32(pduFormat)<<8
+	if pduFormat >= PDU2FormatMin {
+		pgn += uint32(pduSpecific)
+	}
+	return pgn
+}
+
+// ValidatePGN checks that PGN fits into 18 bits and PDU1 PGN does not have PDU specific (PS) bits set.
+func ValidatePGN(pgn uint32) error {
+	if pgn > PGNMax {
+		return fmt.Errorf("pgn %v is out of range, max %v", pgn, PGNMax)
+	}
+	if IsPDU1PGN(pgn) && uint8(pgn) != 0 {
+		return fmt.Errorf("pgn %v is PDU1 PGN with PDU specific bits set", pgn)
+	}
+	return nil
+}
+
+// ParseCANID parses can bus header fields from CANID (29 bits of 32 bit). Is inverse of CanBusHeader.Uint32.
 func ParseCANID(canID uint32) CanBusHeader {
 	result := CanBusHeader{
 		Priority: uint8((canID >> 26) & 0x7), // bit 26,27,28
@@ -28,13 +84,11 @@ func ParseCANID(canID uint32) CanBusHeader {
 	ps := uint8(canID >> 8)         // bits 8-15
 	pduFormat := uint8(canID >> 16) // bits 16-23
 	rAndDP := uint8(canID>>24) & 3  // bits 24,25
-	pgn := (uint32(rAndDP) << 16) + uint32(pduFormat)<<8
-	if pduFormat < 240 {
+	result.PGN = PGNFromPDU(rAndDP, pduFormat, ps)
+	if pduFormat < PDU2FormatMin {
 		result.Destination = ps
-		result.PGN = pgn
 	} else {
 		result.Destination = AddressGlobal // 0xff is broadcast to all
-		result.PGN = pgn + uint32(ps)
 	}
 	return result
 }
@@ -95,7 +95,7 @@ func TestCanBusHeader_Uint32(t *testing.T) {
 				Source:      23,  // 0x17
 				Destination: 255, // 0xFF
 			},
-			expect: 0x15fdff17,
+			expect: 0x15fd0717,
 		},
 		{
 			name: "ok, 130310",
@@ -105,7 +105,7 @@ func TestCanBusHeader_Uint32(t *testing.T) {
 				Source:      23,  // 0x17
 				Destination: 255, // 0xFF
 			},
-			expect: 0x15fdff17,
+			expect: 0x15fd0617,
 		},
 	}
 	for _, tc := range testCases {
@@ -115,3 +115,70 @@ func TestCanBusHeader_Uint32(t *testing.T) {
 		})
 	}
 }
+
+func TestCanBusHeader_Uint32_ParseCANIDSymmetric(t *testing.T) {
+	var testCases = []struct {
+		name string
+		when CanBusHeader
+	}{
+		{name: "ok, PDU1 addressed", when: CanBusHeader{PGN: uint32(PGNISORequest), Priority: 6, Source: 35, Destination: 12}},
+		{name: "ok, PDU1 global", when: CanBusHeader{PGN: uint32(PGNISOAddressClaim), Priority: 6, Source: 35, Destination: AddressGlobal}},
+		{name: "ok, PDU2 with data page", when: CanBusHeader{PGN: 130310, Priority: 5, Source: 23, Destination: AddressGlobal}},
+		{name: "ok, PDU2 without data page", when: CanBusHeader{PGN: 65280, Priority: 7, Source: 1, Destination: AddressGlobal}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.when, ParseCANID(tc.when.Uint32()))
+		})
+	}
+}
+
+func TestCanBusHeader_IsBroadcast(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		when         CanBusHeader
+		expectPDU1   bool
+		expectGlobal bool
+	}{
+		{name: "ok, PDU1 addressed", when: CanBusHeader{PGN: uint32(PGNISORequest), Destination: 12}, expectPDU1: true},
+		{name: "ok, PDU1 global", when: CanBusHeader{PGN: uint32(PGNISORequest), Destination: AddressGlobal}, expectPDU1: true, expectGlobal: true},
+		{name: "ok, PDU2", when: CanBusHeader{PGN: 130310, Destination: 12}, expectGlobal: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectPDU1, tc.when.IsPDU1())
+			assert.Equal(t, !tc.expectPDU1, tc.when.IsPDU2())
+			assert.Equal(t, tc.expectGlobal, tc.when.IsBroadcast())
+		})
+	}
+}
+
+func TestPGNFromPDU(t *testing.T) {
+	assert.Equal(t, uint32(PGNISORequest), PGNFromPDU(0, 0xEA, 0x12))
+	assert.Equal(t, uint32(130310), PGNFromPDU(1, 0xFD, 0x06))
+	assert.Equal(t, uint32(126208), PGNFromPDU(1, 0xED, 0xFF))
+}
+
+func TestValidatePGN(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        uint32
+		expectError string
+	}{
+		{name: "ok, PDU1", when: uint32(PGNISORequest)},
+		{name: "ok, PDU2", when: 130310},
+		{name: "ok, max", when: PGNMax},
+		{name: "nok, out of range", when: PGNMax + 1, expectError: "pgn 262144 is out of range, max 262143"},
+		{name: "nok, PDU1 with PS bits", when: uint32(PGNISORequest) + 1, expectError: "pgn 59905 is PDU1 PGN with PDU specific bits set"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePGN(tc.when)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
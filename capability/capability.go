@@ -0,0 +1,200 @@
+package capability
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Matrix summarizes which PGNs each source on the bus is actively producing. It is meant to be serialized (i.e. JSON)
+// and consumed by higher-level servers (i.e. Signal K plugins) to advertise available data without manual configuration.
+type Matrix struct {
+	// GeneratedAt is time when matrix was created
+	GeneratedAt time.Time `json:"generated_at"`
+	// Window is duration within which PGN must have been seen to be considered as actively produced. Window is
+	// measured back from current time (Config.Now) or from time of the latest observed message when
+	// Config.UseMessageTime is set.
+	Window  time.Duration        `json:"window"`
+	Sources []SourceCapabilities `json:"sources"`
+}
+
+// SourceCapabilities lists PGNs that single source is actively producing
+type SourceCapabilities struct {
+	Source uint8 `json:"source"`
+	// NodeNAME is ISO Address Claim NAME of the node using that source. Value `0` means that NAME is unknown.
+	NodeNAME uint64        `json:"node_name,omitempty"`
+	PGNs     []ObservedPGN `json:"pgns"`
+}
+
+// ObservedPGN holds statistics of single PGN observed from source
+type ObservedPGN struct {
+	PGN       uint32    `json:"pgn"`
+	Count     uint64    `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Interval is average time between messages. Zero when PGN has been seen only once.
+	Interval time.Duration `json:"interval"`
+}
+
+// Supports checks if given PGN is produced by the source
+func (sc SourceCapabilities) Supports(pgn uint32) bool {
+	for _, p := range sc.PGNs {
+		if p.PGN == pgn {
+			return true
+		}
+	}
+	return false
+}
+
+// SourcesFor returns list of sources that are producing given PGN
+func (m Matrix) SourcesFor(pgn uint32) []uint8 {
+	result := make([]uint8, 0)
+	for _, s := range m.Sources {
+		if s.Supports(pgn) {
+			result = append(result, s.Source)
+		}
+	}
+	return result
+}
+
+// Config configures Tracker instance
+type Config struct {
+	// Window is duration within which PGN must have been seen to be included in Matrix.
+	// Defaults to: 60 seconds
+	Window time.Duration
+
+	// Now returns current time that Window is measured back from.
+	// Defaults to: time.Now
+	Now func() time.Time
+
+	// UseMessageTime instructs Tracker to measure Window back from time of the latest observed message instead of Now.
+	// Use this when messages are replayed from recorded file and their times are in the past. Do not use with live
+	// input as PGNs would be considered as active forever when bus goes silent.
+	UseMessageTime bool
+}
+
+type observed struct {
+	count     uint64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// Tracker observes messages from the bus and keeps track which PGNs are sent by which source.
+//
+// Tracker is safe for concurrent use.
+type Tracker struct {
+	mutex  sync.Mutex
+	window time.Duration
+
+	// observed is keyed by source and PGN
+	observed  map[uint8]map[uint32]*observed
+	nodeNames map[uint8]uint64
+	// latest is time of the latest observed message. Used as reference for window when useMessageTime is set as
+	// message times are not wall clock times when reading recorded file.
+	latest         time.Time
+	useMessageTime bool
+
+	now func() time.Time
+}
+
+// NewTracker creates new instance of Tracker with default configuration
+func NewTracker() *Tracker {
+	return NewTrackerWithConfig(Config{})
+}
+
+// NewTrackerWithConfig creates new instance of Tracker with given configuration
+func NewTrackerWithConfig(config Config) *Tracker {
+	if config.Window <= 0 {
+		config.Window = 60 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Tracker{
+		window:         config.Window,
+		observed:       make(map[uint8]map[uint32]*observed),
+		nodeNames:      make(map[uint8]uint64),
+		useMessageTime: config.UseMessageTime,
+		now:            config.Now,
+	}
+}
+
+// Process records raw message as observed from its source
+func (t *Tracker) Process(raw nmea.RawMessage) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	source := raw.Header.Source
+	pgns, ok := t.observed[source]
+	if !ok {
+		pgns = make(map[uint32]*observed)
+		t.observed[source] = pgns
+	}
+	o, ok := pgns[raw.Header.PGN]
+	if !ok {
+		o = &observed{firstSeen: raw.Time}
+		pgns[raw.Header.PGN] = o
+	}
+	o.count++
+	o.lastSeen = raw.Time
+	if raw.Time.After(t.latest) {
+		t.latest = raw.Time
+	}
+}
+
+// SetNodeNAME associates node NAME (from ISO Address Claim) with source so Matrix can identify nodes by NAME.
+func (t *Tracker) SetNodeNAME(source uint8, NAME uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.nodeNames[source] = NAME
+}
+
+// Matrix returns capabilities of all sources that have produced data within configured window before current time (or
+// the latest observed message with Config.UseMessageTime). Sources and PGNs are sorted in ascending order.
+func (t *Tracker) Matrix() Matrix {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	reference := now
+	if t.useMessageTime {
+		reference = t.latest
+	}
+	threshold := reference.Add(-t.window)
+	result := Matrix{
+		GeneratedAt: now,
+		Window:      t.window,
+		Sources:     make([]SourceCapabilities, 0, len(t.observed)),
+	}
+	for source, pgns := range t.observed {
+		sc := SourceCapabilities{
+			Source:   source,
+			NodeNAME: t.nodeNames[source],
+			PGNs:     make([]ObservedPGN, 0, len(pgns)),
+		}
+		for pgn, o := range pgns {
+			if o.lastSeen.Before(threshold) {
+				continue
+			}
+			op := ObservedPGN{
+				PGN:       pgn,
+				Count:     o.count,
+				FirstSeen: o.firstSeen,
+				LastSeen:  o.lastSeen,
+			}
+			if o.count > 1 {
+				op.Interval = o.lastSeen.Sub(o.firstSeen) / time.Duration(o.count-1)
+			}
+			sc.PGNs = append(sc.PGNs, op)
+		}
+		if len(sc.PGNs) == 0 {
+			continue
+		}
+		sort.Slice(sc.PGNs, func(i, j int) bool { return sc.PGNs[i].PGN < sc.PGNs[j].PGN })
+		result.Sources = append(result.Sources, sc)
+	}
+	sort.Slice(result.Sources, func(i, j int) bool { return result.Sources[i].Source < result.Sources[j].Source })
+	return result
+}
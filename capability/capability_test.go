@@ -0,0 +1,109 @@
+package capability
+
+import (
+	"encoding/json"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTracker_Matrix(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	tracker := NewTrackerWithConfig(Config{Window: 10 * time.Second})
+	tracker.now = func() time.Time {
+		return now
+	}
+
+	tracker.Process(nmea.RawMessage{Time: now.Add(-30 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 3}})
+	tracker.Process(nmea.RawMessage{Time: now.Add(-2 * time.Second), Header: nmea.CanBusHeader{PGN: 129026, Source: 1}})
+	tracker.Process(nmea.RawMessage{Time: now.Add(-3 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 1}})
+	tracker.Process(nmea.RawMessage{Time: now.Add(-2 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 1}})
+	tracker.Process(nmea.RawMessage{Time: now.Add(-1 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 1}})
+	tracker.Process(nmea.RawMessage{Time: now, Header: nmea.CanBusHeader{PGN: 127250, Source: 2}})
+	tracker.SetNodeNAME(2, 123456)
+
+	result := tracker.Matrix()
+
+	expect := Matrix{
+		GeneratedAt: now,
+		Window:      10 * time.Second,
+		Sources: []SourceCapabilities{
+			{
+				Source: 1,
+				PGNs: []ObservedPGN{
+					{
+						PGN:       129025,
+						Count:     3,
+						FirstSeen: now.Add(-3 * time.Second),
+						LastSeen:  now.Add(-1 * time.Second),
+						Interval:  1 * time.Second,
+					},
+					{
+						PGN:       129026,
+						Count:     1,
+						FirstSeen: now.Add(-2 * time.Second),
+						LastSeen:  now.Add(-2 * time.Second),
+					},
+				},
+			},
+			{
+				Source:   2,
+				NodeNAME: 123456,
+				PGNs: []ObservedPGN{
+					{PGN: 127250, Count: 1, FirstSeen: now, LastSeen: now},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expect, result)
+	assert.Equal(t, []uint8{1}, result.SourcesFor(129025))
+	assert.Equal(t, []uint8{}, result.SourcesFor(130306))
+
+	b, err := json.Marshal(result.Sources[1])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"source":2,"node_name":123456,"pgns":[{"pgn":127250,"count":1,"first_seen":"2022-10-11T11:47:22Z","last_seen":"2022-10-11T11:47:22Z","interval":0}]}`, string(b))
+}
+
+func TestTracker_Matrix_replayTime(t *testing.T) {
+	recorded := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	tracker := NewTrackerWithConfig(Config{
+		Window:         10 * time.Second,
+		UseMessageTime: true,
+		Now: func() time.Time {
+			return recorded.Add(365 * 24 * time.Hour) // file is replayed year later
+		},
+	})
+
+	tracker.Process(nmea.RawMessage{Time: recorded.Add(-30 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 3}})
+	tracker.Process(nmea.RawMessage{Time: recorded.Add(-1 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 1}})
+	tracker.Process(nmea.RawMessage{Time: recorded, Header: nmea.CanBusHeader{PGN: 127250, Source: 2}})
+
+	result := tracker.Matrix()
+
+	assert.Equal(t, recorded.Add(365*24*time.Hour), result.GeneratedAt)
+	assert.Len(t, result.Sources, 2)
+	assert.Equal(t, []uint8{1}, result.SourcesFor(129025))
+	assert.Equal(t, []uint8{2}, result.SourcesFor(127250))
+}
+
+func TestTracker_Matrix_silentBus(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	tracker := NewTrackerWithConfig(Config{
+		Window: 10 * time.Second,
+		Now: func() time.Time {
+			return now
+		},
+	})
+	tracker.Process(nmea.RawMessage{Time: now.Add(-1 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 1}})
+	assert.Equal(t, []uint8{1}, tracker.Matrix().SourcesFor(129025))
+
+	// no messages are received after bus goes silent
+	now = now.Add(30 * time.Second)
+
+	assert.Len(t, tracker.Matrix().Sources, 0)
+}
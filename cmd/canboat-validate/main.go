@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client/canboat"
+	"io"
+	"os"
+)
+
+// canboat-validate checks Canboat schema (pgns.json) for errors: unknown lookup enumeration references, overlapping
+// field bit offsets, invalid repeating field sets etc. Exits with code 1 when schema has errors, for example:
+//
+//	go run github.com/aldas/go-nmea-client/cmd/canboat-validate -pgns=canboat.json -format=json
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("canboat-validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pgnsPath := flags.String("pgns", "", "path to Canboat pgns.json file")
+	outputFormat := flags.String("format", "text", "in which format validation report is written (text, json)")
+	warningsAsErrors := flags.Bool("warnings-as-errors", false, "exit with non-zero code also when schema has only warnings")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *pgnsPath == "" {
+		fmt.Fprintln(stderr, "path to Canboat pgns.json is required")
+		return 2
+	}
+
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load schema: %v\n", err)
+		return 2
+	}
+	report := schema.Validate()
+
+	switch *outputFormat {
+	case "text":
+		err = report.WriteText(stdout)
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	default:
+		fmt.Fprintf(stderr, "unknown output format: %v\n", *outputFormat)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to write report: %v\n", err)
+		return 2
+	}
+
+	if report.HasErrors() || (*warningsAsErrors && report.Warnings > 0) {
+		return 1
+	}
+	return 0
+}
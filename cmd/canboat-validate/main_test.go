@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const validSchema = `{
+  "PGNs": [
+    {
+      "PGN": 127250, "Id": "vesselHeading", "Length": 8,
+      "Fields": [
+        {"Id": "sid", "Order": 1, "BitLength": 8, "BitOffset": 0, "FieldType": "NUMBER"},
+        {"Id": "heading", "Order": 2, "BitLength": 16, "BitOffset": 8, "FieldType": "NUMBER"},
+        {"Id": "deviation", "Order": 3, "BitLength": 16, "BitOffset": 24, "Signed": true, "FieldType": "NUMBER"},
+        {"Id": "variation", "Order": 4, "BitLength": 16, "BitOffset": 40, "Signed": true, "FieldType": "NUMBER"},
+        {"Id": "reference", "Order": 5, "BitLength": 2, "BitOffset": 56, "FieldType": "LOOKUP", "LookupEnumeration": "DIRECTION_REFERENCE"},
+        {"Id": "reserved", "Order": 6, "BitLength": 6, "BitOffset": 58, "FieldType": "RESERVED"}
+      ]
+    }
+  ],
+  "LookupEnumerations": [
+    {"Name": "DIRECTION_REFERENCE", "EnumValues": [{"Name": "True", "Value": 0}, {"Name": "Magnetic", "Value": 1}]}
+  ]
+}`
+
+const invalidSchema = `{
+  "PGNs": [
+    {
+      "PGN": 127250, "Id": "vesselHeading", "Length": 8,
+      "Fields": [
+        {"Id": "sid", "Order": 1, "BitLength": 8, "BitOffset": 0, "FieldType": "NUMBER"},
+        {"Id": "heading", "Order": 2, "BitLength": 16, "BitOffset": 4, "FieldType": "NUMBER"},
+        {"Id": "reference", "Order": 3, "BitLength": 2, "BitOffset": 20, "FieldType": "LOOKUP", "LookupEnumeration": "UNKNOWN_LOOKUP"}
+      ]
+    },
+    {
+      "PGN": 129540, "Id": "gnssSatsInView",
+      "RepeatingFieldSet1Size": 3, "RepeatingFieldSet1StartField": 2, "RepeatingFieldSet1CountField": 1,
+      "Fields": [
+        {"Id": "satsInView", "Order": 1, "BitLength": 8, "BitOffset": 0, "FieldType": "NUMBER"},
+        {"Id": "prn", "Order": 2, "BitLength": 8, "BitOffset": 8, "FieldType": "NUMBER"}
+      ]
+    }
+  ]
+}`
+
+func writeSchema(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "pgns.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	validPath := writeSchema(t, validSchema)
+	invalidPath := writeSchema(t, invalidSchema)
+
+	var testCases = []struct {
+		name         string
+		whenArgs     []string
+		expectCode   int
+		expectStdout string
+		expectStderr string
+	}{
+		{
+			name:         "ok, valid schema",
+			whenArgs:     []string{"-pgns=" + validPath},
+			expectCode:   0,
+			expectStdout: "validated 1 PGNs: 0 errors, 0 warnings\n",
+		},
+		{
+			name:       "nok, invalid schema",
+			whenArgs:   []string{"-pgns=" + invalidPath},
+			expectCode: 1,
+			expectStdout: "error: PGN 127250 (vesselHeading) field 2 (heading): field bit offset 4 overlaps with previous field ending at bit 8\n" +
+				"error: PGN 127250 (vesselHeading) field 3 (reference): unknown lookup enumeration UNKNOWN_LOOKUP\n" +
+				"warning: PGN 127250 (vesselHeading): PGN Length is 8 bytes but fields take 22 bits\n" +
+				"error: PGN 129540 (gnssSatsInView): RepeatingFieldSet1 fields 2-4 are out of fields list (2 fields)\n" +
+				"validated 2 PGNs: 3 errors, 1 warnings\n",
+		},
+		{
+			name:         "nok, missing path",
+			whenArgs:     []string{},
+			expectCode:   2,
+			expectStderr: "path to Canboat pgns.json is required\n",
+		},
+		{
+			name:         "nok, unknown format",
+			whenArgs:     []string{"-pgns=" + validPath, "-format=xml"},
+			expectCode:   2,
+			expectStderr: "unknown output format: xml\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := bytes.Buffer{}
+			stderr := bytes.Buffer{}
+
+			code := run(tc.whenArgs, &stdout, &stderr)
+
+			assert.Equal(t, tc.expectCode, code)
+			assert.Equal(t, tc.expectStdout, stdout.String())
+			assert.Equal(t, tc.expectStderr, stderr.String())
+		})
+	}
+}
+
+func TestRun_json(t *testing.T) {
+	stdout := bytes.Buffer{}
+
+	code := run([]string{"-pgns=" + writeSchema(t, invalidSchema), "-format=json"}, &stdout, &bytes.Buffer{})
+
+	assert.Equal(t, 1, code)
+	report := canboat.ValidationReport{}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, 3, report.Errors)
+	assert.Equal(t, canboat.ValidationIssue{
+		Severity:   canboat.SeverityError,
+		Code:       canboat.IssueUnknownLookup,
+		PGN:        127250,
+		PGNID:      "vesselHeading",
+		FieldID:    "reference",
+		FieldOrder: 3,
+		Message:    "unknown lookup enumeration UNKNOWN_LOOKUP",
+	}, report.Issues[1])
+}
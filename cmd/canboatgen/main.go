@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client/canboat"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// canboatgen generates Go source with typed constants for PGN numbers, field IDs and lookup enumerations from Canboat
+// schema. Meant to be used with go:generate, for example:
+//
+//	//go:generate go run github.com/aldas/go-nmea-client/cmd/canboatgen -pgns=canboat.json -filter=127250,129029 -package=main -output=pgns_gen.go
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("canboatgen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pgnsPath := flags.String("pgns", "", "path to Canboat pgns.json file")
+	pgnFilter := flags.String("filter", "", "comma separated list of PGNs to generate constants for (with lookup enumerations their fields use). Defaults to all PGNs")
+	packageName := flags.String("package", "main", "package name for generated Go source")
+	outputPath := flags.String("output", "", "file where generated source is written (defaults to STDOUT)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *pgnsPath == "" {
+		fmt.Fprintln(stderr, "path to Canboat pgns.json is required")
+		return 2
+	}
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load schema: %v\n", err)
+		return 1
+	}
+	if *pgnFilter != "" {
+		pgns, err := parsePGNs(*pgnFilter)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid pgn filter given, %v\n", err)
+			return 2
+		}
+		schema = schema.Subset(pgns...)
+	}
+
+	out := bytes.Buffer{}
+	if err := canboat.WriteCANBoatConstantsGoSource(&out, schema, *packageName); err != nil {
+		fmt.Fprintf(stderr, "failed to generate source: %v\n", err)
+		return 1
+	}
+	if *outputPath == "" {
+		_, err = stdout.Write(out.Bytes())
+	} else {
+		err = os.WriteFile(*outputPath, out.Bytes(), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to write source: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func parsePGNs(raw string) ([]uint32, error) {
+	result := make([]uint32, 0)
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, uint32(v))
+	}
+	return result, nil
+}
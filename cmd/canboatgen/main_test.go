@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+
+	code := run([]string{"-pgns=../../canboat/testdata/canboat.json", "-filter=127250", "-package=n2k"}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stderr.String())
+	assert.Contains(t, stdout.String(), "package n2k\n")
+	assert.Contains(t, stdout.String(), "PGNVesselHeading = uint32(127250)")
+	assert.Contains(t, stdout.String(), `FieldVesselHeadingHeading   = "heading"`)
+}
+
+func TestRun_errors(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenArgs     []string
+		expectCode   int
+		expectStderr string
+	}{
+		{
+			name:         "nok, missing path",
+			whenArgs:     []string{},
+			expectCode:   2,
+			expectStderr: "path to Canboat pgns.json is required\n",
+		},
+		{
+			name:         "nok, invalid filter",
+			whenArgs:     []string{"-pgns=../../canboat/testdata/canboat.json", "-filter=abc"},
+			expectCode:   2,
+			expectStderr: "invalid pgn filter given, strconv.ParseUint: parsing \"abc\": invalid syntax\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stderr := bytes.Buffer{}
+
+			code := run(tc.whenArgs, &bytes.Buffer{}, &stderr)
+
+			assert.Equal(t, tc.expectCode, code)
+			assert.Equal(t, tc.expectStderr, stderr.String())
+		})
+	}
+}
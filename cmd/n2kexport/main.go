@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/export"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("n2kexport", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	inputPath := flags.String("input", "", "path to recorded capture file")
+	inputFormat := flags.String("input-format", "canboat-raw", "in which format capture file is (ngt, n2k-bin, n2k-ascii, n2k-raw-ascii, canboat-raw, ebl)")
+	pgnsPath := flags.String("pgns", "", "path to Canboat pgns.json file")
+	outputFormat := flags.String("output-format", "json", "in which format decoded messages are written (json, csv)")
+	outputPath := flags.String("output", "", "JSON output file (defaults to STDOUT) or directory for CSV files (defaults to current directory)")
+	mappingPath := flags.String("mapping", "", "path to JSON file with CSV mappings. `[{\"pgn\":129025,\"fields\":[\"_time_ms\",\"latitude\",\"longitude\"]}]`")
+	pgnFilter := flags.String("filter", "", "comma separated list of PGNs to export")
+	workers := flags.Int("workers", 0, "number of decoding workers (defaults to number of CPUs)")
+	progressInterval := flags.Duration("progress", 5*time.Second, "interval for printing progress to STDERR (0 disables)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *inputPath == "" {
+		fmt.Fprintln(stderr, "input file is required")
+		return 2
+	}
+	if *pgnsPath == "" {
+		fmt.Fprintln(stderr, "path to Canboat pgns.json is required")
+		return 2
+	}
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load schema: %v\n", err)
+		return 1
+	}
+	decoder := canboat.NewDecoder(schema)
+
+	var writer export.Writer
+	var pgns []uint32
+	switch *outputFormat {
+	case "json":
+		out := stdout
+		if *outputPath != "" {
+			f, err := os.Create(*outputPath)
+			if err != nil {
+				fmt.Fprintf(stderr, "failed to create output file: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		}
+		writer = export.NewJSONWriter(out)
+	case "csv":
+		if *mappingPath == "" {
+			fmt.Fprintln(stderr, "CSV output requires mapping file")
+			return 2
+		}
+		b, err := os.ReadFile(*mappingPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read mapping file: %v\n", err)
+			return 1
+		}
+		var mappings []export.CSVMapping
+		if err := json.Unmarshal(b, &mappings); err != nil {
+			fmt.Fprintf(stderr, "invalid mapping file given, %v\n", err)
+			return 2
+		}
+		for _, m := range mappings {
+			pgns = append(pgns, m.PGN)
+		}
+		dir := *outputPath
+		if dir == "" {
+			dir = "."
+		}
+		writer = export.NewCSVWriter(dir, mappings)
+	default:
+		fmt.Fprintln(stderr, "unknown output format type given")
+		return 2
+	}
+
+	if *pgnFilter != "" {
+		pgns = pgns[:0]
+		for _, p := range strings.Split(*pgnFilter, ",") {
+			pgn, err := strconv.ParseUint(strings.TrimSpace(p), 10, 32)
+			if err != nil {
+				fmt.Fprintf(stderr, "invalid pgn filter given, %v\n", err)
+				return 2
+			}
+			pgns = append(pgns, uint32(pgn))
+		}
+	}
+
+	file, err := os.Open(*inputPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to open input file: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to stat input file: %v\n", err)
+		return 1
+	}
+	input := export.NewCountingReader(file)
+
+	fastPacketAssembler := nmea.NewFastPacketAssembler(schema.PGNs.FastPacketPGNs())
+	config := actisense.Config{
+		ReceiveDataTimeout:  100 * time.Millisecond,
+		FastPacketAssembler: nmea.NewISOTPAssembler(fastPacketAssembler),
+	}
+	var reader nmea.RawMessageReader
+	switch *inputFormat {
+	case "canboat-raw":
+		reader = canboat.NewCanBoatReader(input)
+	case "ebl":
+		reader = actisense.NewEBLFormatDeviceWithConfig(input, config)
+	case "ngt", "n2k-bin":
+		reader = actisense.NewBinaryDeviceWithConfig(input, config)
+	case "n2k-ascii":
+		reader = actisense.NewN2kASCIIDevice(input, config)
+	case "n2k-raw-ascii":
+		reader = actisense.NewRawASCIIDevice(input, config)
+	default:
+		fmt.Fprintln(stderr, "unknown input format type given")
+		return 2
+	}
+
+	exportConfig := export.Config{
+		Decoder:    decoder,
+		Writers:    []export.Writer{writer},
+		Workers:    *workers,
+		BytesRead:  input.Count,
+		TotalBytes: fi.Size(),
+		OnProgress: func(p export.Progress) {
+			fmt.Fprintf(stderr, "# Progress: %.1f%%, messages: %v, written: %v, read errors: %v, decode errors: %v, elapsed: %v\n",
+				p.Percent(), p.Messages, p.Written, p.ReadErrors, p.DecodeErrors, p.Elapsed.Round(time.Millisecond))
+		},
+	}
+	if *progressInterval > 0 {
+		exportConfig.ProgressInterval = *progressInterval
+	} else {
+		exportConfig.ProgressInterval = 24 * time.Hour // only final progress is printed
+	}
+	if len(pgns) > 0 {
+		exportConfig.Filter = func(raw nmea.RawMessage) bool {
+			for _, pgn := range pgns {
+				if raw.Header.PGN == pgn {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	if _, err := export.Export(ctx, reader, exportConfig); err != nil {
+		fmt.Fprintf(stderr, "export failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_rawASCIIFastPacket(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.json")
+	stderr := bytes.Buffer{}
+
+	code := run(context.Background(), []string{
+		"-input=../../actisense/testdata/actisense_rawascii_20221028_10s.txt",
+		"-input-format=n2k-raw-ascii",
+		"-pgns=../../canboat/testdata/canboat.json",
+		"-filter=129029,130845",
+		"-output=" + output,
+		"-progress=0",
+	}, &bytes.Buffer{}, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr.String(), "written: 38, read errors: 0, decode errors: 0")
+
+	raw, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	b := string(raw)
+	lines := strings.Split(strings.TrimSpace(b), "\n")
+	assert.Len(t, lines, 38)
+	assert.Equal(t, 23, strings.Count(b, `"pgn":129029`))
+	assert.Equal(t, 15, strings.Count(b, `"pgn":130845`))
+}
+
+func TestRun_errors(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenArgs     []string
+		expectCode   int
+		expectStderr string
+	}{
+		{
+			name:         "nok, missing input",
+			whenArgs:     []string{},
+			expectCode:   2,
+			expectStderr: "input file is required\n",
+		},
+		{
+			name:         "nok, missing pgns path",
+			whenArgs:     []string{"-input=../../actisense/testdata/actisense_rawascii_20221028_10s.txt"},
+			expectCode:   2,
+			expectStderr: "path to Canboat pgns.json is required\n",
+		},
+		{
+			name: "nok, unknown input format",
+			whenArgs: []string{
+				"-input=../../actisense/testdata/actisense_rawascii_20221028_10s.txt",
+				"-pgns=../../canboat/testdata/canboat.json",
+				"-input-format=xxx",
+			},
+			expectCode:   2,
+			expectStderr: "unknown input format type given\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stderr := bytes.Buffer{}
+
+			code := run(context.Background(), tc.whenArgs, &bytes.Buffer{}, &stderr)
+
+			assert.Equal(t, tc.expectCode, code)
+			assert.Equal(t, tc.expectStderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/bridge"
+	"github.com/aldas/go-nmea-client/digitalyacht"
+	"github.com/aldas/go-nmea-client/serialport"
+	"github.com/aldas/go-nmea-client/socketcan"
+	"github.com/aldas/go-nmea-client/yachtdevices"
+	"io"
+	"strings"
+	"time"
+)
+
+// openBridgeDevice opens device messages are bridged to. Address is SocketCAN interface name (with `socketcan` format),
+// `tcp://host:port` or `tcps://host:port` (TLS) address of network gateway (i.e. Actisense W2K-1) or path to serial
+// device.
+func openBridgeDevice(ctx context.Context, addr string, format string, baudRate int, fastPacketPGNs []uint32) (nmea.RawMessageReaderWriter, error) {
+	fastPacketAssembler := nmea.NewISOTPAssembler(nmea.NewFastPacketAssembler(fastPacketPGNs))
+	if format == "socketcan" {
+		return socketcan.NewDevice(socketcan.DeviceConfig{
+			InterfaceName:       addr,
+			FastPacketAssembler: fastPacketAssembler,
+		}), nil
+	}
+
+	var conn io.ReadWriteCloser
+	var err error
+	if strings.HasPrefix(addr, "tcp://") || strings.HasPrefix(addr, "tcps://") {
+		var dialConfig nmea.DialConfig
+		if dialConfig, err = nmea.ParseDialURL(addr); err == nil {
+			conn, err = nmea.Dial(ctx, dialConfig)
+		}
+	} else {
+		conn, err = serialport.Open(nmea.SerialConfig{Name: addr, Baud: baudRate})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := actisense.Config{ReceiveDataTimeout: 5 * time.Second}
+	switch format {
+	case "n2k-ascii":
+		return actisense.NewN2kASCIIDevice(conn, config), nil
+	case "ngt", "n2k-bin":
+		return actisense.NewBinaryDeviceWithConfig(conn, config), nil
+	case "ydwg":
+		return yachtdevices.NewRawDevice(conn, yachtdevices.Config{FastPacketAssembler: fastPacketAssembler}), nil
+	case "ikonvert":
+		return digitalyacht.NewIKonvertDevice(conn, digitalyacht.Config{}), nil
+	}
+	conn.Close()
+	return nil, fmt.Errorf("unsupported bridge format: %v", format)
+}
+
+// parseBridgeFilter parses `-bridge-allow` and `-bridge-deny` values (`pgn` or `pgn:source` items) to bridge filter.
+func parseBridgeFilter(allow string, deny string) (bridge.Filter, error) {
+	var result bridge.Filter
+	var err error
+	if result.Allow, err = parseBridgeRules(allow); err != nil {
+		return bridge.Filter{}, err
+	}
+	if result.Deny, err = parseBridgeRules(deny); err != nil {
+		return bridge.Filter{}, err
+	}
+	return result, nil
+}
+
+func parseBridgeRules(s string) ([]bridge.Rule, error) {
+	if s == "" {
+		return nil, nil
+	}
+	filters, err := parseMsgFilters(s)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]bridge.Rule, 0, len(filters))
+	for _, f := range filters {
+		rule := bridge.Rule{PGNs: []uint32{f.PGN}}
+		if f.HasSource {
+			rule.Sources = []uint8{f.Source}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
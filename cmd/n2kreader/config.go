@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+// applyConfigFile sets flag values from YAML (or JSON) config file. Keys are flag names. Nested maps are flattened by
+// joining keys with `-` (i.e. `output: {format: json}` sets `-output-format`) and lists are joined with `,`. Flags
+// given on command line override config file values.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	values, err := parseConfig(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	setOnCommandLine := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown option in config file: %v", name)
+		}
+		if setOnCommandLine[name] {
+			continue
+		}
+		if err := fs.Set(name, values[name]); err != nil {
+			return fmt.Errorf("invalid value for config file option %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func parseConfig(content []byte) (map[string]string, error) {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	if err := flattenConfig("", raw, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func flattenConfig(prefix string, value interface{}, result map[string]string) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			name := key
+			if prefix != "" {
+				name = prefix + "-" + key
+			}
+			if err := flattenConfig(name, item, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("option %v list can contain only values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		result[prefix] = strings.Join(items, ",")
+	case nil:
+		result[prefix] = ""
+	default:
+		result[prefix] = fmt.Sprint(v)
+	}
+	if prefix == "" {
+		return fmt.Errorf("config file must contain map of options")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenArgs   []string
+		givenConfig string
+		expect      map[string]string
+		expectError string
+	}{
+		{
+			name:      "ok, nested keys, lists and command line overrides",
+			givenArgs: []string{"-output-format", "canboat"},
+			givenConfig: `
+device: /dev/ttyUSB1
+input-format: n2k-ascii
+filter: [127250, 129029]
+dam: true
+output:
+  format: json
+  rotate: 24h
+`,
+			expect: map[string]string{
+				"device":        "/dev/ttyUSB1",
+				"input-format":  "n2k-ascii",
+				"filter":        "127250,129029",
+				"dam":           "true",
+				"output-format": "canboat",
+				"output-rotate": "24h0m0s",
+			},
+		},
+		{
+			name:        "ok, JSON",
+			givenConfig: `{"device": "tcp://192.168.1.20:60002", "speed": 0.5}`,
+			expect: map[string]string{
+				"device":        "tcp://192.168.1.20:60002",
+				"input-format":  "ngt",
+				"output-format": "json",
+				"speed":         "0.5",
+			},
+		},
+		{
+			name:        "nok, unknown option",
+			givenConfig: "output:\n  colour: red\n",
+			expectError: "unknown option in config file: output-colour",
+		},
+		{
+			name:        "nok, invalid value",
+			givenConfig: "output-rotate: 2 days\n",
+			expectError: `invalid value for config file option output-rotate: parse error`,
+		},
+		{
+			name:        "nok, not map",
+			givenConfig: "- device\n",
+			expectError: "failed to parse config file: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into map[string]interface {}",
+		},
+		{
+			name:        "nok, list of maps",
+			givenConfig: "filter:\n  - pgn: 1\n",
+			expectError: "failed to parse config file: option filter list can contain only values",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.String("device", "/dev/ttyUSB0", "")
+			fs.String("input-format", "ngt", "")
+			fs.String("filter", "", "")
+			fs.Bool("dam", false, "")
+			fs.String("output-format", "json", "")
+			fs.Duration("output-rotate", 0, "")
+			fs.Float64("speed", 0, "")
+			assert.NoError(t, fs.Parse(tc.givenArgs))
+
+			path := filepath.Join(t.TempDir(), "n2kreader.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(tc.givenConfig), 0o600))
+
+			err := applyConfigFile(fs, path)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			for name, value := range tc.expect {
+				assert.Equal(t, value, fs.Lookup(name).Value.String(), name)
+			}
+		})
+	}
+}
+
+func TestApplyConfigFile_missingFile(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	err := applyConfigFile(fs, filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
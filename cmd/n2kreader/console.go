@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/addressmapper"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/capability"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const consoleHelp = `# Commands:
+#   !help                                  - prints this help
+#   !nodes, !nodes-details                 - lists known nodes
+#   !addr-claim                            - sends broadcast request for ISO Address Claim
+#   !capabilities                          - prints JSON matrix of PGNs that each source has sent within last minute
+#   !gateway                               - prints gateway info and health
+#   !req <dst> <pgn>[,<pgn>...]            - sends ISO request for PGNs to node (255 is broadcast)
+#   !send <pgn>[:<dst>] [field=value ...]  - composes message from field values using schema and sends it
+#   !pgns [text]                           - lists known PGNs (filtered by PGN, ID or description)
+#   !fields <pgn>                          - lists fields of PGN that can be used with !send
+#   <prio>,<pgn>,<src>,<dst>,<len>,<data>  - sends raw message in Canboat plain format
+`
+
+// consoleSource is source address messages composed by console are sent with
+const consoleSource = 0
+
+// console handles lines written to STDIN. Lines starting with `!` are commands, all other lines are raw messages in
+// Canboat plain format.
+type console struct {
+	out           io.Writer
+	writer        nmea.RawMessageWriter
+	addressMapper *addressmapper.AddressMapper
+	capabilities  *capability.Tracker
+	// gateway is nil when device does not report gateway info (not Actisense NGT-1)
+	gateway gatewayInfoDevice
+	// health is nil when health monitoring is not enabled (-health)
+	health *actisense.HealthMonitor
+
+	// pgns and encoder are nil when schema is not loaded (-raw-only)
+	pgns    canboat.PGNs
+	encoder *canboat.Encoder
+}
+
+func (c *console) handleLine(ctx context.Context, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if !strings.HasPrefix(line, "!") {
+		msg, err := parseLine(line)
+		if err != nil {
+			fmt.Fprintf(c.out, "%v\n", err)
+			return
+		}
+		c.write(ctx, msg)
+		return
+	}
+
+	args := splitConsoleArgs(line)
+	var err error
+	switch args[0] {
+	case "!help":
+		fmt.Fprint(c.out, consoleHelp)
+	case "!nodes", "!nodes-details":
+		err = c.printNodes(args[0] == "!nodes-details")
+	case "!addr-claim":
+		if c.addressMapper == nil {
+			err = errors.New("address mapper is not enabled")
+			break
+		}
+		c.addressMapper.BroadcastIsoAddressClaimRequest()
+	case "!gateway":
+		c.printGateway()
+	case "!capabilities":
+		err = c.printCapabilities()
+	case "!req":
+		err = c.sendRequest(ctx, args[1:])
+	case "!send":
+		err = c.sendFields(ctx, args[1:])
+	case "!pgns":
+		err = c.printPGNs(strings.Join(args[1:], " "))
+	case "!fields":
+		err = c.printFields(args[1:])
+	default:
+		err = fmt.Errorf("unknown command %v, send !help to list commands", args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(c.out, "# Error: %v\n", err)
+	}
+}
+
+func (c *console) write(ctx context.Context, msg nmea.RawMessage) {
+	if err := c.writer.WriteRawMessage(ctx, msg); err != nil {
+		fmt.Fprintf(c.out, "# Error at writing: %v\n", err)
+	}
+}
+
+func (c *console) printNodes(isDetailed bool) error {
+	if c.addressMapper == nil {
+		return errors.New("address mapper is not enabled")
+	}
+	nodes := c.addressMapper.Nodes()
+	sort.Sort(nodesBySrc(nodes))
+
+	fmt.Fprintf(c.out, "# Known nodes: %v\n", len(nodes))
+	for _, n := range nodes {
+		if isDetailed {
+			fmt.Fprintf(c.out, "# node: NAME: %v, source: %v, NAME: %+v\n", n.NAME, n.Source, n.Name)
+			if n.ValidTransmitPGNs || n.ValidReceivePGNs {
+				fmt.Fprintf(c.out, "#   transmit PGNs: %v, receive PGNs: %v\n", n.TransmitPGNs, n.ReceivePGNs)
+			}
+		} else {
+			fmt.Fprintf(c.out, "# node: NAME: %v, source: %v\n", n.NAME, n.Source)
+		}
+	}
+	return nil
+}
+
+func (c *console) printGateway() {
+	if c.health != nil {
+		b, _ := json.Marshal(c.health.Health())
+		fmt.Fprintf(c.out, "# Monitored health: %s\n", b)
+	}
+	gw := c.gateway
+	if gw == nil {
+		fmt.Fprintf(c.out, "# Device does not support gateway info\n")
+		return
+	}
+	if info, ok := gw.DeviceInfo(); ok {
+		fmt.Fprintf(c.out, "# Gateway: model ID: %v, serial: %v, firmware: %v, error code: %v (updated at %v)\n",
+			info.ModelID, info.SerialID, info.FirmwareVersion, info.ErrorCode, info.UpdatedAt)
+	}
+	if health, ok := gw.GatewayHealth(); ok {
+		b, _ := json.Marshal(health)
+		fmt.Fprintf(c.out, "# Gateway health: %s\n", b)
+	}
+	if err := gw.RequestDeviceInfo(); err != nil {
+		fmt.Fprintf(c.out, "# Error requesting gateway info: %v\n", err)
+	}
+}
+
+func (c *console) printCapabilities() error {
+	b, err := json.Marshal(c.capabilities.Matrix())
+	if err != nil {
+		return fmt.Errorf("marshalling capabilities failed: %w", err)
+	}
+	fmt.Fprintf(c.out, "# Capabilities: %s\n", b)
+	return nil
+}
+
+// sendRequest sends ISO request (PGN 59904) for each given PGN. Example: `!req 35 126996,126998` requests product
+// and configuration information from node with source address 35.
+func (c *console) sendRequest(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errors.New("invalid arguments, usage: !req <dst> <pgn>[,<pgn>...]")
+	}
+	dst, err := strconv.ParseUint(args[0], 10, 8)
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+	pgns, err := string2intSlice[uint32](args[1])
+	if err != nil {
+		return fmt.Errorf("invalid PGN: %w", err)
+	}
+	for _, pgn := range pgns {
+		c.write(ctx, nmea.RawMessage{
+			Header: nmea.CanBusHeader{
+				PGN:         uint32(nmea.PGNISORequest),
+				Priority:    6,
+				Source:      consoleSource,
+				Destination: uint8(dst),
+			},
+			Data: []byte{uint8(pgn), uint8(pgn >> 8), uint8(pgn >> 16)},
+		})
+	}
+	fmt.Fprintf(c.out, "# Sent ISO request for PGNs %v to %v\n", pgns, dst)
+	return nil
+}
+
+// sendFields composes message from field values and sends it. Example: `!send 127245 instance=0 rudderOrder=0.1`.
+// Fields that are not given are sent as "no data".
+func (c *console) sendFields(ctx context.Context, args []string) error {
+	if c.encoder == nil {
+		return errors.New("schema is not loaded")
+	}
+	if len(args) == 0 {
+		return errors.New("invalid arguments, usage: !send <pgn>[:<dst>] [field=value ...]")
+	}
+	pgnPart, dstPart, hasDst := strings.Cut(args[0], ":")
+	pgn, err := strconv.ParseUint(pgnPart, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid PGN: %w", err)
+	}
+	dst := uint64(nmea.AddressGlobal)
+	if hasDst {
+		if dst, err = strconv.ParseUint(dstPart, 10, 8); err != nil {
+			return fmt.Errorf("invalid destination: %w", err)
+		}
+	}
+
+	fields := make(nmea.FieldValues, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		id, value, ok := strings.Cut(arg, "=")
+		if !ok || id == "" {
+			return fmt.Errorf("invalid field value %q, expected: field=value", arg)
+		}
+		if !c.hasField(uint32(pgn), id) {
+			return fmt.Errorf("PGN %v has no field %v, send !fields %v to list fields", pgn, id, pgn)
+		}
+		fields = append(fields, nmea.FieldValue{ID: id, Value: parseConsoleValue(value)})
+	}
+
+	msg, err := c.encoder.Encode(nmea.Message{
+		Header: nmea.CanBusHeader{
+			PGN:         uint32(pgn),
+			Priority:    6,
+			Source:      consoleSource,
+			Destination: uint8(dst),
+		},
+		Fields: fields,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "# Sending: %s\n", canboat.MarshalPlain(msg))
+	c.write(ctx, msg)
+	return nil
+}
+
+func (c *console) hasField(pgn uint32, id string) bool {
+	for _, p := range c.pgns {
+		if p.PGN != pgn {
+			continue
+		}
+		for _, f := range p.Fields {
+			if f.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseConsoleValue converts field value given in console to type Encoder understands. Numbers are converted to
+// int64 or float64, `hh:mm:ss` to time of day and quoted values are unquoted. Everything else (i.e. lookup names) is
+// used as string.
+func parseConsoleValue(value string) interface{} {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if strings.Count(value, ":") == 2 {
+		if t, err := nmea.ParseTimeOfDay(value); err == nil {
+			return t
+		}
+	}
+	if s, err := strconv.Unquote(value); err == nil {
+		return s
+	}
+	return value
+}
+
+func (c *console) printPGNs(filter string) error {
+	if c.pgns == nil {
+		return errors.New("schema is not loaded")
+	}
+	filter = strings.ToLower(filter)
+	count := 0
+	for _, p := range c.pgns {
+		pgn := strconv.FormatUint(uint64(p.PGN), 10)
+		if filter != "" && pgn != filter &&
+			!strings.Contains(strings.ToLower(p.ID), filter) &&
+			!strings.Contains(strings.ToLower(p.Description), filter) {
+			continue
+		}
+		fmt.Fprintf(c.out, "# %v %v - %v\n", pgn, p.ID, p.Description)
+		count++
+	}
+	fmt.Fprintf(c.out, "# Listed PGNs: %v\n", count)
+	return nil
+}
+
+func (c *console) printFields(args []string) error {
+	if c.pgns == nil {
+		return errors.New("schema is not loaded")
+	}
+	if len(args) != 1 {
+		return errors.New("invalid arguments, usage: !fields <pgn>")
+	}
+	pgn, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid PGN: %w", err)
+	}
+	found := false
+	for _, p := range c.pgns {
+		if p.PGN != uint32(pgn) {
+			continue
+		}
+		found = true
+		fmt.Fprintf(c.out, "# %v %v - %v\n", p.PGN, p.ID, p.Description)
+		for _, f := range p.Fields {
+			if f.FieldType == canboat.FieldTypeReserved || f.FieldType == canboat.FieldTypeSpare {
+				continue
+			}
+			fmt.Fprintf(c.out, "#   %v: %v", f.ID, f.FieldType)
+			if f.Unit != "" {
+				fmt.Fprintf(c.out, ", unit: %v", f.Unit)
+			}
+			if f.Match != 0 {
+				fmt.Fprintf(c.out, ", match: %v", f.Match)
+			}
+			if lookup := f.LookupEnumeration + f.LookupBitEnumeration + f.LookupIndirectEnumeration; lookup != "" {
+				fmt.Fprintf(c.out, ", lookup: %v", lookup)
+			}
+			fmt.Fprintf(c.out, "\n")
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown PGN: %v", pgn)
+	}
+	return nil
+}
+
+// splitConsoleArgs splits line to space separated arguments. Spaces inside double quotes do not split arguments.
+func splitConsoleArgs(line string) []string {
+	var args []string
+	var sb strings.Builder
+	inQuotes := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ' ' && !inQuotes:
+			if sb.Len() > 0 {
+				args = append(args, sb.String())
+				sb.Reset()
+			}
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() > 0 {
+		args = append(args, sb.String())
+	}
+	return args
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConsole_handleLine(t *testing.T) {
+	schema, err := canboat.LoadCANBoatSchemaFile("../../canboat/testdata/canboat.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var testCases = []struct {
+		name          string
+		when          string
+		expect        string
+		expectWritten []nmea.RawMessage
+	}{
+		{
+			name: "ok, raw message",
+			when: "6,59904,0,255,3,14,f0,01",
+			expectWritten: []nmea.RawMessage{
+				{
+					Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0, Destination: 255},
+					Data:   []byte{0x14, 0xf0, 0x01},
+				},
+			},
+		},
+		{
+			name:   "nok, invalid raw message",
+			when:   "6,59904,0",
+			expect: "# Error invalid input format, err: canboat input has fewer components than expected\n",
+		},
+		{
+			name:   "ok, ISO request",
+			when:   "!req 35 126996,126998",
+			expect: "# Sent ISO request for PGNs [126996 126998] to 35\n",
+			expectWritten: []nmea.RawMessage{
+				{
+					Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0, Destination: 35},
+					Data:   []byte{0x14, 0xf0, 0x01},
+				},
+				{
+					Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0, Destination: 35},
+					Data:   []byte{0x16, 0xf0, 0x01},
+				},
+			},
+		},
+		{
+			name:   "nok, ISO request with invalid destination",
+			when:   "!req 256 126996",
+			expect: "# Error: invalid destination: strconv.ParseUint: parsing \"256\": value out of range\n",
+		},
+		{
+			name:   "ok, send composed message",
+			when:   "!send 127250:35 heading=1.5",
+			expect: "# Sending: 6,127250,0,35,8,00,98,3a,03,ff,ff,ff,ff\n",
+			expectWritten: []nmea.RawMessage{
+				{
+					Header: nmea.CanBusHeader{PGN: 127250, Priority: 6, Source: 0, Destination: 35},
+					Data:   []byte{0x00, 0x98, 0x3a, 0x03, 0xff, 0xff, 0xff, 0xff},
+				},
+			},
+		},
+		{
+			name:   "nok, send unknown field",
+			when:   "!send 127250 xxx=1",
+			expect: "# Error: PGN 127250 has no field xxx, send !fields 127250 to list fields\n",
+		},
+		{
+			name:   "nok, send invalid field value",
+			when:   "!send 127250 heading",
+			expect: "# Error: invalid field value \"heading\", expected: field=value\n",
+		},
+		{
+			name:   "ok, list PGNs",
+			when:   "!pgns vessel heading",
+			expect: "# 127250 vesselHeading - Vessel Heading\n# Listed PGNs: 1\n",
+		},
+		{
+			name: "ok, list fields",
+			when: "!fields 127250",
+			expect: "# 127250 vesselHeading - Vessel Heading\n" +
+				"#   heading: NUMBER, unit: rad\n" +
+				"#   reference: LOOKUP, lookup: DIRECTION_REFERENCE\n",
+		},
+		{
+			name:   "nok, send unknown PGN",
+			when:   "!send 1",
+			expect: "# Error: encode failed, unknown PGN given\n",
+		},
+		{
+			name:   "nok, unknown command",
+			when:   "!xxx",
+			expect: "# Error: unknown command !xxx, send !help to list commands\n",
+		},
+		{
+			name:   "nok, nodes without address mapper",
+			when:   "!nodes",
+			expect: "# Error: address mapper is not enabled\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			device := nmea.NewMockDevice(nmea.MockDeviceConfig{})
+			c := &console{
+				out:     out,
+				writer:  device,
+				pgns:    schema.PGNs,
+				encoder: canboat.NewEncoder(schema),
+			}
+
+			c.handleLine(context.Background(), tc.when)
+
+			assert.Equal(t, tc.expect, out.String())
+			assert.Equal(t, tc.expectWritten, device.Written())
+		})
+	}
+}
+
+func TestSplitConsoleArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"!send", "126208", `name="My boat"`, "x=1"},
+		splitConsoleArgs(`!send  126208 name="My boat" x=1`),
+	)
+}
+
+func TestConsole_printGateway(t *testing.T) {
+	health := actisense.NewHealthMonitor(actisense.HealthThresholds{}, nil)
+	health.SetSupplyVoltage(12.5, time.Unix(1665488842, 0).UTC())
+
+	out := new(bytes.Buffer)
+	c := &console{
+		out:    out,
+		writer: nmea.NewMockDevice(nmea.MockDeviceConfig{}),
+		health: health,
+	}
+
+	c.handleLine(context.Background(), "!gateway")
+
+	assert.Equal(t,
+		"# Monitored health: {\"updated_at\":\"2022-10-11T11:47:22Z\",\"error_code\":0,\"channels\":null,\"supply_voltage\":12.5}\n"+
+			"# Device does not support gateway info\n",
+		out.String(),
+	)
+}
@@ -14,15 +14,32 @@ import (
 	"github.com/aldas/go-nmea-client"
 	"github.com/aldas/go-nmea-client/actisense"
 	"github.com/aldas/go-nmea-client/addressmapper"
+	"github.com/aldas/go-nmea-client/bridge"
+	"github.com/aldas/go-nmea-client/calibration"
 	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/capability"
+	"github.com/aldas/go-nmea-client/digitalyacht"
+	"github.com/aldas/go-nmea-client/export"
+	"github.com/aldas/go-nmea-client/gateway"
+	"github.com/aldas/go-nmea-client/influx"
+	"github.com/aldas/go-nmea-client/metrics"
+	"github.com/aldas/go-nmea-client/mqtt"
+	"github.com/aldas/go-nmea-client/pcan"
+	"github.com/aldas/go-nmea-client/pipeline"
+	"github.com/aldas/go-nmea-client/serialport"
+	"github.com/aldas/go-nmea-client/signalk"
+	"github.com/aldas/go-nmea-client/sink"
 	"github.com/aldas/go-nmea-client/socketcan"
-	"github.com/tarm/serial"
+	"github.com/aldas/go-nmea-client/udp"
+	"github.com/aldas/go-nmea-client/yachtdevices"
 	"io"
 	"io/fs"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,22 +52,80 @@ var canboatDB embed.FS
 
 func main() {
 	printRaw := flag.Bool("raw", false, "prints raw message")
+	writeInterval := flag.Duration("write-interval", nmea.DefaultFrameInterval, "minimum time between CAN frames written to device. Writes from address mapper, console, gateway and MQTT clients are queued and written in order of message priority")
 	onlyRead := flag.Bool("read-only", false, "only reads device/file and does not write into it")
 	onlyRaw := flag.Bool("raw-only", false, "prints only raw message (does not parse to pgn)")
 	noShowPNG := flag.Bool("np", false, "do not print parsed PNGs")
 	noAddressMapper := flag.Bool("dam", false, "disable address mapper")
 	isFile := flag.Bool("is-file", false, "consider device as ordinary file")
-	inputFormat := flag.String("input-format", "ngt", "in which format packet are read (ngt, n2k-bin, n2k-ascii, n2k-raw-ascii, canboat-raw, ebl)")
+	inputFormat := flag.String("input-format", "ngt", "in which format packet are read (ngt, n2k-bin, n2k-ascii, n2k-raw-ascii, canboat-raw, ebl, ydwg, ikonvert, pcan-trc, candump)")
 	deviceAddr := flag.String("device", "/dev/ttyUSB0", "path to Actisense NGT-1 USB device")
 	pgnsPath := flag.String("pgns", "", "path to Canboat pgns.json file")
+	pgnsExtra := flag.String("pgns-extra", "", "comma separated list of additional Canboat pgns.json style files (i.e. proprietary vendor PGNs) merged over -pgns or embedded schema. PGN definitions with same PGN and Id replace existing ones")
+	schemaCheck := flag.String("schema-check", "warn", "what to do when -pgns file is older than embedded schema or has missing lookups (warn, fail, ignore)")
 	sources := flag.String("source", "", "comma separated list of Source addresses to filter")
 	pgnFilter := flag.String("filter", "", "comma separated list of PGNs to filter")
 	csvFieldsRaw := flag.String("csv-fields", "", "list of PGNs and their fields to be written in CSV. `129025:time_ms,latitude,longitude;65280:time_ms,manufacturerCode,industryCode`")
-	outputFormat := flag.String("output-format", "json", "in which format raw and decoded packet should be printed out (json, canboat, hex, base64)")
+	csvDir := flag.String("csv-dir", "", "directory where every decoded PGN is written to its own CSV file with columns derived from schema (no need to list fields with -csv-fields)")
+	csvRotateSize := flag.Int64("csv-rotate-size", 0, "size in bytes after which new CSV file is started for PGN. Used with -csv-dir")
+	csvRotateInterval := flag.Duration("csv-rotate-interval", 0, "time period (i.e. `24h`) after which new CSV file is started for PGN. Used with -csv-dir")
+	outputFormat := flag.String("output-format", "json", "in which format raw and decoded packet should be printed out (json, canboat, hex, base64, signalk, influx)")
+	outputFile := flag.String("output-file", "", "path where decoded messages are logged as newline delimited JSON (i.e. `/var/log/n2k/boat.jsonl.gz`). Files are named `<name>_<start time>.jsonl` and gzip compressed when path ends with .gz")
+	outputRotate := flag.Duration("output-rotate", 0, "time period (i.e. `24h`) after which new -output-file is started")
+	outputRotateSize := flag.Int64("output-rotate-size", 0, "size in (uncompressed) bytes after which new -output-file is started")
 	throttle := flag.Duration("throttle", 0, "Throttle output of messages by PGN into given duration window")
+	replaceLegacy := flag.Bool("replace-legacy", false, "drop legacy PGNs (i.e. 130312 Temperature, J1939 engine PGNs) from source that also sends newer PGN superseding them (130316, 127489)")
+	forceLegacy := flag.String("force-legacy", "", "comma separated list of legacy PGNs that are preferred over their newer replacements. Newer PGN is dropped instead. Used with -replace-legacy")
+	throttleKey := flag.String("throttle-key", "", "comma separated list of field IDs which value is included into throttle key (i.e. `instance,sid`) so multi-instance PGNs are throttled per instance")
 	baudRate := flag.Int("baud", 115200, "device baud rate.")
+	fileTimeMode := flag.String("file-time-mode", "", "how message times are assigned when reading file (anchored, interval, estimate). Defaults to read time")
+	fileTimeStart := flag.String("file-time-start", "", "RFC3339 time assigned to first message read from file. Used with -file-time-mode")
+	fileTimeInterval := flag.Duration("file-time-interval", 10*time.Millisecond, "time between messages read from file. Used with -file-time-mode")
+	recordPath := flag.String("record", "", "path to file where all read raw messages are recorded (regardless of filters)")
+	recordFormat := flag.String("record-format", "canboat", "in which format raw messages are recorded with -record (canboat, ebl). EBL files can be opened with Actisense EBL Reader")
+	calibrationPath := flag.String("calibration", "", "path to JSON file with per source calibration offsets (heading deviation, pitch/roll, depth)")
+	partialMatch := flag.Bool("partial-match", false, "decode messages of PGNs with multiple definitions (proprietary PGNs) that match none of the definitions fully with best partially matching definition. Message gets `partial_pgn_match` warning listing candidates")
+	absentFields := flag.Bool("absent-fields", false, "list fields without value (no data, out of range, reserved, not transmitted) in decoded message")
+	replaySpeed := flag.Float64("speed", 0, "replay file in pace of original message timestamps (EBL, candump, canboat-raw files) multiplied by speed (i.e. 0.5, 1, 10). Used with -is-file. By default file is read as fast as possible")
+	replayLoop := flag.Bool("loop", false, "replay file again from start when end of file is reached. Message times are set to replay time so they do not go backwards. Implies -speed=1 when speed is not set. Used with -is-file")
+	candumpRealtime := flag.Bool("candump-realtime", false, "replay candump log in real time (delays reads by time between logged frames). Used with -input-format=candump")
+	timeOfDay := flag.Bool("time-of-day", false, "decode time of day fields (i.e. PGN 129029 time) as `hh:mm:ss.ffff` time of day instead of duration")
+	lengthValidation := flag.String("length-validation", "", "how messages with data length different from PGN definition are handled (permissive, report, strict). `report` adds `data_too_short`/`data_too_long` warning to message, `strict` fails decoding. Defaults to permissive")
+	rawBits := flag.Bool("raw-bits", false, "include bit offset, bit length and data bytes of each field in decoded message")
+	units := flag.String("units", "", "in which units decoded field values are output and annotated with (si, display). Display units are degrees, Celsius, knots and bars. Defaults to SI units without annotation")
+	socketcanFilter := flag.Bool("socketcan-filter", false, "apply -filter and -source as SocketCAN kernel filters so other frames do not reach n2k-reader (address mapper does not see them either). Used with -input-format=socketcan")
+	socketcanFD := flag.Bool("socketcan-fd", false, "open SocketCAN interface in CAN-FD mode (frames up to 64 bytes). Used with -input-format=socketcan")
+	socketcanErrors := flag.Bool("socketcan-errors", false, "print CAN bus error frames (error counters, error passive, bus-off). Used with -input-format=socketcan")
+	mirrorTo := flag.String("mirror-to", "", "SocketCAN interface (i.e. vcan0) where all frames read from socketcan device are retransmitted to")
+	bridgeAddr := flag.String("bridge", "", "second device (i.e. `can1`, `tcp://192.168.1.20:60002`) where all messages are forwarded to and from which messages are forwarded to -device, turning n2k-reader into software NMEA2000 gateway/filter")
+	bridgeFormat := flag.String("bridge-format", "n2k-ascii", "format of -bridge device (socketcan, n2k-ascii, ngt, n2k-bin, ydwg, ikonvert)")
+	bridgeAllow := flag.String("bridge-allow", "", "comma separated list of PGNs (`pgn` or `pgn:source`) that are forwarded between -device and -bridge. Defaults to all PGNs")
+	bridgeDeny := flag.String("bridge-deny", "", "comma separated list of PGNs (`pgn` or `pgn:source`) that are never forwarded between -device and -bridge")
+	listen := flag.String("listen", "", "comma separated list of addresses where all read messages are served to network clients (i.e. `tcp://:2000,udp://192.168.1.255:2001`). TCP clients can write messages to bus (unless -read-only), UDP only sends")
+	listenFormat := flag.String("listen-format", "canboat", "in which format messages are served to and read from -listen clients (canboat, n2k-ascii, hex, json). json sends decoded messages and does not accept writes")
+	mqttAddr := flag.String("mqtt", "", "MQTT broker address (i.e. `tcp://localhost:1883`) where decoded messages are published to `<prefix>/<src>/<pgn>` topics")
+	mqttPrefix := flag.String("mqtt-prefix", mqtt.DefaultTopicPrefix, "first level of topics decoded messages are published to. Used with -mqtt")
+	mqttPerField := flag.Bool("mqtt-per-field", false, "publish each field value to its own `<prefix>/<src>/<pgn>/<field id>` topic instead of whole message. Used with -mqtt")
+	mqttCommandTopic := flag.String("mqtt-command-topic", "", "MQTT topic where raw messages in Canboat format are received and written to bus (unless -read-only). Used with -mqtt")
+	influxURL := flag.String("influx-url", "", "InfluxDB v2 server address (i.e. `http://localhost:8086`) where decoded messages are written in line protocol")
+	influxOrg := flag.String("influx-org", "", "InfluxDB organization. Used with -influx-url")
+	influxBucket := flag.String("influx-bucket", "nmea", "InfluxDB bucket where messages are written to. Used with -influx-url")
+	influxToken := flag.String("influx-token", "", "InfluxDB API token. Defaults to INFLUX_TOKEN environment variable value. Used with -influx-url")
+	metricsAddr := flag.String("metrics-addr", "", "address where Prometheus metrics are served at /metrics path and per PGN statistics as JSON at /stats path (i.e. `:9100`)")
+	healthAlerts := flag.Bool("health", false, "monitor gateway health (Actisense NGT-1 system status channel load, dropped messages and error code) and bus supply voltage (PGN 127751 DC Voltage/Current) and print alerts when thresholds are crossed")
+	healthMinVoltage := flag.Float64("health-min-voltage", 10.5, "bus supply voltage below which alert is raised. Used with -health")
+	statsInterval := flag.Duration("stats", 0, "interval at which table of message counts, rates, last seen times and decode errors per PGN and source is printed (i.e. `10s`). Table is also printed at exit")
+	readBuffer := flag.Int("read-buffer", 0, "number of messages buffered between device and processing. Device is read in separate goroutine so slow processing does not stall reading. 0 disables buffering")
+	readBufferDrop := flag.String("read-buffer-drop", "oldest", "which message is discarded when -read-buffer is full (oldest, newest, priority, block). priority discards messages with lowest priority first, block stops reading until there is room")
+	configPath := flag.String("config", "", "path to YAML (or JSON) file with options. Keys are flag names (nested keys are joined with `-`, lists with `,`). Flags given on command line override file values")
 	flag.Parse()
 
+	if *configPath != "" {
+		if err := applyConfigFile(flag.CommandLine, *configPath); err != nil {
+			log.Fatalf("# %v\n", err)
+		}
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
@@ -58,8 +133,52 @@ func main() {
 		log.Fatal("# missing device path\n")
 	}
 
-	var decoder *canboat.Decoder
+	var metricsCollector *metrics.Prometheus
+	var collectors metrics.Collectors
+	var stats *metrics.Stats
+	if *statsInterval > 0 || *metricsAddr != "" {
+		stats = metrics.NewStats()
+		collectors = append(collectors, stats)
+	}
+	if *statsInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(*statsInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					stats.WriteTable(os.Stdout)
+				}
+			}
+		}()
+		defer stats.WriteTable(os.Stdout)
+	}
+	if *metricsAddr != "" {
+		metricsCollector = metrics.NewPrometheus()
+		collectors = append(collectors, metricsCollector)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsCollector)
+		mux.Handle("/stats", stats)
+		metricsServer := &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+		defer metricsServer.Close()
+		fmt.Printf("# Serving Prometheus metrics at: %v/metrics\n", *metricsAddr)
+	}
+
+	var decoder nmea.MessageDecoder
+	var analyzerJSON *canboat.AnalyzerJSONMarshaller
 	var fastPacketPGNs []uint32
+	var transmissionIntervals map[uint32]time.Duration
+	var throttleKeyFields map[uint32]pipeline.ThrottleKeyField
+	var autoCSV *export.AutoCSVWriter
+	var schemaPGNs canboat.PGNs
+	var encoder *canboat.Encoder
 	if !*onlyRaw {
 		var canboatDBFS fs.FS
 		var canboatDBPath string
@@ -75,10 +194,108 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		fmt.Printf("# Parsed %v known PGN definitions\n", len(schema.PGNs))
+		fmt.Printf("# Parsed %v known PGN definitions (schema version: %v)\n", len(schema.PGNs), schema.Version)
+
+		if pgnsPath != nil && *pgnsPath != "" {
+			var checkMode canboat.SchemaCheckMode
+			switch *schemaCheck {
+			case "warn":
+				checkMode = canboat.SchemaCheckWarn
+			case "fail":
+				checkMode = canboat.SchemaCheckFail
+			case "ignore":
+				checkMode = canboat.SchemaCheckIgnore
+			default:
+				log.Fatal("unknown schema check mode given\n")
+			}
+			embedded, err := canboat.LoadCANBoatSchema(canboatDB, "canboat.json")
+			if err != nil {
+				log.Fatal(err)
+			}
+			warnings, err := canboat.CheckSchema(schema, embedded.Version, checkMode)
+			for _, w := range warnings {
+				fmt.Printf("# Schema warning: %v\n", w.Message)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *pgnsExtra != "" {
+			extra, err := canboat.LoadCANBoatSchemaFiles(strings.Split(*pgnsExtra, ",")...)
+			if err != nil {
+				log.Fatal(err)
+			}
+			schema = schema.Merge(extra)
+			fmt.Printf("# Merged %v additional PGN definitions from: %v\n", len(extra.PGNs), *pgnsExtra)
+		}
 
-		decoder = canboat.NewDecoder(schema)
+		var unitSystem canboat.UnitSystem
+		switch *units {
+		case "":
+		case "si":
+			unitSystem = canboat.UnitSystemSI
+		case "display":
+			unitSystem = canboat.UnitSystemDisplay
+			if *outputFormat == "signalk" || *calibrationPath != "" {
+				log.Fatal("display units can not be used with signalk output format or calibration as they expect SI units\n")
+			}
+		default:
+			log.Fatal("unknown units given\n")
+		}
+		var lengthMode canboat.LengthValidationMode
+		switch *lengthValidation {
+		case "", "permissive":
+		case "report":
+			lengthMode = canboat.LengthValidationReport
+		case "strict":
+			lengthMode = canboat.LengthValidationStrict
+		default:
+			log.Fatal("unknown length validation mode given\n")
+		}
+		decoder = canboat.NewDecoderWithConfig(schema, canboat.DecoderConfig{
+			DecodeAbsentFields:   *absentFields,
+			PartialMatchFallback: *partialMatch,
+			IncludeRawBits:       *rawBits,
+			DecodeTimeOfDay:      *timeOfDay,
+			Units:                unitSystem,
+			LengthValidation:     lengthMode,
+		})
+		analyzerJSON = canboat.NewAnalyzerJSONMarshaller(schema)
+		if *calibrationPath != "" {
+			b, err := os.ReadFile(*calibrationPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			var calibrationConfig calibration.Config
+			if err := json.Unmarshal(b, &calibrationConfig); err != nil {
+				log.Fatalf("invalid calibration file given, %v\n", err)
+			}
+			decoder = calibration.NewDecoder(decoder, calibration.NewCalibrator(calibrationConfig))
+			fmt.Printf("# Using calibration for %v sources\n", len(calibrationConfig.Sources))
+		}
+		if len(collectors) > 0 {
+			decoder = metrics.NewDecoder(decoder, collectors)
+		}
+		if *csvDir != "" {
+			if err := os.MkdirAll(*csvDir, 0o755); err != nil {
+				log.Fatal(err)
+			}
+			autoCSV = export.NewAutoCSVWriter(export.AutoCSVConfig{
+				Dir:            *csvDir,
+				Schema:         schema,
+				MaxFileSize:    *csvRotateSize,
+				RotateInterval: *csvRotateInterval,
+			})
+			defer autoCSV.Close()
+			fmt.Printf("# Writing CSV files to: %v\n", *csvDir)
+		}
+		schemaPGNs = schema.PGNs
+		encoder = canboat.NewEncoder(schema)
 		fastPacketPGNs = schema.PGNs.FastPacketPGNs()
+		transmissionIntervals = schema.PGNs.TransmissionIntervals()
+		if *throttleKey != "" {
+			throttleKeyFields = pipeline.ThrottleKeyFields(schema, strings.Split(*throttleKey, ",")...)
+		}
 	}
 
 	var err error
@@ -98,6 +315,13 @@ func main() {
 		}
 		fmt.Printf("# Using Source address filter: %v\n", filter)
 	}
+	var forceLegacyPGNs []uint32
+	if *forceLegacy != "" {
+		forceLegacyPGNs, err = string2intSlice[uint32](*forceLegacy)
+		if err != nil {
+			log.Fatalf("invalid force legacy PGNs given, %v\n", err)
+		}
+	}
 
 	var csvFields csvPGNs
 	isCSV := false
@@ -117,23 +341,75 @@ func main() {
 
 	switch *outputFormat {
 	case "json", "canboat", "hex", "base64":
+	case "signalk", "influx":
+		if *onlyRaw {
+			log.Fatalf("%v output format can not be used with -raw-only\n", *outputFormat)
+		}
 	default:
 		log.Fatal("unknown output format type given\n")
 	}
+	if *onlyRaw && (*csvDir != "" || *outputFile != "" || *mqttAddr != "" || *influxURL != "") {
+		log.Fatal("-csv-dir, -output-file, -mqtt and -influx-url can not be used with -raw-only\n")
+	}
+	var messageLog *sink.JSONLWriter
+	if *outputFile != "" {
+		messageLog = sink.NewJSONLWriter(outputFileConfig(*outputFile, *outputRotate, *outputRotateSize))
+		defer messageLog.Close()
+		fmt.Printf("# Logging decoded messages to: %v\n", *outputFile)
+	}
+	var influxWriter *influx.HTTPWriter
+	if *influxURL != "" {
+		token := *influxToken
+		if token == "" {
+			token = os.Getenv("INFLUX_TOKEN")
+		}
+		influxWriter, err = influx.NewHTTPWriter(influx.HTTPConfig{
+			URL:    *influxURL,
+			Org:    *influxOrg,
+			Bucket: *influxBucket,
+			Token:  token,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer influxWriter.Close()
+		fmt.Printf("# Writing decoded messages to InfluxDB: %v\n", *influxURL)
+	}
 
 	switch *inputFormat {
-	case "ngt", "n2k-bin", "n2k-ascii", "n2k-raw-ascii", "ebl", "canboat-raw", "socketcan":
+	case "ngt", "n2k-bin", "n2k-ascii", "n2k-raw-ascii", "ebl", "canboat-raw", "socketcan", "ydwg", "ikonvert", "pcan-trc", "candump":
 	default:
 		log.Fatal("unknown input format type given\n")
 	}
 
+	udpFormat, isUDPReader := udpReaderFormat(*deviceAddr, *inputFormat)
+
 	var reader io.ReadWriteCloser
 	if *isFile {
 		reader, err = os.OpenFile(*deviceAddr, os.O_RDONLY, 0)
-	} else if strings.HasPrefix(*deviceAddr, "tcp://") {
-		var dialer net.Dialer
-		addr := strings.TrimPrefix(*deviceAddr, "tcp://")
-		reader, err = dialer.DialContext(ctx, "tcp", addr)
+	} else if strings.HasPrefix(*deviceAddr, "tcp://") || strings.HasPrefix(*deviceAddr, "tcps://") {
+		var dialConfig nmea.DialConfig
+		dialConfig, err = nmea.ParseDialURL(*deviceAddr)
+		if err == nil {
+			reader, err = nmea.Dial(ctx, dialConfig)
+		}
+		if err == nil {
+			go func() {
+				<-ctx.Done()
+				reader.Close()
+			}()
+		}
+	} else if isUDPReader {
+		// datagrams are read and parsed by udp.Reader
+	} else if strings.HasPrefix(*deviceAddr, "udp://") {
+		// `udp://192.168.4.1:1456` listens on port 1456 and sends to gateway, `udp://:1456` only listens
+		addr := strings.TrimPrefix(*deviceAddr, "udp://")
+		gatewayAddr := ""
+		if !strings.HasPrefix(addr, ":") {
+			gatewayAddr = addr
+		}
+		_, port, _ := net.SplitHostPort(addr)
+		reader, err = yachtdevices.ListenUDP(":"+port, gatewayAddr)
 		go func() {
 			<-ctx.Done()
 			reader.Close()
@@ -142,14 +418,7 @@ func main() {
 		switch *inputFormat {
 		case "socketcan":
 		default:
-			reader, err = serial.OpenPort(&serial.Config{
-				Name: *deviceAddr,
-				Baud: *baudRate,
-				// ReadTimeout is duration that Read call is allowed to block. Device has different timeout for situation when
-				// there is no activity on bus. Can not be smaller than 100ms
-				ReadTimeout: 100 * time.Millisecond,
-				Size:        8,
-			})
+			reader, err = serialport.Open(nmea.SerialConfig{Name: *deviceAddr, Baud: *baudRate})
 		}
 	}
 	if err != nil {
@@ -169,24 +438,222 @@ func main() {
 	if *isFile {
 		config.ReceiveDataTimeout = 100 * time.Millisecond
 	}
+	var healthMonitor *actisense.HealthMonitor
+	if *healthAlerts {
+		healthMonitor = actisense.NewHealthMonitor(
+			actisense.HealthThresholds{MinSupplyVoltage: *healthMinVoltage},
+			func(alert actisense.HealthAlert) {
+				b, _ := json.Marshal(alert)
+				fmt.Printf("# Health alert: %s\n", b)
+			},
+		)
+		config.HealthMonitor = healthMonitor
+	}
 
-	var device nmea.RawMessageReaderWriter
-	switch *inputFormat {
-	case "socketcan":
-		device = socketcan.NewDevice(socketcan.DeviceConfig{
-			InterfaceName:       *deviceAddr,
-			FastPacketAssembler: nmea.NewFastPacketAssembler(fastPacketPGNs),
+	fastPacketAssembler := nmea.NewFastPacketAssembler(fastPacketPGNs)
+	if metricsCollector != nil {
+		metricsCollector.RegisterFastPacketAssembler(fastPacketAssembler)
+	}
+	deviceType := *inputFormat
+	if isUDPReader {
+		deviceType = "udp"
+	}
+	createDevice := func(reader io.ReadWriteCloser) nmea.RawMessageReaderWriter {
+		switch deviceType {
+		case "udp":
+			return udp.NewReader(udp.Config{
+				Address:                 strings.TrimPrefix(*deviceAddr, "udp://"),
+				Format:                  udpFormat,
+				FastPacketAssembler:     nmea.NewISOTPAssembler(fastPacketAssembler),
+				DebugLogRawMessageBytes: *printRaw,
+				LogFunc:                 config.LogFunc,
+			})
+		case "socketcan":
+			var filters []socketcan.Filter
+			if *socketcanFilter {
+				filters = socketcanFilters(filter, sourceAllowFilter)
+				fmt.Printf("# Using %v SocketCAN kernel filters\n", len(filters))
+			}
+			return socketcan.NewDevice(socketcan.DeviceConfig{
+				InterfaceName:       *deviceAddr,
+				FastPacketAssembler: nmea.NewISOTPAssembler(fastPacketAssembler),
+				Filters:             filters,
+				CANFD:               *socketcanFD,
+				OnErrorFrame:        socketcanErrorPrinter(*socketcanErrors),
+			})
+		case "canboat-raw":
+			return canboat.NewCanBoatReader(reader)
+		case "ebl":
+			return actisense.NewEBLFormatDeviceWithConfig(reader, config)
+		case "ngt", "n2k-bin":
+			return actisense.NewBinaryDeviceWithConfig(reader, config)
+		case "n2k-ascii":
+			return actisense.NewN2kASCIIDevice(reader, config)
+		case "n2k-raw-ascii":
+			return actisense.NewRawASCIIDevice(reader, config)
+		case "candump":
+			return socketcan.NewCandumpReader(reader, socketcan.CandumpConfig{
+				FastPacketAssembler: nmea.NewISOTPAssembler(fastPacketAssembler),
+				Realtime:            *candumpRealtime,
+			})
+		case "pcan-trc":
+			return pcan.NewTRCReader(reader, pcan.TRCConfig{
+				FastPacketAssembler: nmea.NewISOTPAssembler(fastPacketAssembler),
+			})
+		case "ydwg":
+			return yachtdevices.NewRawDevice(reader, yachtdevices.Config{
+				DebugLogRawMessageBytes: *printRaw,
+				LogFunc:                 config.LogFunc,
+				FastPacketAssembler:     nmea.NewISOTPAssembler(fastPacketAssembler),
+			})
+		case "ikonvert":
+			return digitalyacht.NewIKonvertDevice(reader, digitalyacht.Config{
+				DebugLogRawMessageBytes: *printRaw,
+				LogFunc:                 config.LogFunc,
+			})
+		}
+		return nil
+	}
+	device := createDevice(reader)
+	gatewayDevice, _ := device.(gatewayInfoDevice)
+
+	if *bridgeAddr != "" {
+		if *isFile || isUDPReader {
+			log.Fatal("bridge can not be used with file or UDP broadcast input\n")
+		}
+		bridgeFilter, err := parseBridgeFilter(*bridgeAllow, *bridgeDeny)
+		if err != nil {
+			log.Fatalf("invalid bridge filter given, %v\n", err)
+		}
+		bridgeDevice, err := openBridgeDevice(ctx, *bridgeAddr, *bridgeFormat, *baudRate, fastPacketPGNs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gw := bridge.NewBridge(device, bridgeDevice, bridge.Config{
+			AToB: bridgeFilter,
+			BToA: bridgeFilter,
+			OnError: func(direction bridge.Direction, msg nmea.RawMessage, err error) {
+				fmt.Printf("# Error forwarding message %v: %v\n", direction, err)
+			},
 		})
-	case "canboat-raw":
-		device = canboat.NewCanBoatReader(reader)
-	case "ebl":
-		device = actisense.NewEBLFormatDeviceWithConfig(reader, config)
-	case "ngt", "n2k-bin":
-		device = actisense.NewBinaryDeviceWithConfig(reader, config)
-	case "n2k-ascii":
-		device = actisense.NewN2kASCIIDevice(reader, config)
-	case "n2k-raw-ascii":
-		device = actisense.NewRawASCIIDevice(reader, config)
+		defer func() {
+			gw.Close()
+			fmt.Printf("# Bridge stats: %+v\n", gw.Stats())
+		}()
+		device = gw
+		fmt.Printf("# Bridging messages between %v and %v\n", *deviceAddr, *bridgeAddr)
+	}
+
+	var timeConfig *nmea.SyntheticTimeConfig
+	if *isFile && *fileTimeMode != "" {
+		timeConfig = &nmea.SyntheticTimeConfig{
+			Interval:           *fileTimeInterval,
+			ReferenceIntervals: transmissionIntervals,
+		}
+		switch *fileTimeMode {
+		case "anchored":
+			timeConfig.Mode = nmea.SyntheticTimeAnchored
+		case "interval":
+			timeConfig.Mode = nmea.SyntheticTimeFixedInterval
+		case "estimate":
+			timeConfig.Mode = nmea.SyntheticTimeRateEstimate
+		default:
+			log.Fatal("unknown file time mode given\n")
+		}
+		if *fileTimeStart != "" {
+			timeConfig.StartTime, err = time.Parse(time.RFC3339Nano, *fileTimeStart)
+			if err != nil {
+				log.Fatalf("invalid file time start given, %v\n", err)
+			}
+		}
+	}
+	withSyntheticTime := func(r nmea.RawMessageReader) nmea.RawMessageReader {
+		if timeConfig == nil {
+			return r
+		}
+		return nmea.NewSyntheticTimeReader(r, *timeConfig)
+	}
+
+	messageReader := withSyntheticTime(device)
+	// replay wraps device before read buffer so that reader reopened for next loop round is read into the same buffer
+	if *isFile && (*replaySpeed > 0 || *replayLoop) {
+		replayConfig := nmea.ReplayConfig{Speed: *replaySpeed, Loop: *replayLoop, ShiftTime: *replayLoop}
+		if replayConfig.Speed <= 0 {
+			replayConfig.Speed = 1
+		}
+		if *replayLoop {
+			replayConfig.Reopen = func() (nmea.RawMessageReader, error) {
+				f, err := os.OpenFile(*deviceAddr, os.O_RDONLY, 0)
+				if err != nil {
+					return nil, err
+				}
+				return withSyntheticTime(createDevice(f)), nil
+			}
+		}
+		messageReader = nmea.NewReplayReader(messageReader, replayConfig)
+		fmt.Printf("# Replaying file with speed: %vx, loop: %v\n", replayConfig.Speed, *replayLoop)
+	}
+	var asyncReader *nmea.AsyncReader
+	if *readBuffer > 0 {
+		dropPolicy, err := parseDropPolicy(*readBufferDrop)
+		if err != nil {
+			log.Fatal(err)
+		}
+		asyncReader = nmea.NewAsyncReaderWithConfig(messageReader, nmea.AsyncReaderConfig{
+			Size:       *readBuffer,
+			DropPolicy: dropPolicy,
+		})
+		messageReader = asyncReader
+	}
+	if len(collectors) > 0 {
+		messageReader = metrics.NewReader(messageReader, collectors)
+	}
+
+	if *recordPath != "" {
+		recordFile, err := os.OpenFile(*recordPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer recordFile.Close()
+		var recorder nmea.RawMessageWriter
+		switch *recordFormat {
+		case "canboat":
+			recorder = canboat.NewCanBoatWriter(recordFile)
+		case "ebl":
+			recorder = actisense.NewEBLWriter(recordFile)
+		default:
+			log.Fatal("unknown record format given\n")
+		}
+		messageReader = nmea.NewTeeWithConfig(messageReader, nmea.TeeConfig{
+			Outputs: []nmea.TeeOutput{{Writer: recorder}},
+			OnError: func(output int, msg nmea.RawMessage, err error) {
+				fmt.Printf("# Error recording raw message: %v\n", err)
+			},
+		})
+		fmt.Printf("# Recording raw messages to: %v\n", *recordPath)
+	}
+
+	if *mirrorTo != "" {
+		if *inputFormat != "socketcan" {
+			log.Fatal("mirroring is supported only for socketcan input format\n")
+		}
+		mirror := socketcan.NewMirror(socketcan.MirrorConfig{
+			SourceInterface:      *deviceAddr,
+			DestinationInterface: *mirrorTo,
+		})
+		if err := mirror.Initialize(); err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			mirror.Close()
+			fmt.Printf("# Mirror stats: %+v\n", mirror.Stats())
+		}()
+		fmt.Printf("# Starting to mirror frames from %v to %v\n", *deviceAddr, *mirrorTo)
+		go func(ctx context.Context) {
+			if err := mirror.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				fmt.Printf("# Mirror ended with error: %v\n", err)
+			}
+		}(ctx)
 	}
 
 	if !*isFile {
@@ -198,10 +665,30 @@ func main() {
 	}
 	fmt.Printf("# Starting to read device: %v\n", *deviceAddr)
 
+	// all writes to device go through scheduler so bus is not flooded and high priority messages are written first
+	writeScheduler := nmea.NewWriteScheduler(device, nmea.WriteSchedulerConfig{
+		FrameInterval: *writeInterval,
+		OnError: func(msg nmea.RawMessage, err error) {
+			fmt.Printf("# Error writing message to device, PGN: %v, err: %v\n", msg.Header.PGN, err)
+		},
+	})
+	go func(ctx context.Context) {
+		if err := writeScheduler.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Printf("# Write scheduler ended with error: %v\n", err)
+		}
+	}(ctx)
+	defer func() {
+		if stats := writeScheduler.Stats(); stats.Written > 0 || stats.Overflows > 0 {
+			fmt.Printf("# Write scheduler stats: %+v\n", stats)
+		}
+	}()
+
 	isAddressMapperEnabled := noAddressMapper == nil || !*noAddressMapper
 	var addressMapper *addressmapper.AddressMapper
 	if isAddressMapperEnabled {
-		addressMapper = addressmapper.NewAddressMapper(device)
+		addressMapper = addressmapper.NewAddressMapperWithConfig(writeScheduler, addressmapper.Config{
+			Logger: nmea.LogFuncLogger(config.LogFunc),
+		})
 		fmt.Printf("# Starting address mapper process\n")
 		go func(ctx context.Context, am *addressmapper.AddressMapper) {
 			if err := am.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
@@ -225,62 +712,95 @@ func main() {
 		}
 	}
 
+	if *listen != "" {
+		var busWriter nmea.RawMessageWriter
+		if !*onlyRead && !*isFile {
+			busWriter = writeScheduler
+		}
+		gatewayServer, err := startGateway(ctx, *listen, *listenFormat, decoder, busWriter)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer gatewayServer.Close()
+		messageReader = nmea.NewTee(messageReader, nmea.TeeOutput{Writer: &gatewayPublisher{server: gatewayServer}})
+	}
+
+	var mqttPublisher *mqtt.Publisher
+	if *mqttAddr != "" {
+		var busWriter nmea.RawMessageWriter
+		if !*onlyRead && !*isFile {
+			busWriter = writeScheduler
+		}
+		mqttPublisher, err = startMQTT(ctx, *mqttAddr, *mqttCommandTopic, busWriter, mqtt.PublisherConfig{
+			Prefix: *mqttPrefix,
+			Mode:   mqttTopicMode(*mqttPerField),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// messages read from file have recorded (past) times so their activity is measured against the latest message
+	capabilities := capability.NewTrackerWithConfig(capability.Config{UseMessageTime: *isFile})
 	if onlyRead != nil && !*onlyRead && !*isFile {
 		fmt.Printf("# Starting STDIN process\n")
-		go handleSTDIO(ctx, device, addressMapper)
+		go handleSTDIO(ctx, &console{
+			out:           os.Stdout,
+			writer:        writeScheduler,
+			addressMapper: addressMapper,
+			capabilities:  capabilities,
+			gateway:       gatewayDevice,
+			health:        healthMonitor,
+			pgns:          schemaPGNs,
+			encoder:       encoder,
+		})
 	}
 
-	throttled := map[uint64]time.Time{}
 	msgCount := uint64(0)
 	errorCountDecode := uint64(0)
 	errorCountRead := uint64(0)
 	nodesBySource := map[uint8]addressmapper.Node{}
-	for {
-		rawMessage, err := device.ReadRawMessage(ctx)
-		msgCount++
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
-			errorCountRead++
-			if errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed) {
-				return
-			}
-			fmt.Printf("# Error ReadRawMessage: %v\n", err)
-			if errorCountRead > 20 {
-				return
-			}
-			continue
-		}
-		errorCountRead = 0
+	isNodeChanged := false
+	nodeNAME := uint64(0)
+	signalkConverter := signalk.NewConverter(signalk.Config{})
 
-		isNodeChanged := false
-		if isAddressMapperEnabled {
-			isNodeChanged, err = addressMapper.Process(rawMessage)
-			if err != nil {
-				fmt.Printf("# Error at addressMapper processing: %v\n", err)
+	stages := []pipeline.Handler{
+		pipeline.RawHandlerFunc(func(ctx context.Context, rawMessage nmea.RawMessage) (bool, error) {
+			capabilities.Process(rawMessage)
+
+			isNodeChanged = false
+			if isAddressMapperEnabled {
+				var err error
+				isNodeChanged, err = addressMapper.Process(rawMessage)
+				if err != nil {
+					fmt.Printf("# Error at addressMapper processing: %v\n", err)
+				}
+				if isNodeChanged {
+					nodesBySource = addressMapper.NodesInUseBySource()
+					for src, n := range nodesBySource {
+						capabilities.SetNodeNAME(src, n.NAME)
+					}
+				}
 			}
-			if isNodeChanged {
-				nodesBySource = addressMapper.NodesInUseBySource()
+			return true, nil
+		}),
+		pipeline.NewFilter(func(rawMessage nmea.RawMessage) bool {
+			if sourceAllowFilter != nil && !contains(sourceAllowFilter, rawMessage.Header.Source) {
+				return false
 			}
-		}
-
-		if sourceAllowFilter != nil && !contains(sourceAllowFilter, rawMessage.Header.Source) {
-			continue
-		}
-		if !filter.matches(rawMessage.Header) {
-			continue
-		}
-
-		var nodeNAME uint64
-		if node, ok := nodesBySource[rawMessage.Header.Source]; ok {
-			nodeNAME = node.NAME
-			if isNodeChanged {
-				fmt.Printf("# New or changed Node: %+v\n", node)
+			return filter.matches(rawMessage.Header)
+		}),
+		pipeline.RawHandlerFunc(func(ctx context.Context, rawMessage nmea.RawMessage) (bool, error) {
+			nodeNAME = 0
+			if node, ok := nodesBySource[rawMessage.Header.Source]; ok {
+				nodeNAME = node.NAME
+				if isNodeChanged {
+					fmt.Printf("# New or changed Node: %+v\n", node)
+				}
+			}
+			if !*onlyRaw {
+				return true, nil
 			}
-		}
-
-		if *onlyRaw {
 			var b []byte
 			switch *outputFormat {
 			case "json":
@@ -293,33 +813,23 @@ func main() {
 				b = []byte(base64.StdEncoding.EncodeToString(nmea.MarshalRawMessage(rawMessage)))
 			}
 			fmt.Printf("%s\n", b)
-			continue
-		}
-
-		if throttle != nil && *throttle > 0 {
-			tKey := uint64(rawMessage.Header.PGN)<<2 | uint64(rawMessage.Header.Source)
-			lastTime, ok := throttled[tKey]
-			if ok && !rawMessage.Time.After(lastTime) {
-				continue
-			}
-			throttled[tKey] = rawMessage.Time.Add(*throttle)
-		}
-
-		decoded, err := decoder.Decode(rawMessage)
-		if err != nil {
-			errorCountDecode++
-			var b []byte
-			switch *outputFormat {
-			case "json":
-				b, _ = json.Marshal(rawMessage)
-			case "canboat":
-				b, _ = canboat.MarshalRawMessage(rawMessage)
-			}
-			fmt.Printf("# unknown PGN: %v NodeNAME: %v (msgCount: %v, errCount: %v)\n", rawMessage.Header.PGN, nodeNAME, msgCount, errorCountDecode)
-			fmt.Printf("%s\n", b)
-			continue
-		}
-
+			return false, nil
+		}),
+	}
+	if healthMonitor != nil {
+		// health is checked before filters so supply voltage and gateway status messages are seen regardless of them
+		stages = append([]pipeline.Handler{healthMonitor}, stages...)
+	}
+	if *replaceLegacy {
+		stages = append(stages, pipeline.NewReplacementFilter(nmea.ReplacementFilterConfig{ForceLegacy: forceLegacyPGNs}))
+	}
+	if throttle != nil && *throttle > 0 {
+		stages = append(stages, pipeline.NewThrottleFilterWithConfig(pipeline.ThrottleConfig{
+			Window:    *throttle,
+			KeyFields: throttleKeyFields,
+		}))
+	}
+	stages = append(stages, pipeline.DecodedHandlerFunc(func(ctx context.Context, decoded nmea.Message, rawMessage nmea.RawMessage) (bool, error) {
 		decoded.NodeNAME = nodeNAME
 		if isCSV {
 			if fields, cpgn, ok := csvFields.Match(decoded, rawMessage.Time); ok {
@@ -328,108 +838,346 @@ func main() {
 				}
 			}
 		}
+		if autoCSV != nil {
+			if err := autoCSV.WriteMessage(rawMessage, decoded); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if messageLog != nil {
+			if err := messageLog.WriteMessage(rawMessage, decoded); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if mqttPublisher != nil {
+			if err := mqttPublisher.WriteMessage(rawMessage, decoded); err != nil {
+				fmt.Printf("# Failed to publish to MQTT: %v\n", err)
+			}
+		}
+		if influxWriter != nil {
+			if err := influxWriter.WriteMessage(rawMessage, decoded); err != nil {
+				fmt.Printf("# Failed to write to InfluxDB: %v\n", err)
+			}
+		}
 
 		if *noShowPNG {
-			continue
+			return false, nil
 		}
 		var b []byte
+		var err error
 		switch *outputFormat {
 		case "json":
 			b, err = json.Marshal(decoded)
 		case "canboat":
-			b, err = canboat.MarshalRawMessage(rawMessage) // FIXME: as raw and not as canboat json
+			b, err = analyzerJSON.Marshal(decoded, rawMessage)
 		case "hex":
 			b = marshalRawHexString(rawMessage, nodeNAME)
+		case "signalk":
+			delta, ok := signalkConverter.Convert(decoded, rawMessage.Time)
+			if !ok {
+				return true, nil
+			}
+			b, err = json.Marshal(delta)
+		case "influx":
+			line := influx.AppendLine(nil, rawMessage, decoded)
+			if len(line) == 0 {
+				return true, nil
+			}
+			b = line[:len(line)-1]
 		}
 		if err != nil {
 			log.Fatal(err)
 		}
 		fmt.Printf("%s\n", b)
+		return true, nil
+	}))
+
+	processor := pipeline.New(pipeline.Config{
+		Decoder:  decoder,
+		Handlers: stages,
+		OnDecodeError: func(ctx context.Context, rawMessage nmea.RawMessage, err error) error {
+			errorCountDecode++
+			var b []byte
+			switch *outputFormat {
+			case "json":
+				b, _ = json.Marshal(rawMessage)
+			case "canboat":
+				b, _ = canboat.MarshalRawMessage(rawMessage)
+			}
+			fmt.Printf("# unknown PGN: %v NodeNAME: %v (msgCount: %v, errCount: %v)\n", rawMessage.Header.PGN, nodeNAME, msgCount, errorCountDecode)
+			fmt.Printf("%s\n", b)
+			return nil
+		},
+	})
+
+	for {
+		rawMessage, err := messageReader.ReadRawMessage(ctx)
+		msgCount++
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			errorCountRead++
+			if errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			fmt.Printf("# Error ReadRawMessage: %v\n", err)
+			if errorCountRead > 20 {
+				return
+			}
+			continue
+		}
+		errorCountRead = 0
+
+		if err := processor.Process(ctx, rawMessage); err != nil {
+			fmt.Printf("# Error processing message: %v\n", err)
+		}
 	}
 	fmt.Printf("# Finishing, number of processed messages: %v, errors: %v\n", msgCount, errorCountDecode)
+	if asyncReader != nil {
+		stats := asyncReader.Stats()
+		fmt.Printf("# Read buffer, dropped messages: %v, max buffered: %v\n", stats.Dropped, stats.MaxBuffered)
+	}
 }
 
-func handleSTDIO(ctx context.Context, device nmea.RawMessageWriter, addressMapper *addressmapper.AddressMapper) {
+func handleSTDIO(ctx context.Context, c *console) {
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		if strings.HasPrefix(line, "!nodes") && addressMapper != nil {
-			nodes := addressMapper.Nodes()
-			sort.Sort(nodesBySrc(nodes))
+		c.handleLine(ctx, scanner.Text())
+	}
+}
 
-			isDetailed := strings.HasSuffix(line, "-details")
-			fmt.Printf("# Known nodes: %v\n", len(nodes))
-			for _, n := range nodes {
-				if isDetailed {
-					fmt.Printf("# node: NAME: %v, source: %v, NAME: %+v\n", n.NAME, n.Source, n.Name)
-				} else {
-					fmt.Printf("# node: NAME: %v, source: %v\n", n.NAME, n.Source)
-				}
-			}
-			continue
-		} else if strings.HasPrefix(line, "!addr-claim") && addressMapper != nil {
-			addressMapper.BroadcastIsoAddressClaimRequest()
-			continue
+// startGateway starts serving all read messages to network clients on given comma separated list of addresses
+func startGateway(ctx context.Context, addresses string, format string, decoder nmea.MessageDecoder, busWriter nmea.RawMessageWriter) (*gateway.Server, error) {
+	config := gateway.Config{
+		Writer: busWriter,
+		OnError: func(client string, err error) {
+			fmt.Printf("# Gateway client %v error: %v\n", client, err)
+		},
+	}
+	switch format {
+	case "canboat":
+		config.NewWriter = func(conn io.ReadWriter) nmea.RawMessageWriter { return canboat.NewCanBoatWriter(conn) }
+		config.NewReader = func(conn io.ReadWriter) nmea.RawMessageReader { return canboat.NewCanBoatReader(conn) }
+	case "n2k-ascii":
+		config.NewWriter = func(conn io.ReadWriter) nmea.RawMessageWriter {
+			return actisense.NewN2kASCIIDevice(conn, actisense.Config{})
 		}
-		msg, err := parseLine(line)
-		if err != nil {
-			fmt.Printf("%v", err)
-			continue
+		config.NewReader = func(conn io.ReadWriter) nmea.RawMessageReader {
+			return actisense.NewN2kASCIIDevice(conn, actisense.Config{})
 		}
+	case "hex":
+		config.NewWriter = func(conn io.ReadWriter) nmea.RawMessageWriter {
+			return &lineWriter{writer: conn, marshal: func(msg nmea.RawMessage) ([]byte, bool) {
+				return marshalRawHexString(msg, 0), true
+			}}
+		}
+		// clients write messages in same format as STDIN: `prio,pgn,src,dst,len,data...`
+		config.NewReader = func(conn io.ReadWriter) nmea.RawMessageReader {
+			return &lineReader{scanner: bufio.NewScanner(conn), parse: parseLine}
+		}
+	case "json":
+		if decoder == nil {
+			return nil, errors.New("json listen format can not be used with -raw-only")
+		}
+		config.NewWriter = func(conn io.ReadWriter) nmea.RawMessageWriter {
+			return &lineWriter{writer: conn, marshal: func(msg nmea.RawMessage) ([]byte, bool) {
+				decoded, err := decoder.Decode(msg)
+				if err != nil {
+					return nil, false
+				}
+				b, err := json.Marshal(decoded)
+				return b, err == nil
+			}}
+		}
+	default:
+		return nil, errors.New("unknown listen format given")
+	}
 
-		if err = device.WriteRawMessage(ctx, msg); err != nil {
-			fmt.Printf("# Error at writing: %v", err)
+	server := gateway.NewServer(config)
+	for _, addr := range strings.Split(addresses, ",") {
+		switch {
+		case strings.HasPrefix(addr, "tcp://"):
+			var lc net.ListenConfig
+			listener, err := lc.Listen(ctx, "tcp", strings.TrimPrefix(addr, "tcp://"))
+			if err != nil {
+				server.Close()
+				return nil, err
+			}
+			fmt.Printf("# Serving messages to TCP clients at: %v\n", listener.Addr())
+			go func() {
+				if err := server.Serve(ctx, listener); err != nil && !errors.Is(err, context.Canceled) {
+					fmt.Printf("# Gateway listener ended with error: %v\n", err)
+				}
+			}()
+		case strings.HasPrefix(addr, "udp://"):
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "udp", strings.TrimPrefix(addr, "udp://"))
+			if err != nil {
+				server.Close()
+				return nil, err
+			}
+			fmt.Printf("# Sending messages over UDP to: %v\n", conn.RemoteAddr())
+			go func() {
+				if err := server.ServeConn(ctx, conn); err != nil && !errors.Is(err, gateway.ErrServerClosed) {
+					fmt.Printf("# Gateway UDP output ended with error: %v\n", err)
+				}
+			}()
+		default:
+			server.Close()
+			return nil, fmt.Errorf("invalid listen address, must start with tcp:// or udp://, got: %v", addr)
 		}
 	}
+	return server, nil
 }
 
-func parseLine(line string) (nmea.RawMessage, error) {
-	// Canboat format is
-	// prio, pgn, src, dst, len, data...
-	// 6,59904,0,128,3,16,f0,01
-	parts := strings.Split(line, ",")
-	if len(parts) < 6 {
-		return nmea.RawMessage{}, fmt.Errorf("# Error invalid input format")
+// startMQTT connects to MQTT broker and subscribes to command topic when given. Messages received from command topic
+// are written to busWriter, when busWriter is nil command topic can not be used.
+func startMQTT(ctx context.Context, addr string, commandTopic string, busWriter nmea.RawMessageWriter, config mqtt.PublisherConfig) (*mqtt.Publisher, error) {
+	if commandTopic != "" && busWriter == nil {
+		return nil, errors.New("-mqtt-command-topic can not be used with -read-only or -is-file")
 	}
-	msg := nmea.RawMessage{}
-	n, err := parseUint8(parts[0], 0, 7, "priority")
+	client, err := mqtt.Dial(ctx, addr, mqtt.ClientConfig{ClientID: fmt.Sprintf("n2kreader-%d", os.Getpid())})
 	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("# Error parsing priority, err: %v", err)
+		return nil, fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+	if commandTopic != "" {
+		err := mqtt.SubscribeCommands(client, mqtt.CommandConfig{
+			Topic:     commandTopic,
+			NewReader: func(stream io.ReadWriter) nmea.RawMessageReader { return canboat.NewCanBoatReader(stream) },
+			Writer:    busWriter,
+			OnError: func(err error) {
+				fmt.Printf("# MQTT command error: %v\n", err)
+			},
+		})
+		if err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+		fmt.Printf("# Writing messages from MQTT topic to bus: %v\n", commandTopic)
 	}
-	msg.Header.Priority = n
+	go func() {
+		if err := client.Run(ctx); err != nil {
+			fmt.Printf("# MQTT connection ended with error: %v\n", err)
+		}
+	}()
+	fmt.Printf("# Publishing decoded messages to MQTT broker: %v\n", addr)
+	return mqtt.NewPublisher(client, config), nil
+}
 
-	pgn, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("# Error parsing PGN, err: %v", err)
+func parseDropPolicy(name string) (nmea.DropPolicy, error) {
+	switch name {
+	case "oldest":
+		return nmea.DropOldest, nil
+	case "newest":
+		return nmea.DropNewest, nil
+	case "priority":
+		return nmea.DropLowestPriority, nil
+	case "block":
+		return nmea.Block, nil
 	}
-	msg.Header.PGN = uint32(pgn)
+	return 0, fmt.Errorf("unknown read buffer drop policy: %v", name)
+}
 
-	n, err = parseUint8(parts[2], 0, 256, "src")
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("# Error parsing src, err: %v", err)
+func mqttTopicMode(perField bool) mqtt.TopicMode {
+	if perField {
+		return mqtt.TopicPerField
 	}
-	msg.Header.Source = n
+	return mqtt.TopicPerMessage
+}
 
-	n, err = parseUint8(parts[3], 0, 256, "dst")
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("# Error parsing dst, err: %v", err)
+// outputFileConfig creates log file config from path like `/var/log/n2k/boat.jsonl.gz`. Directory, file name prefix,
+// extension and compression are derived from path.
+func outputFileConfig(path string, rotate time.Duration, rotateSize int64) sink.FileConfig {
+	name := filepath.Base(path)
+	compress := strings.HasSuffix(name, ".gz")
+	name = strings.TrimSuffix(name, ".gz")
+	extension := filepath.Ext(name)
+	return sink.FileConfig{
+		Dir:            filepath.Dir(path),
+		Prefix:         strings.TrimSuffix(name, extension),
+		Extension:      extension,
+		Compress:       compress,
+		MaxFileSize:    rotateSize,
+		RotateInterval: rotate,
 	}
-	msg.Header.Destination = n
+}
 
-	dataLen, err := strconv.Atoi(parts[4])
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("# Error parsing data length, err: %v", err)
+// gatewayPublisher publishes messages to gateway clients. Used as nmea.Tee output so all read messages (regardless of
+// filters) are served.
+type gatewayPublisher struct {
+	server *gateway.Server
+}
+
+func (p *gatewayPublisher) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	p.server.Publish(msg)
+	return nil
+}
+
+func (p *gatewayPublisher) Close() error {
+	return nil
+}
+
+// lineWriter writes messages to gateway client one message per line. Messages that marshal skips are not written.
+type lineWriter struct {
+	writer  io.Writer
+	marshal func(msg nmea.RawMessage) ([]byte, bool)
+}
+
+func (w *lineWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	b, ok := w.marshal(msg)
+	if !ok {
+		return nil
 	}
-	data, err := hex.DecodeString(strings.Join(parts[5:], ""))
-	if err != nil {
-		return nmea.RawMessage{}, fmt.Errorf("# Error decoding hex data, err: %v", err)
+	_, err := w.writer.Write(append(b, '\n'))
+	return err
+}
+
+func (w *lineWriter) Close() error {
+	return nil
+}
+
+// lineReader reads messages sent by gateway client one message per line
+type lineReader struct {
+	scanner *bufio.Scanner
+	parse   func(line string) (nmea.RawMessage, error)
+}
+
+func (r *lineReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		return r.parse(line)
 	}
-	msg.Data = data[0:dataLen]
+	if err := r.scanner.Err(); err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return nmea.RawMessage{}, io.EOF
+}
+
+func (r *lineReader) Initialize() error {
+	return nil
+}
+
+func (r *lineReader) Close() error {
+	return nil
+}
+
+type gatewayInfoDevice interface {
+	DeviceInfo() (actisense.DeviceInfo, bool)
+	GatewayHealth() (actisense.GatewayHealth, bool)
+	RequestDeviceInfo() error
+}
 
+func parseLine(line string) (nmea.RawMessage, error) {
+	// Canboat plain format without time column
+	// prio, pgn, src, dst, len, data...
+	// 6,59904,0,128,3,16,f0,01
+	msg, err := canboat.UnmarshalString(line)
+	if err != nil {
+		return nmea.RawMessage{}, fmt.Errorf("# Error invalid input format, err: %w", err)
+	}
 	return msg, nil
 }
 
@@ -454,17 +1202,6 @@ func marshalRawHexString(raw nmea.RawMessage, name uint64) []byte {
 	return buf.Bytes()
 }
 
-func parseUint8(raw string, min int, max int, name string) (uint8, error) {
-	n, err := strconv.Atoi(raw)
-	if err != nil {
-		return 0, fmt.Errorf("# Error failed to parse %v, err: %w", name, err)
-	}
-	if n < min || n > max {
-		return 0, fmt.Errorf("# Error invalid %v", name)
-	}
-	return uint8(n), nil
-}
-
 func string2intSlice[T uint8 | uint32](s string) ([]T, error) {
 	result := make([]T, 0, 10)
 	for _, p := range strings.Split(s, ",") {
@@ -526,6 +1263,55 @@ func parseMsgFilters(s string) (msgFilters, error) {
 	return result, nil
 }
 
+// udpReaderFormat returns datagram format when device is read with udp.Reader. Yacht Devices gateways on unicast or
+// broadcast address are read with yachtdevices.UDPConn as it can also write to gateway.
+func udpReaderFormat(deviceAddr string, inputFormat string) (udp.Format, bool) {
+	if !strings.HasPrefix(deviceAddr, "udp://") {
+		return "", false
+	}
+	addr := strings.TrimPrefix(deviceAddr, "udp://")
+	switch inputFormat {
+	case "n2k-ascii":
+		return udp.FormatN2KASCII, true
+	case "canboat-raw":
+		return udp.FormatCanboatRaw, true
+	case "ydwg":
+		host, _, _ := net.SplitHostPort(addr)
+		if ip := net.ParseIP(host); ip != nil && ip.IsMulticast() {
+			return udp.FormatYDWGRaw, true
+		}
+	}
+	return "", false
+}
+
+func socketcanErrorPrinter(enabled bool) func(frame socketcan.ErrorFrame) {
+	if !enabled {
+		return nil
+	}
+	return func(frame socketcan.ErrorFrame) {
+		fmt.Printf("# %v\n", frame.Error())
+	}
+}
+
+// socketcanFilters converts PGN filter and source allow-list to SocketCAN kernel filters. Both must match, same as
+// with userspace filtering. ISO 11783-3 transport protocol PGNs are allowed so multi-packet messages can be assembled.
+func socketcanFilters(filter msgFilters, sources []uint8) []socketcan.Filter {
+	if len(filter) == 0 {
+		return socketcan.AllowListFilters(nil, sources)
+	}
+	result := socketcan.AllowListFilters([]uint32{uint32(nmea.PGNISOTransportProtocolConnectionManagement), uint32(nmea.PGNISOTransportProtocolDataTransfer)}, sources)
+	for _, f := range filter {
+		if !f.HasSource {
+			result = append(result, socketcan.AllowListFilters([]uint32{f.PGN}, sources)...)
+			continue
+		}
+		if len(sources) == 0 || contains(sources, f.Source) {
+			result = append(result, socketcan.PGNSourceFilter(f.PGN, f.Source))
+		}
+	}
+	return result
+}
+
 func (mf msgFilters) appendPGN(pgn uint32) msgFilters {
 	// in case existing filters already have same PGN with source filter we do not add thing PGN
 	for _, f := range mf {
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/bridge"
+	"github.com/aldas/go-nmea-client/mqtt"
+	"github.com/aldas/go-nmea-client/sink"
+	"github.com/aldas/go-nmea-client/socketcan"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
 	"testing"
 	"time"
 )
@@ -22,3 +30,101 @@ func TestParseLine(t *testing.T) {
 		Data: []byte{0x14, 0xf0, 0x01},
 	}, msg)
 }
+
+func TestLineReader(t *testing.T) {
+	r := &lineReader{
+		scanner: bufio.NewScanner(strings.NewReader("6,59904,0,255,3,14,f0,01\n\ninvalid\n")),
+		parse:   parseLine,
+	}
+
+	msg, err := r.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(59904), msg.Header.PGN)
+
+	_, err = r.ReadRawMessage(context.Background())
+	assert.EqualError(t, err, "# Error invalid input format, err: canboat input has fewer components than expected")
+
+	_, err = r.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestStartGateway_errors(t *testing.T) {
+	_, err := startGateway(context.Background(), "tcp://127.0.0.1:0", "ydwg", nil, nil)
+	assert.EqualError(t, err, "unknown listen format given")
+
+	_, err = startGateway(context.Background(), "tcp://127.0.0.1:0", "json", nil, nil)
+	assert.EqualError(t, err, "json listen format can not be used with -raw-only")
+
+	_, err = startGateway(context.Background(), "127.0.0.1:2000", "canboat", nil, nil)
+	assert.EqualError(t, err, "invalid listen address, must start with tcp:// or udp://, got: 127.0.0.1:2000")
+}
+
+func TestStartMQTT_commandTopicRequiresWriter(t *testing.T) {
+	_, err := startMQTT(context.Background(), "tcp://127.0.0.1:1883", "n2k/command", nil, mqtt.PublisherConfig{})
+	assert.EqualError(t, err, "-mqtt-command-topic can not be used with -read-only or -is-file")
+}
+
+func TestSocketcanFilters(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		filter  msgFilters
+		sources []uint8
+		expect  []socketcan.Filter
+	}{
+		{
+			name:    "ok, only sources",
+			sources: []uint8{24},
+			expect:  []socketcan.Filter{socketcan.SourceFilter(24)},
+		},
+		{
+			name:   "ok, PGNs with and without source",
+			filter: msgFilters{{PGN: 127250}, {PGN: 129025, Source: 3, HasSource: true}},
+			expect: []socketcan.Filter{
+				socketcan.PGNFilter(60416),
+				socketcan.PGNFilter(60160),
+				socketcan.PGNFilter(127250),
+				socketcan.PGNSourceFilter(129025, 3),
+			},
+		},
+		{
+			name:    "ok, PGNs combined with sources",
+			filter:  msgFilters{{PGN: 127250}, {PGN: 129025, Source: 3, HasSource: true}},
+			sources: []uint8{24},
+			expect: []socketcan.Filter{
+				socketcan.PGNSourceFilter(60416, 24),
+				socketcan.PGNSourceFilter(60160, 24),
+				socketcan.PGNSourceFilter(127250, 24),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, socketcanFilters(tc.filter, tc.sources))
+		})
+	}
+}
+
+func TestOutputFileConfig(t *testing.T) {
+	assert.Equal(t, sink.FileConfig{
+		Dir:            "/var/log/n2k",
+		Prefix:         "boat",
+		Extension:      ".jsonl",
+		Compress:       true,
+		MaxFileSize:    1000,
+		RotateInterval: time.Hour,
+	}, outputFileConfig("/var/log/n2k/boat.jsonl.gz", time.Hour, 1000))
+
+	assert.Equal(t, sink.FileConfig{Dir: ".", Prefix: "log", Extension: ".json"}, outputFileConfig("log.json", 0, 0))
+}
+
+func TestParseBridgeFilter(t *testing.T) {
+	filter, err := parseBridgeFilter("127250,129025:3", "59904")
+	assert.NoError(t, err)
+	assert.Equal(t, bridge.Filter{
+		Allow: []bridge.Rule{{PGNs: []uint32{127250}}, {PGNs: []uint32{129025}, Sources: []uint8{3}}},
+		Deny:  []bridge.Rule{{PGNs: []uint32{59904}}},
+	}, filter)
+
+	_, err = parseBridgeFilter("", "x")
+	assert.EqualError(t, err, `failed to parse PGN in filter, err: strconv.Atoi: parsing "x": invalid syntax`)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client/canboat"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// n2kschema filters Canboat schema down to given PGNs and writes it as Go source or gob blob. Meant to be used with
+// go:generate, for example:
+//
+//	//go:generate go run github.com/aldas/go-nmea-client/cmd/n2kschema -pgns=canboat.json -filter=127250,129029 -package=main -output=schema_gen.go
+func main() {
+	pgnsPath := flag.String("pgns", "", "path to Canboat pgns.json file")
+	pgnFilter := flag.String("filter", "", "comma separated list of PGNs to keep in schema")
+	outputFormat := flag.String("format", "go", "in which format schema subset is written (go, gob)")
+	outputPath := flag.String("output", "", "file where schema subset is written (defaults to STDOUT)")
+	packageName := flag.String("package", "main", "package name for generated Go source")
+	varName := flag.String("var", "schema", "variable name for schema in generated Go source")
+	flag.Parse()
+
+	if *pgnsPath == "" {
+		log.Fatal("path to Canboat pgns.json is required\n")
+	}
+	pgns, err := parsePGNs(*pgnFilter)
+	if err != nil {
+		log.Fatalf("invalid pgn filter given, %v\n", err)
+	}
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out, err := generate(schema.Subset(pgns...), *outputFormat, *packageName, *varName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *outputPath == "" {
+		_, err = os.Stdout.Write(out)
+	} else {
+		err = os.WriteFile(*outputPath, out, 0644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(schema canboat.CanboatSchema, format string, packageName string, varName string) ([]byte, error) {
+	buf := bytes.Buffer{}
+	var err error
+	switch format {
+	case "go":
+		err = canboat.WriteCANBoatSchemaGoSource(&buf, schema, packageName, varName)
+	case "gob":
+		err = canboat.WriteCANBoatSchemaGob(&buf, schema)
+	default:
+		err = fmt.Errorf("unknown output format: %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func parsePGNs(raw string) ([]uint32, error) {
+	result := make([]uint32, 0)
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, uint32(v))
+	}
+	if len(result) == 0 {
+		return nil, errors.New("at least one PGN is required")
+	}
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParsePGNs(t *testing.T) {
+	result, err := parsePGNs("127250, 129029,,")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{127250, 129029}, result)
+
+	_, err = parsePGNs(" , ")
+	assert.EqualError(t, err, "at least one PGN is required")
+}
+
+func TestGenerate(t *testing.T) {
+	schema, err := canboat.LoadCANBoatSchemaFile("../../canboat/testdata/canboat.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	subset := schema.Subset(127250)
+
+	src, err := generate(subset, "go", "main", "schema")
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), "var schema = canboat.CanboatSchema{")
+
+	blob, err := generate(subset, "gob", "", "")
+	assert.NoError(t, err)
+	loaded, err := canboat.LoadCANBoatSchemaGob(bytes.NewReader(blob))
+	assert.NoError(t, err)
+	assert.Equal(t, subset, loaded)
+
+	_, err = generate(subset, "xml", "main", "schema")
+	assert.EqualError(t, err, "unknown output format: xml")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/gateway"
+	"github.com/aldas/go-nmea-client/simulator"
+	"github.com/aldas/go-nmea-client/socketcan"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	scenario := flag.String("scenario", "random", "generated traffic: random (random field values for -pgns schema PGNs) or boat (plausible GPS, heading, depth, wind and engine traffic from nodes that claim addresses and respond to ISO requests, no schema needed)")
+	pgnsPath := flag.String("pgns", "", "path to Canboat pgns.json file. Required for random scenario")
+	pgnFilter := flag.String("filter", "", "comma separated list of PGNs to generate (defaults to all PGNs in schema). Used with random scenario")
+	sources := flag.String("source", "1", "comma separated list of Source addresses used for generated messages. In boat scenario first address is preferred address of first simulated node")
+	rate := flag.Float64("rate", 10, "number of messages generated per second (0 means as fast as possible). Used with random scenario")
+	count := flag.Uint64("count", 0, "number of messages to generate (0 means until interrupted)")
+	seed := flag.Int64("seed", 0, "seed for random generator (0 means random seed)")
+	outputPath := flag.String("output", "", "file where generated messages are written in Canboat format (defaults to STDOUT when -socketcan and -listen are not set)")
+	socketcanIface := flag.String("socketcan", "", "SocketCAN interface (i.e. vcan0) where generated messages are sent as frames (fast-packet messages are split). In boat scenario messages read from interface are delivered to simulated nodes")
+	listen := flag.String("listen", "", "TCP address (i.e. `:2000`) where generated messages are served to clients in Canboat format. In boat scenario messages sent by clients are delivered to simulated nodes")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	sourceList, err := parseList[uint8](*sources, 8)
+	if err != nil {
+		log.Fatalf("invalid source address list given, %v\n", err)
+	}
+
+	var generator nmea.RawMessageReader
+	var nodes nmea.RawMessageWriter // receives messages from bus, nil when scenario does not react to bus traffic
+	switch *scenario {
+	case "random":
+		generator, err = newRandomGenerator(*pgnsPath, *pgnFilter, sourceList, *rate, *seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "boat":
+		sim := newBoatSimulator(sourceList, *seed)
+		defer sim.Close()
+		if err := sim.Initialize(); err != nil {
+			log.Fatal(err)
+		}
+		generator = sim
+		nodes = sim
+	default:
+		log.Fatal("unknown scenario given\n")
+	}
+
+	outputs := make([]nmea.TeeOutput, 0)
+	if *socketcanIface != "" {
+		device := socketcan.NewDevice(socketcan.DeviceConfig{
+			InterfaceName:      *socketcanIface,
+			ReceiveDataTimeout: 24 * time.Hour, // simulated bus may be idle for long time
+		})
+		if err := device.Initialize(); err != nil {
+			log.Fatal(err)
+		}
+		defer device.Close()
+		if nodes != nil {
+			go deliverBusMessages(ctx, device, nodes)
+		}
+		outputs = append(outputs, nmea.TeeOutput{Writer: device})
+	}
+	if *listen != "" {
+		server, err := startServer(ctx, *listen, nodes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer server.Close()
+		outputs = append(outputs, nmea.TeeOutput{Writer: publisher{server: server}})
+	}
+	if *outputPath != "" || len(outputs) == 0 {
+		var out io.Writer = os.Stdout
+		if *outputPath != "" {
+			f, err := os.Create(*outputPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			out = f
+		}
+		writer := canboat.NewCanBoatWriter(out)
+		defer writer.Close()
+		outputs = append(outputs, nmea.TeeOutput{Writer: writer})
+	}
+	reader := nmea.NewTee(generator, outputs...)
+
+	generated := uint64(0)
+	started := time.Now()
+	for *count == 0 || generated < *count {
+		if _, err := reader.ReadRawMessage(ctx); err != nil {
+			if errors.Is(err, context.Canceled) {
+				break
+			}
+			log.Fatal(err)
+		}
+		generated++
+	}
+	fmt.Fprintf(os.Stderr, "# Generated %v messages in %v\n", generated, time.Since(started).Round(time.Millisecond))
+}
+
+func newRandomGenerator(pgnsPath string, pgnFilter string, sources []uint8, rate float64, seed int64) (*canboat.Generator, error) {
+	if pgnsPath == "" {
+		return nil, errors.New("path to Canboat pgns.json is required for random scenario")
+	}
+	schema, err := canboat.LoadCANBoatSchemaFile(pgnsPath)
+	if err != nil {
+		return nil, err
+	}
+	config := canboat.GeneratorConfig{
+		Rate:    rate,
+		Seed:    seed,
+		Sources: sources,
+	}
+	config.PGNs, err = parseList[uint32](pgnFilter, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pgn filter given, %w", err)
+	}
+	return canboat.NewGenerator(schema, config)
+}
+
+func newBoatSimulator(sources []uint8, seed int64) *simulator.Simulator {
+	firstAddress := uint8(1)
+	if len(sources) > 0 {
+		firstAddress = sources[0]
+	}
+	boat := simulator.NewBoat(simulator.BoatConfig{Course: 45, Seed: seed})
+	return simulator.New(simulator.BoatNodes(boat, firstAddress, seed))
+}
+
+// deliverBusMessages delivers messages read from bus to simulated nodes so they can respond to requests
+func deliverBusMessages(ctx context.Context, bus nmea.RawMessageReader, nodes nmea.RawMessageWriter) {
+	for {
+		msg, err := bus.ReadRawMessage(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) && !errors.Is(err, nmea.ErrDeviceClosed) {
+				fmt.Fprintf(os.Stderr, "# Reading bus ended with error: %v\n", err)
+			}
+			return
+		}
+		if err := nodes.WriteRawMessage(ctx, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "# Simulated nodes failed to process message: %v\n", err)
+		}
+	}
+}
+
+func startServer(ctx context.Context, addr string, nodes nmea.RawMessageWriter) (*gateway.Server, error) {
+	config := gateway.Config{
+		NewWriter: func(conn io.ReadWriter) nmea.RawMessageWriter { return canboat.NewCanBoatWriter(conn) },
+		OnError: func(client string, err error) {
+			fmt.Fprintf(os.Stderr, "# Client %v error: %v\n", client, err)
+		},
+	}
+	if nodes != nil {
+		config.NewReader = func(conn io.ReadWriter) nmea.RawMessageReader { return canboat.NewCanBoatReader(conn) }
+		config.Writer = nodes
+	}
+	server := gateway.NewServer(config)
+
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "# Serving generated messages to TCP clients at: %v\n", listener.Addr())
+	go func() {
+		if err := server.Serve(ctx, listener); err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "# TCP listener ended with error: %v\n", err)
+		}
+	}()
+	return server, nil
+}
+
+// publisher sends written messages to all gateway server clients
+type publisher struct {
+	server *gateway.Server
+}
+
+func (p publisher) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	p.server.Publish(msg)
+	return nil
+}
+
+func (p publisher) Close() error {
+	return nil
+}
+
+func parseList[T uint8 | uint32](raw string, bitSize int) ([]T, error) {
+	result := make([]T, 0)
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(p, 10, bitSize)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, T(v))
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseList(t *testing.T) {
+	result, err := parseList[uint32]("127250, 129029,,", 32)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{127250, 129029}, result)
+
+	_, err = parseList[uint8]("256", 8)
+	assert.EqualError(t, err, `strconv.ParseUint: parsing "256": value out of range`)
+}
+
+func TestNewRandomGenerator_requiresSchema(t *testing.T) {
+	_, err := newRandomGenerator("", "", nil, 10, 1)
+	assert.EqualError(t, err, "path to Canboat pgns.json is required for random scenario")
+}
+
+func TestNewBoatSimulator(t *testing.T) {
+	sim := newBoatSimulator([]uint8{20, 30}, 1)
+	defer sim.Close()
+	assert.NoError(t, sim.Initialize())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sources := make([]uint8, 0, 5)
+	for i := 0; i < 5; i++ {
+		msg, err := sim.ReadRawMessage(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, uint32(nmea.PGNISOAddressClaim), msg.Header.PGN)
+		sources = append(sources, msg.Header.Source)
+	}
+	assert.Equal(t, []uint8{20, 21, 22, 23, 24}, sources)
+}
@@ -0,0 +1,116 @@
+package nmea
+
+import (
+	"time"
+)
+
+// DeviceClock converts gateway device timestamps (i.e. Actisense W2K-1/NGT-1 millisecond counters) into bus times.
+//
+// Device timestamps are counters with device specific origin (i.e. device uptime) that wrap around, so they can not be
+// used as time as is. DeviceClock unwraps counter values and anchors them to local time. Anchor is chosen so that
+// message with the smallest observed delay between device and local clock has zero latency, so bus times never
+// lie in future compared to local receive times.
+//
+// Note: when there are no messages for longer than wrap-around period counter wrapping can not be detected.
+type DeviceClock struct {
+	wrapAround time.Duration
+	// reorderTolerance is how much counter may go backwards for message that arrived late (reordered UDP datagrams)
+	// without it being treated as wrap-around or device restart
+	reorderTolerance time.Duration
+
+	isStarted   bool
+	lastCounter time.Duration
+	elapsed     time.Duration
+	// origin is local time that corresponds to elapsed device time 0
+	origin time.Time
+}
+
+// MessageTiming holds bus receive and local times of message. Allows aligning NMEA2000 data with other sensor feeds.
+type MessageTiming struct {
+	// BusTime is estimated time when gateway device received message from NMEA bus
+	BusTime time.Time `json:"bus_time"`
+	// ReceivedTime is local time when message was read from device (RawMessage.Time)
+	ReceivedTime time.Time `json:"received_time"`
+	// ProcessedTime is local time when message was decoded
+	ProcessedTime time.Time `json:"processed_time"`
+
+	// Latency is skew between bus time and local receive time. Consists of transport latency (serial/network buffers)
+	// and clock drift between device and local clock.
+	Latency time.Duration `json:"latency"`
+	// ProcessingDelay is time between local receive and decoding
+	ProcessingDelay time.Duration `json:"processing_delay"`
+}
+
+// NewDeviceClock creates new instance of DeviceClock. wrapAround is period after which device counter starts again
+// from zero (i.e. 2^32 ms for 32bit millisecond counter). Value of 0 means that counter does not wrap around.
+func NewDeviceClock(wrapAround time.Duration) *DeviceClock {
+	return &DeviceClock{wrapAround: wrapAround}
+}
+
+// NewDeviceClockWithReorderTolerance creates new instance of DeviceClock that accepts messages arriving out of order.
+// Counter values that are at most reorderTolerance behind last seen counter are considered late messages and their
+// bus time is calculated relative to last message without advancing the clock.
+func NewDeviceClockWithReorderTolerance(wrapAround time.Duration, reorderTolerance time.Duration) *DeviceClock {
+	return &DeviceClock{wrapAround: wrapAround, reorderTolerance: reorderTolerance}
+}
+
+// BusTime returns estimated bus time for device counter value of message that was received at given local time.
+func (c *DeviceClock) BusTime(counter time.Duration, received time.Time) time.Time {
+	if !c.isStarted {
+		c.isStarted = true
+		c.lastCounter = counter
+		c.origin = received.Add(-counter)
+		c.elapsed = counter
+		return received
+	}
+	if behind, ok := c.behindLast(counter); ok {
+		return c.origin.Add(c.elapsed - behind)
+	}
+
+	if counter >= c.lastCounter {
+		c.elapsed += counter - c.lastCounter
+	} else if c.wrapAround > 0 {
+		c.elapsed += c.wrapAround - c.lastCounter + counter
+	} else {
+		// counter went backwards without wrapping (i.e. device was restarted). Start anchoring from scratch.
+		c.isStarted = false
+		return c.BusTime(counter, received)
+	}
+	c.lastCounter = counter
+
+	origin := received.Add(-c.elapsed)
+	if origin.Before(c.origin) {
+		c.origin = origin
+	}
+	return c.origin.Add(c.elapsed)
+}
+
+// behindLast returns how much counter is behind last counter when it is within reorder tolerance
+func (c *DeviceClock) behindLast(counter time.Duration) (time.Duration, bool) {
+	if c.reorderTolerance <= 0 || counter == c.lastCounter {
+		return 0, false
+	}
+	behind := c.lastCounter - counter
+	if counter > c.lastCounter { // late message from before last counter wrapped around
+		if c.wrapAround <= 0 {
+			return 0, false
+		}
+		behind = c.wrapAround - counter + c.lastCounter
+	}
+	return behind, behind <= c.reorderTolerance
+}
+
+// NewMessageTiming creates timing annotation for message decoded at given time. Returns nil when raw message has no
+// bus time (device did not provide timestamps).
+func NewMessageTiming(raw RawMessage, processed time.Time) *MessageTiming {
+	if raw.BusTime.IsZero() {
+		return nil
+	}
+	return &MessageTiming{
+		BusTime:         raw.BusTime,
+		ReceivedTime:    raw.Time,
+		ProcessedTime:   processed,
+		Latency:         raw.Time.Sub(raw.BusTime),
+		ProcessingDelay: processed.Sub(raw.Time),
+	}
+}
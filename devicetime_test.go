@@ -0,0 +1,112 @@
+package nmea
+
+import (
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDeviceClock_BusTime(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	type when struct {
+		counter  time.Duration
+		received time.Duration // offset from now
+	}
+	var testCases = []struct {
+		name           string
+		givenWrap      time.Duration
+		when           []when
+		expectBusTimes []time.Duration // offset from now
+	}{
+		{
+			name:      "ok, anchored to first message",
+			givenWrap: 65536 * time.Millisecond,
+			when: []when{
+				{counter: 1000 * time.Millisecond, received: 0},
+				{counter: 1100 * time.Millisecond, received: 150 * time.Millisecond},
+				{counter: 1200 * time.Millisecond, received: 210 * time.Millisecond},
+			},
+			expectBusTimes: []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:      "ok, anchor moves to message with smaller latency",
+			givenWrap: 65536 * time.Millisecond,
+			when: []when{
+				{counter: 1000 * time.Millisecond, received: 50 * time.Millisecond}, // first message was delayed
+				{counter: 1100 * time.Millisecond, received: 100 * time.Millisecond},
+				{counter: 1200 * time.Millisecond, received: 230 * time.Millisecond},
+			},
+			expectBusTimes: []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:      "ok, counter wraps around",
+			givenWrap: 65536 * time.Millisecond,
+			when: []when{
+				{counter: 65500 * time.Millisecond, received: 0},
+				{counter: 64 * time.Millisecond, received: 100 * time.Millisecond},
+			},
+			expectBusTimes: []time.Duration{0, 100 * time.Millisecond},
+		},
+		{
+			name:      "ok, counter going backwards without wrap around restarts anchoring",
+			givenWrap: 0,
+			when: []when{
+				{counter: 5000 * time.Millisecond, received: 0},
+				{counter: 10 * time.Millisecond, received: 100 * time.Millisecond},
+				{counter: 20 * time.Millisecond, received: 120 * time.Millisecond},
+			},
+			expectBusTimes: []time.Duration{0, 100 * time.Millisecond, 110 * time.Millisecond},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewDeviceClock(tc.givenWrap)
+
+			result := make([]time.Duration, 0)
+			for _, w := range tc.when {
+				busTime := clock.BusTime(w.counter, now.Add(w.received))
+				result = append(result, busTime.Sub(now))
+			}
+			assert.Equal(t, tc.expectBusTimes, result)
+		})
+	}
+}
+
+func TestDeviceClock_BusTime_reorderTolerance(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	clock := NewDeviceClockWithReorderTolerance(24*time.Hour, time.Second)
+
+	assert.Equal(t, now, clock.BusTime(24*time.Hour-100*time.Millisecond, now))
+	assert.Equal(t, now.Add(200*time.Millisecond), clock.BusTime(100*time.Millisecond, now.Add(200*time.Millisecond)))
+	// late message from before wrap-around
+	assert.Equal(t, now.Add(50*time.Millisecond), clock.BusTime(24*time.Hour-50*time.Millisecond, now.Add(210*time.Millisecond)))
+	// late message after wrap-around
+	assert.Equal(t, now.Add(150*time.Millisecond), clock.BusTime(50*time.Millisecond, now.Add(220*time.Millisecond)))
+	// clock continues from last counter
+	assert.Equal(t, now.Add(300*time.Millisecond), clock.BusTime(200*time.Millisecond, now.Add(300*time.Millisecond)))
+	// counter going backwards more than tolerance is wrap-around, clock is re-anchored to receive time
+	assert.Equal(t, now.Add(400*time.Millisecond), clock.BusTime(24*time.Hour-1800*time.Millisecond, now.Add(400*time.Millisecond)))
+}
+
+func TestNewMessageTiming(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	timing := NewMessageTiming(RawMessage{
+		Time:    now,
+		BusTime: now.Add(-40 * time.Millisecond),
+	}, now.Add(5*time.Millisecond))
+
+	expect := &MessageTiming{
+		BusTime:         now.Add(-40 * time.Millisecond),
+		ReceivedTime:    now,
+		ProcessedTime:   now.Add(5 * time.Millisecond),
+		Latency:         40 * time.Millisecond,
+		ProcessingDelay: 5 * time.Millisecond,
+	}
+	assert.Equal(t, expect, timing)
+
+	assert.Nil(t, NewMessageTiming(RawMessage{Time: now}, now))
+}
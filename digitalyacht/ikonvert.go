@@ -0,0 +1,332 @@
+package digitalyacht
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// lineMaxSize is longest valid line. Received sentence has at most 1785 data bytes that are base64 encoded (2380)
+	// plus header fields.
+	lineMaxSize = 2500
+
+	rxPrefix     = "!PDGY,"
+	statusPrefix = "$PDGY,"
+)
+
+// Config is configuration for Digital Yacht iKonvert gateway
+type Config struct {
+	// DebugLogRawMessageBytes instructs device to log all sent/received lines
+	DebugLogRawMessageBytes bool
+	// LogFunc callback to output/print debug/log statements. Used when Logger is not set.
+	LogFunc func(format string, a ...any)
+	// Logger outputs debug/log statements. Raw lines are logged on Debug level when DebugLogRawMessageBytes is set.
+	// Optional: when not set LogFunc is used
+	Logger nmea.Logger
+
+	// RxPGNs is list of PGNs gateway is instructed to receive from bus.
+	// Optional: when empty gateway is initialized to receive all PGNs (`ALL` mode).
+	RxPGNs []uint32
+	// TxPGNs is list of PGNs that are written to bus. Gateway sends only PGNs that are in its TX list.
+	// Optional: when empty TX list is not changed.
+	TxPGNs []uint32
+
+	// OnStatus is called for every network status sentence gateway sends (once per second).
+	OnStatus func(status NetworkStatus)
+	// OnResponse is called for every command response (ACK/NAK) and text sentence gateway sends.
+	OnResponse func(response Response)
+}
+
+// NetworkStatus is network status reported by gateway with `$PDGY,000000,...` sentence
+type NetworkStatus struct {
+	// BusLoad is CAN bus load in percents
+	BusLoad int
+	// Errors is number of CAN bus errors
+	Errors int
+	// DeviceCount is number of devices on bus
+	DeviceCount int
+	// Uptime is time since gateway was powered on
+	Uptime time.Duration
+	// Address is gateway own address on bus
+	Address uint8
+	// RejectedTX is number of rejected transmit requests (PGNs not in TX list)
+	RejectedTX int
+}
+
+// ResponseType is type of gateway response sentence
+type ResponseType string
+
+const (
+	// ResponseACK means that command was accepted
+	ResponseACK ResponseType = "ACK"
+	// ResponseNAK means that command was rejected
+	ResponseNAK ResponseType = "NAK"
+	// ResponseText is text sentence (i.e. firmware version at startup)
+	ResponseText ResponseType = "TEXT"
+)
+
+// Response is gateway response sentence (i.e. `$PDGY,ACK,N2NET_INIT,ALL` or `$PDGY,NAK,1,bad command`)
+type Response struct {
+	Type ResponseType
+	Text string
+}
+
+// IKonvertDevice is implementing Digital Yacht iKonvert (NMEA 2000 to USB) gateway protocol.
+//
+// Gateway assembles fast-packet and multi-packet (ISO TP) messages itself and sends every received message as single
+// sentence: `!PDGY,<pgn>,<priority>,<source>,<destination>,<timer>,<base64 data><CR><LF>` where timer is gateway
+// uptime in seconds with milliseconds. Messages are sent to bus with `!PDGY,<pgn>,<destination>,<base64 data><CR><LF>`
+// sentence. Gateway status and command responses start with `$PDGY,`.
+//
+// Note: is not go-routine safe
+type IKonvertDevice struct {
+	device  io.ReadWriter
+	timeNow func() time.Time
+
+	readBuffer []byte
+	chunk      []byte
+
+	config Config
+
+	// busClock converts gateway timer to bus times
+	busClock *nmea.DeviceClock
+
+	closed atomic.Bool
+}
+
+// NewIKonvertDevice creates new instance of Digital Yacht iKonvert gateway device
+func NewIKonvertDevice(device io.ReadWriter, config Config) *IKonvertDevice {
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
+	return &IKonvertDevice{
+		device:     device,
+		timeNow:    time.Now,
+		readBuffer: make([]byte, 0, 4096),
+		chunk:      make([]byte, 1024),
+		config:     config,
+		busClock:   nmea.NewDeviceClock(0),
+	}
+}
+
+// Initialize takes gateway offline, configures TX and RX PGN lists and starts gateway in `ALL` mode (all PGNs are
+// received) or normal mode (only PGNs in RX list are received).
+func (d *IKonvertDevice) Initialize() error {
+	commands := []string{"N2NET_OFFLINE"}
+	if len(d.config.TxPGNs) > 0 {
+		commands = append(commands, "TX_LIST,"+joinPGNs(d.config.TxPGNs))
+	}
+	if len(d.config.RxPGNs) > 0 {
+		commands = append(commands, "RX_LIST,"+joinPGNs(d.config.RxPGNs), "N2NET_INIT")
+	} else {
+		commands = append(commands, "N2NET_INIT,ALL")
+	}
+	for _, c := range commands {
+		if err := d.writeLine([]byte(statusPrefix + c + "\r\n")); err != nil {
+			return fmt.Errorf("iKonvert initialization failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func joinPGNs(pgns []uint32) string {
+	parts := make([]string, len(pgns))
+	for i, pgn := range pgns {
+		parts[i] = strconv.FormatUint(uint64(pgn), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Close closes device. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
+func (d *IKonvertDevice) Close() error {
+	d.closed.Store(true)
+	if c, ok := d.device.(io.Closer); ok {
+		return c.Close()
+	}
+	return errors.New("device does not implement Closer interface")
+}
+
+// ReadRawMessage reads next message from gateway. Status and response sentences are delivered to Config.OnStatus and
+// Config.OnResponse callbacks.
+func (d *IKonvertDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nmea.RawMessage{}, ctx.Err()
+		default:
+		}
+		if d.closed.Load() {
+			return nmea.RawMessage{}, nmea.ErrDeviceClosed
+		}
+
+		// process lines that are already buffered before reading more
+		if endIndex := bytes.IndexByte(d.readBuffer, '\n'); endIndex != -1 {
+			line := bytes.TrimRight(d.readBuffer[:endIndex], "\r")
+			if d.config.DebugLogRawMessageBytes {
+				d.config.Logger.Debug("read iKonvert line", "line", string(line))
+			}
+			msg, skip, err := d.handleLine(line)
+			d.readBuffer = d.readBuffer[:copy(d.readBuffer, d.readBuffer[endIndex+1:])]
+			if skip {
+				continue
+			}
+			return msg, err
+		}
+		if len(d.readBuffer) > lineMaxSize { // garbage without line ends
+			d.readBuffer = d.readBuffer[:0]
+		}
+
+		n, err := d.device.Read(d.chunk)
+		if err != nil {
+			if d.closed.Load() {
+				return nmea.RawMessage{}, nmea.ErrDeviceClosed
+			}
+			return nmea.RawMessage{}, err
+		}
+		d.readBuffer = append(d.readBuffer, d.chunk[:n]...)
+	}
+}
+
+// handleLine handles single line. Returns true for lines that are not messages (status, responses, garbage).
+func (d *IKonvertDevice) handleLine(line []byte) (nmea.RawMessage, bool, error) {
+	switch {
+	case bytes.HasPrefix(line, []byte(rxPrefix)):
+		now := d.timeNow()
+		msg, timer, err := parseRxSentence(line, now)
+		if err != nil {
+			return nmea.RawMessage{}, false, err
+		}
+		msg.BusTime = d.busClock.BusTime(timer, now)
+		msg.DeviceTime = timer
+		return msg, false, nil
+	case bytes.HasPrefix(line, []byte(statusPrefix)):
+		status, response, isStatus, err := parseStatusSentence(line)
+		if err != nil {
+			return nmea.RawMessage{}, true, nil // status sentences are informative, broken ones are ignored
+		}
+		if isStatus && d.config.OnStatus != nil {
+			d.config.OnStatus(status)
+		} else if !isStatus && d.config.OnResponse != nil {
+			d.config.OnResponse(response)
+		}
+	}
+	return nmea.RawMessage{}, true, nil
+}
+
+// parseRxSentence parses received message sentence. Example: `!PDGY,127250,2,36,255,4321.107,AP//f/8AAP8=`
+func parseRxSentence(line []byte, now time.Time) (nmea.RawMessage, time.Duration, error) {
+	parts := strings.Split(string(line[len(rxPrefix):]), ",")
+	if len(parts) != 6 {
+		return nmea.RawMessage{}, 0, errors.New("invalid iKonvert sentence field count")
+	}
+	pgn, err := strconv.ParseUint(parts[0], 10, 18)
+	if err != nil {
+		return nmea.RawMessage{}, 0, fmt.Errorf("invalid iKonvert sentence PGN: %w", err)
+	}
+	priority, err := strconv.ParseUint(parts[1], 10, 3)
+	if err != nil {
+		return nmea.RawMessage{}, 0, fmt.Errorf("invalid iKonvert sentence priority: %w", err)
+	}
+	source, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil {
+		return nmea.RawMessage{}, 0, fmt.Errorf("invalid iKonvert sentence source: %w", err)
+	}
+	destination, err := strconv.ParseUint(parts[3], 10, 8)
+	if err != nil {
+		return nmea.RawMessage{}, 0, fmt.Errorf("invalid iKonvert sentence destination: %w", err)
+	}
+	timer, err := strconv.ParseFloat(parts[4], 64)
+	if err != nil || timer < 0 {
+		return nmea.RawMessage{}, 0, errors.New("invalid iKonvert sentence timer")
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nmea.RawMessage{}, 0, fmt.Errorf("invalid iKonvert sentence data: %w", err)
+	}
+	if len(data) == 0 || len(data) > nmea.ISOTPDataMaxSize {
+		return nmea.RawMessage{}, 0, errors.New("invalid iKonvert sentence data length")
+	}
+
+	return nmea.RawMessage{
+		Time: now,
+		Header: nmea.CanBusHeader{
+			PGN:         uint32(pgn),
+			Priority:    uint8(priority),
+			Source:      uint8(source),
+			Destination: uint8(destination),
+		},
+		Data: data,
+	}, time.Duration(math.Round(timer*1000)) * time.Millisecond, nil
+}
+
+// parseStatusSentence parses gateway status or response sentence. Examples: `$PDGY,000000,4,0,5,482,36,0`,
+// `$PDGY,ACK,N2NET_INIT,ALL`, `$PDGY,TEXT,Digital_Yacht_iKonvert_v2.3`
+func parseStatusSentence(line []byte) (NetworkStatus, Response, bool, error) {
+	parts := strings.Split(string(line[len(statusPrefix):]), ",")
+	switch ResponseType(parts[0]) {
+	case ResponseACK, ResponseNAK, ResponseText:
+		return NetworkStatus{}, Response{Type: ResponseType(parts[0]), Text: strings.Join(parts[1:], ",")}, false, nil
+	}
+	if parts[0] != "000000" || len(parts) < 7 {
+		return NetworkStatus{}, Response{}, false, errors.New("unknown iKonvert status sentence")
+	}
+	values := make([]int, 6)
+	for i, p := range parts[1:7] {
+		if p == "" {
+			continue
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return NetworkStatus{}, Response{}, false, fmt.Errorf("invalid iKonvert status sentence field: %w", err)
+		}
+		values[i] = v
+	}
+	return NetworkStatus{
+		BusLoad:     values[0],
+		Errors:      values[1],
+		DeviceCount: values[2],
+		Uptime:      time.Duration(values[3]) * time.Second,
+		Address:     uint8(values[4]),
+		RejectedTX:  values[5],
+	}, Response{}, true, nil
+}
+
+// formatTxSentence formats message as sentence sent to gateway. Example: `!PDGY,59904,255,AO4B<CR><LF>`
+func formatTxSentence(msg nmea.RawMessage) []byte {
+	b := make([]byte, 0, 24+base64.StdEncoding.EncodedLen(len(msg.Data)))
+	b = append(b, rxPrefix...)
+	b = strconv.AppendUint(b, uint64(msg.Header.PGN), 10)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, uint64(msg.Header.Destination), 10)
+	b = append(b, ',')
+	b = append(b, base64.StdEncoding.EncodeToString(msg.Data)...)
+	return append(b, '\r', '\n')
+}
+
+// WriteRawMessage writes message to gateway. Gateway splits messages longer than 8 bytes into fast-packet or ISO TP
+// frames itself. Message PGN must be in gateway TX list (see Config.TxPGNs).
+func (d *IKonvertDevice) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
+	if len(msg.Data) == 0 || len(msg.Data) > nmea.ISOTPDataMaxSize {
+		return fmt.Errorf("iKonvert message data length must be 1-%v bytes, got %v", nmea.ISOTPDataMaxSize, len(msg.Data))
+	}
+	return d.writeLine(formatTxSentence(msg))
+}
+
+func (d *IKonvertDevice) writeLine(line []byte) error {
+	if d.config.DebugLogRawMessageBytes {
+		d.config.Logger.Debug("writing iKonvert line", "line", string(bytes.TrimSpace(line)))
+	}
+	_, err := d.device.Write(line)
+	return err
+}
@@ -0,0 +1,206 @@
+package digitalyacht
+
+import (
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseRxSentence(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	var testCases = []struct {
+		name        string
+		when        string
+		expect      nmea.RawMessage
+		expectTimer time.Duration
+		expectError string
+	}{
+		{
+			name: "ok",
+			when: "!PDGY,127250,2,36,255,4321.107,AP//f/8AAP8=",
+			expect: nmea.RawMessage{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 127250, Priority: 2, Source: 36, Destination: 255},
+				Data:   []byte{0x00, 0xff, 0xff, 0x7f, 0xff, 0x00, 0x00, 0xff},
+			},
+			expectTimer: 4321*time.Second + 107*time.Millisecond,
+		},
+		{
+			name:        "nok, missing fields",
+			when:        "!PDGY,127250,2,36,255,AP//f/8AAP8=",
+			expectError: "invalid iKonvert sentence field count",
+		},
+		{
+			name:        "nok, invalid PGN",
+			when:        "!PDGY,12725X,2,36,255,4321.107,AP//f/8AAP8=",
+			expectError: `invalid iKonvert sentence PGN: strconv.ParseUint: parsing "12725X": invalid syntax`,
+		},
+		{
+			name:        "nok, invalid timer",
+			when:        "!PDGY,127250,2,36,255,x,AP//f/8AAP8=",
+			expectError: "invalid iKonvert sentence timer",
+		},
+		{
+			name:        "nok, invalid data",
+			when:        "!PDGY,127250,2,36,255,4321.107,AP//f/8AAP8",
+			expectError: "invalid iKonvert sentence data: illegal base64 data at input byte 8",
+		},
+		{
+			name:        "nok, empty data",
+			when:        "!PDGY,127250,2,36,255,4321.107,",
+			expectError: "invalid iKonvert sentence data length",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, timer, err := parseRxSentence([]byte(tc.when), now)
+
+			assert.Equal(t, tc.expect, result)
+			assert.Equal(t, tc.expectTimer, timer)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseStatusSentence(t *testing.T) {
+	status, _, isStatus, err := parseStatusSentence([]byte("$PDGY,000000,4,0,5,482,36,1"))
+	assert.NoError(t, err)
+	assert.True(t, isStatus)
+	assert.Equal(t, NetworkStatus{
+		BusLoad:     4,
+		Errors:      0,
+		DeviceCount: 5,
+		Uptime:      482 * time.Second,
+		Address:     36,
+		RejectedTX:  1,
+	}, status)
+
+	_, response, isStatus, err := parseStatusSentence([]byte("$PDGY,NAK,1,bad command"))
+	assert.NoError(t, err)
+	assert.False(t, isStatus)
+	assert.Equal(t, Response{Type: ResponseNAK, Text: "1,bad command"}, response)
+
+	_, _, _, err = parseStatusSentence([]byte("$PDGY,000000,4,x,5,482,36,1"))
+	assert.EqualError(t, err, `invalid iKonvert status sentence field: strconv.Atoi: parsing "x": invalid syntax`)
+
+	_, _, _, err = parseStatusSentence([]byte("$PDGY,UNKNOWN"))
+	assert.EqualError(t, err, "unknown iKonvert status sentence")
+}
+
+type testConn struct {
+	reader io.Reader
+	bytes.Buffer
+}
+
+func (c *testConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func TestIKonvertDevice_ReadRawMessage(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	input := "$PDGY,TEXT,Digital_Yacht_iKonvert_v2.3\r\n" +
+		"!PDGY,127250,2,36,255,4321.107,AP//f/8AAP8=\r\n" +
+		"$PDGY,000000,4,0,5,482,36,0\r\n" +
+		"garbage\r\n" +
+		"!PDGY,59904,6,1,36,4321.207,AO4B\r\n"
+
+	var statuses []NetworkStatus
+	var responses []Response
+	device := NewIKonvertDevice(&testConn{reader: bytes.NewReader([]byte(input))}, Config{
+		OnStatus:   func(status NetworkStatus) { statuses = append(statuses, status) },
+		OnResponse: func(response Response) { responses = append(responses, response) },
+	})
+	device.timeNow = func() time.Time { return now }
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:       now,
+		BusTime:    now,
+		DeviceTime: 4321107 * time.Millisecond,
+		Header:     nmea.CanBusHeader{PGN: 127250, Priority: 2, Source: 36, Destination: 255},
+		Data:       []byte{0x00, 0xff, 0xff, 0x7f, 0xff, 0x00, 0x00, 0xff},
+	}, msg)
+
+	msg, err = device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:       now,
+		BusTime:    now, // bus time never lies in future compared to local receive time
+		DeviceTime: 4321207 * time.Millisecond,
+		Header:     nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 1, Destination: 36},
+		Data:       []byte{0x00, 0xee, 0x01},
+	}, msg)
+
+	_, err = device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Equal(t, []Response{{Type: ResponseText, Text: "Digital_Yacht_iKonvert_v2.3"}}, responses)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, 5, statuses[0].DeviceCount)
+}
+
+func TestIKonvertDevice_ReadRawMessage_closed(t *testing.T) {
+	device := NewIKonvertDevice(&testConn{reader: bytes.NewReader(nil)}, Config{})
+	assert.EqualError(t, device.Close(), "device does not implement Closer interface")
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+}
+
+func TestIKonvertDevice_Initialize(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		given  Config
+		expect string
+	}{
+		{
+			name:  "ok, all PGNs",
+			given: Config{},
+			expect: "$PDGY,N2NET_OFFLINE\r\n" +
+				"$PDGY,N2NET_INIT,ALL\r\n",
+		},
+		{
+			name:  "ok, RX and TX lists",
+			given: Config{RxPGNs: []uint32{127250, 129025}, TxPGNs: []uint32{59904}},
+			expect: "$PDGY,N2NET_OFFLINE\r\n" +
+				"$PDGY,TX_LIST,59904\r\n" +
+				"$PDGY,RX_LIST,127250,129025\r\n" +
+				"$PDGY,N2NET_INIT\r\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &testConn{}
+			device := NewIKonvertDevice(conn, tc.given)
+
+			assert.NoError(t, device.Initialize())
+			assert.Equal(t, tc.expect, conn.String())
+		})
+	}
+}
+
+func TestIKonvertDevice_WriteRawMessage(t *testing.T) {
+	conn := &testConn{}
+	device := NewIKonvertDevice(conn, Config{})
+
+	err := device.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNISORequest), Priority: 6, Destination: 36},
+		Data:   []byte{0x00, 0xee, 0x01},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "!PDGY,59904,36,AO4B\r\n", conn.String())
+
+	err = device.WriteRawMessage(context.Background(), nmea.RawMessage{})
+	assert.EqualError(t, err, "iKonvert message data length must be 1-1785 bytes, got 0")
+}
@@ -0,0 +1,123 @@
+// CSV logger writes selected fields of decoded messages into CSV files (one file per PGN). Input is capture in Canboat
+// format (i.e. output of n2ksim or `n2k-reader -output-format=canboat-raw`) read from file or STDIN.
+//
+//	go run ./cmd/n2ksim -pgns canboat.json -filter 129025 -count 100 | \
+//	  go run ./examples/csvlogger -pgns canboat.json -map 129025=_time_ms,_src,latitude,longitude -throttle 1s
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/export"
+	"github.com/aldas/go-nmea-client/pipeline"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	pgnsPath := flag.String("pgns", "canboat.json", "path to Canboat pgns.json file")
+	inputPath := flag.String("input", "-", "path to capture file in Canboat format (`-` reads STDIN)")
+	outputDir := flag.String("output", ".", "directory where CSV files are written")
+	throttle := flag.Duration("throttle", 0, "write at most one row per PGN and source within given time window")
+	var mappings []export.CSVMapping
+	flag.Func("map", "CSV mapping in form of `pgn=field1,field2`, can be given multiple times", func(s string) error {
+		mapping, err := parseMapping(s)
+		if err != nil {
+			return err
+		}
+		mappings = append(mappings, mapping)
+		return nil
+	})
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if len(mappings) == 0 {
+		log.Fatal("at least one CSV mapping is required\n")
+	}
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var input io.Reader = os.Stdin
+	if *inputPath != "-" {
+		f, err := os.Open(*inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		input = f
+	}
+	reader := canboat.NewCanBoatReader(input)
+
+	writer := export.NewCSVWriter(*outputDir, mappings)
+	defer func() {
+		if err := writer.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	handlers := []pipeline.Handler{
+		pipeline.NewFilter(func(raw nmea.RawMessage) bool {
+			for _, m := range mappings {
+				if m.PGN == raw.Header.PGN {
+					return true
+				}
+			}
+			return false
+		}),
+	}
+	if *throttle > 0 {
+		handlers = append(handlers, pipeline.NewThrottleFilter(*throttle))
+	}
+	handlers = append(handlers, pipeline.DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+		return true, writer.WriteMessage(raw, msg)
+	}))
+
+	p := pipeline.New(pipeline.Config{
+		Decoder:  canboat.NewDecoder(schema),
+		Handlers: handlers,
+		OnDecodeError: func(ctx context.Context, raw nmea.RawMessage, err error) error {
+			fmt.Fprintf(os.Stderr, "# %v\n", err)
+			return nil
+		},
+	})
+	started := time.Now()
+	if err := p.Run(ctx, reader); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "# Finished in %v\n", time.Since(started).Round(time.Millisecond))
+}
+
+func parseMapping(s string) (export.CSVMapping, error) {
+	pgnPart, fieldsPart, ok := strings.Cut(s, "=")
+	if !ok {
+		return export.CSVMapping{}, errors.New("mapping must be in form of `pgn=field1,field2`")
+	}
+	pgn, err := strconv.ParseUint(strings.TrimSpace(pgnPart), 10, 32)
+	if err != nil {
+		return export.CSVMapping{}, fmt.Errorf("invalid mapping PGN: %w", err)
+	}
+	mapping := export.CSVMapping{PGN: uint32(pgn)}
+	for _, f := range strings.Split(fieldsPart, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			mapping.Fields = append(mapping.Fields, f)
+		}
+	}
+	if len(mapping.Fields) == 0 {
+		return export.CSVMapping{}, errors.New("mapping must have at least one field")
+	}
+	return mapping, nil
+}
@@ -0,0 +1,71 @@
+// Minimal reader reads messages from Actisense NGT-1/W2K-1 device (or recorded binary capture file) and prints
+// decoded messages as JSON.
+//
+//	go run ./examples/minimalreader -pgns canboat.json -device /dev/ttyUSB0
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/serialport"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	pgnsPath := flag.String("pgns", "canboat.json", "path to Canboat pgns.json file")
+	deviceAddr := flag.String("device", "/dev/ttyUSB0", "path to serial device or capture file")
+	isFile := flag.Bool("is-file", false, "device is capture file")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	decoder := canboat.NewDecoder(schema)
+
+	var reader io.ReadWriteCloser
+	if *isFile {
+		reader, err = os.Open(*deviceAddr)
+	} else {
+		reader, err = serialport.Open(nmea.SerialConfig{Name: *deviceAddr})
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer reader.Close()
+
+	device := actisense.NewBinaryDevice(reader)
+	if err := device.Initialize(); err != nil {
+		log.Fatal(err)
+	}
+
+	for {
+		raw, err := device.ReadRawMessage(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) || errors.Is(err, nmea.ErrDeviceClosed) {
+				return
+			}
+			log.Fatal(err)
+		}
+		msg, err := decoder.Decode(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "# %v\n", err)
+			continue
+		}
+		b, _ := json.Marshal(msg)
+		fmt.Printf("%s\n", b)
+	}
+}
@@ -0,0 +1,84 @@
+// Signal K feeder reads messages from Actisense NGT-1/W2K-1 device, converts common navigation PGNs to Signal K delta
+// messages and sends them to Signal K server over UDP (server needs "Signal K" data connection with UDP input).
+//
+//	go run ./examples/signalkfeeder -pgns canboat.json -device /dev/ttyUSB0 -server 127.0.0.1:7777
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/pipeline"
+	"github.com/aldas/go-nmea-client/serialport"
+	"github.com/aldas/go-nmea-client/signalk"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	pgnsPath := flag.String("pgns", "canboat.json", "path to Canboat pgns.json file")
+	deviceAddr := flag.String("device", "/dev/ttyUSB0", "path to serial device")
+	serverAddr := flag.String("server", "127.0.0.1:7777", "Signal K server UDP address")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := net.Dial("udp", *serverAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	device, err := actisense.OpenBinaryDevice(serialport.Opener, nmea.SerialConfig{Name: *deviceAddr}, actisense.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer device.Close()
+	if err := device.Initialize(); err != nil {
+		log.Fatal(err)
+	}
+
+	converter := signalk.NewConverter(signalk.Config{})
+	p := pipeline.New(pipeline.Config{
+		Decoder: canboat.NewDecoder(schema),
+		Handlers: []pipeline.Handler{
+			pipeline.NewFilter(func(raw nmea.RawMessage) bool {
+				return signalk.IsSupported(raw.Header.PGN)
+			}),
+			pipeline.DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+				delta, ok := converter.Convert(msg, raw.Time)
+				if !ok {
+					return true, nil
+				}
+				b, err := json.Marshal(delta)
+				if err != nil {
+					return false, err
+				}
+				_, err = conn.Write(b)
+				return true, err
+			}),
+		},
+		OnDecodeError: func(ctx context.Context, raw nmea.RawMessage, err error) error {
+			fmt.Fprintf(os.Stderr, "# %v\n", err)
+			return nil
+		},
+	})
+	if err := p.Run(ctx, device); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, io.EOF) {
+		log.Fatal(err)
+	}
+}
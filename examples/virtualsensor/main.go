@@ -0,0 +1,113 @@
+// Virtual sensor node simulates heading sensor and depth sounder. Field values are encoded with canboat.Encoder and
+// written to Actisense NGT-1/W2K-1 device or to STDOUT in Canboat format.
+//
+//	go run ./examples/virtualsensor -pgns canboat.json -device /dev/ttyUSB0
+//	go run ./examples/virtualsensor -pgns canboat.json -device - | n2k-reader -input-format=canboat-raw -device /dev/stdin -is-file
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/serialport"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	pgnVesselHeading = 127250
+	pgnWaterDepth    = 128267
+)
+
+func main() {
+	pgnsPath := flag.String("pgns", "canboat.json", "path to Canboat pgns.json file")
+	deviceAddr := flag.String("device", "-", "path to serial device (`-` writes to STDOUT in Canboat format)")
+	source := flag.Uint("source", 100, "source address of virtual node")
+	interval := flag.Duration("interval", 100*time.Millisecond, "interval between heading messages")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	schema, err := canboat.LoadCANBoatSchemaFile(*pgnsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var encoder nmea.MessageEncoder = canboat.NewEncoder(schema)
+
+	var writer nmea.RawMessageWriter
+	if *deviceAddr == "-" {
+		writer = canboat.NewCanBoatWriter(os.Stdout)
+	} else {
+		device, err := actisense.OpenBinaryDevice(serialport.Opener, nmea.SerialConfig{Name: *deviceAddr}, actisense.Config{})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := device.Initialize(); err != nil {
+			log.Fatal(err)
+		}
+		writer = device
+	}
+	defer writer.Close()
+
+	sensor := &sensor{encoder: encoder, writer: writer, source: uint8(*source)}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for tick := 0; ; tick++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// heading turns slowly around full circle
+		heading := math.Mod(float64(tick)*0.01, 2*math.Pi)
+		if err := sensor.send(ctx, 2, pgnVesselHeading, nmea.FieldValues{
+			{ID: "sid", Value: uint64(tick % 250)},
+			{ID: "heading", Value: heading},
+			{ID: "reference", Value: uint64(1)}, // magnetic
+		}); err != nil {
+			log.Fatal(err)
+		}
+		if tick%10 != 0 {
+			continue
+		}
+		// depth goes up and down between 5 and 15 meters
+		depth := 10 + 5*math.Sin(float64(tick)/100)
+		if err := sensor.send(ctx, 3, pgnWaterDepth, nmea.FieldValues{
+			{ID: "sid", Value: uint64(tick % 250)},
+			{ID: "depth", Value: depth},
+			{ID: "offset", Value: 0.5},
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+type sensor struct {
+	encoder nmea.MessageEncoder
+	writer  nmea.RawMessageWriter
+	source  uint8
+}
+
+func (s *sensor) send(ctx context.Context, priority uint8, pgn uint32, fields nmea.FieldValues) error {
+	raw, err := s.encoder.Encode(nmea.Message{
+		Header: nmea.CanBusHeader{
+			PGN:         pgn,
+			Priority:    priority,
+			Source:      s.source,
+			Destination: 255,
+		},
+		Fields: fields,
+	})
+	if err != nil {
+		return err
+	}
+	raw.Time = time.Now()
+	return s.writer.WriteRawMessage(ctx, raw)
+}
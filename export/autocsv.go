@@ -0,0 +1,217 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// AutoCSVMetaColumns are special columns written before PGN fields by AutoCSVWriter
+var AutoCSVMetaColumns = []string{"_time_ms", "_src", "_dst", "_prio"}
+
+// AutoCSVConfig configures AutoCSVWriter
+type AutoCSVConfig struct {
+	// Dir is directory where CSV files are created
+	Dir string
+	// Schema is used to derive CSV columns for PGNs
+	Schema canboat.CanboatSchema
+
+	// MaxFileSize is file size in bytes after which new file is started for PGN. Optional: 0 means no size limit.
+	MaxFileSize int64
+	// RotateInterval is time period after which new file is started for PGN. Periods are aligned to interval
+	// (i.e. 1h rotates at the start of every hour) and are based on message time. Optional: 0 means no time based
+	// rotation.
+	RotateInterval time.Duration
+}
+
+// AutoCSVWriter writes decoded messages into CSV files, one file per PGN, without pre-declared field lists. Columns are
+// AutoCSVMetaColumns followed by PGN field IDs in schema order (reserved, spare and repeating fields are left out).
+// Files are created as messages with new PGNs appear and are named `<pgn>_<start time>.csv`. Messages with PGNs that
+// schema does not know are skipped.
+//
+// Rows are flushed to file after every message so data is not lost when logging process is terminated.
+type AutoCSVWriter struct {
+	config  AutoCSVConfig
+	pgns    map[uint32]canboat.PGNs
+	columns map[uint32][]string
+	files   map[uint32]*autoCSVFile
+}
+
+type autoCSVFile struct {
+	file    *os.File
+	counter *countingFileWriter
+	writer  *csv.Writer
+	// until is time when file is rotated due RotateInterval. Zero when time based rotation is not used.
+	until time.Time
+}
+
+type countingFileWriter struct {
+	file *os.File
+	n    int64
+}
+
+func (w *countingFileWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// NewAutoCSVWriter creates new instance of AutoCSVWriter
+func NewAutoCSVWriter(config AutoCSVConfig) *AutoCSVWriter {
+	pgns := map[uint32]canboat.PGNs{}
+	for _, p := range config.Schema.PGNs {
+		pgns[p.PGN] = append(pgns[p.PGN], p)
+	}
+	return &AutoCSVWriter{
+		config:  config,
+		pgns:    pgns,
+		columns: map[uint32][]string{},
+		files:   map[uint32]*autoCSVFile{},
+	}
+}
+
+// CSVColumns returns CSV columns for PGN definitions (same PGN can have multiple definitions for different
+// manufacturers). Columns are field IDs in schema order without reserved, spare and repeating fields. Fields of
+// following definitions that first definition does not have are appended.
+func CSVColumns(definitions canboat.PGNs) []string {
+	seen := map[string]bool{}
+	columns := make([]string, 0)
+	for _, pgn := range definitions {
+		for _, f := range pgn.Fields {
+			if f.FieldType == canboat.FieldTypeReserved || f.FieldType == canboat.FieldTypeSpare || isRepeatingField(pgn, f) {
+				continue
+			}
+			if seen[f.ID] {
+				continue
+			}
+			seen[f.ID] = true
+			columns = append(columns, f.ID)
+		}
+	}
+	return columns
+}
+
+func isRepeatingField(pgn canboat.PGN, f canboat.Field) bool {
+	if pgn.RepeatingFieldSet1Size > 0 && f.Order >= pgn.RepeatingFieldSet1StartField {
+		return true
+	}
+	return pgn.RepeatingFieldSet2Size > 0 && f.Order >= pgn.RepeatingFieldSet2StartField
+}
+
+// WriteMessage writes decoded message as row into PGN CSV file
+func (w *AutoCSVWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	columns, ok := w.pgnColumns(msg.Header.PGN)
+	if !ok {
+		return nil
+	}
+	now := raw.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	f, err := w.file(msg.Header.PGN, columns, now)
+	if err != nil {
+		return err
+	}
+	row := make([]string, 0, len(columns))
+	for _, c := range columns {
+		row = append(row, csvValue(c, raw, msg))
+	}
+	if err := f.writer.Write(row); err != nil {
+		return fmt.Errorf("csv failed to write row, err: %w", err)
+	}
+	f.writer.Flush()
+	if err := f.writer.Error(); err != nil {
+		return fmt.Errorf("csv failed to write row, err: %w", err)
+	}
+	return nil
+}
+
+func (w *AutoCSVWriter) pgnColumns(pgn uint32) ([]string, bool) {
+	if columns, ok := w.columns[pgn]; ok {
+		return columns, true
+	}
+	definitions, ok := w.pgns[pgn]
+	if !ok {
+		return nil, false
+	}
+	columns := append(append([]string{}, AutoCSVMetaColumns...), CSVColumns(definitions)...)
+	w.columns[pgn] = columns
+	return columns, true
+}
+
+func (w *AutoCSVWriter) file(pgn uint32, columns []string, now time.Time) (*autoCSVFile, error) {
+	f, ok := w.files[pgn]
+	if ok && !w.needsRotation(f, now) {
+		return f, nil
+	}
+	if ok {
+		delete(w.files, pgn)
+		if err := f.file.Close(); err != nil {
+			return nil, fmt.Errorf("csv failed to close rotated file, err: %w", err)
+		}
+	}
+
+	start := now
+	var until time.Time
+	if w.config.RotateInterval > 0 {
+		start = now.Truncate(w.config.RotateInterval)
+		until = start.Add(w.config.RotateInterval)
+	}
+	file, err := w.createFile(pgn, start)
+	if err != nil {
+		return nil, err
+	}
+	counter := &countingFileWriter{file: file}
+	f = &autoCSVFile{file: file, counter: counter, writer: csv.NewWriter(counter), until: until}
+	if err := f.writer.Write(columns); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("csv failed to write header, err: %w", err)
+	}
+	w.files[pgn] = f
+	return f, nil
+}
+
+func (w *AutoCSVWriter) needsRotation(f *autoCSVFile, now time.Time) bool {
+	if w.config.MaxFileSize > 0 && f.counter.n >= w.config.MaxFileSize {
+		return true
+	}
+	return !f.until.IsZero() && !now.Before(f.until)
+}
+
+// createFile creates new file for PGN. When file with same name exists (i.e. size rotation within same second)
+// sequence number is added to name.
+func (w *AutoCSVWriter) createFile(pgn uint32, start time.Time) (*os.File, error) {
+	base := strconv.FormatUint(uint64(pgn), 10) + "_" + start.UTC().Format("20060102T150405")
+	name := base + ".csv"
+	for i := 1; ; i++ {
+		file, err := os.OpenFile(filepath.Join(w.config.Dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("csv failed to create file, err: %w", err)
+		}
+		name = base + "_" + strconv.Itoa(i) + ".csv"
+	}
+}
+
+// Close flushes and closes all open CSV files
+func (w *AutoCSVWriter) Close() error {
+	var err error
+	for _, f := range w.files {
+		f.writer.Flush()
+		if fErr := f.writer.Error(); fErr != nil && err == nil {
+			err = fErr
+		}
+		if cErr := f.file.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	w.files = map[uint32]*autoCSVFile{}
+	return err
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+var autoCSVSchema = canboat.CanboatSchema{
+	PGNs: canboat.PGNs{
+		{
+			PGN: 127250,
+			Fields: []canboat.Field{
+				{ID: "sid", Order: 1, FieldType: canboat.FieldTypeNumber},
+				{ID: "heading", Order: 2, FieldType: canboat.FieldTypeNumber},
+				{ID: "reserved", Order: 3, FieldType: canboat.FieldTypeReserved},
+			},
+		},
+		{
+			PGN:                          126464,
+			RepeatingFieldSet1Size:       1,
+			RepeatingFieldSet1StartField: 2,
+			Fields: []canboat.Field{
+				{ID: "functionCode", Order: 1, FieldType: canboat.FieldTypeLookup},
+				{ID: "pgn", Order: 2, FieldType: canboat.FieldTypeNumber},
+			},
+		},
+	},
+}
+
+func TestCSVColumns(t *testing.T) {
+	definitions := canboat.PGNs{
+		{PGN: 130824, Fields: []canboat.Field{
+			{ID: "manufacturerCode", Order: 1},
+			{ID: "spare", Order: 2, FieldType: canboat.FieldTypeSpare},
+			{ID: "key", Order: 3},
+		}},
+		{PGN: 130824, Fields: []canboat.Field{
+			{ID: "manufacturerCode", Order: 1},
+			{ID: "value", Order: 2},
+		}},
+	}
+	assert.Equal(t, []string{"manufacturerCode", "key", "value"}, CSVColumns(definitions))
+	assert.Equal(t, []string{"functionCode"}, CSVColumns(autoCSVSchema.PGNs[1:]))
+}
+
+func readDir(t *testing.T, dir string) map[string]string {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	result := map[string]string{}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		assert.NoError(t, err)
+		result[e.Name()] = string(b)
+	}
+	return result
+}
+
+func TestAutoCSVWriter_WriteMessage(t *testing.T) {
+	dir := t.TempDir()
+	w := NewAutoCSVWriter(AutoCSVConfig{Dir: dir, Schema: autoCSVSchema})
+
+	now := time.Unix(1665488842, 0)
+	messages := []nmea.Message{
+		{Header: nmea.CanBusHeader{PGN: 127250, Source: 1, Destination: 255, Priority: 2}, Fields: nmea.FieldValues{{ID: "heading", Value: 1.5}}},
+		{Header: nmea.CanBusHeader{PGN: 126464, Source: 2, Destination: 255}, Fields: nmea.FieldValues{{ID: "functionCode", Value: uint64(0)}}},
+		{Header: nmea.CanBusHeader{PGN: 65280, Source: 3}}, // not in schema
+		{Header: nmea.CanBusHeader{PGN: 127250, Source: 1, Destination: 255, Priority: 2}, Fields: nmea.FieldValues{{ID: "sid", Value: uint64(7)}, {ID: "heading", Value: 1.75}}},
+	}
+	for i, m := range messages {
+		err := w.WriteMessage(nmea.RawMessage{Time: now.Add(time.Duration(i) * time.Second)}, m)
+		assert.NoError(t, err)
+	}
+
+	// rows are flushed after every write
+	files := readDir(t, dir)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, map[string]string{
+		"127250_20221011T114722.csv": "_time_ms,_src,_dst,_prio,sid,heading\n" +
+			"1665488842000,1,255,2,,1.5\n" +
+			"1665488845000,1,255,2,7,1.75\n",
+		"126464_20221011T114723.csv": "_time_ms,_src,_dst,_prio,functionCode\n" +
+			"1665488843000,2,255,0,0\n",
+	}, files)
+}
+
+func TestAutoCSVWriter_rotation(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		config      AutoCSVConfig
+		expectFiles []string
+	}{
+		{
+			name:   "ok, rotate by size",
+			config: AutoCSVConfig{MaxFileSize: 60},
+			expectFiles: []string{
+				"127250_20221011T114722.csv",   // header + 1 row
+				"127250_20221011T114722_1.csv", // same second, gets sequence number
+				"127250_20221011T114723.csv",
+			},
+		},
+		{
+			name:   "ok, rotate by interval",
+			config: AutoCSVConfig{RotateInterval: time.Second},
+			expectFiles: []string{
+				"127250_20221011T114722.csv", // 2 rows
+				"127250_20221011T114723.csv",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.config.Dir = dir
+			tc.config.Schema = autoCSVSchema
+			w := NewAutoCSVWriter(tc.config)
+
+			now := time.Unix(1665488842, 0)
+			msg := nmea.Message{Header: nmea.CanBusHeader{PGN: 127250, Source: 1}, Fields: nmea.FieldValues{{ID: "heading", Value: 1.5}}}
+			for _, d := range []time.Duration{0, 500 * time.Millisecond, 1500 * time.Millisecond} {
+				assert.NoError(t, w.WriteMessage(nmea.RawMessage{Time: now.Add(d)}, msg))
+			}
+			assert.NoError(t, w.Close())
+
+			files := make([]string, 0)
+			for name := range readDir(t, dir) {
+				files = append(files, name)
+			}
+			sort.Strings(files)
+			expect := append([]string{}, tc.expectFiles...)
+			sort.Strings(expect)
+			assert.Equal(t, expect, files)
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Writer writes decoded messages to export output (i.e. JSON lines file, CSV files)
+type Writer interface {
+	// WriteMessage writes decoded message. Raw message is given for information that decoded message does not hold
+	// (i.e. message time).
+	WriteMessage(raw nmea.RawMessage, msg nmea.Message) error
+	// Close flushes buffered output and releases resources
+	Close() error
+}
+
+// Progress is state of export process
+type Progress struct {
+	// BytesRead is number of bytes read from input. Zero when Config.BytesRead is not set.
+	BytesRead int64 `json:"bytes_read"`
+	// TotalBytes is total size of input. Zero when unknown.
+	TotalBytes int64 `json:"total_bytes"`
+	// Messages is number of raw messages read
+	Messages uint64 `json:"messages"`
+	// Written is number of decoded messages written to outputs
+	Written uint64 `json:"written"`
+	// ReadErrors is number of errors reading raw messages (i.e. malformed messages)
+	ReadErrors uint64 `json:"read_errors"`
+	// DecodeErrors is number of messages that could not be decoded (i.e. unknown PGN)
+	DecodeErrors uint64 `json:"decode_errors"`
+	// Elapsed is time since export was started
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Percent returns export progress in percents. Returns -1 when total size of input is unknown.
+func (p Progress) Percent() float64 {
+	if p.TotalBytes <= 0 {
+		return -1
+	}
+	return float64(p.BytesRead) / float64(p.TotalBytes) * 100
+}
+
+// Config configures export process
+type Config struct {
+	// Decoder decodes raw messages. Decoder is shared by workers and must be safe for concurrent use (canboat.Decoder is).
+	Decoder nmea.MessageDecoder
+	// Writers are outputs where decoded messages are written to. Messages are written in the same order as they were read.
+	Writers []Writer
+
+	// Filter decides which raw messages are decoded and exported. Optional: nil means all messages are exported.
+	Filter func(raw nmea.RawMessage) bool
+
+	// Workers is number of goroutines decoding messages in parallel.
+	// Defaults to: runtime.NumCPU()
+	Workers int
+	// BatchSize is number of raw messages given to worker at once. Bigger batches reduce synchronization overhead.
+	// Defaults to: 512
+	BatchSize int
+	// MaxConsecutiveReadErrors is number of read errors in row after which export is aborted.
+	// Defaults to: 20
+	MaxConsecutiveReadErrors int
+
+	// BytesRead returns number of bytes read from input so far (see CountingReader). Optional.
+	BytesRead func() int64
+	// TotalBytes is total size of input (i.e. file size). Optional.
+	TotalBytes int64
+
+	// ProgressInterval is interval how often OnProgress is called.
+	// Defaults to: 1 second
+	ProgressInterval time.Duration
+	// OnProgress is called periodically during export and once when export ends. Optional.
+	OnProgress func(progress Progress)
+	// OnDecodeError is called for every message that failed to decode. Called from single goroutine. Optional.
+	OnDecodeError func(raw nmea.RawMessage, err error)
+
+	// now is used in tests to control time
+	now func() time.Time
+}
+
+type batch struct {
+	seq  uint64
+	raw  []nmea.RawMessage
+	msgs []nmea.Message
+	errs []error
+}
+
+// Export reads all messages from reader until io.EOF, decodes them with multiple workers and writes them to
+// configured writers. It is meant for processing large recorded captures offline - reading, decoding and writing
+// happen in parallel and only read order is preserved.
+//
+// Writers are closed when export ends. Returned progress contains final counts even when error is returned.
+func Export(ctx context.Context, reader nmea.RawMessageReader, config Config) (Progress, error) {
+	if config.Decoder == nil {
+		return Progress{}, errors.New("export requires decoder")
+	}
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 512
+	}
+	if config.MaxConsecutiveReadErrors <= 0 {
+		config.MaxConsecutiveReadErrors = 20
+	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = 1 * time.Second
+	}
+	if config.now == nil {
+		config.now = time.Now
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	e := &exporter{config: config, started: config.now()}
+
+	jobs := make(chan *batch, config.Workers)
+	results := make(chan *batch, config.Workers)
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		readErr = e.read(ctx, reader, jobs)
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range jobs {
+				e.decode(b)
+				results <- b
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	writeErr := e.write(results)
+	if writeErr != nil {
+		cancel()
+		for range results { // drain so reader and workers can finish
+		}
+	}
+
+	for _, w := range config.Writers {
+		if err := w.Close(); err != nil && writeErr == nil {
+			writeErr = fmt.Errorf("export failed to close writer: %w", err)
+		}
+	}
+
+	progress := e.progress()
+	if config.OnProgress != nil {
+		config.OnProgress(progress)
+	}
+	if writeErr != nil {
+		return progress, writeErr
+	}
+	return progress, readErr
+}
+
+type exporter struct {
+	config  Config
+	started time.Time
+
+	messages     atomic.Uint64
+	readErrors   atomic.Uint64
+	written      uint64
+	decodeErrors uint64
+}
+
+func (e *exporter) read(ctx context.Context, reader nmea.RawMessageReader, jobs chan<- *batch) error {
+	seq := uint64(0)
+	current := &batch{seq: seq, raw: make([]nmea.RawMessage, 0, e.config.BatchSize)}
+	consecutiveErrors := 0
+	for {
+		raw, err := reader.ReadRawMessage(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			e.readErrors.Add(1)
+			consecutiveErrors++
+			if consecutiveErrors >= e.config.MaxConsecutiveReadErrors {
+				return fmt.Errorf("export aborted after %v consecutive read errors: %w", consecutiveErrors, err)
+			}
+			continue
+		}
+		consecutiveErrors = 0
+		e.messages.Add(1)
+		if e.config.Filter != nil && !e.config.Filter(raw) {
+			continue
+		}
+
+		current.raw = append(current.raw, raw)
+		if len(current.raw) < e.config.BatchSize {
+			continue
+		}
+		select {
+		case jobs <- current:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		seq++
+		current = &batch{seq: seq, raw: make([]nmea.RawMessage, 0, e.config.BatchSize)}
+	}
+	if len(current.raw) > 0 {
+		select {
+		case jobs <- current:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (e *exporter) decode(b *batch) {
+	b.msgs = make([]nmea.Message, len(b.raw))
+	b.errs = make([]error, len(b.raw))
+	for i, raw := range b.raw {
+		b.msgs[i], b.errs[i] = e.config.Decoder.Decode(raw)
+	}
+}
+
+// write writes decoded batches to outputs in read order. Workers can finish batches in any order so batches that
+// arrive early wait in pending until all preceding batches are written.
+func (e *exporter) write(results <-chan *batch) error {
+	pending := map[uint64]*batch{}
+	next := uint64(0)
+	lastProgress := e.started
+	for b := range results {
+		pending[b.seq] = b
+		for {
+			current, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := e.writeBatch(current); err != nil {
+				return err
+			}
+		}
+
+		if e.config.OnProgress != nil {
+			now := e.config.now()
+			if now.Sub(lastProgress) >= e.config.ProgressInterval {
+				lastProgress = now
+				e.config.OnProgress(e.progress())
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) writeBatch(b *batch) error {
+	for i, raw := range b.raw {
+		if err := b.errs[i]; err != nil {
+			e.decodeErrors++
+			if e.config.OnDecodeError != nil {
+				e.config.OnDecodeError(raw, err)
+			}
+			continue
+		}
+		for _, w := range e.config.Writers {
+			if err := w.WriteMessage(raw, b.msgs[i]); err != nil {
+				return fmt.Errorf("export failed to write message: %w", err)
+			}
+		}
+		e.written++
+	}
+	return nil
+}
+
+// progress is called only from writing goroutine (or after it has finished)
+func (e *exporter) progress() Progress {
+	p := Progress{
+		TotalBytes:   e.config.TotalBytes,
+		Messages:     e.messages.Load(),
+		Written:      e.written,
+		ReadErrors:   e.readErrors.Load(),
+		DecodeErrors: e.decodeErrors,
+		Elapsed:      e.config.now().Sub(e.started),
+	}
+	if e.config.BytesRead != nil {
+		p.BytesRead = e.config.BytesRead()
+	}
+	return p
+}
+
+// CountingReader is io.Reader that counts bytes read through it. Used to report export progress for input files.
+type CountingReader struct {
+	reader io.Reader
+	count  atomic.Int64
+}
+
+// NewCountingReader creates new instance of CountingReader
+func NewCountingReader(reader io.Reader) *CountingReader {
+	return &CountingReader{reader: reader}
+}
+
+// Read reads from wrapped reader
+func (r *CountingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+// Write discards given bytes. Exists so CountingReader can be given to readers that require io.ReadWriter (i.e.
+// actisense.NewBinaryDevice) when reading from file.
+func (r *CountingReader) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Count returns number of bytes read so far
+func (r *CountingReader) Count() int64 {
+	return r.count.Load()
+}
@@ -0,0 +1,204 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sliceReader struct {
+	messages []nmea.RawMessage
+	errs     map[int]error
+	index    int
+}
+
+func (r *sliceReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if err, ok := r.errs[r.index]; ok {
+		delete(r.errs, r.index)
+		return nmea.RawMessage{}, err
+	}
+	if r.index >= len(r.messages) {
+		return nmea.RawMessage{}, io.EOF
+	}
+	msg := r.messages[r.index]
+	r.index++
+	return msg, nil
+}
+
+func (r *sliceReader) Initialize() error {
+	return nil
+}
+
+func (r *sliceReader) Close() error {
+	return nil
+}
+
+type decoderFunc func(raw nmea.RawMessage) (nmea.Message, error)
+
+func (f decoderFunc) Decode(raw nmea.RawMessage) (nmea.Message, error) {
+	return f(raw)
+}
+
+var errUnknownPGN = errors.New("unknown PGN")
+
+func testDecoder(raw nmea.RawMessage) (nmea.Message, error) {
+	if raw.Header.PGN == 1 {
+		return nmea.Message{}, errUnknownPGN
+	}
+	if raw.Header.Source%2 == 0 {
+		time.Sleep(100 * time.Microsecond) // make workers finish batches out of order
+	}
+	return nmea.Message{
+		Header: raw.Header,
+		Fields: nmea.FieldValues{{ID: "source", Value: uint64(raw.Header.Source)}},
+	}, nil
+}
+
+type memoryWriter struct {
+	messages []nmea.Message
+	err      error
+	closed   bool
+}
+
+func (w *memoryWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msg)
+	return nil
+}
+
+func (w *memoryWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func testMessages(count int) []nmea.RawMessage {
+	result := make([]nmea.RawMessage, 0, count)
+	for i := 0; i < count; i++ {
+		pgn := uint32(127250)
+		if i%10 == 9 {
+			pgn = 1
+		}
+		result = append(result, nmea.RawMessage{
+			Header: nmea.CanBusHeader{PGN: pgn, Source: uint8(i)},
+			Data:   []byte{byte(i)},
+		})
+	}
+	return result
+}
+
+func TestExport(t *testing.T) {
+	reader := &sliceReader{
+		messages: testMessages(100),
+		errs:     map[int]error{5: errors.New("malformed message")},
+	}
+	writer := &memoryWriter{}
+	var decodeErrors []error
+	var progresses []Progress
+
+	progress, err := Export(context.Background(), reader, Config{
+		Decoder:       decoderFunc(testDecoder),
+		Writers:       []Writer{writer},
+		Workers:       4,
+		BatchSize:     3,
+		BytesRead:     func() int64 { return 500 },
+		TotalBytes:    1000,
+		OnProgress:    func(p Progress) { progresses = append(progresses, p) },
+		OnDecodeError: func(raw nmea.RawMessage, err error) { decodeErrors = append(decodeErrors, err) },
+	})
+	assert.NoError(t, err)
+	assert.True(t, writer.closed)
+
+	assert.Equal(t, uint64(100), progress.Messages)
+	assert.Equal(t, uint64(90), progress.Written)
+	assert.Equal(t, uint64(10), progress.DecodeErrors)
+	assert.Equal(t, uint64(1), progress.ReadErrors)
+	assert.Equal(t, int64(500), progress.BytesRead)
+	assert.Equal(t, 50.0, progress.Percent())
+	assert.Len(t, decodeErrors, 10)
+	if assert.NotEmpty(t, progresses) {
+		assert.Equal(t, progress.Written, progresses[len(progresses)-1].Written)
+	}
+
+	// messages must be written in read order even when decoded in parallel
+	if assert.Len(t, writer.messages, 90) {
+		previous := -1
+		for _, m := range writer.messages {
+			assert.Greater(t, int(m.Header.Source), previous)
+			previous = int(m.Header.Source)
+		}
+	}
+}
+
+func TestExport_filter(t *testing.T) {
+	writer := &memoryWriter{}
+	progress, err := Export(context.Background(), &sliceReader{messages: testMessages(20)}, Config{
+		Decoder: decoderFunc(testDecoder),
+		Writers: []Writer{writer},
+		Filter: func(raw nmea.RawMessage) bool {
+			return raw.Header.Source < 5
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), progress.Messages)
+	assert.Equal(t, uint64(5), progress.Written)
+	assert.Len(t, writer.messages, 5)
+}
+
+func TestExport_writeError(t *testing.T) {
+	writer := &memoryWriter{err: errors.New("disk full")}
+	_, err := Export(context.Background(), &sliceReader{messages: testMessages(2000)}, Config{
+		Decoder:   decoderFunc(testDecoder),
+		Writers:   []Writer{writer},
+		BatchSize: 10,
+	})
+	assert.EqualError(t, err, "export failed to write message: disk full")
+	assert.True(t, writer.closed)
+}
+
+func TestExport_tooManyReadErrors(t *testing.T) {
+	reader := &failingReader{err: errors.New("malformed message")}
+	progress, err := Export(context.Background(), reader, Config{
+		Decoder:                  decoderFunc(testDecoder),
+		MaxConsecutiveReadErrors: 3,
+	})
+	assert.EqualError(t, err, "export aborted after 3 consecutive read errors: malformed message")
+	assert.Equal(t, uint64(3), progress.ReadErrors)
+}
+
+type failingReader struct {
+	sliceReader
+	err error
+}
+
+func (r *failingReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	return nmea.RawMessage{}, r.err
+}
+
+func TestExport_noDecoder(t *testing.T) {
+	_, err := Export(context.Background(), &sliceReader{}, Config{})
+	assert.EqualError(t, err, "export requires decoder")
+}
+
+func TestCountingReader(t *testing.T) {
+	r := NewCountingReader(strings.NewReader("hello world"))
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+	assert.Equal(t, int64(11), r.Count())
+
+	n, err := r.Write([]byte("xx"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestProgress_Percent(t *testing.T) {
+	assert.Equal(t, -1.0, Progress{BytesRead: 10}.Percent())
+	assert.Equal(t, 25.0, Progress{BytesRead: 10, TotalBytes: 40}.Percent())
+}
@@ -0,0 +1,178 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// JSONWriter writes decoded messages as JSON lines (one JSON object per line)
+type JSONWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+// NewJSONWriter creates new instance of JSONWriter. When writer implements io.Closer it is closed on Close.
+func NewJSONWriter(writer io.Writer) *JSONWriter {
+	buffered := bufio.NewWriterSize(writer, 64*1024)
+	w := &JSONWriter{
+		writer:  buffered,
+		encoder: json.NewEncoder(buffered),
+	}
+	if c, ok := writer.(io.Closer); ok {
+		w.closer = c
+	}
+	return w
+}
+
+// WriteMessage writes decoded message as single JSON line
+func (w *JSONWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	return w.encoder.Encode(msg)
+}
+
+// Close flushes buffered output and closes underlying writer
+func (w *JSONWriter) Close() error {
+	err := w.writer.Flush()
+	if w.closer != nil {
+		if cErr := w.closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// CSVMapping describes which fields of PGN are written to CSV file as columns.
+//
+// Besides PGN field IDs following special columns are supported: `_time` (unix seconds), `_time_ms` (unix
+// milliseconds), `_time_nano` (unix nanoseconds), `_src`, `_dst`, `_prio` and `_node_name`.
+type CSVMapping struct {
+	PGN    uint32   `json:"pgn"`
+	Fields []string `json:"fields"`
+	// File is name of the CSV file in output directory. Defaults to: `<pgn>.csv`
+	File string `json:"file,omitempty"`
+}
+
+// CSVWriter writes decoded messages into CSV files, one file per mapped PGN. Messages with PGNs that have no mapping
+// are skipped.
+type CSVWriter struct {
+	dir      string
+	mappings map[uint32]CSVMapping
+	files    map[uint32]*csvFile
+}
+
+type csvFile struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+// NewCSVWriter creates new instance of CSVWriter writing files into given directory. Existing files are truncated.
+func NewCSVWriter(dir string, mappings []CSVMapping) *CSVWriter {
+	m := make(map[uint32]CSVMapping, len(mappings))
+	for _, mapping := range mappings {
+		m[mapping.PGN] = mapping
+	}
+	return &CSVWriter{
+		dir:      dir,
+		mappings: m,
+		files:    map[uint32]*csvFile{},
+	}
+}
+
+// WriteMessage writes mapped fields of decoded message as CSV row
+func (w *CSVWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	mapping, ok := w.mappings[msg.Header.PGN]
+	if !ok {
+		return nil
+	}
+	f, err := w.file(mapping)
+	if err != nil {
+		return err
+	}
+	row := make([]string, 0, len(mapping.Fields))
+	for _, fID := range mapping.Fields {
+		row = append(row, csvValue(fID, raw, msg))
+	}
+	if err := f.writer.Write(row); err != nil {
+		return fmt.Errorf("csv failed to write row, err: %w", err)
+	}
+	return nil
+}
+
+func (w *CSVWriter) file(mapping CSVMapping) (*csvFile, error) {
+	if f, ok := w.files[mapping.PGN]; ok {
+		return f, nil
+	}
+	name := mapping.File
+	if name == "" {
+		name = strconv.FormatUint(uint64(mapping.PGN), 10) + ".csv"
+	}
+	file, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("csv failed to create file, err: %w", err)
+	}
+	f := &csvFile{file: file, writer: csv.NewWriter(file)}
+	if err := f.writer.Write(mapping.Fields); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("csv failed to write header, err: %w", err)
+	}
+	w.files[mapping.PGN] = f
+	return f, nil
+}
+
+// Close flushes and closes all created CSV files
+func (w *CSVWriter) Close() error {
+	var err error
+	for _, f := range w.files {
+		f.writer.Flush()
+		if fErr := f.writer.Error(); fErr != nil && err == nil {
+			err = fErr
+		}
+		if cErr := f.file.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	w.files = map[uint32]*csvFile{}
+	return err
+}
+
+func csvValue(fieldID string, raw nmea.RawMessage, msg nmea.Message) string {
+	switch fieldID {
+	case "_node_name":
+		return strconv.FormatUint(msg.NodeNAME, 10)
+	case "_time":
+		return strconv.FormatInt(raw.Time.Unix(), 10)
+	case "_time_ms":
+		return strconv.FormatInt(raw.Time.UnixMilli(), 10)
+	case "_time_nano":
+		return strconv.FormatInt(raw.Time.UnixNano(), 10)
+	case "_src":
+		return strconv.FormatInt(int64(msg.Header.Source), 10)
+	case "_dst":
+		return strconv.FormatInt(int64(msg.Header.Destination), 10)
+	case "_prio":
+		return strconv.FormatInt(int64(msg.Header.Priority), 10)
+	}
+	fv, ok := msg.Fields.FindByID(fieldID)
+	if !ok {
+		return ""
+	}
+	switch vv := fv.Value.(type) {
+	case string:
+		return vv
+	case []byte:
+		return string(vv)
+	}
+	ff, ok := fv.AsFloat64()
+	if !ok || math.IsInf(ff, 0) || math.IsNaN(ff) {
+		return ""
+	}
+	return fmt.Sprintf("%.8g", ff)
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONWriter_WriteMessage(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewJSONWriter(buf)
+
+	err := w.WriteMessage(nmea.RawMessage{}, nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+		Fields: nmea.FieldValues{{ID: "heading", Value: 1.5}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "", buf.String()) // buffered until close
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t,
+		`{"node_name":0,"header":{"pgn":127250,"priority":0,"source":1,"destination":0},"fields":[{"id":"heading","type":"float64","value":1.5}]}`+"\n",
+		buf.String(),
+	)
+}
+
+func TestCSVWriter_WriteMessage(t *testing.T) {
+	dir := t.TempDir()
+	w := NewCSVWriter(dir, []CSVMapping{
+		{PGN: 127250, Fields: []string{"_time_ms", "_src", "heading", "missing"}},
+		{PGN: 129026, Fields: []string{"_time", "sog"}, File: "cogsog.csv"},
+	})
+
+	now := time.Unix(1665488842, 0)
+	messages := []nmea.Message{
+		{Header: nmea.CanBusHeader{PGN: 127250, Source: 1}, Fields: nmea.FieldValues{{ID: "heading", Value: 1.5}}},
+		{Header: nmea.CanBusHeader{PGN: 129026, Source: 2}, Fields: nmea.FieldValues{{ID: "sog", Value: uint64(3)}}},
+		{Header: nmea.CanBusHeader{PGN: 127245, Source: 3}}, // not mapped
+		{Header: nmea.CanBusHeader{PGN: 127250, Source: 1}, Fields: nmea.FieldValues{{ID: "heading", Value: 1.75}}},
+	}
+	for i, m := range messages {
+		err := w.WriteMessage(nmea.RawMessage{Time: now.Add(time.Duration(i) * time.Second)}, m)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	b, err := os.ReadFile(filepath.Join(dir, "127250.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "_time_ms,_src,heading,missing\n1665488842000,1,1.5,\n1665488845000,1,1.75,\n", string(b))
+
+	b, err = os.ReadFile(filepath.Join(dir, "cogsog.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "_time,sog\n1665488843,3\n", string(b))
+
+	_, err = os.Stat(filepath.Join(dir, "127245.csv"))
+	assert.True(t, os.IsNotExist(err))
+}
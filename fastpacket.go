@@ -1,6 +1,7 @@
 package nmea
 
 import (
+	"errors"
 	"sync"
 	"time"
 )
@@ -9,6 +10,12 @@ type Assembler interface {
 	Assemble(frame RawFrame, to *RawMessage) bool
 }
 
+// Splitter splits message into frames so it can be transmitted by devices that send ordinary CAN frames
+// (i.e. SocketCAN, Actisense W2K-1 RAW ASCII format).
+type Splitter interface {
+	Split(msg RawMessage) ([]RawFrame, error)
+}
+
 type fastPacketSequence struct {
 	header CanBusHeader
 
@@ -49,13 +56,16 @@ func (m *fastPacketSequence) Append(frame RawFrame) bool {
 	if frameNr == 0 { // first frame initializes lengths ,so we know when sequence is complete
 		// very first frame 0th, has 2 bytes for metadata (3 bits sequence counter, 5bits frame counter, 8bits length)
 		// and 6 bytes actual data
+		if frame.Data[1] > FastRawPacketMaxSize {
+			return false // corrupt length, Assembler rejects these before appending
+		}
 		m.length = frame.Data[1]
 
-		frameCount := uint8(1)
-		if m.length > 6 { // fast packet data is multiple frames long
-			frameCount += (m.length - 6 + 7) / 7
+		frameCount := 1
+		if m.length > 6 { // fast packet data is multiple frames long, last frame can be partially filled
+			frameCount += (int(m.length) - 6 + 6) / 7
 		}
-		m.completeFramesMask = ^(0xFFFFFFFF << frameCount)
+		m.completeFramesMask = ^(uint32(0xFFFFFFFF) << frameCount)
 
 		copy(m.data[:6], frame.Data[2:])
 	} else { // subsequent frames, have 7 bytes of data, first byte is for sequence counter and frame counter
@@ -90,7 +100,8 @@ func (m *fastPacketSequence) To(to *RawMessage) {
 	if cap(to.Data) < int(m.length) {
 		to.Data = make([]byte, m.length)
 	}
-	copy(to.Data[:], m.data[0:m.length])
+	to.Data = to.Data[:m.length]
+	copy(to.Data, m.data[0:m.length])
 }
 
 func (m *fastPacketSequence) As() RawMessage {
@@ -104,25 +115,85 @@ func (m *fastPacketSequence) As() RawMessage {
 	}
 }
 
+// FastPacketAssemblerStats is statistics of FastPacketAssembler
+type FastPacketAssemblerStats struct {
+	// Frames is number of frames given to assembler
+	Frames uint64 `json:"frames"`
+	// Assembled is number of fast-packet messages assembled from frames
+	Assembled uint64 `json:"assembled"`
+	// Dropped is number of incomplete fast-packet sequences discarded because frames were missing (Expired + Restarted)
+	Dropped uint64 `json:"dropped"`
+	// Expired is number of incomplete sequences discarded because no frames were received within timeout
+	Expired uint64 `json:"expired"`
+	// Restarted is number of incomplete sequences discarded because first frame of new message with same sequence
+	// counter was received
+	Restarted uint64 `json:"restarted"`
+	// DuplicateFrames is number of frames ignored because sequence already had frame with same number
+	DuplicateFrames uint64 `json:"duplicate_frames"`
+	// InvalidFrames is number of fast-packet frames ignored because they were too short to be fast-packet frame or
+	// first frame had total length over FastRawPacketMaxSize
+	InvalidFrames uint64 `json:"invalid_frames"`
+	// InTransfer is number of currently incomplete sequences
+	InTransfer int `json:"in_transfer"`
+}
+
+// FastPacketAssemblerConfig is configuration for FastPacketAssembler
+type FastPacketAssemblerConfig struct {
+	// PGNs is list of PGNs that are transferred as Fast-Packet frames and should be assembled to messages
+	PGNs []uint32
+	// Timeout is maximum time between frames of the same sequence. Incomplete sequences that have not received frames
+	// within timeout are discarded.
+	// Optional: defaults to 750ms
+	Timeout time.Duration
+}
+
+// fastPacketKey identifies sequence. Different sources can send same PGN simultaneously and same source can have
+// frames of multiple messages of same PGN in transfer (distinguished by sequence counter).
+type fastPacketKey struct {
+	pgn      uint32
+	source   uint8
+	sequence uint8
+}
+
+// FastPacketAssembler assembles Fast-Packet frames to messages. Frames are tracked per PGN, source and sequence counter,
+// so frames from multiple sources and frames arriving out of order are assembled correctly. Sequences that do not
+// complete within timeout are discarded.
+//
+// FastPacketAssembler is safe for concurrent use.
 type FastPacketAssembler struct {
 	// pgns is list of PGNs that are transferred as Fast-Packet RawFrame and should be assembled to RawMessage
 	pgns       []uint32
-	inTransfer []*fastPacketSequence
+	timeout    time.Duration
+	inTransfer map[fastPacketKey]*fastPacketSequence
+	// lastGC is reference time when stale sequences were last removed
+	lastGC time.Time
+
+	stats FastPacketAssemblerStats
 
 	now  func() time.Time
 	pool *sync.Pool
 	lock sync.Mutex
 }
 
+// NewFastPacketAssembler creates new instance of FastPacketAssembler with default timeout
 func NewFastPacketAssembler(fpPGNs []uint32) *FastPacketAssembler {
+	return NewFastPacketAssemblerWithConfig(FastPacketAssemblerConfig{PGNs: fpPGNs})
+}
+
+// NewFastPacketAssemblerWithConfig creates new instance of FastPacketAssembler
+func NewFastPacketAssemblerWithConfig(config FastPacketAssemblerConfig) *FastPacketAssembler {
+	if config.Timeout <= 0 {
+		config.Timeout = 750 * time.Millisecond
+	}
 	pool := new(sync.Pool)
 	pool.New = func() any {
 		return &fastPacketSequence{}
 	}
 
 	return &FastPacketAssembler{
-		pgns:       append([]uint32{}, fpPGNs...),
-		inTransfer: make([]*fastPacketSequence, 0, 10),
+		pgns:       append([]uint32{}, config.PGNs...),
+		timeout:    config.Timeout,
+		inTransfer: make(map[fastPacketKey]*fastPacketSequence, 10),
 
 		now:  time.Now,
 		pool: pool,
@@ -133,6 +204,7 @@ func (a *FastPacketAssembler) Assemble(frame RawFrame, to *RawMessage) bool {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
+	a.stats.Frames++
 	isFastPacket := false
 	if couldBeFastPacket(frame.Header.PGN) {
 		for _, pgn := range a.pgns {
@@ -146,47 +218,205 @@ func (a *FastPacketAssembler) Assemble(frame RawFrame, to *RawMessage) bool {
 		if cap(to.Data) < int(frame.Length) {
 			to.Data = make([]byte, frame.Length)
 		}
-		copy(to.Data[:], frame.Data[0:frame.Length])
+		to.Data = to.Data[:frame.Length]
+		copy(to.Data, frame.Data[0:frame.Length])
 		to.Time = frame.Time
 		to.Header = frame.Header
 		return true
 	}
+	if frame.Length < 2 {
+		a.stats.InvalidFrames++
+		return false
+	}
 
 	// fast packet sequence is uniquely identified by: source+pgn+sequence+lastReceivedFrameTime
+	// frame time is used as reference so frames replayed from recordings (with original times) are assembled as well
+	reference := frame.Time
+	if reference.IsZero() {
+		reference = a.now()
+	}
+	threshold := reference.Add(-a.timeout)
+	a.removeStale(reference, threshold)
 
-	threshold := a.now().Add(-750 * time.Millisecond)
-	sequence := frame.Data[0] >> 5 // last 3 bits (sequence counter range is 0-7)
-
-	var fp *fastPacketSequence
-	idx := 0
-	for i, tmpFp := range a.inTransfer {
-		if tmpFp.header.Source != frame.Header.Source ||
-			tmpFp.header.PGN != frame.Header.PGN ||
-			tmpFp.sequence != sequence {
-			continue
+	key := fastPacketKey{
+		pgn:      frame.Header.PGN,
+		source:   frame.Header.Source,
+		sequence: frame.Data[0] >> 5, // last 3 bits (sequence counter range is 0-7)
+	}
+	fp, ok := a.inTransfer[key]
+	if frame.Data[0]&0b0001_1111 == 0 && frame.Data[1] > FastRawPacketMaxSize {
+		// first frame with corrupt total length can not be assembled. Frames received for that sequence are discarded.
+		a.stats.InvalidFrames++
+		if ok {
+			a.drop(fp)
+			delete(a.inTransfer, key)
+			a.pool.Put(fp)
 		}
-		fp = a.inTransfer[i]
-		idx = i
-		if fp.lastReceivedFrameTime.Before(threshold) { // sequence is too old to be this frame sequence
-			fp.Reset()
+		return false
+	}
+	if ok && fp.lastReceivedFrameTime.Before(threshold) { // sequence is too old to be this frame sequence
+		a.drop(fp)
+		a.stats.Expired++
+	} else if ok {
+		frameNr := frame.Data[0] & 0b0001_1111
+		if fp.receivedFramesMask&(1<<frameNr) != 0 {
+			if frameNr != 0 {
+				a.stats.DuplicateFrames++
+				return false
+			}
+			// first frame of new message with same sequence counter. previous message lost its frames
+			a.drop(fp)
+			a.stats.Restarted++
 		}
 	}
-	if fp == nil {
+	if !ok {
 		fp = a.pool.Get().(*fastPacketSequence)
 		fp.Reset()
-		a.inTransfer = append(a.inTransfer, fp)
-		idx = len(a.inTransfer) - 1
+		a.inTransfer[key] = fp
 	}
+
 	isComplete := fp.Append(frame)
 	if isComplete { // message is now complete
 		fp.To(to) // copy data over to rawMessage
+		a.stats.Assembled++
 
 		// remove item from in transfer list and put it back to pool
-		a.inTransfer[idx] = a.inTransfer[len(a.inTransfer)-1]
-		a.inTransfer = a.inTransfer[:len(a.inTransfer)-1]
+		delete(a.inTransfer, key)
 		a.pool.Put(fp)
-	} else {
-		a.inTransfer[idx] = fp
 	}
 	return isComplete
 }
+
+// drop discards frames of incomplete sequence. Sequence stays in transfer list.
+func (a *FastPacketAssembler) drop(fp *fastPacketSequence) {
+	if fp.receivedFramesMask != 0 {
+		a.stats.Dropped++
+	}
+	fp.Reset()
+}
+
+// removeStale removes sequences that have not received frames within timeout. Removal is done at most once per
+// timeout period so that assembling does not scan all sequences for every frame.
+func (a *FastPacketAssembler) removeStale(reference time.Time, threshold time.Time) {
+	if !a.lastGC.IsZero() && reference.Sub(a.lastGC) < a.timeout && !reference.Before(a.lastGC) {
+		return
+	}
+	a.lastGC = reference
+	for key, fp := range a.inTransfer {
+		if !fp.lastReceivedFrameTime.Before(threshold) {
+			continue
+		}
+		if fp.receivedFramesMask != 0 {
+			a.stats.Dropped++
+			a.stats.Expired++
+		}
+		delete(a.inTransfer, key)
+		a.pool.Put(fp)
+	}
+}
+
+// Stats returns statistics of assembler
+func (a *FastPacketAssembler) Stats() FastPacketAssemblerStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	stats := a.stats
+	stats.InTransfer = len(a.inTransfer)
+	return stats
+}
+
+// SplitFastPacket splits message into Fast-Packet frames. Sequence is message counter (0-7) that sender increments for
+// each message of that PGN so receivers can distinguish frames of simultaneously sent messages. Unused bytes of last
+// frame are filled with 0xFF.
+func SplitFastPacket(msg RawMessage, sequence uint8) ([]RawFrame, error) {
+	length := len(msg.Data)
+	if length > FastRawPacketMaxSize {
+		return nil, errors.New("message data is too long for fast packet")
+	}
+	counter := (sequence & 0b0000_0111) << 5
+
+	frameCount := 1
+	if length > 6 {
+		frameCount += (length - 6 + 6) / 7
+	}
+	frames := make([]RawFrame, 0, frameCount)
+
+	first := RawFrame{Time: msg.Time, Header: msg.Header, Length: 8}
+	first.Data = [8]byte{counter, uint8(length), 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	offset := copy(first.Data[2:], msg.Data)
+	frames = append(frames, first)
+
+	for frameNr := uint8(1); offset < length; frameNr++ {
+		frame := RawFrame{Time: msg.Time, Header: msg.Header, Length: 8}
+		frame.Data = [8]byte{counter | frameNr, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+		offset += copy(frame.Data[1:], msg.Data[offset:])
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+type fastPacketSplitKey struct {
+	pgn         uint32
+	source      uint8
+	destination uint8
+}
+
+// FastPacketSplitter splits messages into frames for transmitting. Messages with Fast-Packet PGNs and messages longer
+// than 8 bytes are split into Fast-Packet frames. Sequence counter is incremented for every split message of the same
+// PGN, source and destination so receivers can tell apart frames of consecutive messages. Other messages are sent as
+// single frame.
+//
+// FastPacketSplitter is safe for concurrent use.
+type FastPacketSplitter struct {
+	// pgns is list of PGNs that are always transferred as Fast-Packet frames (even when data fits into single frame)
+	pgns []uint32
+
+	lock      sync.Mutex
+	sequences map[fastPacketSplitKey]uint8
+}
+
+// NewFastPacketSplitter creates new instance of FastPacketSplitter
+func NewFastPacketSplitter(fpPGNs []uint32) *FastPacketSplitter {
+	return &FastPacketSplitter{
+		pgns:      append([]uint32{}, fpPGNs...),
+		sequences: map[fastPacketSplitKey]uint8{},
+	}
+}
+
+// Split splits message into frames
+func (s *FastPacketSplitter) Split(msg RawMessage) ([]RawFrame, error) {
+	if !s.isFastPacket(msg) {
+		frame := RawFrame{Time: msg.Time, Header: msg.Header, Length: uint8(len(msg.Data))}
+		copy(frame.Data[:], msg.Data)
+		return []RawFrame{frame}, nil
+	}
+	if len(msg.Data) > FastRawPacketMaxSize {
+		return nil, errors.New("message data is too long for fast packet")
+	}
+
+	key := fastPacketSplitKey{
+		pgn:         msg.Header.PGN,
+		source:      msg.Header.Source,
+		destination: msg.Header.Destination,
+	}
+	s.lock.Lock()
+	sequence := s.sequences[key]
+	s.sequences[key] = (sequence + 1) & 0b0000_0111
+	s.lock.Unlock()
+
+	return SplitFastPacket(msg, sequence)
+}
+
+func (s *FastPacketSplitter) isFastPacket(msg RawMessage) bool {
+	if len(msg.Data) > 8 {
+		return true
+	}
+	if !couldBeFastPacket(msg.Header.PGN) {
+		return false
+	}
+	for _, pgn := range s.pgns {
+		if pgn == msg.Header.PGN {
+			return true
+		}
+	}
+	return false
+}
@@ -367,3 +367,258 @@ func TestAssembleMaxSizeFastPacket(t *testing.T) {
 	assert.Equal(t, expected, msg)
 
 }
+
+func TestFastPacketAssembler_Assemble_replayedFrames(t *testing.T) {
+	fps := exampleFPS()
+	msg := fps.As() // frames have recorded time from past
+	frames, err := SplitFastPacket(msg, 3)
+	assert.NoError(t, err)
+
+	assembler := NewFastPacketAssembler([]uint32{130323}) // uses current time as now
+	result := RawMessage{}
+	for i, f := range frames {
+		f.Time = f.Time.Add(time.Duration(i) * 10 * time.Millisecond)
+		isComplete := assembler.Assemble(f, &result)
+		assert.Equal(t, i == len(frames)-1, isComplete)
+	}
+	assert.Equal(t, msg.Data, result.Data)
+
+	// gap between frames larger than 750ms discards started sequence
+	result = RawMessage{}
+	frames[1].Time = frames[0].Time.Add(time.Second)
+	for i, f := range frames {
+		if i > 1 {
+			f.Time = frames[1].Time
+		}
+		assert.False(t, assembler.Assemble(f, &result))
+	}
+	assert.Equal(t, FastPacketAssemblerStats{
+		Frames:     uint64(2 * len(frames)),
+		Assembled:  1,
+		Dropped:    1,
+		Expired:    1,
+		InTransfer: 1,
+	}, assembler.Stats())
+}
+
+func TestFastPacketAssembler_Assemble_reusesMessage(t *testing.T) {
+	fps := exampleFPS()
+	msg := fps.As()
+	frames, err := SplitFastPacket(msg, 3)
+	assert.NoError(t, err)
+
+	assembler := NewFastPacketAssembler([]uint32{130323})
+	result := RawMessage{Data: make([]byte, 0, FastRawPacketMaxSize)}
+	for _, f := range frames {
+		assembler.Assemble(f, &result)
+	}
+	assert.Equal(t, msg.Data, result.Data)
+
+	single := RawFrame{Header: CanBusHeader{PGN: 127250}, Length: 3, Data: [8]byte{1, 2, 3}}
+	assert.True(t, assembler.Assemble(single, &result))
+	assert.Equal(t, RawData{1, 2, 3}, result.Data)
+}
+
+func TestFastPacketAssembler_Assemble_interleavedSources(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	msgA := RawMessage{Time: now, Header: CanBusHeader{PGN: 130323, Source: 35, Destination: 255}, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	msgB := RawMessage{Time: now, Header: CanBusHeader{PGN: 130323, Source: 36, Destination: 255}, Data: []byte{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}}
+	framesA, _ := SplitFastPacket(msgA, 2)
+	framesB, _ := SplitFastPacket(msgB, 2) // same sequence counter from different source
+	framesA2, _ := SplitFastPacket(msgB, 3)
+
+	assembler := NewFastPacketAssembler([]uint32{130323})
+	result := RawMessage{}
+	assert.False(t, assembler.Assemble(framesA[0], &result))
+	assert.False(t, assembler.Assemble(framesB[0], &result))
+	assert.False(t, assembler.Assemble(framesA2[0], &result))
+
+	assert.True(t, assembler.Assemble(framesB[1], &result))
+	assert.Equal(t, msgB.Data, result.Data)
+	assert.Equal(t, uint8(36), result.Header.Source)
+
+	assert.True(t, assembler.Assemble(framesA[1], &result))
+	assert.Equal(t, msgA.Data, result.Data)
+	assert.Equal(t, uint8(35), result.Header.Source)
+
+	assert.Equal(t, 1, assembler.Stats().InTransfer)
+}
+
+func TestFastPacketAssembler_Assemble_failures(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	msg := RawMessage{Time: now, Header: CanBusHeader{PGN: 130323, Source: 35, Destination: 255}, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}}
+	frames, _ := SplitFastPacket(msg, 1)
+
+	assembler := NewFastPacketAssemblerWithConfig(FastPacketAssemblerConfig{
+		PGNs:    []uint32{130323},
+		Timeout: 100 * time.Millisecond,
+	})
+	result := RawMessage{}
+
+	assert.False(t, assembler.Assemble(frames[0], &result))
+	assert.False(t, assembler.Assemble(frames[1], &result))
+	assert.False(t, assembler.Assemble(frames[1], &result)) // duplicate
+	// first frame of next message with same sequence counter, previous message lost its last frame
+	assert.False(t, assembler.Assemble(frames[0], &result))
+	assert.False(t, assembler.Assemble(RawFrame{Time: now, Header: msg.Header, Length: 1}, &result))
+
+	// frame from other source after timeout removes stale sequence
+	other := frames[0]
+	other.Time = now.Add(150 * time.Millisecond)
+	other.Header.Source = 36
+	assert.False(t, assembler.Assemble(other, &result))
+
+	assert.Equal(t, FastPacketAssemblerStats{
+		Frames:          6,
+		Dropped:         2,
+		Expired:         1,
+		Restarted:       1,
+		DuplicateFrames: 1,
+		InvalidFrames:   1,
+		InTransfer:      1,
+	}, assembler.Stats())
+}
+
+func TestFastPacketAssembler_Assemble_invalidLength(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	header := CanBusHeader{PGN: 130323, Source: 35, Destination: 255}
+	assembler := NewFastPacketAssembler([]uint32{130323})
+	result := RawMessage{}
+
+	second := RawFrame{Time: now, Header: header, Length: 8, Data: [8]byte{0x21, 7, 8, 9, 10, 11, 12, 13}}
+	assert.False(t, assembler.Assemble(second, &result))
+	for _, length := range []uint8{224, 250, 255} {
+		first := RawFrame{Time: now, Header: header, Length: 8, Data: [8]byte{0x20, length, 1, 2, 3, 4, 5, 6}}
+		assert.False(t, assembler.Assemble(first, &result))
+	}
+	assert.False(t, assembler.Assemble(second, &result))
+
+	assert.Equal(t, FastPacketAssemblerStats{
+		Frames:        5,
+		Dropped:       1,
+		InvalidFrames: 3,
+		InTransfer:    1,
+	}, assembler.Stats())
+}
+
+func TestFastPacketAssembler_Assemble_lengths(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	for _, length := range []int{1, 6, 7, 13, 20, 216, 222, 223} {
+		data := make([]byte, length)
+		for i := range data {
+			data[i] = uint8(i)
+		}
+		msg := RawMessage{Time: now, Header: CanBusHeader{PGN: 130323, Source: 35, Destination: 255}, Data: data}
+		frames, err := SplitFastPacket(msg, 2)
+		assert.NoError(t, err)
+
+		assembler := NewFastPacketAssembler([]uint32{130323})
+		result := RawMessage{}
+		for i, frame := range frames {
+			assert.Equal(t, i == len(frames)-1, assembler.Assemble(frame, &result), "length %v, frame %v", length, i)
+		}
+		assert.Equal(t, msg, result, "length %v", length)
+	}
+}
+
+func TestSplitFastPacket(t *testing.T) {
+	fps := exampleFPS()
+	msg := fps.As()
+
+	frames, err := SplitFastPacket(msg, 3)
+	assert.NoError(t, err)
+
+	header := CanBusHeader{PGN: 130323, Priority: 6, Source: 35, Destination: 255}
+	now := test_test.UTCTime(1665488842)
+	expect := []RawFrame{
+		{Time: now, Header: header, Length: 8, Data: [8]byte{0x60, 0x1E, 0xF0, 0x30, 0x4B, 0x08, 0xAC, 0x02}},
+		{Time: now, Header: header, Length: 8, Data: [8]byte{0x61, 0x12, 0x8B, 0x01, 0xB3, 0x22, 0x34, 0x38}},
+		{Time: now, Header: header, Length: 8, Data: [8]byte{0x62, 0x59, 0x0D, 0xA4, 0x00, 0xF5, 0xC7, 0xFA}},
+		{Time: now, Header: header, Length: 8, Data: [8]byte{0x63, 0xFF, 0xFF, 0xF0, 0x03, 0x95, 0x6F, 0x02}},
+		{Time: now, Header: header, Length: 8, Data: [8]byte{0x64, 0x01, 0x02, 0x01, 0xFF, 0xFF, 0xFF, 0xFF}},
+	}
+	assert.Equal(t, expect, frames)
+
+	assembler := NewFastPacketAssembler([]uint32{130323})
+	assembler.now = func() time.Time { return now }
+	result := RawMessage{}
+	for i, f := range frames {
+		isComplete := assembler.Assemble(f, &result)
+		assert.Equal(t, i == len(frames)-1, isComplete)
+	}
+	assert.Equal(t, msg, result)
+}
+
+func TestSplitFastPacket_short(t *testing.T) {
+	frames, err := SplitFastPacket(RawMessage{Data: []byte{1, 2, 3, 4, 5, 6}}, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []RawFrame{
+		{Length: 8, Data: [8]byte{0x20, 0x06, 1, 2, 3, 4, 5, 6}},
+	}, frames)
+
+	frames, err = SplitFastPacket(RawMessage{Data: []byte{1, 2, 3, 4, 5, 6, 7}}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []RawFrame{
+		{Length: 8, Data: [8]byte{0x00, 0x07, 1, 2, 3, 4, 5, 6}},
+		{Length: 8, Data: [8]byte{0x01, 7, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	}, frames)
+}
+
+func TestSplitFastPacket_tooLong(t *testing.T) {
+	_, err := SplitFastPacket(RawMessage{Data: make([]byte, FastRawPacketMaxSize+1)}, 0)
+	assert.EqualError(t, err, "message data is too long for fast packet")
+
+	frames, err := SplitFastPacket(RawMessage{Data: make([]byte, FastRawPacketMaxSize)}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, frames, 32)
+}
+
+func TestFastPacketSplitter_Split(t *testing.T) {
+	splitter := NewFastPacketSplitter([]uint32{126996})
+	header := CanBusHeader{PGN: 129029, Priority: 3, Source: 10, Destination: 255}
+
+	for i := 0; i < 10; i++ {
+		frames, err := splitter.Split(RawMessage{Header: header, Data: make([]byte, 20)})
+		assert.NoError(t, err)
+		if assert.Len(t, frames, 3) {
+			expectSequence := uint8(i%8) << 5
+			for nr, f := range frames {
+				assert.Equal(t, expectSequence|uint8(nr), f.Data[0])
+				assert.Equal(t, header, f.Header)
+			}
+		}
+	}
+
+	// other destination has its own sequence counter
+	otherDst := header
+	otherDst.Destination = 20
+	frames, err := splitter.Split(RawMessage{Header: otherDst, Data: make([]byte, 20)})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(0), frames[0].Data[0])
+}
+
+func TestFastPacketSplitter_Split_singleFrame(t *testing.T) {
+	splitter := NewFastPacketSplitter([]uint32{126996})
+
+	frames, err := splitter.Split(RawMessage{
+		Header: CanBusHeader{PGN: 127251},
+		Data:   []byte{0x3a, 0x9c, 0x63},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []RawFrame{
+		{Header: CanBusHeader{PGN: 127251}, Length: 3, Data: [8]byte{0x3a, 0x9c, 0x63}},
+	}, frames)
+
+	// known fast-packet PGN is split even when data would fit into single frame
+	frames, err = splitter.Split(RawMessage{
+		Header: CanBusHeader{PGN: 126996},
+		Data:   []byte{1, 2, 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []RawFrame{
+		{Header: CanBusHeader{PGN: 126996}, Length: 8, Data: [8]byte{0x00, 0x03, 1, 2, 3, 0xFF, 0xFF, 0xFF}},
+	}, frames)
+
+	_, err = splitter.Split(RawMessage{Data: make([]byte, FastRawPacketMaxSize+1)})
+	assert.EqualError(t, err, "message data is too long for fast packet")
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 	"unicode/utf16"
 )
@@ -43,10 +46,183 @@ type FieldValue struct {
 	// * uint64,
 	// * []byte,
 	// * time.Duration,
+	// * nmea.TimeOfDay,
 	// * time.Time,
 	// * nmea.EnumValue,
 	// * [][]nmea.EnumValue <-- for repeating fieldsets/groups
 	Value interface{} `json:"value"`
+
+	// Unit is unit of Value (i.e. `m/s`, `kn`, `deg`). Set only when decoder is configured to annotate units
+	// (i.e. canboat.DecoderConfig.Units).
+	Unit string `json:"unit,omitempty"`
+
+	// Raw is part of message data that field value was decoded from. Set only when decoder is configured to include
+	// raw bits (i.e. canboat.DecoderConfig.IncludeRawBits).
+	Raw *FieldRaw `json:"raw,omitempty"`
+}
+
+// FieldRaw describes message data bits backing decoded field value
+type FieldRaw struct {
+	// BitOffset is offset of first field bit from start of message data
+	BitOffset uint32 `json:"bitOffset"`
+	// BitLength is number of bits field value was decoded from
+	BitLength uint32 `json:"bitLength"`
+	// Bytes are message data bytes containing field bits. First byte is byte at BitOffset/8 so field bits may start
+	// and end in the middle of first/last byte.
+	Bytes RawData `json:"bytes"`
+}
+
+// Types of field values in JSON representation of FieldValue
+const (
+	FieldValueTypeNull      = "null"
+	FieldValueTypeFloat64   = "float64"
+	FieldValueTypeInt64     = "int64"
+	FieldValueTypeUint64    = "uint64"
+	FieldValueTypeString    = "string"
+	FieldValueTypeBytes     = "bytes"       // value is base64 encoded string
+	FieldValueTypeDuration  = "duration"    // value is duration string (i.e. `1.5s`)
+	FieldValueTypeTimeOfDay = "time_of_day" // value is time of day string (i.e. `13:45:01.0005`)
+	FieldValueTypeTime      = "time"        // value is RFC3339 (ISO8601) time string with nanoseconds
+	FieldValueTypeEnum      = "enum"        // value is object `{"value":1,"code":"A"}`
+	FieldValueTypeEnums     = "enums"       // value is array of enum objects (bit lookups)
+	FieldValueTypeFieldSets = "fieldsets"   // value is array of arrays of field values (repeating field sets)
+	FieldValueTypeAny       = "any"         // value of other Go type, read back as generic JSON value
+)
+
+type fieldValueJSON struct {
+	ID    string          `json:"id"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+	Unit  string          `json:"unit,omitempty"`
+	Raw   *FieldRaw       `json:"raw,omitempty"`
+}
+
+type enumValueJSON struct {
+	Value uint32 `json:"value"`
+	Code  string `json:"code"`
+}
+
+// MarshalJSON marshals field value to canonical JSON `{"id":"speed","type":"float64","value":1.5}` where type
+// (FieldValueType* constants) describes Go type of value so it can be unmarshalled back to same type.
+func (f FieldValue) MarshalJSON() ([]byte, error) {
+	typ, value := fieldValueToJSON(f.Value)
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal field %v value: %w", f.ID, err)
+	}
+	return json.Marshal(fieldValueJSON{ID: f.ID, Type: typ, Value: b, Unit: f.Unit, Raw: f.Raw})
+}
+
+func fieldValueToJSON(value interface{}) (string, interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return FieldValueTypeNull, nil
+	case float64:
+		return FieldValueTypeFloat64, v
+	case int64:
+		return FieldValueTypeInt64, v
+	case uint64:
+		return FieldValueTypeUint64, v
+	case string:
+		return FieldValueTypeString, v
+	case []byte:
+		return FieldValueTypeBytes, v
+	case RawData:
+		return FieldValueTypeBytes, []byte(v)
+	case time.Duration:
+		return FieldValueTypeDuration, v.String()
+	case TimeOfDay:
+		return FieldValueTypeTimeOfDay, v.String()
+	case time.Time:
+		return FieldValueTypeTime, v.Format(time.RFC3339Nano)
+	case EnumValue:
+		return FieldValueTypeEnum, enumValueJSON{Value: v.Value, Code: v.Code}
+	case []EnumValue:
+		enums := make([]enumValueJSON, 0, len(v))
+		for _, e := range v {
+			enums = append(enums, enumValueJSON{Value: e.Value, Code: e.Code})
+		}
+		return FieldValueTypeEnums, enums
+	case [][]FieldValue:
+		return FieldValueTypeFieldSets, v
+	}
+	return FieldValueTypeAny, value
+}
+
+// UnmarshalJSON unmarshals field value from canonical JSON created by MarshalJSON. Value is converted to Go type
+// described by type. Values without type (older JSON) are unmarshalled as generic JSON values (numbers as float64).
+func (f *FieldValue) UnmarshalJSON(b []byte) error {
+	var tmp fieldValueJSON
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+	value, err := fieldValueFromJSON(tmp.Type, tmp.Value)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal field %v value: %w", tmp.ID, err)
+	}
+	*f = FieldValue{ID: tmp.ID, Value: value, Unit: tmp.Unit, Raw: tmp.Raw}
+	return nil
+}
+
+func fieldValueFromJSON(typ string, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	switch typ {
+	case FieldValueTypeFloat64:
+		var v float64
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeInt64:
+		var v int64
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeUint64:
+		var v uint64
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeString:
+		var v string
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeBytes:
+		var v []byte
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeDuration:
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return time.ParseDuration(v)
+	case FieldValueTypeTimeOfDay:
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return ParseTimeOfDay(v)
+	case FieldValueTypeTime:
+		var v time.Time
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeEnum:
+		var v enumValueJSON
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return EnumValue{Value: v.Value, Code: v.Code}, nil
+	case FieldValueTypeEnums:
+		var v []enumValueJSON
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		enums := make([]EnumValue, 0, len(v))
+		for _, e := range v {
+			enums = append(enums, EnumValue{Value: e.Value, Code: e.Code})
+		}
+		return enums, nil
+	case FieldValueTypeFieldSets:
+		var v [][]FieldValue
+		return v, json.Unmarshal(raw, &v)
+	case FieldValueTypeAny, "":
+		var v interface{}
+		return v, json.Unmarshal(raw, &v)
+	}
+	return nil, fmt.Errorf("unknown field value type: %v", typ)
 }
 
 // AsFloat64 converts value to float64 if it is possible.
@@ -60,6 +236,8 @@ func (f FieldValue) AsFloat64() (float64, bool) {
 		return float64(v), true
 	case time.Duration:
 		return float64(v), true
+	case TimeOfDay:
+		return float64(v), true
 	case time.Time:
 		return float64(v.UnixNano()), true
 	case EnumValue:
@@ -68,6 +246,73 @@ func (f FieldValue) AsFloat64() (float64, bool) {
 	return 0, false
 }
 
+// AsInt64 converts integer value to int64 if it is possible. Enum values are converted to their numeric value.
+func (f FieldValue) AsInt64() (int64, bool) {
+	switch v := f.Value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case EnumValue:
+		return int64(v.Value), true
+	}
+	return 0, false
+}
+
+// AsUint64 converts integer value to uint64 if it is possible. Enum values are converted to their numeric value.
+func (f FieldValue) AsUint64() (uint64, bool) {
+	switch v := f.Value.(type) {
+	case uint64:
+		return v, true
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case EnumValue:
+		return uint64(v.Value), true
+	}
+	return 0, false
+}
+
+// AsString returns string value. Enum values are converted to their code.
+func (f FieldValue) AsString() (string, bool) {
+	switch v := f.Value.(type) {
+	case string:
+		return v, true
+	case EnumValue:
+		return v.Code, true
+	}
+	return "", false
+}
+
+// AsDuration returns time.Duration value. TimeOfDay values are converted to duration since midnight.
+func (f FieldValue) AsDuration() (time.Duration, bool) {
+	switch v := f.Value.(type) {
+	case time.Duration:
+		return v, true
+	case TimeOfDay:
+		return v.Duration(), true
+	}
+	return 0, false
+}
+
+// AsTime returns time.Time value.
+func (f FieldValue) AsTime() (time.Time, bool) {
+	v, ok := f.Value.(time.Time)
+	return v, ok
+}
+
+// AsEnum returns EnumValue value (lookup field decoded with canboat.DecoderConfig.DecodeLookupsToEnumType).
+func (f FieldValue) AsEnum() (EnumValue, bool) {
+	v, ok := f.Value.(EnumValue)
+	return v, ok
+}
+
+// FindByID returns first field value with given ID
 func (fvs FieldValues) FindByID(ID string) (FieldValue, bool) {
 	for _, f := range fvs {
 		if f.ID == ID {
@@ -77,85 +322,91 @@ func (fvs FieldValues) FindByID(ID string) (FieldValue, bool) {
 	return FieldValue{}, false
 }
 
+// AsMap converts field values to map of field ID to value. Repeating field sets are converted to slice of maps.
+func (fvs FieldValues) AsMap() map[string]interface{} {
+	result := make(map[string]interface{}, len(fvs))
+	for _, f := range fvs {
+		if sets, ok := f.Value.([][]FieldValue); ok {
+			maps := make([]map[string]interface{}, 0, len(sets))
+			for _, set := range sets {
+				maps = append(maps, FieldValues(set).AsMap())
+			}
+			result[f.ID] = maps
+			continue
+		}
+		result[f.ID] = f.Value
+	}
+	return result
+}
+
 type RawData []byte
 
-func (d *RawData) DecodeBytes(bitOffset uint16, bitLength uint16, isVariableSize bool) ([]byte, uint16, error) {
+// DecodeBytes decodes bitLength bits starting from bitOffset to bytes. First decoded bit is the least significant bit
+// of the first result byte. When isVariableSize is true bit length is capped to the end of data. Returns bytes and number
+// of bits read.
+func (d *RawData) DecodeBytes(bitOffset uint32, bitLength uint32, isVariableSize bool) ([]byte, uint32, error) {
 	rawData := []byte(*d)
 
-	endByteIndex := (bitOffset + bitLength - 1) / 8
-	if int(endByteIndex) > len(rawData)-1 {
-		if isVariableSize { // variable length caps bit length to packet end so we can read shorter data
-			endByteIndex = uint16(len(rawData) - 1)
-			bitLength -= (bitOffset + bitLength) - uint16(len(rawData)*8)
-		} else {
+	if bitLength == 0 {
+		return []byte{}, 0, nil
+	}
+	dataBits := uint64(len(rawData)) * 8
+	if uint64(bitOffset)+uint64(bitLength) > dataBits {
+		if !isVariableSize || uint64(bitOffset) >= dataBits {
 			return nil, 0, fmt.Errorf("bitoffset is out of bounds of data")
 		}
+		// variable length caps bit length to packet end so we can read shorter data
+		bitLength = uint32(dataBits - uint64(bitOffset))
 	}
 
-	length := (bitLength + 7) / 8
-	result := make([]byte, length)
-
+	result := make([]byte, (bitLength+7)/8)
 	startByteIndex := bitOffset / 8
 	startBitIndex := bitOffset % 8
-	if startByteIndex == endByteIndex { // single byte, everything starts and ends at the same byte
-		result[0] = rawData[startByteIndex] >> startBitIndex
-		if unnecessaryBits := bitLength % 8; unnecessaryBits != 0 {
-			result[0] &= 0xFF >> (8 - unnecessaryBits)
-		}
-	} else if startBitIndex != 0 { // multibyte, we need to sift bits to get rid of unneeded leading bits
-		maskLeading := uint8(0xFF >> startBitIndex)
-
-		result[0] = rawData[startByteIndex] >> startBitIndex
-		remainingBits := int(bitLength) - int(startBitIndex)
-		for i := uint16(1); i <= length; i++ {
-			current := rawData[startByteIndex+i]
-			leadingAsTrailing := (current & maskLeading) << startBitIndex
-			result[i-1] |= leadingAsTrailing
-
-			remainingBits -= 8
-			if remainingBits > 0 {
-				result[i] = current >> startBitIndex
+	if startBitIndex == 0 { // starts exactly at byte border, copy whole bytes
+		copy(result, rawData[startByteIndex:])
+	} else { // we need to shift bits to get rid of unneeded leading bits. Data is processed byte at a time.
+		for i := range result {
+			index := startByteIndex + uint32(i)
+			result[i] = rawData[index] >> startBitIndex
+			if int(index)+1 < len(rawData) {
+				result[i] |= rawData[index+1] << (8 - startBitIndex)
 			}
 		}
-	} else { // multibyte, but starts exactly at byte border
-		copy(result, rawData[startByteIndex:endByteIndex+1])
-		unnecessaryBits := bitLength % 8
-		if unnecessaryBits != 0 {
-			result[len(result)-1] &= 0xFF >> (8 - unnecessaryBits)
-		}
+	}
+	if unnecessaryBits := bitLength % 8; unnecessaryBits != 0 {
+		result[len(result)-1] &= 0xFF >> (8 - unnecessaryBits)
 	}
 
 	return result, bitLength, nil
 }
 
-func (d *RawData) DecodeVariableUint(bitOffset uint16, bitLength uint16) (uint64, error) {
+func (d *RawData) DecodeVariableUint(bitOffset uint32, bitLength uint32) (uint64, error) {
 	return d.decodeVariableInt(bitOffset, bitLength, false)
 }
 
-func (d *RawData) DecodeVariableInt(bitOffset uint16, bitLength uint16) (int64, error) {
+func (d *RawData) DecodeVariableInt(bitOffset uint32, bitLength uint32) (int64, error) {
 	variableUInt, err := d.decodeVariableInt(bitOffset, bitLength, true)
 	return int64(variableUInt), err
 }
 
-func (d *RawData) decodeVariableInt(bitOffset uint16, bitLength uint16, signed bool) (uint64, error) {
+func (d *RawData) decodeVariableInt(bitOffset uint32, bitLength uint32, signed bool) (uint64, error) {
 	if bitLength > 64 {
 		return 0, fmt.Errorf("bit length larger than can be decoded")
 	}
-	startByteIndex := bitOffset / 8
-	endByteIndex := ((bitOffset + bitLength + 7) / 8) - 1
 	rawData := []byte(*d)
-	if int(endByteIndex) >= len(rawData) {
+	if bitLength == 0 || uint64(bitOffset)+uint64(bitLength) > uint64(len(rawData))*8 {
 		return 0, fmt.Errorf("bitoffset is out of bounds of data")
 	}
-
-	var result uint64
-
-	rawBytes := make([]byte, 8)
-	copy(rawBytes, rawData[startByteIndex:endByteIndex+1])
-	result = binary.LittleEndian.Uint64(rawBytes)
-
-	// in case we do not start of the byte then the rightmost bits are what interest us, and we clear leading bits off
-	result >>= bitOffset % 8
+	startByteIndex := bitOffset / 8
+	endByteIndex := (bitOffset+bitLength+7)/8 - 1
+
+	// value spans up to 9 bytes (64 bits not starting at byte border). In case we do not start of the byte then the
+	// rightmost bits of first byte are what interest us, and we clear leading bits off
+	shift := bitOffset % 8
+	result := uint64(rawData[startByteIndex]) >> shift
+	for i := uint32(1); startByteIndex+i <= endByteIndex; i++ {
+		result |= uint64(rawData[startByteIndex+i]) << (i*8 - shift)
+	}
 	mask := (^uint64(0)) >> (64 - bitLength)
 	// in case we do not end exactly at the end of last byte, clear those bits at the end
 	result = result & mask
@@ -185,26 +436,31 @@ func (d *RawData) decodeVariableInt(bitOffset uint16, bitLength uint16, signed b
 	return result, nil
 }
 
-func (d *RawData) DecodeTime(bitOffset uint16, bitLength uint16, resolution float64) (time.Duration, error) {
+func (d *RawData) DecodeTime(bitOffset uint32, bitLength uint32, resolution float64) (time.Duration, error) {
 	// From Canboat: Absolute times in NMEA2000 are expressed as seconds since midnight(in an undefined timezone)
 	rawSeconds, err := d.DecodeVariableUint(bitOffset, bitLength)
 	if err != nil {
 		return 0, err
 	}
 
-	result := time.Duration(uint64(float64(rawSeconds)*resolution)) * time.Second
-	if resolution < 1 { // we need to extract decimal parts as smaller than seconds units
-		// 1 / resolution => 1 / 0.001 => 1 second is 1000 units (millisecond)
-		unitsInSecond := uint64(1 / resolution)
-		fraction := rawSeconds % unitsInSecond
-		// convert fraction to nanoseconds and then add to result
-		result += time.Duration((uint64(time.Second) / unitsInSecond) * fraction)
+	if resolution == 0 {
+		resolution = 1
 	}
+	if resolution >= 1 {
+		return time.Duration(math.Round(float64(rawSeconds)*resolution)) * time.Second, nil
+	}
+	// we need to extract decimal parts as smaller than seconds units.
+	// 1 / resolution => 1 / 0.0001 => 1 second is 10000 units. Rounded as 1/0.0001 is 9999.999999999998 as float64
+	unitsInSecond := uint64(math.Round(1 / resolution))
+	result := time.Duration(rawSeconds/unitsInSecond) * time.Second
+	// convert fraction to nanoseconds and then add to result
+	fraction := rawSeconds % unitsInSecond
+	result += time.Duration(fraction * uint64(time.Second) / unitsInSecond)
 
 	return result, nil
 }
 
-func (d *RawData) DecodeStringFix(bitOffset uint16, bitLength uint16) (string, error) {
+func (d *RawData) DecodeStringFix(bitOffset uint32, bitLength uint32) (string, error) {
 	rawBytes, _, err := d.DecodeBytes(bitOffset, bitLength, false)
 	if err != nil {
 		return "", err
@@ -225,12 +481,12 @@ func (d *RawData) DecodeStringFix(bitOffset uint16, bitLength uint16) (string, e
 	return string(rawBytes[0:length]), nil
 }
 
-func (d *RawData) DecodeStringLAU(bitOffset uint16) (string, uint16, error) {
+func (d *RawData) DecodeStringLAU(bitOffset uint32) (string, uint32, error) {
 	headerBytes, _, err := d.DecodeBytes(bitOffset, 16, false)
 	if err != nil {
 		return "", 0, err
 	}
-	length := uint16(headerBytes[0])
+	length := uint32(headerBytes[0])
 	if length == 2 {
 		return "", 16, nil
 	} else if length < 2 {
@@ -287,11 +543,11 @@ func decodeUtf16(b []byte, order binary.ByteOrder) (string, error) {
 	return string(utf16.Decode(ints)), nil
 }
 
-func (d *RawData) DecodeStringLZ(bitOffset uint16, bitLength uint16) (string, uint16, error) {
+func (d *RawData) DecodeStringLZ(bitOffset uint32, bitLength uint32) (string, uint32, error) {
 	rawData := []byte(*d)
 	lengthByteIndex := bitOffset / 8
 
-	actualLength := uint16(rawData[lengthByteIndex])
+	actualLength := uint32(rawData[lengthByteIndex])
 	fieldLength := (bitLength + 7) / 8
 	if actualLength > fieldLength {
 		actualLength = fieldLength
@@ -306,7 +562,54 @@ func (d *RawData) DecodeStringLZ(bitOffset uint16, bitLength uint16) (string, ui
 	return string(rawBytes), readBits, nil
 }
 
-func (d *RawData) DecodeDate(bitOffset uint16, bitLength uint16) (time.Time, error) {
+// DecodeStringVar decodes varying length string (canboat STRING_VAR) starting at bitOffset. String length is
+// determined by first byte:
+// * 0x00 or 0x01 - empty string,
+// * 0x02 - start of text (STX), string ends with 0x01 (ETX) byte or at the end of data,
+// * >0x02 - length byte, length includes length byte itself.
+// Trailing 0x00 and 0xFF bytes are trimmed. Returns string and number of bits read.
+func (d *RawData) DecodeStringVar(bitOffset uint32) (string, uint32, error) {
+	first, _, err := d.DecodeBytes(bitOffset, 8, false)
+	if err != nil {
+		return "", 0, err
+	}
+	remainingBits := uint32(len(*d)*8) - bitOffset - 8
+	marker := first[0]
+	if marker < 0x02 || remainingBits == 0 { // empty string
+		return "", 8, nil
+	}
+	if marker == 0x02 { // STX ... ETX
+		rawBytes, _, err := d.DecodeBytes(bitOffset+8, remainingBits, true)
+		if err != nil {
+			return "", 0, err
+		}
+		end := bytes.IndexByte(rawBytes, 0x01)
+		if end == -1 {
+			return trimStringPadding(rawBytes), 8 + uint32(len(rawBytes))*8, nil
+		}
+		return trimStringPadding(rawBytes[0:end]), 8 + uint32(end+1)*8, nil
+	}
+	// length byte
+	length := uint32(marker-1) * 8
+	if length > remainingBits {
+		return "", 0, fmt.Errorf("string var length %v exceeds data", marker)
+	}
+	rawBytes, readBits, err := d.DecodeBytes(bitOffset+8, length, false)
+	if err != nil {
+		return "", 0, err
+	}
+	return trimStringPadding(rawBytes), readBits + 8, nil
+}
+
+func trimStringPadding(b []byte) string {
+	end := len(b)
+	for end > 0 && (b[end-1] == 0x0 || b[end-1] == 0xFF) {
+		end--
+	}
+	return string(b[0:end])
+}
+
+func (d *RawData) DecodeDate(bitOffset uint32, bitLength uint32) (time.Time, error) {
 	if bitLength != 16 {
 		return time.Time{}, fmt.Errorf("can only decode date with 16 bits")
 	}
@@ -328,7 +631,7 @@ func (d *RawData) DecodeDate(bitOffset uint16, bitLength uint16) (time.Time, err
 	return result, nil
 }
 
-func (d *RawData) DecodeDecimal(bitOffset uint16, bitLength uint16) (uint64, error) {
+func (d *RawData) DecodeDecimal(bitOffset uint32, bitLength uint32) (uint64, error) {
 	rawBytes, _, err := d.DecodeBytes(bitOffset, bitLength, false)
 	if err != nil {
 		return 0, err
@@ -359,7 +662,7 @@ func (d *RawData) DecodeDecimal(bitOffset uint16, bitLength uint16) (uint64, err
 	return result, nil
 }
 
-func (d *RawData) DecodeFloat(bitOffset uint16, bitLength uint16) (float64, error) {
+func (d *RawData) DecodeFloat(bitOffset uint32, bitLength uint32) (float64, error) {
 	if bitLength != 32 {
 		return 0.0, fmt.Errorf("can only decode float with 32 bits")
 	}
@@ -388,6 +691,64 @@ func (d *RawData) AsHex() string {
 	return hex.EncodeToString(*d)
 }
 
+// TimeOfDay is time since midnight with nanosecond precision. NMEA2000 expresses absolute times as seconds since
+// midnight (in undefined, usually UTC, timezone). Canboat TIME fields with that meaning (i.e. PGN 129029 `time`) are
+// decoded as TimeOfDay when canboat.DecoderConfig.DecodeTimeOfDay is set.
+type TimeOfDay time.Duration
+
+// Duration returns time since midnight as time.Duration
+func (t TimeOfDay) Duration() time.Duration {
+	return time.Duration(t)
+}
+
+// On returns time of day on the date of given time (in location of given time).
+func (t TimeOfDay) On(date time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, date.Location()).Add(time.Duration(t))
+}
+
+// String returns time of day as `15:04:05.0001` string. Fraction of second is omitted when it is zero and trailing zeros
+// of fraction are trimmed.
+func (t TimeOfDay) String() string {
+	d := time.Duration(t)
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	result := fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	if ns := d % time.Second; ns > 0 {
+		result += "." + strings.TrimRight(fmt.Sprintf("%09d", ns), "0")
+	}
+	return result
+}
+
+// ParseTimeOfDay parses time of day from `15:04:05.0001` string (format of TimeOfDay.String).
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time of day: %v", s)
+	}
+	secondsPart, fractionPart, hasFraction := strings.Cut(parts[2], ".")
+	if hasFraction && (fractionPart == "" || len(fractionPart) > 9) {
+		return 0, fmt.Errorf("invalid time of day fraction: %v", s)
+	}
+	var result time.Duration
+	for i, p := range []string{parts[0], parts[1], secondsPart} {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || len(p) != 2 {
+			return 0, fmt.Errorf("invalid time of day: %v", s)
+		}
+		result += time.Duration(n) * []time.Duration{time.Hour, time.Minute, time.Second}[i]
+	}
+	if hasFraction {
+		ns, err := strconv.ParseUint(fractionPart+strings.Repeat("0", 9-len(fractionPart)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time of day fraction: %v", s)
+		}
+		result += time.Duration(ns)
+	}
+	return TimeOfDay(result), nil
+}
+
 type EnumValue struct {
 	Value uint32
 	Code  string
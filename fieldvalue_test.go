@@ -1,6 +1,7 @@
 package nmea
 
 import (
+	"encoding/json"
 	test_test "github.com/aldas/go-nmea-client/test"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -71,12 +72,300 @@ func TestFieldValue_AsFloat64(t *testing.T) {
 	}
 }
 
+func TestFieldValue_AsInt64(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		given    interface{}
+		expect   int64
+		expectOK bool
+	}{
+		{name: "ok, INT64", given: int64(-12), expect: -12, expectOK: true},
+		{name: "ok, UINT64", given: uint64(12), expect: 12, expectOK: true},
+		{name: "ok, EnumValue", given: EnumValue{Value: 3, Code: "C"}, expect: 3, expectOK: true},
+		{name: "nok, UINT64 overflows", given: uint64(1 << 63), expectOK: false},
+		{name: "nok, FLOAT64", given: 1.5, expectOK: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := FieldValue{ID: "x", Value: tc.given}.AsInt64()
+
+			assert.Equal(t, tc.expectOK, ok)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestFieldValue_AsUint64(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		given    interface{}
+		expect   uint64
+		expectOK bool
+	}{
+		{name: "ok, UINT64", given: uint64(12), expect: 12, expectOK: true},
+		{name: "ok, INT64", given: int64(12), expect: 12, expectOK: true},
+		{name: "ok, EnumValue", given: EnumValue{Value: 3, Code: "C"}, expect: 3, expectOK: true},
+		{name: "nok, negative INT64", given: int64(-1), expectOK: false},
+		{name: "nok, STRING", given: "1", expectOK: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := FieldValue{ID: "x", Value: tc.given}.AsUint64()
+
+			assert.Equal(t, tc.expectOK, ok)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestFieldValue_typedGetters(t *testing.T) {
+	now := test_test.UTCTime(1668428165)
+
+	s, ok := FieldValue{Value: "hi"}.AsString()
+	assert.True(t, ok)
+	assert.Equal(t, "hi", s)
+	s, ok = FieldValue{Value: EnumValue{Value: 1, Code: "A"}}.AsString()
+	assert.True(t, ok)
+	assert.Equal(t, "A", s)
+	_, ok = FieldValue{Value: 1.0}.AsString()
+	assert.False(t, ok)
+
+	d, ok := FieldValue{Value: 2 * time.Second}.AsDuration()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+	_, ok = FieldValue{Value: int64(2)}.AsDuration()
+	assert.False(t, ok)
+
+	tm, ok := FieldValue{Value: now}.AsTime()
+	assert.True(t, ok)
+	assert.Equal(t, now, tm)
+	_, ok = FieldValue{Value: "x"}.AsTime()
+	assert.False(t, ok)
+
+	e, ok := FieldValue{Value: EnumValue{Value: 1, Code: "A"}}.AsEnum()
+	assert.True(t, ok)
+	assert.Equal(t, EnumValue{Value: 1, Code: "A"}, e)
+	_, ok = FieldValue{Value: uint64(1)}.AsEnum()
+	assert.False(t, ok)
+}
+
+func TestFieldValues_AsMap(t *testing.T) {
+	given := FieldValues{
+		{ID: "sid", Value: uint64(1)},
+		{ID: "mode", Value: EnumValue{Value: 1, Code: "A"}},
+		{ID: "stations", Value: [][]FieldValue{
+			{{ID: "id", Value: uint64(10)}},
+			{{ID: "id", Value: uint64(11)}},
+		}},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"sid":  uint64(1),
+		"mode": EnumValue{Value: 1, Code: "A"},
+		"stations": []map[string]interface{}{
+			{"id": uint64(10)},
+			{"id": uint64(11)},
+		},
+	}, given.AsMap())
+}
+
+func TestFieldValue_MarshalJSON(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		given  FieldValue
+		expect string
+	}{
+		{
+			name:   "ok, without raw",
+			given:  FieldValue{ID: "speed", Value: 1.5},
+			expect: `{"id":"speed","type":"float64","value":1.5}`,
+		},
+		{
+			name: "ok, with raw",
+			given: FieldValue{
+				ID:    "speed",
+				Value: 1.5,
+				Raw:   &FieldRaw{BitOffset: 12, BitLength: 16, Bytes: RawData{0x12, 0x34, 0x56}},
+			},
+			expect: `{"id":"speed","type":"float64","value":1.5,"raw":{"bitOffset":12,"bitLength":16,"bytes":"EjRW"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := json.Marshal(tc.given)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, string(result))
+		})
+	}
+}
+
+func TestMessage_JSONRoundTrip(t *testing.T) {
+	given := Message{
+		NodeNAME: 1234,
+		Header:   CanBusHeader{PGN: 129029, Priority: 3, Source: 1, Destination: 255},
+		Fields: FieldValues{
+			{ID: "latitude", Value: 59.123, Unit: "deg"},
+			{ID: "offset", Value: int64(-3)},
+			{ID: "sid", Value: uint64(18446744073709551000)},
+			{ID: "name", Value: "GPS"},
+			{ID: "data", Value: []byte{0x01, 0x02}, Raw: &FieldRaw{BitOffset: 8, BitLength: 16, Bytes: RawData{0x01, 0x02}}},
+			{ID: "age", Value: 1500 * time.Millisecond},
+			{ID: "timeOfDay", Value: TimeOfDay(13*time.Hour + 39*time.Minute + 17*time.Second + 721500*time.Microsecond)},
+			{ID: "time", Value: time.Date(2022, 10, 11, 11, 47, 22, 123456789, time.UTC)},
+			{ID: "method", Value: EnumValue{Value: 1, Code: "GNSS fix"}},
+			{ID: "flags", Value: []EnumValue{{Value: 0, Code: "A"}, {Value: 3, Code: "D"}}},
+			{ID: "stations", Value: [][]FieldValue{{{ID: "id", Value: uint64(10)}}}},
+			{ID: "empty", Value: nil},
+		},
+	}
+
+	b, err := json.Marshal(given)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `{"id":"age","type":"duration","value":"1.5s"}`)
+	assert.Contains(t, string(b), `{"id":"timeOfDay","type":"time_of_day","value":"13:39:17.7215"}`)
+	assert.Contains(t, string(b), `{"id":"time","type":"time","value":"2022-10-11T11:47:22.123456789Z"}`)
+	assert.Contains(t, string(b), `{"id":"method","type":"enum","value":{"value":1,"code":"GNSS fix"}}`)
+
+	var result Message
+	assert.NoError(t, json.Unmarshal(b, &result))
+	assert.Equal(t, given, result)
+}
+
+func TestTimeOfDay_String(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		given  TimeOfDay
+		expect string
+	}{
+		{
+			name:   "ok, midnight",
+			given:  0,
+			expect: "00:00:00",
+		},
+		{
+			name:   "ok, with fraction",
+			given:  TimeOfDay(13*time.Hour + 39*time.Minute + 17*time.Second + 721500*time.Microsecond),
+			expect: "13:39:17.7215",
+		},
+		{
+			name:   "ok, nanoseconds",
+			given:  TimeOfDay(23*time.Hour + 59*time.Minute + 59*time.Second + 999999999),
+			expect: "23:59:59.999999999",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.given.String())
+		})
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		given       string
+		expect      TimeOfDay
+		expectError string
+	}{
+		{
+			name:   "ok",
+			given:  "13:39:17",
+			expect: TimeOfDay(13*time.Hour + 39*time.Minute + 17*time.Second),
+		},
+		{
+			name:   "ok, with fraction",
+			given:  "13:39:17.7215",
+			expect: TimeOfDay(13*time.Hour + 39*time.Minute + 17*time.Second + 721500*time.Microsecond),
+		},
+		{
+			name:        "nok, missing seconds",
+			given:       "13:39",
+			expectError: "invalid time of day: 13:39",
+		},
+		{
+			name:        "nok, invalid fraction",
+			given:       "13:39:17.",
+			expectError: "invalid time of day fraction: 13:39:17.",
+		},
+		{
+			name:        "nok, not a number",
+			given:       "13:xx:17",
+			expectError: "invalid time of day: 13:xx:17",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseTimeOfDay(tc.given)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTimeOfDay_On(t *testing.T) {
+	given := TimeOfDay(13*time.Hour + 39*time.Minute + 17*time.Second)
+
+	result := given.On(time.Date(2022, 10, 11, 23, 10, 0, 0, time.UTC))
+
+	assert.Equal(t, time.Date(2022, 10, 11, 13, 39, 17, 0, time.UTC), result)
+}
+
+func TestFieldValue_UnmarshalJSON(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		given       string
+		expect      FieldValue
+		expectError string
+	}{
+		{
+			name:   "ok, value without type",
+			given:  `{"id":"heading","value":1}`,
+			expect: FieldValue{ID: "heading", Value: float64(1)},
+		},
+		{
+			name:   "ok, any",
+			given:  `{"id":"x","type":"any","value":{"a":true}}`,
+			expect: FieldValue{ID: "x", Value: map[string]interface{}{"a": true}},
+		},
+		{
+			name:        "nok, unknown type",
+			given:       `{"id":"x","type":"complex128","value":1}`,
+			expectError: "failed to unmarshal field x value: unknown field value type: complex128",
+		},
+		{
+			name:        "nok, value does not match type",
+			given:       `{"id":"x","type":"duration","value":"1 second"}`,
+			expectError: `failed to unmarshal field x value: time: unknown unit " second" in duration "1 second"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result FieldValue
+			err := json.Unmarshal([]byte(tc.given), &result)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestRawData_DecodeVariableUint(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		given         []byte
-		whenBitOffset uint16
-		whenBitLength uint16
+		whenBitOffset uint32
+		whenBitLength uint32
 		expect        uint64
 		expectError   string
 	}{
@@ -87,6 +376,27 @@ func TestRawData_DecodeVariableUint(t *testing.T) {
 			whenBitLength: 16,
 			expect:        1,
 		},
+		{
+			name:          "decode unsigned 64bit value not starting at byte border",
+			given:         []byte{0x1F, 0x21, 0x43, 0x65, 0x87, 0xA9, 0xCB, 0xED, 0x0F, 0xFF},
+			whenBitOffset: 4,
+			whenBitLength: 64,
+			expect:        0xFEDCBA9876543211,
+		},
+		{
+			name:          "decode unsigned 16bit value beyond uint16 bit offset",
+			given:         append(make([]byte, 9000), 0x34, 0x12),
+			whenBitOffset: 72000,
+			whenBitLength: 16,
+			expect:        0x1234,
+		},
+		{
+			name:          "nok, 64bit value not starting at byte border exceeds data",
+			given:         []byte{0x1F, 0x21, 0x43, 0x65, 0x87, 0xA9, 0xCB, 0xED},
+			whenBitOffset: 4,
+			whenBitLength: 64,
+			expectError:   "bitoffset is out of bounds of data",
+		},
 		{
 			name:          "decode unsigned 3bit value",
 			given:         []byte{0xFF, 0b1001_1111, 0xFF, 0xFF},
@@ -167,8 +477,8 @@ func TestRawData_DecodeVariableInt(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		given         []byte
-		whenBitOffset uint16
-		whenBitLength uint16
+		whenBitOffset uint32
+		whenBitLength uint32
 		expect        int64
 		expectError   string
 	}{
@@ -245,11 +555,11 @@ func TestRawData_DecodeBytes(t *testing.T) {
 	var testCases = []struct {
 		name                 string
 		given                []byte
-		whenBitOffset        uint16
-		whenBitLength        uint16
+		whenBitOffset        uint32
+		whenBitLength        uint32
 		whenIsVariableLength bool
 		expect               []byte
-		expectReadBits       uint16
+		expectReadBits       uint32
 		expectError          string
 	}{
 		{
@@ -324,6 +634,30 @@ func TestRawData_DecodeBytes(t *testing.T) {
 			expect:         []byte{0x21, 0x03}, // 0010_0001 0000_0011
 			expectReadBits: 12,
 		},
+		{
+			name:           "decode 12bits not starting at byte border ends at the end of data",
+			given:          []byte{0x1F, 0x32},
+			whenBitOffset:  4,
+			whenBitLength:  12,
+			expect:         []byte{0x21, 0x03},
+			expectReadBits: 12,
+		},
+		{
+			name:                 "decode variable length till the end of data not starting at byte border",
+			given:                []byte{0x1F, 0x32, 0x54},
+			whenBitOffset:        4,
+			whenBitLength:        200,
+			whenIsVariableLength: true,
+			expect:               []byte{0x21, 0x43, 0x05},
+			expectReadBits:       20,
+		},
+		{
+			name:          "nok, offset out of bounds of data",
+			given:         []byte{0x1F, 0x32},
+			whenBitOffset: 16,
+			whenBitLength: 8,
+			expectError:   "bitoffset is out of bounds of data",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -342,12 +676,31 @@ func TestRawData_DecodeBytes(t *testing.T) {
 	}
 }
 
+func TestRawData_DecodeBytes_isoTPPayload(t *testing.T) {
+	given := make(RawData, 1785) // largest ISO TP payload
+	for i := range given {
+		given[i] = byte(i)
+	}
+
+	result, bits, err := given.DecodeBytes(3, 1784*8, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1784*8), bits)
+	assert.Len(t, result, 1784)
+	for i, b := range result {
+		expect := given[i]>>3 | given[i+1]<<5
+		if !assert.Equal(t, expect, b, "byte %v", i) {
+			break
+		}
+	}
+}
+
 func TestRawData_DecodeTime(t *testing.T) {
 	var testCases = []struct {
 		name           string
 		given          []byte
-		whenBitOffset  uint16
-		whenBitLength  uint16
+		whenBitOffset  uint32
+		whenBitLength  uint32
 		whenResolution float64
 		expect         time.Duration
 		expectError    string
@@ -368,6 +721,14 @@ func TestRawData_DecodeTime(t *testing.T) {
 			whenResolution: 0.001,
 			expect:         1*time.Minute + 1*time.Millisecond, // 00:01:00.001 // 61EA = 60001
 		},
+		{
+			name:           "decode time of day with resolution = 0.0001",
+			given:          []byte{0x7f, 0xdf, 0x4c, 0x1d}, // 0x1D4CDF7F = 491577215
+			whenBitOffset:  0,
+			whenBitLength:  32,
+			whenResolution: 0.0001,
+			expect:         13*time.Hour + 39*time.Minute + 17*time.Second + 721500*time.Microsecond, // 13:39:17.7215
+		},
 	}
 
 	for _, tc := range testCases {
@@ -389,8 +750,8 @@ func TestRawData_DecodeStringFix(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		given         []byte
-		whenBitOffset uint16
-		whenBitLength uint16
+		whenBitOffset uint32
+		whenBitLength uint32
 		expect        string
 		expectError   string
 	}{
@@ -465,9 +826,9 @@ func TestRawData_DecodeStringLAU(t *testing.T) {
 	var testCases = []struct {
 		name           string
 		given          []byte
-		whenBitOffset  uint16
+		whenBitOffset  uint32
 		expect         string
-		expectReadBits uint16
+		expectReadBits uint32
 		expectError    string
 	}{
 		{
@@ -539,14 +900,102 @@ func TestRawData_DecodeStringLAU(t *testing.T) {
 	}
 }
 
+func TestRawData_DecodeStringVar(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		given          []byte
+		whenBitOffset  uint32
+		expect         string
+		expectReadBits uint32
+		expectError    string
+	}{
+		{
+			name:           "ok, length byte",
+			given:          []byte{0xFF, 0x06, 'H', 'e', 'l', 'l', 'o', 0x33},
+			whenBitOffset:  8,
+			expect:         "Hello",
+			expectReadBits: 48, // length byte + 5 characters
+		},
+		{
+			name:           "ok, length byte with trailing padding",
+			given:          []byte{0x06, 'H', 'i', 0x00, 0xFF, 0xFF},
+			whenBitOffset:  0,
+			expect:         "Hi",
+			expectReadBits: 48,
+		},
+		{
+			name:           "ok, STX and ETX",
+			given:          []byte{0x02, 'H', 'e', 'l', 'l', 'o', 0x01, 0x33},
+			whenBitOffset:  0,
+			expect:         "Hello",
+			expectReadBits: 56, // STX + 5 characters + ETX
+		},
+		{
+			name:           "ok, STX without ETX ends at data end",
+			given:          []byte{0xFF, 0x02, 'H', 'i'},
+			whenBitOffset:  8,
+			expect:         "Hi",
+			expectReadBits: 24,
+		},
+		{
+			name:           "ok, empty string 0x01",
+			given:          []byte{0x01, 0x33},
+			whenBitOffset:  0,
+			expect:         "",
+			expectReadBits: 8,
+		},
+		{
+			name:           "ok, empty string 0x00",
+			given:          []byte{0x00},
+			whenBitOffset:  0,
+			expect:         "",
+			expectReadBits: 8,
+		},
+		{
+			name:           "ok, empty STX ETX",
+			given:          []byte{0x02, 0x01},
+			whenBitOffset:  0,
+			expect:         "",
+			expectReadBits: 16,
+		},
+		{
+			name:          "nok, length exceeds data",
+			given:         []byte{0x08, 'H', 'i'},
+			whenBitOffset: 0,
+			expectError:   "string var length 8 exceeds data",
+		},
+		{
+			name:          "nok, offset out of data",
+			given:         []byte{0x08},
+			whenBitOffset: 8,
+			expectError:   "bitoffset is out of bounds of data",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rd := RawData(tc.given)
+			result, readBits, err := rd.DecodeStringVar(tc.whenBitOffset)
+
+			assert.Equal(t, tc.expectReadBits, readBits)
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestRawData_DecodeStringLZ(t *testing.T) {
 	var testCases = []struct {
 		name           string
 		given          []byte
-		whenBitOffset  uint16
-		whenBitLength  uint16
+		whenBitOffset  uint32
+		whenBitLength  uint32
 		expect         string
-		expectReadBits uint16
+		expectReadBits uint32
 		expectError    string
 	}{
 		{
@@ -607,8 +1056,8 @@ func TestRawData_DecodeDate(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		given         []byte
-		whenBitOffset uint16
-		whenBitLength uint16
+		whenBitOffset uint32
+		whenBitLength uint32
 		expect        time.Time
 		expectError   string
 	}{
@@ -691,8 +1140,8 @@ func TestRawData_DecodeDecimal(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		given         []byte
-		whenBitOffset uint16
-		whenBitLength uint16
+		whenBitOffset uint32
+		whenBitLength uint32
 		expect        uint64
 		expectError   string
 	}{
@@ -755,8 +1204,8 @@ func TestRawData_DecodeFloat(t *testing.T) {
 	var testCases = []struct {
 		name          string
 		given         []byte
-		whenBitOffset uint16
-		whenBitLength uint16
+		whenBitOffset uint32
+		whenBitLength uint32
 		expect        float64
 		expectError   string
 	}{
@@ -807,3 +1256,50 @@ func TestRawData_DecodeFloat(t *testing.T) {
 		})
 	}
 }
+
+func TestMessage_fieldAccessors(t *testing.T) {
+	now := test_test.UTCTime(1668428165)
+	msg := Message{
+		Fields: FieldValues{
+			{ID: "latitude", Value: 59.123},
+			{ID: "sid", Value: uint64(7)},
+			{ID: "offset", Value: int64(-3)},
+			{ID: "name", Value: "GPS"},
+			{ID: "age", Value: 2 * time.Second},
+			{ID: "time", Value: now},
+			{ID: "method", Value: EnumValue{Value: 1, Code: "GNSS fix"}},
+		},
+	}
+
+	lat, ok := msg.Float64("latitude")
+	assert.True(t, ok)
+	assert.Equal(t, 59.123, lat)
+	_, ok = msg.Float64("longitude")
+	assert.False(t, ok)
+
+	sid, ok := msg.Uint64("sid")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), sid)
+
+	offset, ok := msg.Int64("offset")
+	assert.True(t, ok)
+	assert.Equal(t, int64(-3), offset)
+
+	name, ok := msg.String("name")
+	assert.True(t, ok)
+	assert.Equal(t, "GPS", name)
+
+	age, ok := msg.Duration("age")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, age)
+
+	tm, ok := msg.Time("time")
+	assert.True(t, ok)
+	assert.Equal(t, now, tm)
+
+	method, ok := msg.Enum("method")
+	assert.True(t, ok)
+	assert.Equal(t, "GNSS fix", method.Code)
+	_, ok = msg.Enum("name")
+	assert.False(t, ok)
+}
@@ -0,0 +1,263 @@
+// Package gateway re-serves messages read from NMEA2000 bus to network clients (TCP connections, UDP broadcast) and
+// writes messages sent by clients back to the bus. Together with a device (i.e. Actisense NGT-1) this acts as network
+// gateway similar to Yacht Devices YDWG-02.
+//
+// Server is format agnostic - message format used on client connection is determined by WriterFactory and
+// nmea.ReaderFactory (i.e. canboat.NewCanBoatWriter and canboat.NewCanBoatReader).
+package gateway
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultClientBufferSize is default number of messages buffered per client
+	DefaultClientBufferSize = 100
+	// DefaultWriteTimeout is default time writing single message to client connection may take
+	DefaultWriteTimeout = 5 * time.Second
+)
+
+// ErrServerClosed is returned when serving connection with closed Server
+var ErrServerClosed = errors.New("gateway server closed")
+
+// WriterFactory creates format specific message writer (i.e. canboat.NewCanBoatWriter) on top of client connection.
+type WriterFactory func(conn io.ReadWriter) nmea.RawMessageWriter
+
+// Config configures Server
+type Config struct {
+	// NewWriter creates writer that formats messages sent to client. Required.
+	NewWriter WriterFactory
+	// NewReader creates reader that parses messages sent by client.
+	// Optional: when nil client input is discarded.
+	NewReader nmea.ReaderFactory
+
+	// Writer is device where messages received from clients are written to. Writes are serialized by server.
+	// Optional: when nil messages sent by clients are discarded (read-only gateway).
+	Writer nmea.RawMessageWriter
+
+	// ClientBufferSize is number of messages buffered per client. When client is too slow to consume messages the
+	// oldest messages are dropped so slow client does not affect other clients or reading the bus.
+	// Defaults to: DefaultClientBufferSize
+	ClientBufferSize int
+	// WriteTimeout limits time writing single message to client connection may take. Client connection is closed when
+	// write times out. Applied to connections that support write deadlines (net.Conn).
+	// Defaults to: DefaultWriteTimeout
+	WriteTimeout time.Duration
+
+	// OnError is called for client connection errors (failed writes, malformed input). Optional.
+	OnError func(client string, err error)
+}
+
+// Server delivers published messages to all connected clients and writes messages received from clients to
+// Config.Writer.
+//
+// Server is safe for concurrent use.
+type Server struct {
+	config Config
+	router *nmea.Router
+
+	// writeLock serializes writes from multiple clients to device
+	writeLock sync.Mutex
+
+	lock    sync.Mutex
+	clients map[io.Closer]struct{}
+	closed  bool
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// NewServer creates new instance of Server
+func NewServer(config Config) *Server {
+	if config.ClientBufferSize <= 0 {
+		config.ClientBufferSize = DefaultClientBufferSize
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = DefaultWriteTimeout
+	}
+	return &Server{
+		config:  config,
+		router:  nmea.NewRouter(),
+		clients: map[io.Closer]struct{}{},
+	}
+}
+
+// Publish sends message to all connected clients. Does not block on slow clients.
+func (s *Server) Publish(msg nmea.RawMessage) {
+	s.router.Publish(msg)
+}
+
+// Clients returns number of connected clients
+func (s *Server) Clients() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.clients)
+}
+
+// Serve accepts client connections from listener and serves each of them in separate goroutine until context is
+// cancelled or listener fails. Listener is closed when Serve returns.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		go func() {
+			if err := s.ServeConn(ctx, conn); err != nil {
+				s.onError(clientName(conn), err)
+			}
+		}()
+	}
+}
+
+// ServeConn serves single client connection (i.e. accepted TCP connection or UDP connection to broadcast address)
+// until client closes connection, writing to client fails or context is cancelled. Connection is closed when
+// ServeConn returns.
+func (s *Server) ServeConn(ctx context.Context, conn io.ReadWriteCloser) error {
+	if err := s.addClient(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	defer s.removeClient(conn)
+
+	name := clientName(conn)
+	writer := s.config.NewWriter(conn)
+	sub, err := s.router.Subscribe(nmea.SubscriptionConfig{
+		Name:       name,
+		BufferSize: s.config.ClientBufferSize,
+		DropPolicy: nmea.DropOldest,
+		Handler: func(msg nmea.RawMessage) error {
+			if d, ok := conn.(writeDeadliner); ok {
+				d.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout))
+			}
+			if err := writer.WriteRawMessage(ctx, msg); err != nil {
+				s.onError(name, err)
+				conn.Close() // unblocks reading side that ends serving this client
+				return err
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		conn.Close()
+		if errors.Is(err, nmea.ErrRouterClosed) {
+			return ErrServerClosed
+		}
+		return err
+	}
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = s.readClient(ctx, name, conn)
+	conn.Close()
+	return err
+}
+
+func (s *Server) readClient(ctx context.Context, name string, conn io.ReadWriteCloser) error {
+	if s.config.NewReader == nil {
+		_, err := io.Copy(io.Discard, conn)
+		return ignoreClosed(err)
+	}
+
+	reader := s.config.NewReader(conn)
+	for {
+		msg, err := reader.ReadRawMessage(ctx)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) || ignoreClosed(err) == nil || ctx.Err() != nil {
+				return ignoreClosed(err)
+			}
+			s.onError(name, err) // malformed input, continue with next message
+			continue
+		}
+		if s.config.Writer == nil {
+			continue
+		}
+		if err := s.write(ctx, msg); err != nil {
+			s.onError(name, err)
+		}
+	}
+}
+
+func (s *Server) write(ctx context.Context, msg nmea.RawMessage) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return s.config.Writer.WriteRawMessage(ctx, msg)
+}
+
+func (s *Server) addClient(conn io.Closer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrServerClosed
+	}
+	s.clients[conn] = struct{}{}
+	return nil
+}
+
+func (s *Server) removeClient(conn io.Closer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.clients, conn)
+}
+
+func (s *Server) onError(client string, err error) {
+	if s.config.OnError != nil {
+		s.config.OnError(client, err)
+	}
+}
+
+// Close disconnects all clients. Serving new connections with closed Server fails with ErrServerClosed.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	for c := range s.clients {
+		c.Close()
+	}
+	s.lock.Unlock()
+
+	return s.router.Close()
+}
+
+func clientName(conn io.ReadWriteCloser) string {
+	if c, ok := conn.(net.Conn); ok && c.RemoteAddr() != nil {
+		return c.RemoteAddr().String()
+	}
+	return "client"
+}
+
+func ignoreClosed(err error) error {
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		return nil
+	}
+	return err
+}
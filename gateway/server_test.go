@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testWriter struct {
+	lock     sync.Mutex
+	messages []nmea.RawMessage
+}
+
+func (w *testWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.messages = append(w.messages, msg)
+	return nil
+}
+
+func (w *testWriter) Close() error {
+	return nil
+}
+
+func (w *testWriter) Messages() []nmea.RawMessage {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]nmea.RawMessage(nil), w.messages...)
+}
+
+func newCanboatServer(writer nmea.RawMessageWriter) *Server {
+	return NewServer(Config{
+		NewWriter: func(conn io.ReadWriter) nmea.RawMessageWriter {
+			return canboat.NewCanBoatWriter(conn)
+		},
+		NewReader: func(conn io.ReadWriter) nmea.RawMessageReader {
+			return canboat.NewCanBoatReader(conn)
+		},
+		Writer: writer,
+	})
+}
+
+func startServer(t *testing.T, ctx context.Context, server *Server) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	go server.Serve(ctx, listener)
+	return listener.Addr().String()
+}
+
+func TestServer_Publish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newCanboatServer(nil)
+	defer server.Close()
+	addr := startServer(t, ctx, server)
+
+	conn1, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn1.Close()
+	conn2, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn2.Close()
+
+	assert.Eventually(t, func() bool {
+		return server.Clients() == 2
+	}, time.Second, time.Millisecond)
+
+	server.Publish(nmea.RawMessage{
+		Time:   test_test.UTCTime(1665488842),
+		Header: nmea.CanBusHeader{PGN: 129025, Priority: 2, Source: 1, Destination: 255},
+		Data:   nmea.RawData{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+	})
+
+	expect := "2022-10-11T11:47:22Z,2,129025,1,255,8,01,02,03,04,05,06,07,08\n"
+	for _, conn := range []net.Conn{conn1, conn2} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, expect, line)
+	}
+
+	conn1.Close()
+	assert.Eventually(t, func() bool {
+		return server.Clients() == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return server.Clients() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestServer_clientWritesToBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lock sync.Mutex
+	errs := make([]string, 0)
+	device := &testWriter{}
+	server := newCanboatServer(device)
+	server.config.OnError = func(client string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		errs = append(errs, err.Error())
+	}
+	defer server.Close()
+	addr := startServer(t, ctx, server)
+
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("invalid\n2022-10-11T11:47:22Z,6,59904,0,255,3,14,f0,01\n"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(device.Messages()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 0, Destination: 255}, device.Messages()[0].Header)
+	assert.Equal(t, nmea.RawData{0x14, 0xf0, 0x01}, device.Messages()[0].Data)
+
+	lock.Lock()
+	assert.Len(t, errs, 1)
+	lock.Unlock()
+}
+
+func TestServer_ServeConn_readOnly(t *testing.T) {
+	server := NewServer(Config{
+		NewWriter: func(conn io.ReadWriter) nmea.RawMessageWriter {
+			return canboat.NewCanBoatWriter(conn)
+		},
+	})
+
+	client, serverConn := net.Pipe()
+	defer client.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- server.ServeConn(context.Background(), serverConn)
+	}()
+
+	_, err := client.Write([]byte("ignored input\n"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, server.Close())
+	assert.NoError(t, <-done)
+	assert.Equal(t, 0, server.Clients())
+
+	client2, serverConn2 := net.Pipe()
+	defer client2.Close()
+	assert.ErrorIs(t, server.ServeConn(context.Background(), serverConn2), ErrServerClosed)
+}
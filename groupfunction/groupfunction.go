@@ -0,0 +1,378 @@
+// Package groupfunction builds and parses NMEA 2000 Group Function (PGN 126208) messages. Group functions are used to
+// request PGNs with changed transmission interval, command devices to change field values of their PGNs (i.e.
+// instance numbers) and to acknowledge these requests and commands.
+//
+// Parameters are field number/value pairs where field number is field order in commanded PGN (canboat Field.Order).
+// Parameter values are encoded and decoded according to commanded PGN field definition in Canboat schema.
+package groupfunction
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"math"
+	"time"
+)
+
+// FunctionCode identifies type of group function
+type FunctionCode uint8
+
+const (
+	// FunctionCodeRequest requests commanded PGN to be transmitted (optionally with changed transmission interval)
+	FunctionCodeRequest FunctionCode = 0
+	// FunctionCodeCommand commands device to change field values of commanded PGN
+	FunctionCodeCommand FunctionCode = 1
+	// FunctionCodeAcknowledge is response to request or command
+	FunctionCodeAcknowledge FunctionCode = 2
+	// FunctionCodeReadFields is NMEA proprietary read fields request
+	FunctionCodeReadFields FunctionCode = 3
+	// FunctionCodeReadFieldsReply is reply to read fields request
+	FunctionCodeReadFieldsReply FunctionCode = 4
+	// FunctionCodeWriteFields is NMEA proprietary write fields request
+	FunctionCodeWriteFields FunctionCode = 5
+	// FunctionCodeWriteFieldsReply is reply to write fields request
+	FunctionCodeWriteFieldsReply FunctionCode = 6
+)
+
+// PriorityNoChange is Command priority value that leaves commanded PGN priority unchanged
+const PriorityNoChange = uint8(0x8)
+
+const (
+	// PGNErrorAcknowledge means that request or command for PGN was accepted
+	PGNErrorAcknowledge = uint8(0)
+	// PGNErrorNotSupported means that device does not support commanded PGN
+	PGNErrorNotSupported = uint8(1)
+	// PGNErrorNotAvailable means that commanded PGN is temporarily not available
+	PGNErrorNotAvailable = uint8(2)
+	// PGNErrorAccessDenied means that device denied changing commanded PGN
+	PGNErrorAccessDenied = uint8(3)
+	// PGNErrorRequestOrCommandNotSupported means that device does not support request or command for PGN
+	PGNErrorRequestOrCommandNotSupported = uint8(4)
+)
+
+const (
+	// TransmissionIntervalErrorAcknowledge means that transmission interval or priority change was accepted
+	TransmissionIntervalErrorAcknowledge = uint8(0)
+	// TransmissionIntervalErrorNotSupported means that changing transmission interval or priority is not supported
+	TransmissionIntervalErrorNotSupported = uint8(1)
+	// TransmissionIntervalErrorTooLow means that requested transmission interval is too low
+	TransmissionIntervalErrorTooLow = uint8(2)
+	// TransmissionIntervalErrorAccessDenied means that device denied changing transmission interval or priority
+	TransmissionIntervalErrorAccessDenied = uint8(3)
+)
+
+const (
+	// ParameterErrorAcknowledge means that parameter was accepted
+	ParameterErrorAcknowledge = uint8(0)
+	// ParameterErrorInvalidField means that parameter field number is invalid
+	ParameterErrorInvalidField = uint8(1)
+	// ParameterErrorTemporary means that parameter could not be changed temporarily
+	ParameterErrorTemporary = uint8(2)
+	// ParameterErrorOutOfRange means that parameter value is out of range
+	ParameterErrorOutOfRange = uint8(3)
+	// ParameterErrorAccessDenied means that device denied changing parameter
+	ParameterErrorAccessDenied = uint8(4)
+	// ParameterErrorNotSupported means that changing parameter is not supported
+	ParameterErrorNotSupported = uint8(5)
+)
+
+// ErrUnknownPGN is returned when schema does not have definition for commanded PGN
+var ErrUnknownPGN = errors.New("group function failed, unknown commanded PGN")
+
+// Parameter is field number and value pair of commanded PGN
+type Parameter struct {
+	// Field is field number (canboat Field.Order) in commanded PGN
+	Field uint8
+	// Value is field value in same types as canboat Decoder produces them and Encoder accepts (i.e. float64 for numbers
+	// with resolution, string or nmea.EnumValue for lookups). Value is nil when parameter has "no data" value.
+	Value interface{}
+}
+
+// Request is Request Group Function. Requested PGN is transmitted by receiving device, optionally with changed
+// transmission interval. Parameters act as selection criteria (i.e. instance of requested PGN).
+type Request struct {
+	PGN uint32
+	// TransmissionInterval is new transmission interval for requested PGN. Nil leaves interval unchanged.
+	TransmissionInterval *time.Duration
+	// TransmissionIntervalOffset is new transmission interval offset for requested PGN. Nil leaves offset unchanged.
+	TransmissionIntervalOffset *time.Duration
+	Parameters                 []Parameter
+}
+
+// Command is Command Group Function. Receiving device changes commanded PGN fields to given parameter values.
+type Command struct {
+	PGN uint32
+	// Priority is new priority for commanded PGN. Use PriorityNoChange to leave priority unchanged.
+	Priority   uint8
+	Parameters []Parameter
+}
+
+// Acknowledge is Acknowledge Group Function that is sent as response to Request and Command.
+type Acknowledge struct {
+	PGN                                   uint32
+	PGNErrorCode                          uint8
+	TransmissionIntervalPriorityErrorCode uint8
+	// ParameterErrorCodes has error code for each parameter of acknowledged request or command
+	ParameterErrorCodes []uint8
+}
+
+// Codec encodes and decodes group functions. Commanded PGN definitions are taken from Canboat schema.
+type Codec struct {
+	pgns    canboat.PGNs
+	encoder *canboat.Encoder
+}
+
+// NewCodec creates new instance of Codec
+func NewCodec(schema canboat.CanboatSchema) *Codec {
+	return &Codec{
+		pgns:    schema.PGNs,
+		encoder: canboat.NewEncoder(schema),
+	}
+}
+
+// FunctionCodeOf returns function code of Group Function message
+func FunctionCodeOf(raw nmea.RawMessage) (FunctionCode, error) {
+	if raw.Header.PGN != uint32(nmea.PGNGroupFunction) {
+		return 0, errors.New("group function can only be created from rawMessage with PGN 126208")
+	}
+	if len(raw.Data) < 1 {
+		return 0, errors.New("rawMessage has invalid length to be group function")
+	}
+	return FunctionCode(raw.Data[0]), nil
+}
+
+// EncodeRequest creates Request Group Function message sent to given destination
+func (c *Codec) EncodeRequest(destination uint8, r Request) (nmea.RawMessage, error) {
+	interval := uint32(math.MaxUint32)
+	if r.TransmissionInterval != nil {
+		interval = uint32(*r.TransmissionInterval / time.Millisecond) // resolution 0.001s
+	}
+	offset := uint16(math.MaxUint16)
+	if r.TransmissionIntervalOffset != nil {
+		offset = uint16(*r.TransmissionIntervalOffset / (10 * time.Millisecond)) // resolution 0.01s
+	}
+	data := []byte{
+		uint8(FunctionCodeRequest),
+		uint8(r.PGN), uint8(r.PGN >> 8), uint8(r.PGN >> 16),
+		uint8(interval), uint8(interval >> 8), uint8(interval >> 16), uint8(interval >> 24),
+		uint8(offset), uint8(offset >> 8),
+	}
+	data, err := c.appendParameters(data, r.PGN, r.Parameters)
+	if err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return createMessage(destination, data), nil
+}
+
+// EncodeCommand creates Command Group Function message sent to given destination
+func (c *Codec) EncodeCommand(destination uint8, cmd Command) (nmea.RawMessage, error) {
+	data := []byte{
+		uint8(FunctionCodeCommand),
+		uint8(cmd.PGN), uint8(cmd.PGN >> 8), uint8(cmd.PGN >> 16),
+		cmd.Priority&0x0f | 0xf0, // priority (4 bits), reserved (4 bits)
+	}
+	data, err := c.appendParameters(data, cmd.PGN, cmd.Parameters)
+	if err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return createMessage(destination, data), nil
+}
+
+// EncodeAcknowledge creates Acknowledge Group Function message sent to given destination
+func EncodeAcknowledge(destination uint8, a Acknowledge) nmea.RawMessage {
+	data := []byte{
+		uint8(FunctionCodeAcknowledge),
+		uint8(a.PGN), uint8(a.PGN >> 8), uint8(a.PGN >> 16),
+		a.PGNErrorCode&0x0f | a.TransmissionIntervalPriorityErrorCode<<4,
+		uint8(len(a.ParameterErrorCodes)),
+	}
+	for i, code := range a.ParameterErrorCodes { // each error code is 4 bits, unused bits of last byte are set to 1
+		if i%2 == 0 {
+			data = append(data, code&0x0f|0xf0)
+		} else {
+			data[len(data)-1] = data[len(data)-1]&0x0f | code<<4
+		}
+	}
+	return createMessage(destination, data)
+}
+
+func createMessage(destination uint8, data []byte) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{
+			PGN:         uint32(nmea.PGNGroupFunction),
+			Priority:    3,
+			Source:      nmea.AddressNull,
+			Destination: destination,
+		},
+		Data: data,
+	}
+}
+
+func (c *Codec) appendParameters(data []byte, pgnNumber uint32, params []Parameter) ([]byte, error) {
+	if len(params) > 253 {
+		return nil, errors.New("group function failed, too many parameters")
+	}
+	data = append(data, uint8(len(params)))
+	if len(params) == 0 {
+		return data, nil
+	}
+	pgn, err := c.findPGN(pgnNumber)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		f, err := findField(pgn, p.Field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.encoder.EncodeField(f, p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("group function failed to encode parameter %v, err: %w", p.Field, err)
+		}
+		data = append(data, p.Field)
+		data = append(data, value...)
+	}
+	return data, nil
+}
+
+// ParseRequest parses Request Group Function message
+func (c *Codec) ParseRequest(raw nmea.RawMessage) (Request, error) {
+	if err := checkFunctionCode(raw, FunctionCodeRequest, 11); err != nil {
+		return Request{}, err
+	}
+	b := raw.Data
+	r := Request{PGN: uint32(b[1]) | uint32(b[2])<<8 | uint32(b[3])<<16}
+	if interval := uint32(b[4]) | uint32(b[5])<<8 | uint32(b[6])<<16 | uint32(b[7])<<24; interval != math.MaxUint32 {
+		d := time.Duration(interval) * time.Millisecond
+		r.TransmissionInterval = &d
+	}
+	if offset := uint16(b[8]) | uint16(b[9])<<8; offset != math.MaxUint16 {
+		d := time.Duration(offset) * 10 * time.Millisecond
+		r.TransmissionIntervalOffset = &d
+	}
+	params, err := c.parseParameters(b, 10, r.PGN)
+	if err != nil {
+		return Request{}, err
+	}
+	r.Parameters = params
+	return r, nil
+}
+
+// ParseCommand parses Command Group Function message
+func (c *Codec) ParseCommand(raw nmea.RawMessage) (Command, error) {
+	if err := checkFunctionCode(raw, FunctionCodeCommand, 6); err != nil {
+		return Command{}, err
+	}
+	b := raw.Data
+	cmd := Command{
+		PGN:      uint32(b[1]) | uint32(b[2])<<8 | uint32(b[3])<<16,
+		Priority: b[4] & 0x0f,
+	}
+	params, err := c.parseParameters(b, 5, cmd.PGN)
+	if err != nil {
+		return Command{}, err
+	}
+	cmd.Parameters = params
+	return cmd, nil
+}
+
+// ParseAcknowledge parses Acknowledge Group Function message
+func ParseAcknowledge(raw nmea.RawMessage) (Acknowledge, error) {
+	if err := checkFunctionCode(raw, FunctionCodeAcknowledge, 6); err != nil {
+		return Acknowledge{}, err
+	}
+	b := raw.Data
+	count := int(b[5])
+	if len(b) < 6+(count+1)/2 {
+		return Acknowledge{}, errors.New("rawMessage is too short for acknowledge parameter count")
+	}
+	a := Acknowledge{
+		PGN:                                   uint32(b[1]) | uint32(b[2])<<8 | uint32(b[3])<<16,
+		PGNErrorCode:                          b[4] & 0x0f,
+		TransmissionIntervalPriorityErrorCode: b[4] >> 4,
+		ParameterErrorCodes:                   make([]uint8, 0, count),
+	}
+	for i := 0; i < count; i++ {
+		code := b[6+i/2]
+		if i%2 == 0 {
+			code &= 0x0f
+		} else {
+			code >>= 4
+		}
+		a.ParameterErrorCodes = append(a.ParameterErrorCodes, code)
+	}
+	return a, nil
+}
+
+func checkFunctionCode(raw nmea.RawMessage, expect FunctionCode, minLength int) error {
+	code, err := FunctionCodeOf(raw)
+	if err != nil {
+		return err
+	}
+	if code != expect {
+		return fmt.Errorf("group function has unexpected function code: %v", code)
+	}
+	if len(raw.Data) < minLength {
+		return errors.New("rawMessage has invalid length to be group function")
+	}
+	return nil
+}
+
+func (c *Codec) parseParameters(b nmea.RawData, offset int, pgnNumber uint32) ([]Parameter, error) {
+	count := int(b[offset])
+	offset++
+	if count == 0 {
+		return nil, nil
+	}
+	pgn, err := c.findPGN(pgnNumber)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]Parameter, 0, count)
+	for i := 0; i < count; i++ {
+		if offset >= len(b) {
+			return nil, errors.New("rawMessage is too short for group function parameter count")
+		}
+		fieldNumber := b[offset]
+		offset++
+		f, err := findField(pgn, fieldNumber)
+		if err != nil {
+			return nil, err
+		}
+		// parameter value is field value starting from byte boundary and taking whole bytes
+		fv, readBits, err := f.Decode(b, uint32(offset*8))
+		var value interface{}
+		switch err {
+		case nil:
+			value = fv.Value
+		case nmea.ErrValueNoData, nmea.ErrValueOutOfRange, nmea.ErrValueReserved:
+			value = nil
+		default:
+			return nil, fmt.Errorf("group function failed to decode parameter %v, err: %w", fieldNumber, err)
+		}
+		offset += (int(readBits) + 7) / 8
+		if offset > len(b) {
+			return nil, errors.New("rawMessage is too short for group function parameter value")
+		}
+		params = append(params, Parameter{Field: fieldNumber, Value: value})
+	}
+	return params, nil
+}
+
+func (c *Codec) findPGN(pgnNumber uint32) (canboat.PGN, error) {
+	pgns := c.pgns.FilterByPGN(pgnNumber)
+	if len(pgns) == 0 {
+		return canboat.PGN{}, fmt.Errorf("%w: %v", ErrUnknownPGN, pgnNumber)
+	}
+	return pgns[0], nil
+}
+
+func findField(pgn canboat.PGN, fieldNumber uint8) (canboat.Field, error) {
+	for _, f := range pgn.Fields {
+		if f.Order == int8(fieldNumber) && fieldNumber != 0 {
+			return f, nil
+		}
+	}
+	return canboat.Field{}, fmt.Errorf("group function failed, PGN %v has no field number %v", pgn.PGN, fieldNumber)
+}
@@ -0,0 +1,218 @@
+package groupfunction
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testSchema = canboat.CanboatSchema{
+	Enums: canboat.LookupEnumerations{
+		{Name: "TANK_TYPE", Values: []canboat.EnumValue{{Name: "Fuel", Value: 0}, {Name: "Water", Value: 1}}},
+	},
+	PGNs: canboat.PGNs{
+		{
+			PGN:         127505,
+			ID:          "fluidLevel",
+			Description: "Fluid Level",
+			Type:        canboat.PacketTypeSingle,
+			Fields: []canboat.Field{
+				{ID: "instance", Order: 1, BitOffset: 0, BitLength: 4, Resolution: 1, FieldType: canboat.FieldTypeNumber},
+				{ID: "type", Order: 2, BitOffset: 4, BitLength: 4, Resolution: 1, FieldType: canboat.FieldTypeLookup, LookupEnumeration: "TANK_TYPE"},
+				{ID: "level", Order: 3, BitOffset: 8, BitLength: 16, Resolution: 0.004, Signed: true, FieldType: canboat.FieldTypeNumber},
+				{ID: "capacity", Order: 4, BitOffset: 24, BitLength: 32, Resolution: 0.1, FieldType: canboat.FieldTypeNumber},
+				{ID: "reserved", Order: 5, BitOffset: 56, BitLength: 8, FieldType: canboat.FieldTypeReserved},
+			},
+		},
+	},
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestCodec_EncodeRequest(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        Request
+		expect      nmea.RawData
+		expectError string
+	}{
+		{
+			name: "ok, request with changed interval and instance selection",
+			when: Request{
+				PGN:                  127505,
+				TransmissionInterval: durationPtr(5 * time.Second),
+				Parameters:           []Parameter{{Field: 1, Value: uint64(2)}},
+			},
+			expect: nmea.RawData{
+				0x00,             // function code: request
+				0x11, 0xf2, 0x01, // PGN 127505
+				0x88, 0x13, 0x00, 0x00, // interval 5000ms
+				0xff, 0xff, // interval offset: no change
+				0x01,       // number of parameters
+				0x01, 0xf2, // field 1 = 2 (4 bits, unused bits set)
+			},
+		},
+		{
+			name: "ok, request without parameters",
+			when: Request{PGN: 129025, TransmissionIntervalOffset: durationPtr(500 * time.Millisecond)},
+			expect: nmea.RawData{
+				0x00, 0x01, 0xf8, 0x01, 0xff, 0xff, 0xff, 0xff, 0x32, 0x00, 0x00,
+			},
+		},
+		{
+			name:        "nok, unknown PGN with parameters",
+			when:        Request{PGN: 129025, Parameters: []Parameter{{Field: 1, Value: uint64(2)}}},
+			expectError: "group function failed, unknown commanded PGN: 129025",
+		},
+		{
+			name:        "nok, unknown field number",
+			when:        Request{PGN: 127505, Parameters: []Parameter{{Field: 9, Value: uint64(2)}}},
+			expectError: "group function failed, PGN 127505 has no field number 9",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := NewCodec(testSchema).EncodeRequest(35, tc.when)
+
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, nmea.CanBusHeader{PGN: 126208, Priority: 3, Source: 254, Destination: 35}, result.Header)
+			assert.Equal(t, tc.expect, result.Data)
+		})
+	}
+}
+
+func TestCodec_EncodeCommand(t *testing.T) {
+	result, err := NewCodec(testSchema).EncodeCommand(35, Command{
+		PGN:      127505,
+		Priority: PriorityNoChange,
+		Parameters: []Parameter{
+			{Field: 1, Value: uint64(3)},
+			{Field: 4, Value: 200.0},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.CanBusHeader{PGN: 126208, Priority: 3, Source: 254, Destination: 35}, result.Header)
+	expect := nmea.RawData{
+		0x01,             // function code: command
+		0x11, 0xf2, 0x01, // PGN 127505
+		0xf8,       // priority: no change
+		0x02,       // number of parameters
+		0x01, 0xf3, // field 1 = 3
+		0x04, 0xd0, 0x07, 0x00, 0x00, // field 4 = 200.0 / 0.1 = 2000
+	}
+	assert.Equal(t, expect, result.Data)
+}
+
+func TestCodec_ParseCommand(t *testing.T) {
+	codec := NewCodec(testSchema)
+	given := Command{
+		PGN:      127505,
+		Priority: 5,
+		Parameters: []Parameter{
+			{Field: 2, Value: uint64(1)},
+			{Field: 3, Value: 50.0},
+			{Field: 4, Value: nil},
+		},
+	}
+	raw, err := codec.EncodeCommand(35, given)
+	assert.NoError(t, err)
+
+	code, err := FunctionCodeOf(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, FunctionCodeCommand, code)
+
+	result, err := codec.ParseCommand(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, given, result)
+}
+
+func TestCodec_ParseRequest(t *testing.T) {
+	codec := NewCodec(testSchema)
+	given := Request{
+		PGN:                        127505,
+		TransmissionInterval:       durationPtr(2500 * time.Millisecond),
+		TransmissionIntervalOffset: durationPtr(100 * time.Millisecond),
+		Parameters:                 []Parameter{{Field: 1, Value: uint64(2)}},
+	}
+	raw, err := codec.EncodeRequest(255, given)
+	assert.NoError(t, err)
+
+	result, err := codec.ParseRequest(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, given, result)
+}
+
+func TestCodec_ParseRequest_errors(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        nmea.RawMessage
+		expectError string
+	}{
+		{
+			name:        "nok, invalid PGN",
+			when:        nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 126996}, Data: nmea.RawData{0x00}},
+			expectError: "group function can only be created from rawMessage with PGN 126208",
+		},
+		{
+			name:        "nok, invalid function code",
+			when:        nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 126208}, Data: nmea.RawData{0x01, 0x11, 0xf2, 0x01, 0xf8, 0x00}},
+			expectError: "group function has unexpected function code: 1",
+		},
+		{
+			name:        "nok, too short",
+			when:        nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 126208}, Data: nmea.RawData{0x00, 0x11, 0xf2, 0x01}},
+			expectError: "rawMessage has invalid length to be group function",
+		},
+		{
+			name: "nok, missing parameter",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126208},
+				Data:   nmea.RawData{0x00, 0x11, 0xf2, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02, 0x01, 0xf2},
+			},
+			expectError: "rawMessage is too short for group function parameter count",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewCodec(testSchema).ParseRequest(tc.when)
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestEncodeAcknowledge(t *testing.T) {
+	given := Acknowledge{
+		PGN:                                   127505,
+		PGNErrorCode:                          PGNErrorAcknowledge,
+		TransmissionIntervalPriorityErrorCode: TransmissionIntervalErrorNotSupported,
+		ParameterErrorCodes:                   []uint8{ParameterErrorAcknowledge, ParameterErrorOutOfRange, ParameterErrorAccessDenied},
+	}
+
+	result := EncodeAcknowledge(10, given)
+
+	assert.Equal(t, nmea.CanBusHeader{PGN: 126208, Priority: 3, Source: 254, Destination: 10}, result.Header)
+	assert.Equal(t, nmea.RawData{0x02, 0x11, 0xf2, 0x01, 0x10, 0x03, 0x30, 0xf4}, result.Data)
+
+	parsed, err := ParseAcknowledge(result)
+	assert.NoError(t, err)
+	assert.Equal(t, given, parsed)
+}
+
+func TestParseAcknowledge_tooShort(t *testing.T) {
+	_, err := ParseAcknowledge(nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 126208},
+		Data:   nmea.RawData{0x02, 0x11, 0xf2, 0x01, 0x00, 0x03, 0x00},
+	})
+	assert.EqualError(t, err, "rawMessage is too short for acknowledge parameter count")
+}
@@ -0,0 +1,166 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchSize is default number of lines sent to InfluxDB in single write request
+	DefaultBatchSize = 5000
+	// DefaultFlushInterval is default maximum time lines are held in batch before they are sent
+	DefaultFlushInterval = 1 * time.Second
+	// DefaultRequestTimeout is default timeout of single write request
+	DefaultRequestTimeout = 10 * time.Second
+)
+
+// ErrWriteFailed is returned when InfluxDB responded to write request with non-success status
+var ErrWriteFailed = errors.New("influx: write request failed")
+
+// HTTPConfig configures HTTPWriter
+type HTTPConfig struct {
+	// URL is InfluxDB server address (i.e. `http://localhost:8086`)
+	URL string
+	// Org is organization name or ID that bucket belongs to
+	Org string
+	// Bucket is name of bucket where lines are written to
+	Bucket string
+	// Token is API token sent as `Authorization: Token <token>` header. Optional.
+	Token string
+
+	// BatchSize is number of lines sent in single write request.
+	// Defaults to: DefaultBatchSize
+	BatchSize int
+	// FlushInterval is maximum time (by message time) lines are held in batch before they are sent.
+	// Defaults to: DefaultFlushInterval
+	FlushInterval time.Duration
+	// Client is HTTP client used to send write requests. Optional.
+	// Defaults to: client with DefaultRequestTimeout timeout
+	Client *http.Client
+}
+
+// HTTPWriter writes decoded messages directly to InfluxDB v2 write API (`/api/v2/write`) in batches. Implements
+// export.Writer.
+//
+// Batch that fails to be sent is discarded so unreachable server does not grow memory usage without bounds.
+type HTTPWriter struct {
+	mutex    sync.Mutex
+	config   HTTPConfig
+	writeURL string
+
+	batch      []byte
+	batchLines int
+	batchStart time.Time
+	timeNow    func() time.Time
+}
+
+// NewHTTPWriter creates new instance of HTTPWriter
+func NewHTTPWriter(config HTTPConfig) (*HTTPWriter, error) {
+	if config.URL == "" {
+		return nil, errors.New("influx: URL is required")
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("influx: bucket is required")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: DefaultRequestTimeout}
+	}
+	u, err := url.Parse(strings.TrimSuffix(config.URL, "/") + "/api/v2/write")
+	if err != nil {
+		return nil, fmt.Errorf("influx: invalid URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("org", config.Org)
+	q.Set("bucket", config.Bucket)
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+
+	return &HTTPWriter{
+		config:   config,
+		writeURL: u.String(),
+		timeNow:  time.Now,
+	}, nil
+}
+
+// WriteMessage adds decoded message to batch. Batch is sent when it is full or FlushInterval has passed since first
+// message in batch. Messages without time are timestamped with current time.
+func (w *HTTPWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	if raw.Time.IsZero() {
+		raw.Time = w.timeNow()
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	n := len(w.batch)
+	w.batch = AppendLine(w.batch, raw, msg)
+	if len(w.batch) == n {
+		return nil
+	}
+	if w.batchLines == 0 {
+		w.batchStart = raw.Time
+	}
+	w.batchLines++
+	if w.batchLines >= w.config.BatchSize || raw.Time.Sub(w.batchStart) >= w.config.FlushInterval {
+		return w.flush(context.Background())
+	}
+	return nil
+}
+
+// Flush sends batched lines to InfluxDB
+func (w *HTTPWriter) Flush(ctx context.Context) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.flush(ctx)
+}
+
+func (w *HTTPWriter) flush(ctx context.Context) error {
+	if w.batchLines == 0 {
+		return nil
+	}
+	defer func() {
+		w.batch = w.batch[:0]
+		w.batchLines = 0
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.writeURL, bytes.NewReader(w.batch))
+	if err != nil {
+		return fmt.Errorf("influx: failed to create write request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.config.Token)
+	}
+	res, err := w.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx: failed to send write request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%w, status: %v, body: %s", ErrWriteFailed, res.StatusCode, bytes.TrimSpace(body))
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// Close sends remaining batched lines
+func (w *HTTPWriter) Close() error {
+	return w.Flush(context.Background())
+}
@@ -0,0 +1,123 @@
+package influx
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type writeRequest struct {
+	uri           string
+	authorization string
+	body          string
+}
+
+type testServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []writeRequest
+	status   int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{status: http.StatusNoContent}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mutex.Lock()
+		s.requests = append(s.requests, writeRequest{
+			uri:           r.Method + " " + r.URL.RequestURI(),
+			authorization: r.Header.Get("Authorization"),
+			body:          string(body),
+		})
+		status := s.status
+		s.mutex.Unlock()
+		w.WriteHeader(status)
+		if status != http.StatusNoContent {
+			_, _ = w.Write([]byte(`{"code":"invalid","message":"bad line"}` + "\n"))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func depthMessage(sec int64, depth float64) (nmea.RawMessage, nmea.Message) {
+	return nmea.RawMessage{Time: test_test.UTCTime(sec)}, nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 128267, Source: 10},
+		Fields: nmea.FieldValues{{ID: "depth", Value: depth}},
+	}
+}
+
+func TestHTTPWriter_WriteMessage(t *testing.T) {
+	server := newTestServer(t)
+	w, err := NewHTTPWriter(HTTPConfig{
+		URL:       server.URL + "/",
+		Org:       "my org",
+		Bucket:    "boat",
+		Token:     "secret",
+		BatchSize: 2,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488842, 12.5)))
+	assert.Len(t, server.requests, 0)
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488842, 12.0)))
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488843, 11.5)))
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []writeRequest{
+		{
+			uri:           "POST /api/v2/write?bucket=boat&org=my+org&precision=ns",
+			authorization: "Token secret",
+			body:          "128267,src=10 depth=12.5 1665488842000000000\n128267,src=10 depth=12 1665488842000000000\n",
+		},
+		{
+			uri:           "POST /api/v2/write?bucket=boat&org=my+org&precision=ns",
+			authorization: "Token secret",
+			body:          "128267,src=10 depth=11.5 1665488843000000000\n",
+		},
+	}, server.requests)
+}
+
+func TestHTTPWriter_WriteMessage_flushInterval(t *testing.T) {
+	server := newTestServer(t)
+	w, err := NewHTTPWriter(HTTPConfig{URL: server.URL, Bucket: "boat", FlushInterval: 2 * time.Second})
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488842, 12.5)))
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488843, 12.0)))
+	assert.Len(t, server.requests, 0)
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488844, 11.5)))
+	if assert.Len(t, server.requests, 1) {
+		assert.Equal(t, "", server.requests[0].authorization)
+	}
+	assert.Equal(t, 0, w.batchLines)
+}
+
+func TestHTTPWriter_Flush_error(t *testing.T) {
+	server := newTestServer(t)
+	server.status = http.StatusBadRequest
+	w, err := NewHTTPWriter(HTTPConfig{URL: server.URL, Bucket: "boat"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.WriteMessage(depthMessage(1665488842, 12.5)))
+	err = w.Flush(context.Background())
+
+	assert.ErrorIs(t, err, ErrWriteFailed)
+	assert.EqualError(t, err, `influx: write request failed, status: 400, body: {"code":"invalid","message":"bad line"}`)
+	assert.Len(t, w.batch, 0) // failed batch is discarded
+}
+
+func TestNewHTTPWriter_errors(t *testing.T) {
+	_, err := NewHTTPWriter(HTTPConfig{Bucket: "boat"})
+	assert.EqualError(t, err, "influx: URL is required")
+
+	_, err = NewHTTPWriter(HTTPConfig{URL: "http://localhost:8086"})
+	assert.EqualError(t, err, "influx: bucket is required")
+}
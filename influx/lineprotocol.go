@@ -0,0 +1,161 @@
+// Package influx writes decoded messages in InfluxDB line protocol so they can be stored in time-series database.
+//
+// Every message is written as single line where measurement is PGN, tags are source address (`src`) and instance
+// (`instance`, when message has `instance` field) and fields are decoded field values. Timestamp is message time in
+// unix nanoseconds:
+//
+//	130312,instance=1,src=35 source="Outside Temperature",actualTemperature=285.15 1665488842000000000
+package influx
+
+import (
+	"bufio"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	keyEscaper    = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+	stringEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// AppendLine appends decoded message as single line protocol line (with trailing newline) to dst. Field values are
+// converted as following:
+//   - float64 is written as float, NaN and infinite values are skipped
+//   - int64 is written as integer (`i` suffix), uint64 as unsigned integer (`u` suffix)
+//   - string is written as string
+//   - time.Duration and nmea.TimeOfDay are written as float seconds
+//   - time.Time is written as integer unix nanoseconds
+//   - nmea.EnumValue is written as string lookup name (numeric value when lookup has no name)
+//
+// Other values (bytes, bit lookups, repeating field sets) are skipped. Message time is used as timestamp, messages
+// without time are written without timestamp so InfluxDB uses server time. Returns dst unchanged when message has no
+// values that can be written as fields.
+func AppendLine(dst []byte, raw nmea.RawMessage, msg nmea.Message) []byte {
+	start := len(dst)
+	dst = strconv.AppendUint(dst, uint64(msg.Header.PGN), 10)
+
+	// tags are written sorted by key as recommended for best performance
+	instanceField := ""
+	if fv, ok := msg.Fields.FindByID("instance"); ok {
+		if v, ok := fv.AsUint64(); ok {
+			instanceField = fv.ID
+			dst = append(dst, ",instance="...)
+			dst = strconv.AppendUint(dst, v, 10)
+		}
+	}
+	dst = append(dst, ",src="...)
+	dst = strconv.AppendUint(dst, uint64(msg.Header.Source), 10)
+
+	fieldCount := 0
+	for _, f := range msg.Fields {
+		if f.ID == instanceField {
+			continue
+		}
+		mark := len(dst)
+		if fieldCount == 0 {
+			dst = append(dst, ' ')
+		} else {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, keyEscaper.Replace(f.ID)...)
+		dst = append(dst, '=')
+		var ok bool
+		if dst, ok = appendFieldValue(dst, f.Value); !ok {
+			dst = dst[:mark]
+			continue
+		}
+		fieldCount++
+	}
+	if fieldCount == 0 {
+		return dst[:start]
+	}
+	if !raw.Time.IsZero() {
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, raw.Time.UnixNano(), 10)
+	}
+	return append(dst, '\n')
+}
+
+func appendFieldValue(dst []byte, value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return dst, false
+		}
+		return strconv.AppendFloat(dst, v, 'f', -1, 64), true
+	case int64:
+		return append(strconv.AppendInt(dst, v, 10), 'i'), true
+	case uint64:
+		return append(strconv.AppendUint(dst, v, 10), 'u'), true
+	case string:
+		return appendString(dst, v), true
+	case time.Duration:
+		return strconv.AppendFloat(dst, v.Seconds(), 'f', -1, 64), true
+	case nmea.TimeOfDay:
+		return strconv.AppendFloat(dst, v.Duration().Seconds(), 'f', -1, 64), true
+	case time.Time:
+		return append(strconv.AppendInt(dst, v.UnixNano(), 10), 'i'), true
+	case nmea.EnumValue:
+		if v.Code == "" {
+			return appendString(dst, strconv.FormatUint(uint64(v.Value), 10)), true
+		}
+		return appendString(dst, v.Code), true
+	}
+	return dst, false
+}
+
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = append(dst, stringEscaper.Replace(s)...)
+	return append(dst, '"')
+}
+
+// LineWriter writes decoded messages in line protocol to writer (i.e. file or STDOUT). Implements export.Writer.
+//
+// Note: is not go-routine safe
+type LineWriter struct {
+	writer *bufio.Writer
+	closer io.Closer
+	line   []byte
+}
+
+// NewLineWriter creates new instance of LineWriter. When writer implements io.Closer it is closed on Close.
+func NewLineWriter(writer io.Writer) *LineWriter {
+	w := &LineWriter{
+		writer: bufio.NewWriterSize(writer, 64*1024),
+	}
+	if c, ok := writer.(io.Closer); ok {
+		w.closer = c
+	}
+	return w
+}
+
+// WriteMessage writes decoded message as single line. Messages without writable field values are skipped.
+func (w *LineWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	w.line = AppendLine(w.line[:0], raw, msg)
+	if len(w.line) == 0 {
+		return nil
+	}
+	_, err := w.writer.Write(w.line)
+	return err
+}
+
+// Flush writes buffered lines to underlying writer
+func (w *LineWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+// Close flushes buffered output and closes underlying writer
+func (w *LineWriter) Close() error {
+	err := w.writer.Flush()
+	if w.closer != nil {
+		if cErr := w.closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package influx
+
+import (
+	"bytes"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAppendLine(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+
+	var testCases = []struct {
+		name     string
+		givenRaw nmea.RawMessage
+		givenMsg nmea.Message
+		expect   string
+	}{
+		{
+			name:     "ok, with instance tag",
+			givenRaw: nmea.RawMessage{Time: now},
+			givenMsg: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 130312, Source: 35},
+				Fields: nmea.FieldValues{
+					{ID: "sid", Value: uint64(1)},
+					{ID: "instance", Value: uint64(2)},
+					{ID: "source", Value: nmea.EnumValue{Value: 1, Code: "Outside Temperature"}},
+					{ID: "actualTemperature", Value: 285.15},
+				},
+			},
+			expect: "130312,instance=2,src=35 sid=1u,source=\"Outside Temperature\",actualTemperature=285.15 1665488842000000000\n",
+		},
+		{
+			name:     "ok, all value types",
+			givenRaw: nmea.RawMessage{Time: now},
+			givenMsg: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 126992, Source: 1},
+				Fields: nmea.FieldValues{
+					{ID: "float", Value: -0.000125},
+					{ID: "int", Value: int64(-5)},
+					{ID: "string", Value: `say "hi" \o/`},
+					{ID: "duration", Value: 1500 * time.Millisecond},
+					{ID: "timeOfDay", Value: nmea.TimeOfDay(90 * time.Second)},
+					{ID: "time", Value: time.Unix(1, 5)},
+					{ID: "enum", Value: nmea.EnumValue{Value: 7}},
+					{ID: "nan", Value: math.NaN()},
+					{ID: "bytes", Value: []byte{0x01}},
+					{ID: "nil", Value: nil},
+					{ID: "key with,=", Value: 1.0},
+				},
+			},
+			expect: `126992,src=1 float=-0.000125,int=-5i,string="say \"hi\" \\o/",duration=1.5,timeOfDay=90,` +
+				`time=1000000005i,enum="7",key\ with\,\==1 1665488842000000000` + "\n",
+		},
+		{
+			name: "ok, without time",
+			givenMsg: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 10},
+				Fields: nmea.FieldValues{{ID: "depth", Value: 12.5}},
+			},
+			expect: "128267,src=10 depth=12.5\n",
+		},
+		{
+			name:     "ok, no writable fields",
+			givenRaw: nmea.RawMessage{Time: now},
+			givenMsg: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 130312, Source: 35},
+				Fields: nmea.FieldValues{
+					{ID: "instance", Value: uint64(2)},
+					{ID: "data", Value: []byte{0x01}},
+				},
+			},
+			expect: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := AppendLine([]byte("prefix\n"), tc.givenRaw, tc.givenMsg)
+
+			assert.Equal(t, "prefix\n"+tc.expect, string(result))
+		})
+	}
+}
+
+func TestLineWriter_WriteMessage(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewLineWriter(out)
+
+	err := w.WriteMessage(nmea.RawMessage{Time: test_test.UTCTime(1665488842)}, nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 128267, Source: 10},
+		Fields: nmea.FieldValues{{ID: "depth", Value: 12.5}},
+	})
+	assert.NoError(t, err)
+	err = w.WriteMessage(nmea.RawMessage{}, nmea.Message{Header: nmea.CanBusHeader{PGN: 128267, Source: 10}})
+	assert.NoError(t, err)
+	assert.Equal(t, "", out.String())
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "128267,src=10 depth=12.5 1665488842000000000\n", out.String())
+}
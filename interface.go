@@ -2,8 +2,24 @@ package nmea
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 )
 
+// ErrDeviceClosed is returned by ReadRawMessage and WriteRawMessage when device has been closed. It wraps net.ErrClosed
+// so `errors.Is(err, net.ErrClosed)` checks work for all transports (serial, TCP, socketcan, files).
+var ErrDeviceClosed = fmt.Errorf("device is closed: %w", net.ErrClosed)
+
+// ErrNotRawFrame is returned by ReadRawFrame when device delivered message that was assembled by device hardware (i.e.
+// NGT-1 binary format) and original frames are not available. Reading can be continued with next ReadRawFrame call.
+var ErrNotRawFrame = errors.New("read message is not raw CAN frame")
+
+// RawMessageReader reads raw messages from device.
+//
+// Calling Close while ReadRawMessage is blocked unblocks it and ReadRawMessage returns ErrDeviceClosed. All following
+// reads also return ErrDeviceClosed. Note: blocked read can be unblocked only when underlying transport unblocks on
+// close or has read timeout (serial port, socketcan).
 type RawMessageReader interface {
 	ReadRawMessage(ctx context.Context) (msg RawMessage, err error)
 	Initialize() error
@@ -19,3 +35,15 @@ type RawMessageReaderWriter interface {
 	RawMessageReader
 	RawMessageWriter
 }
+
+// RawFrameReader reads unmodified CAN frames from device. Implemented by devices that receive ordinary CAN frames from
+// hardware (SocketCAN, Actisense W2K-1 RAW formats, Yacht Devices RAW format) so applications can do their own
+// fast-packet assembly or bridge frames between buses.
+type RawFrameReader interface {
+	ReadRawFrame(ctx context.Context) (RawFrame, error)
+}
+
+// RawFrameWriter writes CAN frames to device as is (without fast-packet splitting)
+type RawFrameWriter interface {
+	WriteRawFrame(ctx context.Context, frame RawFrame) error
+}
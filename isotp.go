@@ -0,0 +1,411 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ISO 11783-3 transport protocol (TP) transfers messages up to 1785 bytes with PGN 60416 connection management (TP.CM)
+// frames and PGN 60160 data transfer (TP.DT) frames. Broadcast messages are sent with BAM (broadcast announce message)
+// and data frames. Addressed messages are sent after RTS (request to send) and receiver controls transfer with CTS
+// (clear to send) frames and acknowledges complete message with End of Message Acknowledgment frame.
+const (
+	isoTPControlRTS       = uint8(16)
+	isoTPControlCTS       = uint8(17)
+	isoTPControlEndOfMsg  = uint8(19)
+	isoTPControlBAM       = uint8(32)
+	isoTPControlAbort     = uint8(255)
+	isoTPPacketDataLength = 7
+
+	// ISOTPAbortTimeout is TP.CM abort reason when timeout occurred
+	ISOTPAbortTimeout = uint8(3)
+)
+
+var (
+	// ErrISOTPTimeout is returned when receiver did not respond to transport protocol transfer in time
+	ErrISOTPTimeout = errors.New("transport protocol transfer timed out")
+	// ErrISOTPAborted is returned when receiver aborted transport protocol transfer
+	ErrISOTPAborted = errors.New("transport protocol transfer aborted")
+	// ErrISOTPSessionInProgress is returned when there is already transfer in progress between same nodes
+	ErrISOTPSessionInProgress = errors.New("transport protocol transfer already in progress")
+)
+
+type isoTPKey struct {
+	source      uint8
+	destination uint8
+}
+
+type isoTPTransfer struct {
+	header       CanBusHeader
+	size         int
+	totalPackets uint8
+	received     [256]bool
+	receivedNr   int
+	data         [ISOTPDataMaxSize]byte
+	lastFrame    time.Time
+}
+
+// ISOTPAssembler assembles ISO 11783-3 transport protocol frames (BAM and RTS/CTS transfers) to complete messages.
+// Assembler is passive - it only listens to frames and does not send CTS frames to sender, so for addressed transfers
+// assembler relies on real receiver to do the handshake.
+//
+// Frames that are not transport protocol frames are given to next assembler (i.e. FastPacketAssembler) or passed
+// through as is when next assembler is not set.
+type ISOTPAssembler struct {
+	next       Assembler
+	inTransfer map[isoTPKey]*isoTPTransfer
+
+	now  func() time.Time
+	lock sync.Mutex
+}
+
+// NewISOTPAssembler creates new instance of ISOTPAssembler. Next is optional assembler for non-transport protocol frames.
+func NewISOTPAssembler(next Assembler) *ISOTPAssembler {
+	return &ISOTPAssembler{
+		next:       next,
+		inTransfer: map[isoTPKey]*isoTPTransfer{},
+		now:        time.Now,
+	}
+}
+
+// Assemble adds frame to message assembly. Returns true when message is complete and copied to `to`.
+func (a *ISOTPAssembler) Assemble(frame RawFrame, to *RawMessage) bool {
+	switch PGN(frame.Header.PGN) {
+	case PGNISOTransportProtocolConnectionManagement:
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		a.connectionManagement(frame)
+		return false
+	case PGNISOTransportProtocolDataTransfer:
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		return a.dataTransfer(frame, to)
+	}
+	if a.next != nil {
+		return a.next.Assemble(frame, to)
+	}
+	if cap(to.Data) < int(frame.Length) {
+		to.Data = make([]byte, frame.Length)
+	}
+	to.Data = to.Data[:frame.Length]
+	copy(to.Data, frame.Data[0:frame.Length])
+	to.Time = frame.Time
+	to.Header = frame.Header
+	return true
+}
+
+func (a *ISOTPAssembler) connectionManagement(frame RawFrame) {
+	if frame.Length < 8 {
+		return
+	}
+	key := isoTPKey{source: frame.Header.Source, destination: frame.Header.Destination}
+	switch frame.Data[0] {
+	case isoTPControlRTS, isoTPControlBAM:
+		size := int(frame.Data[1]) | int(frame.Data[2])<<8
+		totalPackets := frame.Data[3]
+		if size > ISOTPDataMaxSize || size == 0 || int(totalPackets) != (size+isoTPPacketDataLength-1)/isoTPPacketDataLength {
+			delete(a.inTransfer, key)
+			return
+		}
+		a.inTransfer[key] = &isoTPTransfer{
+			header: CanBusHeader{
+				PGN:         uint32(frame.Data[5]) | uint32(frame.Data[6])<<8 | uint32(frame.Data[7])<<16,
+				Priority:    frame.Header.Priority,
+				Source:      frame.Header.Source,
+				Destination: frame.Header.Destination,
+			},
+			size:         size,
+			totalPackets: totalPackets,
+			lastFrame:    frame.Time,
+		}
+	case isoTPControlAbort:
+		delete(a.inTransfer, key)
+		// abort can be sent by either party. receiver sends abort with sender as destination
+		delete(a.inTransfer, isoTPKey{source: frame.Header.Destination, destination: frame.Header.Source})
+	}
+}
+
+func (a *ISOTPAssembler) dataTransfer(frame RawFrame, to *RawMessage) bool {
+	if frame.Length < 2 {
+		return false
+	}
+	key := isoTPKey{source: frame.Header.Source, destination: frame.Header.Destination}
+	t, ok := a.inTransfer[key]
+	if !ok {
+		return false
+	}
+	// ISO 11783-3 T1 timeout is 750ms between data packets. Frame time is used as reference so transfers replayed from
+	// recordings (with original times) are assembled as well
+	reference := frame.Time
+	if reference.IsZero() {
+		reference = a.now()
+	}
+	if t.lastFrame.Before(reference.Add(-750 * time.Millisecond)) {
+		delete(a.inTransfer, key)
+		return false
+	}
+	sequence := frame.Data[0]
+	if sequence == 0 || sequence > t.totalPackets {
+		return false
+	}
+	t.lastFrame = frame.Time
+	if !t.received[sequence] {
+		t.received[sequence] = true
+		t.receivedNr++
+		copy(t.data[int(sequence-1)*isoTPPacketDataLength:], frame.Data[1:frame.Length])
+	}
+	if t.receivedNr != int(t.totalPackets) {
+		return false
+	}
+	delete(a.inTransfer, key)
+
+	if cap(to.Data) < t.size {
+		to.Data = make([]byte, t.size)
+	}
+	to.Data = to.Data[:t.size]
+	copy(to.Data, t.data[:t.size])
+	to.Time = frame.Time
+	to.Header = t.header
+	return true
+}
+
+// ISOTPSenderConfig is configuration for ISOTPSender
+type ISOTPSenderConfig struct {
+	// PGNs is list of PGNs that are always sent with transport protocol. Messages longer than fast-packet maximum size
+	// (223 bytes) are always sent with transport protocol.
+	PGNs []uint32
+
+	// BAMPacketInterval is delay between data packets of broadcast (BAM) transfer. ISO 11783-3 requires 50-200ms.
+	// Defaults to: 50ms
+	BAMPacketInterval time.Duration
+	// ResponseTimeout is maximum time to wait for CTS or End of Message Acknowledgment from receiver.
+	// Defaults to: 1250ms (ISO 11783-3 T3)
+	ResponseTimeout time.Duration
+	// MaxPacketsPerCTS is number of packets receiver is allowed to request with single CTS.
+	// Defaults to: 255 (no limit)
+	MaxPacketsPerCTS uint8
+}
+
+// ISOTPSender sends messages with ISO 11783-3 transport protocol. Broadcast messages (destination 255) are sent with
+// BAM and addressed messages with RTS/CTS handshake. Transport protocol frames are sent with message priority so
+// receivers can restore it.
+//
+// For addressed transfers sender needs to see frames that receiver sends back. Device that owns sender gives read
+// frames to HandleFrame so reading must happen concurrently with sending.
+//
+// ISOTPSender is safe for concurrent use.
+type ISOTPSender struct {
+	config ISOTPSenderConfig
+
+	lock     sync.Mutex
+	sessions map[isoTPKey]*isoTPSession
+}
+
+type isoTPSession struct {
+	pgn    uint32
+	frames chan RawFrame
+}
+
+// NewISOTPSender creates new instance of ISOTPSender
+func NewISOTPSender(config ISOTPSenderConfig) *ISOTPSender {
+	if config.BAMPacketInterval <= 0 {
+		config.BAMPacketInterval = 50 * time.Millisecond
+	}
+	if config.ResponseTimeout <= 0 {
+		config.ResponseTimeout = 1250 * time.Millisecond
+	}
+	if config.MaxPacketsPerCTS == 0 {
+		config.MaxPacketsPerCTS = 255
+	}
+	config.PGNs = append([]uint32{}, config.PGNs...)
+	return &ISOTPSender{
+		config:   config,
+		sessions: map[isoTPKey]*isoTPSession{},
+	}
+}
+
+// IsTransportMessage checks if message should be sent with transport protocol
+func (s *ISOTPSender) IsTransportMessage(msg RawMessage) bool {
+	if len(msg.Data) > FastRawPacketMaxSize {
+		return true
+	}
+	for _, pgn := range s.config.PGNs {
+		if pgn == msg.Header.PGN {
+			return len(msg.Data) > 8
+		}
+	}
+	return false
+}
+
+// HandleFrame gives frame read from bus to sender. Returns true when frame was response (CTS, End of Message
+// Acknowledgment, Abort) to transfer in progress.
+func (s *ISOTPSender) HandleFrame(frame RawFrame) bool {
+	if PGN(frame.Header.PGN) != PGNISOTransportProtocolConnectionManagement || frame.Length < 8 {
+		return false
+	}
+	switch frame.Data[0] {
+	case isoTPControlCTS, isoTPControlEndOfMsg, isoTPControlAbort:
+	default:
+		return false
+	}
+	pgn := uint32(frame.Data[5]) | uint32(frame.Data[6])<<8 | uint32(frame.Data[7])<<16
+
+	s.lock.Lock()
+	session, ok := s.sessions[isoTPKey{source: frame.Header.Destination, destination: frame.Header.Source}]
+	s.lock.Unlock()
+	if !ok || session.pgn != pgn {
+		return false
+	}
+	select {
+	case session.frames <- frame:
+	default: // sender is not keeping up, receiver will resend or time out
+	}
+	return true
+}
+
+// Send sends message with transport protocol using write function to send frames to bus
+func (s *ISOTPSender) Send(ctx context.Context, msg RawMessage, write func(frame RawFrame) error) error {
+	size := len(msg.Data)
+	if size <= 8 || size > ISOTPDataMaxSize {
+		return fmt.Errorf("transport protocol message size must be 9-%v bytes, got %v", ISOTPDataMaxSize, size)
+	}
+	if msg.Header.Destination == AddressGlobal {
+		return s.sendBAM(ctx, msg, write)
+	}
+	return s.sendRTS(ctx, msg, write)
+}
+
+func (s *ISOTPSender) sendBAM(ctx context.Context, msg RawMessage, write func(frame RawFrame) error) error {
+	totalPackets := isoTPPacketCount(len(msg.Data))
+	if err := write(s.cmFrame(msg, isoTPControlBAM, totalPackets, 0xFF)); err != nil {
+		return err
+	}
+	for nr := uint8(1); nr <= totalPackets; nr++ {
+		timer := time.NewTimer(s.config.BAMPacketInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if err := write(dtFrame(msg, nr)); err != nil {
+			return err
+		}
+		if nr == 255 {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *ISOTPSender) sendRTS(ctx context.Context, msg RawMessage, write func(frame RawFrame) error) error {
+	key := isoTPKey{source: msg.Header.Source, destination: msg.Header.Destination}
+	session := &isoTPSession{pgn: msg.Header.PGN, frames: make(chan RawFrame, 10)}
+	s.lock.Lock()
+	if _, ok := s.sessions[key]; ok {
+		s.lock.Unlock()
+		return ErrISOTPSessionInProgress
+	}
+	s.sessions[key] = session
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.sessions, key)
+		s.lock.Unlock()
+	}()
+
+	totalPackets := isoTPPacketCount(len(msg.Data))
+	if err := write(s.cmFrame(msg, isoTPControlRTS, totalPackets, s.config.MaxPacketsPerCTS)); err != nil {
+		return err
+	}
+	timer := time.NewTimer(s.config.ResponseTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = write(abortFrame(msg, ISOTPAbortTimeout))
+			return ctx.Err()
+		case <-timer.C:
+			_ = write(abortFrame(msg, ISOTPAbortTimeout))
+			return ErrISOTPTimeout
+		case frame := <-session.frames:
+			switch frame.Data[0] {
+			case isoTPControlEndOfMsg:
+				return nil
+			case isoTPControlAbort:
+				return fmt.Errorf("%w, reason: %v", ErrISOTPAborted, frame.Data[1])
+			case isoTPControlCTS: // count 0 means that receiver wants us to wait (hold connection open)
+				count, next := frame.Data[1], frame.Data[2]
+				for i := uint8(0); i < count && next != 0 && next <= totalPackets; i++ {
+					if err := write(dtFrame(msg, next)); err != nil {
+						return err
+					}
+					if next == 255 {
+						break
+					}
+					next++
+				}
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(s.config.ResponseTimeout)
+		}
+	}
+}
+
+func isoTPPacketCount(size int) uint8 {
+	return uint8((size + isoTPPacketDataLength - 1) / isoTPPacketDataLength)
+}
+
+func (s *ISOTPSender) cmFrame(msg RawMessage, control uint8, totalPackets uint8, maxPackets uint8) RawFrame {
+	size := len(msg.Data)
+	pgn := msg.Header.PGN
+	return RawFrame{
+		Time: msg.Time,
+		Header: CanBusHeader{
+			PGN:         uint32(PGNISOTransportProtocolConnectionManagement),
+			Priority:    msg.Header.Priority,
+			Source:      msg.Header.Source,
+			Destination: msg.Header.Destination,
+		},
+		Length: 8,
+		Data:   [8]byte{control, uint8(size), uint8(size >> 8), totalPackets, maxPackets, uint8(pgn), uint8(pgn >> 8), uint8(pgn >> 16)},
+	}
+}
+
+func abortFrame(msg RawMessage, reason uint8) RawFrame {
+	pgn := msg.Header.PGN
+	return RawFrame{
+		Time: msg.Time,
+		Header: CanBusHeader{
+			PGN:         uint32(PGNISOTransportProtocolConnectionManagement),
+			Priority:    msg.Header.Priority,
+			Source:      msg.Header.Source,
+			Destination: msg.Header.Destination,
+		},
+		Length: 8,
+		Data:   [8]byte{isoTPControlAbort, reason, 0xFF, 0xFF, 0xFF, uint8(pgn), uint8(pgn >> 8), uint8(pgn >> 16)},
+	}
+}
+
+// dtFrame creates data transfer frame with given sequence number (1-255). Unused bytes of last frame are filled with 0xFF.
+func dtFrame(msg RawMessage, sequence uint8) RawFrame {
+	frame := RawFrame{
+		Time: msg.Time,
+		Header: CanBusHeader{
+			PGN:         uint32(PGNISOTransportProtocolDataTransfer),
+			Priority:    msg.Header.Priority,
+			Source:      msg.Header.Source,
+			Destination: msg.Header.Destination,
+		},
+		Length: 8,
+		Data:   [8]byte{sequence, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+	}
+	start := int(sequence-1) * isoTPPacketDataLength
+	copy(frame.Data[1:], msg.Data[start:])
+	return frame
+}
@@ -0,0 +1,301 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func isoTPTestMessage(size int, destination uint8) RawMessage {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = uint8(i)
+	}
+	return RawMessage{
+		Time:   test_test.UTCTime(1665488842),
+		Header: CanBusHeader{PGN: 126464, Priority: 6, Source: 10, Destination: destination},
+		Data:   data,
+	}
+}
+
+func TestISOTPSender_Send_BAM(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{BAMPacketInterval: time.Microsecond})
+	msg := isoTPTestMessage(20, AddressGlobal)
+
+	frames := make([]RawFrame, 0)
+	err := sender.Send(context.Background(), msg, func(frame RawFrame) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	cmHeader := CanBusHeader{PGN: 60416, Priority: 6, Source: 10, Destination: 255}
+	dtHeader := CanBusHeader{PGN: 60160, Priority: 6, Source: 10, Destination: 255}
+	now := msg.Time
+	expect := []RawFrame{
+		{Time: now, Header: cmHeader, Length: 8, Data: [8]byte{32, 20, 0, 3, 0xFF, 0x00, 0xEE, 0x01}},
+		{Time: now, Header: dtHeader, Length: 8, Data: [8]byte{1, 0, 1, 2, 3, 4, 5, 6}},
+		{Time: now, Header: dtHeader, Length: 8, Data: [8]byte{2, 7, 8, 9, 10, 11, 12, 13}},
+		{Time: now, Header: dtHeader, Length: 8, Data: [8]byte{3, 14, 15, 16, 17, 18, 19, 0xFF}},
+	}
+	assert.Equal(t, expect, frames)
+
+	assembler := NewISOTPAssembler(nil)
+	assembler.now = func() time.Time { return now }
+	result := RawMessage{}
+	for i, f := range frames {
+		isComplete := assembler.Assemble(f, &result)
+		assert.Equal(t, i == len(frames)-1, isComplete)
+	}
+	assert.Equal(t, msg, result)
+}
+
+func TestISOTPSender_Send_maxSize(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{BAMPacketInterval: time.Nanosecond})
+	msg := isoTPTestMessage(ISOTPDataMaxSize, AddressGlobal)
+
+	assembler := NewISOTPAssembler(nil)
+	assembler.now = func() time.Time { return msg.Time }
+	result := RawMessage{}
+	frameCount := 0
+	isComplete := false
+	err := sender.Send(context.Background(), msg, func(frame RawFrame) error {
+		frameCount++
+		isComplete = assembler.Assemble(frame, &result)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 256, frameCount) // BAM + 255 data packets
+	assert.True(t, isComplete)
+	assert.Equal(t, msg, result)
+}
+
+func TestISOTPSender_Send_invalidSize(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{})
+	write := func(frame RawFrame) error { return nil }
+
+	err := sender.Send(context.Background(), isoTPTestMessage(8, AddressGlobal), write)
+	assert.EqualError(t, err, "transport protocol message size must be 9-1785 bytes, got 8")
+
+	err = sender.Send(context.Background(), isoTPTestMessage(ISOTPDataMaxSize+1, AddressGlobal), write)
+	assert.EqualError(t, err, "transport protocol message size must be 9-1785 bytes, got 1786")
+}
+
+func ctsFrame(count uint8, next uint8) RawFrame {
+	return RawFrame{
+		Header: CanBusHeader{PGN: 60416, Priority: 7, Source: 20, Destination: 10},
+		Length: 8,
+		Data:   [8]byte{17, count, next, 0xFF, 0xFF, 0x00, 0xEE, 0x01},
+	}
+}
+
+func TestISOTPSender_Send_RTSCTS(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{ResponseTimeout: time.Second, MaxPacketsPerCTS: 2})
+	msg := isoTPTestMessage(30, 20) // 5 packets
+
+	assembler := NewISOTPAssembler(nil)
+	assembler.now = func() time.Time { return msg.Time }
+
+	written := make(chan RawFrame, 20)
+	done := make(chan error)
+	go func() {
+		done <- sender.Send(context.Background(), msg, func(frame RawFrame) error {
+			written <- frame
+			return nil
+		})
+	}()
+
+	rts := <-written
+	assert.Equal(t, [8]byte{16, 30, 0, 5, 2, 0x00, 0xEE, 0x01}, rts.Data)
+	assert.Equal(t, CanBusHeader{PGN: 60416, Priority: 6, Source: 10, Destination: 20}, rts.Header)
+	result := RawMessage{}
+	assert.False(t, assembler.Assemble(rts, &result))
+
+	assert.False(t, sender.HandleFrame(RawFrame{Header: CanBusHeader{PGN: 127250}, Length: 8}))
+	assert.True(t, sender.HandleFrame(ctsFrame(2, 1)))
+	assert.False(t, assembler.Assemble(<-written, &result))
+	assert.False(t, assembler.Assemble(<-written, &result))
+
+	// hold connection open, then request rest of the packets (packet 3 twice as retransmission request)
+	assert.True(t, sender.HandleFrame(ctsFrame(0, 0)))
+	assert.True(t, sender.HandleFrame(ctsFrame(1, 3)))
+	assert.Equal(t, uint8(3), (<-written).Data[0])
+	assert.True(t, sender.HandleFrame(ctsFrame(2, 3)))
+	assert.False(t, assembler.Assemble(<-written, &result))
+	assert.False(t, assembler.Assemble(<-written, &result))
+	assert.True(t, sender.HandleFrame(ctsFrame(2, 5)))
+	last := <-written
+	assert.Equal(t, uint8(5), last.Data[0])
+	assert.True(t, assembler.Assemble(last, &result))
+	assert.Equal(t, msg, result)
+
+	eom := ctsFrame(0, 0)
+	eom.Data = [8]byte{19, 30, 0, 5, 0xFF, 0x00, 0xEE, 0x01}
+	assert.True(t, sender.HandleFrame(eom))
+	assert.NoError(t, <-done)
+
+	assert.False(t, sender.HandleFrame(eom)) // no session anymore
+}
+
+func TestISOTPSender_Send_abortedByReceiver(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{ResponseTimeout: time.Second})
+	msg := isoTPTestMessage(30, 20)
+
+	written := make(chan RawFrame, 20)
+	done := make(chan error)
+	go func() {
+		done <- sender.Send(context.Background(), msg, func(frame RawFrame) error {
+			written <- frame
+			return nil
+		})
+	}()
+	<-written // RTS
+
+	err := sender.Send(context.Background(), msg, func(frame RawFrame) error { return nil })
+	assert.ErrorIs(t, err, ErrISOTPSessionInProgress)
+
+	abort := ctsFrame(0, 0)
+	abort.Data = [8]byte{255, 2, 0xFF, 0xFF, 0xFF, 0x00, 0xEE, 0x01}
+	assert.True(t, sender.HandleFrame(abort))
+
+	err = <-done
+	assert.True(t, errors.Is(err, ErrISOTPAborted))
+	assert.EqualError(t, err, "transport protocol transfer aborted, reason: 2")
+}
+
+func TestISOTPSender_Send_timeout(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{ResponseTimeout: 10 * time.Millisecond})
+	msg := isoTPTestMessage(30, 20)
+
+	frames := make([]RawFrame, 0)
+	err := sender.Send(context.Background(), msg, func(frame RawFrame) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrISOTPTimeout)
+	if assert.Len(t, frames, 2) {
+		assert.Equal(t, [8]byte{255, 3, 0xFF, 0xFF, 0xFF, 0x00, 0xEE, 0x01}, frames[1].Data)
+	}
+}
+
+func TestISOTPSender_IsTransportMessage(t *testing.T) {
+	sender := NewISOTPSender(ISOTPSenderConfig{PGNs: []uint32{65280}})
+
+	assert.True(t, sender.IsTransportMessage(RawMessage{Header: CanBusHeader{PGN: 65280}, Data: make([]byte, 9)}))
+	assert.False(t, sender.IsTransportMessage(RawMessage{Header: CanBusHeader{PGN: 65280}, Data: make([]byte, 8)}))
+	assert.False(t, sender.IsTransportMessage(RawMessage{Header: CanBusHeader{PGN: 126464}, Data: make([]byte, 100)}))
+	assert.True(t, sender.IsTransportMessage(RawMessage{Header: CanBusHeader{PGN: 126464}, Data: make([]byte, 224)}))
+}
+
+func TestISOTPAssembler_Assemble_passThrough(t *testing.T) {
+	frame := RawFrame{
+		Time:   test_test.UTCTime(1665488842),
+		Header: CanBusHeader{PGN: 127250, Priority: 2, Source: 10, Destination: 255},
+		Length: 3,
+		Data:   [8]byte{1, 2, 3},
+	}
+
+	result := RawMessage{}
+	assert.True(t, NewISOTPAssembler(nil).Assemble(frame, &result))
+	assert.Equal(t, RawMessage{Time: frame.Time, Header: frame.Header, Data: []byte{1, 2, 3}}, result)
+
+	// fast-packet frames are given to next assembler
+	fps := exampleFPS()
+	fpMsg := fps.As()
+	fpFrames, err := SplitFastPacket(fpMsg, 3)
+	assert.NoError(t, err)
+
+	fpAssembler := NewFastPacketAssembler([]uint32{130323})
+	fpAssembler.now = func() time.Time { return fpMsg.Time }
+	assembler := NewISOTPAssembler(fpAssembler)
+	result = RawMessage{}
+	for i, f := range fpFrames {
+		assert.Equal(t, i == len(fpFrames)-1, assembler.Assemble(f, &result))
+	}
+	assert.Equal(t, fpMsg, result)
+}
+
+func TestISOTPAssembler_Assemble_abortAndTimeout(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	bam := RawFrame{
+		Time:   now,
+		Header: CanBusHeader{PGN: 60416, Priority: 7, Source: 10, Destination: 255},
+		Length: 8,
+		Data:   [8]byte{32, 9, 0, 2, 0xFF, 0x00, 0xEE, 0x01},
+	}
+	dt1 := RawFrame{
+		Time:   now,
+		Header: CanBusHeader{PGN: 60160, Priority: 7, Source: 10, Destination: 255},
+		Length: 8,
+		Data:   [8]byte{1, 1, 2, 3, 4, 5, 6, 7},
+	}
+	dt2 := dt1
+	dt2.Data = [8]byte{2, 8, 9, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	abort := bam
+	abort.Data = [8]byte{255, 3, 0xFF, 0xFF, 0xFF, 0x00, 0xEE, 0x01}
+
+	assembler := NewISOTPAssembler(nil)
+	assembler.now = func() time.Time { return now }
+	result := RawMessage{}
+
+	// data without announcement is ignored
+	assert.False(t, assembler.Assemble(dt1, &result))
+
+	assert.False(t, assembler.Assemble(bam, &result))
+	assert.False(t, assembler.Assemble(dt1, &result))
+	assert.False(t, assembler.Assemble(abort, &result))
+	assert.False(t, assembler.Assemble(dt2, &result))
+
+	// packets arriving after T1 timeout are discarded
+	assert.False(t, assembler.Assemble(bam, &result))
+	assert.False(t, assembler.Assemble(dt1, &result))
+	late := dt2
+	late.Time = now.Add(time.Second)
+	assert.False(t, assembler.Assemble(late, &result))
+
+	assert.False(t, assembler.Assemble(bam, &result))
+	assert.False(t, assembler.Assemble(dt2, &result)) // out of order
+	assert.True(t, assembler.Assemble(dt1, &result))
+	assert.Equal(t, RawMessage{
+		Time:   now,
+		Header: CanBusHeader{PGN: 126464, Priority: 7, Source: 10, Destination: 255},
+		Data:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9},
+	}, result)
+}
+
+func TestISOTPAssembler_Assemble_recordedTransfer(t *testing.T) {
+	recorded := time.Date(2023, time.May, 10, 21, 16, 16, 0, time.UTC)
+	header := CanBusHeader{PGN: 60160, Priority: 7, Source: 10, Destination: 255}
+	frames := []RawFrame{
+		{
+			Time:   recorded,
+			Header: CanBusHeader{PGN: 60416, Priority: 7, Source: 10, Destination: 255},
+			Length: 8,
+			Data:   [8]byte{32, 9, 0, 2, 0xFF, 0x00, 0xEE, 0x01},
+		},
+		{Time: recorded.Add(50 * time.Millisecond), Header: header, Length: 8, Data: [8]byte{1, 1, 2, 3, 4, 5, 6, 7}},
+		{Time: recorded.Add(100 * time.Millisecond), Header: header, Length: 8, Data: [8]byte{2, 8, 9, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	}
+
+	// assembler uses wall clock (time.Now) but frames are replayed with their original times
+	assembler := NewISOTPAssembler(nil)
+	result := RawMessage{}
+	assert.False(t, assembler.Assemble(frames[0], &result))
+	assert.False(t, assembler.Assemble(frames[1], &result))
+	assert.True(t, assembler.Assemble(frames[2], &result))
+	assert.Equal(t, RawMessage{
+		Time:   recorded.Add(100 * time.Millisecond),
+		Header: CanBusHeader{PGN: 126464, Priority: 7, Source: 10, Destination: 255},
+		Data:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9},
+	}, result)
+
+	// gap in recording longer than T1 timeout discards transfer
+	assert.False(t, assembler.Assemble(frames[0], &result))
+	assert.False(t, assembler.Assemble(frames[1], &result))
+	late := frames[2]
+	late.Time = recorded.Add(time.Second)
+	assert.False(t, assembler.Assemble(late, &result))
+}
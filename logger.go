@@ -0,0 +1,85 @@
+package nmea
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logger is leveled, structured logger used by devices and address mapper. Args are key-value pairs
+// (`"pgn", 129025, "err", err`). Signature matches *slog.Logger methods so slog logger can be used as is and other
+// logging libraries (i.e. zap SugaredLogger) with thin adapter.
+//
+// Raw message bytes dumps are logged on Debug level.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// NopLogger is Logger that discards all log statements
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...any) {}
+func (nopLogger) Info(msg string, args ...any)  {}
+func (nopLogger) Warn(msg string, args ...any)  {}
+
+// LogFuncLogger is Logger that outputs log statements with printf style function as `# LEVEL msg key=value\n` lines.
+// Byte slice values are formatted as hex.
+type LogFuncLogger func(format string, a ...any)
+
+// Debug logs statement with DEBUG level
+func (l LogFuncLogger) Debug(msg string, args ...any) {
+	l.log("DEBUG", msg, args)
+}
+
+// Info logs statement with INFO level
+func (l LogFuncLogger) Info(msg string, args ...any) {
+	l.log("INFO", msg, args)
+}
+
+// Warn logs statement with WARN level
+func (l LogFuncLogger) Warn(msg string, args ...any) {
+	l.log("WARN", msg, args)
+}
+
+func (l LogFuncLogger) log(level string, msg string, args []any) {
+	var sb strings.Builder
+	sb.WriteString("# ")
+	sb.WriteString(level)
+	sb.WriteByte(' ')
+	sb.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		sb.WriteByte(' ')
+		if i+1 == len(args) {
+			fmt.Fprintf(&sb, "!BADKEY=%v", formatLogValue(args[i]))
+			break
+		}
+		fmt.Fprintf(&sb, "%v=%v", args[i], formatLogValue(args[i+1]))
+	}
+	sb.WriteByte('\n')
+	l("%s", sb.String())
+}
+
+func formatLogValue(v any) any {
+	switch tv := v.(type) {
+	case []byte:
+		return fmt.Sprintf("%x", tv)
+	case RawData:
+		return fmt.Sprintf("%x", []byte(tv))
+	}
+	return v
+}
+
+// ResolveLogger returns logger when it is set, otherwise logFunc wrapped as LogFuncLogger or NopLogger when neither
+// is set. Used by device constructors to support both Config.Logger and Config.LogFunc.
+func ResolveLogger(logger Logger, logFunc func(format string, a ...any)) Logger {
+	if logger != nil {
+		return logger
+	}
+	if logFunc != nil {
+		return LogFuncLogger(logFunc)
+	}
+	return NopLogger
+}
@@ -0,0 +1,38 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLogFuncLogger(t *testing.T) {
+	var lines []string
+	logger := LogFuncLogger(func(format string, a ...any) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	})
+
+	logger.Debug("read message", "bytes", []byte{0x01, 0xab}, "data", RawData{0xff})
+	logger.Info("started")
+	logger.Warn("write failed", "pgn", 59904, "err", errors.New("bus off"), "dangling")
+
+	assert.Equal(t, []string{
+		"# DEBUG read message bytes=01ab data=ff\n",
+		"# INFO started\n",
+		"# WARN write failed pgn=59904 err=bus off !BADKEY=dangling\n",
+	}, lines)
+}
+
+func TestResolveLogger(t *testing.T) {
+	assert.Equal(t, NopLogger, ResolveLogger(nil, nil))
+
+	called := false
+	logger := ResolveLogger(nil, func(format string, a ...any) { called = true })
+	logger.Info("x")
+	assert.True(t, called)
+
+	given := LogFuncLogger(func(format string, a ...any) {})
+	assert.IsType(t, given, ResolveLogger(given, func(format string, a ...any) { t.Fatal("must not be called") }))
+	ResolveLogger(given, func(format string, a ...any) { t.Fatal("must not be called") }).Warn("x")
+}
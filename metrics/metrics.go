@@ -0,0 +1,82 @@
+// Package metrics collects read loop and decoder metrics (messages per PGN and source, read and decode errors,
+// fast-packet reassembly drops, read durations) and exposes them in Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"net"
+	"time"
+)
+
+// Collector receives read loop and decoder events. Implementations must be safe for concurrent use.
+type Collector interface {
+	// MessageRead is called for every message read from device. readDuration is time ReadRawMessage call took.
+	MessageRead(msg nmea.RawMessage, readDuration time.Duration)
+	// ReadError is called when reading message from device fails
+	ReadError(err error)
+	// DecodeError is called when message can not be decoded
+	DecodeError(msg nmea.RawMessage, err error)
+}
+
+// Reader decorates nmea.RawMessageReader and reports read messages and errors to Collector
+type Reader struct {
+	reader    nmea.RawMessageReader
+	collector Collector
+	timeNow   func() time.Time
+}
+
+// NewReader creates new instance of metrics collecting Reader
+func NewReader(reader nmea.RawMessageReader, collector Collector) *Reader {
+	return &Reader{reader: reader, collector: collector, timeNow: time.Now}
+}
+
+// ReadRawMessage reads message from wrapped reader. Context cancellation and end of input are not counted as errors.
+func (r *Reader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	start := r.timeNow()
+	msg, err := r.reader.ReadRawMessage(ctx)
+	if err != nil {
+		if ctx.Err() == nil && !isEndOfInput(err) {
+			r.collector.ReadError(err)
+		}
+		return msg, err
+	}
+	r.collector.MessageRead(msg, r.timeNow().Sub(start))
+	return msg, nil
+}
+
+// Initialize initializes wrapped reader
+func (r *Reader) Initialize() error {
+	return r.reader.Initialize()
+}
+
+// Close closes wrapped reader
+func (r *Reader) Close() error {
+	return r.reader.Close()
+}
+
+// Decoder decorates nmea.MessageDecoder and reports decode errors to Collector
+type Decoder struct {
+	decoder   nmea.MessageDecoder
+	collector Collector
+}
+
+// NewDecoder creates new instance of metrics collecting Decoder
+func NewDecoder(decoder nmea.MessageDecoder, collector Collector) *Decoder {
+	return &Decoder{decoder: decoder, collector: collector}
+}
+
+// Decode decodes raw message with wrapped decoder
+func (d *Decoder) Decode(raw nmea.RawMessage) (nmea.Message, error) {
+	msg, err := d.decoder.Decode(raw)
+	if err != nil {
+		d.collector.DecodeError(raw, err)
+	}
+	return msg, err
+}
+
+func isEndOfInput(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)
+}
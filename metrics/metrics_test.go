@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type testReader struct {
+	messages []nmea.RawMessage
+	errs     []error
+}
+
+func (r *testReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		if err != nil {
+			return nmea.RawMessage{}, err
+		}
+	}
+	if len(r.messages) == 0 {
+		return nmea.RawMessage{}, io.EOF
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	return msg, nil
+}
+
+func (r *testReader) Initialize() error {
+	return nil
+}
+
+func (r *testReader) Close() error {
+	return nil
+}
+
+type testCollector struct {
+	messages     []nmea.RawMessage
+	durations    []time.Duration
+	readErrors   []error
+	decodeErrors []error
+}
+
+func (c *testCollector) MessageRead(msg nmea.RawMessage, readDuration time.Duration) {
+	c.messages = append(c.messages, msg)
+	c.durations = append(c.durations, readDuration)
+}
+
+func (c *testCollector) ReadError(err error) {
+	c.readErrors = append(c.readErrors, err)
+}
+
+func (c *testCollector) DecodeError(msg nmea.RawMessage, err error) {
+	c.decodeErrors = append(c.decodeErrors, err)
+}
+
+func TestReader_ReadRawMessage(t *testing.T) {
+	collector := &testCollector{}
+	reader := NewReader(&testReader{
+		messages: []nmea.RawMessage{{Header: nmea.CanBusHeader{PGN: 129025}}},
+		errs:     []error{errors.New("read failed")},
+	}, collector)
+	now := time.Unix(1665488842, 0)
+	reader.timeNow = func() time.Time {
+		now = now.Add(5 * time.Millisecond)
+		return now
+	}
+
+	_, err := reader.ReadRawMessage(context.Background())
+	assert.EqualError(t, err, "read failed")
+
+	msg, err := reader.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(129025), msg.Header.PGN)
+
+	_, err = reader.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Equal(t, []nmea.RawMessage{msg}, collector.messages)
+	assert.Equal(t, []time.Duration{5 * time.Millisecond}, collector.durations)
+	assert.Len(t, collector.readErrors, 1) // io.EOF is not counted
+}
+
+type testDecoder struct{}
+
+func (d testDecoder) Decode(raw nmea.RawMessage) (nmea.Message, error) {
+	if raw.Header.PGN == 1 {
+		return nmea.Message{}, errors.New("unknown PGN")
+	}
+	return nmea.Message{Header: raw.Header}, nil
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	collector := &testCollector{}
+	decoder := NewDecoder(testDecoder{}, collector)
+
+	_, err := decoder.Decode(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}})
+	assert.EqualError(t, err, "unknown PGN")
+
+	msg, err := decoder.Decode(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), msg.Header.PGN)
+
+	assert.Len(t, collector.decodeErrors, 1)
+}
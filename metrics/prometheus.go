@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are upper bounds (in seconds) of read duration and latency histogram buckets
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+type messageKey struct {
+	pgn    uint32
+	source uint8
+}
+
+// Prometheus is Collector that keeps metrics in memory and writes them in Prometheus text exposition format. Prometheus
+// implements http.Handler so it can be served as `/metrics` endpoint.
+//
+// Exposed metrics:
+//   - nmea_messages_total{pgn,source} - number of read messages
+//   - nmea_read_errors_total - number of failed reads
+//   - nmea_decode_errors_total{pgn} - number of messages that failed to decode
+//   - nmea_read_duration_seconds - histogram of ReadRawMessage call durations
+//   - nmea_message_latency_seconds - histogram of time between bus time and read time (for devices with timestamps)
+//   - nmea_frames_total, nmea_fast_packet_dropped_total - frames given to and incomplete sequences discarded by
+//     registered fast-packet assemblers
+type Prometheus struct {
+	lock sync.Mutex
+
+	messages     map[messageKey]uint64
+	decodeErrors map[uint32]uint64
+	readErrors   uint64
+	readDuration histogram
+	latency      histogram
+
+	assemblers []*nmea.FastPacketAssembler
+}
+
+// NewPrometheus creates new instance of Prometheus collector
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		messages:     map[messageKey]uint64{},
+		decodeErrors: map[uint32]uint64{},
+		readDuration: newHistogram(DefaultDurationBuckets),
+		latency:      newHistogram(DefaultDurationBuckets),
+	}
+}
+
+// RegisterFastPacketAssembler adds assembler which frame and drop counts are exposed
+func (p *Prometheus) RegisterFastPacketAssembler(a *nmea.FastPacketAssembler) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.assemblers = append(p.assemblers, a)
+}
+
+// MessageRead counts read message and observes read duration and bus latency
+func (p *Prometheus) MessageRead(msg nmea.RawMessage, readDuration time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.messages[messageKey{pgn: msg.Header.PGN, source: msg.Header.Source}]++
+	p.readDuration.observe(readDuration.Seconds())
+	if !msg.BusTime.IsZero() && !msg.Time.IsZero() {
+		p.latency.observe(msg.Time.Sub(msg.BusTime).Seconds())
+	}
+}
+
+// ReadError counts failed read
+func (p *Prometheus) ReadError(err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.readErrors++
+}
+
+// DecodeError counts message that failed to decode
+func (p *Prometheus) DecodeError(msg nmea.RawMessage, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.decodeErrors[msg.Header.PGN]++
+}
+
+// ServeHTTP writes metrics in Prometheus text exposition format
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes metrics in Prometheus text exposition format to writer
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	cw := &countingWriter{writer: bufio.NewWriter(w)}
+
+	keys := make([]messageKey, 0, len(p.messages))
+	for k := range p.messages {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pgn == keys[j].pgn {
+			return keys[i].source < keys[j].source
+		}
+		return keys[i].pgn < keys[j].pgn
+	})
+	writeHeader(cw, "nmea_messages_total", "counter", "Number of messages read from device.")
+	for _, k := range keys {
+		fmt.Fprintf(cw, "nmea_messages_total{pgn=\"%d\",source=\"%d\"} %d\n", k.pgn, k.source, p.messages[k])
+	}
+
+	writeHeader(cw, "nmea_read_errors_total", "counter", "Number of failed reads from device.")
+	fmt.Fprintf(cw, "nmea_read_errors_total %d\n", p.readErrors)
+
+	pgns := make([]uint32, 0, len(p.decodeErrors))
+	for pgn := range p.decodeErrors {
+		pgns = append(pgns, pgn)
+	}
+	sort.Slice(pgns, func(i, j int) bool { return pgns[i] < pgns[j] })
+	writeHeader(cw, "nmea_decode_errors_total", "counter", "Number of messages that failed to decode.")
+	for _, pgn := range pgns {
+		fmt.Fprintf(cw, "nmea_decode_errors_total{pgn=\"%d\"} %d\n", pgn, p.decodeErrors[pgn])
+	}
+
+	writeHeader(cw, "nmea_read_duration_seconds", "histogram", "Duration of device read calls.")
+	p.readDuration.writeTo(cw, "nmea_read_duration_seconds")
+	writeHeader(cw, "nmea_message_latency_seconds", "histogram", "Time between message bus time and read time.")
+	p.latency.writeTo(cw, "nmea_message_latency_seconds")
+
+	if len(p.assemblers) > 0 {
+		stats := nmea.FastPacketAssemblerStats{}
+		for _, a := range p.assemblers {
+			s := a.Stats()
+			stats.Frames += s.Frames
+			stats.Dropped += s.Dropped
+		}
+		writeHeader(cw, "nmea_frames_total", "counter", "Number of frames given to fast-packet assembler.")
+		fmt.Fprintf(cw, "nmea_frames_total %d\n", stats.Frames)
+		writeHeader(cw, "nmea_fast_packet_dropped_total", "counter", "Number of incomplete fast-packet sequences discarded.")
+		fmt.Fprintf(cw, "nmea_fast_packet_dropped_total %d\n", stats.Dropped)
+	}
+
+	if err := cw.writer.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+func writeHeader(w io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+}
+
+// histogram is cumulative Prometheus histogram
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) histogram {
+	return histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) writeTo(w io.Writer, name string) {
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%v_bucket{le=\"%v\"} %d\n", name, strconv.FormatFloat(b, 'g', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(w, "%v_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%v_sum %v\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%v_count %d\n", name, h.count)
+}
+
+type countingWriter struct {
+	writer *bufio.Writer
+	n      int64
+	err    error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.writer.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrometheus_WriteTo(t *testing.T) {
+	p := NewPrometheus()
+	now := time.Unix(1665488842, 0)
+
+	p.MessageRead(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 129026, Source: 3}}, 2*time.Millisecond)
+	p.MessageRead(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 129025, Source: 3}}, 20*time.Millisecond)
+	p.MessageRead(nmea.RawMessage{
+		Time:    now,
+		BusTime: now.Add(-50 * time.Millisecond),
+		Header:  nmea.CanBusHeader{PGN: 129025, Source: 3},
+	}, 2*time.Second)
+	p.ReadError(errors.New("read failed"))
+	p.DecodeError(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 65280}}, errors.New("unknown PGN"))
+
+	assembler := nmea.NewFastPacketAssembler(nil)
+	assembler.Assemble(nmea.RawFrame{Header: nmea.CanBusHeader{PGN: 129025}, Length: 8}, &nmea.RawMessage{})
+	p.RegisterFastPacketAssembler(assembler)
+
+	buf := bytes.Buffer{}
+	n, err := p.WriteTo(&buf)
+
+	expect := `# HELP nmea_messages_total Number of messages read from device.
+# TYPE nmea_messages_total counter
+nmea_messages_total{pgn="129025",source="3"} 2
+nmea_messages_total{pgn="129026",source="3"} 1
+# HELP nmea_read_errors_total Number of failed reads from device.
+# TYPE nmea_read_errors_total counter
+nmea_read_errors_total 1
+# HELP nmea_decode_errors_total Number of messages that failed to decode.
+# TYPE nmea_decode_errors_total counter
+nmea_decode_errors_total{pgn="65280"} 1
+# HELP nmea_read_duration_seconds Duration of device read calls.
+# TYPE nmea_read_duration_seconds histogram
+nmea_read_duration_seconds_bucket{le="0.001"} 0
+nmea_read_duration_seconds_bucket{le="0.005"} 1
+nmea_read_duration_seconds_bucket{le="0.01"} 1
+nmea_read_duration_seconds_bucket{le="0.05"} 2
+nmea_read_duration_seconds_bucket{le="0.1"} 2
+nmea_read_duration_seconds_bucket{le="0.5"} 2
+nmea_read_duration_seconds_bucket{le="1"} 2
+nmea_read_duration_seconds_bucket{le="5"} 3
+nmea_read_duration_seconds_bucket{le="+Inf"} 3
+nmea_read_duration_seconds_sum 2.022
+nmea_read_duration_seconds_count 3
+# HELP nmea_message_latency_seconds Time between message bus time and read time.
+# TYPE nmea_message_latency_seconds histogram
+nmea_message_latency_seconds_bucket{le="0.001"} 0
+nmea_message_latency_seconds_bucket{le="0.005"} 0
+nmea_message_latency_seconds_bucket{le="0.01"} 0
+nmea_message_latency_seconds_bucket{le="0.05"} 1
+nmea_message_latency_seconds_bucket{le="0.1"} 1
+nmea_message_latency_seconds_bucket{le="0.5"} 1
+nmea_message_latency_seconds_bucket{le="1"} 1
+nmea_message_latency_seconds_bucket{le="5"} 1
+nmea_message_latency_seconds_bucket{le="+Inf"} 1
+nmea_message_latency_seconds_sum 0.05
+nmea_message_latency_seconds_count 1
+# HELP nmea_frames_total Number of frames given to fast-packet assembler.
+# TYPE nmea_frames_total counter
+nmea_frames_total 1
+# HELP nmea_fast_packet_dropped_total Number of incomplete fast-packet sequences discarded.
+# TYPE nmea_fast_packet_dropped_total counter
+nmea_fast_packet_dropped_total 0
+`
+	assert.NoError(t, err)
+	assert.Equal(t, expect, buf.String())
+	assert.Equal(t, int64(len(expect)), n)
+}
+
+func TestPrometheus_ServeHTTP(t *testing.T) {
+	p := NewPrometheus()
+	p.ReadError(errors.New("read failed"))
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "nmea_read_errors_total 1\n")
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// PGNStats contains statistics of messages with same PGN from same source
+type PGNStats struct {
+	PGN    uint32 `json:"pgn"`
+	Source uint8  `json:"source"`
+	// Count is number of read messages
+	Count uint64 `json:"count"`
+	// Rate is average number of messages per second between first and last seen message
+	Rate float64 `json:"rate"`
+	// FirstSeen is time of first read message
+	FirstSeen time.Time `json:"first_seen"`
+	// LastSeen is time of last read message
+	LastSeen time.Time `json:"last_seen"`
+	// DecodeErrors is number of messages that failed to decode
+	DecodeErrors uint64 `json:"decode_errors"`
+}
+
+// StatsSnapshot contains statistics of all read messages at given time
+type StatsSnapshot struct {
+	Time       time.Time `json:"time"`
+	Messages   uint64    `json:"messages"`
+	ReadErrors uint64    `json:"read_errors"`
+	// PGNs contains statistics per PGN and source, ordered by PGN and source
+	PGNs []PGNStats `json:"pgns"`
+}
+
+// Stats is Collector that keeps message counts, rates, last seen times and decode error counts per PGN and source.
+// Similar to Canboat `analyzer -stats`, useful to diagnose chatty devices and missing data. Stats implements
+// http.Handler serving snapshot as JSON.
+type Stats struct {
+	lock    sync.Mutex
+	timeNow func() time.Time
+
+	pgns       map[messageKey]*PGNStats
+	messages   uint64
+	readErrors uint64
+}
+
+// NewStats creates new instance of Stats collector
+func NewStats() *Stats {
+	return &Stats{
+		timeNow: time.Now,
+		pgns:    map[messageKey]*PGNStats{},
+	}
+}
+
+// MessageRead counts read message. Message time is used as seen time when it is set.
+func (s *Stats) MessageRead(msg nmea.RawMessage, readDuration time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	seen := msg.Time
+	if seen.IsZero() {
+		seen = s.timeNow()
+	}
+	ps := s.pgnStats(msg.Header)
+	if ps.Count == 0 {
+		ps.FirstSeen = seen
+	}
+	ps.Count++
+	ps.LastSeen = seen
+	s.messages++
+}
+
+// ReadError counts failed read
+func (s *Stats) ReadError(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.readErrors++
+}
+
+// DecodeError counts message that failed to decode
+func (s *Stats) DecodeError(msg nmea.RawMessage, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pgnStats(msg.Header).DecodeErrors++
+}
+
+func (s *Stats) pgnStats(header nmea.CanBusHeader) *PGNStats {
+	key := messageKey{pgn: header.PGN, source: header.Source}
+	ps, ok := s.pgns[key]
+	if !ok {
+		ps = &PGNStats{PGN: header.PGN, Source: header.Source}
+		s.pgns[key] = ps
+	}
+	return ps
+}
+
+// Snapshot returns current statistics
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := StatsSnapshot{
+		Time:       s.timeNow(),
+		Messages:   s.messages,
+		ReadErrors: s.readErrors,
+		PGNs:       make([]PGNStats, 0, len(s.pgns)),
+	}
+	for _, ps := range s.pgns {
+		tmp := *ps
+		if elapsed := tmp.LastSeen.Sub(tmp.FirstSeen).Seconds(); tmp.Count > 1 && elapsed > 0 {
+			tmp.Rate = float64(tmp.Count-1) / elapsed
+		}
+		result.PGNs = append(result.PGNs, tmp)
+	}
+	sort.Slice(result.PGNs, func(i, j int) bool {
+		if result.PGNs[i].PGN == result.PGNs[j].PGN {
+			return result.PGNs[i].Source < result.PGNs[j].Source
+		}
+		return result.PGNs[i].PGN < result.PGNs[j].PGN
+	})
+	return result
+}
+
+// WriteTable writes statistics as text table to writer. Every line is prefixed with `# ` so table can be mixed with
+// other output.
+func (s *Stats) WriteTable(w io.Writer) error {
+	snapshot := s.Snapshot()
+
+	table := new(bytes.Buffer)
+	tw := tabwriter.NewWriter(table, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "PGN\tsource\tcount\tmsg/s\tlast seen\tdecode errors\t\n")
+	for _, ps := range snapshot.PGNs {
+		lastSeen := "-"
+		if !ps.LastSeen.IsZero() {
+			lastSeen = ps.LastSeen.Format("15:04:05.000")
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.2f\t%v\t%d\t\n", ps.PGN, ps.Source, ps.Count, ps.Rate, lastSeen, ps.DecodeErrors)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(table.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# %s", line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "# Messages: %d, read errors: %d\n", snapshot.Messages, snapshot.ReadErrors)
+	return err
+}
+
+// ServeHTTP writes statistics snapshot as JSON
+func (s *Stats) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+// Collectors is Collector that reports events to all its collectors
+type Collectors []Collector
+
+// MessageRead reports read message to all collectors
+func (c Collectors) MessageRead(msg nmea.RawMessage, readDuration time.Duration) {
+	for _, collector := range c {
+		collector.MessageRead(msg, readDuration)
+	}
+}
+
+// ReadError reports failed read to all collectors
+func (c Collectors) ReadError(err error) {
+	for _, collector := range c {
+		collector.ReadError(err)
+	}
+}
+
+// DecodeError reports message that failed to decode to all collectors
+func (c Collectors) DecodeError(msg nmea.RawMessage, err error) {
+	for _, collector := range c {
+		collector.DecodeError(msg, err)
+	}
+}
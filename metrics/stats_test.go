@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStats_Snapshot(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+	s := NewStats()
+	s.timeNow = func() time.Time { return now }
+
+	s.MessageRead(nmea.RawMessage{Time: now, Header: nmea.CanBusHeader{PGN: 129025, Source: 3}}, 0)
+	s.MessageRead(nmea.RawMessage{Time: now.Add(time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 3}}, 0)
+	s.MessageRead(nmea.RawMessage{Time: now.Add(2 * time.Second), Header: nmea.CanBusHeader{PGN: 129025, Source: 3}}, 0)
+	s.MessageRead(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 129025, Source: 1}}, 0) // without time
+	s.ReadError(errors.New("read failed"))
+	s.DecodeError(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 65280, Source: 2}}, errors.New("unknown PGN"))
+
+	assert.Equal(t, StatsSnapshot{
+		Time:       now,
+		Messages:   4,
+		ReadErrors: 1,
+		PGNs: []PGNStats{
+			{PGN: 65280, Source: 2, DecodeErrors: 1},
+			{PGN: 129025, Source: 1, Count: 1, FirstSeen: now, LastSeen: now},
+			{PGN: 129025, Source: 3, Count: 3, Rate: 1, FirstSeen: now, LastSeen: now.Add(2 * time.Second)},
+		},
+	}, s.Snapshot())
+}
+
+func TestStats_WriteTable(t *testing.T) {
+	now := time.Unix(1665488842, 0).UTC()
+	s := NewStats()
+	s.timeNow = func() time.Time { return now }
+
+	s.MessageRead(nmea.RawMessage{Time: now, Header: nmea.CanBusHeader{PGN: 129025, Source: 3}}, 0)
+	s.MessageRead(nmea.RawMessage{Time: now.Add(500 * time.Millisecond), Header: nmea.CanBusHeader{PGN: 129025, Source: 3}}, 0)
+	s.DecodeError(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 65280, Source: 2}}, errors.New("unknown PGN"))
+
+	buf := bytes.Buffer{}
+	err := s.WriteTable(&buf)
+
+	expect := `#      PGN  source  count  msg/s     last seen  decode errors
+#    65280       2      0   0.00             -              1
+#   129025       3      2   2.00  11:47:22.500              0
+# Messages: 2, read errors: 0
+`
+	assert.NoError(t, err)
+	assert.Equal(t, expect, buf.String())
+}
+
+func TestStats_ServeHTTP(t *testing.T) {
+	s := NewStats()
+	s.timeNow = func() time.Time { return time.Unix(1665488842, 0).UTC() }
+	s.ReadError(errors.New("read failed"))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/stats", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"time":"2022-10-11T11:47:22Z","messages":0,"read_errors":1,"pgns":[]}`, rec.Body.String())
+}
+
+func TestCollectors(t *testing.T) {
+	a := &testCollector{}
+	b := &testCollector{}
+	c := Collectors{a, b}
+
+	c.MessageRead(nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 129025}}, time.Millisecond)
+	c.ReadError(errors.New("read failed"))
+	c.DecodeError(nmea.RawMessage{}, errors.New("decode failed"))
+
+	for _, collector := range []*testCollector{a, b} {
+		assert.Len(t, collector.messages, 1)
+		assert.Equal(t, []error{errors.New("read failed")}, collector.readErrors)
+		assert.Equal(t, []error{errors.New("decode failed")}, collector.decodeErrors)
+	}
+}
@@ -0,0 +1,253 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// MockDeviceConfig configures MockDevice
+type MockDeviceConfig struct {
+	// Periodic messages are emitted with their intervals. First emission happens on first read.
+	Periodic []PeriodicMessage
+
+	// Recorded messages (i.e. loaded from file with ReadAllRawMessages) are emitted in order. Message time is set to
+	// emission time.
+	Recorded []RawMessage
+	// Realtime keeps time between Recorded messages same as in recording. When false recorded messages are emitted as
+	// fast as they are read.
+	Realtime bool
+	// Loop starts emitting Recorded messages from the beginning when all of them have been emitted.
+	Loop bool
+
+	// OnWrite is called for every written message. Can be used to script responses to requests (i.e. call Emit with
+	// Product Information message when ISO Request for PGN 126996 is written).
+	OnWrite func(device *MockDevice, msg RawMessage)
+	// WriteError is returned by WriteRawMessage when set. Written message is not captured.
+	WriteError error
+
+	// EOFWhenDone makes ReadRawMessage to return io.EOF when there is nothing left to emit (no periodic messages,
+	// recorded messages are emitted without loop and no emitted messages are queued). Otherwise ReadRawMessage blocks
+	// until message is emitted, device is closed or context is cancelled.
+	EOFWhenDone bool
+}
+
+// MockDevice is virtual bus device for testing applications without hardware. It emits scripted, periodic and recorded
+// messages to reader and captures written messages for assertions.
+//
+// MockDevice is safe for concurrent use.
+type MockDevice struct {
+	config MockDeviceConfig
+
+	mu         sync.Mutex
+	queue      []RawMessage
+	written    []RawMessage
+	started    bool
+	periodic   []time.Time // next emission time of each periodic message
+	recorded   int         // index of next recorded message
+	recordBase time.Time   // emission time of first recorded message in current loop
+	notify     chan struct{}
+	closed     bool
+	closing    chan struct{}
+
+	timeNow func() time.Time
+}
+
+// NewMockDevice creates new instance of MockDevice
+func NewMockDevice(config MockDeviceConfig) *MockDevice {
+	return &MockDevice{
+		config:  config,
+		notify:  make(chan struct{}),
+		closing: make(chan struct{}),
+		timeNow: time.Now,
+	}
+}
+
+// ReadAllRawMessages reads messages from reader until io.EOF. Useful to load recorded files for MockDeviceConfig.Recorded.
+func ReadAllRawMessages(ctx context.Context, reader RawMessageReader) ([]RawMessage, error) {
+	result := make([]RawMessage, 0)
+	for {
+		msg, err := reader.ReadRawMessage(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return result, err
+		}
+		result = append(result, msg)
+	}
+}
+
+// Initialize does nothing. Exists to implement RawMessageReaderWriter interface.
+func (d *MockDevice) Initialize() error {
+	return nil
+}
+
+// Emit queues messages to be read. Queued messages are read before periodic and recorded messages. Message time is
+// set to read time when it is empty.
+func (d *MockDevice) Emit(messages ...RawMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queue = append(d.queue, messages...)
+	close(d.notify)
+	d.notify = make(chan struct{})
+}
+
+// Written returns copy of messages written to device
+func (d *MockDevice) Written() []RawMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]RawMessage(nil), d.written...)
+}
+
+// WriteRawMessage captures written message and calls MockDeviceConfig.OnWrite
+func (d *MockDevice) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrDeviceClosed
+	}
+	if d.config.WriteError != nil {
+		d.mu.Unlock()
+		return d.config.WriteError
+	}
+	d.written = append(d.written, msg)
+	d.mu.Unlock()
+
+	if d.config.OnWrite != nil {
+		d.config.OnWrite(d, msg)
+	}
+	return nil
+}
+
+// ReadRawMessage returns next emitted message. Blocks until next periodic or recorded message is due.
+func (d *MockDevice) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	for {
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			return RawMessage{}, ErrDeviceClosed
+		}
+		now := d.timeNow()
+		if !d.started {
+			d.start(now)
+		}
+		if len(d.queue) > 0 {
+			msg := d.queue[0]
+			d.queue = d.queue[1:]
+			d.mu.Unlock()
+			if msg.Time.IsZero() {
+				msg.Time = now
+			}
+			return msg, nil
+		}
+
+		due, periodicIndex, ok := d.nextDue(now)
+		if ok && !due.After(now) {
+			msg := d.take(due, periodicIndex)
+			d.mu.Unlock()
+			return msg, nil
+		}
+		if !ok && d.config.EOFWhenDone {
+			d.mu.Unlock()
+			return RawMessage{}, io.EOF
+		}
+		notify := d.notify
+		d.mu.Unlock()
+
+		if err := d.wait(ctx, notify, due.Sub(now), ok); err != nil {
+			return RawMessage{}, err
+		}
+	}
+}
+
+func (d *MockDevice) wait(ctx context.Context, notify chan struct{}, timeout time.Duration, hasTimeout bool) error {
+	var timeoutC <-chan time.Time
+	if hasTimeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.closing:
+		return ErrDeviceClosed
+	case <-notify:
+	case <-timeoutC:
+	}
+	return nil
+}
+
+func (d *MockDevice) start(now time.Time) {
+	d.started = true
+	d.periodic = make([]time.Time, len(d.config.Periodic))
+	for i := range d.periodic {
+		d.periodic[i] = now
+	}
+	d.recordBase = now
+}
+
+// nextDue returns time when next periodic or recorded message is due. Periodic index is -1 when next message is
+// recorded message.
+func (d *MockDevice) nextDue(now time.Time) (time.Time, int, bool) {
+	due := time.Time{}
+	index := -1
+	ok := false
+	if len(d.config.Recorded) > 0 && (d.recorded < len(d.config.Recorded) || d.config.Loop) {
+		if d.recorded == len(d.config.Recorded) { // start next loop
+			d.recordBase = d.recordBase.Add(recordingSpan(d.config.Recorded))
+			d.recorded = 0
+		}
+		due = now
+		if d.config.Realtime {
+			due = d.recordBase.Add(d.config.Recorded[d.recorded].Time.Sub(d.config.Recorded[0].Time))
+		}
+		ok = true
+	}
+	for i, next := range d.periodic {
+		if d.config.Periodic[i].Interval <= 0 {
+			continue
+		}
+		if !ok || next.Before(due) {
+			due = next
+			index = i
+			ok = true
+		}
+	}
+	return due, index, ok
+}
+
+func recordingSpan(recorded []RawMessage) time.Duration {
+	return recorded[len(recorded)-1].Time.Sub(recorded[0].Time)
+}
+
+func (d *MockDevice) take(due time.Time, periodicIndex int) RawMessage {
+	if periodicIndex == -1 {
+		msg := d.config.Recorded[d.recorded]
+		d.recorded++
+		msg.Time = due
+		return msg
+	}
+	p := d.config.Periodic[periodicIndex]
+	d.periodic[periodicIndex] = due.Add(p.Interval)
+	msg := p.Create(due)
+	if msg.Time.IsZero() {
+		msg.Time = due
+	}
+	return msg
+}
+
+// Close closes device. Blocked ReadRawMessage call is unblocked with ErrDeviceClosed error.
+func (d *MockDevice) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	close(d.closing)
+	return nil
+}
@@ -0,0 +1,200 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type mockClock struct {
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func readMessages(t *testing.T, d *MockDevice, count int) []RawMessage {
+	result := make([]RawMessage, 0, count)
+	for i := 0; i < count; i++ {
+		msg, err := d.ReadRawMessage(context.Background())
+		if !assert.NoError(t, err) {
+			break
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+func TestMockDevice_Emit(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	d := NewMockDevice(MockDeviceConfig{EOFWhenDone: true})
+	d.timeNow = func() time.Time { return now }
+
+	d.Emit(
+		RawMessage{Header: CanBusHeader{PGN: 127250}},
+		RawMessage{Time: now.Add(-time.Second), Header: CanBusHeader{PGN: 127251}},
+	)
+
+	assert.Equal(t, []RawMessage{
+		{Time: now, Header: CanBusHeader{PGN: 127250}},
+		{Time: now.Add(-time.Second), Header: CanBusHeader{PGN: 127251}},
+	}, readMessages(t, d, 2))
+
+	_, err := d.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestMockDevice_WriteRawMessage(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	productInfo := RawMessage{Header: CanBusHeader{PGN: uint32(PGNProductInfo), Source: 5}, Data: RawData{1, 2}}
+	d := NewMockDevice(MockDeviceConfig{
+		OnWrite: func(device *MockDevice, msg RawMessage) {
+			if msg.Header.PGN == uint32(PGNISORequest) {
+				device.Emit(productInfo)
+			}
+		},
+	})
+	d.timeNow = func() time.Time { return now }
+
+	request := RawMessage{Header: CanBusHeader{PGN: uint32(PGNISORequest), Destination: 5}, Data: RawData{0x14, 0xf0, 0x01}}
+	assert.NoError(t, d.WriteRawMessage(context.Background(), request))
+	assert.Equal(t, []RawMessage{request}, d.Written())
+
+	productInfo.Time = now
+	assert.Equal(t, []RawMessage{productInfo}, readMessages(t, d, 1))
+}
+
+func TestMockDevice_WriteRawMessage_error(t *testing.T) {
+	d := NewMockDevice(MockDeviceConfig{WriteError: errors.New("bus-off")})
+
+	err := d.WriteRawMessage(context.Background(), RawMessage{})
+	assert.EqualError(t, err, "bus-off")
+	assert.Len(t, d.Written(), 0)
+
+	d.Close()
+	err = d.WriteRawMessage(context.Background(), RawMessage{})
+	assert.ErrorIs(t, err, ErrDeviceClosed)
+}
+
+func TestMockDevice_periodic(t *testing.T) {
+	clock := &mockClock{now: test_test.UTCTime(1665488842)}
+	start := clock.now
+	d := NewMockDevice(MockDeviceConfig{
+		Periodic: []PeriodicMessage{
+			{Interval: 100 * time.Millisecond, Create: func(now time.Time) RawMessage {
+				return RawMessage{Header: CanBusHeader{PGN: 127250}}
+			}},
+			{Interval: 250 * time.Millisecond, Create: func(now time.Time) RawMessage {
+				return RawMessage{Header: CanBusHeader{PGN: 129025}}
+			}},
+		},
+	})
+	d.timeNow = clock.Now
+
+	result := readMessages(t, d, 2)
+	clock.now = start.Add(300 * time.Millisecond)
+	result = append(result, readMessages(t, d, 4)...)
+
+	times := make([]time.Duration, 0, len(result))
+	pgns := make([]uint32, 0, len(result))
+	for _, msg := range result {
+		times = append(times, msg.Time.Sub(start))
+		pgns = append(pgns, msg.Header.PGN)
+	}
+	assert.Equal(t, []uint32{127250, 129025, 127250, 127250, 129025, 127250}, pgns)
+	assert.Equal(t, []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond, 300 * time.Millisecond}, times)
+}
+
+func TestMockDevice_recorded(t *testing.T) {
+	recordedAt := test_test.UTCTime(1600000000)
+	recorded := []RawMessage{
+		{Time: recordedAt, Header: CanBusHeader{PGN: 1}},
+		{Time: recordedAt.Add(10 * time.Millisecond), Header: CanBusHeader{PGN: 2}},
+		{Time: recordedAt.Add(30 * time.Millisecond), Header: CanBusHeader{PGN: 3}},
+	}
+	var testCases = []struct {
+		name        string
+		config      MockDeviceConfig
+		expectPGNs  []uint32
+		expectTimes []time.Duration
+	}{
+		{
+			name:        "ok, realtime loop",
+			config:      MockDeviceConfig{Recorded: recorded, Realtime: true, Loop: true},
+			expectPGNs:  []uint32{1, 2, 3, 1, 2, 3},
+			expectTimes: []time.Duration{0, 10 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond},
+		},
+		{
+			name:        "ok, as fast as possible",
+			config:      MockDeviceConfig{Recorded: recorded, EOFWhenDone: true},
+			expectPGNs:  []uint32{1, 2, 3},
+			expectTimes: []time.Duration{0, time.Second, time.Second},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := test_test.UTCTime(1665488842)
+			clock := &mockClock{now: start}
+			d := NewMockDevice(tc.config)
+			d.timeNow = clock.Now
+
+			result := readMessages(t, d, 1)
+			clock.now = start.Add(time.Second) // all following messages are due
+			result = append(result, readMessages(t, d, len(tc.expectPGNs)-1)...)
+			pgns := make([]uint32, 0, len(result))
+			times := make([]time.Duration, 0, len(result))
+			for _, msg := range result {
+				pgns = append(pgns, msg.Header.PGN)
+				times = append(times, msg.Time.Sub(start))
+			}
+			assert.Equal(t, tc.expectPGNs, pgns)
+			assert.Equal(t, tc.expectTimes, times)
+
+			if tc.config.EOFWhenDone {
+				_, err := d.ReadRawMessage(context.Background())
+				assert.ErrorIs(t, err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestMockDevice_ReadRawMessage_blocksUntilEmitOrClose(t *testing.T) {
+	d := NewMockDevice(MockDeviceConfig{})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.Emit(RawMessage{Header: CanBusHeader{PGN: 127250}})
+	}()
+	msg, err := d.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(127250), msg.Header.PGN)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.Close()
+	}()
+	_, err = d.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, ErrDeviceClosed)
+}
+
+func TestMockDevice_ReadRawMessage_contextCancelled(t *testing.T) {
+	d := NewMockDevice(MockDeviceConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := d.ReadRawMessage(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReadAllRawMessages(t *testing.T) {
+	messages := []RawMessage{{Header: CanBusHeader{PGN: 1}}, {Header: CanBusHeader{PGN: 2}}}
+
+	result, err := ReadAllRawMessages(context.Background(), &sliceReader{messages: messages})
+	assert.NoError(t, err)
+	assert.Equal(t, messages, result)
+}
@@ -0,0 +1,383 @@
+// Package mqtt bridges NMEA2000 bus to MQTT broker. Decoded messages are published to topics by source and PGN (i.e.
+// `n2k/<src>/<pgn>`) or per field, and raw messages received from command topic can be written to the bus.
+//
+// Package contains minimal MQTT 3.1.1 client that supports QoS 0 (at most once) publishing and subscribing. This is
+// sufficient for streaming sensor data where next message supersedes the previous one.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultKeepAlive is default interval client pings broker when idle
+	DefaultKeepAlive = 30 * time.Second
+	// DefaultConnectTimeout is default time connection handshake may take
+	DefaultConnectTimeout = 10 * time.Second
+)
+
+// ErrClientClosed is returned when using closed Client
+var ErrClientClosed = errors.New("mqtt client closed")
+
+const (
+	packetConnect     = 0x10
+	packetConnAck     = 0x20
+	packetPublish     = 0x30
+	packetSubscribe   = 0x82 // SUBSCRIBE has fixed flags 0b0010
+	packetSubAck      = 0x90
+	packetPingReq     = 0xC0
+	packetPingResp    = 0xD0
+	packetDisconnect  = 0xE0
+	maxRemainingBytes = 268_435_455
+)
+
+// ClientConfig configures Client
+type ClientConfig struct {
+	// ClientID identifies client to broker. Empty ID lets broker assign one.
+	ClientID string
+	// Username and Password are used to authenticate to broker. Optional.
+	Username string
+	Password string
+	// KeepAlive is interval client pings broker so broker (and client) can detect dead connections.
+	// Defaults to: DefaultKeepAlive
+	KeepAlive time.Duration
+	// ConnectTimeout limits time connection handshake may take when context has no deadline.
+	// Defaults to: DefaultConnectTimeout
+	ConnectTimeout time.Duration
+}
+
+// MessageHandler handles message received from subscribed topic. Handler is called from Run go-routine so it should
+// not block for long.
+type MessageHandler func(topic string, payload []byte)
+
+type subscription struct {
+	filter  string
+	handler MessageHandler
+}
+
+// Client is minimal MQTT 3.1.1 client supporting QoS 0 publish and subscribe over single connection.
+//
+// Client is safe for concurrent use.
+type Client struct {
+	config ClientConfig
+	conn   io.ReadWriteCloser
+	reader *bufio.Reader
+
+	writeLock sync.Mutex
+
+	lock          sync.Mutex
+	subscriptions []subscription
+	packetID      uint16
+	closed        bool
+}
+
+// Dial connects to broker at address (`host:port`, `tcp://host:port` or `mqtt://host:port`) and performs connection
+// handshake.
+func Dial(ctx context.Context, address string, config ClientConfig) (*Client, error) {
+	address = strings.TrimPrefix(strings.TrimPrefix(address, "tcp://"), "mqtt://")
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(conn, config)
+	if err := client.Connect(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClient creates new instance of Client on top of existing connection. Connect must be called before client is used.
+func NewClient(conn io.ReadWriteCloser, config ClientConfig) *Client {
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = DefaultKeepAlive
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = DefaultConnectTimeout
+	}
+	return &Client{
+		config: config,
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
+
+// Connect sends CONNECT packet and waits for broker to accept connection.
+func (c *Client) Connect(ctx context.Context) error {
+	if dl, ok := c.conn.(deadliner); ok {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(c.config.ConnectTimeout)
+		}
+		if err := dl.SetDeadline(deadline); err != nil {
+			return err
+		}
+		defer dl.SetDeadline(time.Time{})
+	}
+
+	var flags byte = 0x02 // clean session
+	payload := appendString(nil, c.config.ClientID)
+	if c.config.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, c.config.Username)
+	}
+	if c.config.Password != "" {
+		flags |= 0x40
+		payload = appendString(payload, c.config.Password)
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 = MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(c.config.KeepAlive/time.Second))
+	body = append(body, payload...)
+	if err := c.writePacket(packetConnect, body); err != nil {
+		return err
+	}
+
+	packetType, resp, err := readPacket(c.reader)
+	if err != nil {
+		return fmt.Errorf("mqtt: failed to read connack, err: %w", err)
+	}
+	if packetType != packetConnAck || len(resp) != 2 {
+		return fmt.Errorf("mqtt: unexpected packet instead of connack: %#x", packetType)
+	}
+	if resp[1] != 0 {
+		return fmt.Errorf("mqtt: connection refused, return code: %v", resp[1])
+	}
+	return nil
+}
+
+// Publish publishes payload to topic with QoS 0. Retained messages are kept by broker and sent to new subscribers.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var packetType byte = packetPublish
+	if retain {
+		packetType |= 0x01
+	}
+	body := appendString(make([]byte, 0, 2+len(topic)+len(payload)), topic)
+	return c.writePacket(packetType, append(body, payload...))
+}
+
+// Subscribe subscribes to topic filter (wildcards `+` and `#` are supported) with QoS 0. Messages are delivered to
+// handler while Run is running.
+func (c *Client) Subscribe(filter string, handler MessageHandler) error {
+	if filter == "" {
+		return errors.New("mqtt: empty topic filter")
+	}
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return ErrClientClosed
+	}
+	c.subscriptions = append(c.subscriptions, subscription{filter: filter, handler: handler})
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+	packetID := c.packetID
+	c.lock.Unlock()
+
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	body = appendString(body, filter)
+	body = append(body, 0) // requested QoS 0
+	return c.writePacket(packetSubscribe, body)
+}
+
+// Run reads packets from broker and dispatches received messages to subscription handlers. Run pings broker in
+// KeepAlive intervals. Run blocks until context is cancelled, client is closed or connection fails. Client is closed
+// when Run returns. Returns nil when client was closed or context cancelled.
+func (c *Client) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(c.config.KeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				_ = c.Close()
+				return
+			case <-ticker.C:
+				if err := c.writePacket(packetPingReq, nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		packetType, body, err := readPacket(c.reader)
+		if err != nil {
+			if c.isClosed() {
+				return nil
+			}
+			return err
+		}
+		switch packetType & 0xF0 {
+		case packetPublish:
+			topic, payload, err := parsePublish(packetType, body)
+			if err != nil {
+				return err
+			}
+			c.dispatch(topic, payload)
+		case packetSubAck:
+			if len(body) > 2 && body[2] == 0x80 {
+				return errors.New("mqtt: broker rejected subscription")
+			}
+		case packetPingResp:
+		default:
+			return fmt.Errorf("mqtt: unexpected packet from broker: %#x", packetType)
+		}
+	}
+}
+
+func (c *Client) dispatch(topic string, payload []byte) {
+	c.lock.Lock()
+	subscriptions := c.subscriptions
+	c.lock.Unlock()
+	for _, s := range subscriptions {
+		if TopicMatches(s.filter, topic) {
+			s.handler(topic, payload)
+		}
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+// Close sends DISCONNECT packet and closes connection
+func (c *Client) Close() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.lock.Unlock()
+
+	_ = c.writePacket(packetDisconnect, nil)
+	return c.conn.Close()
+}
+
+func (c *Client) writePacket(packetType byte, body []byte) error {
+	if len(body) > maxRemainingBytes {
+		return errors.New("mqtt: packet too large")
+	}
+	b := make([]byte, 0, 5+len(body))
+	b = append(b, packetType)
+	b = appendRemainingLength(b, len(body))
+	b = append(b, body...)
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if packetType != packetDisconnect && c.isClosed() {
+		return ErrClientClosed
+	}
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// TopicMatches checks if topic matches subscription filter with `+` (single level) and `#` (multi level) wildcards.
+func TopicMatches(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, f := range filterLevels {
+		if f == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if f != "+" && f != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+func parsePublish(packetType byte, body []byte) (string, []byte, error) {
+	topic, n, err := readString(body)
+	if err != nil {
+		return "", nil, err
+	}
+	body = body[n:]
+	if qos := (packetType >> 1) & 0x03; qos > 0 {
+		if len(body) < 2 {
+			return "", nil, errors.New("mqtt: publish packet is too short")
+		}
+		body = body[2:] // packet identifier
+	}
+	return topic, body, nil
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	packetType, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := 0
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return packetType, body, nil
+}
+
+func appendRemainingLength(b []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if length == 0 {
+			return b
+		}
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) (string, int, error) {
+	if len(b) < 2 {
+		return "", 0, errors.New("mqtt: string is too short")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", 0, errors.New("mqtt: string is too short")
+	}
+	return string(b[2 : 2+n]), 2 + n, nil
+}
@@ -0,0 +1,210 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type packet struct {
+	Type byte
+	Body []byte
+}
+
+// fakeBroker is broker side of connection that records packets sent by client
+type fakeBroker struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	packets chan packet
+}
+
+func newFakeBroker(t *testing.T) (*fakeBroker, net.Conn) {
+	brokerConn, clientConn := net.Pipe()
+	b := &fakeBroker{conn: brokerConn, reader: bufio.NewReader(brokerConn), packets: make(chan packet, 100)}
+	go func() {
+		defer close(b.packets)
+		for {
+			packetType, body, err := readPacket(b.reader)
+			if err != nil {
+				return
+			}
+			b.packets <- packet{Type: packetType, Body: body}
+		}
+	}()
+	t.Cleanup(func() { brokerConn.Close() })
+	return b, clientConn
+}
+
+func (b *fakeBroker) send(t *testing.T, packetType byte, body []byte) {
+	p := append([]byte{packetType}, appendRemainingLength(nil, len(body))...)
+	_, err := b.conn.Write(append(p, body...))
+	assert.NoError(t, err)
+}
+
+func (b *fakeBroker) next(t *testing.T) packet {
+	select {
+	case p := <-b.packets:
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for packet")
+	}
+	return packet{}
+}
+
+func connectedClient(t *testing.T, config ClientConfig) (*Client, *fakeBroker) {
+	broker, conn := newFakeBroker(t)
+	client := NewClient(conn, config)
+
+	errs := make(chan error, 1)
+	go func() { errs <- client.Connect(context.Background()) }()
+	assert.Equal(t, byte(packetConnect), broker.next(t).Type)
+	broker.send(t, packetConnAck, []byte{0, 0})
+	assert.NoError(t, <-errs)
+	return client, broker
+}
+
+func TestClient_Connect(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		config      ClientConfig
+		connAck     []byte
+		expectBody  []byte
+		expectError string
+	}{
+		{
+			name:    "ok",
+			config:  ClientConfig{ClientID: "n2k"},
+			connAck: []byte{0, 0},
+			expectBody: []byte{
+				0, 4, 'M', 'Q', 'T', 'T', 4, 0x02, 0, 30,
+				0, 3, 'n', '2', 'k',
+			},
+		},
+		{
+			name:    "ok, with credentials",
+			config:  ClientConfig{ClientID: "a", Username: "u", Password: "p", KeepAlive: 10 * time.Second},
+			connAck: []byte{0, 0},
+			expectBody: []byte{
+				0, 4, 'M', 'Q', 'T', 'T', 4, 0xC2, 0, 10,
+				0, 1, 'a', 0, 1, 'u', 0, 1, 'p',
+			},
+		},
+		{
+			name:    "nok, refused",
+			config:  ClientConfig{ClientID: "n2k"},
+			connAck: []byte{0, 5},
+			expectBody: []byte{
+				0, 4, 'M', 'Q', 'T', 'T', 4, 0x02, 0, 30,
+				0, 3, 'n', '2', 'k',
+			},
+			expectError: "mqtt: connection refused, return code: 5",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			broker, conn := newFakeBroker(t)
+			client := NewClient(conn, tc.config)
+
+			errs := make(chan error, 1)
+			go func() { errs <- client.Connect(context.Background()) }()
+
+			p := broker.next(t)
+			assert.Equal(t, byte(packetConnect), p.Type)
+			assert.Equal(t, tc.expectBody, p.Body)
+			broker.send(t, packetConnAck, tc.connAck)
+
+			err := <-errs
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	client, broker := connectedClient(t, ClientConfig{})
+
+	go func() {
+		assert.NoError(t, client.Publish("n2k/1/127250", []byte(`{}`), true))
+	}()
+
+	p := broker.next(t)
+	assert.Equal(t, byte(packetPublish|0x01), p.Type)
+	assert.Equal(t, append([]byte{0, 12}, "n2k/1/127250{}"...), p.Body)
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	client, broker := connectedClient(t, ClientConfig{})
+
+	var lock sync.Mutex
+	received := make([]string, 0)
+	go func() {
+		assert.NoError(t, client.Subscribe("n2k/command/#", func(topic string, payload []byte) {
+			lock.Lock()
+			defer lock.Unlock()
+			received = append(received, topic+"="+string(payload))
+		}))
+	}()
+	p := broker.next(t)
+	assert.Equal(t, byte(packetSubscribe), p.Type)
+	assert.Equal(t, append([]byte{0, 1, 0, 13}, "n2k/command/#\x00"...), p.Body)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- client.Run(ctx) }()
+
+	broker.send(t, packetSubAck, []byte{0, 1, 0})
+	broker.send(t, packetPublish, append([]byte{0, 5}, "other1"...))
+	broker.send(t, packetPublish, append([]byte{0, 13}, "n2k/command/xhello"...))
+	broker.send(t, packetPublish|0x02, append([]byte{0, 11}, "n2k/command\x00\x07qos1"...))
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"n2k/command/x=hello", "n2k/command=qos1"}, received)
+
+	cancel()
+	assert.NoError(t, <-runErr)
+	assert.Equal(t, byte(packetDisconnect), broker.next(t).Type)
+	assert.ErrorIs(t, client.Publish("x", nil, false), ErrClientClosed)
+}
+
+func TestClient_Run_connectionLost(t *testing.T) {
+	client, broker := connectedClient(t, ClientConfig{})
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- client.Run(context.Background()) }()
+	broker.conn.Close()
+
+	assert.Error(t, <-runErr)
+}
+
+func TestTopicMatches(t *testing.T) {
+	var testCases = []struct {
+		filter string
+		topic  string
+		expect bool
+	}{
+		{filter: "n2k/1/127250", topic: "n2k/1/127250", expect: true},
+		{filter: "n2k/+/127250", topic: "n2k/1/127250", expect: true},
+		{filter: "n2k/#", topic: "n2k/1/127250", expect: true},
+		{filter: "n2k/#", topic: "n2k", expect: true},
+		{filter: "#", topic: "n2k/1", expect: true},
+		{filter: "n2k/+", topic: "n2k/1/127250", expect: false},
+		{filter: "n2k/1/127250/x", topic: "n2k/1/127250", expect: false},
+		{filter: "n2k/2/127250", topic: "n2k/1/127250", expect: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.filter+" "+tc.topic, func(t *testing.T) {
+			assert.Equal(t, tc.expect, TopicMatches(tc.filter, tc.topic))
+		})
+	}
+}
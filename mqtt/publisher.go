@@ -0,0 +1,123 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"strconv"
+)
+
+// DefaultTopicPrefix is default prefix for topics messages are published to
+const DefaultTopicPrefix = "n2k"
+
+// TopicMode determines how decoded messages are mapped to topics
+type TopicMode uint8
+
+const (
+	// TopicPerMessage publishes whole decoded message as JSON to `<prefix>/<src>/<pgn>` topic.
+	TopicPerMessage TopicMode = iota
+	// TopicPerField publishes each field value as JSON to `<prefix>/<src>/<pgn>/<field id>` topic. Suitable for
+	// dashboards and home automation systems that bind single topic to single value.
+	TopicPerField
+)
+
+// Publisher publishes decoded messages to MQTT broker. Implements export.Writer.
+type Publisher struct {
+	client *Client
+	config PublisherConfig
+}
+
+// PublisherConfig configures Publisher
+type PublisherConfig struct {
+	// Prefix is first level of topics.
+	// Defaults to: DefaultTopicPrefix
+	Prefix string
+	// Mode determines how messages are mapped to topics
+	Mode TopicMode
+	// Retain marks published messages as retained so new subscribers receive last known value immediately.
+	Retain bool
+}
+
+// NewPublisher creates new instance of Publisher
+func NewPublisher(client *Client, config PublisherConfig) *Publisher {
+	if config.Prefix == "" {
+		config.Prefix = DefaultTopicPrefix
+	}
+	return &Publisher{
+		client: client,
+		config: config,
+	}
+}
+
+// WriteMessage publishes decoded message to broker
+func (p *Publisher) WriteMessage(_ nmea.RawMessage, msg nmea.Message) error {
+	topic := p.config.Prefix + "/" + strconv.Itoa(int(msg.Header.Source)) + "/" + strconv.FormatUint(uint64(msg.Header.PGN), 10)
+	if p.config.Mode == TopicPerMessage {
+		b, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		return p.client.Publish(topic, b, p.config.Retain)
+	}
+
+	for _, f := range msg.Fields {
+		b, err := json.Marshal(f.Value)
+		if err != nil {
+			return err
+		}
+		if err := p.client.Publish(topic+"/"+f.ID, b, p.config.Retain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing. Client is owned and closed by caller.
+func (p *Publisher) Close() error {
+	return nil
+}
+
+// CommandConfig configures subscription to command topic
+type CommandConfig struct {
+	// Topic is topic filter for command messages (i.e. `n2k/command`). Required.
+	Topic string
+	// NewReader creates reader that parses raw messages from command message payload (i.e. canboat.NewCanBoatReader).
+	// Payload may contain multiple messages. Required.
+	NewReader nmea.ReaderFactory
+	// Writer is device where parsed raw messages are written to. Required.
+	Writer nmea.RawMessageWriter
+	// OnError is called for malformed payloads and failed writes. Optional.
+	OnError func(err error)
+}
+
+// SubscribeCommands subscribes client to command topic and writes raw messages parsed from received payloads to
+// config.Writer. Messages are delivered while client Run is running.
+func SubscribeCommands(client *Client, config CommandConfig) error {
+	if config.NewReader == nil || config.Writer == nil {
+		return errors.New("mqtt: command subscription requires reader factory and writer")
+	}
+	onError := config.OnError
+	if onError == nil {
+		onError = func(err error) {}
+	}
+
+	return client.Subscribe(config.Topic, func(topic string, payload []byte) {
+		reader := config.NewReader(bytes.NewBuffer(payload))
+		for {
+			raw, err := reader.ReadRawMessage(context.Background())
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					onError(err)
+				}
+				return
+			}
+			if err := config.Writer.WriteRawMessage(context.Background(), raw); err != nil {
+				onError(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package mqtt
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testWriter struct {
+	lock     sync.Mutex
+	messages []nmea.RawMessage
+}
+
+func (w *testWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.messages = append(w.messages, msg)
+	return nil
+}
+
+func (w *testWriter) Close() error {
+	return nil
+}
+
+func (w *testWriter) Messages() []nmea.RawMessage {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]nmea.RawMessage(nil), w.messages...)
+}
+
+func publishedTopics(t *testing.T, broker *fakeBroker, count int) map[string]string {
+	result := map[string]string{}
+	for i := 0; i < count; i++ {
+		p := broker.next(t)
+		topic, n, err := readString(p.Body)
+		assert.NoError(t, err)
+		result[topic] = string(p.Body[n:])
+	}
+	return result
+}
+
+func TestPublisher_WriteMessage(t *testing.T) {
+	msg := nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 127250, Source: 24, Destination: 255},
+		Fields: nmea.FieldValues{
+			{ID: "heading", Value: 1.5},
+			{ID: "reference", Value: nmea.EnumValue{Value: 1, Code: "Magnetic"}},
+		},
+	}
+	var testCases = []struct {
+		name   string
+		config PublisherConfig
+		expect map[string]string
+	}{
+		{
+			name:   "ok, per message",
+			config: PublisherConfig{},
+			expect: map[string]string{
+				"n2k/24/127250": `{"node_name":0,"header":{"pgn":127250,"priority":0,"source":24,"destination":255},"fields":[{"id":"heading","type":"float64","value":1.5},{"id":"reference","type":"enum","value":{"value":1,"code":"Magnetic"}}]}`,
+			},
+		},
+		{
+			name:   "ok, per field",
+			config: PublisherConfig{Prefix: "boat", Mode: TopicPerField},
+			expect: map[string]string{
+				"boat/24/127250/heading":   `1.5`,
+				"boat/24/127250/reference": `{"Value":1,"Code":"Magnetic"}`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, broker := connectedClient(t, ClientConfig{})
+			p := NewPublisher(client, tc.config)
+
+			go func() {
+				assert.NoError(t, p.WriteMessage(nmea.RawMessage{}, msg))
+			}()
+
+			assert.Equal(t, tc.expect, publishedTopics(t, broker, len(tc.expect)))
+			assert.NoError(t, p.Close())
+		})
+	}
+}
+
+func TestSubscribeCommands(t *testing.T) {
+	client, broker := connectedClient(t, ClientConfig{})
+
+	writer := &testWriter{}
+	errs := make(chan error, 10)
+	go func() {
+		err := SubscribeCommands(client, CommandConfig{
+			Topic: "n2k/command",
+			NewReader: func(stream io.ReadWriter) nmea.RawMessageReader {
+				return canboat.NewCanBoatReader(stream)
+			},
+			Writer:  writer,
+			OnError: func(err error) { errs <- err },
+		})
+		assert.NoError(t, err)
+	}()
+	assert.Equal(t, byte(packetSubscribe), broker.next(t).Type)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx)
+
+	payload := "2023-02-07T11:55:11.002803898+02:00,2,127245,13,255,8,ff,07,ff,7f,00,00,ff,ff\ninvalid"
+	broker.send(t, packetPublish, append([]byte{0, 11}, "n2k/command"+payload...))
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+	messages := writer.Messages()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, uint32(127245), messages[0].Header.PGN)
+		assert.Equal(t, uint8(13), messages[0].Header.Source)
+	}
+}
+
+func TestSubscribeCommands_missingConfig(t *testing.T) {
+	client, _ := connectedClient(t, ClientConfig{})
+
+	err := SubscribeCommands(client, CommandConfig{Topic: "n2k/command"})
+	assert.EqualError(t, err, "mqtt: command subscription requires reader factory and writer")
+}
@@ -43,9 +43,26 @@ type PGN uint32
 const (
 	PGNISORequest               = PGN(59904)  // 0xEA00
 	PGNISOAddressClaim          = PGN(60928)  // 0xEE00
+	PGNISOCommandedAddress      = PGN(65240)  // 0xFED8
 	PGNProductInfo              = PGN(126996) // 0x1F014
 	PGNConfigurationInformation = PGN(126998) // 0x1F016
 	PGNPGNList                  = PGN(126464) // 0x1EE00
+	PGNHeartbeat                = PGN(126993) // 0x1F011
+	PGNGroupFunction            = PGN(126208) // 0x1ED00
+	PGNTemperature              = PGN(130312) // 0x1FD08, superseded by PGNTemperatureExtendedRange
+	PGNTemperatureExtendedRange = PGN(130316) // 0x1FD0C
+	PGNEngineParametersDynamic  = PGN(127489) // 0x1F201
+
+	// SAE J1939 engine PGNs that engine gateways may pass through to NMEA2000 bus. Superseded by PGNEngineParametersDynamic
+	PGNJ1939EngineTemperature1        = PGN(65262) // 0xFEEE, ET1
+	PGNJ1939EngineFluidLevelPressure1 = PGN(65263) // 0xFEEF, EFL/P1
+	PGNJ1939FuelEconomy               = PGN(65266) // 0xFEF2, LFE
+	PGNJ1939VehicleElectricalPower1   = PGN(65271) // 0xFEF7, VEP1
+
+	// PGNISOTransportProtocolConnectionManagement is ISO 11783-3 transport protocol connection management (TP.CM)
+	PGNISOTransportProtocolConnectionManagement = PGN(60416) // 0xEC00
+	// PGNISOTransportProtocolDataTransfer is ISO 11783-3 transport protocol data transfer (TP.DT)
+	PGNISOTransportProtocolDataTransfer = PGN(60160) // 0xEB00
 
 	// AddressGlobal is broadcast address used to send messages for all nodes on the n2k bus.
 	AddressGlobal = uint8(255)
@@ -67,12 +84,22 @@ type RawFrame struct {
 type RawMessage struct {
 	// Time is when message was read from NMEA bus. Filled by this library.
 	Time time.Time
+	// BusTime is estimated time when gateway device received message from NMEA bus. Filled from device timestamps by
+	// devices that provide them (i.e. Actisense W2K-1, EBL files). Zero when not available. See DeviceClock.
+	BusTime time.Time
+	// DeviceTime is timestamp as gateway device supplied it (i.e. millisecond counter of NGT-1, time of day of W2K-1
+	// ASCII format) without conversion to local time. Zero when not available.
+	DeviceTime time.Duration
 
 	Header CanBusHeader
 	Data   RawData // usually 8 bytes but fast-packets can be up to 223 bytes, assembled multi-packets (ISO-TP) up to 1785 bytes
 }
 
 // Message is parsed value of PGN packet(s). Message could be assembled from multiple RawMessage instances.
+//
+// Message JSON has stable schema: header fields, fields as array of `{"id":..,"type":..,"value":..}` objects (see
+// FieldValue.MarshalJSON) and times in RFC3339 (ISO8601) format. JSON can be unmarshalled back to Message with same
+// field value types (i.e. recorded `-output-file` for replay and tests).
 type Message struct {
 	// NodeNAME is unique identifier (ISO Address Claim) for Node in NMEA bus.
 	//
@@ -87,12 +114,138 @@ type Message struct {
 
 	Header CanBusHeader `json:"header"`
 	Fields FieldValues  `json:"fields"`
+
+	// Warnings contains non-fatal problems that decoder encountered while decoding this message (i.e. malformed data
+	// that was decoded with best effort).
+	Warnings []DecodeWarning `json:"warnings,omitempty"`
+
+	// Adjustments marks fields which values were changed after decoding (i.e. by calibration) and what the original
+	// decoded values were.
+	Adjustments []FieldAdjustment `json:"adjustments,omitempty"`
+
+	// Absent lists schema fields that have no value in Fields and the reason why. Allows distinguishing sensor reporting
+	// "no data" from field not being transmitted at all. Filled only when decoder is configured to do so.
+	Absent []AbsentField `json:"absent,omitempty"`
+
+	// Timing holds bus receive and local processing times of message. Filled only when RawMessage had BusTime.
+	Timing *MessageTiming `json:"timing,omitempty"`
+}
+
+// Float64 returns value of field with given ID converted to float64. Returns false when message has no such field or
+// value can not be converted.
+func (m Message) Float64(fieldID string) (float64, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return 0, false
+	}
+	return f.AsFloat64()
+}
+
+// Int64 returns value of field with given ID converted to int64.
+func (m Message) Int64(fieldID string) (int64, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return 0, false
+	}
+	return f.AsInt64()
+}
+
+// Uint64 returns value of field with given ID converted to uint64.
+func (m Message) Uint64(fieldID string) (uint64, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return 0, false
+	}
+	return f.AsUint64()
+}
+
+// String returns value of string (or enum code) field with given ID.
+func (m Message) String(fieldID string) (string, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return "", false
+	}
+	return f.AsString()
+}
+
+// Duration returns value of time.Duration field with given ID.
+func (m Message) Duration(fieldID string) (time.Duration, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return 0, false
+	}
+	return f.AsDuration()
+}
+
+// Time returns value of time.Time field with given ID.
+func (m Message) Time(fieldID string) (time.Time, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return f.AsTime()
+}
+
+// Enum returns value of enum field with given ID.
+func (m Message) Enum(fieldID string) (EnumValue, bool) {
+	f, ok := m.Fields.FindByID(fieldID)
+	if !ok {
+		return EnumValue{}, false
+	}
+	return f.AsEnum()
+}
+
+// AbsenceReason describes why field has no value in decoded Message
+type AbsenceReason string
+
+const (
+	// AbsenceNoData means that field was transmitted with "no data" value (i.e. all bits set)
+	AbsenceNoData AbsenceReason = "no_data"
+	// AbsenceOutOfRange means that field was transmitted with value outside of valid range
+	AbsenceOutOfRange AbsenceReason = "out_of_range"
+	// AbsenceReserved means that field was transmitted with reserved value
+	AbsenceReserved AbsenceReason = "reserved"
+	// AbsenceNotTransmitted means that message data ended before field (optional field was left out)
+	AbsenceNotTransmitted AbsenceReason = "not_transmitted"
+)
+
+// AbsentField is schema field that has no value in decoded Message
+type AbsentField struct {
+	// FieldID is ID of absent field
+	FieldID string `json:"field_id"`
+	// Reason is why field has no value
+	Reason AbsenceReason `json:"reason"`
+}
+
+// FieldAdjustment is provenance information for field value that was changed after decoding
+type FieldAdjustment struct {
+	// FieldID is ID of adjusted field
+	FieldID string `json:"field_id"`
+	// Kind identifies what adjusted the value (i.e. "heading_deviation", "depth_offset")
+	Kind string `json:"kind"`
+	// Original is field value before adjustment
+	Original interface{} `json:"original"`
+}
+
+// DecodeWarning describes non-fatal problem seen while decoding RawMessage to Message
+type DecodeWarning struct {
+	// Code is machine-readable identifier of warning kind
+	Code string `json:"code"`
+	// FieldID is ID of field this warning relates to
+	FieldID string `json:"field_id,omitempty"`
+	// Message is human-readable description of the problem
+	Message string `json:"message"`
 }
 
 type MessageDecoder interface {
 	Decode(raw RawMessage) (Message, error)
 }
 
+// MessageEncoder encodes message fields back to raw message data. Counterpart of MessageDecoder.
+type MessageEncoder interface {
+	Encode(msg Message) (RawMessage, error)
+}
+
 func MarshalRawMessage(raw RawMessage) []byte {
 	b := make([]byte, 8+2+3+len(raw.Data))
 
@@ -0,0 +1,285 @@
+package nmea0183
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrUnsupportedSentence is returned when there is no conversion for sentence type
+var ErrUnsupportedSentence = errors.New("unsupported NMEA0183 sentence type for conversion")
+
+const (
+	knotsToMetersPerSecond = 1852.0 / 3600.0
+	feetToMeters           = 0.3048
+	degreesToRadians       = math.Pi / 180
+)
+
+// ConverterConfig is configuration for Converter
+type ConverterConfig struct {
+	// Source is source address set to converted messages headers as NMEA0183 has no addresses.
+	Source uint8
+}
+
+// Converter converts NMEA0183 sentences to equivalent NMEA2000 messages so downstream code can process data from both
+// buses uniformly. Messages have same field IDs, units (SI) and value types as Canboat decoder produces. Empty sentence
+// fields are left out of message same way as decoder leaves out fields with "no data" values.
+//
+// Supported sentences:
+// * RMC - PGN 129025 (Position, Rapid Update) and PGN 129026 (COG & SOG, Rapid Update)
+// * GGA - PGN 129029 (GNSS Position Data)
+// * HDG - PGN 127250 (Vessel Heading)
+// * DBT - PGN 128267 (Water Depth)
+type Converter struct {
+	source uint8
+}
+
+// NewConverter creates new instance of Converter
+func NewConverter(config ConverterConfig) *Converter {
+	return &Converter{source: config.Source}
+}
+
+// Convert converts sentence to NMEA2000 messages. Sentences that have no valid data (i.e. RMC with void status, GGA
+// without fix) result no messages.
+func (c *Converter) Convert(s Sentence) ([]nmea.Message, error) {
+	var err error
+	var result []nmea.Message
+	switch s.Type {
+	case "RMC":
+		result, err = c.convertRMC(s)
+	case "GGA":
+		result, err = c.convertGGA(s)
+	case "HDG":
+		result, err = c.convertHDG(s)
+	case "DBT":
+		result, err = c.convertDBT(s)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedSentence, s.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %v%v sentence: %w", s.Talker, s.Type, err)
+	}
+	return result, nil
+}
+
+func (c *Converter) message(pgn uint32, priority uint8, fields nmea.FieldValues) nmea.Message {
+	return nmea.Message{
+		Header: nmea.CanBusHeader{
+			PGN:         pgn,
+			Priority:    priority,
+			Source:      c.source,
+			Destination: nmea.AddressGlobal,
+		},
+		Fields: fields,
+	}
+}
+
+// $GPRMC,hhmmss.ss,A,llll.ll,a,yyyyy.yy,a,x.x,x.x,ddmmyy,x.x,a,m*hh
+func (c *Converter) convertRMC(s Sentence) ([]nmea.Message, error) {
+	if len(s.Fields) < 9 {
+		return nil, ErrInvalidSentence
+	}
+	if s.Fields[1] != "A" { // V = void (navigation receiver warning)
+		return nil, nil
+	}
+	result := make([]nmea.Message, 0, 2)
+
+	position := nmea.FieldValues{}
+	if err := appendCoordinates(&position, s.Fields[2], s.Fields[3], s.Fields[4], s.Fields[5]); err != nil {
+		return nil, err
+	}
+	if len(position) > 0 {
+		result = append(result, c.message(129025, 2, position))
+	}
+
+	cogSog := nmea.FieldValues{{ID: "cogReference", Value: uint64(0)}} // true
+	if err := appendFloat(&cogSog, "cog", s.Fields[7], degreesToRadians); err != nil {
+		return nil, err
+	}
+	if err := appendFloat(&cogSog, "sog", s.Fields[6], knotsToMetersPerSecond); err != nil {
+		return nil, err
+	}
+	if len(cogSog) > 1 {
+		result = append(result, c.message(129026, 2, cogSog))
+	}
+	return result, nil
+}
+
+// gnssTypes maps talker IDs to Canboat GNS lookup values
+var gnssTypes = map[string]uint64{
+	"GP": 0, // GPS
+	"GL": 1, // GLONASS
+	"GN": 2, // GPS+GLONASS (combined GNSS)
+	"GA": 8, // Galileo
+}
+
+// $GPGGA,hhmmss.ss,llll.ll,a,yyyyy.yy,a,x,xx,x.x,x.x,M,x.x,M,x.x,xxxx*hh
+func (c *Converter) convertGGA(s Sentence) ([]nmea.Message, error) {
+	if len(s.Fields) < 11 {
+		return nil, ErrInvalidSentence
+	}
+	quality, err := strconv.ParseUint(s.Fields[5], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fix quality: %w", err)
+	}
+	if quality == 0 { // no fix
+		return nil, nil
+	}
+
+	fields := nmea.FieldValues{}
+	if s.Fields[0] != "" {
+		t, err := parseTime(s.Fields[0])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, nmea.FieldValue{ID: "time", Value: t})
+	}
+	if err := appendCoordinates(&fields, s.Fields[1], s.Fields[2], s.Fields[3], s.Fields[4]); err != nil {
+		return nil, err
+	}
+	if err := appendFloat(&fields, "altitude", s.Fields[8], 1); err != nil {
+		return nil, err
+	}
+	if gnssType, ok := gnssTypes[s.Talker]; ok {
+		fields = append(fields, nmea.FieldValue{ID: "gnssType", Value: gnssType})
+	}
+	// GGA fix quality values 1-8 match Canboat GNSS_METHOD lookup values
+	fields = append(fields, nmea.FieldValue{ID: "method", Value: quality})
+	if s.Fields[6] != "" {
+		svs, err := strconv.ParseUint(s.Fields[6], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of satellites: %w", err)
+		}
+		fields = append(fields, nmea.FieldValue{ID: "numberOfSvs", Value: svs})
+	}
+	if err := appendFloat(&fields, "hdop", s.Fields[7], 1); err != nil {
+		return nil, err
+	}
+	if err := appendFloat(&fields, "geoidalSeparation", s.Fields[10], 1); err != nil {
+		return nil, err
+	}
+	return []nmea.Message{c.message(129029, 3, fields)}, nil
+}
+
+// $HCHDG,x.x,x.x,a,x.x,a*hh
+func (c *Converter) convertHDG(s Sentence) ([]nmea.Message, error) {
+	if len(s.Fields) < 5 {
+		return nil, ErrInvalidSentence
+	}
+	fields := nmea.FieldValues{}
+	if err := appendFloat(&fields, "heading", s.Fields[0], degreesToRadians); err != nil {
+		return nil, err
+	}
+	if err := appendDirectional(&fields, "deviation", s.Fields[1], s.Fields[2]); err != nil {
+		return nil, err
+	}
+	if err := appendDirectional(&fields, "variation", s.Fields[3], s.Fields[4]); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	fields = append(fields, nmea.FieldValue{ID: "reference", Value: uint64(1)}) // magnetic
+	return []nmea.Message{c.message(127250, 2, fields)}, nil
+}
+
+// $SDDBT,x.x,f,x.x,M,x.x,F*hh
+func (c *Converter) convertDBT(s Sentence) ([]nmea.Message, error) {
+	if len(s.Fields) < 4 {
+		return nil, ErrInvalidSentence
+	}
+	fields := nmea.FieldValues{}
+	if err := appendFloat(&fields, "depth", s.Fields[2], 1); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 { // some devices send only feet
+		if err := appendFloat(&fields, "depth", s.Fields[0], feetToMeters); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return []nmea.Message{c.message(128267, 3, fields)}, nil
+}
+
+func appendFloat(fields *nmea.FieldValues, ID string, raw string, multiplier float64) error {
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %v value: %w", ID, err)
+	}
+	*fields = append(*fields, nmea.FieldValue{ID: ID, Value: v * multiplier})
+	return nil
+}
+
+// appendDirectional appends angle in radians. East is positive and west negative.
+func appendDirectional(fields *nmea.FieldValues, ID string, raw string, direction string) error {
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %v value: %w", ID, err)
+	}
+	if direction == "W" {
+		v = -v
+	}
+	*fields = append(*fields, nmea.FieldValue{ID: ID, Value: v * degreesToRadians})
+	return nil
+}
+
+func appendCoordinates(fields *nmea.FieldValues, lat string, latDir string, lon string, lonDir string) error {
+	if lat == "" || lon == "" {
+		return nil
+	}
+	latitude, err := parseCoordinate(lat, latDir, "N", "S")
+	if err != nil {
+		return fmt.Errorf("invalid latitude: %w", err)
+	}
+	longitude, err := parseCoordinate(lon, lonDir, "E", "W")
+	if err != nil {
+		return fmt.Errorf("invalid longitude: %w", err)
+	}
+	*fields = append(*fields,
+		nmea.FieldValue{ID: "latitude", Value: latitude},
+		nmea.FieldValue{ID: "longitude", Value: longitude},
+	)
+	return nil
+}
+
+// parseCoordinate parses coordinate in `dddmm.mmmm` format to decimal degrees
+func parseCoordinate(raw string, direction string, positive string, negative string) (float64, error) {
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, err
+	}
+	degrees := math.Floor(v / 100)
+	result := degrees + (v-degrees*100)/60
+	switch direction {
+	case positive:
+		return result, nil
+	case negative:
+		return -result, nil
+	}
+	return 0, fmt.Errorf("unknown direction %q", direction)
+}
+
+// parseTime parses UTC time in `hhmmss.ss` format to duration since midnight
+func parseTime(raw string) (time.Duration, error) {
+	if len(raw) < 6 {
+		return 0, fmt.Errorf("invalid time %q", raw)
+	}
+	h, errH := strconv.Atoi(raw[0:2])
+	m, errM := strconv.Atoi(raw[2:4])
+	sec, errS := strconv.ParseFloat(raw[4:], 64)
+	if errH != nil || errM != nil || errS != nil || h > 23 || m > 59 || sec >= 61 {
+		return 0, fmt.Errorf("invalid time %q", raw)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(math.Round(sec*1000))*time.Millisecond, nil
+}
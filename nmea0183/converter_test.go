@@ -0,0 +1,149 @@
+package nmea0183
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func assertFieldValues(t *testing.T, expect nmea.FieldValues, actual nmea.FieldValues) {
+	if !assert.Len(t, actual, len(expect)) {
+		return
+	}
+	for i, e := range expect {
+		assert.Equal(t, e.ID, actual[i].ID)
+		if f, ok := e.Value.(float64); ok {
+			assert.InDelta(t, f, actual[i].Value, 0.000001, e.ID)
+		} else {
+			assert.Equal(t, e.Value, actual[i].Value, e.ID)
+		}
+	}
+}
+
+func TestConverter_Convert(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		when         string
+		expectPGNs   []uint32
+		expectFields []nmea.FieldValues
+		expectErr    string
+	}{
+		{
+			name:       "RMC",
+			when:       "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A",
+			expectPGNs: []uint32{129025, 129026},
+			expectFields: []nmea.FieldValues{
+				{
+					{ID: "latitude", Value: 48.1173},
+					{ID: "longitude", Value: 11.516666},
+				},
+				{
+					{ID: "cogReference", Value: uint64(0)},
+					{ID: "cog", Value: 1.473058},
+					{ID: "sog", Value: 11.523555},
+				},
+			},
+		},
+		{
+			name:       "RMC, void status",
+			when:       "$GPRMC,123519,V,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*7D",
+			expectPGNs: []uint32{},
+		},
+		{
+			name:       "GGA",
+			when:       "$GPGGA,123519,4807.038,S,01131.000,W,1,08,0.9,545.4,M,46.9,M,,*48",
+			expectPGNs: []uint32{129029},
+			expectFields: []nmea.FieldValues{
+				{
+					{ID: "time", Value: 12*time.Hour + 35*time.Minute + 19*time.Second},
+					{ID: "latitude", Value: -48.1173},
+					{ID: "longitude", Value: -11.516666},
+					{ID: "altitude", Value: 545.4},
+					{ID: "gnssType", Value: uint64(0)},
+					{ID: "method", Value: uint64(1)},
+					{ID: "numberOfSvs", Value: uint64(8)},
+					{ID: "hdop", Value: 0.9},
+					{ID: "geoidalSeparation", Value: 46.9},
+				},
+			},
+		},
+		{
+			name:       "GGA, no fix",
+			when:       "$GPGGA,123519,,,,,0,00,,,M,,M,,*6B",
+			expectPGNs: []uint32{},
+		},
+		{
+			name:       "HDG",
+			when:       "$GPHDG,98.3,0.0,E,12.6,W*4B",
+			expectPGNs: []uint32{127250},
+			expectFields: []nmea.FieldValues{
+				{
+					{ID: "heading", Value: 1.715658},
+					{ID: "deviation", Value: 0.0},
+					{ID: "variation", Value: -0.219911},
+					{ID: "reference", Value: uint64(1)},
+				},
+			},
+		},
+		{
+			name:       "DBT",
+			when:       "$SDDBT,7.8,f,2.4,M,1.3,F*0D",
+			expectPGNs: []uint32{128267},
+			expectFields: []nmea.FieldValues{
+				{{ID: "depth", Value: 2.4}},
+			},
+		},
+		{
+			name:       "DBT, only feet",
+			when:       "$SDDBT,10.0,f,,M,,F*37",
+			expectPGNs: []uint32{128267},
+			expectFields: []nmea.FieldValues{
+				{{ID: "depth", Value: 3.048}},
+			},
+		},
+		{
+			name:      "nok, unsupported sentence",
+			when:      "$GPGLL,4916.45,N,12311.12,W,225444,A*31",
+			expectErr: "unsupported NMEA0183 sentence type for conversion: GLL",
+		},
+		{
+			name:      "nok, invalid value",
+			when:      "$SDDBT,7.8,f,x,M,1.3,F*5D",
+			expectErr: `failed to convert SDDBT sentence: invalid depth value: strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+		{
+			name:      "nok, too few fields",
+			when:      "$SDDBT,7.8,f*02",
+			expectErr: "failed to convert SDDBT sentence: invalid NMEA0183 sentence",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseSentence(tc.when, false)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			result, err := NewConverter(ConverterConfig{Source: 100}).Convert(s)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Nil(t, result)
+				return
+			}
+			assert.NoError(t, err)
+
+			pgns := make([]uint32, 0, len(result))
+			for _, m := range result {
+				pgns = append(pgns, m.Header.PGN)
+				assert.Equal(t, uint8(100), m.Header.Source)
+				assert.Equal(t, nmea.AddressGlobal, m.Header.Destination)
+			}
+			assert.Equal(t, tc.expectPGNs, pgns)
+			for i, fields := range tc.expectFields {
+				assertFieldValues(t, fields, result[i].Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package nmea0183
+
+import (
+	"bufio"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ReaderConfig is configuration for Reader
+type ReaderConfig struct {
+	// AllowMissingChecksum allows sentences without checksum. By default sentences without checksum result error.
+	AllowMissingChecksum bool
+}
+
+// Reader reads NMEA0183 sentences line by line from underlying reader (serial port, TCP connection, file). Parts of
+// multi-sentence messages (i.e. GSV, AIS VDM) are aggregated and returned as single Sentence with all parts.
+//
+// Tag blocks (`\s:source,c:1234*hh\` prefix used by NMEA0183 v4 and some gateways) are skipped.
+type Reader struct {
+	reader  io.Reader
+	scanner *bufio.Scanner
+	config  ReaderConfig
+	timeNow func() time.Time
+
+	// inProgress holds parts of multi-sentence messages by talker+type (+ sequential message ID for AIS)
+	inProgress map[string][]Sentence
+
+	closed atomic.Bool
+}
+
+// NewReader creates new instance of NMEA0183 sentence Reader
+func NewReader(reader io.Reader, config ReaderConfig) *Reader {
+	return &Reader{
+		reader:     reader,
+		scanner:    bufio.NewScanner(reader),
+		config:     config,
+		timeNow:    time.Now,
+		inProgress: map[string][]Sentence{},
+	}
+}
+
+// ReadSentence reads next sentence. Invalid sentences are returned as errors so caller can decide to skip them and
+// continue reading. Returns io.EOF when underlying reader is exhausted.
+func (r *Reader) ReadSentence(ctx context.Context) (Sentence, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Sentence{}, ctx.Err()
+		default:
+		}
+		if r.closed.Load() {
+			return Sentence{}, nmea.ErrDeviceClosed
+		}
+		if !r.scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] == '\\' { // tag block
+			if end := strings.IndexByte(line[1:], '\\'); end != -1 {
+				line = line[end+2:]
+			}
+		}
+
+		s, err := ParseSentence(line, r.config.AllowMissingChecksum)
+		if err != nil {
+			return Sentence{}, err
+		}
+		s.Time = r.timeNow()
+		if complete, ok := r.aggregate(s); ok {
+			return complete, nil
+		}
+	}
+	if r.closed.Load() {
+		return Sentence{}, nmea.ErrDeviceClosed
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Sentence{}, err
+	}
+	return Sentence{}, io.EOF
+}
+
+// aggregate collects parts of multi-sentence messages. Returns true when sentence is complete.
+func (r *Reader) aggregate(s Sentence) (Sentence, bool) {
+	total, number, ok := s.partInfo()
+	if !ok {
+		return s, true
+	}
+	if total == 1 {
+		s.Parts = []Sentence{s}
+		return s, true
+	}
+	key := s.Talker + s.Type
+	if (s.Type == "VDM" || s.Type == "VDO") && len(s.Fields) > 2 {
+		key += "," + s.Fields[2] // sequential message identifier
+	}
+
+	parts := r.inProgress[key]
+	if number == 1 {
+		parts = parts[:0]
+	} else if len(parts) != number-1 { // missing or out of order part, message can not be completed
+		delete(r.inProgress, key)
+		return Sentence{}, false
+	}
+	parts = append(parts, s)
+	if number != total {
+		r.inProgress[key] = parts
+		return Sentence{}, false
+	}
+	delete(r.inProgress, key)
+
+	result := parts[0]
+	result.Time = s.Time
+	result.Parts = parts
+	return result, true
+}
+
+// Close closes underlying reader. Blocked ReadSentence call is unblocked with nmea.ErrDeviceClosed error.
+func (r *Reader) Close() error {
+	r.closed.Store(true)
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
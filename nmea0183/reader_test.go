@@ -0,0 +1,122 @@
+package nmea0183
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReader(input string, config ReaderConfig) *Reader {
+	r := NewReader(strings.NewReader(input), config)
+	r.timeNow = func() time.Time {
+		return test_test.UTCTime(1665488842)
+	}
+	return r
+}
+
+func TestReader_ReadSentence(t *testing.T) {
+	input := "$GPHDG,98.3,0.0,E,12.6,W*4B\r\n" +
+		"\r\n" +
+		"\\s:gateway,c:1665488842*3D\\$SDDBT,7.8,f,2.4,M,1.3,F*0D\r\n"
+	r := testReader(input, ReaderConfig{})
+
+	s, err := r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Sentence{
+		Time:   test_test.UTCTime(1665488842),
+		Start:  '$',
+		Talker: "GP",
+		Type:   "HDG",
+		Fields: []string{"98.3", "0.0", "E", "12.6", "W"},
+	}, s)
+
+	s, err = r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "DBT", s.Type)
+	assert.Equal(t, []string{"7.8", "f", "2.4", "M", "1.3", "F"}, s.Fields)
+
+	_, err = r.ReadSentence(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReader_ReadSentence_invalidSentenceCanBeSkipped(t *testing.T) {
+	input := "$SDDBT,7.8,f,2.4,M,1.3,F*0E\r\n" +
+		"$SDDBT,7.8,f,2.4,M,1.3,F\r\n" +
+		"$SDDBT,7.8,f,2.4,M,1.3,F*0D\r\n"
+	r := testReader(input, ReaderConfig{})
+
+	_, err := r.ReadSentence(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidChecksum)
+
+	_, err = r.ReadSentence(context.Background())
+	assert.ErrorIs(t, err, ErrMissingChecksum)
+
+	s, err := r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "DBT", s.Type)
+}
+
+func TestReader_ReadSentence_allowMissingChecksum(t *testing.T) {
+	r := testReader("$SDDBT,7.8,f,2.4,M,1.3,F\r\n", ReaderConfig{AllowMissingChecksum: true})
+
+	s, err := r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "DBT", s.Type)
+}
+
+func TestReader_ReadSentence_multiSentence(t *testing.T) {
+	input := "$GPGSV,2,2,08,15,61,190,42,16,22,040,43,17,07,123,38,18,10,090,40*77\r\n" + // no first part, discarded
+		"$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75\r\n" +
+		"!AIVDM,2,1,3,B,55P5TL01VIaAL@7WKO@mBplU@<PDhh000000001S;AJ::4A80?4i@E53,0*3E\r\n" +
+		"$GPGSV,2,2,08,15,61,190,42,16,22,040,43,17,07,123,38,18,10,090,40*77\r\n" +
+		"!AIVDM,2,2,3,B,1@0000000000000,2*55\r\n" +
+		"!AIVDM,1,1,,B,15M67FC000G?ufbE`FepT@3n00Sa,0*5C\r\n"
+	r := testReader(input, ReaderConfig{})
+
+	s, err := r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "GSV", s.Type)
+	assert.Equal(t, []string{"2", "1", "08", "01", "40", "083", "46", "02", "17", "308", "41", "12", "07", "344", "39", "14", "22", "228", "45"}, s.Fields)
+	if assert.Len(t, s.Parts, 2) {
+		assert.Equal(t, "1", s.Parts[0].Fields[1])
+		assert.Equal(t, "2", s.Parts[1].Fields[1])
+	}
+
+	s, err = r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "VDM", s.Type)
+	if assert.Len(t, s.Parts, 2) {
+		assert.Equal(t, "1@0000000000000", s.Parts[1].Fields[4])
+	}
+
+	s, err = r.ReadSentence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "VDM", s.Type)
+	assert.Len(t, s.Parts, 1)
+
+	_, err = r.ReadSentence(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReader_ReadSentence_closed(t *testing.T) {
+	r := testReader("$SDDBT,7.8,f,2.4,M,1.3,F*0D\r\n", ReaderConfig{})
+	assert.NoError(t, r.Close())
+
+	_, err := r.ReadSentence(context.Background())
+	assert.True(t, errors.Is(err, nmea.ErrDeviceClosed))
+}
+
+func TestReader_ReadSentence_contextCancelled(t *testing.T) {
+	r := testReader("$SDDBT,7.8,f,2.4,M,1.3,F*0D\r\n", ReaderConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.ReadSentence(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
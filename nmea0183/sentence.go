@@ -0,0 +1,138 @@
+package nmea0183
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSentence is returned when line is not valid NMEA0183 sentence
+	ErrInvalidSentence = errors.New("invalid NMEA0183 sentence")
+	// ErrInvalidChecksum is returned when sentence checksum does not match its contents
+	ErrInvalidChecksum = errors.New("invalid NMEA0183 sentence checksum")
+	// ErrMissingChecksum is returned when sentence has no checksum and missing checksums are not allowed
+	ErrMissingChecksum = errors.New("NMEA0183 sentence has no checksum")
+)
+
+// Sentence is single NMEA0183 sentence (i.e. `$GPRMC,...*hh`) or multiple sentences of multi-sentence message
+// (i.e. GSV, AIS VDM) aggregated together.
+type Sentence struct {
+	// Time is when sentence was read. Filled by this library.
+	Time time.Time
+	// Start is sentence start character. `$` for ordinary sentences and `!` for encapsulated sentences (i.e. AIS).
+	Start byte
+	// Talker is talker identifier (i.e. `GP` for GPS). Empty for proprietary sentences.
+	Talker string
+	// Type is sentence type (i.e. `RMC`). For proprietary sentences it is whole address field (i.e. `PGRME`).
+	Type string
+	// Fields are data fields that follow address field
+	Fields []string
+
+	// Parts contains all sentences of multi-sentence message in order. Nil for single sentences. Fields of aggregated
+	// sentence are fields of the first part.
+	Parts []Sentence
+}
+
+// NewSentence creates new sentence with `$` start character
+func NewSentence(talker string, sentenceType string, fields ...string) Sentence {
+	return Sentence{
+		Start:  '$',
+		Talker: talker,
+		Type:   sentenceType,
+		Fields: fields,
+	}
+}
+
+// ParseSentence parses single sentence line (i.e. `$GPHDG,98.3,0.0,E,12.6,W*4B`). Trailing CR/LF is ignored.
+func ParseSentence(raw string, allowMissingChecksum bool) (Sentence, error) {
+	raw = strings.TrimRight(raw, "\r\n")
+	if len(raw) < 6 || (raw[0] != '$' && raw[0] != '!') {
+		return Sentence{}, fmt.Errorf("%w: %q", ErrInvalidSentence, raw)
+	}
+
+	data := raw[1:]
+	if idx := strings.LastIndexByte(raw, '*'); idx != -1 {
+		data = raw[1:idx]
+		expected, err := strconv.ParseUint(raw[idx+1:], 16, 8)
+		if err != nil || len(raw)-idx-1 != 2 {
+			return Sentence{}, fmt.Errorf("%w: %q", ErrInvalidChecksum, raw)
+		}
+		if Checksum(data) != uint8(expected) {
+			return Sentence{}, fmt.Errorf("%w: %q", ErrInvalidChecksum, raw)
+		}
+	} else if !allowMissingChecksum {
+		return Sentence{}, fmt.Errorf("%w: %q", ErrMissingChecksum, raw)
+	}
+
+	parts := strings.Split(data, ",")
+	address := parts[0]
+	if len(address) < 3 {
+		return Sentence{}, fmt.Errorf("%w: %q", ErrInvalidSentence, raw)
+	}
+	s := Sentence{
+		Start:  raw[0],
+		Fields: parts[1:],
+	}
+	if address[0] == 'P' { // proprietary sentence, i.e. `$PGRME`
+		s.Type = address
+	} else {
+		s.Talker = address[:2]
+		s.Type = address[2:]
+	}
+	return s, nil
+}
+
+// Checksum calculates NMEA0183 checksum (XOR of all bytes) of data between start character and `*`
+func Checksum(data string) uint8 {
+	sum := uint8(0)
+	for i := 0; i < len(data); i++ {
+		sum ^= data[i]
+	}
+	return sum
+}
+
+// String returns sentence as line with checksum (without CR/LF). For aggregated sentences only the first part is
+// returned.
+func (s Sentence) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(s.Talker)
+	sb.WriteString(s.Type)
+	for _, f := range s.Fields {
+		sb.WriteByte(',')
+		sb.WriteString(f)
+	}
+	data := sb.String()
+
+	start := s.Start
+	if start == 0 {
+		start = '$'
+	}
+	return fmt.Sprintf("%c%s*%02X", start, data, Checksum(data))
+}
+
+// multiSentenceTypes are sentence types that can be split into multiple sentences. First field is total number of
+// sentences and second field is sentence number (1-based).
+var multiSentenceTypes = map[string]bool{
+	"GSV": true, // satellites in view
+	"VDM": true, // AIS VHF data-link message
+	"VDO": true, // AIS VHF data-link own-vessel report
+}
+
+// partInfo returns total number of sentences and sentence number for multi-sentence message sentence
+func (s Sentence) partInfo() (int, int, bool) {
+	if !multiSentenceTypes[s.Type] || len(s.Fields) < 2 {
+		return 0, 0, false
+	}
+	total, err := strconv.Atoi(s.Fields[0])
+	if err != nil || total < 1 {
+		return 0, 0, false
+	}
+	number, err := strconv.Atoi(s.Fields[1])
+	if err != nil || number < 1 || number > total {
+		return 0, 0, false
+	}
+	return total, number, true
+}
@@ -0,0 +1,121 @@
+package nmea0183
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSentence(t *testing.T) {
+	var testCases = []struct {
+		name                 string
+		when                 string
+		allowMissingChecksum bool
+		expect               Sentence
+		expectErr            string
+	}{
+		{
+			name: "ok",
+			when: "$GPHDG,98.3,0.0,E,12.6,W*4B\r\n",
+			expect: Sentence{
+				Start:  '$',
+				Talker: "GP",
+				Type:   "HDG",
+				Fields: []string{"98.3", "0.0", "E", "12.6", "W"},
+			},
+		},
+		{
+			name: "ok, lowercase checksum",
+			when: "$SDDBT,7.8,f,2.4,M,1.3,F*0d",
+			expect: Sentence{
+				Start:  '$',
+				Talker: "SD",
+				Type:   "DBT",
+				Fields: []string{"7.8", "f", "2.4", "M", "1.3", "F"},
+			},
+		},
+		{
+			name: "ok, encapsulated sentence",
+			when: "!AIVDM,1,1,,B,15M67FC000G?ufbE`FepT@3n00Sa,0*5C",
+			expect: Sentence{
+				Start:  '!',
+				Talker: "AI",
+				Type:   "VDM",
+				Fields: []string{"1", "1", "", "B", "15M67FC000G?ufbE`FepT@3n00Sa", "0"},
+			},
+		},
+		{
+			name: "ok, proprietary sentence",
+			when: "$PGRME,15.0,M,45.0,M,25.0,M*1C",
+			expect: Sentence{
+				Start:  '$',
+				Type:   "PGRME",
+				Fields: []string{"15.0", "M", "45.0", "M", "25.0", "M"},
+			},
+		},
+		{
+			name:                 "ok, missing checksum allowed",
+			when:                 "$SDDBT,7.8,f,2.4,M,1.3,F",
+			allowMissingChecksum: true,
+			expect: Sentence{
+				Start:  '$',
+				Talker: "SD",
+				Type:   "DBT",
+				Fields: []string{"7.8", "f", "2.4", "M", "1.3", "F"},
+			},
+		},
+		{
+			name:      "nok, missing checksum",
+			when:      "$SDDBT,7.8,f,2.4,M,1.3,F",
+			expectErr: `NMEA0183 sentence has no checksum: "$SDDBT,7.8,f,2.4,M,1.3,F"`,
+		},
+		{
+			name:      "nok, invalid checksum",
+			when:      "$SDDBT,7.8,f,2.4,M,1.3,F*0E",
+			expectErr: `invalid NMEA0183 sentence checksum: "$SDDBT,7.8,f,2.4,M,1.3,F*0E"`,
+		},
+		{
+			name:      "nok, invalid checksum format",
+			when:      "$SDDBT,7.8,f,2.4,M,1.3,F*D",
+			expectErr: `invalid NMEA0183 sentence checksum: "$SDDBT,7.8,f,2.4,M,1.3,F*D"`,
+		},
+		{
+			name:      "nok, invalid start character",
+			when:      "SDDBT,7.8,f,2.4,M,1.3,F*0D",
+			expectErr: `invalid NMEA0183 sentence: "SDDBT,7.8,f,2.4,M,1.3,F*0D"`,
+		},
+		{
+			name:                 "nok, too short address",
+			when:                 "$SD,7.8,f,2.4,M",
+			allowMissingChecksum: true,
+			expectErr:            `invalid NMEA0183 sentence: "$SD,7.8,f,2.4,M"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseSentence(tc.when, tc.allowMissingChecksum)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	assert.Equal(t, uint8(0x4B), Checksum("GPHDG,98.3,0.0,E,12.6,W"))
+	assert.Equal(t, uint8(0x00), Checksum(""))
+}
+
+func TestSentence_String(t *testing.T) {
+	assert.Equal(t, "$GPHDG,98.3,0.0,E,12.6,W*4B", NewSentence("GP", "HDG", "98.3", "0.0", "E", "12.6", "W").String())
+
+	s, err := ParseSentence("!AIVDM,1,1,,B,15M67FC000G?ufbE`FepT@3n00Sa,0*5C", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "!AIVDM,1,1,,B,15M67FC000G?ufbE`FepT@3n00Sa,0*5C", s.String())
+
+	assert.Equal(t, "$PGRME,15.0,M*1A", Sentence{Type: "PGRME", Fields: []string{"15.0", "M"}}.String())
+}
@@ -0,0 +1,38 @@
+package nmea0183
+
+import (
+	"context"
+	"io"
+)
+
+// Writer writes NMEA0183 sentences with checksum and CR/LF line ending to underlying writer
+type Writer struct {
+	writer io.Writer
+}
+
+// NewWriter creates new instance of NMEA0183 sentence Writer
+func NewWriter(writer io.Writer) *Writer {
+	return &Writer{writer: writer}
+}
+
+// WriteSentence writes sentence. All parts are written for aggregated multi-sentence messages.
+func (w *Writer) WriteSentence(ctx context.Context, s Sentence) error {
+	parts := s.Parts
+	if len(parts) == 0 {
+		parts = []Sentence{s}
+	}
+	for _, p := range parts {
+		if _, err := io.WriteString(w.writer, p.String()+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes underlying writer if it implements io.Closer
+func (w *Writer) Close() error {
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
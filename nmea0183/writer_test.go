@@ -0,0 +1,29 @@
+package nmea0183
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriter_WriteSentence(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+
+	err := w.WriteSentence(context.Background(), NewSentence("GP", "HDG", "98.3", "0.0", "E", "12.6", "W"))
+	assert.NoError(t, err)
+	assert.Equal(t, "$GPHDG,98.3,0.0,E,12.6,W*4B\r\n", buf.String())
+}
+
+func TestWriter_WriteSentence_multiSentence(t *testing.T) {
+	input := "$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75\r\n" +
+		"$GPGSV,2,2,08,15,61,190,42,16,22,040,43,17,07,123,38,18,10,090,40*77\r\n"
+	s, err := testReader(input, ReaderConfig{}).ReadSentence(context.Background())
+	assert.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	err = NewWriter(buf).WriteSentence(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Equal(t, input, buf.String())
+}
@@ -0,0 +1,311 @@
+package pcan
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriteNotSupported is returned when writing to trace file reader
+var ErrWriteNotSupported = errors.New("PCAN trace reader does not support writing")
+
+// TRCConfig is configuration for TRCReader
+type TRCConfig struct {
+	// Location is time zone of trace file start time. PEAK tools (PCAN-View, PCAN-Explorer) write start time in local
+	// time of recording computer.
+	// Optional: defaults to time.Local
+	Location *time.Location
+
+	// FastPacketAssembler assembles fast-packet PGN frames to complete messages. Use nmea.ISOTPAssembler wrapping
+	// nmea.FastPacketAssembler to assemble ISO 11783-3 transport protocol messages as well.
+	// Optional: when not set every frame is returned as separate message
+	FastPacketAssembler nmea.Assembler
+}
+
+// TRCReader reads PEAK-System PCAN trace files (`.trc`, versions 1.0-1.3 and 2.0-2.1) created by PCAN-View,
+// PCAN-Explorer or PCAN-Basic tracer.
+//
+// Message times are original recording times - file start time (`$STARTTIME` or `Start time:` header) plus frame
+// time offset. When file has no start time, offsets are anchored to time when first frame was read.
+//
+// Only received and transmitted extended (29bit) data frames are returned. Standard (11bit) frames, remote requests,
+// error and status frames are skipped as they are not NMEA2000 frames.
+//
+// Note: live PCAN-USB devices are available as SocketCAN interfaces (`peak_usb` driver) on Linux and can be read
+// with socketcan.Device.
+type TRCReader struct {
+	reader  io.Reader
+	scanner *bufio.Scanner
+	timeNow func() time.Time
+	config  TRCConfig
+
+	lineNumber int
+	// version is file format version (i.e. `1.1`, `2.0`). Files without `$FILEVERSION` header are version 1.0
+	version string
+	// columns are v2.x data line columns (`$COLUMNS` header)
+	columns   []string
+	startTime time.Time
+
+	closed atomic.Bool
+}
+
+// NewTRCReader creates new instance of PCAN trace file reader
+func NewTRCReader(reader io.Reader, config TRCConfig) *TRCReader {
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	return &TRCReader{
+		reader:  reader,
+		scanner: bufio.NewScanner(reader),
+		timeNow: time.Now,
+		config:  config,
+		version: "1.0",
+	}
+}
+
+// Initialize initializes reader. Trace files need no initialization.
+func (r *TRCReader) Initialize() error {
+	return nil
+}
+
+// Close closes underlying reader. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
+func (r *TRCReader) Close() error {
+	r.closed.Store(true)
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// WriteRawMessage always returns ErrWriteNotSupported
+func (r *TRCReader) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	return ErrWriteNotSupported
+}
+
+// ReadRawMessage reads next message from trace file. When TRCConfig.FastPacketAssembler is set frames are assembled
+// to complete messages. Returns io.EOF when end of file is reached.
+func (r *TRCReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if r.config.FastPacketAssembler != nil {
+		msg := nmea.RawMessage{}
+		for {
+			frame, err := r.ReadRawFrame(ctx)
+			if err != nil {
+				return nmea.RawMessage{}, err
+			}
+			if r.config.FastPacketAssembler.Assemble(frame, &msg) {
+				return msg, nil
+			}
+		}
+	}
+
+	frame, err := r.ReadRawFrame(ctx)
+	if err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return nmea.RawMessage{
+		Time:   frame.Time,
+		Header: frame.Header,
+		Data:   frame.Data[:frame.Length],
+	}, nil
+}
+
+// ReadRawFrame reads next frame from trace file. Invalid lines are returned as errors so caller can decide to skip
+// them and continue reading. Returns io.EOF when end of file is reached.
+func (r *TRCReader) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nmea.RawFrame{}, ctx.Err()
+		default:
+		}
+		if r.closed.Load() {
+			return nmea.RawFrame{}, nmea.ErrDeviceClosed
+		}
+		if !r.scanner.Scan() {
+			break
+		}
+		r.lineNumber++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] == ';' {
+			if err := r.parseHeaderLine(line); err != nil {
+				return nmea.RawFrame{}, fmt.Errorf("PCAN trace line %v: %w", r.lineNumber, err)
+			}
+			continue
+		}
+
+		frame, offset, skip, err := r.parseDataLine(line)
+		if err != nil {
+			return nmea.RawFrame{}, fmt.Errorf("PCAN trace line %v: %w", r.lineNumber, err)
+		}
+		if skip {
+			continue
+		}
+		if r.startTime.IsZero() {
+			r.startTime = r.timeNow().Add(-offset)
+		}
+		frame.Time = r.startTime.Add(offset)
+		return frame, nil
+	}
+	if r.closed.Load() {
+		return nmea.RawFrame{}, nmea.ErrDeviceClosed
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nmea.RawFrame{}, err
+	}
+	return nmea.RawFrame{}, io.EOF
+}
+
+func (r *TRCReader) parseHeaderLine(line string) error {
+	// Examples:
+	// `;$FILEVERSION=2.0`
+	// `;$STARTTIME=43347.6406113889`
+	// `;$COLUMNS=N,O,T,I,d,l,D`
+	// `;   Start time: 05.09.2018 15:22:28.823.0`
+	line = strings.TrimSpace(line[1:])
+	switch {
+	case strings.HasPrefix(line, "$FILEVERSION="):
+		r.version = strings.TrimPrefix(line, "$FILEVERSION=")
+		if r.version == "2.0" && r.columns == nil {
+			r.columns = []string{"N", "O", "T", "I", "d", "l", "D"}
+		} else if r.version == "2.1" && r.columns == nil {
+			r.columns = []string{"N", "O", "T", "B", "I", "d", "R", "L", "D"}
+		}
+	case strings.HasPrefix(line, "$STARTTIME="):
+		days, err := strconv.ParseFloat(strings.TrimPrefix(line, "$STARTTIME="), 64)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %w", err)
+		}
+		r.startTime = fromOLEDate(days, r.config.Location)
+	case strings.HasPrefix(line, "$COLUMNS="):
+		r.columns = strings.Split(strings.TrimPrefix(line, "$COLUMNS="), ",")
+	case strings.HasPrefix(line, "Start time:") && r.startTime.IsZero():
+		value := strings.TrimSpace(strings.TrimPrefix(line, "Start time:"))
+		if len(value) > 23 {
+			value = value[:23] // strip microseconds part `.0`
+		}
+		t, err := time.ParseInLocation("02.01.2006 15:04:05.000", value, r.config.Location)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %w", err)
+		}
+		r.startTime = t
+	}
+	return nil
+}
+
+// fromOLEDate converts OLE automation date (days since 1899-12-30, fraction is time of day) to time
+func fromOLEDate(days float64, location *time.Location) time.Time {
+	wholeDays := math.Floor(days)
+	ms := math.Round((days - wholeDays) * 24 * 60 * 60 * 1000)
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, location).
+		AddDate(0, 0, int(wholeDays)).
+		Add(time.Duration(ms) * time.Millisecond)
+}
+
+// parseDataLine parses frame line. Returns true for frames that should be skipped (not extended data frames).
+func (r *TRCReader) parseDataLine(line string) (nmea.RawFrame, time.Duration, bool, error) {
+	tokens := strings.Fields(line)
+
+	columns := r.columns
+	if columns == nil {
+		switch r.version {
+		case "1.0":
+			// `     1)      1841  0300  8  00 00 00 00 00 00 00 00`
+			columns = []string{"N", "O", "I", "l", "D"}
+		case "1.1":
+			// `     1)      1841.0  Rx     18EFC0F0  8  01 02 03 04 05 06 07 08`
+			columns = []string{"N", "O", "T", "I", "l", "D"}
+		case "1.2":
+			// `     1)      1841.0 1  Rx     18EFC0F0  8  01 02 03 04 05 06 07 08`
+			columns = []string{"N", "O", "B", "T", "I", "l", "D"}
+		case "1.3":
+			// `     1)      1841.0 1  Rx     18EFC0F0 -  8  01 02 03 04 05 06 07 08`
+			columns = []string{"N", "O", "B", "T", "I", "R", "l", "D"}
+		default:
+			return nmea.RawFrame{}, 0, false, fmt.Errorf("unsupported file version: %v", r.version)
+		}
+	}
+
+	var offset time.Duration
+	var canID string
+	length := -1
+	var data []string
+	for i, column := range columns {
+		if column == "D" {
+			if i < len(tokens) {
+				data = tokens[i:]
+			}
+			break
+		}
+		if i >= len(tokens) {
+			return nmea.RawFrame{}, 0, false, errors.New("too few columns")
+		}
+		token := tokens[i]
+		switch column {
+		case "O":
+			ms, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return nmea.RawFrame{}, 0, false, fmt.Errorf("invalid time offset: %w", err)
+			}
+			offset = time.Duration(math.Round(ms*1000)) * time.Microsecond
+		case "T":
+			// v1.x: Rx, Tx, Warng, Error. v2.x: DT (data frame), RR (remote request), FD, FB, FE, BI (CAN FD),
+			// ST, EC, ER, EV (status/error/event)
+			if token != "Rx" && token != "Tx" && token != "DT" {
+				return nmea.RawFrame{}, 0, true, nil
+			}
+		case "I":
+			canID = token
+		case "l", "L":
+			if length != -1 && column == "L" {
+				continue // actual data length `l` takes precedence over DLC
+			}
+			l, err := strconv.Atoi(token)
+			if err != nil || l < 0 || l > 8 {
+				return nmea.RawFrame{}, 0, false, fmt.Errorf("invalid data length: %v", token)
+			}
+			length = l
+		}
+	}
+	if len(canID) <= 4 { // standard 11bit frame
+		return nmea.RawFrame{}, 0, true, nil
+	}
+	if len(data) > 0 && data[0] == "RTR" {
+		return nmea.RawFrame{}, 0, true, nil
+	}
+	if length == -1 {
+		length = len(data)
+	}
+	if len(data) < length {
+		return nmea.RawFrame{}, 0, false, errors.New("too few data bytes")
+	}
+
+	id, err := strconv.ParseUint(canID, 16, 29)
+	if err != nil {
+		return nmea.RawFrame{}, 0, false, fmt.Errorf("invalid CAN ID: %w", err)
+	}
+	frame := nmea.RawFrame{
+		Header: nmea.ParseCANID(uint32(id)),
+		Length: uint8(length),
+	}
+	for i := 0; i < length; i++ {
+		if len(data[i]) != 2 {
+			return nmea.RawFrame{}, 0, false, fmt.Errorf("invalid data byte: %v", data[i])
+		}
+		if _, err := hex.Decode(frame.Data[i:i+1], []byte(data[i])); err != nil {
+			return nmea.RawFrame{}, 0, false, fmt.Errorf("invalid data byte: %w", err)
+		}
+	}
+	return frame, offset, false, nil
+}
@@ -0,0 +1,200 @@
+package pcan
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTRCReader_ReadRawMessage(t *testing.T) {
+	start := time.Date(2022, 10, 11, 11, 47, 22, 0, time.UTC)
+	expect := []nmea.RawMessage{
+		{
+			Time:   start.Add(100500 * time.Microsecond),
+			Header: nmea.CanBusHeader{PGN: 130312, Priority: 5, Source: 0, Destination: 255},
+			Data:   []byte{0xFF, 0x00, 0x01, 0xCA, 0x6F, 0xFF, 0xFF, 0xFF},
+		},
+		{
+			Time:   start.Add(250 * time.Millisecond),
+			Header: nmea.CanBusHeader{PGN: 130820, Priority: 7, Source: 0, Destination: 255},
+			Data:   []byte{0x80, 0x07, 0x3F, 0x9F, 0x00, 0x40, 0x00, 0x00},
+		},
+		{
+			Time:   start.Add(251300 * time.Microsecond),
+			Header: nmea.CanBusHeader{PGN: 130820, Priority: 7, Source: 0, Destination: 255},
+			Data:   []byte{0x81, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+		},
+		{
+			Time:   start.Add(300 * time.Millisecond),
+			Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: 255},
+			Data:   []byte{0x00, 0xEE, 0x00},
+		},
+	}
+
+	for _, file := range []string{"testdata/trace_v1_1.trc", "testdata/trace_v2_0.trc"} {
+		t.Run(file, func(t *testing.T) {
+			f, err := os.Open(file)
+			if !assert.NoError(t, err) {
+				return
+			}
+			reader := NewTRCReader(f, TRCConfig{Location: time.UTC})
+			defer reader.Close()
+
+			result := make([]nmea.RawMessage, 0)
+			for {
+				msg, err := reader.ReadRawMessage(context.Background())
+				if err == io.EOF {
+					break
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+				result = append(result, msg)
+			}
+			assert.Equal(t, expect, result)
+		})
+	}
+}
+
+func TestTRCReader_ReadRawMessage_fastPacket(t *testing.T) {
+	f, err := os.Open("testdata/trace_v2_0.trc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader := NewTRCReader(f, TRCConfig{
+		Location:            time.UTC,
+		FastPacketAssembler: nmea.NewFastPacketAssembler([]uint32{130820}),
+	})
+	defer reader.Close()
+
+	_, err = reader.ReadRawMessage(context.Background()) // 130312
+	assert.NoError(t, err)
+
+	msg, err := reader.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(130820), msg.Header.PGN)
+	assert.Equal(t, nmea.RawData{0x3F, 0x9F, 0x00, 0x40, 0x00, 0x00, 0x01}, msg.Data)
+}
+
+func TestTRCReader_ReadRawFrame(t *testing.T) {
+	now := time.Date(2022, 10, 11, 11, 47, 22, 0, time.UTC)
+	var testCases = []struct {
+		name        string
+		when        string
+		expect      nmea.RawFrame
+		expectError string
+	}{
+		{
+			name: "ok, v1.0 without start time is anchored to read time",
+			when: "     1)      1841  18EAFFFE  3  00 EE 00\n",
+			expect: nmea.RawFrame{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: 255},
+				Length: 3,
+				Data:   [8]byte{0x00, 0xEE, 0x00},
+			},
+		},
+		{
+			name: "ok, v1.0 with start time comment",
+			when: ";   Start time: 11.10.2022 11:47:22.000.0\n" +
+				"     1)      1841  18EAFFFE  3  00 EE 00\n",
+			expect: nmea.RawFrame{
+				Time:   now.Add(1841 * time.Millisecond),
+				Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: 255},
+				Length: 3,
+				Data:   [8]byte{0x00, 0xEE, 0x00},
+			},
+		},
+		{
+			name: "ok, v1.3",
+			when: ";$FILEVERSION=1.3\n" +
+				";$STARTTIME=44845.4912268519\n" +
+				"     1)      1841.0 1  Rx     18EAFFFE -  3  00 EE 00\n",
+			expect: nmea.RawFrame{
+				Time:   now.Add(1841 * time.Millisecond),
+				Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: 255},
+				Length: 3,
+				Data:   [8]byte{0x00, 0xEE, 0x00},
+			},
+		},
+		{
+			name: "ok, v2.1 default columns",
+			when: ";$FILEVERSION=2.1\n" +
+				";$STARTTIME=44845.4912268519\n" +
+				"      1      1841.000 DT 1 18EAFFFE Rx - 3    00 EE 00\n",
+			expect: nmea.RawFrame{
+				Time:   now.Add(1841 * time.Millisecond),
+				Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: 255},
+				Length: 3,
+				Data:   [8]byte{0x00, 0xEE, 0x00},
+			},
+		},
+		{
+			name: "nok, unsupported version",
+			when: ";$FILEVERSION=3.0\n" +
+				"      1      1841.000 DT 18EAFFFE Rx 3  00 EE 00\n",
+			expectError: "PCAN trace line 2: unsupported file version: 3.0",
+		},
+		{
+			name: "nok, invalid offset",
+			when: ";$FILEVERSION=1.1\n" +
+				"     1)      x  Rx     18EAFFFE  3  00 EE 00\n",
+			expectError: `PCAN trace line 2: invalid time offset: strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+		{
+			name: "nok, too few data bytes",
+			when: ";$FILEVERSION=1.1\n" +
+				"     1)      1841.0  Rx     18EAFFFE  3  00 EE\n",
+			expectError: "PCAN trace line 2: too few data bytes",
+		},
+		{
+			name: "nok, invalid data byte",
+			when: ";$FILEVERSION=1.1\n" +
+				"     1)      1841.0  Rx     18EAFFFE  3  00 EX 00\n",
+			expectError: "PCAN trace line 2: invalid data byte: encoding/hex: invalid byte: U+0058 'X'",
+		},
+		{
+			name:        "nok, invalid start time",
+			when:        ";$STARTTIME=x\n",
+			expectError: `PCAN trace line 1: invalid start time: strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewTRCReader(strings.NewReader(tc.when), TRCConfig{Location: time.UTC})
+			reader.timeNow = func() time.Time { return now }
+
+			result, err := reader.ReadRawFrame(context.Background())
+			assert.Equal(t, tc.expect, result)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTRCReader_WriteRawMessage(t *testing.T) {
+	reader := NewTRCReader(strings.NewReader(""), TRCConfig{})
+	assert.ErrorIs(t, reader.WriteRawMessage(context.Background(), nmea.RawMessage{}), ErrWriteNotSupported)
+}
+
+func TestTRCReader_Close(t *testing.T) {
+	reader := NewTRCReader(strings.NewReader("     1)      1841  18EAFFFE  3  00 EE 00\n"), TRCConfig{})
+	assert.NoError(t, reader.Close())
+
+	_, err := reader.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+}
+
+func TestFromOLEDate(t *testing.T) {
+	assert.Equal(t, time.Date(2022, 10, 11, 11, 47, 22, 0, time.UTC), fromOLEDate(44845.4912268519, time.UTC))
+	assert.Equal(t, time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC), fromOLEDate(0, time.UTC))
+}
@@ -0,0 +1,87 @@
+package pgns
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"time"
+)
+
+// PGNEngineParametersDynamic is PGN 127489 Engine Parameters, Dynamic
+const PGNEngineParametersDynamic = uint32(127489)
+
+// EngineParametersDynamic is PGN 127489 Engine Parameters, Dynamic
+type EngineParametersDynamic struct {
+	// Instance is engine instance (0 = single engine or dual engine port, 1 = dual engine starboard)
+	Instance *uint8
+	// OilPressure is oil pressure in Pascals
+	OilPressure *float64
+	// OilTemperature is oil temperature in Kelvins
+	OilTemperature *float64
+	// Temperature is engine (coolant) temperature in Kelvins
+	Temperature *float64
+	// AlternatorPotential is alternator voltage in Volts
+	AlternatorPotential *float64
+	// FuelRate is fuel consumption rate in L/h
+	FuelRate *float64
+	// TotalEngineHours is total engine running time
+	TotalEngineHours *time.Duration
+	// CoolantPressure is coolant pressure in Pascals
+	CoolantPressure *float64
+	// FuelPressure is fuel pressure in Pascals
+	FuelPressure *float64
+	// DiscreteStatus1 is ENGINE_STATUS_1 bit field (i.e. bit 0 = Check Engine, bit 1 = Over Temperature)
+	DiscreteStatus1 *uint16
+	// DiscreteStatus2 is ENGINE_STATUS_2 bit field (i.e. bit 0 = Warning Level 1)
+	DiscreteStatus2 *uint16
+	// EngineLoad is engine load in percents
+	EngineLoad *int8
+	// EngineTorque is engine torque in percents
+	EngineTorque *int8
+}
+
+// PGN returns PGN number of EngineParametersDynamic
+func (p *EngineParametersDynamic) PGN() uint32 {
+	return PGNEngineParametersDynamic
+}
+
+// FromMessage fills EngineParametersDynamic fields from decoded message
+func (p *EngineParametersDynamic) FromMessage(msg nmea.Message) error {
+	if err := checkPGN(msg, PGNEngineParametersDynamic); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = EngineParametersDynamic{
+		Instance:            r.uint8("instance"),
+		OilPressure:         r.float64("oilPressure"),
+		OilTemperature:      r.float64("oilTemperature"),
+		Temperature:         r.float64("temperature"),
+		AlternatorPotential: r.float64("alternatorPotential"),
+		FuelRate:            r.float64("fuelRate"),
+		TotalEngineHours:    r.duration("totalEngineHours"),
+		CoolantPressure:     r.float64("coolantPressure"),
+		FuelPressure:        r.float64("fuelPressure"),
+		DiscreteStatus1:     r.uint16("discreteStatus1"),
+		DiscreteStatus2:     r.uint16("discreteStatus2"),
+		EngineLoad:          r.int8("engineLoad"),
+		EngineTorque:        r.int8("engineTorque"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with EngineParametersDynamic fields
+func (p *EngineParametersDynamic) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := fieldsBuilder{}
+	b.uint8("instance", p.Instance)
+	b.float64("oilPressure", p.OilPressure)
+	b.float64("oilTemperature", p.OilTemperature)
+	b.float64("temperature", p.Temperature)
+	b.float64("alternatorPotential", p.AlternatorPotential)
+	b.float64("fuelRate", p.FuelRate)
+	b.duration("totalEngineHours", p.TotalEngineHours)
+	b.float64("coolantPressure", p.CoolantPressure)
+	b.float64("fuelPressure", p.FuelPressure)
+	b.uint16("discreteStatus1", p.DiscreteStatus1)
+	b.uint16("discreteStatus2", p.DiscreteStatus2)
+	b.int8("engineLoad", p.EngineLoad)
+	b.int8("engineTorque", p.EngineTorque)
+	return createMessage(header, PGNEngineParametersDynamic, b.fields)
+}
@@ -0,0 +1,91 @@
+package pgns
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEngineParametersDynamic_FromMessage(t *testing.T) {
+	pgn := canboat.PGN{}
+	test_test.LoadJSON(t, "../../canboat/testdata/canboat_pgn_127489.json", &pgn)
+	schema := canboat.CanboatSchema{PGNs: canboat.PGNs{pgn}}
+
+	raw := nmea.RawMessage{
+		Header: nmea.CanBusHeader{Priority: 2, PGN: 127489, Destination: 255, Source: 236},
+		Data: []byte{
+			0x00, 0x28, 0x00, 0xff, 0xff, 0xbb, 0x71, 0x57, 0x03, 0x00,
+			0x00, 0xe0, 0xb0, 0x05, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0x20, 0x00, 0x00, 0x00, 0x7e, 0xff,
+		},
+	}
+	decoded, err := canboat.NewDecoder(schema).Decode(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	result := EngineParametersDynamic{}
+	err = result.FromMessage(decoded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(0), *result.Instance)
+	assert.InDelta(t, 4000.0, *result.OilPressure, 0.001)
+	assert.Nil(t, result.OilTemperature) // no data
+	assert.InDelta(t, 291.15, *result.Temperature, 0.001)
+	assert.InDelta(t, 8.55, *result.AlternatorPotential, 0.001)
+	assert.InDelta(t, 0.0, *result.FuelRate, 0.001)
+	assert.Equal(t, 103*time.Hour+36*time.Minute, *result.TotalEngineHours)
+	assert.Nil(t, result.CoolantPressure)
+	assert.Nil(t, result.FuelPressure)
+	assert.Equal(t, uint16(32), *result.DiscreteStatus1)
+	assert.Equal(t, uint16(0), *result.DiscreteStatus2)
+	assert.Nil(t, result.EngineLoad) // out of range
+	assert.Equal(t, int8(-1), *result.EngineTorque)
+
+	// encoding typed struct produces same fields when decoded again
+	msg := result.ToMessage(raw.Header)
+	encoded, err := canboat.NewEncoder(schema).Encode(msg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	roundTrip, err := canboat.NewDecoder(schema).Decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, decoded.Fields, roundTrip.Fields)
+}
+
+func TestEngineParametersDynamic_FromMessage_enumValues(t *testing.T) {
+	result := EngineParametersDynamic{}
+	err := result.FromMessage(nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 127489},
+		Fields: nmea.FieldValues{
+			{ID: "instance", Value: nmea.EnumValue{Value: 1, Code: "Dual Engine Starboard"}},
+			{ID: "discreteStatus1", Value: []nmea.EnumValue{{Value: 0, Code: "Check Engine"}, {Value: 5, Code: "Low System Voltage"}}},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(1), *result.Instance)
+	assert.Equal(t, uint16(0b100001), *result.DiscreteStatus1)
+	assert.Nil(t, result.DiscreteStatus2)
+}
+
+func TestEngineParametersDynamic_ToMessage(t *testing.T) {
+	instance := uint8(1)
+	load := int8(-5)
+	hours := 2 * time.Hour
+	p := EngineParametersDynamic{Instance: &instance, EngineLoad: &load, TotalEngineHours: &hours}
+
+	result := p.ToMessage(nmea.CanBusHeader{Priority: 2, Source: 10, Destination: 255})
+
+	assert.Equal(t, nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 127489, Priority: 2, Source: 10, Destination: 255},
+		Fields: nmea.FieldValues{
+			{ID: "instance", Value: uint64(1)},
+			{ID: "totalEngineHours", Value: 2 * time.Hour},
+			{ID: "engineLoad", Value: int64(-5)},
+		},
+	}, result)
+}
@@ -0,0 +1,193 @@
+package pgns
+
+import (
+	"github.com/aldas/go-nmea-client"
+)
+
+const (
+	// PGNWaterDepth is PGN 128267 Water Depth
+	PGNWaterDepth = uint32(128267)
+	// PGNPositionRapidUpdate is PGN 129025 Position, Rapid Update
+	PGNPositionRapidUpdate = uint32(129025)
+	// PGNCOGSOGRapidUpdate is PGN 129026 COG & SOG, Rapid Update
+	PGNCOGSOGRapidUpdate = uint32(129026)
+	// PGNWindData is PGN 130306 Wind Data
+	PGNWindData = uint32(130306)
+)
+
+const (
+	// DirectionReferenceTrue is DIRECTION_REFERENCE lookup value for true (north referenced) direction
+	DirectionReferenceTrue = uint8(0)
+	// DirectionReferenceMagnetic is DIRECTION_REFERENCE lookup value for magnetic direction
+	DirectionReferenceMagnetic = uint8(1)
+)
+
+const (
+	// WindReferenceTrueGround is WIND_REFERENCE lookup value for true wind (ground referenced to North)
+	WindReferenceTrueGround = uint8(0)
+	// WindReferenceMagnetic is WIND_REFERENCE lookup value for magnetic wind (ground referenced to Magnetic North)
+	WindReferenceMagnetic = uint8(1)
+	// WindReferenceApparent is WIND_REFERENCE lookup value for apparent wind
+	WindReferenceApparent = uint8(2)
+	// WindReferenceTrueBoat is WIND_REFERENCE lookup value for true wind (boat referenced)
+	WindReferenceTrueBoat = uint8(3)
+	// WindReferenceTrueWater is WIND_REFERENCE lookup value for true wind (water referenced)
+	WindReferenceTrueWater = uint8(4)
+)
+
+// WaterDepth is PGN 128267 Water Depth
+type WaterDepth struct {
+	SID *uint8
+	// Depth is depth below transducer in meters
+	Depth *float64
+	// Offset is distance between transducer and water surface (positive) or keel (negative) in meters
+	Offset *float64
+	// Range is maximum depth range of transducer in meters
+	Range *float64
+}
+
+// PGN returns PGN number of WaterDepth
+func (p *WaterDepth) PGN() uint32 {
+	return PGNWaterDepth
+}
+
+// FromMessage fills WaterDepth fields from decoded message
+func (p *WaterDepth) FromMessage(msg nmea.Message) error {
+	if err := checkPGN(msg, PGNWaterDepth); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = WaterDepth{
+		SID:    r.uint8("sid"),
+		Depth:  r.float64("depth"),
+		Offset: r.float64("offset"),
+		Range:  r.float64("range"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with WaterDepth fields
+func (p *WaterDepth) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := fieldsBuilder{}
+	b.uint8("sid", p.SID)
+	b.float64("depth", p.Depth)
+	b.float64("offset", p.Offset)
+	b.float64("range", p.Range)
+	return createMessage(header, PGNWaterDepth, b.fields)
+}
+
+// PositionRapidUpdate is PGN 129025 Position, Rapid Update
+type PositionRapidUpdate struct {
+	// Latitude in degrees
+	Latitude *float64
+	// Longitude in degrees
+	Longitude *float64
+}
+
+// PGN returns PGN number of PositionRapidUpdate
+func (p *PositionRapidUpdate) PGN() uint32 {
+	return PGNPositionRapidUpdate
+}
+
+// FromMessage fills PositionRapidUpdate fields from decoded message
+func (p *PositionRapidUpdate) FromMessage(msg nmea.Message) error {
+	if err := checkPGN(msg, PGNPositionRapidUpdate); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = PositionRapidUpdate{
+		Latitude:  r.float64("latitude"),
+		Longitude: r.float64("longitude"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with PositionRapidUpdate fields
+func (p *PositionRapidUpdate) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := fieldsBuilder{}
+	b.float64("latitude", p.Latitude)
+	b.float64("longitude", p.Longitude)
+	return createMessage(header, PGNPositionRapidUpdate, b.fields)
+}
+
+// COGSOGRapidUpdate is PGN 129026 COG & SOG, Rapid Update
+type COGSOGRapidUpdate struct {
+	SID *uint8
+	// COGReference is direction reference of COG (DirectionReferenceTrue, DirectionReferenceMagnetic)
+	COGReference *uint8
+	// COG is course over ground in radians
+	COG *float64
+	// SOG is speed over ground in m/s
+	SOG *float64
+}
+
+// PGN returns PGN number of COGSOGRapidUpdate
+func (p *COGSOGRapidUpdate) PGN() uint32 {
+	return PGNCOGSOGRapidUpdate
+}
+
+// FromMessage fills COGSOGRapidUpdate fields from decoded message
+func (p *COGSOGRapidUpdate) FromMessage(msg nmea.Message) error {
+	if err := checkPGN(msg, PGNCOGSOGRapidUpdate); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = COGSOGRapidUpdate{
+		SID:          r.uint8("sid"),
+		COGReference: r.uint8("cogReference"),
+		COG:          r.float64("cog"),
+		SOG:          r.float64("sog"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with COGSOGRapidUpdate fields
+func (p *COGSOGRapidUpdate) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := fieldsBuilder{}
+	b.uint8("sid", p.SID)
+	b.uint8("cogReference", p.COGReference)
+	b.float64("cog", p.COG)
+	b.float64("sog", p.SOG)
+	return createMessage(header, PGNCOGSOGRapidUpdate, b.fields)
+}
+
+// WindData is PGN 130306 Wind Data
+type WindData struct {
+	SID *uint8
+	// WindSpeed is wind speed in m/s
+	WindSpeed *float64
+	// WindAngle is wind angle in radians
+	WindAngle *float64
+	// Reference is wind reference (i.e. WindReferenceApparent, WindReferenceTrueBoat)
+	Reference *uint8
+}
+
+// PGN returns PGN number of WindData
+func (p *WindData) PGN() uint32 {
+	return PGNWindData
+}
+
+// FromMessage fills WindData fields from decoded message
+func (p *WindData) FromMessage(msg nmea.Message) error {
+	if err := checkPGN(msg, PGNWindData); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = WindData{
+		SID:       r.uint8("sid"),
+		WindSpeed: r.float64("windSpeed"),
+		WindAngle: r.float64("windAngle"),
+		Reference: r.uint8("reference"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with WindData fields
+func (p *WindData) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := fieldsBuilder{}
+	b.uint8("sid", p.SID)
+	b.float64("windSpeed", p.WindSpeed)
+	b.float64("windAngle", p.WindAngle)
+	b.uint8("reference", p.Reference)
+	return createMessage(header, PGNWindData, b.fields)
+}
@@ -0,0 +1,87 @@
+package pgns
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func uint8Ptr(v uint8) *uint8 {
+	return &v
+}
+
+func TestNavigation_roundTrip(t *testing.T) {
+	header := nmea.CanBusHeader{Priority: 2, Source: 10, Destination: 255}
+
+	var testCases = []struct {
+		name         string
+		given        Typed
+		expectFields nmea.FieldValues
+	}{
+		{
+			name:  "ok, PositionRapidUpdate",
+			given: &PositionRapidUpdate{Latitude: float64Ptr(58.38), Longitude: float64Ptr(24.49)},
+			expectFields: nmea.FieldValues{
+				{ID: "latitude", Value: 58.38},
+				{ID: "longitude", Value: 24.49},
+			},
+		},
+		{
+			name:  "ok, COGSOGRapidUpdate",
+			given: &COGSOGRapidUpdate{SID: uint8Ptr(1), COGReference: uint8Ptr(DirectionReferenceTrue), COG: float64Ptr(1.5), SOG: float64Ptr(3.2)},
+			expectFields: nmea.FieldValues{
+				{ID: "sid", Value: uint64(1)},
+				{ID: "cogReference", Value: uint64(0)},
+				{ID: "cog", Value: 1.5},
+				{ID: "sog", Value: 3.2},
+			},
+		},
+		{
+			name:  "ok, WaterDepth without offset",
+			given: &WaterDepth{Depth: float64Ptr(12.5)},
+			expectFields: nmea.FieldValues{
+				{ID: "depth", Value: 12.5},
+			},
+		},
+		{
+			name:  "ok, WindData",
+			given: &WindData{WindSpeed: float64Ptr(5.5), WindAngle: float64Ptr(0.7), Reference: uint8Ptr(WindReferenceApparent)},
+			expectFields: nmea.FieldValues{
+				{ID: "windSpeed", Value: 5.5},
+				{ID: "windAngle", Value: 0.7},
+				{ID: "reference", Value: uint64(2)},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := tc.given.ToMessage(header)
+
+			assert.Equal(t, tc.given.PGN(), msg.Header.PGN)
+			assert.Equal(t, tc.expectFields, msg.Fields)
+
+			result, err := FromMessage(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.given, result)
+		})
+	}
+}
+
+func TestWindData_FromMessage_enumReference(t *testing.T) {
+	result := WindData{}
+	err := result.FromMessage(nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 130306},
+		Fields: nmea.FieldValues{
+			{ID: "sid", Value: uint64(0)},
+			{ID: "windSpeed", Value: 3.0},
+			{ID: "reference", Value: nmea.EnumValue{Value: 2, Code: "Apparent"}},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, WindData{SID: uint8Ptr(0), WindSpeed: float64Ptr(3.0), Reference: uint8Ptr(WindReferenceApparent)}, result)
+}
@@ -0,0 +1,258 @@
+// Package pgns has typed Go structs for commonly used PGNs and converters between them and generic nmea.Message
+// decoded/encoded by canboat package.
+//
+// Struct fields are pointers so "no data" fields (fields that are not present in decoded message) are nil. Values are
+// in same (SI) units as canboat.Decoder outputs them (radians, m/s, Kelvins, Pascals). Converters use Canboat field IDs
+// and accept lookup values decoded both as numbers and as nmea.EnumValue (canboat.DecoderConfig.DecodeLookupsToEnumType).
+package pgns
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"math"
+	"time"
+)
+
+// ErrUnsupportedPGN is returned by FromMessage when message PGN has no typed struct
+var ErrUnsupportedPGN = errors.New("pgns: unsupported PGN")
+
+// Typed is typed PGN struct that can be converted from and to nmea.Message
+type Typed interface {
+	// PGN returns PGN number of struct
+	PGN() uint32
+	// FromMessage fills struct fields from decoded message fields
+	FromMessage(msg nmea.Message) error
+	// ToMessage creates message with given header (PGN is set from struct) and fields that have value
+	ToMessage(header nmea.CanBusHeader) nmea.Message
+}
+
+// FromMessage converts decoded message to typed struct (pointer to struct) by message PGN. Proprietary PGNs are
+// converted by message manufacturer code (and proprietary ID). Returns ErrUnsupportedPGN when PGN has no typed struct.
+func FromMessage(msg nmea.Message) (Typed, error) {
+	var t Typed
+	switch msg.Header.PGN {
+	case PGNEngineParametersDynamic:
+		t = &EngineParametersDynamic{}
+	case PGNWaterDepth:
+		t = &WaterDepth{}
+	case PGNPositionRapidUpdate:
+		t = &PositionRapidUpdate{}
+	case PGNCOGSOGRapidUpdate:
+		t = &COGSOGRapidUpdate{}
+	case PGNWindData:
+		t = &WindData{}
+	case PGNProprietaryAddressedFastPacket, PGNMaretronTemperatureHighRange:
+		p, err := proprietaryFromMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		t = p
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedPGN, msg.Header.PGN)
+	}
+	if err := t.FromMessage(msg); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func checkPGN(msg nmea.Message, pgn uint32) error {
+	if msg.Header.PGN != pgn {
+		return fmt.Errorf("pgns: message PGN %v can not be converted to PGN %v struct", msg.Header.PGN, pgn)
+	}
+	return nil
+}
+
+func createMessage(header nmea.CanBusHeader, pgn uint32, fields nmea.FieldValues) nmea.Message {
+	header.PGN = pgn
+	return nmea.Message{Header: header, Fields: fields}
+}
+
+func fieldTypeError(fv nmea.FieldValue) error {
+	return fmt.Errorf("pgns: field %v has unsupported value type %T", fv.ID, fv.Value)
+}
+
+func getFloat64(fields nmea.FieldValues, ID string) (*float64, error) {
+	fv, ok := fields.FindByID(ID)
+	if !ok {
+		return nil, nil
+	}
+	switch fv.Value.(type) {
+	case float64, int64, uint64:
+		v, _ := fv.AsFloat64()
+		return &v, nil
+	}
+	return nil, fieldTypeError(fv)
+}
+
+func getUint(fields nmea.FieldValues, ID string, max uint64) (*uint64, error) {
+	fv, ok := fields.FindByID(ID)
+	if !ok {
+		return nil, nil
+	}
+	var v uint64
+	switch value := fv.Value.(type) {
+	case uint64:
+		v = value
+	case int64:
+		if value < 0 {
+			return nil, fmt.Errorf("pgns: field %v value %v is out of range", fv.ID, value)
+		}
+		v = uint64(value)
+	case nmea.EnumValue:
+		v = uint64(value.Value)
+	case []nmea.EnumValue: // bit lookup, values are bit numbers
+		for _, ev := range value {
+			v |= 1 << ev.Value
+		}
+	default:
+		return nil, fieldTypeError(fv)
+	}
+	if v > max {
+		return nil, fmt.Errorf("pgns: field %v value %v is out of range", fv.ID, v)
+	}
+	return &v, nil
+}
+
+func getUint8(fields nmea.FieldValues, ID string) (*uint8, error) {
+	v, err := getUint(fields, ID, math.MaxUint8)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	result := uint8(*v)
+	return &result, nil
+}
+
+func getUint16(fields nmea.FieldValues, ID string) (*uint16, error) {
+	v, err := getUint(fields, ID, math.MaxUint16)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	result := uint16(*v)
+	return &result, nil
+}
+
+func getInt8(fields nmea.FieldValues, ID string) (*int8, error) {
+	fv, ok := fields.FindByID(ID)
+	if !ok {
+		return nil, nil
+	}
+	var v int64
+	switch value := fv.Value.(type) {
+	case int64:
+		v = value
+	case uint64:
+		if value > math.MaxInt8 {
+			return nil, fmt.Errorf("pgns: field %v value %v is out of range", fv.ID, value)
+		}
+		v = int64(value)
+	default:
+		return nil, fieldTypeError(fv)
+	}
+	if v < math.MinInt8 || v > math.MaxInt8 {
+		return nil, fmt.Errorf("pgns: field %v value %v is out of range", fv.ID, v)
+	}
+	result := int8(v)
+	return &result, nil
+}
+
+func getDuration(fields nmea.FieldValues, ID string) (*time.Duration, error) {
+	fv, ok := fields.FindByID(ID)
+	if !ok {
+		return nil, nil
+	}
+	v, ok := fv.AsDuration()
+	if !ok {
+		return nil, fieldTypeError(fv)
+	}
+	return &v, nil
+}
+
+// fieldsBuilder collects field values of non-nil struct fields. Values are in same types as canboat.Decoder produces
+// them so encoded and decoded messages are comparable.
+type fieldsBuilder struct {
+	fields nmea.FieldValues
+}
+
+func (b *fieldsBuilder) float64(ID string, v *float64) {
+	if v != nil {
+		b.fields = append(b.fields, nmea.FieldValue{ID: ID, Value: *v})
+	}
+}
+
+func (b *fieldsBuilder) uint8(ID string, v *uint8) {
+	if v != nil {
+		b.fields = append(b.fields, nmea.FieldValue{ID: ID, Value: uint64(*v)})
+	}
+}
+
+func (b *fieldsBuilder) uint16(ID string, v *uint16) {
+	if v != nil {
+		b.fields = append(b.fields, nmea.FieldValue{ID: ID, Value: uint64(*v)})
+	}
+}
+
+func (b *fieldsBuilder) int8(ID string, v *int8) {
+	if v != nil {
+		b.fields = append(b.fields, nmea.FieldValue{ID: ID, Value: int64(*v)})
+	}
+}
+
+func (b *fieldsBuilder) duration(ID string, v *time.Duration) {
+	if v != nil {
+		b.fields = append(b.fields, nmea.FieldValue{ID: ID, Value: *v})
+	}
+}
+
+// fieldsReader reads typed values from message fields and remembers first error so struct fields can be read without
+// checking error after every field.
+type fieldsReader struct {
+	fields nmea.FieldValues
+	err    error
+}
+
+func (r *fieldsReader) float64(ID string) *float64 {
+	if r.err != nil {
+		return nil
+	}
+	v, err := getFloat64(r.fields, ID)
+	r.err = err
+	return v
+}
+
+func (r *fieldsReader) uint8(ID string) *uint8 {
+	if r.err != nil {
+		return nil
+	}
+	v, err := getUint8(r.fields, ID)
+	r.err = err
+	return v
+}
+
+func (r *fieldsReader) uint16(ID string) *uint16 {
+	if r.err != nil {
+		return nil
+	}
+	v, err := getUint16(r.fields, ID)
+	r.err = err
+	return v
+}
+
+func (r *fieldsReader) int8(ID string) *int8 {
+	if r.err != nil {
+		return nil
+	}
+	v, err := getInt8(r.fields, ID)
+	r.err = err
+	return v
+}
+
+func (r *fieldsReader) duration(ID string) *time.Duration {
+	if r.err != nil {
+		return nil
+	}
+	v, err := getDuration(r.fields, ID)
+	r.err = err
+	return v
+}
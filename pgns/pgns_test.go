@@ -0,0 +1,68 @@
+package pgns
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFromMessage_errors(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        nmea.Message
+		expectError string
+	}{
+		{
+			name:        "nok, unsupported PGN",
+			when:        nmea.Message{Header: nmea.CanBusHeader{PGN: 60928}},
+			expectError: "pgns: unsupported PGN: 60928",
+		},
+		{
+			name: "nok, invalid value type",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 129025},
+				Fields: nmea.FieldValues{{ID: "latitude", Value: "58.38"}},
+			},
+			expectError: "pgns: field latitude has unsupported value type string",
+		},
+		{
+			name: "nok, value out of range",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 130306},
+				Fields: nmea.FieldValues{{ID: "sid", Value: uint64(256)}},
+			},
+			expectError: "pgns: field sid value 256 is out of range",
+		},
+		{
+			name: "nok, negative value for unsigned field",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267},
+				Fields: nmea.FieldValues{{ID: "sid", Value: int64(-1)}},
+			},
+			expectError: "pgns: field sid value -1 is out of range",
+		},
+		{
+			name: "nok, value out of range for signed field",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127489},
+				Fields: nmea.FieldValues{{ID: "engineTorque", Value: int64(128)}},
+			},
+			expectError: "pgns: field engineTorque value 128 is out of range",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := FromMessage(tc.when)
+
+			assert.Nil(t, result)
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestPositionRapidUpdate_FromMessage_wrongPGN(t *testing.T) {
+	p := PositionRapidUpdate{}
+	err := p.FromMessage(nmea.Message{Header: nmea.CanBusHeader{PGN: 129026}})
+
+	assert.EqualError(t, err, "pgns: message PGN 129026 can not be converted to PGN 129025 struct")
+}
@@ -0,0 +1,311 @@
+package pgns
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+)
+
+const (
+	// ManufacturerAirmar is NMEA2000 manufacturer code of Airmar
+	ManufacturerAirmar = uint16(135)
+	// ManufacturerMaretron is NMEA2000 manufacturer code of Maretron
+	ManufacturerMaretron = uint16(137)
+	// IndustryMarine is industry code of marine industry. Proprietary PGNs of marine devices have this industry code.
+	IndustryMarine = uint8(4)
+)
+
+const (
+	// PGNProprietaryAddressedFastPacket is PGN 126720 that manufacturers use for addressed proprietary commands
+	// (i.e. Airmar calibration commands)
+	PGNProprietaryAddressedFastPacket = uint32(126720)
+	// PGNMaretronTemperatureHighRange is PGN 130823 Maretron: Proprietary Temperature High Range
+	PGNMaretronTemperatureHighRange = uint32(130823)
+)
+
+const (
+	// AirmarIDAttitudeOffset is proprietary ID of Airmar: Attitude Offset command (PGN 126720)
+	AirmarIDAttitudeOffset = uint8(32)
+	// AirmarIDCalibrateDepth is proprietary ID of Airmar: Calibrate Depth command (PGN 126720)
+	AirmarIDCalibrateDepth = uint8(40)
+	// AirmarIDCalibrateTemperature is proprietary ID of Airmar: Calibrate Temperature command (PGN 126720)
+	AirmarIDCalibrateTemperature = uint8(42)
+)
+
+// ErrNotProprietary is returned when message PGN is not in proprietary PGN range or has no proprietary header
+var ErrNotProprietary = errors.New("pgns: message is not proprietary")
+
+// ErrManufacturerMismatch is returned when proprietary message is from different manufacturer than typed struct is for
+var ErrManufacturerMismatch = errors.New("pgns: proprietary message manufacturer does not match")
+
+// ProprietaryHeader is manufacturer and industry code that proprietary PGN data starts with (first 2 bytes: 11 bits
+// manufacturer code, 2 bits reserved and 3 bits industry code). Proprietary PGNs are shared by all manufacturers and
+// this header tells whose definition message data follows.
+type ProprietaryHeader struct {
+	ManufacturerCode uint16
+	IndustryCode     uint8
+}
+
+// Matches checks if header is from given manufacturer in marine industry
+func (h ProprietaryHeader) Matches(manufacturer uint16) bool {
+	return h.ManufacturerCode == manufacturer && h.IndustryCode == IndustryMarine
+}
+
+// IsProprietaryPGN checks if PGN is in one of the manufacturer proprietary PGN ranges (61184, 65280-65535, 126720,
+// 130816-131071)
+func IsProprietaryPGN(pgn uint32) bool {
+	return pgn == 61184 ||
+		(pgn >= 65280 && pgn <= 65535) ||
+		pgn == PGNProprietaryAddressedFastPacket ||
+		(pgn >= 130816 && pgn <= 131071)
+}
+
+// ParseProprietaryHeader reads proprietary header from raw message data. Useful to decide whose definition to decode
+// raw message with before decoding it.
+func ParseProprietaryHeader(raw nmea.RawMessage) (ProprietaryHeader, error) {
+	if !IsProprietaryPGN(raw.Header.PGN) || len(raw.Data) < 2 {
+		return ProprietaryHeader{}, ErrNotProprietary
+	}
+	return ProprietaryHeader{
+		ManufacturerCode: uint16(raw.Data[0]) | uint16(raw.Data[1]&0x07)<<8,
+		IndustryCode:     raw.Data[1] >> 5,
+	}, nil
+}
+
+// MessageProprietaryHeader reads proprietary header from decoded message `manufacturerCode` and `industryCode`
+// fields. Manufacturer code can be decoded as number or as nmea.EnumValue (MANUFACTURER_CODE lookup).
+func MessageProprietaryHeader(msg nmea.Message) (ProprietaryHeader, error) {
+	if !IsProprietaryPGN(msg.Header.PGN) {
+		return ProprietaryHeader{}, ErrNotProprietary
+	}
+	manufacturer, err := getUint(msg.Fields, "manufacturerCode", 0x7ff)
+	if err != nil {
+		return ProprietaryHeader{}, err
+	}
+	industry, err := getUint(msg.Fields, "industryCode", 0x07)
+	if err != nil {
+		return ProprietaryHeader{}, err
+	}
+	if manufacturer == nil || industry == nil {
+		return ProprietaryHeader{}, ErrNotProprietary
+	}
+	return ProprietaryHeader{ManufacturerCode: uint16(*manufacturer), IndustryCode: uint8(*industry)}, nil
+}
+
+func checkProprietary(msg nmea.Message, pgn uint32, manufacturer uint16) error {
+	if err := checkPGN(msg, pgn); err != nil {
+		return err
+	}
+	header, err := MessageProprietaryHeader(msg)
+	if err != nil {
+		return err
+	}
+	if !header.Matches(manufacturer) {
+		return fmt.Errorf("%w: manufacturer %v, industry %v", ErrManufacturerMismatch, header.ManufacturerCode, header.IndustryCode)
+	}
+	return nil
+}
+
+func checkAirmarCommand(msg nmea.Message, proprietaryID uint8) error {
+	if err := checkProprietary(msg, PGNProprietaryAddressedFastPacket, ManufacturerAirmar); err != nil {
+		return err
+	}
+	id, err := getUint8(msg.Fields, "proprietaryId")
+	if err != nil {
+		return err
+	}
+	if id == nil || *id != proprietaryID {
+		return fmt.Errorf("pgns: message is not Airmar command with proprietary ID %v", proprietaryID)
+	}
+	return nil
+}
+
+// proprietaryFields creates proprietary header fields that Canboat definitions match message definition with
+func proprietaryFields(manufacturer uint16) fieldsBuilder {
+	return fieldsBuilder{fields: nmea.FieldValues{
+		{ID: "manufacturerCode", Value: uint64(manufacturer)},
+		{ID: "industryCode", Value: uint64(IndustryMarine)},
+	}}
+}
+
+func airmarCommandFields(proprietaryID uint8) fieldsBuilder {
+	b := proprietaryFields(ManufacturerAirmar)
+	b.uint8("proprietaryId", &proprietaryID)
+	return b
+}
+
+// proprietaryFromMessage returns typed struct for proprietary message by message manufacturer (and proprietary ID)
+func proprietaryFromMessage(msg nmea.Message) (Typed, error) {
+	header, err := MessageProprietaryHeader(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedPGN, msg.Header.PGN)
+	}
+	switch {
+	case msg.Header.PGN == PGNMaretronTemperatureHighRange && header.Matches(ManufacturerMaretron):
+		return &MaretronTemperatureHighRange{}, nil
+	case msg.Header.PGN == PGNProprietaryAddressedFastPacket && header.Matches(ManufacturerAirmar):
+		id, _ := getUint8(msg.Fields, "proprietaryId")
+		if id == nil {
+			break
+		}
+		switch *id {
+		case AirmarIDAttitudeOffset:
+			return &AirmarAttitudeOffset{}, nil
+		case AirmarIDCalibrateDepth:
+			return &AirmarCalibrateDepth{}, nil
+		case AirmarIDCalibrateTemperature:
+			return &AirmarCalibrateTemperature{}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v (manufacturer %v)", ErrUnsupportedPGN, msg.Header.PGN, header.ManufacturerCode)
+}
+
+// AirmarAttitudeOffset is PGN 126720 Airmar: Attitude Offset command. Offsets are added to heading, pitch and roll
+// measured by Airmar sensor (i.e. when sensor is not mounted aligned with boat centerline).
+type AirmarAttitudeOffset struct {
+	// AzimuthOffset is heading offset in radians
+	AzimuthOffset *float64
+	// PitchOffset is pitch offset in radians
+	PitchOffset *float64
+	// RollOffset is roll offset in radians
+	RollOffset *float64
+}
+
+// PGN returns PGN number of AirmarAttitudeOffset
+func (p *AirmarAttitudeOffset) PGN() uint32 {
+	return PGNProprietaryAddressedFastPacket
+}
+
+// FromMessage fills AirmarAttitudeOffset fields from decoded message
+func (p *AirmarAttitudeOffset) FromMessage(msg nmea.Message) error {
+	if err := checkAirmarCommand(msg, AirmarIDAttitudeOffset); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = AirmarAttitudeOffset{
+		AzimuthOffset: r.float64("azimuthOffset"),
+		PitchOffset:   r.float64("pitchOffset"),
+		RollOffset:    r.float64("rollOffset"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with AirmarAttitudeOffset fields. Header destination should be Airmar sensor address.
+func (p *AirmarAttitudeOffset) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := airmarCommandFields(AirmarIDAttitudeOffset)
+	b.float64("azimuthOffset", p.AzimuthOffset)
+	b.float64("pitchOffset", p.PitchOffset)
+	b.float64("rollOffset", p.RollOffset)
+	return createMessage(header, PGNProprietaryAddressedFastPacket, b.fields)
+}
+
+// AirmarCalibrateDepth is PGN 126720 Airmar: Calibrate Depth command
+type AirmarCalibrateDepth struct {
+	// SpeedOfSound is speed of sound in water used for depth calculation in m/s (allowed range is 1350 to 1650 m/s)
+	SpeedOfSound *float64
+}
+
+// PGN returns PGN number of AirmarCalibrateDepth
+func (p *AirmarCalibrateDepth) PGN() uint32 {
+	return PGNProprietaryAddressedFastPacket
+}
+
+// FromMessage fills AirmarCalibrateDepth fields from decoded message
+func (p *AirmarCalibrateDepth) FromMessage(msg nmea.Message) error {
+	if err := checkAirmarCommand(msg, AirmarIDCalibrateDepth); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = AirmarCalibrateDepth{
+		SpeedOfSound: r.float64("speedOfSoundMode"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with AirmarCalibrateDepth fields. Header destination should be Airmar sensor address.
+func (p *AirmarCalibrateDepth) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := airmarCommandFields(AirmarIDCalibrateDepth)
+	b.float64("speedOfSoundMode", p.SpeedOfSound)
+	return createMessage(header, PGNProprietaryAddressedFastPacket, b.fields)
+}
+
+// AirmarCalibrateTemperature is PGN 126720 Airmar: Calibrate Temperature command
+type AirmarCalibrateTemperature struct {
+	// TemperatureInstance is AIRMAR_TEMPERATURE_INSTANCE lookup value (0 = Device Sensor, 1 = Onboard Water Sensor,
+	// 2 = Optional Water Sensor)
+	TemperatureInstance *uint8
+	// TemperatureOffset is offset added to measured temperature in Kelvins
+	TemperatureOffset *float64
+}
+
+// PGN returns PGN number of AirmarCalibrateTemperature
+func (p *AirmarCalibrateTemperature) PGN() uint32 {
+	return PGNProprietaryAddressedFastPacket
+}
+
+// FromMessage fills AirmarCalibrateTemperature fields from decoded message
+func (p *AirmarCalibrateTemperature) FromMessage(msg nmea.Message) error {
+	if err := checkAirmarCommand(msg, AirmarIDCalibrateTemperature); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = AirmarCalibrateTemperature{
+		TemperatureInstance: r.uint8("temperatureInstance"),
+		TemperatureOffset:   r.float64("temperatureOffset"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with AirmarCalibrateTemperature fields. Header destination should be Airmar sensor
+// address.
+func (p *AirmarCalibrateTemperature) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := airmarCommandFields(AirmarIDCalibrateTemperature)
+	b.uint8("temperatureInstance", p.TemperatureInstance)
+	b.float64("temperatureOffset", p.TemperatureOffset)
+	return createMessage(header, PGNProprietaryAddressedFastPacket, b.fields)
+}
+
+// MaretronTemperatureHighRange is PGN 130823 Maretron: Proprietary Temperature High Range. Maretron temperature
+// sensors (i.e. TMP100, EMS100 exhaust temperature) send temperatures above range of PGN 130312/130316 with this PGN.
+type MaretronTemperatureHighRange struct {
+	SID      *uint8
+	Instance *uint8
+	// Source is TEMPERATURE_SOURCE lookup value (i.e. 14 = Exhaust Gas Temperature)
+	Source *uint8
+	// ActualTemperature is measured temperature in Kelvins
+	ActualTemperature *float64
+	// SetTemperature is set (target) temperature in Kelvins
+	SetTemperature *float64
+}
+
+// PGN returns PGN number of MaretronTemperatureHighRange
+func (p *MaretronTemperatureHighRange) PGN() uint32 {
+	return PGNMaretronTemperatureHighRange
+}
+
+// FromMessage fills MaretronTemperatureHighRange fields from decoded message
+func (p *MaretronTemperatureHighRange) FromMessage(msg nmea.Message) error {
+	if err := checkProprietary(msg, PGNMaretronTemperatureHighRange, ManufacturerMaretron); err != nil {
+		return err
+	}
+	r := fieldsReader{fields: msg.Fields}
+	*p = MaretronTemperatureHighRange{
+		SID:               r.uint8("sid"),
+		Instance:          r.uint8("instance"),
+		Source:            r.uint8("source"),
+		ActualTemperature: r.float64("actualTemperature"),
+		SetTemperature:    r.float64("setTemperature"),
+	}
+	return r.err
+}
+
+// ToMessage creates message with MaretronTemperatureHighRange fields
+func (p *MaretronTemperatureHighRange) ToMessage(header nmea.CanBusHeader) nmea.Message {
+	b := proprietaryFields(ManufacturerMaretron)
+	b.uint8("sid", p.SID)
+	b.uint8("instance", p.Instance)
+	b.uint8("source", p.Source)
+	b.float64("actualTemperature", p.ActualTemperature)
+	b.float64("setTemperature", p.SetTemperature)
+	return createMessage(header, PGNMaretronTemperatureHighRange, b.fields)
+}
@@ -0,0 +1,190 @@
+package pgns
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProprietary_roundTrip(t *testing.T) {
+	header := nmea.CanBusHeader{Priority: 3, Source: 10, Destination: 35}
+
+	var testCases = []struct {
+		name         string
+		given        Typed
+		expectFields nmea.FieldValues
+	}{
+		{
+			name:  "ok, AirmarAttitudeOffset",
+			given: &AirmarAttitudeOffset{AzimuthOffset: float64Ptr(0.05), PitchOffset: float64Ptr(-0.01)},
+			expectFields: nmea.FieldValues{
+				{ID: "manufacturerCode", Value: uint64(135)},
+				{ID: "industryCode", Value: uint64(4)},
+				{ID: "proprietaryId", Value: uint64(32)},
+				{ID: "azimuthOffset", Value: 0.05},
+				{ID: "pitchOffset", Value: -0.01},
+			},
+		},
+		{
+			name:  "ok, AirmarCalibrateDepth",
+			given: &AirmarCalibrateDepth{SpeedOfSound: float64Ptr(1500)},
+			expectFields: nmea.FieldValues{
+				{ID: "manufacturerCode", Value: uint64(135)},
+				{ID: "industryCode", Value: uint64(4)},
+				{ID: "proprietaryId", Value: uint64(40)},
+				{ID: "speedOfSoundMode", Value: 1500.0},
+			},
+		},
+		{
+			name:  "ok, AirmarCalibrateTemperature",
+			given: &AirmarCalibrateTemperature{TemperatureInstance: uint8Ptr(1), TemperatureOffset: float64Ptr(-0.5)},
+			expectFields: nmea.FieldValues{
+				{ID: "manufacturerCode", Value: uint64(135)},
+				{ID: "industryCode", Value: uint64(4)},
+				{ID: "proprietaryId", Value: uint64(42)},
+				{ID: "temperatureInstance", Value: uint64(1)},
+				{ID: "temperatureOffset", Value: -0.5},
+			},
+		},
+		{
+			name:  "ok, MaretronTemperatureHighRange",
+			given: &MaretronTemperatureHighRange{Instance: uint8Ptr(0), Source: uint8Ptr(14), ActualTemperature: float64Ptr(623.15)},
+			expectFields: nmea.FieldValues{
+				{ID: "manufacturerCode", Value: uint64(137)},
+				{ID: "industryCode", Value: uint64(4)},
+				{ID: "instance", Value: uint64(0)},
+				{ID: "source", Value: uint64(14)},
+				{ID: "actualTemperature", Value: 623.15},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := tc.given.ToMessage(header)
+
+			assert.Equal(t, tc.given.PGN(), msg.Header.PGN)
+			assert.Equal(t, tc.expectFields, msg.Fields)
+
+			result, err := FromMessage(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.given, result)
+		})
+	}
+}
+
+func TestProprietary_FromMessage_errors(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        nmea.Message
+		whenTyped   Typed
+		expectError string
+	}{
+		{
+			name: "nok, unsupported manufacturer",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 126720},
+				Fields: nmea.FieldValues{
+					{ID: "manufacturerCode", Value: nmea.EnumValue{Value: 137, Code: "Maretron"}},
+					{ID: "industryCode", Value: uint64(4)},
+				},
+			},
+			expectError: "pgns: unsupported PGN: 126720 (manufacturer 137)",
+		},
+		{
+			name:        "nok, missing manufacturer code",
+			when:        nmea.Message{Header: nmea.CanBusHeader{PGN: 130823}},
+			expectError: "pgns: unsupported PGN: 130823",
+		},
+		{
+			name: "nok, typed struct manufacturer mismatch",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 130823},
+				Fields: nmea.FieldValues{
+					{ID: "manufacturerCode", Value: uint64(135)},
+					{ID: "industryCode", Value: uint64(4)},
+				},
+			},
+			whenTyped:   &MaretronTemperatureHighRange{},
+			expectError: "pgns: proprietary message manufacturer does not match: manufacturer 135, industry 4",
+		},
+		{
+			name: "nok, typed struct proprietary ID mismatch",
+			when: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 126720},
+				Fields: nmea.FieldValues{
+					{ID: "manufacturerCode", Value: uint64(135)},
+					{ID: "industryCode", Value: uint64(4)},
+					{ID: "proprietaryId", Value: uint64(40)},
+				},
+			},
+			whenTyped:   &AirmarAttitudeOffset{},
+			expectError: "pgns: message is not Airmar command with proprietary ID 32",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.whenTyped != nil {
+				err = tc.whenTyped.FromMessage(tc.when)
+			} else {
+				_, err = FromMessage(tc.when)
+			}
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestParseProprietaryHeader(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        nmea.RawMessage
+		expect      ProprietaryHeader
+		expectError string
+	}{
+		{
+			name: "ok, Airmar",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 126720},
+				Data:   []byte{0x87, 0x98, 0x20, 0x00},
+			},
+			expect: ProprietaryHeader{ManufacturerCode: 135, IndustryCode: 4},
+		},
+		{
+			name: "ok, Maretron",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 130823},
+				Data:   []byte{0x89, 0x98, 0x01},
+			},
+			expect: ProprietaryHeader{ManufacturerCode: 137, IndustryCode: 4},
+		},
+		{
+			name: "nok, not proprietary PGN",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 129025},
+				Data:   []byte{0x87, 0x98},
+			},
+			expectError: "pgns: message is not proprietary",
+		},
+		{
+			name: "nok, data too short",
+			when: nmea.RawMessage{
+				Header: nmea.CanBusHeader{PGN: 65280},
+				Data:   []byte{0x87},
+			},
+			expectError: "pgns: message is not proprietary",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseProprietaryHeader(tc.when)
+
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, result)
+			assert.True(t, result.Matches(tc.expect.ManufacturerCode))
+		})
+	}
+}
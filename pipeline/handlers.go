@@ -0,0 +1,316 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"math"
+	"sync"
+	"time"
+)
+
+// NewFilter creates stage that passes only raw messages that accept returns true for
+func NewFilter(accept func(raw nmea.RawMessage) bool) Handler {
+	return RawHandlerFunc(func(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+		return accept(raw), nil
+	})
+}
+
+// NewForwarder creates stage that writes every raw message that reaches it to given writer (i.e. other device)
+func NewForwarder(writer nmea.RawMessageWriter) Handler {
+	return RawHandlerFunc(func(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+		return true, writer.WriteRawMessage(ctx, raw)
+	})
+}
+
+// NewReplacementFilter creates stage that drops legacy PGNs when source also sends newer PGN that supersedes them (or
+// newer PGN when legacy is forced with config.ForceLegacy). See nmea.ReplacementFilter.
+func NewReplacementFilter(config nmea.ReplacementFilterConfig) Handler {
+	f := nmea.NewReplacementFilterWithConfig(config)
+	return NewFilter(func(raw nmea.RawMessage) bool {
+		return f.Accept(raw.Header, raw.Time)
+	})
+}
+
+type throttleKey struct {
+	pgn    uint32
+	source uint8
+	// value and text hold key field value. text is used for decoded values that are not numeric.
+	value uint64
+	text  string
+}
+
+// ThrottleKeyField describes field which value is included into throttle key so messages of multi-instance PGNs
+// (tanks, batteries etc.) are throttled per instance and not per PGN.
+type ThrottleKeyField struct {
+	// FieldID is ID of the field in decoded message (i.e. "instance", "sid")
+	FieldID string
+	// Fixed denotes that field has fixed location in message data and its value is extracted from raw data before
+	// decoding. When false, messages are throttled after decoding by value of the decoded field.
+	Fixed     bool
+	BitOffset uint16
+	BitLength uint16
+}
+
+// ThrottleKeyFields finds key fields for all PGNs in schema that have any of given field IDs. Field IDs are checked
+// in given order and first one PGN has is used. Field has fixed location when it is not in repeating field set and
+// there are no variable length fields before it. PGNs with multiple definitions (field sets) are given fixed location
+// only when all definitions agree on it.
+func ThrottleKeyFields(schema canboat.CanboatSchema, fieldIDs ...string) map[uint32]ThrottleKeyField {
+	result := map[uint32]ThrottleKeyField{}
+	for _, pgn := range schema.PGNs {
+		kf, ok := findThrottleKeyField(pgn, fieldIDs)
+		if !ok {
+			continue
+		}
+		existing, ok := result[pgn.PGN]
+		if !ok {
+			result[pgn.PGN] = kf
+			continue
+		}
+		if existing != kf {
+			existing.Fixed = false
+			existing.BitOffset = 0
+			existing.BitLength = 0
+			result[pgn.PGN] = existing
+		}
+	}
+	return result
+}
+
+func findThrottleKeyField(pgn canboat.PGN, fieldIDs []string) (ThrottleKeyField, bool) {
+	repeatingStart := int(pgn.RepeatingFieldSet1StartField)
+	if repeatingStart == 0 || (pgn.RepeatingFieldSet2StartField > 0 && int(pgn.RepeatingFieldSet2StartField) < repeatingStart) {
+		repeatingStart = int(pgn.RepeatingFieldSet2StartField)
+	}
+	for _, fieldID := range fieldIDs {
+		isVariableBefore := false
+		for i, f := range pgn.Fields {
+			isRepeating := repeatingStart > 0 && i+1 >= repeatingStart
+			if f.ID != fieldID {
+				isVariableBefore = isVariableBefore || f.BitLengthVariable || isRepeating
+				continue
+			}
+			kf := ThrottleKeyField{FieldID: fieldID}
+			if !isVariableBefore && !isRepeating && !f.BitLengthVariable && f.BitLength > 0 && f.BitLength <= 64 {
+				kf.Fixed = true
+				kf.BitOffset = f.BitOffset
+				kf.BitLength = f.BitLength
+			}
+			return kf, true
+		}
+	}
+	return ThrottleKeyField{}, false
+}
+
+// ThrottleConfig configures ThrottleFilter
+type ThrottleConfig struct {
+	// Window is time window in which at most one message per key is passed
+	Window time.Duration
+	// KeyFields maps PGN to field which value is included into throttle key (see ThrottleKeyFields). Optional.
+	KeyFields map[uint32]ThrottleKeyField
+}
+
+// ThrottleFilter is stage that passes at most one message per PGN and source (and key field value when configured)
+// within time window. Message time (RawMessage.Time) is used, so throttling works the same way for live data and
+// replayed files.
+//
+// Messages with fixed location key field are throttled before decoding. Messages with key field that can only be
+// found by decoding are throttled in HandleDecoded stage.
+//
+// ThrottleFilter is safe for concurrent use.
+type ThrottleFilter struct {
+	window    time.Duration
+	keyFields map[uint32]ThrottleKeyField
+
+	lock sync.Mutex
+	next map[throttleKey]time.Time
+}
+
+// NewThrottleFilter creates new instance of ThrottleFilter that throttles messages per PGN and source
+func NewThrottleFilter(window time.Duration) *ThrottleFilter {
+	return NewThrottleFilterWithConfig(ThrottleConfig{Window: window})
+}
+
+// NewThrottleFilterWithConfig creates new instance of ThrottleFilter with given config
+func NewThrottleFilterWithConfig(config ThrottleConfig) *ThrottleFilter {
+	return &ThrottleFilter{
+		window:    config.Window,
+		keyFields: config.KeyFields,
+		next:      map[throttleKey]time.Time{},
+	}
+}
+
+// Accept returns true when message is first of its key in current time window. Messages which key field can only be
+// found by decoding are always accepted (see AcceptDecoded).
+func (f *ThrottleFilter) Accept(raw nmea.RawMessage) bool {
+	key := throttleKey{pgn: raw.Header.PGN, source: raw.Header.Source}
+	if kf, ok := f.keyFields[raw.Header.PGN]; ok {
+		if !kf.Fixed {
+			return true
+		}
+		data := nmea.RawData(raw.Data)
+		// special values (no data etc.) and too short messages are throttled together under zero value
+		key.value, _ = data.DecodeVariableUint(uint32(kf.BitOffset), uint32(kf.BitLength))
+	}
+	return f.accept(key, raw.Time)
+}
+
+// AcceptDecoded returns true when decoded message is first of its key in current time window. Only messages which key
+// field can not be extracted from raw data are throttled here, all others are accepted.
+func (f *ThrottleFilter) AcceptDecoded(msg nmea.Message, raw nmea.RawMessage) bool {
+	kf, ok := f.keyFields[raw.Header.PGN]
+	if !ok || kf.Fixed {
+		return true
+	}
+	key := throttleKey{pgn: raw.Header.PGN, source: raw.Header.Source}
+	if fv, ok := msg.Fields.FindByID(kf.FieldID); ok {
+		if v, ok := fv.AsFloat64(); ok {
+			key.value = math.Float64bits(v)
+		} else {
+			key.text = fmt.Sprint(fv.Value)
+		}
+	}
+	return f.accept(key, raw.Time)
+}
+
+func (f *ThrottleFilter) accept(key throttleKey, now time.Time) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	next, ok := f.next[key]
+	if ok && !now.After(next) {
+		return false
+	}
+	f.next[key] = now.Add(f.window)
+	return true
+}
+
+// HandleRaw passes message when it is not throttled
+func (f *ThrottleFilter) HandleRaw(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+	return f.Accept(raw), nil
+}
+
+// HandleDecoded passes message when it is not throttled
+func (f *ThrottleFilter) HandleDecoded(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+	return f.AcceptDecoded(msg, raw), nil
+}
+
+// DefaultDeduplicationWindow is default time window in which identical messages are considered duplicates
+const DefaultDeduplicationWindow = 50 * time.Millisecond
+
+type dedupKey struct {
+	pgn         uint32
+	source      uint8
+	destination uint8
+	length      int
+	hash        uint64
+}
+
+// DeduplicatorConfig configures Deduplicator
+type DeduplicatorConfig struct {
+	// Window is time window in which message with same source, PGN and data is considered duplicate of already seen
+	// message. Window must be shorter than transmit interval of PGNs, otherwise legitimate repeated messages with
+	// unchanged data are dropped.
+	// Defaults to: DefaultDeduplicationWindow
+	Window time.Duration
+}
+
+// Deduplicator is stage that drops duplicate messages when same bus is read through redundant gateways (i.e. NGT-1
+// and SocketCAN on the same backbone). Message is duplicate when message with same source, destination, PGN and data
+// was seen within time window. Message time (RawMessage.Time) is used and messages from different gateways may arrive
+// out of order, so time difference in both directions is considered.
+//
+// Deduplicator is safe for concurrent use.
+type Deduplicator struct {
+	window time.Duration
+
+	lock      sync.Mutex
+	seen      map[dedupKey]time.Time
+	lastSweep time.Time
+	dropped   uint64
+}
+
+// NewDeduplicator creates new instance of Deduplicator
+func NewDeduplicator(config DeduplicatorConfig) *Deduplicator {
+	if config.Window <= 0 {
+		config.Window = DefaultDeduplicationWindow
+	}
+	return &Deduplicator{
+		window: config.Window,
+		seen:   map[dedupKey]time.Time{},
+	}
+}
+
+// Accept returns true when message is not duplicate of message seen within time window
+func (d *Deduplicator) Accept(raw nmea.RawMessage) bool {
+	key := dedupKey{
+		pgn:         raw.Header.PGN,
+		source:      raw.Header.Source,
+		destination: raw.Header.Destination,
+		length:      len(raw.Data),
+		hash:        fnv64a(raw.Data),
+	}
+	now := raw.Time
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.sweep(now)
+	if seen, ok := d.seen[key]; ok {
+		diff := now.Sub(seen)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= d.window {
+			d.dropped++
+			return false
+		}
+	}
+	d.seen[key] = now
+	return true
+}
+
+// sweep removes expired entries so memory use is bounded by number of unique messages in time window
+func (d *Deduplicator) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+	for k, seen := range d.seen {
+		if now.Sub(seen) > d.window {
+			delete(d.seen, k)
+		}
+	}
+}
+
+// Dropped returns number of messages dropped as duplicates
+func (d *Deduplicator) Dropped() uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.dropped
+}
+
+// HandleRaw passes message when it is not duplicate
+func (d *Deduplicator) HandleRaw(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+	return d.Accept(raw), nil
+}
+
+// HandleDecoded passes all messages as duplicates are dropped in HandleRaw stage
+func (d *Deduplicator) HandleDecoded(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+	return true, nil
+}
+
+// fnv64a calculates 64-bit FNV-1a hash of data
+func fnv64a(data []byte) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for _, b := range data {
+		h ^= uint64(b)
+		h *= prime64
+	}
+	return h
+}
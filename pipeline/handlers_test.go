@@ -0,0 +1,278 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testWriter struct {
+	written []nmea.RawMessage
+	err     error
+}
+
+func (w *testWriter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	w.written = append(w.written, msg)
+	return w.err
+}
+
+func (w *testWriter) Close() error {
+	return nil
+}
+
+func TestNewFilter(t *testing.T) {
+	f := NewFilter(func(raw nmea.RawMessage) bool {
+		return raw.Header.Source == 1
+	})
+
+	ok, err := f.HandleRaw(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{Source: 1}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = f.HandleRaw(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{Source: 2}})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNewForwarder(t *testing.T) {
+	w := &testWriter{}
+	f := NewForwarder(w)
+
+	ok, err := f.HandleRaw(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []nmea.RawMessage{{Header: nmea.CanBusHeader{PGN: 1}}}, w.written)
+
+	w.err = errors.New("write failure")
+	_, err = f.HandleRaw(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 2}})
+	assert.EqualError(t, err, "write failure")
+}
+
+func TestNewReplacementFilter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewReplacementFilter(nmea.ReplacementFilterConfig{})
+
+	handle := func(pgn uint32, offset time.Duration) bool {
+		ok, err := f.HandleRaw(context.Background(), nmea.RawMessage{
+			Time:   now.Add(offset),
+			Header: nmea.CanBusHeader{PGN: pgn, Source: 1},
+		})
+		assert.NoError(t, err)
+		return ok
+	}
+
+	assert.True(t, handle(130312, 0))
+	assert.True(t, handle(130316, 100*time.Millisecond))
+	assert.False(t, handle(130312, 1*time.Second))
+	assert.True(t, handle(127489, 1*time.Second))
+	assert.False(t, handle(65262, 2*time.Second))
+
+	ok, err := f.HandleDecoded(context.Background(), nmea.Message{}, nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 130312, Source: 1}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestThrottleFilter_Accept(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewThrottleFilter(1 * time.Second)
+
+	msg := func(pgn uint32, source uint8, offset time.Duration) nmea.RawMessage {
+		return nmea.RawMessage{Time: now.Add(offset), Header: nmea.CanBusHeader{PGN: pgn, Source: source}}
+	}
+
+	assert.True(t, f.Accept(msg(129025, 1, 0)))
+	assert.False(t, f.Accept(msg(129025, 1, 500*time.Millisecond)))
+	assert.True(t, f.Accept(msg(129025, 2, 500*time.Millisecond)))
+	assert.True(t, f.Accept(msg(129026, 1, 500*time.Millisecond)))
+	assert.False(t, f.Accept(msg(129025, 1, 1*time.Second)))
+	assert.True(t, f.Accept(msg(129025, 1, 1001*time.Millisecond)))
+}
+
+func TestThrottleFilter_Handle(t *testing.T) {
+	f := NewThrottleFilter(1 * time.Second)
+	raw := nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}}
+
+	ok, err := f.HandleRaw(context.Background(), raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = f.HandleRaw(context.Background(), raw)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = f.HandleDecoded(context.Background(), nmea.Message{}, raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestThrottleKeyFields(t *testing.T) {
+	schema := canboat.CanboatSchema{
+		PGNs: canboat.PGNs{
+			{
+				PGN: 127505, // fluid level
+				Fields: []canboat.Field{
+					{ID: "instance", BitOffset: 0, BitLength: 4},
+					{ID: "type", BitOffset: 4, BitLength: 4},
+				},
+			},
+			{
+				PGN: 127508, // battery status, has both instance and sid
+				Fields: []canboat.Field{
+					{ID: "instance", BitOffset: 0, BitLength: 8},
+					{ID: "voltage", BitOffset: 8, BitLength: 16},
+					{ID: "sid", BitOffset: 56, BitLength: 8},
+				},
+			},
+			{
+				PGN: 126464, // no key fields
+				Fields: []canboat.Field{
+					{ID: "functionCode", BitOffset: 0, BitLength: 8},
+				},
+			},
+			{
+				PGN: 129540, // key field after variable length field
+				Fields: []canboat.Field{
+					{ID: "name", BitLengthVariable: true},
+					{ID: "sid", BitOffset: 0, BitLength: 8},
+				},
+			},
+			{
+				PGN:                          130820, // key field in repeating field set
+				RepeatingFieldSet1StartField: 2,
+				RepeatingFieldSet1Size:       1,
+				Fields: []canboat.Field{
+					{ID: "count", BitOffset: 0, BitLength: 8},
+					{ID: "instance", BitOffset: 8, BitLength: 8},
+				},
+			},
+			{
+				PGN: 65000, // multiple definitions that disagree on location
+				Fields: []canboat.Field{
+					{ID: "instance", BitOffset: 0, BitLength: 8},
+				},
+			},
+			{
+				PGN: 65000,
+				Fields: []canboat.Field{
+					{ID: "type", BitOffset: 0, BitLength: 8},
+					{ID: "instance", BitOffset: 8, BitLength: 8},
+				},
+			},
+		},
+	}
+
+	result := ThrottleKeyFields(schema, "instance", "sid")
+
+	expect := map[uint32]ThrottleKeyField{
+		127505: {FieldID: "instance", Fixed: true, BitOffset: 0, BitLength: 4},
+		127508: {FieldID: "instance", Fixed: true, BitOffset: 0, BitLength: 8},
+		129540: {FieldID: "sid", Fixed: false},
+		130820: {FieldID: "instance", Fixed: false},
+		65000:  {FieldID: "instance", Fixed: false},
+	}
+	assert.Equal(t, expect, result)
+}
+
+func TestThrottleFilter_Accept_keyField(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewThrottleFilterWithConfig(ThrottleConfig{
+		Window: 1 * time.Second,
+		KeyFields: map[uint32]ThrottleKeyField{
+			127505: {FieldID: "instance", Fixed: true, BitOffset: 0, BitLength: 4},
+		},
+	})
+
+	msg := func(pgn uint32, data []byte, offset time.Duration) nmea.RawMessage {
+		return nmea.RawMessage{Time: now.Add(offset), Header: nmea.CanBusHeader{PGN: pgn, Source: 1}, Data: data}
+	}
+
+	// instance is lower nibble of first byte, type is upper nibble
+	assert.True(t, f.Accept(msg(127505, []byte{0x10}, 0)))
+	assert.True(t, f.Accept(msg(127505, []byte{0x11}, 100*time.Millisecond)))
+	assert.False(t, f.Accept(msg(127505, []byte{0x20}, 200*time.Millisecond)))
+	assert.False(t, f.Accept(msg(127505, []byte{0x01}, 300*time.Millisecond)))
+	assert.True(t, f.Accept(msg(127505, []byte{0x12}, 400*time.Millisecond)))
+
+	// PGN without key field is throttled by PGN and source
+	assert.True(t, f.Accept(msg(127508, []byte{0x00}, 0)))
+	assert.False(t, f.Accept(msg(127508, []byte{0x01}, 100*time.Millisecond)))
+}
+
+func TestThrottleFilter_HandleDecoded_keyField(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewThrottleFilterWithConfig(ThrottleConfig{
+		Window: 1 * time.Second,
+		KeyFields: map[uint32]ThrottleKeyField{
+			130820: {FieldID: "instance", Fixed: false},
+		},
+	})
+
+	handle := func(instance interface{}, offset time.Duration) bool {
+		raw := nmea.RawMessage{Time: now.Add(offset), Header: nmea.CanBusHeader{PGN: 130820, Source: 1}}
+		ok, err := f.HandleRaw(context.Background(), raw)
+		assert.NoError(t, err)
+		assert.True(t, ok) // key can not be extracted before decoding
+
+		msg := nmea.Message{Fields: nmea.FieldValues{{ID: "instance", Value: instance}}}
+		ok, err = f.HandleDecoded(context.Background(), msg, raw)
+		assert.NoError(t, err)
+		return ok
+	}
+
+	assert.True(t, handle(uint64(1), 0))
+	assert.True(t, handle(uint64(2), 100*time.Millisecond))
+	assert.False(t, handle(uint64(1), 200*time.Millisecond))
+	assert.True(t, handle("text", 300*time.Millisecond))
+	assert.False(t, handle("text", 400*time.Millisecond))
+}
+
+func TestDeduplicator_Accept(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDeduplicator(DeduplicatorConfig{Window: 50 * time.Millisecond})
+
+	msg := func(source uint8, offset time.Duration, data ...byte) nmea.RawMessage {
+		return nmea.RawMessage{Time: now.Add(offset), Header: nmea.CanBusHeader{PGN: 129025, Source: source}, Data: data}
+	}
+
+	assert.True(t, d.Accept(msg(1, 0, 0x01, 0x02)))
+	assert.False(t, d.Accept(msg(1, 2*time.Millisecond, 0x01, 0x02)))  // same message from other gateway
+	assert.False(t, d.Accept(msg(1, -3*time.Millisecond, 0x01, 0x02))) // other gateway message time is earlier
+	assert.True(t, d.Accept(msg(2, 2*time.Millisecond, 0x01, 0x02)))   // other source
+	assert.True(t, d.Accept(msg(1, 3*time.Millisecond, 0x01, 0x03)))   // other data
+	assert.True(t, d.Accept(msg(1, 100*time.Millisecond, 0x01, 0x02))) // next transmit with unchanged data
+	assert.Equal(t, uint64(2), d.Dropped())
+}
+
+func TestDeduplicator_Handle(t *testing.T) {
+	d := NewDeduplicator(DeduplicatorConfig{})
+	raw := nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}, Data: []byte{1}}
+
+	ok, err := d.HandleRaw(context.Background(), raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = d.HandleRaw(context.Background(), raw)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = d.HandleDecoded(context.Background(), nmea.Message{}, raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestDeduplicator_sweep(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDeduplicator(DeduplicatorConfig{Window: 50 * time.Millisecond})
+
+	for i := 0; i < 10; i++ {
+		d.Accept(nmea.RawMessage{Time: now, Header: nmea.CanBusHeader{PGN: uint32(i)}})
+	}
+	assert.Len(t, d.seen, 10)
+
+	d.Accept(nmea.RawMessage{Time: now.Add(time.Second), Header: nmea.CanBusHeader{PGN: 1}})
+	assert.Len(t, d.seen, 1)
+}
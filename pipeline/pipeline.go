@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+)
+
+// Handler is single processing stage of Pipeline. For every message Pipeline calls HandleRaw of all handlers in
+// registration order, then decodes message and calls HandleDecoded of all handlers in registration order.
+//
+// Returning false from either method stops processing of that message in following stages (i.e. filter or throttle).
+// Returned error stops processing of that message and is given to Config.OnError.
+type Handler interface {
+	HandleRaw(ctx context.Context, raw nmea.RawMessage) (bool, error)
+	HandleDecoded(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error)
+}
+
+// RawHandlerFunc is Handler that processes only raw messages. Decoded messages are passed through.
+type RawHandlerFunc func(ctx context.Context, raw nmea.RawMessage) (bool, error)
+
+// HandleRaw calls f(ctx, raw)
+func (f RawHandlerFunc) HandleRaw(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+	return f(ctx, raw)
+}
+
+// HandleDecoded passes message through
+func (f RawHandlerFunc) HandleDecoded(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+	return true, nil
+}
+
+// DecodedHandlerFunc is Handler that processes only decoded messages. Raw messages are passed through.
+type DecodedHandlerFunc func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error)
+
+// HandleRaw passes message through
+func (f DecodedHandlerFunc) HandleRaw(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+	return true, nil
+}
+
+// HandleDecoded calls f(ctx, msg, raw)
+func (f DecodedHandlerFunc) HandleDecoded(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+	return f(ctx, msg, raw)
+}
+
+// Config configures Pipeline
+type Config struct {
+	// Decoder decodes raw messages for HandleDecoded stages. Optional: when nil only HandleRaw stages are called.
+	Decoder nmea.MessageDecoder
+	// Handlers are processing stages in order of execution
+	Handlers []Handler
+
+	// OnDecodeError is called when message fails to decode (i.e. unknown PGN). Returned error is given to OnError.
+	// When not set decode errors are given to OnError.
+	OnDecodeError func(ctx context.Context, raw nmea.RawMessage, err error) error
+	// OnError is called when stage or decoder returns error. Returned error is returned from Process (and stops Run).
+	// When not set errors are returned from Process as is.
+	OnError func(ctx context.Context, raw nmea.RawMessage, err error) error
+}
+
+// Pipeline is processing chain of raw and decoded messages. It replaces single hardcoded read loop with list of
+// stages (filter, throttle, decode, CSV, forward) that can be composed in Go code.
+//
+// Pipeline is not safe for concurrent use unless all handlers are.
+type Pipeline struct {
+	decoder       nmea.MessageDecoder
+	handlers      []Handler
+	onDecodeError func(ctx context.Context, raw nmea.RawMessage, err error) error
+	onError       func(ctx context.Context, raw nmea.RawMessage, err error) error
+}
+
+// New creates new instance of Pipeline
+func New(config Config) *Pipeline {
+	return &Pipeline{
+		decoder:       config.Decoder,
+		handlers:      append([]Handler{}, config.Handlers...),
+		onDecodeError: config.OnDecodeError,
+		onError:       config.OnError,
+	}
+}
+
+// Use adds handlers to the end of the pipeline
+func (p *Pipeline) Use(handlers ...Handler) {
+	p.handlers = append(p.handlers, handlers...)
+}
+
+// Process runs message through all stages
+func (p *Pipeline) Process(ctx context.Context, raw nmea.RawMessage) error {
+	for i, h := range p.handlers {
+		ok, err := h.HandleRaw(ctx, raw)
+		if err != nil {
+			return p.handleError(ctx, raw, fmt.Errorf("pipeline stage %v failed to handle raw message: %w", i, err))
+		}
+		if !ok {
+			return nil
+		}
+	}
+	if p.decoder == nil {
+		return nil
+	}
+
+	msg, err := p.decoder.Decode(raw)
+	if err != nil {
+		if p.onDecodeError != nil {
+			err = p.onDecodeError(ctx, raw, err)
+		}
+		if err != nil {
+			return p.handleError(ctx, raw, err)
+		}
+		return nil
+	}
+
+	for i, h := range p.handlers {
+		ok, err := h.HandleDecoded(ctx, msg, raw)
+		if err != nil {
+			return p.handleError(ctx, raw, fmt.Errorf("pipeline stage %v failed to handle decoded message: %w", i, err))
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) handleError(ctx context.Context, raw nmea.RawMessage, err error) error {
+	if p.onError != nil {
+		return p.onError(ctx, raw, err)
+	}
+	return err
+}
+
+// Run reads messages from reader and processes them until reader returns io.EOF (nil is returned), read fails or
+// Process returns an error.
+func (p *Pipeline) Run(ctx context.Context, reader nmea.RawMessageReader) error {
+	for {
+		raw, err := reader.ReadRawMessage(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := p.Process(ctx, raw); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+type decoderFunc func(raw nmea.RawMessage) (nmea.Message, error)
+
+func (f decoderFunc) Decode(raw nmea.RawMessage) (nmea.Message, error) {
+	return f(raw)
+}
+
+var testDecoder = decoderFunc(func(raw nmea.RawMessage) (nmea.Message, error) {
+	if raw.Header.PGN == 0 {
+		return nmea.Message{}, errors.New("unknown pgn")
+	}
+	return nmea.Message{Header: raw.Header}, nil
+})
+
+type sliceReader struct {
+	messages []nmea.RawMessage
+	err      error
+}
+
+func (r *sliceReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if len(r.messages) == 0 {
+		return nmea.RawMessage{}, r.err
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	return msg, nil
+}
+
+func (r *sliceReader) Initialize() error {
+	return nil
+}
+
+func (r *sliceReader) Close() error {
+	return nil
+}
+
+func TestPipeline_Process(t *testing.T) {
+	calls := make([]string, 0)
+	p := New(Config{
+		Decoder: testDecoder,
+		Handlers: []Handler{
+			RawHandlerFunc(func(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+				calls = append(calls, "raw1")
+				return true, nil
+			}),
+			DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+				calls = append(calls, "decoded2")
+				assert.Equal(t, raw.Header, msg.Header)
+				return true, nil
+			}),
+		},
+	})
+	p.Use(RawHandlerFunc(func(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+		calls = append(calls, "raw3")
+		return true, nil
+	}))
+
+	err := p.Process(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"raw1", "raw3", "decoded2"}, calls)
+}
+
+func TestPipeline_Process_stopsOnFalse(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		stopRaw     bool
+		expectCalls []string
+	}{
+		{
+			name:        "ok, raw stage stops processing before decoding",
+			stopRaw:     true,
+			expectCalls: []string{"raw"},
+		},
+		{
+			name:        "ok, decoded stage stops following decoded stages",
+			stopRaw:     false,
+			expectCalls: []string{"raw", "decode", "decoded"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := make([]string, 0)
+			p := New(Config{
+				Decoder: decoderFunc(func(raw nmea.RawMessage) (nmea.Message, error) {
+					calls = append(calls, "decode")
+					return nmea.Message{}, nil
+				}),
+				Handlers: []Handler{
+					RawHandlerFunc(func(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+						calls = append(calls, "raw")
+						return !tc.stopRaw, nil
+					}),
+					DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+						calls = append(calls, "decoded")
+						return false, nil
+					}),
+					DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+						calls = append(calls, "never")
+						return true, nil
+					}),
+				},
+			})
+
+			err := p.Process(context.Background(), nmea.RawMessage{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectCalls, calls)
+		})
+	}
+}
+
+func TestPipeline_Process_decodeError(t *testing.T) {
+	var decodeErr error
+	decodedCalled := false
+	p := New(Config{
+		Decoder: testDecoder,
+		Handlers: []Handler{
+			DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+				decodedCalled = true
+				return true, nil
+			}),
+		},
+		OnDecodeError: func(ctx context.Context, raw nmea.RawMessage, err error) error {
+			decodeErr = err
+			return nil
+		},
+	})
+
+	err := p.Process(context.Background(), nmea.RawMessage{})
+
+	assert.NoError(t, err)
+	assert.EqualError(t, decodeErr, "unknown pgn")
+	assert.False(t, decodedCalled)
+}
+
+func TestPipeline_Process_decodeErrorWithoutCallback(t *testing.T) {
+	p := New(Config{Decoder: testDecoder})
+
+	err := p.Process(context.Background(), nmea.RawMessage{})
+
+	assert.EqualError(t, err, "unknown pgn")
+}
+
+func TestPipeline_Process_stageError(t *testing.T) {
+	stageErr := errors.New("stage failure")
+	var onErrorErr error
+	p := New(Config{
+		Decoder: testDecoder,
+		Handlers: []Handler{
+			RawHandlerFunc(func(ctx context.Context, raw nmea.RawMessage) (bool, error) {
+				return true, nil
+			}),
+			DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+				return true, stageErr
+			}),
+		},
+		OnError: func(ctx context.Context, raw nmea.RawMessage, err error) error {
+			onErrorErr = err
+			return nil
+		},
+	})
+
+	err := p.Process(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}})
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, onErrorErr, stageErr)
+	assert.EqualError(t, onErrorErr, "pipeline stage 1 failed to handle decoded message: stage failure")
+}
+
+func TestPipeline_Process_withoutDecoder(t *testing.T) {
+	decodedCalled := false
+	p := New(Config{
+		Handlers: []Handler{
+			DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+				decodedCalled = true
+				return true, nil
+			}),
+		},
+	})
+
+	err := p.Process(context.Background(), nmea.RawMessage{Header: nmea.CanBusHeader{PGN: 1}})
+
+	assert.NoError(t, err)
+	assert.False(t, decodedCalled)
+}
+
+func TestPipeline_Run(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		readErr     error
+		expectErr   string
+		expectCount int
+	}{
+		{
+			name:        "ok, EOF ends processing without error",
+			readErr:     io.EOF,
+			expectCount: 2,
+		},
+		{
+			name:        "nok, read error is returned",
+			readErr:     errors.New("read failure"),
+			expectErr:   "read failure",
+			expectCount: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count := 0
+			p := New(Config{
+				Decoder: testDecoder,
+				Handlers: []Handler{
+					DecodedHandlerFunc(func(ctx context.Context, msg nmea.Message, raw nmea.RawMessage) (bool, error) {
+						count++
+						return true, nil
+					}),
+				},
+			})
+			reader := &sliceReader{
+				messages: []nmea.RawMessage{
+					{Header: nmea.CanBusHeader{PGN: 1}},
+					{Header: nmea.CanBusHeader{PGN: 2}},
+				},
+				err: tc.readErr,
+			}
+
+			err := p.Run(context.Background(), reader)
+
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectCount, count)
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConnectionState is state of ReconnectingDevice connection
+type ConnectionState uint8
+
+const (
+	// ConnectionStateDisconnected means that there is no open connection. Next read or write opens new connection.
+	ConnectionStateDisconnected ConnectionState = iota
+	// ConnectionStateConnecting means that connection is being opened (possibly waiting for backoff between attempts)
+	ConnectionStateConnecting
+	// ConnectionStateConnected means that connection is open and device is initialized
+	ConnectionStateConnected
+	// ConnectionStateClosed means that ReconnectingDevice has been closed and will not connect again
+	ConnectionStateClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionStateDisconnected:
+		return "disconnected"
+	case ConnectionStateConnecting:
+		return "connecting"
+	case ConnectionStateConnected:
+		return "connected"
+	case ConnectionStateClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(s))
+	}
+}
+
+// ErrReconnectAttemptsExceeded is returned when connection could not be opened in ReconnectingDeviceConfig.MaxAttempts
+// attempts.
+var ErrReconnectAttemptsExceeded = errors.New("reconnect attempts exceeded")
+
+// ReconnectingDeviceConfig configures ReconnectingDevice
+type ReconnectingDeviceConfig struct {
+	// Open opens connection (serial port, TCP connection, socketcan interface) and creates device on top of it. Device
+	// is initialized by ReconnectingDevice after Open returns. Required.
+	Open func(ctx context.Context) (RawMessageReaderWriter, error)
+
+	// InitialBackoff is time waited before first reconnect attempt. Following attempts double wait time until
+	// MaxBackoff is reached. Defaults to 500ms.
+	InitialBackoff time.Duration
+	// MaxBackoff is maximum time waited between reconnect attempts. Defaults to 30s.
+	MaxBackoff time.Duration
+	// MaxAttempts is number of failed consecutive connection attempts after which ErrReconnectAttemptsExceeded is
+	// returned. Zero means that connecting is retried until context is cancelled or device is closed.
+	MaxAttempts int
+
+	// ShouldReconnect decides if connection is considered broken after read or write error. Errors that are not
+	// considered fatal are returned to caller and connection is kept. Optional: by default all errors are considered
+	// fatal.
+	ShouldReconnect func(err error) bool
+
+	// OnStateChange is called when connection state changes and for every failed connection attempt. Err is read/write
+	// error that broke connection (disconnected state) or open/initialize error of failed attempt (connecting state).
+	// Err is nil for other changes.
+	OnStateChange func(state ConnectionState, err error)
+}
+
+// ReconnectingDevice wraps device which connection can break (serial adapter unplugged, TCP gateway rebooted) and
+// transparently opens new connection with exponential backoff when reads or writes fail. New connection is
+// initialized before it is used. Messages that were in transfer when connection broke are lost.
+//
+// ReconnectingDevice is safe for concurrent reads and writes.
+type ReconnectingDevice struct {
+	config ReconnectingDeviceConfig
+
+	mu      sync.Mutex
+	device  RawMessageReaderWriter
+	state   ConnectionState
+	closing chan struct{}
+
+	// connecting is semaphore that ensures that only one goroutine is opening connection at the time. Channel is used
+	// instead of mutex so goroutines waiting for other goroutine to connect can give up when their context is cancelled.
+	connecting chan struct{}
+}
+
+// NewReconnectingDevice creates new instance of ReconnectingDevice
+func NewReconnectingDevice(config ReconnectingDeviceConfig) *ReconnectingDevice {
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = config.InitialBackoff
+	}
+	return &ReconnectingDevice{
+		config:     config,
+		state:      ConnectionStateDisconnected,
+		closing:    make(chan struct{}),
+		connecting: make(chan struct{}, 1),
+	}
+}
+
+// Initialize opens and initializes connection. Single attempt is made and its error is returned. Reads and writes
+// open connection (with backoff) when Initialize failed so application may choose to ignore Initialize error when
+// device is not yet available at startup.
+func (d *ReconnectingDevice) Initialize() error {
+	if err := d.lockConnecting(context.Background()); err != nil {
+		return err
+	}
+	defer d.unlockConnecting()
+
+	if dev, state := d.current(); state == ConnectionStateClosed {
+		return ErrDeviceClosed
+	} else if dev != nil {
+		return nil
+	}
+	_, err := d.open(context.Background())
+	if err != nil {
+		d.setState(ConnectionStateDisconnected)
+	}
+	return err
+}
+
+// State returns current connection state
+func (d *ReconnectingDevice) State() ConnectionState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state
+}
+
+// ReadRawMessage reads message from current connection. When read fails connection is closed and new connection is
+// opened until read succeeds, context is cancelled or device is closed.
+func (d *ReconnectingDevice) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	for {
+		dev, err := d.connected(ctx)
+		if err != nil {
+			return RawMessage{}, err
+		}
+		msg, err := dev.ReadRawMessage(ctx)
+		if err == nil {
+			return msg, nil
+		}
+		if !d.handleError(ctx, dev, err) {
+			return RawMessage{}, err
+		}
+	}
+}
+
+// WriteRawMessage writes message to current connection, opening connection first when there is none. When write
+// fails connection is closed (next read or write opens new one) and error is returned. Failed writes are not retried
+// as device may have sent message partially.
+func (d *ReconnectingDevice) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	dev, err := d.connected(ctx)
+	if err != nil {
+		return err
+	}
+	if err := dev.WriteRawMessage(ctx, msg); err != nil {
+		d.handleError(ctx, dev, err)
+		return err
+	}
+	return nil
+}
+
+// Close closes current connection. Reads and writes (including ones waiting for reconnect) return ErrDeviceClosed.
+func (d *ReconnectingDevice) Close() error {
+	d.mu.Lock()
+	if d.state == ConnectionStateClosed {
+		d.mu.Unlock()
+		return nil
+	}
+	dev := d.device
+	d.device = nil
+	d.state = ConnectionStateClosed
+	close(d.closing)
+	d.mu.Unlock()
+
+	if d.config.OnStateChange != nil {
+		d.config.OnStateChange(ConnectionStateClosed, nil)
+	}
+	if dev != nil {
+		return dev.Close()
+	}
+	return nil
+}
+
+// handleError closes broken connection. Returns true when caller should retry with new connection.
+func (d *ReconnectingDevice) handleError(ctx context.Context, dev RawMessageReaderWriter, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if d.config.ShouldReconnect != nil && !d.config.ShouldReconnect(err) {
+		return false
+	}
+	d.mu.Lock()
+	if d.device != dev { // already replaced by other goroutine or device was closed
+		closed := d.state == ConnectionStateClosed
+		d.mu.Unlock()
+		return !closed
+	}
+	d.device = nil
+	d.state = ConnectionStateDisconnected
+	d.mu.Unlock()
+
+	_ = dev.Close()
+	d.notify(ConnectionStateDisconnected, err)
+	return true
+}
+
+func (d *ReconnectingDevice) lockConnecting(ctx context.Context) error {
+	select {
+	case d.connecting <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.closing:
+		return ErrDeviceClosed
+	}
+}
+
+func (d *ReconnectingDevice) unlockConnecting() {
+	<-d.connecting
+}
+
+func (d *ReconnectingDevice) current() (RawMessageReaderWriter, ConnectionState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.device, d.state
+}
+
+// connected returns current connection or opens new connection with backoff
+func (d *ReconnectingDevice) connected(ctx context.Context) (RawMessageReaderWriter, error) {
+	if dev, state := d.current(); state == ConnectionStateClosed {
+		return nil, ErrDeviceClosed
+	} else if dev != nil {
+		return dev, nil
+	}
+
+	if err := d.lockConnecting(ctx); err != nil {
+		return nil, err
+	}
+	defer d.unlockConnecting()
+
+	backoff := d.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		// connection could have been opened by other goroutine while we were waiting for lock or backoff
+		if dev, state := d.current(); state == ConnectionStateClosed {
+			return nil, ErrDeviceClosed
+		} else if dev != nil {
+			return dev, nil
+		}
+
+		dev, err := d.open(ctx)
+		if err == nil {
+			return dev, nil
+		}
+		if errors.Is(err, ErrDeviceClosed) || ctx.Err() != nil {
+			d.setState(ConnectionStateDisconnected)
+			return nil, err
+		}
+		if d.config.MaxAttempts > 0 && attempt >= d.config.MaxAttempts {
+			d.setState(ConnectionStateDisconnected)
+			return nil, fmt.Errorf("%w: %v", ErrReconnectAttemptsExceeded, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.setState(ConnectionStateDisconnected)
+			return nil, ctx.Err()
+		case <-d.closing:
+			timer.Stop()
+			return nil, ErrDeviceClosed
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > d.config.MaxBackoff {
+			backoff = d.config.MaxBackoff
+		}
+	}
+}
+
+// open makes single attempt to open and initialize connection
+func (d *ReconnectingDevice) open(ctx context.Context) (RawMessageReaderWriter, error) {
+	d.setState(ConnectionStateConnecting)
+
+	dev, err := d.config.Open(ctx)
+	if err != nil {
+		d.notify(ConnectionStateConnecting, err)
+		return nil, err
+	}
+	if err := dev.Initialize(); err != nil {
+		_ = dev.Close()
+		d.notify(ConnectionStateConnecting, err)
+		return nil, err
+	}
+
+	d.mu.Lock()
+	if d.state == ConnectionStateClosed {
+		d.mu.Unlock()
+		_ = dev.Close()
+		return nil, ErrDeviceClosed
+	}
+	d.device = dev
+	d.state = ConnectionStateConnected
+	d.mu.Unlock()
+
+	d.notify(ConnectionStateConnected, nil)
+	return dev, nil
+}
+
+// setState changes state (unless device is closed) and notifies when state actually changed
+func (d *ReconnectingDevice) setState(state ConnectionState) {
+	d.mu.Lock()
+	if d.state == ConnectionStateClosed || d.state == state {
+		d.mu.Unlock()
+		return
+	}
+	d.state = state
+	d.mu.Unlock()
+
+	d.notify(state, nil)
+}
+
+func (d *ReconnectingDevice) notify(state ConnectionState, err error) {
+	if d.config.OnStateChange != nil {
+		d.config.OnStateChange(state, err)
+	}
+}
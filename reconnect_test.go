@@ -0,0 +1,203 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type flakyDevice struct {
+	reads       []error // nil means successful read
+	writeErr    error
+	initErr     error
+	initialized bool
+	closed      bool
+}
+
+func (d *flakyDevice) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	if len(d.reads) == 0 {
+		return RawMessage{}, io.EOF
+	}
+	err := d.reads[0]
+	d.reads = d.reads[1:]
+	if err != nil {
+		return RawMessage{}, err
+	}
+	return RawMessage{Header: CanBusHeader{PGN: 127250}}, nil
+}
+
+func (d *flakyDevice) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	return d.writeErr
+}
+
+func (d *flakyDevice) Initialize() error {
+	d.initialized = true
+	return d.initErr
+}
+
+func (d *flakyDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []ConnectionState
+}
+
+func (r *stateRecorder) OnStateChange(state ConnectionState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+}
+
+func TestReconnectingDevice_ReadRawMessage_reconnects(t *testing.T) {
+	devices := []*flakyDevice{
+		{reads: []error{nil, io.EOF}},
+		{reads: []error{nil}},
+	}
+	opened := 0
+	recorder := &stateRecorder{}
+	device := NewReconnectingDevice(ReconnectingDeviceConfig{
+		Open: func(ctx context.Context) (RawMessageReaderWriter, error) {
+			if opened == 1 { // first reconnect attempt fails
+				opened++
+				return nil, errors.New("connection refused")
+			}
+			dev := devices[opened/2]
+			opened++
+			return dev, nil
+		},
+		InitialBackoff: time.Millisecond,
+		OnStateChange:  recorder.OnStateChange,
+	})
+	assert.NoError(t, device.Initialize())
+	assert.Equal(t, ConnectionStateConnected, device.State())
+
+	for i := 0; i < 2; i++ {
+		msg, err := device.ReadRawMessage(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(127250), msg.Header.PGN)
+	}
+
+	assert.Equal(t, 3, opened)
+	assert.True(t, devices[0].closed)
+	assert.True(t, devices[1].initialized)
+	assert.Equal(t, []ConnectionState{
+		ConnectionStateConnecting,
+		ConnectionStateConnected,
+		ConnectionStateDisconnected, // read failed with EOF
+		ConnectionStateConnecting,
+		ConnectionStateConnecting, // failed attempt
+		ConnectionStateConnected,
+	}, recorder.states)
+
+	assert.NoError(t, device.Close())
+	assert.Equal(t, ConnectionStateClosed, device.State())
+	assert.True(t, devices[1].closed)
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, ErrDeviceClosed)
+}
+
+func TestReconnectingDevice_maxAttempts(t *testing.T) {
+	attempts := 0
+	device := NewReconnectingDevice(ReconnectingDeviceConfig{
+		Open: func(ctx context.Context) (RawMessageReaderWriter, error) {
+			attempts++
+			return &flakyDevice{initErr: errors.New("no response")}, nil
+		},
+		InitialBackoff: time.Millisecond,
+		MaxAttempts:    3,
+	})
+
+	err := device.WriteRawMessage(context.Background(), RawMessage{})
+
+	assert.EqualError(t, err, "reconnect attempts exceeded: no response")
+	assert.ErrorIs(t, err, ErrReconnectAttemptsExceeded)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, ConnectionStateDisconnected, device.State())
+}
+
+func TestReconnectingDevice_WriteRawMessage_errorDisconnects(t *testing.T) {
+	dev := &flakyDevice{writeErr: errors.New("broken pipe")}
+	device := NewReconnectingDevice(ReconnectingDeviceConfig{
+		Open: func(ctx context.Context) (RawMessageReaderWriter, error) {
+			return dev, nil
+		},
+	})
+
+	err := device.WriteRawMessage(context.Background(), RawMessage{})
+
+	assert.EqualError(t, err, "broken pipe")
+	assert.True(t, dev.closed)
+	assert.Equal(t, ConnectionStateDisconnected, device.State())
+}
+
+func TestReconnectingDevice_shouldReconnect(t *testing.T) {
+	dev := &flakyDevice{reads: []error{errors.New("crc error")}}
+	device := NewReconnectingDevice(ReconnectingDeviceConfig{
+		Open: func(ctx context.Context) (RawMessageReaderWriter, error) {
+			return dev, nil
+		},
+		ShouldReconnect: func(err error) bool {
+			return errors.Is(err, io.EOF)
+		},
+	})
+
+	_, err := device.ReadRawMessage(context.Background())
+
+	assert.EqualError(t, err, "crc error")
+	assert.False(t, dev.closed)
+	assert.Equal(t, ConnectionStateConnected, device.State())
+}
+
+func TestReconnectingDevice_contextCancelledDuringBackoff(t *testing.T) {
+	device := NewReconnectingDevice(ReconnectingDeviceConfig{
+		Open: func(ctx context.Context) (RawMessageReaderWriter, error) {
+			return nil, errors.New("connection refused")
+		},
+		InitialBackoff: time.Hour,
+	})
+	assert.EqualError(t, device.Initialize(), "connection refused")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := device.ReadRawMessage(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ConnectionStateDisconnected, device.State())
+}
+
+func TestReconnectingDevice_CloseDuringBackoff(t *testing.T) {
+	device := NewReconnectingDevice(ReconnectingDeviceConfig{
+		Open: func(ctx context.Context) (RawMessageReaderWriter, error) {
+			return nil, errors.New("connection refused")
+		},
+		InitialBackoff: time.Hour,
+	})
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := device.ReadRawMessage(context.Background())
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, device.Close())
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, ErrDeviceClosed)
+	case <-time.After(time.Second):
+		t.Fatal("read was not unblocked by Close")
+	}
+}
+
+func TestConnectionState_String(t *testing.T) {
+	assert.Equal(t, "connected", ConnectionStateConnected.String())
+	assert.Equal(t, "unknown(9)", ConnectionState(9).String())
+}
@@ -0,0 +1,120 @@
+package nmea
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultPGNReplacements returns mapping of legacy PGNs to newer PGNs that supersede them. Devices often send both
+// (legacy for older displays and newer for everyone else) so consumers that aggregate data end up with duplicate
+// values from same source. Returned map is a copy and can be modified by caller.
+//
+// NMEA2000 has deprecated PGN 130312 (Temperature) in favour of 130316 (Temperature Extended Range) which has better
+// resolution and range. Engine gateways (J1939 to NMEA2000) often pass through SAE J1939 engine PGNs in addition to
+// PGN 127489 (Engine Parameters, Dynamic) that contains same values (temperatures, pressures, alternator potential and
+// fuel rate) combined into single message.
+func DefaultPGNReplacements() map[uint32]uint32 {
+	return map[uint32]uint32{
+		uint32(PGNTemperature):                    uint32(PGNTemperatureExtendedRange),
+		uint32(PGNJ1939EngineTemperature1):        uint32(PGNEngineParametersDynamic),
+		uint32(PGNJ1939EngineFluidLevelPressure1): uint32(PGNEngineParametersDynamic),
+		uint32(PGNJ1939FuelEconomy):               uint32(PGNEngineParametersDynamic),
+		uint32(PGNJ1939VehicleElectricalPower1):   uint32(PGNEngineParametersDynamic),
+	}
+}
+
+// ReplacementFilterConfig configures how ReplacementFilter chooses between legacy and newer PGNs
+type ReplacementFilterConfig struct {
+	// Replacements maps legacy PGN to newer PGN that supersedes it. Multiple legacy PGNs can be superseded by same
+	// newer PGN.
+	// Defaults to: DefaultPGNReplacements()
+	Replacements map[uint32]uint32
+
+	// ForceLegacy is list of legacy PGNs that are preferred over their replacements. Use this when consumer only
+	// understands legacy PGN and newer PGN should be dropped when source sends both.
+	ForceLegacy []uint32
+
+	// StaleAfter is duration after which preferred PGN is considered to be not sent anymore by the source and the other
+	// PGN is let through again.
+	// Defaults to: 10 seconds
+	StaleAfter time.Duration
+}
+
+// ReplacementFilter decides per source which one of the legacy/newer PGN pair is let through. When source sends both
+// PGNs only preferred (by default the newer) PGN is accepted. When source sends only one of them it is always accepted.
+//
+// Filter is meant to be applied once on message stream before any consumer that keeps latest values or statistics
+// (i.e. as pipeline.NewReplacementFilter stage before output, telemetry.Aggregator, alert rules or Signal K
+// conversion). telemetry.Aggregator can also be given filter with telemetry.Config.ReplacementFilter when messages are
+// fed to it directly.
+//
+// ReplacementFilter is safe for concurrent use.
+type ReplacementFilter struct {
+	// dropWhenSeen maps PGN that is dropped to PGNs that are preferred over it
+	dropWhenSeen map[uint32][]uint32
+	// isPreferred contains all PGNs that are preferred over some other PGN
+	isPreferred map[uint32]bool
+	staleAfter  time.Duration
+
+	lock sync.Mutex
+	// lastSeen holds time when preferred PGN was last seen from source. Key is PGN<<8 | source
+	lastSeen map[uint64]time.Time
+}
+
+// NewReplacementFilter creates new instance of ReplacementFilter preferring newer PGNs from DefaultPGNReplacements()
+func NewReplacementFilter() *ReplacementFilter {
+	return NewReplacementFilterWithConfig(ReplacementFilterConfig{})
+}
+
+// NewReplacementFilterWithConfig creates new instance of ReplacementFilter with given config
+func NewReplacementFilterWithConfig(config ReplacementFilterConfig) *ReplacementFilter {
+	if config.Replacements == nil {
+		config.Replacements = DefaultPGNReplacements()
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 10 * time.Second
+	}
+
+	f := &ReplacementFilter{
+		dropWhenSeen: make(map[uint32][]uint32, len(config.Replacements)),
+		isPreferred:  make(map[uint32]bool, len(config.Replacements)),
+		staleAfter:   config.StaleAfter,
+		lastSeen:     make(map[uint64]time.Time),
+	}
+	for legacy, newer := range config.Replacements {
+		preferred, dropped := newer, legacy
+		for _, l := range config.ForceLegacy {
+			if l == legacy {
+				preferred, dropped = legacy, newer
+				break
+			}
+		}
+		f.dropWhenSeen[dropped] = append(f.dropWhenSeen[dropped], preferred)
+		f.isPreferred[preferred] = true
+	}
+	return f
+}
+
+// Accept checks if message with given header and time should be let through. Superseded PGN is accepted only when
+// source has not sent (accepted) preferred PGN within StaleAfter duration. Accepted preferred PGN is remembered for the
+// source.
+//
+// PGN can be both preferred and superseded when ForceLegacy prefers only some of the legacy PGNs replaced by same newer
+// PGN (i.e. 127489 is dropped for forced 65262 but preferred over 65271). Dropped PGN is forgotten for the source so it
+// does not suppress other PGNs - their values would be lost otherwise.
+func (f *ReplacementFilter) Accept(header CanBusHeader, t time.Time) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, preferred := range f.dropWhenSeen[header.PGN] {
+		seen, ok := f.lastSeen[uint64(preferred)<<8|uint64(header.Source)]
+		if ok && t.Sub(seen) <= f.staleAfter {
+			delete(f.lastSeen, uint64(header.PGN)<<8|uint64(header.Source))
+			return false
+		}
+	}
+	if f.isPreferred[header.PGN] {
+		f.lastSeen[uint64(header.PGN)<<8|uint64(header.Source)] = t
+	}
+	return true
+}
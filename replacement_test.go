@@ -0,0 +1,132 @@
+package nmea
+
+import (
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestReplacementFilter_Accept(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	type when struct {
+		header CanBusHeader
+		time   time.Time
+	}
+	var testCases = []struct {
+		name        string
+		givenConfig ReplacementFilterConfig
+		when        []when
+		expect      []bool
+	}{
+		{
+			name: "ok, legacy is accepted when source does not send newer PGN",
+			when: []when{
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 130316, Source: 2}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now.Add(2 * time.Second)},
+			},
+			expect: []bool{true, true, true},
+		},
+		{
+			name: "ok, legacy is dropped when source sends newer PGN",
+			when: []when{
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 130316, Source: 1}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now.Add(2 * time.Second)},
+				{header: CanBusHeader{PGN: 130316, Source: 1}, time: now.Add(3 * time.Second)},
+			},
+			expect: []bool{true, true, false, true},
+		},
+		{
+			name: "ok, legacy is accepted again when newer PGN is stale",
+			givenConfig: ReplacementFilterConfig{
+				StaleAfter: 5 * time.Second,
+			},
+			when: []when{
+				{header: CanBusHeader{PGN: 130316, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now.Add(5 * time.Second)},
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now.Add(6 * time.Second)},
+			},
+			expect: []bool{true, false, true},
+		},
+		{
+			name: "ok, force legacy drops newer PGN",
+			givenConfig: ReplacementFilterConfig{
+				ForceLegacy: []uint32{130312},
+			},
+			when: []when{
+				{header: CanBusHeader{PGN: 130316, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 130316, Source: 1}, time: now.Add(2 * time.Second)},
+			},
+			expect: []bool{true, true, false},
+		},
+		{
+			name: "ok, J1939 engine PGNs are dropped when source sends engine parameters dynamic",
+			when: []when{
+				{header: CanBusHeader{PGN: 65262, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 127489, Source: 1}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 65262, Source: 1}, time: now.Add(2 * time.Second)},
+				{header: CanBusHeader{PGN: 65263, Source: 1}, time: now.Add(2 * time.Second)},
+				{header: CanBusHeader{PGN: 65266, Source: 1}, time: now.Add(2 * time.Second)},
+				{header: CanBusHeader{PGN: 65271, Source: 1}, time: now.Add(2 * time.Second)},
+				{header: CanBusHeader{PGN: 65271, Source: 2}, time: now.Add(2 * time.Second)},
+			},
+			expect: []bool{true, true, false, false, false, false, true},
+		},
+		{
+			name: "ok, force legacy drops newer PGN when any of its forced legacy PGNs is seen",
+			givenConfig: ReplacementFilterConfig{
+				ForceLegacy: []uint32{65262, 65263},
+			},
+			when: []when{
+				{header: CanBusHeader{PGN: 127489, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 65263, Source: 1}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 127489, Source: 1}, time: now.Add(2 * time.Second)},
+				// dropped 127489 does not suppress 65271 which values would be lost otherwise
+				{header: CanBusHeader{PGN: 65271, Source: 1}, time: now.Add(3 * time.Second)},
+				{header: CanBusHeader{PGN: 127489, Source: 2}, time: now.Add(4 * time.Second)},
+				// 65263 has not been seen within stale duration
+				{header: CanBusHeader{PGN: 127489, Source: 1}, time: now.Add(12 * time.Second)},
+				{header: CanBusHeader{PGN: 65271, Source: 1}, time: now.Add(13 * time.Second)},
+			},
+			expect: []bool{true, true, false, true, true, true, false},
+		},
+		{
+			name: "ok, custom replacements",
+			givenConfig: ReplacementFilterConfig{
+				Replacements: map[uint32]uint32{127489: 127488},
+			},
+			when: []when{
+				{header: CanBusHeader{PGN: 127488, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 127489, Source: 1}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 130316, Source: 1}, time: now.Add(2 * time.Second)},
+				{header: CanBusHeader{PGN: 130312, Source: 1}, time: now.Add(3 * time.Second)},
+			},
+			expect: []bool{true, false, true, true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := NewReplacementFilterWithConfig(tc.givenConfig)
+
+			result := make([]bool, 0, len(tc.when))
+			for _, w := range tc.when {
+				result = append(result, f.Accept(w.header, w.time))
+			}
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestDefaultPGNReplacements(t *testing.T) {
+	replacements := DefaultPGNReplacements()
+	assert.Equal(t, uint32(130316), replacements[130312])
+	assert.Equal(t, uint32(127489), replacements[65262])
+
+	delete(replacements, 130312)
+	assert.Equal(t, uint32(130316), DefaultPGNReplacements()[130312])
+}
@@ -0,0 +1,153 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ReplayConfig configures ReplayReader
+type ReplayConfig struct {
+	// Speed is replay speed multiplier. 1 replays recording in original pace, 0.5 half as fast and 10 ten times faster.
+	// Defaults to: 1
+	Speed float64
+
+	// MaxDelay limits time waited between two messages so long pauses in recording (i.e. logging was stopped for
+	// hours) do not stall replay.
+	// Optional: when zero, delays are not limited
+	MaxDelay time.Duration
+
+	// Loop replays recording again from start when wrapped reader reaches end of input (io.EOF). Reopen must be set for
+	// looping.
+	Loop bool
+
+	// Reopen returns new reader that reads recording again from start. Previous reader is closed before Reopen is
+	// called.
+	// Optional: required when Loop is true
+	Reopen func() (RawMessageReader, error)
+
+	// ShiftTime sets message Time (and BusTime when set) to time message is replayed at so that replayed messages
+	// look like they were just read. Useful with Loop so that downstream software does not see time going backwards.
+	ShiftTime bool
+}
+
+// ReplayReader wraps reader of recorded messages (EBL, candump, canboat-raw files) and delays reads so that messages
+// are returned in the same pace as they were recorded, according to message BusTime (or Time when BusTime is not
+// set). Without ReplayReader recorded files are read as fast as possible.
+//
+// Note: ReadRawMessage is not go-routine safe
+type ReplayReader struct {
+	reader RawMessageReader
+	config ReplayConfig
+
+	timeNow func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	isStarted bool
+	// lastMessageTime is recorded time of previous message
+	lastMessageTime time.Time
+	// scheduled is local time when previous message was returned (or should have been returned)
+	scheduled time.Time
+}
+
+// NewReplayReader creates new instance of ReplayReader
+func NewReplayReader(reader RawMessageReader, config ReplayConfig) *ReplayReader {
+	if config.Speed <= 0 {
+		config.Speed = 1
+	}
+	return &ReplayReader{
+		reader:  reader,
+		config:  config,
+		timeNow: time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// ReadRawMessage reads message from wrapped reader and waits until it is time to return it.
+func (r *ReplayReader) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	msg, err := r.reader.ReadRawMessage(ctx)
+	if errors.Is(err, io.EOF) && r.config.Loop && r.config.Reopen != nil {
+		if msg, err = r.rewind(ctx); err != nil {
+			return RawMessage{}, err
+		}
+	} else if err != nil {
+		return msg, err
+	}
+
+	if err := r.wait(ctx, replayTime(msg)); err != nil {
+		return RawMessage{}, err
+	}
+	if r.config.ShiftTime {
+		msg.Time = r.scheduled
+		if !msg.BusTime.IsZero() {
+			msg.BusTime = r.scheduled
+		}
+	}
+	return msg, nil
+}
+
+func (r *ReplayReader) rewind(ctx context.Context) (RawMessage, error) {
+	if err := r.reader.Close(); err != nil {
+		return RawMessage{}, err
+	}
+	reader, err := r.config.Reopen()
+	if err != nil {
+		return RawMessage{}, err
+	}
+	if err := reader.Initialize(); err != nil {
+		return RawMessage{}, err
+	}
+	r.reader = reader
+	msg, err := r.reader.ReadRawMessage(ctx)
+	if err != nil {
+		return RawMessage{}, err // recording without messages is not looped
+	}
+	// first message of next round is replayed right after last message of previous round
+	r.isStarted = false
+	return msg, nil
+}
+
+// wait delays read until time between this and previous message has passed (scaled by replay speed)
+func (r *ReplayReader) wait(ctx context.Context, messageTime time.Time) error {
+	if !r.isStarted {
+		r.isStarted = true
+		r.lastMessageTime = messageTime
+		r.scheduled = r.timeNow()
+		return nil
+	}
+
+	delay := time.Duration(float64(messageTime.Sub(r.lastMessageTime)) / r.config.Speed)
+	if delay < 0 { // messages out of order or time jumped backwards
+		delay = 0
+	}
+	if r.config.MaxDelay > 0 && delay > r.config.MaxDelay {
+		delay = r.config.MaxDelay
+	}
+	if messageTime.After(r.lastMessageTime) {
+		r.lastMessageTime = messageTime
+	}
+	r.scheduled = r.scheduled.Add(delay)
+
+	if d := r.scheduled.Sub(r.timeNow()); d > 0 {
+		return r.sleep(ctx, d)
+	}
+	return nil
+}
+
+func replayTime(msg RawMessage) time.Time {
+	if !msg.BusTime.IsZero() {
+		return msg.BusTime
+	}
+	return msg.Time
+}
+
+// Initialize initializes wrapped reader
+func (r *ReplayReader) Initialize() error {
+	return r.reader.Initialize()
+}
+
+// Close closes wrapped reader
+func (r *ReplayReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,126 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReplayReader_ReadRawMessage(t *testing.T) {
+	recorded := test_test.UTCTime(1600000000)
+	messages := []RawMessage{
+		{Time: recorded, Header: CanBusHeader{PGN: 129025}},
+		{Time: recorded.Add(100 * time.Millisecond), Header: CanBusHeader{PGN: 129026}},
+		{Time: recorded.Add(50 * time.Millisecond), Header: CanBusHeader{PGN: 129025}}, // out of order
+		{Time: recorded.Add(1 * time.Hour), Header: CanBusHeader{PGN: 129025}},
+	}
+
+	var testCases = []struct {
+		name         string
+		givenConfig  ReplayConfig
+		expectSleeps []time.Duration
+	}{
+		{
+			name:         "ok, original speed",
+			givenConfig:  ReplayConfig{},
+			expectSleeps: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 1 * time.Hour},
+		},
+		{
+			name:         "ok, 10x speed",
+			givenConfig:  ReplayConfig{Speed: 10},
+			expectSleeps: []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 6 * time.Minute},
+		},
+		{
+			name:         "ok, half speed with max delay",
+			givenConfig:  ReplayConfig{Speed: 0.5, MaxDelay: time.Second},
+			expectSleeps: []time.Duration{200 * time.Millisecond, 200 * time.Millisecond, 1200 * time.Millisecond},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := test_test.UTCTime(1665488842)
+			r := NewReplayReader(&sliceReader{messages: messages}, tc.givenConfig)
+			r.timeNow = func() time.Time { return now }
+			var sleeps []time.Duration
+			r.sleep = func(ctx context.Context, d time.Duration) error {
+				sleeps = append(sleeps, d)
+				return nil
+			}
+
+			for i := range messages {
+				msg, err := r.ReadRawMessage(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, messages[i], msg)
+			}
+			_, err := r.ReadRawMessage(context.Background())
+			assert.ErrorIs(t, err, io.EOF)
+
+			assert.Equal(t, tc.expectSleeps, sleeps)
+		})
+	}
+}
+
+func TestReplayReader_ReadRawMessage_loopWithShiftTime(t *testing.T) {
+	recorded := test_test.UTCTime(1600000000)
+	messages := []RawMessage{
+		{Time: recorded, Header: CanBusHeader{PGN: 129025}},
+		{Time: recorded.Add(100 * time.Millisecond), Header: CanBusHeader{PGN: 129026}},
+		{Time: recorded, BusTime: recorded.Add(300 * time.Millisecond), Header: CanBusHeader{PGN: 129025}},
+	}
+
+	reopened := 0
+	r := NewReplayReader(&sliceReader{messages: messages}, ReplayConfig{
+		Loop:      true,
+		ShiftTime: true,
+		Reopen: func() (RawMessageReader, error) {
+			reopened++
+			return &sliceReader{messages: messages}, nil
+		},
+	})
+	now := test_test.UTCTime(1665488842)
+	r.timeNow = func() time.Time { return now }
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		now = now.Add(d)
+		return nil
+	}
+
+	start := now
+	var times []time.Time
+	var busTimes []time.Time
+	for i := 0; i < 5; i++ {
+		msg, err := r.ReadRawMessage(context.Background())
+		assert.NoError(t, err)
+		times = append(times, msg.Time)
+		busTimes = append(busTimes, msg.BusTime)
+	}
+	assert.Equal(t, 1, reopened)
+	assert.Equal(t, []time.Time{
+		start,
+		start.Add(100 * time.Millisecond),
+		start.Add(300 * time.Millisecond), // BusTime is used for timing when set
+		start.Add(300 * time.Millisecond), // next round starts right after previous one
+		start.Add(400 * time.Millisecond),
+	}, times)
+	assert.Equal(t, []time.Time{{}, {}, start.Add(300 * time.Millisecond), {}, {}}, busTimes)
+}
+
+func TestReplayReader_ReadRawMessage_contextCancelled(t *testing.T) {
+	recorded := test_test.UTCTime(1600000000)
+	r := NewReplayReader(&sliceReader{messages: []RawMessage{
+		{Time: recorded},
+		{Time: recorded.Add(time.Hour)},
+	}}, ReplayConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := r.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+
+	_, err = r.ReadRawMessage(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,314 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrSubscriberPanic is wrapped by SubscriptionError.Err when subscriber handler panicked
+	ErrSubscriberPanic = errors.New("subscriber panicked")
+	// ErrRouterClosed is returned when subscribing to closed Router
+	ErrRouterClosed = errors.New("router is closed")
+)
+
+// DropPolicy determines what Router and AsyncReader do when buffer is full
+type DropPolicy uint8
+
+const (
+	// DropNewest discards message that did not fit into full buffer. Messages already in buffer are delivered.
+	DropNewest DropPolicy = iota
+	// DropOldest discards the oldest message in buffer to make room for new message. Subscriber always gets the latest
+	// data.
+	DropOldest
+	// Block waits until there is room in buffer. Note: blocking subscription slows down delivery to all other
+	// subscriptions as Publish waits for it.
+	Block
+	// DropLowestPriority discards the oldest buffered message with the lowest priority (highest CanBusHeader.Priority
+	// value), or the new message when its priority is lower than priority of all buffered messages. Keeps important
+	// (i.e. navigation, control) messages when bus is flooded with low priority messages. Supported by AsyncReader,
+	// Router handles it as DropNewest.
+	DropLowestPriority
+)
+
+// SubscriptionConfig configures single Router subscription
+type SubscriptionConfig struct {
+	// Name identifies subscription in errors and statistics. Optional.
+	Name string
+	// Filter decides which messages are delivered to this subscription. Optional: nil means all messages.
+	Filter func(msg RawMessage) bool
+	// Handler is called for every delivered message from subscription own goroutine. Returned error and recovered
+	// panic are reported through Subscription.Errors and do not stop delivery.
+	Handler func(msg RawMessage) error
+
+	// BufferSize is number of messages that can wait for delivery to this subscription.
+	// Defaults to: 100
+	BufferSize int
+	// DropPolicy determines what to do when buffer is full.
+	// Defaults to: DropNewest
+	DropPolicy DropPolicy
+	// ErrorBufferSize is number of errors that can wait in Errors channel. When channel is full new errors are
+	// discarded (but still counted in statistics).
+	// Defaults to: 10
+	ErrorBufferSize int
+}
+
+// SubscriptionError is error that happened while delivering message to subscriber
+type SubscriptionError struct {
+	Subscription string
+	Time         time.Time
+	Message      RawMessage
+	// Err is error returned by handler or ErrSubscriberPanic wrapping recovered panic value
+	Err error
+}
+
+func (e SubscriptionError) Error() string {
+	return fmt.Sprintf("subscription %v: %v", e.Subscription, e.Err)
+}
+
+func (e SubscriptionError) Unwrap() error {
+	return e.Err
+}
+
+// SubscriptionStats is delivery statistics of single subscription
+type SubscriptionStats struct {
+	// Delivered is number of messages given to handler
+	Delivered uint64 `json:"delivered"`
+	// Dropped is number of messages discarded due to full buffer
+	Dropped uint64 `json:"dropped"`
+	// Errors is number of errors returned by handler
+	Errors uint64 `json:"errors"`
+	// Panics is number of recovered handler panics
+	Panics uint64 `json:"panics"`
+	// ErrorsDropped is number of errors that did not fit into Errors channel
+	ErrorsDropped uint64 `json:"errors_dropped"`
+	// Queued is number of messages currently waiting in buffer
+	Queued int `json:"queued"`
+}
+
+// Subscription is single subscriber of Router. Each subscription has its own buffer and delivery goroutine so slow or
+// panicking subscriber does not affect other subscriptions.
+type Subscription struct {
+	router *Router
+	name   string
+	config SubscriptionConfig
+
+	queue  chan RawMessage
+	errors chan SubscriptionError
+	done   chan struct{}
+	// stop is closed when subscription is removed. Publishing and draining stops after that.
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	delivered     atomic.Uint64
+	dropped       atomic.Uint64
+	errorCount    atomic.Uint64
+	panics        atomic.Uint64
+	errorsDropped atomic.Uint64
+}
+
+// Errors returns channel where handler errors and recovered panics are reported. Channel is closed when subscription
+// is closed.
+func (s *Subscription) Errors() <-chan SubscriptionError {
+	return s.errors
+}
+
+// Stats returns delivery statistics of subscription
+func (s *Subscription) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		Delivered:     s.delivered.Load(),
+		Dropped:       s.dropped.Load(),
+		Errors:        s.errorCount.Load(),
+		Panics:        s.panics.Load(),
+		ErrorsDropped: s.errorsDropped.Load(),
+		Queued:        len(s.queue),
+	}
+}
+
+// Close removes subscription from router and waits until handler has returned. Messages still in buffer are discarded.
+func (s *Subscription) Close() error {
+	s.router.remove(s)
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+	return nil
+}
+
+func (s *Subscription) publish(msg RawMessage) {
+	if s.config.Filter != nil && !s.config.Filter(msg) {
+		return
+	}
+	switch s.config.DropPolicy {
+	case Block:
+		select {
+		case s.queue <- msg:
+		case <-s.stop:
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.queue <- msg:
+				return
+			default:
+			}
+			select {
+			case <-s.queue:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.queue <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+func (s *Subscription) run() {
+	defer close(s.done)
+	defer close(s.errors)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case msg := <-s.queue:
+			s.deliver(msg)
+		}
+	}
+}
+
+func (s *Subscription) deliver(msg RawMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.panics.Add(1)
+			s.reportError(msg, fmt.Errorf("%w: %v", ErrSubscriberPanic, r))
+		}
+	}()
+	s.delivered.Add(1)
+	if err := s.config.Handler(msg); err != nil {
+		s.errorCount.Add(1)
+		s.reportError(msg, err)
+	}
+}
+
+func (s *Subscription) reportError(msg RawMessage, err error) {
+	select {
+	case s.errors <- SubscriptionError{Subscription: s.name, Time: time.Now(), Message: msg, Err: err}:
+	default:
+		s.errorsDropped.Add(1)
+	}
+}
+
+// Router delivers (fans out) messages to multiple subscribers. Subscribers are isolated from each other: every
+// subscription has its own buffer with drop policy and its own delivery goroutine, handler panics are recovered and
+// handler errors and panics are reported per subscription.
+//
+// Router is safe for concurrent use.
+type Router struct {
+	lock          sync.RWMutex
+	subscriptions []*Subscription
+	closed        bool
+	nameCounter   int
+}
+
+// NewRouter creates new instance of Router
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Subscribe adds new subscription to router. Handler is required.
+func (r *Router) Subscribe(config SubscriptionConfig) (*Subscription, error) {
+	if config.Handler == nil {
+		return nil, errors.New("subscription requires handler")
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 100
+	}
+	if config.ErrorBufferSize <= 0 {
+		config.ErrorBufferSize = 10
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return nil, ErrRouterClosed
+	}
+	r.nameCounter++
+	name := config.Name
+	if name == "" {
+		name = fmt.Sprintf("subscription-%d", r.nameCounter)
+	}
+	s := &Subscription{
+		router: r,
+		name:   name,
+		config: config,
+		queue:  make(chan RawMessage, config.BufferSize),
+		errors: make(chan SubscriptionError, config.ErrorBufferSize),
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	r.subscriptions = append(r.subscriptions, s)
+	go s.run()
+	return s, nil
+}
+
+func (r *Router) remove(s *Subscription) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// Publish iterates over snapshot of subscriptions slice, so slice is copied instead of modified in place
+	subscriptions := make([]*Subscription, 0, len(r.subscriptions))
+	for _, tmp := range r.subscriptions {
+		if tmp != s {
+			subscriptions = append(subscriptions, tmp)
+		}
+	}
+	r.subscriptions = subscriptions
+}
+
+// Publish delivers message to all subscriptions according to their filters and drop policies. Publish does not wait
+// for handlers and blocks only when some subscription uses Block policy and its buffer is full.
+func (r *Router) Publish(msg RawMessage) {
+	r.lock.RLock()
+	subscriptions := r.subscriptions
+	r.lock.RUnlock()
+
+	for _, s := range subscriptions {
+		s.publish(msg)
+	}
+}
+
+// Run reads messages from reader and publishes them until reader returns io.EOF, context is cancelled or read fails.
+func (r *Router) Run(ctx context.Context, reader RawMessageReader) error {
+	for {
+		msg, err := reader.ReadRawMessage(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		r.Publish(msg)
+	}
+}
+
+// Close closes all subscriptions. Subscribing to closed router fails with ErrRouterClosed.
+func (r *Router) Close() error {
+	r.lock.Lock()
+	r.closed = true
+	subscriptions := r.subscriptions
+	r.subscriptions = nil
+	r.lock.Unlock()
+
+	for _, s := range subscriptions {
+		s.Close()
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouter_Publish_isolatesPanickingSubscriber(t *testing.T) {
+	router := NewRouter()
+	defer router.Close()
+
+	var lock sync.Mutex
+	received := make([]uint32, 0)
+	good, err := router.Subscribe(SubscriptionConfig{
+		Name: "good",
+		Handler: func(msg RawMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+			received = append(received, msg.Header.PGN)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	bad, err := router.Subscribe(SubscriptionConfig{
+		Name: "bad",
+		Handler: func(msg RawMessage) error {
+			if msg.Header.PGN == 2 {
+				panic("boom")
+			}
+			return errors.New("handler failure")
+		},
+	})
+	assert.NoError(t, err)
+
+	for pgn := uint32(1); pgn <= 3; pgn++ {
+		router.Publish(RawMessage{Header: CanBusHeader{PGN: pgn}})
+	}
+
+	var errs []SubscriptionError
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-bad.Errors():
+			errs = append(errs, e)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for subscription errors")
+		}
+	}
+	assert.Equal(t, "subscription bad: handler failure", errs[0].Error())
+	assert.ErrorIs(t, errs[1], ErrSubscriberPanic)
+	assert.Equal(t, "subscription bad: subscriber panicked: boom", errs[1].Error())
+	assert.Equal(t, uint32(2), errs[1].Message.Header.PGN)
+	assert.Equal(t, "subscription bad: handler failure", errs[2].Error())
+
+	assert.Eventually(t, func() bool {
+		return good.Stats().Delivered == 3
+	}, time.Second, time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, []uint32{1, 2, 3}, received)
+	lock.Unlock()
+
+	stats := bad.Stats()
+	assert.Equal(t, uint64(3), stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Errors)
+	assert.Equal(t, uint64(1), stats.Panics)
+}
+
+func TestRouter_Publish_dropPolicies(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		policy        DropPolicy
+		expectPGNs    []uint32
+		expectDropped uint64
+	}{
+		{
+			name:          "ok, drop newest",
+			policy:        DropNewest,
+			expectPGNs:    []uint32{1, 2, 3},
+			expectDropped: 2,
+		},
+		{
+			name:          "ok, drop oldest",
+			policy:        DropOldest,
+			expectPGNs:    []uint32{1, 4, 5},
+			expectDropped: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := NewRouter()
+			defer router.Close()
+
+			release := make(chan struct{})
+			started := make(chan struct{}, 1)
+			var lock sync.Mutex
+			received := make([]uint32, 0)
+			sub, err := router.Subscribe(SubscriptionConfig{
+				BufferSize: 2,
+				DropPolicy: tc.policy,
+				Handler: func(msg RawMessage) error {
+					if msg.Header.PGN == 1 {
+						started <- struct{}{}
+						<-release // slow subscriber
+					}
+					lock.Lock()
+					defer lock.Unlock()
+					received = append(received, msg.Header.PGN)
+					return nil
+				},
+			})
+			assert.NoError(t, err)
+
+			router.Publish(RawMessage{Header: CanBusHeader{PGN: 1}})
+			<-started // first message is taken out of buffer and handler blocks
+			for pgn := uint32(2); pgn <= 5; pgn++ {
+				router.Publish(RawMessage{Header: CanBusHeader{PGN: pgn}})
+			}
+			assert.Equal(t, 2, sub.Stats().Queued)
+			close(release)
+
+			assert.Eventually(t, func() bool {
+				return sub.Stats().Delivered == 3
+			}, time.Second, time.Millisecond)
+			lock.Lock()
+			assert.Equal(t, tc.expectPGNs, received)
+			lock.Unlock()
+			assert.Equal(t, tc.expectDropped, sub.Stats().Dropped)
+		})
+	}
+}
+
+func TestRouter_Publish_filterAndErrorBuffer(t *testing.T) {
+	router := NewRouter()
+	defer router.Close()
+
+	sub, err := router.Subscribe(SubscriptionConfig{
+		ErrorBufferSize: 1,
+		Filter: func(msg RawMessage) bool {
+			return msg.Header.Source == 5
+		},
+		Handler: func(msg RawMessage) error {
+			return errors.New("fail")
+		},
+	})
+	assert.NoError(t, err)
+
+	router.Publish(RawMessage{Header: CanBusHeader{PGN: 1, Source: 5}})
+	router.Publish(RawMessage{Header: CanBusHeader{PGN: 2, Source: 6}})
+	router.Publish(RawMessage{Header: CanBusHeader{PGN: 3, Source: 5}})
+
+	assert.Eventually(t, func() bool {
+		return sub.Stats().Errors == 2
+	}, time.Second, time.Millisecond)
+	stats := sub.Stats()
+	assert.Equal(t, uint64(2), stats.Delivered)
+	assert.Equal(t, uint64(1), stats.ErrorsDropped)
+
+	e := <-sub.Errors()
+	assert.Equal(t, uint32(1), e.Message.Header.PGN)
+}
+
+func TestRouter_Subscription_Close(t *testing.T) {
+	router := NewRouter()
+
+	count := 0
+	sub, err := router.Subscribe(SubscriptionConfig{
+		Handler: func(msg RawMessage) error {
+			count++
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sub.Close())
+	assert.NoError(t, sub.Close())
+
+	_, ok := <-sub.Errors()
+	assert.False(t, ok)
+
+	router.Publish(RawMessage{})
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, router.Close())
+	_, err = router.Subscribe(SubscriptionConfig{Handler: func(msg RawMessage) error { return nil }})
+	assert.ErrorIs(t, err, ErrRouterClosed)
+
+	_, err = NewRouter().Subscribe(SubscriptionConfig{})
+	assert.EqualError(t, err, "subscription requires handler")
+}
+
+func TestRouter_Run(t *testing.T) {
+	router := NewRouter()
+	defer router.Close()
+
+	var lock sync.Mutex
+	received := 0
+	_, err := router.Subscribe(SubscriptionConfig{
+		Handler: func(msg RawMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+			received++
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	reader := &sliceReader{messages: []RawMessage{{}, {}, {}}}
+	assert.NoError(t, router.Run(context.Background(), reader))
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return received == 3
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,178 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// HeartbeatInterval is interval NMEA 2000 nodes must send Heartbeat (PGN 126993)
+const HeartbeatInterval = 60 * time.Second
+
+// heartbeatSequenceMax is sequence counter value after which counter wraps to 0 (values 253-255 are reserved)
+const heartbeatSequenceMax = 252
+
+// PeriodicMessage is message that Scheduler sends with given interval
+type PeriodicMessage struct {
+	// Interval is time between transmissions of message
+	Interval time.Duration
+	// Create creates message to be sent. Is called for every transmission so message content can change between
+	// transmissions (i.e. sequence counter). Message time is set to transmission time when Create leaves it empty.
+	Create func(now time.Time) RawMessage
+}
+
+// SchedulerConfig configures Scheduler
+type SchedulerConfig struct {
+	Messages []PeriodicMessage
+
+	// Jitter is maximum random delay added to each transmission so nodes started at same time do not send their
+	// periodic messages at same moment. Jitter does not accumulate - messages are still sent on average with interval.
+	Jitter time.Duration
+
+	// OnError is called when writing message fails. When not set, write error ends Run with that error.
+	OnError func(msg RawMessage, err error)
+}
+
+// Scheduler periodically sends configured messages (i.e. Heartbeat) through RawMessageWriter. Messages are first sent
+// when Run is started.
+type Scheduler struct {
+	writer   RawMessageWriter
+	messages []PeriodicMessage
+	jitter   time.Duration
+	onError  func(msg RawMessage, err error)
+
+	now       func() time.Time
+	randInt63 func(n int64) int64
+}
+
+// NewScheduler creates new instance of Scheduler
+func NewScheduler(writer RawMessageWriter, config SchedulerConfig) *Scheduler {
+	return &Scheduler{
+		writer:   writer,
+		messages: config.Messages,
+		jitter:   config.Jitter,
+		onError:  config.OnError,
+
+		now:       time.Now,
+		randInt63: rand.Int63n,
+	}
+}
+
+// Run sends messages periodically and blocks until context is cancelled or writing message fails.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.messages) == 0 {
+		return errors.New("scheduler has no messages to send")
+	}
+	for _, m := range s.messages {
+		if m.Interval <= 0 {
+			return errors.New("scheduler message interval must be greater than 0")
+		}
+		if m.Create == nil {
+			return errors.New("scheduler message must have create function")
+		}
+	}
+
+	start := s.now()
+	scheduled := make([]time.Time, len(s.messages)) // transmission times without jitter
+	next := make([]time.Time, len(s.messages))
+	for i := range s.messages {
+		scheduled[i] = start
+		next[i] = start.Add(s.randomJitter())
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		now := s.now()
+		earliest := time.Time{}
+		for i, m := range s.messages {
+			if !now.Before(next[i]) {
+				if err := s.send(ctx, m, now); err != nil {
+					return err
+				}
+				scheduled[i] = scheduled[i].Add(m.Interval)
+				if scheduled[i].Before(now) {
+					scheduled[i] = now // writer was slow, do not try to catch up with missed transmissions
+				}
+				next[i] = scheduled[i].Add(s.randomJitter())
+			}
+			if earliest.IsZero() || next[i].Before(earliest) {
+				earliest = next[i]
+			}
+		}
+		timer.Reset(earliest.Sub(now))
+	}
+}
+
+func (s *Scheduler) send(ctx context.Context, m PeriodicMessage, now time.Time) error {
+	msg := m.Create(now)
+	if msg.Time.IsZero() {
+		msg.Time = now
+	}
+	if err := s.writer.WriteRawMessage(ctx, msg); err != nil {
+		if s.onError == nil {
+			return err
+		}
+		s.onError(msg, err)
+	}
+	return nil
+}
+
+func (s *Scheduler) randomJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(s.randInt63(int64(s.jitter)))
+}
+
+// NewHeartbeatMessage creates periodic Heartbeat (PGN 126993) message with given interval. Sequence counter is
+// incremented with every transmission. Source address is left as AddressNull so writer (i.e.
+// addressmapper.ClaimedAddressWriter) can set claimed address.
+func NewHeartbeatMessage(interval time.Duration) PeriodicMessage {
+	sequence := uint8(0)
+	return PeriodicMessage{
+		Interval: interval,
+		Create: func(now time.Time) RawMessage {
+			msg := CreateHeartbeat(interval, sequence)
+			msg.Time = now
+			if sequence >= heartbeatSequenceMax {
+				sequence = 0
+			} else {
+				sequence++
+			}
+			return msg
+		},
+	}
+}
+
+// CreateHeartbeat creates Heartbeat (PGN 126993) message with given transmit interval and sequence counter. Controller
+// states are "Error Active" and equipment status "Operational".
+func CreateHeartbeat(interval time.Duration, sequence uint8) RawMessage {
+	offset := interval / (10 * time.Millisecond) // resolution 0.01s
+	if offset > 0xfffd {
+		offset = 0xfffd
+	}
+	return RawMessage{
+		Header: CanBusHeader{
+			PGN:         uint32(PGNHeartbeat),
+			Priority:    7,
+			Source:      AddressNull,
+			Destination: AddressGlobal,
+		},
+		Data: []byte{
+			uint8(offset & 0xff),
+			uint8(offset >> 8 & 0xff),
+			sequence,
+			// controller 1 state (2 bits), controller 2 state (2 bits), equipment status (2 bits), reserved (2 bits)
+			0b1100_0000,
+			0xff, 0xff, 0xff, 0xff, // reserved
+		},
+	}
+}
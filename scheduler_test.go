@@ -0,0 +1,150 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type cancellingWriter struct {
+	recordingWriter
+	cancelAfter int
+	cancel      context.CancelFunc
+}
+
+func (w *cancellingWriter) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	err := w.recordingWriter.WriteRawMessage(ctx, msg)
+	if len(w.messages) >= w.cancelAfter {
+		w.cancel()
+	}
+	return err
+}
+
+func TestScheduler_Run(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	w := &cancellingWriter{cancelAfter: 4, cancel: cancel}
+	s := NewScheduler(w, SchedulerConfig{
+		Messages: []PeriodicMessage{
+			NewHeartbeatMessage(20 * time.Millisecond),
+			{
+				Interval: time.Hour,
+				Create: func(now time.Time) RawMessage {
+					return RawMessage{Header: CanBusHeader{PGN: 127250}}
+				},
+			},
+		},
+		Jitter: 5 * time.Millisecond,
+	})
+
+	err := s.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	if assert.Len(t, w.messages, 4) {
+		var heartbeats []RawMessage
+		headings := 0
+		for _, m := range w.messages {
+			assert.False(t, m.Time.IsZero())
+			switch m.Header.PGN {
+			case uint32(PGNHeartbeat):
+				heartbeats = append(heartbeats, m)
+			case 127250:
+				headings++
+			}
+		}
+		assert.Equal(t, 1, headings) // hourly message is sent only at start
+		if assert.Len(t, heartbeats, 3) {
+			assert.Equal(t, uint8(0), heartbeats[0].Data[2])
+			assert.Equal(t, uint8(1), heartbeats[1].Data[2])
+			assert.Equal(t, uint8(2), heartbeats[2].Data[2])
+		}
+	}
+}
+
+func TestScheduler_Run_writeError(t *testing.T) {
+	w := &recordingWriter{err: errors.New("write failed")}
+	s := NewScheduler(w, SchedulerConfig{Messages: []PeriodicMessage{NewHeartbeatMessage(time.Second)}})
+
+	err := s.Run(context.Background())
+
+	assert.EqualError(t, err, "write failed")
+}
+
+func TestScheduler_Run_onError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	w := &recordingWriter{err: errors.New("write failed")}
+	errorCount := 0
+	s := NewScheduler(w, SchedulerConfig{
+		Messages: []PeriodicMessage{NewHeartbeatMessage(10 * time.Millisecond)},
+		OnError: func(msg RawMessage, err error) {
+			assert.EqualError(t, err, "write failed")
+			errorCount++
+			if errorCount == 2 {
+				cancel()
+			}
+		},
+	})
+
+	err := s.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, errorCount)
+}
+
+func TestScheduler_Run_invalidConfig(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		given       SchedulerConfig
+		expectError string
+	}{
+		{
+			name:        "nok, no messages",
+			given:       SchedulerConfig{},
+			expectError: "scheduler has no messages to send",
+		},
+		{
+			name: "nok, no interval",
+			given: SchedulerConfig{Messages: []PeriodicMessage{
+				{Create: func(now time.Time) RawMessage { return RawMessage{} }},
+			}},
+			expectError: "scheduler message interval must be greater than 0",
+		},
+		{
+			name:        "nok, no create function",
+			given:       SchedulerConfig{Messages: []PeriodicMessage{{Interval: time.Second}}},
+			expectError: "scheduler message must have create function",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewScheduler(&recordingWriter{}, tc.given).Run(context.Background())
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestCreateHeartbeat(t *testing.T) {
+	result := CreateHeartbeat(HeartbeatInterval, 5)
+
+	expect := RawMessage{
+		Header: CanBusHeader{PGN: 126993, Priority: 7, Source: 254, Destination: 255},
+		Data:   RawData{0x70, 0x17, 0x05, 0xc0, 0xff, 0xff, 0xff, 0xff}, // 6000 * 0.01s = 60s
+	}
+	assert.Equal(t, expect, result)
+}
+
+func TestNewHeartbeatMessage_sequenceWraps(t *testing.T) {
+	m := NewHeartbeatMessage(HeartbeatInterval)
+
+	var last uint8
+	for i := 0; i <= heartbeatSequenceMax; i++ {
+		last = m.Create(time.Time{}).Data[2]
+	}
+	assert.Equal(t, uint8(252), last)
+	assert.Equal(t, uint8(0), m.Create(time.Time{}).Data[2])
+}
@@ -0,0 +1,53 @@
+package nmea
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	// DefaultSerialBaud is default serial port baud rate. Actisense NGT-1 and most USB gateways use 115200.
+	DefaultSerialBaud = 115200
+	// DefaultSerialReadTimeout is default duration single read from serial port is allowed to block.
+	DefaultSerialReadTimeout = 100 * time.Millisecond
+)
+
+// SerialConfig configures serial port that device is connected to.
+type SerialConfig struct {
+	// Name is serial port device path (i.e. `/dev/ttyUSB0`, `COM3`). Required.
+	Name string
+	// Baud is serial port baud rate.
+	// Defaults to: DefaultSerialBaud
+	Baud int
+	// ReadTimeout is duration that single Read call is allowed to block. Devices have separate timeout for situation
+	// when there is no activity on bus.
+	// Defaults to: DefaultSerialReadTimeout
+	ReadTimeout time.Duration
+	// RTSCTSFlowControl enables hardware (RTS/CTS) flow control. Not all SerialOpener implementations support it.
+	RTSCTSFlowControl bool
+}
+
+// SerialOpener opens serial ports. Default implementation is serialport.Opener, other serial libraries (i.e.
+// go.bug.st/serial for better Windows and RTS/CTS support) can be used by implementing this interface.
+type SerialOpener interface {
+	OpenSerial(config SerialConfig) (io.ReadWriteCloser, error)
+}
+
+// SerialOpenerFunc is adapter to use ordinary function as SerialOpener
+type SerialOpenerFunc func(config SerialConfig) (io.ReadWriteCloser, error)
+
+// OpenSerial opens serial port by calling f(config)
+func (f SerialOpenerFunc) OpenSerial(config SerialConfig) (io.ReadWriteCloser, error) {
+	return f(config)
+}
+
+// WithDefaults returns copy of config with unset fields set to their default values.
+func (c SerialConfig) WithDefaults() SerialConfig {
+	if c.Baud <= 0 {
+		c.Baud = DefaultSerialBaud
+	}
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = DefaultSerialReadTimeout
+	}
+	return c
+}
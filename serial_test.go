@@ -0,0 +1,31 @@
+package nmea
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSerialConfig_WithDefaults(t *testing.T) {
+	assert.Equal(t,
+		SerialConfig{Name: "/dev/ttyUSB0", Baud: DefaultSerialBaud, ReadTimeout: DefaultSerialReadTimeout},
+		SerialConfig{Name: "/dev/ttyUSB0"}.WithDefaults(),
+	)
+	assert.Equal(t,
+		SerialConfig{Name: "COM3", Baud: 230400, ReadTimeout: time.Second},
+		SerialConfig{Name: "COM3", Baud: 230400, ReadTimeout: time.Second}.WithDefaults(),
+	)
+}
+
+func TestSerialOpenerFunc_OpenSerial(t *testing.T) {
+	var given SerialConfig
+	opener := SerialOpenerFunc(func(config SerialConfig) (io.ReadWriteCloser, error) {
+		given = config
+		return nil, io.ErrUnexpectedEOF
+	})
+
+	_, err := opener.OpenSerial(SerialConfig{Name: "COM3"})
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, SerialConfig{Name: "COM3"}, given)
+}
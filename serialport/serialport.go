@@ -0,0 +1,46 @@
+// Package serialport provides default nmea.SerialOpener implementation using github.com/tarm/serial library.
+//
+// Other serial libraries can be plugged in by implementing nmea.SerialOpener. For example go.bug.st/serial:
+//
+//	opener := nmea.SerialOpenerFunc(func(config nmea.SerialConfig) (io.ReadWriteCloser, error) {
+//		port, err := serial.Open(config.Name, &serial.Mode{BaudRate: config.Baud})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return port, port.SetReadTimeout(config.ReadTimeout)
+//	})
+package serialport
+
+import (
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/tarm/serial"
+	"io"
+)
+
+// ErrFlowControlNotSupported is returned when RTS/CTS flow control is requested from opener that does not support it
+var ErrFlowControlNotSupported = errors.New("serial port RTS/CTS flow control is not supported")
+
+// Opener is default nmea.SerialOpener implementation
+var Opener nmea.SerialOpener = nmea.SerialOpenerFunc(Open)
+
+// Open opens serial port with 8 data bits, no parity and 1 stop bit.
+func Open(config nmea.SerialConfig) (io.ReadWriteCloser, error) {
+	if config.Name == "" {
+		return nil, errors.New("serial port name can not be empty")
+	}
+	if config.RTSCTSFlowControl {
+		return nil, ErrFlowControlNotSupported
+	}
+	config = config.WithDefaults()
+	port, err := serial.OpenPort(&serial.Config{
+		Name:        config.Name,
+		Baud:        config.Baud,
+		ReadTimeout: config.ReadTimeout,
+		Size:        8,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return port, nil
+}
@@ -0,0 +1,33 @@
+package serialport
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        nmea.SerialConfig
+		expectError string
+	}{
+		{
+			name:        "nok, missing name",
+			when:        nmea.SerialConfig{},
+			expectError: "serial port name can not be empty",
+		},
+		{
+			name:        "nok, flow control not supported",
+			when:        nmea.SerialConfig{Name: "/dev/ttyUSB0", RTSCTSFlowControl: true},
+			expectError: ErrFlowControlNotSupported.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			port, err := Opener.OpenSerial(tc.when)
+			assert.Nil(t, port)
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
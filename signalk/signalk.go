@@ -0,0 +1,323 @@
+package signalk
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Delta is Signal K delta message. See https://signalk.org/specification/1.7.0/doc/data_model.html#delta-format
+type Delta struct {
+	Context string   `json:"context"`
+	Updates []Update `json:"updates"`
+}
+
+// Update is group of values from single source
+type Update struct {
+	Source    Source      `json:"source"`
+	Timestamp time.Time   `json:"timestamp"`
+	Values    []PathValue `json:"values"`
+}
+
+// Source identifies NMEA2000 device that sent values
+type Source struct {
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	PGN   uint32 `json:"pgn"`
+	Src   string `json:"src"`
+}
+
+// PathValue is value of Signal K path
+type PathValue struct {
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// Config is configuration for Converter
+type Config struct {
+	// Context is Signal K context of deltas. Defaults to `vessels.self`
+	Context string
+	// Label is source label of deltas. Defaults to `go-nmea-client`
+	Label string
+}
+
+// Converter converts decoded Canboat messages to Signal K deltas. Canboat decoder produces values in SI units and
+// Signal K uses SI units as well so most of the values are passed as is. Fields with different units (i.e. engine
+// speed in RPM, fuel rate in L/h, percentages) are converted to Signal K units.
+//
+// Supported PGNs:
+// * 127250 Vessel Heading
+// * 127257 Attitude
+// * 127488 Engine Parameters, Rapid Update
+// * 127489 Engine Parameters, Dynamic
+// * 128259 Speed
+// * 128267 Water Depth
+// * 129025 Position, Rapid Update
+// * 129026 COG & SOG, Rapid Update
+// * 129029 GNSS Position Data
+// * 130306 Wind Data
+type Converter struct {
+	context string
+	label   string
+}
+
+// NewConverter creates new instance of Converter
+func NewConverter(config Config) *Converter {
+	c := &Converter{
+		context: config.Context,
+		label:   config.Label,
+	}
+	if c.context == "" {
+		c.context = "vessels.self"
+	}
+	if c.label == "" {
+		c.label = "go-nmea-client"
+	}
+	return c
+}
+
+// IsSupported returns true when PGN can be converted to delta
+func IsSupported(pgn uint32) bool {
+	_, ok := converters[pgn]
+	return ok
+}
+
+// Convert converts decoded message to Signal K delta. Time is timestamp of update (usually nmea.RawMessage.Time).
+// Returns false when message PGN is not supported or message has no convertible values.
+func (c *Converter) Convert(msg nmea.Message, t time.Time) (Delta, bool) {
+	convert, ok := converters[msg.Header.PGN]
+	if !ok {
+		return Delta{}, false
+	}
+	values := convert(msg.Fields)
+	if len(values) == 0 {
+		return Delta{}, false
+	}
+	return Delta{
+		Context: c.context,
+		Updates: []Update{{
+			Source: Source{
+				Label: c.label,
+				Type:  "NMEA2000",
+				PGN:   msg.Header.PGN,
+				Src:   strconv.Itoa(int(msg.Header.Source)),
+			},
+			Timestamp: t.UTC(),
+			Values:    values,
+		}},
+	}, true
+}
+
+// DeltaWriter writes decoded messages as Signal K delta JSON lines. Messages that can not be converted are skipped.
+type DeltaWriter struct {
+	converter *Converter
+	writer    *bufio.Writer
+	encoder   *json.Encoder
+	closer    io.Closer
+}
+
+// NewDeltaWriter creates new instance of DeltaWriter. When writer implements io.Closer it is closed on Close.
+func NewDeltaWriter(writer io.Writer, config Config) *DeltaWriter {
+	buffered := bufio.NewWriter(writer)
+	w := &DeltaWriter{
+		converter: NewConverter(config),
+		writer:    buffered,
+		encoder:   json.NewEncoder(buffered),
+	}
+	if c, ok := writer.(io.Closer); ok {
+		w.closer = c
+	}
+	return w
+}
+
+// WriteMessage writes decoded message as Signal K delta line. Output is flushed after each delta.
+func (w *DeltaWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	delta, ok := w.converter.Convert(msg, raw.Time)
+	if !ok {
+		return nil
+	}
+	if err := w.encoder.Encode(delta); err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+// Close flushes buffered output and closes underlying writer
+func (w *DeltaWriter) Close() error {
+	err := w.writer.Flush()
+	if w.closer != nil {
+		if cErr := w.closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// mapping maps field to Signal K path. Field value is multiplied by scale to convert it to Signal K unit.
+type mapping struct {
+	path  string
+	scale float64
+}
+
+func path(p string) mapping {
+	return mapping{path: p, scale: 1}
+}
+
+const (
+	rpmToHz            = 1.0 / 60
+	percentToRatio     = 1.0 / 100
+	litersPerHourToM3s = 1.0 / 1000 / 3600
+)
+
+// converters convert decoded message fields to Signal K path values
+var converters = map[uint32]func(fields nmea.FieldValues) []PathValue{
+	127250: func(fields nmea.FieldValues) []PathValue { // Vessel Heading
+		heading := path("navigation.headingTrue")
+		if reference, ok := fieldFloat(fields, "reference"); ok && reference == 1 {
+			heading = path("navigation.headingMagnetic")
+		}
+		return values(fields, map[string]mapping{
+			"heading":   heading,
+			"deviation": path("navigation.magneticDeviation"),
+			"variation": path("navigation.magneticVariation"),
+		})
+	},
+	127257: func(fields nmea.FieldValues) []PathValue { // Attitude
+		attitude := map[string]float64{}
+		for _, ID := range []string{"roll", "pitch", "yaw"} {
+			if v, ok := fieldFloat(fields, ID); ok {
+				attitude[ID] = v
+			}
+		}
+		if len(attitude) == 0 {
+			return nil
+		}
+		return []PathValue{{Path: "navigation.attitude", Value: attitude}}
+	},
+	127488: func(fields nmea.FieldValues) []PathValue { // Engine Parameters, Rapid Update
+		prefix, ok := enginePrefix(fields)
+		if !ok {
+			return nil
+		}
+		return values(fields, map[string]mapping{
+			"speed":         {path: prefix + "revolutions", scale: rpmToHz},
+			"boostPressure": path(prefix + "boostPressure"),
+			"tiltTrim":      {path: prefix + "drive.trimState", scale: percentToRatio},
+		})
+	},
+	127489: func(fields nmea.FieldValues) []PathValue { // Engine Parameters, Dynamic
+		prefix, ok := enginePrefix(fields)
+		if !ok {
+			return nil
+		}
+		return values(fields, map[string]mapping{
+			"oilPressure":         path(prefix + "oilPressure"),
+			"oilTemperature":      path(prefix + "oilTemperature"),
+			"temperature":         path(prefix + "temperature"),
+			"alternatorPotential": path(prefix + "alternatorVoltage"),
+			"fuelRate":            {path: prefix + "fuel.rate", scale: litersPerHourToM3s},
+			"totalEngineHours":    path(prefix + "runTime"),
+			"coolantPressure":     path(prefix + "coolantPressure"),
+			"fuelPressure":        path(prefix + "fuel.pressure"),
+			"engineLoad":          {path: prefix + "engineLoad", scale: percentToRatio},
+			"engineTorque":        {path: prefix + "engineTorque", scale: percentToRatio},
+		})
+	},
+	128259: func(fields nmea.FieldValues) []PathValue { // Speed
+		return values(fields, map[string]mapping{"speedWaterReferenced": path("navigation.speedThroughWater")})
+	},
+	128267: func(fields nmea.FieldValues) []PathValue { // Water Depth
+		result := values(fields, map[string]mapping{"depth": path("environment.depth.belowTransducer")})
+		depth, okDepth := fieldFloat(fields, "depth")
+		offset, okOffset := fieldFloat(fields, "offset")
+		if okDepth && okOffset {
+			p := "environment.depth.belowSurface" // positive offset is distance from transducer to water line
+			if offset < 0 {
+				p = "environment.depth.belowKeel" // negative offset is distance from transducer to keel
+			}
+			result = append(result, PathValue{Path: p, Value: depth + offset})
+		}
+		return result
+	},
+	129025: func(fields nmea.FieldValues) []PathValue { // Position, Rapid Update
+		return position(fields)
+	},
+	129026: func(fields nmea.FieldValues) []PathValue { // COG & SOG, Rapid Update
+		cog := path("navigation.courseOverGroundTrue")
+		if reference, ok := fieldFloat(fields, "cogReference"); ok && reference == 1 {
+			cog = path("navigation.courseOverGroundMagnetic")
+		}
+		return values(fields, map[string]mapping{"cog": cog, "sog": path("navigation.speedOverGround")})
+	},
+	129029: func(fields nmea.FieldValues) []PathValue { // GNSS Position Data
+		result := position(fields)
+		if len(result) == 0 {
+			return nil
+		}
+		if alt, ok := fieldFloat(fields, "altitude"); ok {
+			result[0].Value.(map[string]float64)["altitude"] = alt
+		}
+		return append(result, values(fields, map[string]mapping{
+			"numberOfSvs":       path("navigation.gnss.satellites"),
+			"hdop":              path("navigation.gnss.horizontalDilution"),
+			"pdop":              path("navigation.gnss.positionDilution"),
+			"geoidalSeparation": path("navigation.gnss.geoidalSeparation"),
+		})...)
+	},
+	130306: func(fields nmea.FieldValues) []PathValue { // Wind Data
+		if reference, ok := fieldFloat(fields, "reference"); !ok || reference != 2 {
+			return nil // only apparent wind is converted
+		}
+		return values(fields, map[string]mapping{
+			"windSpeed": path("environment.wind.speedApparent"),
+			"windAngle": path("environment.wind.angleApparent"),
+		})
+	},
+}
+
+func position(fields nmea.FieldValues) []PathValue {
+	lat, okLat := fieldFloat(fields, "latitude")
+	lon, okLon := fieldFloat(fields, "longitude")
+	if !okLat || !okLon {
+		return nil
+	}
+	return []PathValue{{
+		Path:  "navigation.position",
+		Value: map[string]float64{"latitude": lat, "longitude": lon},
+	}}
+}
+
+// enginePrefix returns `propulsion.<instance>.` path prefix for engine PGNs
+func enginePrefix(fields nmea.FieldValues) (string, bool) {
+	instance, ok := fieldFloat(fields, "instance")
+	if !ok {
+		return "", false
+	}
+	return "propulsion." + strconv.Itoa(int(instance)) + ".", true
+}
+
+// values converts fields to path values in field order. Fields without value are skipped.
+func values(fields nmea.FieldValues, mappings map[string]mapping) []PathValue {
+	result := make([]PathValue, 0, len(mappings))
+	for _, f := range fields {
+		m, ok := mappings[f.ID]
+		if !ok {
+			continue
+		}
+		if v, ok := f.AsFloat64(); ok {
+			result = append(result, PathValue{Path: m.path, Value: v * m.scale})
+		}
+	}
+	return result
+}
+
+func fieldFloat(fields nmea.FieldValues, ID string) (float64, bool) {
+	f, ok := fields.FindByID(ID)
+	if !ok {
+		return 0, false
+	}
+	return f.AsFloat64()
+}
@@ -0,0 +1,213 @@
+package signalk
+
+import (
+	"bytes"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConverter_Convert(t *testing.T) {
+	now := time.Unix(1665488842, 0)
+
+	var testCases = []struct {
+		name        string
+		whenMessage nmea.Message
+		expect      []PathValue
+		expectFalse bool
+	}{
+		{
+			name: "ok, position",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 129025, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "latitude", Value: 58.5},
+					{ID: "longitude", Value: 23.5},
+				},
+			},
+			expect: []PathValue{
+				{Path: "navigation.position", Value: map[string]float64{"latitude": 58.5, "longitude": 23.5}},
+			},
+		},
+		{
+			name: "ok, GNSS position with altitude",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 129029, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "latitude", Value: 58.5},
+					{ID: "longitude", Value: 23.5},
+					{ID: "altitude", Value: 12.5},
+					{ID: "numberOfSvs", Value: uint64(9)},
+					{ID: "hdop", Value: 0.8},
+				},
+			},
+			expect: []PathValue{
+				{Path: "navigation.position", Value: map[string]float64{"latitude": 58.5, "longitude": 23.5, "altitude": 12.5}},
+				{Path: "navigation.gnss.satellites", Value: 9.0},
+				{Path: "navigation.gnss.horizontalDilution", Value: 0.8},
+			},
+		},
+		{
+			name: "ok, magnetic heading",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127250, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "sid", Value: uint64(1)},
+					{ID: "heading", Value: 1.5},
+					{ID: "reference", Value: nmea.EnumValue{Value: 1, Code: "Magnetic"}},
+				},
+			},
+			expect: []PathValue{{Path: "navigation.headingMagnetic", Value: 1.5}},
+		},
+		{
+			name: "ok, attitude",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127257, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "pitch", Value: -0.0905},
+					{ID: "roll", Value: -0.1556},
+				},
+			},
+			expect: []PathValue{
+				{Path: "navigation.attitude", Value: map[string]float64{"pitch": -0.0905, "roll": -0.1556}},
+			},
+		},
+		{
+			name: "ok, depth with keel offset",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 128267, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "depth", Value: 10.0},
+					{ID: "offset", Value: -1.5},
+				},
+			},
+			expect: []PathValue{
+				{Path: "environment.depth.belowTransducer", Value: 10.0},
+				{Path: "environment.depth.belowKeel", Value: 8.5},
+			},
+		},
+		{
+			name: "ok, engine rapid update",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127488, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "instance", Value: uint64(1)},
+					{ID: "speed", Value: 1500.0},
+					{ID: "boostPressure", Value: uint64(20000)},
+					{ID: "tiltTrim", Value: int64(-10)},
+				},
+			},
+			expect: []PathValue{
+				{Path: "propulsion.1.revolutions", Value: 25.0},
+				{Path: "propulsion.1.boostPressure", Value: 20000.0},
+				{Path: "propulsion.1.drive.trimState", Value: -0.1},
+			},
+		},
+		{
+			name: "ok, engine dynamic",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127489, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "instance", Value: nmea.EnumValue{Value: 0, Code: "Single Engine or Dual Engine Port"}},
+					{ID: "oilPressure", Value: uint64(300000)},
+					{ID: "temperature", Value: 353.15},
+					{ID: "alternatorPotential", Value: 14.2},
+					{ID: "fuelRate", Value: 3.6},
+					{ID: "totalEngineHours", Value: uint64(3600)},
+					{ID: "engineLoad", Value: int64(50)},
+				},
+			},
+			expect: []PathValue{
+				{Path: "propulsion.0.oilPressure", Value: 300000.0},
+				{Path: "propulsion.0.temperature", Value: 353.15},
+				{Path: "propulsion.0.alternatorVoltage", Value: 14.2},
+				{Path: "propulsion.0.fuel.rate", Value: 0.000001},
+				{Path: "propulsion.0.runTime", Value: 3600.0},
+				{Path: "propulsion.0.engineLoad", Value: 0.5},
+			},
+		},
+		{
+			name: "nok, engine without instance",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 127488, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "speed", Value: 1500.0},
+				},
+			},
+			expectFalse: true,
+		},
+		{
+			name: "nok, true wind is not converted",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 130306, Source: 3},
+				Fields: nmea.FieldValues{
+					{ID: "windSpeed", Value: 5.0},
+					{ID: "reference", Value: uint64(0)},
+				},
+			},
+			expectFalse: true,
+		},
+		{
+			name: "nok, unknown PGN",
+			whenMessage: nmea.Message{
+				Header: nmea.CanBusHeader{PGN: 60928, Source: 3},
+			},
+			expectFalse: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delta, ok := NewConverter(Config{}).Convert(tc.whenMessage, now)
+			if tc.expectFalse {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, "vessels.self", delta.Context)
+			if !assert.Len(t, delta.Updates, 1) {
+				return
+			}
+			assert.Equal(t, now.UTC(), delta.Updates[0].Timestamp)
+			assert.Equal(t, tc.whenMessage.Header.PGN, delta.Updates[0].Source.PGN)
+			assert.Equal(t, "3", delta.Updates[0].Source.Src)
+			if !assert.Len(t, delta.Updates[0].Values, len(tc.expect)) {
+				return
+			}
+			for i, e := range tc.expect {
+				actual := delta.Updates[0].Values[i]
+				assert.Equal(t, e.Path, actual.Path)
+				if f, ok := e.Value.(float64); ok {
+					assert.InDelta(t, f, actual.Value, 1e-9, e.Path)
+				} else {
+					assert.Equal(t, e.Value, actual.Value, e.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	assert.True(t, IsSupported(127488))
+	assert.False(t, IsSupported(60928))
+}
+
+func TestDeltaWriter_WriteMessage(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewDeltaWriter(buf, Config{Context: "vessels.urn:mrn:imo:mmsi:276810000", Label: "n2k"})
+
+	raw := nmea.RawMessage{Time: time.Unix(1665488842, 0)}
+	err := w.WriteMessage(raw, nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 128259, Source: 35},
+		Fields: nmea.FieldValues{{ID: "speedWaterReferenced", Value: 2.5}},
+	})
+	assert.NoError(t, err)
+
+	err = w.WriteMessage(raw, nmea.Message{Header: nmea.CanBusHeader{PGN: 60928, Source: 35}})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	expect := `{"context":"vessels.urn:mrn:imo:mmsi:276810000","updates":[{"source":{"label":"n2k","type":"NMEA2000","pgn":128259,"src":"35"},"timestamp":"2022-10-11T11:47:22Z","values":[{"path":"navigation.speedThroughWater","value":2.5}]}]}` + "\n"
+	assert.Equal(t, expect, buf.String())
+}
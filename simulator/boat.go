@@ -0,0 +1,187 @@
+// Package simulator generates plausible NMEA2000 bus traffic of a small motor boat (GNSS, heading, depth, wind and
+// engine sensors) so consumers can be integration tested without being near a boat. Simulated nodes claim their
+// addresses, send heartbeats and respond to ISO requests (address claim, product info) like real devices.
+package simulator
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	earthRadius = 6371000.0 // meters
+	kelvin      = 273.15
+)
+
+// BoatConfig configures simulated boat
+type BoatConfig struct {
+	// Latitude is starting latitude in degrees.
+	// Defaults to: 59.45 (Tallinn bay) when Latitude and Longitude are both 0
+	Latitude float64
+	// Longitude is starting longitude in degrees.
+	// Defaults to: 24.75 (Tallinn bay) when Latitude and Longitude are both 0
+	Longitude float64
+	// Course is base course in degrees. Boat slowly weaves around it.
+	Course float64
+	// Speed is average speed over ground in m/s.
+	// Defaults to: 3 m/s (~6 knots)
+	Speed float64
+	// Depth is average water depth in meters.
+	// Defaults to: 15 m
+	Depth float64
+	// EngineHours is total engine running time at start.
+	EngineHours time.Duration
+
+	// Seed is seed for random variations. Same seed results same values for same sequence of State calls.
+	Seed int64
+}
+
+// State is simulated boat state at given moment
+type State struct {
+	Time time.Time
+
+	// Latitude and Longitude are position in degrees
+	Latitude  float64
+	Longitude float64
+	// Heading is true heading in radians
+	Heading float64
+	// Pitch and Roll are attitude in radians
+	Pitch float64
+	Roll  float64
+	// COG is true course over ground in radians
+	COG float64
+	// SOG is speed over ground in m/s
+	SOG float64
+	// STW is speed through water in m/s
+	STW float64
+
+	// Depth is water depth below transducer in meters
+	Depth float64
+
+	// WindSpeed is apparent wind speed in m/s
+	WindSpeed float64
+	// WindAngle is apparent wind angle in radians relative to bow
+	WindAngle float64
+
+	// EngineSpeed is engine speed in RPM
+	EngineSpeed float64
+	// EngineTemperature is coolant temperature in Kelvins
+	EngineTemperature float64
+	// OilTemperature is oil temperature in Kelvins
+	OilTemperature float64
+	// OilPressure is oil pressure in Pascals
+	OilPressure float64
+	// AlternatorPotential is alternator voltage in Volts
+	AlternatorPotential float64
+	// FuelRate is fuel consumption rate in L/h
+	FuelRate float64
+	// EngineHours is total engine running time
+	EngineHours time.Duration
+}
+
+// Boat simulates boat moving and its sensor values changing smoothly over time. Values are sines with different periods
+// plus small random noise. Position is integrated from course and speed over ground.
+//
+// Boat is safe for concurrent use.
+type Boat struct {
+	config BoatConfig
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	started bool
+	start   time.Time
+	last    State
+}
+
+// NewBoat creates new instance of Boat
+func NewBoat(config BoatConfig) *Boat {
+	if config.Latitude == 0 && config.Longitude == 0 {
+		config.Latitude = 59.45
+		config.Longitude = 24.75
+	}
+	if config.Speed <= 0 {
+		config.Speed = 3
+	}
+	if config.Depth <= 0 {
+		config.Depth = 15
+	}
+	return &Boat{
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// State advances simulation to given time and returns boat state. Time going backwards does not move boat.
+func (b *Boat) State(now time.Time) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.started {
+		b.started = true
+		b.start = now
+		b.last = State{Time: now, Latitude: b.config.Latitude, Longitude: b.config.Longitude}
+	}
+	if now.Before(b.last.Time) {
+		now = b.last.Time
+	}
+	dt := now.Sub(b.last.Time).Seconds()
+	t := now.Sub(b.start).Seconds()
+	c := b.config
+
+	s := State{Time: now}
+	s.Heading = normalizeAngle(deg2rad(c.Course) + 0.35*wave(t, 420) + 0.05*wave(t, 37) + b.noise(0.005))
+	s.Roll = 0.08*wave(t, 6) + b.noise(0.005)
+	s.Pitch = 0.03*wave(t, 4.3) + b.noise(0.002)
+	s.COG = normalizeAngle(s.Heading + 0.03*wave(t, 200))
+	s.SOG = math.Max(0, c.Speed*(1+0.05*wave(t, 90))+b.noise(0.02))
+	s.STW = s.SOG * 0.95
+
+	// move from last position with last course and speed
+	distance := b.last.SOG * dt
+	lat := deg2rad(b.last.Latitude)
+	s.Latitude = b.last.Latitude + rad2deg(distance*math.Cos(b.last.COG)/earthRadius)
+	s.Longitude = b.last.Longitude + rad2deg(distance*math.Sin(b.last.COG)/(earthRadius*math.Cos(lat)))
+
+	s.Depth = math.Max(1, c.Depth*(1+0.4*wave(t, 600))+b.noise(0.05))
+
+	s.WindSpeed = math.Max(0, 7+2*wave(t, 180)+b.noise(0.3))
+	s.WindAngle = normalizeAngle(0.7 + 0.25*wave(t, 240) + b.noise(0.02))
+
+	s.EngineSpeed = c.Speed/3*2300 + 60*wave(t, 45) + b.noise(10)
+	s.EngineTemperature = kelvin + 82 + 1.5*wave(t, 300)
+	s.OilTemperature = kelvin + 95 + 2*wave(t, 330)
+	s.OilPressure = 250_000 + s.EngineSpeed*60 + b.noise(2000)
+	s.AlternatorPotential = 14.1 + b.noise(0.05)
+	s.FuelRate = s.EngineSpeed * 0.0018
+	s.EngineHours = c.EngineHours + now.Sub(b.start)
+
+	b.last = s
+	return s
+}
+
+func (b *Boat) noise(deviation float64) float64 {
+	return b.rand.NormFloat64() * deviation
+}
+
+// wave returns sine value (-1..1) with given period in seconds at time t
+func wave(t float64, period float64) float64 {
+	return math.Sin(2 * math.Pi * t / period)
+}
+
+func normalizeAngle(rad float64) float64 {
+	rad = math.Mod(rad, 2*math.Pi)
+	if rad < 0 {
+		rad += 2 * math.Pi
+	}
+	return rad
+}
+
+func deg2rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func rad2deg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,60 @@
+package simulator
+
+import (
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewBoat_defaults(t *testing.T) {
+	start := test_test.UTCTime(1665488842)
+	boat := NewBoat(BoatConfig{})
+
+	s := boat.State(start)
+	assert.Equal(t, start, s.Time)
+	assert.Equal(t, 59.45, s.Latitude)
+	assert.Equal(t, 24.75, s.Longitude)
+	assert.InDelta(t, 3, s.SOG, 0.2)
+	assert.InDelta(t, 15, s.Depth, 0.5)
+}
+
+func TestBoat_State_moves(t *testing.T) {
+	start := test_test.UTCTime(1665488842)
+	boat := NewBoat(BoatConfig{Latitude: 10, Longitude: 20, Course: 90, Speed: 5, Seed: 1})
+
+	first := boat.State(start)
+	s := first
+	for i := 1; i <= 100; i++ {
+		s = boat.State(start.Add(time.Duration(i) * 100 * time.Millisecond))
+	}
+
+	// 10 seconds eastwards with ~5 m/s is ~50 m, that is ~0.00045 degrees of longitude at latitude 10
+	assert.InDelta(t, 10, s.Latitude, 0.0002)
+	assert.InDelta(t, 20.00045, s.Longitude, 0.0001)
+	assert.InDelta(t, math.Pi/2, s.Heading, 0.5)
+	assert.Equal(t, 10*time.Second, s.EngineHours)
+
+	// time going backwards does not move boat
+	again := boat.State(start)
+	assert.Equal(t, s.Latitude, again.Latitude)
+	assert.Equal(t, s.Longitude, again.Longitude)
+}
+
+func TestBoat_State_sameSeedSameValues(t *testing.T) {
+	start := test_test.UTCTime(1665488842)
+	a := NewBoat(BoatConfig{Seed: 42})
+	b := NewBoat(BoatConfig{Seed: 42})
+
+	for i := 0; i < 10; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		assert.Equal(t, a.State(now), b.State(now))
+	}
+}
+
+func TestNormalizeAngle(t *testing.T) {
+	assert.InDelta(t, 0.5, normalizeAngle(0.5), 1e-9)
+	assert.InDelta(t, 2*math.Pi-0.5, normalizeAngle(-0.5), 1e-9)
+	assert.InDelta(t, 0.5, normalizeAngle(2*math.Pi+0.5), 1e-9)
+}
@@ -0,0 +1,248 @@
+package simulator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/addressmapper"
+	"math"
+	"time"
+)
+
+// PGNs sent by simulated nodes. Data layouts follow Canboat PGN definitions.
+const (
+	pgnSystemTime              = uint32(126992)
+	pgnVesselHeading           = uint32(127250)
+	pgnAttitude                = uint32(127257)
+	pgnEngineParametersRapid   = uint32(127488)
+	pgnEngineParametersDynamic = uint32(127489)
+	pgnSpeed                   = uint32(128259)
+	pgnWaterDepth              = uint32(128267)
+	pgnPositionRapidUpdate     = uint32(129025)
+	pgnCOGSOGRapidUpdate       = uint32(129026)
+	pgnGNSSPositionData        = uint32(129029)
+	pgnWindData                = uint32(130306)
+)
+
+const (
+	// manufacturerCode is manufacturer code in NAME of simulated nodes. Code from the end of the range is used so
+	// simulated nodes are not mistaken for devices of real manufacturers.
+	manufacturerCode    = 2046
+	industryGroupMarine = 4
+
+	nmea2000Version   = 2100   // 2.100
+	magneticVariation = 0.1658 // radians, ~9.5 degrees east
+)
+
+// BoatNodes creates nodes that send traffic of simulated boat: GNSS receiver, heading sensor, depth/speed transducer,
+// wind sensor and engine gateway. Every node sends also Heartbeat (PGN 126993). Nodes prefer addresses from
+// firstAddress onwards.
+func BoatNodes(boat *Boat, firstAddress uint8, seed int64) []Node {
+	nodes := []struct {
+		model    string
+		class    uint8
+		function uint8
+		messages []nmea.PeriodicMessage
+	}{
+		{
+			model: "GNSS receiver", class: 60, function: 145, // Navigation, Ownship Position (GNSS)
+			messages: []nmea.PeriodicMessage{
+				periodic(100*time.Millisecond, boat, positionRapidUpdate),
+				periodic(250*time.Millisecond, boat, cogSOGRapidUpdate),
+				periodic(time.Second, boat, gnssPositionData),
+				periodic(time.Second, boat, systemTime),
+			},
+		},
+		{
+			model: "Heading sensor", class: 60, function: 140, // Navigation, Ownship Attitude
+			messages: []nmea.PeriodicMessage{
+				periodic(100*time.Millisecond, boat, vesselHeading),
+				periodic(time.Second, boat, attitude),
+			},
+		},
+		{
+			model: "Depth transducer", class: 60, function: 130, // Navigation, Bottom Depth
+			messages: []nmea.PeriodicMessage{
+				periodic(time.Second, boat, waterDepth),
+				periodic(time.Second, boat, speed),
+			},
+		},
+		{
+			model: "Wind sensor", class: 85, function: 130, // External Environment, Atmospheric
+			messages: []nmea.PeriodicMessage{
+				periodic(100*time.Millisecond, boat, windData),
+			},
+		},
+		{
+			model: "Engine gateway", class: 50, function: 140, // Propulsion, Engine
+			messages: []nmea.PeriodicMessage{
+				periodic(100*time.Millisecond, boat, engineParametersRapid),
+				periodic(500*time.Millisecond, boat, engineParametersDynamic),
+			},
+		},
+	}
+
+	result := make([]Node, 0, len(nodes))
+	for i, n := range nodes {
+		uniqueNumber := uint32(seed+int64(i)) & 0x1FFFFF
+		result = append(result, Node{
+			Name: addressmapper.NodeName{
+				UniqueNumber:            uniqueNumber,
+				Manufacturer:            manufacturerCode,
+				DeviceFunction:          n.function,
+				DeviceClass:             n.class,
+				IndustryGroup:           industryGroupMarine,
+				ArbitraryAddressCapable: 1,
+			},
+			PreferredAddress: firstAddress + uint8(i),
+			ProductInfo: addressmapper.ProductInfo{
+				NMEA2000Version:     nmea2000Version,
+				ProductCode:         uint16(1000 + i),
+				ModelID:             "Simulated " + n.model,
+				SoftwareVersionCode: "go-nmea-client n2ksim",
+				ModelVersion:        "1.0",
+				ModelSerialCode:     fmt.Sprintf("%07d", uniqueNumber),
+				CertificationLevel:  1,
+				LoadEquivalency:     1,
+			},
+			Messages: append(n.messages, nmea.NewHeartbeatMessage(nmea.HeartbeatInterval)),
+		})
+	}
+	return result
+}
+
+type encodeFunc func(s State) nmea.RawMessage
+
+func periodic(interval time.Duration, boat *Boat, encode encodeFunc) nmea.PeriodicMessage {
+	return nmea.PeriodicMessage{
+		Interval: interval,
+		Create: func(now time.Time) nmea.RawMessage {
+			msg := encode(boat.State(now))
+			msg.Time = now
+			return msg
+		},
+	}
+}
+
+func newMessage(pgn uint32, priority uint8, data []byte) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{
+			PGN:         pgn,
+			Priority:    priority,
+			Source:      nmea.AddressNull,
+			Destination: nmea.AddressGlobal,
+		},
+		Data: data,
+	}
+}
+
+// sid is sequence identifier that ties together messages sent at the same moment (same second)
+func sid(s State) uint8 {
+	return uint8(s.Time.Unix() % 253)
+}
+
+func positionRapidUpdate(s State) nmea.RawMessage {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[0:], uint32(int32(math.Round(s.Latitude*1e7))))
+	binary.LittleEndian.PutUint32(data[4:], uint32(int32(math.Round(s.Longitude*1e7))))
+	return newMessage(pgnPositionRapidUpdate, 2, data)
+}
+
+func cogSOGRapidUpdate(s State) nmea.RawMessage {
+	data := []byte{sid(s), 0xFC, 0, 0, 0, 0, 0xFF, 0xFF} // COG reference: true
+	binary.LittleEndian.PutUint16(data[2:], uint16(math.Round(s.COG*1e4)))
+	binary.LittleEndian.PutUint16(data[4:], uint16(math.Round(s.SOG*100)))
+	return newMessage(pgnCOGSOGRapidUpdate, 2, data)
+}
+
+func gnssPositionData(s State) nmea.RawMessage {
+	utc := s.Time.UTC()
+	midnight := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+
+	data := make([]byte, 43)
+	data[0] = sid(s)
+	binary.LittleEndian.PutUint16(data[1:], uint16(utc.Unix()/86400))
+	binary.LittleEndian.PutUint32(data[3:], uint32(utc.Sub(midnight)/(100*time.Microsecond)))
+	binary.LittleEndian.PutUint64(data[7:], uint64(int64(s.Latitude*1e16)))
+	binary.LittleEndian.PutUint64(data[15:], uint64(int64(s.Longitude*1e16)))
+	binary.LittleEndian.PutUint64(data[23:], uint64(int64(2.5*1e6))) // altitude 2.5 m
+	data[31] = 0x10                                                  // GNSS type: GPS, method: GNSS fix
+	data[32] = 0xFC                                                  // integrity: no checking
+	data[33] = 10                                                    // number of satellites
+	binary.LittleEndian.PutUint16(data[34:], 80)                     // HDOP 0.8
+	binary.LittleEndian.PutUint16(data[36:], 140)                    // PDOP 1.4
+	binary.LittleEndian.PutUint32(data[38:], 1950)                   // geoidal separation 19.5 m
+	data[42] = 0                                                     // reference stations
+	return newMessage(pgnGNSSPositionData, 3, data)
+}
+
+func systemTime(s State) nmea.RawMessage {
+	utc := s.Time.UTC()
+	midnight := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+
+	data := []byte{sid(s), 0xF0, 0, 0, 0, 0, 0, 0} // source: GPS
+	binary.LittleEndian.PutUint16(data[2:], uint16(utc.Unix()/86400))
+	binary.LittleEndian.PutUint32(data[4:], uint32(utc.Sub(midnight)/(100*time.Microsecond)))
+	return newMessage(pgnSystemTime, 3, data)
+}
+
+func vesselHeading(s State) nmea.RawMessage {
+	data := []byte{sid(s), 0, 0, 0xFF, 0x7F, 0, 0, 0xFC} // deviation: n/a, reference: true
+	binary.LittleEndian.PutUint16(data[1:], uint16(math.Round(s.Heading*1e4)))
+	binary.LittleEndian.PutUint16(data[5:], uint16(int16(math.Round(magneticVariation*1e4))))
+	return newMessage(pgnVesselHeading, 2, data)
+}
+
+func attitude(s State) nmea.RawMessage {
+	yaw := s.Heading
+	if yaw > math.Pi {
+		yaw -= 2 * math.Pi
+	}
+	data := []byte{sid(s), 0, 0, 0, 0, 0, 0, 0xFF}
+	binary.LittleEndian.PutUint16(data[1:], uint16(int16(math.Round(yaw*1e4))))
+	binary.LittleEndian.PutUint16(data[3:], uint16(int16(math.Round(s.Pitch*1e4))))
+	binary.LittleEndian.PutUint16(data[5:], uint16(int16(math.Round(s.Roll*1e4))))
+	return newMessage(pgnAttitude, 3, data)
+}
+
+func waterDepth(s State) nmea.RawMessage {
+	data := []byte{sid(s), 0, 0, 0, 0, 0, 0, 0xFF} // range: n/a
+	binary.LittleEndian.PutUint32(data[1:], uint32(math.Round(s.Depth*100)))
+	binary.LittleEndian.PutUint16(data[5:], 400) // offset: transducer is 0.4 m below waterline
+	return newMessage(pgnWaterDepth, 3, data)
+}
+
+func speed(s State) nmea.RawMessage {
+	data := []byte{sid(s), 0, 0, 0xFF, 0xFF, 0, 0xF0, 0xFF} // ground referenced: n/a, type: paddle wheel
+	binary.LittleEndian.PutUint16(data[1:], uint16(math.Round(s.STW*100)))
+	return newMessage(pgnSpeed, 2, data)
+}
+
+func windData(s State) nmea.RawMessage {
+	data := []byte{sid(s), 0, 0, 0, 0, 0xFA, 0xFF, 0xFF} // reference: apparent
+	binary.LittleEndian.PutUint16(data[1:], uint16(math.Round(s.WindSpeed*100)))
+	binary.LittleEndian.PutUint16(data[3:], uint16(math.Round(s.WindAngle*1e4)))
+	return newMessage(pgnWindData, 2, data)
+}
+
+func engineParametersRapid(s State) nmea.RawMessage {
+	data := []byte{0, 0, 0, 0xFF, 0xFF, 0x7F, 0xFF, 0xFF} // instance: 0, boost pressure and tilt/trim: n/a
+	binary.LittleEndian.PutUint16(data[1:], uint16(math.Round(s.EngineSpeed*4)))
+	return newMessage(pgnEngineParametersRapid, 2, data)
+}
+
+func engineParametersDynamic(s State) nmea.RawMessage {
+	data := make([]byte, 26)
+	data[0] = 0 // instance
+	binary.LittleEndian.PutUint16(data[1:], uint16(math.Round(s.OilPressure/100)))
+	binary.LittleEndian.PutUint16(data[3:], uint16(math.Round(s.OilTemperature*10)))
+	binary.LittleEndian.PutUint16(data[5:], uint16(math.Round(s.EngineTemperature*100)))
+	binary.LittleEndian.PutUint16(data[7:], uint16(int16(math.Round(s.AlternatorPotential*100))))
+	binary.LittleEndian.PutUint16(data[9:], uint16(int16(math.Round(s.FuelRate*10))))
+	binary.LittleEndian.PutUint32(data[11:], uint32(s.EngineHours/time.Second))
+	copy(data[15:], []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF}) // coolant and fuel pressure: n/a, reserved
+	// discrete status 1 and 2 (bytes 20-23) are 0: no alarms
+	data[24] = uint8(int8(math.Round(s.EngineSpeed / 40))) // engine load %
+	data[25] = 0x7F                                        // engine torque: n/a
+	return newMessage(pgnEngineParametersDynamic, 2, data)
+}
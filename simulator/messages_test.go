@@ -0,0 +1,128 @@
+package simulator
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/canboat"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func decode(t *testing.T, schemaFile string, raw nmea.RawMessage) nmea.FieldValues {
+	pgn := canboat.PGN{}
+	test_test.LoadJSON(t, "../../canboat/testdata/"+schemaFile, &pgn)
+	decoded, err := canboat.NewDecoder(canboat.CanboatSchema{PGNs: canboat.PGNs{pgn}}).Decode(raw)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	return decoded.Fields
+}
+
+func assertField(t *testing.T, fields nmea.FieldValues, id string, expect interface{}) {
+	field, ok := fields.FindByID(id)
+	if !assert.True(t, ok, "field %v missing", id) {
+		return
+	}
+	if f, isFloat := expect.(float64); isFloat {
+		assert.InDelta(t, f, field.Value, 0.0001, id)
+		return
+	}
+	assert.Equal(t, expect, field.Value, id)
+}
+
+var testState = State{
+	Time:                test_test.UTCTime(1665488842), // Tue Oct 11 2022 11:47:22 GMT+0000
+	Latitude:            59.4512345,
+	Longitude:           24.7512345,
+	Heading:             4.0,
+	Pitch:               0.02,
+	Roll:                -0.05,
+	COG:                 4.01,
+	SOG:                 3.12,
+	STW:                 2.96,
+	Depth:               14.57,
+	WindSpeed:           7.25,
+	WindAngle:           0.72,
+	EngineSpeed:         2310,
+	EngineTemperature:   355.15,
+	OilTemperature:      368.2,
+	OilPressure:         388_600,
+	AlternatorPotential: 14.12,
+	FuelRate:            4.2,
+	EngineHours:         1234*time.Hour + 30*time.Minute,
+}
+
+func TestPositionRapidUpdate(t *testing.T) {
+	msg := positionRapidUpdate(testState)
+
+	assert.Equal(t, nmea.CanBusHeader{PGN: 129025, Priority: 2, Source: nmea.AddressNull, Destination: nmea.AddressGlobal}, msg.Header)
+	assert.Equal(t, nmea.RawData{0xd9, 0x89, 0x6f, 0x23, 0x19, 0xbd, 0xc0, 0x0e}, msg.Data)
+}
+
+func TestGNSSPositionData(t *testing.T) {
+	msg := gnssPositionData(testState)
+	assert.Len(t, msg.Data, 43)
+
+	fields := decode(t, "canboat_pgn_129029.json", msg)
+	assertField(t, fields, "date", time.Date(2022, time.October, 11, 0, 0, 0, 0, time.UTC))
+	assertField(t, fields, "time", 11*time.Hour+47*time.Minute+22*time.Second)
+	assertField(t, fields, "latitude", 59.4512345)
+	assertField(t, fields, "longitude", 24.7512345)
+	assertField(t, fields, "altitude", 2.5)
+	assertField(t, fields, "gnssType", uint64(0))
+	assertField(t, fields, "method", uint64(1))
+	assertField(t, fields, "numberOfSvs", uint64(10))
+	assertField(t, fields, "hdop", 0.8)
+	assertField(t, fields, "geoidalSeparation", 19.5)
+}
+
+func TestAttitude(t *testing.T) {
+	fields := decode(t, "canboat_pgn_127257.json", attitude(testState))
+
+	assertField(t, fields, "yaw", 4.0-2*math.Pi)
+	assertField(t, fields, "pitch", 0.02)
+	assertField(t, fields, "roll", -0.05)
+}
+
+func TestEngineParametersDynamic(t *testing.T) {
+	msg := engineParametersDynamic(testState)
+	assert.Len(t, msg.Data, 26)
+
+	fields := decode(t, "canboat_pgn_127489.json", msg)
+	assertField(t, fields, "oilPressure", 388_600.0)
+	assertField(t, fields, "oilTemperature", 368.2)
+	assertField(t, fields, "temperature", 355.15)
+	assertField(t, fields, "alternatorPotential", 14.12)
+	assertField(t, fields, "fuelRate", 4.2)
+	assertField(t, fields, "totalEngineHours", 1234*time.Hour+30*time.Minute)
+	assertField(t, fields, "engineLoad", int64(58))
+}
+
+func TestSingleFrameMessages(t *testing.T) {
+	for _, encode := range []encodeFunc{
+		cogSOGRapidUpdate, systemTime, vesselHeading, waterDepth, speed, windData, engineParametersRapid,
+	} {
+		msg := encode(testState)
+		assert.Len(t, msg.Data, 8, "PGN %v", msg.Header.PGN)
+	}
+}
+
+func TestBoatNodes(t *testing.T) {
+	nodes := BoatNodes(NewBoat(BoatConfig{}), 10, 100)
+
+	assert.Len(t, nodes, 5)
+	seen := map[uint64]bool{}
+	for i, n := range nodes {
+		assert.Equal(t, uint8(10+i), n.PreferredAddress)
+		assert.Equal(t, uint16(manufacturerCode), n.Name.Manufacturer)
+		assert.False(t, seen[n.Name.Uint64()], "NAMEs must be unique")
+		seen[n.Name.Uint64()] = true
+
+		last := n.Messages[len(n.Messages)-1]
+		assert.Equal(t, nmea.HeartbeatInterval, last.Interval)
+	}
+	assert.Equal(t, "Simulated GNSS receiver", nodes[0].ProductInfo.ModelID)
+	assert.Equal(t, "0000100", nodes[0].ProductInfo.ModelSerialCode)
+}
@@ -0,0 +1,183 @@
+package simulator
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/addressmapper"
+	"time"
+)
+
+// pgnISOAcknowledgement is ISO Acknowledgement (PGN 59392) sent as negative response to requests for PGNs node does
+// not support
+const pgnISOAcknowledgement = uint32(59392)
+
+// Node is simulated device on bus
+type Node struct {
+	// Name is NAME node claims its address with
+	Name addressmapper.NodeName
+	// PreferredAddress is source address node tries to claim first
+	PreferredAddress uint8
+	// ProductInfo is sent as response to ISO request for Product Information (PGN 126996)
+	ProductInfo addressmapper.ProductInfo
+	// Messages are periodically sent messages. Source address is set to claimed address of node.
+	Messages []nmea.PeriodicMessage
+}
+
+// Simulator is virtual bus with simulated nodes. Reading returns messages sent by nodes and writing delivers message
+// to nodes so they can respond to ISO requests (PGN 59904) and competing address claims (PGN 60928). Messages are not
+// split into frames - device messages are written to (i.e. socketcan.Device) splits Fast-Packet messages.
+//
+// Simulator implements nmea.RawMessageReaderWriter and is safe for concurrent use.
+type Simulator struct {
+	device *nmea.MockDevice
+	nodes  []*node
+
+	timeNow func() time.Time
+}
+
+type node struct {
+	Node
+	claimer *addressmapper.AddressClaimer
+}
+
+// emitter writes messages sent by nodes to simulator reading side
+type emitter struct {
+	simulator *Simulator
+}
+
+func (e emitter) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	e.simulator.device.Emit(msg)
+	return nil
+}
+
+func (e emitter) Close() error {
+	return nil
+}
+
+// New creates new instance of Simulator
+func New(nodes []Node) *Simulator {
+	s := &Simulator{timeNow: time.Now}
+
+	periodic := make([]nmea.PeriodicMessage, 0)
+	for _, n := range nodes {
+		sn := &node{Node: n}
+		sn.claimer = addressmapper.NewAddressClaimer(emitter{simulator: s}, addressmapper.AddressClaimConfig{
+			Name:             n.Name,
+			PreferredAddress: n.PreferredAddress,
+		})
+		for _, m := range n.Messages {
+			periodic = append(periodic, sn.withSource(m))
+		}
+		s.nodes = append(s.nodes, sn)
+	}
+	s.device = nmea.NewMockDevice(nmea.MockDeviceConfig{Periodic: periodic})
+	return s
+}
+
+// Initialize sends address claims of all nodes
+func (s *Simulator) Initialize() error {
+	for _, n := range s.nodes {
+		if err := n.claimer.Claim(context.Background()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRawMessage returns next message sent by simulated nodes. Blocks until next periodic message is due.
+func (s *Simulator) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	return s.device.ReadRawMessage(ctx)
+}
+
+// WriteRawMessage delivers message read from bus to simulated nodes. Nodes respond to ISO requests for address claim,
+// product info and PGNs they send periodically. Requests addressed to node for other PGNs are answered with negative
+// acknowledgement.
+func (s *Simulator) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	for _, n := range s.nodes {
+		if err := n.claimer.Process(ctx, msg); err != nil {
+			return err
+		}
+		if msg.Header.PGN != uint32(nmea.PGNISORequest) || len(msg.Data) < 3 {
+			continue
+		}
+		if response, ok := n.respond(msg, s.timeNow()); ok {
+			s.device.Emit(response)
+		}
+	}
+	return nil
+}
+
+// Close closes simulator. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
+func (s *Simulator) Close() error {
+	return s.device.Close()
+}
+
+// address returns claimed address of node. Before claim has completed preferred address is used.
+func (n *node) address() uint8 {
+	if address, ok := n.claimer.Address(); ok {
+		return address
+	}
+	return n.PreferredAddress
+}
+
+func (n *node) withSource(m nmea.PeriodicMessage) nmea.PeriodicMessage {
+	return nmea.PeriodicMessage{
+		Interval: m.Interval,
+		Create: func(now time.Time) nmea.RawMessage {
+			msg := m.Create(now)
+			msg.Header.Source = n.address()
+			return msg
+		},
+	}
+}
+
+func (n *node) respond(request nmea.RawMessage, now time.Time) (nmea.RawMessage, bool) {
+	address := n.address()
+	destination := request.Header.Destination
+	if destination != nmea.AddressGlobal && destination != address {
+		return nmea.RawMessage{}, false
+	}
+	requestedPGN := uint32(request.Data[0]) | uint32(request.Data[1])<<8 | uint32(request.Data[2])<<16
+
+	switch requestedPGN {
+	case uint32(nmea.PGNISOAddressClaim):
+		return nmea.RawMessage{}, false // handled by address claimer
+	case uint32(nmea.PGNProductInfo):
+		return nmea.RawMessage{
+			Time: now,
+			Header: nmea.CanBusHeader{
+				PGN:         requestedPGN,
+				Priority:    6,
+				Source:      address,
+				Destination: nmea.AddressGlobal,
+			},
+			Data: n.ProductInfo.Bytes(),
+		}, true
+	}
+	for _, m := range n.Messages {
+		msg := m.Create(now)
+		if msg.Header.PGN == requestedPGN {
+			msg.Time = now
+			msg.Header.Source = address
+			return msg, true
+		}
+	}
+	if destination == nmea.AddressGlobal {
+		return nmea.RawMessage{}, false
+	}
+	return nmea.RawMessage{
+		Time: now,
+		Header: nmea.CanBusHeader{
+			PGN:         pgnISOAcknowledgement,
+			Priority:    6,
+			Source:      address,
+			Destination: request.Header.Source,
+		},
+		Data: []byte{
+			1,                // control: NAK
+			0xFF,             // group function
+			0xFF, 0xFF, 0xFF, // reserved
+			request.Data[0], request.Data[1], request.Data[2],
+		},
+	}, true
+}
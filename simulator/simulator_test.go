@@ -0,0 +1,177 @@
+package simulator
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/addressmapper"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testSimulator() *Simulator {
+	now := test_test.UTCTime(1665488842)
+	s := New([]Node{
+		{
+			Name:             addressmapper.NodeName{UniqueNumber: 1, Manufacturer: manufacturerCode, IndustryGroup: 4},
+			PreferredAddress: 10,
+			ProductInfo:      addressmapper.ProductInfo{NMEA2000Version: 2100, ModelID: "Simulated"},
+			Messages: []nmea.PeriodicMessage{{Interval: time.Hour, Create: func(now time.Time) nmea.RawMessage {
+				return newMessage(pgnWaterDepth, 3, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+			}}},
+		},
+	})
+	s.timeNow = func() time.Time { return now }
+	return s
+}
+
+func readN(t *testing.T, s *Simulator, count int) []nmea.RawMessage {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result := make([]nmea.RawMessage, 0, count)
+	for i := 0; i < count; i++ {
+		msg, err := s.ReadRawMessage(ctx)
+		if !assert.NoError(t, err) {
+			break
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+func isoRequest(pgn nmea.PGN, destination uint8) nmea.RawMessage {
+	return nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNISORequest), Priority: 6, Source: 1, Destination: destination},
+		Data:   []byte{uint8(pgn), uint8(pgn >> 8), uint8(pgn >> 16)},
+	}
+}
+
+func TestSimulator_Initialize(t *testing.T) {
+	s := testSimulator()
+	defer s.Close()
+
+	assert.NoError(t, s.Initialize())
+	msgs := readN(t, s, 2)
+
+	assert.Equal(t, uint32(nmea.PGNISOAddressClaim), msgs[0].Header.PGN)
+	assert.Equal(t, uint8(10), msgs[0].Header.Source)
+	assert.Equal(t, pgnWaterDepth, msgs[1].Header.PGN)
+	assert.Equal(t, uint8(10), msgs[1].Header.Source)
+}
+
+func TestSimulator_WriteRawMessage(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        nmea.RawMessage
+		expectPGN   uint32
+		expectDst   uint8
+		expectData  []byte
+		expectNoMsg bool
+	}{
+		{
+			name:      "ok, address claim request",
+			when:      isoRequest(nmea.PGNISOAddressClaim, nmea.AddressGlobal),
+			expectPGN: uint32(nmea.PGNISOAddressClaim),
+			expectDst: nmea.AddressGlobal,
+		},
+		{
+			name:      "ok, product info request",
+			when:      isoRequest(nmea.PGNProductInfo, 10),
+			expectPGN: uint32(nmea.PGNProductInfo),
+			expectDst: nmea.AddressGlobal,
+		},
+		{
+			name:       "ok, request for periodic message",
+			when:       isoRequest(nmea.PGN(pgnWaterDepth), nmea.AddressGlobal),
+			expectPGN:  pgnWaterDepth,
+			expectDst:  nmea.AddressGlobal,
+			expectData: []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		},
+		{
+			name:       "ok, NAK for unsupported PGN addressed to node",
+			when:       isoRequest(nmea.PGNConfigurationInformation, 10),
+			expectPGN:  pgnISOAcknowledgement,
+			expectDst:  1,
+			expectData: []byte{1, 0xff, 0xff, 0xff, 0xff, 0x16, 0xf0, 0x01},
+		},
+		{
+			name:        "ok, unsupported PGN requested globally is ignored",
+			when:        isoRequest(nmea.PGNConfigurationInformation, nmea.AddressGlobal),
+			expectNoMsg: true,
+		},
+		{
+			name:        "ok, request to other node is ignored",
+			when:        isoRequest(nmea.PGNProductInfo, 11),
+			expectNoMsg: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSimulator()
+			defer s.Close()
+			assert.NoError(t, s.Initialize())
+			readN(t, s, 2) // address claim and first periodic message
+
+			assert.NoError(t, s.WriteRawMessage(context.Background(), tc.when))
+
+			if tc.expectNoMsg {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				_, err := s.ReadRawMessage(ctx)
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+				return
+			}
+			msgs := readN(t, s, 1)
+			if !assert.Len(t, msgs, 1) {
+				return
+			}
+			msg := msgs[0]
+			assert.Equal(t, tc.expectPGN, msg.Header.PGN)
+			assert.Equal(t, uint8(10), msg.Header.Source)
+			assert.Equal(t, tc.expectDst, msg.Header.Destination)
+			if tc.expectData != nil {
+				assert.Equal(t, nmea.RawData(tc.expectData), msg.Data)
+			}
+		})
+	}
+}
+
+func TestSimulator_productInfoResponse(t *testing.T) {
+	s := testSimulator()
+	defer s.Close()
+	assert.NoError(t, s.Initialize())
+	readN(t, s, 2)
+
+	assert.NoError(t, s.WriteRawMessage(context.Background(), isoRequest(nmea.PGNProductInfo, nmea.AddressGlobal)))
+	msgs := readN(t, s, 1)
+	if !assert.Len(t, msgs, 1) {
+		return
+	}
+
+	info, err := addressmapper.PGN126996ToProductInfo(msgs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "Simulated", info.ModelID)
+}
+
+func TestSimulator_competingAddressClaim(t *testing.T) {
+	s := testSimulator()
+	defer s.Close()
+	assert.NoError(t, s.Initialize())
+	readN(t, s, 2)
+
+	// node with lower NAME (higher priority) claims our address
+	claim := nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNISOAddressClaim), Source: 10, Destination: nmea.AddressGlobal},
+		Data:   addressmapper.NodeName{UniqueNumber: 0}.Bytes(),
+	}
+	assert.NoError(t, s.WriteRawMessage(context.Background(), claim))
+
+	msgs := readN(t, s, 1)
+	if !assert.Len(t, msgs, 1) {
+		return
+	}
+	assert.Equal(t, uint32(nmea.PGNISOAddressClaim), msgs[0].Header.PGN)
+	assert.Equal(t, nmea.AddressNull, msgs[0].Header.Source) // not arbitrary address capable, can not claim
+}
@@ -0,0 +1,183 @@
+// Package sink persists decoded messages into log files for long-term recording. Files are rotated by size and/or time
+// and can be gzip compressed so multi-day recordings stay manageable and queryable with ordinary tools (i.e. `zcat |
+// jq`).
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultFlushInterval is default interval how often buffered data is flushed to file
+	DefaultFlushInterval = 1 * time.Second
+)
+
+// FileConfig configures RotatingFile
+type FileConfig struct {
+	// Dir is directory where files are created
+	Dir string
+	// Prefix is start of file name. File names are `<prefix>_<start time><extension>`.
+	// Defaults to: `n2k`
+	Prefix string
+	// Extension is end of file name (i.e. `.jsonl`). `.gz` is added when Compress is set.
+	Extension string
+	// Compress enables gzip compression of files
+	Compress bool
+
+	// MaxFileSize is number of (uncompressed) bytes after which new file is started. Optional: 0 means no size limit.
+	MaxFileSize int64
+	// RotateInterval is time period after which new file is started. Periods are aligned to interval (i.e. 1h rotates
+	// at the start of every hour) and are based on time given to Write. Optional: 0 means no time based rotation.
+	RotateInterval time.Duration
+	// FlushInterval is interval how often buffered data is flushed to file so data is not lost when process is
+	// terminated. Note: flushing compressed stream often makes compression less effective.
+	// Defaults to: DefaultFlushInterval
+	FlushInterval time.Duration
+}
+
+// RotatingFile writes records into files that are rotated by size and/or time. Records are never split between files.
+//
+// Note: is not go-routine safe
+type RotatingFile struct {
+	config FileConfig
+
+	file      *os.File
+	gzip      *gzip.Writer
+	writer    *bufio.Writer
+	written   int64
+	until     time.Time
+	lastFlush time.Time
+}
+
+// NewRotatingFile creates new instance of RotatingFile. Files are created on first write.
+func NewRotatingFile(config FileConfig) *RotatingFile {
+	if config.Prefix == "" {
+		config.Prefix = "n2k"
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	return &RotatingFile{config: config}
+}
+
+// WriteRecord writes record (i.e. single JSON line) to current file. Time is used for time based rotation and file
+// naming.
+func (f *RotatingFile) WriteRecord(record []byte, now time.Time) error {
+	if f.file != nil && f.needsRotation(now) {
+		if err := f.closeFile(); err != nil {
+			return err
+		}
+	}
+	if f.file == nil {
+		if err := f.open(now); err != nil {
+			return err
+		}
+	}
+	n, err := f.writer.Write(record)
+	f.written += int64(n)
+	if err != nil {
+		return fmt.Errorf("sink failed to write record, err: %w", err)
+	}
+	if now.Sub(f.lastFlush) >= f.config.FlushInterval {
+		return f.flush(now)
+	}
+	return nil
+}
+
+func (f *RotatingFile) needsRotation(now time.Time) bool {
+	if f.config.MaxFileSize > 0 && f.written >= f.config.MaxFileSize {
+		return true
+	}
+	return !f.until.IsZero() && !now.Before(f.until)
+}
+
+func (f *RotatingFile) open(now time.Time) error {
+	start := now
+	f.until = time.Time{}
+	if f.config.RotateInterval > 0 {
+		start = now.Truncate(f.config.RotateInterval)
+		f.until = start.Add(f.config.RotateInterval)
+	}
+	extension := f.config.Extension
+	if f.config.Compress {
+		extension += ".gz"
+	}
+	file, err := createFile(f.config.Dir, f.config.Prefix+"_"+start.UTC().Format("20060102T150405"), extension)
+	if err != nil {
+		return err
+	}
+	f.file = file
+	f.written = 0
+	f.lastFlush = now
+
+	var w io.Writer = file
+	if f.config.Compress {
+		f.gzip = gzip.NewWriter(file)
+		w = f.gzip
+	}
+	f.writer = bufio.NewWriterSize(w, 64*1024)
+	return nil
+}
+
+// createFile creates new file. When file with same name exists (i.e. size rotation within same second) sequence
+// number is added to name.
+func createFile(dir string, base string, extension string) (*os.File, error) {
+	name := base + extension
+	for i := 1; ; i++ {
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("sink failed to create file, err: %w", err)
+		}
+		name = base + "_" + strconv.Itoa(i) + extension
+	}
+}
+
+func (f *RotatingFile) flush(now time.Time) error {
+	f.lastFlush = now
+	if err := f.writer.Flush(); err != nil {
+		return fmt.Errorf("sink failed to flush file, err: %w", err)
+	}
+	if f.gzip != nil {
+		if err := f.gzip.Flush(); err != nil {
+			return fmt.Errorf("sink failed to flush file, err: %w", err)
+		}
+	}
+	return nil
+}
+
+func (f *RotatingFile) closeFile() error {
+	err := f.writer.Flush()
+	if f.gzip != nil {
+		if gErr := f.gzip.Close(); err == nil {
+			err = gErr
+		}
+	}
+	if cErr := f.file.Close(); err == nil {
+		err = cErr
+	}
+	f.file = nil
+	f.gzip = nil
+	f.writer = nil
+	if err != nil {
+		return fmt.Errorf("sink failed to close file, err: %w", err)
+	}
+	return nil
+}
+
+// Close flushes buffered data and closes current file
+func (f *RotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.closeFile()
+}
@@ -0,0 +1,111 @@
+package sink
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	result := map[string]string{}
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		assert.NoError(t, err)
+		var r io.Reader = f
+		if filepath.Ext(e.Name()) == ".gz" {
+			gz, err := gzip.NewReader(f)
+			assert.NoError(t, err)
+			r = gz
+		}
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		f.Close()
+		result[e.Name()] = string(b)
+	}
+	return result
+}
+
+func TestRotatingFile_WriteRecord(t *testing.T) {
+	now := time.Unix(1665488842, 0)
+	var testCases = []struct {
+		name   string
+		config FileConfig
+		expect map[string]string
+	}{
+		{
+			name:   "ok, no rotation",
+			config: FileConfig{Extension: ".txt"},
+			expect: map[string]string{"n2k_20221011T114722.txt": "a\nb\nc\n"},
+		},
+		{
+			name:   "ok, rotate by size",
+			config: FileConfig{Prefix: "boat", Extension: ".txt", MaxFileSize: 4},
+			expect: map[string]string{
+				"boat_20221011T114722.txt": "a\nb\n",
+				"boat_20221011T114723.txt": "c\n",
+			},
+		},
+		{
+			name:   "ok, rotate by interval with compression",
+			config: FileConfig{Extension: ".txt", Compress: true, RotateInterval: time.Second},
+			expect: map[string]string{
+				"n2k_20221011T114722.txt.gz": "a\nb\n",
+				"n2k_20221011T114723.txt.gz": "c\n",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.config.Dir = dir
+			f := NewRotatingFile(tc.config)
+
+			assert.NoError(t, f.WriteRecord([]byte("a\n"), now))
+			assert.NoError(t, f.WriteRecord([]byte("b\n"), now.Add(500*time.Millisecond)))
+			assert.NoError(t, f.WriteRecord([]byte("c\n"), now.Add(1500*time.Millisecond)))
+			assert.NoError(t, f.Close())
+			assert.NoError(t, f.Close())
+
+			assert.Equal(t, tc.expect, readFiles(t, dir))
+		})
+	}
+}
+
+func TestRotatingFile_sameNameGetsSequence(t *testing.T) {
+	dir := t.TempDir()
+	f := NewRotatingFile(FileConfig{Dir: dir, Extension: ".txt", MaxFileSize: 1})
+
+	now := time.Unix(1665488842, 0)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, f.WriteRecord([]byte("a\n"), now))
+	}
+	assert.NoError(t, f.Close())
+
+	names := make([]string, 0)
+	for name := range readFiles(t, dir) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"n2k_20221011T114722.txt", "n2k_20221011T114722_1.txt", "n2k_20221011T114722_2.txt"}, names)
+}
+
+func TestRotatingFile_flushInterval(t *testing.T) {
+	dir := t.TempDir()
+	f := NewRotatingFile(FileConfig{Dir: dir, Extension: ".txt", FlushInterval: time.Second})
+
+	now := time.Unix(1665488842, 0)
+	assert.NoError(t, f.WriteRecord([]byte("a\n"), now))
+	assert.Equal(t, map[string]string{"n2k_20221011T114722.txt": ""}, readFiles(t, dir))
+
+	assert.NoError(t, f.WriteRecord([]byte("b\n"), now.Add(time.Second)))
+	assert.Equal(t, map[string]string{"n2k_20221011T114722.txt": "a\nb\n"}, readFiles(t, dir))
+
+	assert.NoError(t, f.Close())
+}
@@ -0,0 +1,46 @@
+package sink
+
+import (
+	"encoding/json"
+	"github.com/aldas/go-nmea-client"
+	"time"
+)
+
+// JSONLWriter writes decoded messages as newline delimited JSON (one JSON object per line) into rotated files.
+// Implements export.Writer.
+//
+// Note: is not go-routine safe
+type JSONLWriter struct {
+	file    *RotatingFile
+	timeNow func() time.Time
+}
+
+// NewJSONLWriter creates new instance of JSONLWriter. When config.Extension is empty `.jsonl` is used.
+func NewJSONLWriter(config FileConfig) *JSONLWriter {
+	if config.Extension == "" {
+		config.Extension = ".jsonl"
+	}
+	return &JSONLWriter{
+		file:    NewRotatingFile(config),
+		timeNow: time.Now,
+	}
+}
+
+// WriteMessage writes decoded message as single JSON line. Raw message time is used for rotation, messages without
+// time use current time.
+func (w *JSONLWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	now := raw.Time
+	if now.IsZero() {
+		now = w.timeNow()
+	}
+	return w.file.WriteRecord(append(b, '\n'), now)
+}
+
+// Close flushes buffered output and closes current file
+func (w *JSONLWriter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,27 @@
+package sink
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJSONLWriter_WriteMessage(t *testing.T) {
+	dir := t.TempDir()
+	w := NewJSONLWriter(FileConfig{Dir: dir, Compress: true})
+	w.timeNow = func() time.Time {
+		return time.Unix(1665488842, 0)
+	}
+
+	err := w.WriteMessage(nmea.RawMessage{}, nmea.Message{
+		Header: nmea.CanBusHeader{PGN: 127250, Source: 1},
+		Fields: nmea.FieldValues{{ID: "heading", Value: 1.5}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, map[string]string{
+		"n2k_20221011T114722.jsonl.gz": `{"node_name":0,"header":{"pgn":127250,"priority":0,"source":1,"destination":0},"fields":[{"id":"heading","type":"float64","value":1.5}]}` + "\n",
+	}, readFiles(t, dir))
+}
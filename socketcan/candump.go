@@ -0,0 +1,247 @@
+package socketcan
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCandumpWriteNotSupported is returned when writing to candump log reader
+var ErrCandumpWriteNotSupported = errors.New("candump log reader does not support writing")
+
+// CandumpConfig is configuration for CandumpReader
+type CandumpConfig struct {
+	// FastPacketAssembler assembles fast-packet PGN frames to complete messages. Use nmea.ISOTPAssembler wrapping
+	// nmea.FastPacketAssembler to assemble ISO 11783-3 transport protocol messages as well.
+	// Optional: if not set, messages are directly created out of frames with no assembly
+	FastPacketAssembler nmea.Assembler
+
+	// Realtime instructs reader to replay log in real time. Reads are delayed so that time between returned frames
+	// matches time between frames in log.
+	Realtime bool
+}
+
+// CandumpReader reads SocketCAN `candump -L` (or `candump -l` log file) format lines:
+// `(1620807235.135072) can0 09F8027F#00FCFFFF0000FFFF`
+//
+// Frames and messages have original timestamps from log. Only extended (29bit) data frames are returned. Standard
+// (11bit), remote request, error and CAN FD frames are skipped as they are not NMEA2000 frames.
+type CandumpReader struct {
+	reader  io.Reader
+	scanner *bufio.Scanner
+	config  CandumpConfig
+
+	timeNow func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	lineNumber int
+	// replayStart is local time when first frame was returned in realtime mode
+	replayStart time.Time
+	// firstFrameTime is log time of first frame
+	firstFrameTime time.Time
+
+	closed atomic.Bool
+}
+
+// NewCandumpReader creates new instance of CandumpReader
+func NewCandumpReader(reader io.Reader, config CandumpConfig) *CandumpReader {
+	return &CandumpReader{
+		reader:  reader,
+		scanner: bufio.NewScanner(reader),
+		config:  config,
+		timeNow: time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Initialize initializes reader. Log files need no initialization.
+func (r *CandumpReader) Initialize() error {
+	return nil
+}
+
+// Close closes underlying reader. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
+func (r *CandumpReader) Close() error {
+	r.closed.Store(true)
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// WriteRawMessage always returns ErrCandumpWriteNotSupported
+func (r *CandumpReader) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	return ErrCandumpWriteNotSupported
+}
+
+// ReadRawMessage reads next message from log. When CandumpConfig.FastPacketAssembler is set frames are assembled to
+// complete messages. Returns io.EOF when end of log is reached.
+func (r *CandumpReader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if r.config.FastPacketAssembler != nil {
+		msg := nmea.RawMessage{}
+		for {
+			frame, err := r.ReadRawFrame(ctx)
+			if err != nil {
+				return nmea.RawMessage{}, err
+			}
+			if r.config.FastPacketAssembler.Assemble(frame, &msg) {
+				return msg, nil
+			}
+		}
+	}
+
+	frame, err := r.ReadRawFrame(ctx)
+	if err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return nmea.RawMessage{
+		Time:   frame.Time,
+		Header: frame.Header,
+		Data:   frame.Data[:frame.Length],
+	}, nil
+}
+
+// ReadRawFrame reads next frame from log. Invalid lines are returned as errors so caller can decide to skip them and
+// continue reading. Returns io.EOF when end of log is reached.
+func (r *CandumpReader) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nmea.RawFrame{}, ctx.Err()
+		default:
+		}
+		if r.closed.Load() {
+			return nmea.RawFrame{}, nmea.ErrDeviceClosed
+		}
+		if !r.scanner.Scan() {
+			break
+		}
+		r.lineNumber++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		frame, skip, err := parseCandumpLine(line)
+		if err != nil {
+			return nmea.RawFrame{}, fmt.Errorf("candump line %v: %w", r.lineNumber, err)
+		}
+		if skip {
+			continue
+		}
+		if r.config.Realtime {
+			if err := r.waitFrameTime(ctx, frame.Time); err != nil {
+				return nmea.RawFrame{}, err
+			}
+		}
+		return frame, nil
+	}
+	if r.closed.Load() {
+		return nmea.RawFrame{}, nmea.ErrDeviceClosed
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nmea.RawFrame{}, err
+	}
+	return nmea.RawFrame{}, io.EOF
+}
+
+// waitFrameTime delays read until same amount of time has passed since first frame as in log
+func (r *CandumpReader) waitFrameTime(ctx context.Context, frameTime time.Time) error {
+	if r.replayStart.IsZero() {
+		r.replayStart = r.timeNow()
+		r.firstFrameTime = frameTime
+		return nil
+	}
+	delay := r.replayStart.Add(frameTime.Sub(r.firstFrameTime)).Sub(r.timeNow())
+	if delay <= 0 {
+		return nil
+	}
+	return r.sleep(ctx, delay)
+}
+
+// parseCandumpLine parses `candump -L` line. Returns true for frames that should be skipped (not extended data frames).
+func parseCandumpLine(line string) (nmea.RawFrame, bool, error) {
+	// Example: `(1620807235.135072) can0 09F8027F#00FCFFFF0000FFFF`
+	parts := strings.Fields(line)
+	if len(parts) < 3 || len(parts[0]) < 3 || parts[0][0] != '(' || parts[0][len(parts[0])-1] != ')' {
+		return nmea.RawFrame{}, false, errors.New("invalid candump log line")
+	}
+	t, err := parseCandumpTime(parts[0][1 : len(parts[0])-1])
+	if err != nil {
+		return nmea.RawFrame{}, false, err
+	}
+
+	idAndData := parts[2]
+	sepIdx := strings.IndexByte(idAndData, '#')
+	if sepIdx == -1 {
+		return nmea.RawFrame{}, false, errors.New("invalid candump log line, missing data separator")
+	}
+	rawID := idAndData[:sepIdx]
+	rawData := idAndData[sepIdx+1:]
+	if len(rawID) != 8 { // standard 11bit frame
+		return nmea.RawFrame{}, true, nil
+	}
+	if strings.HasPrefix(rawData, "#") || strings.HasPrefix(rawData, "R") { // CAN FD or remote request frame
+		return nmea.RawFrame{}, true, nil
+	}
+
+	canID, err := strconv.ParseUint(rawID, 16, 32)
+	if err != nil {
+		return nmea.RawFrame{}, false, fmt.Errorf("invalid candump CAN ID: %w", err)
+	}
+	if canID&uint64(canIDERRFlag|canIDRTRFlag) != 0 { // error frame
+		return nmea.RawFrame{}, true, nil
+	}
+	rawData = strings.ReplaceAll(rawData, ".", "") // bytes can be separated with dots (same as `cansend` input)
+	if len(rawData) > 16 || len(rawData)%2 != 0 {
+		return nmea.RawFrame{}, false, errors.New("invalid candump data length")
+	}
+
+	frame := nmea.RawFrame{
+		Time:   t,
+		Header: nmea.ParseCANID(uint32(canID)),
+		Length: uint8(len(rawData) / 2),
+	}
+	if _, err := hex.Decode(frame.Data[:], []byte(rawData)); err != nil {
+		return nmea.RawFrame{}, false, fmt.Errorf("invalid candump data: %w", err)
+	}
+	return frame, false, nil
+}
+
+// parseCandumpTime parses `seconds.microseconds` unix timestamp
+func parseCandumpTime(raw string) (time.Time, error) {
+	secPart, fracPart, _ := strings.Cut(raw, ".")
+	sec, err := strconv.ParseInt(secPart, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid candump timestamp: %v", raw)
+	}
+	nsec := int64(0)
+	if fracPart != "" {
+		if len(fracPart) > 9 {
+			fracPart = fracPart[:9]
+		}
+		nsec, err = strconv.ParseInt(fracPart+strings.Repeat("0", 9-len(fracPart)), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid candump timestamp: %v", raw)
+		}
+	}
+	return time.Unix(sec, nsec).UTC(), nil
+}
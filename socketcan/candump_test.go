@@ -0,0 +1,200 @@
+package socketcan
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCandumpLine(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        string
+		expect      nmea.RawFrame
+		expectSkip  bool
+		expectError string
+	}{
+		{
+			name: "ok",
+			when: "(1620807235.135072) can0 09F8027F#00FCFFFF0000FFFF",
+			expect: nmea.RawFrame{
+				Time:   time.Unix(1620807235, 135072000).UTC(),
+				Header: nmea.CanBusHeader{PGN: 129026, Priority: 2, Source: 127, Destination: 255},
+				Length: 8,
+				Data:   [8]byte{0x00, 0xFC, 0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF},
+			},
+		},
+		{
+			name: "ok, short frame with dot separators",
+			when: "(1620807235.1) vcan0 18EAFFFE#00.EE.00",
+			expect: nmea.RawFrame{
+				Time:   time.Unix(1620807235, 100000000).UTC(),
+				Header: nmea.CanBusHeader{PGN: 59904, Priority: 6, Source: 254, Destination: 255},
+				Length: 3,
+				Data:   [8]byte{0x00, 0xEE, 0x00},
+			},
+		},
+		{
+			name:       "ok, standard frame is skipped",
+			when:       "(1620807235.135072) can0 123#DEADBEEF",
+			expectSkip: true,
+		},
+		{
+			name:       "ok, remote request frame is skipped",
+			when:       "(1620807235.135072) can0 18EAFFFE#R",
+			expectSkip: true,
+		},
+		{
+			name:       "ok, CAN FD frame is skipped",
+			when:       "(1620807235.135072) can0 18EAFFFE##100EE00",
+			expectSkip: true,
+		},
+		{
+			name:       "ok, error frame is skipped",
+			when:       "(1620807235.135072) can0 20000080#0000000000000000",
+			expectSkip: true,
+		},
+		{
+			name:        "nok, default candump format",
+			when:        "can0  15FD0617   [8]  01 9D 76 FF FF FF FF FF",
+			expectError: "invalid candump log line",
+		},
+		{
+			name:        "nok, invalid timestamp",
+			when:        "(16208072x5.135072) can0 09F8027F#00FCFFFF0000FFFF",
+			expectError: "invalid candump timestamp: 16208072x5.135072",
+		},
+		{
+			name:        "nok, missing data separator",
+			when:        "(1620807235.135072) can0 09F8027F00FCFFFF0000FFFF",
+			expectError: "invalid candump log line, missing data separator",
+		},
+		{
+			name:        "nok, invalid data length",
+			when:        "(1620807235.135072) can0 09F8027F#00FCFFFF0000FFFF00",
+			expectError: "invalid candump data length",
+		},
+		{
+			name:        "nok, invalid data",
+			when:        "(1620807235.135072) can0 09F8027F#00FCFFFF0000FFFX",
+			expectError: "invalid candump data: encoding/hex: invalid byte: U+0058 'X'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, skip, err := parseCandumpLine(tc.when)
+
+			assert.Equal(t, tc.expect, result)
+			assert.Equal(t, tc.expectSkip, skip)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCandumpReader_ReadRawMessage(t *testing.T) {
+	f, err := os.Open("testdata/candump_L.log")
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader := NewCandumpReader(f, CandumpConfig{})
+	defer reader.Close()
+
+	msg, err := reader.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:   time.Unix(1676903040, 575159000).UTC(),
+		Header: nmea.CanBusHeader{PGN: 130312, Priority: 5, Source: 23, Destination: 255},
+		Data:   []byte{0x01, 0x01, 0x04, 0x85, 0x7A, 0xFF, 0xFF, 0xFF},
+	}, msg)
+
+	count := 1
+	for {
+		_, err := reader.ReadRawMessage(context.Background())
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, 7, count)
+}
+
+func TestCandumpReader_ReadRawMessage_fastPacket(t *testing.T) {
+	f, err := os.Open("testdata/fast_packet_pgn129029.txt")
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader := NewCandumpReader(f, CandumpConfig{
+		FastPacketAssembler: nmea.NewFastPacketAssembler([]uint32{129029}),
+	})
+	defer reader.Close()
+
+	msg, err := reader.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1620807235, 740687000).UTC(), msg.Time)
+	assert.Equal(t, nmea.CanBusHeader{PGN: 129029, Priority: 3, Source: 127, Destination: 255}, msg.Header)
+	assert.Len(t, msg.Data, 43)
+	assert.Equal(t, nmea.RawData{0x00, 0x47, 0x49, 0xA0, 0x08, 0xAA, 0x11, 0x00}, msg.Data[0:8])
+
+	_, err = reader.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestCandumpReader_ReadRawFrame_realtime(t *testing.T) {
+	input := "(1620807235.000000) can0 09F8027F#00FCFFFF0000FFFF\n" +
+		"(1620807235.100000) can0 09F8027F#00FCFFFF0000FFFF\n" +
+		"(1620807235.500000) can0 09F8027F#00FCFFFF0000FFFF\n"
+	reader := NewCandumpReader(strings.NewReader(input), CandumpConfig{Realtime: true})
+
+	now := time.Unix(1700000000, 0)
+	reader.timeNow = func() time.Time { return now }
+	sleeps := make([]time.Duration, 0)
+	reader.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := reader.ReadRawFrame(context.Background())
+		assert.NoError(t, err)
+	}
+	now = now.Add(50 * time.Millisecond) // processing took time
+	frame, err := reader.ReadRawFrame(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1620807235, 500000000).UTC(), frame.Time) // original time is kept
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 350 * time.Millisecond}, sleeps)
+}
+
+func TestCandumpReader_ReadRawFrame_realtimeCancelled(t *testing.T) {
+	input := "(1620807235.000000) can0 09F8027F#00FCFFFF0000FFFF\n" +
+		"(1620807245.000000) can0 09F8027F#00FCFFFF0000FFFF\n"
+	reader := NewCandumpReader(strings.NewReader(input), CandumpConfig{Realtime: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := reader.ReadRawFrame(ctx)
+	assert.NoError(t, err)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = reader.ReadRawFrame(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCandumpReader_ReadRawFrame_error(t *testing.T) {
+	reader := NewCandumpReader(strings.NewReader("# comment\n(1620807235.135072) can0 09F8027F#XX\n"), CandumpConfig{})
+
+	_, err := reader.ReadRawFrame(context.Background())
+	assert.EqualError(t, err, "candump line 2: invalid candump data: encoding/hex: invalid byte: U+0058 'X'")
+
+	assert.ErrorIs(t, reader.WriteRawMessage(context.Background(), nmea.RawMessage{}), ErrCandumpWriteNotSupported)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"github.com/aldas/go-nmea-client"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,15 +21,45 @@ type DeviceConfig struct {
 	// Defaults to: 5 seconds
 	ReceiveDataTimeout time.Duration
 
-	// FastPacketAssembler assembles fast-packet PGN frames to complete messages.
+	// FastPacketAssembler assembles fast-packet PGN frames to complete messages. Use nmea.ISOTPAssembler wrapping
+	// nmea.FastPacketAssembler to assemble ISO 11783-3 transport protocol messages as well.
 	// Optional: if not set, messages are directly created out of frames with no assembly
 	FastPacketAssembler nmea.Assembler
+
+	// FastPacketSplitter splits written messages to frames.
+	// Optional: defaults to splitter that splits messages longer than 8 bytes into fast-packet frames
+	FastPacketSplitter nmea.Splitter
+
+	// ISOTPSender sends long messages with ISO 11783-3 transport protocol. Handshake frames are received by reading
+	// device, so addressed transfers require reading device concurrently with writing.
+	// Optional: when not set messages are split into fast-packet frames
+	ISOTPSender *nmea.ISOTPSender
+
+	// Filters are kernel level CAN ID filters applied to socket so frames not matching any of the filters do not reach
+	// userspace. See AllowListFilters to create filters from PGN and source address allow-lists.
+	// Optional: when empty all frames are received
+	Filters []Filter
+
+	// CANFD opens socket in CAN-FD mode. CAN-FD frames with more than 8 bytes of data are returned as messages without
+	// fast-packet assembly. Written messages are still sent as classic CAN frames, use Connection.SendFDFrame to send
+	// CAN-FD frames. Interface must be configured in CAN-FD mode.
+	CANFD bool
+
+	// OnErrorFrame is called with bus error frames (error counters, error passive and bus-off states) read from
+	// interface. Useful to detect and report bus health problems.
+	// Optional: when not set socket is not subscribed to error frames
+	OnErrorFrame func(frame ErrorFrame)
 }
 
 type Device struct {
 	conn    *Connection
 	config  DeviceConfig
 	timeNow func() time.Time
+
+	// connLock is held (read lock) while socket is used for reading or writing so Close does not close socket file
+	// descriptor in the middle of the operation (and operation does not touch other socket reusing same fd number).
+	connLock sync.RWMutex
+	closed   atomic.Bool
 }
 
 func NewDevice(config DeviceConfig) *Device {
@@ -37,6 +69,9 @@ func NewDevice(config DeviceConfig) *Device {
 	if config.ReceiveDataTimeout <= 0 {
 		config.ReceiveDataTimeout = 5 * time.Second
 	}
+	if config.FastPacketSplitter == nil {
+		config.FastPacketSplitter = nmea.NewFastPacketSplitter(nil)
+	}
 
 	return &Device{
 		conn:    nil,
@@ -45,7 +80,17 @@ func NewDevice(config DeviceConfig) *Device {
 	}
 }
 
+// Close closes device. Blocked ReadRawMessage call is unblocked (within read timeout of 50ms) with
+// nmea.ErrDeviceClosed error. Close waits until read or written frame in progress is done before closing socket.
 func (d *Device) Close() error {
+	if d.closed.Swap(true) {
+		return nil
+	}
+	d.connLock.Lock()
+	defer d.connLock.Unlock()
+	if d.conn == nil {
+		return nil
+	}
 	return d.conn.Close()
 }
 
@@ -54,55 +99,177 @@ func (d *Device) Initialize() error {
 	if err != nil {
 		return err
 	}
+	if err := d.configure(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
 	d.conn = conn
 
 	return nil
 }
 
+func (d *Device) configure(conn *Connection) error {
+	if err := conn.SetFilters(d.config.Filters); err != nil {
+		return err
+	}
+	if d.config.CANFD {
+		if err := conn.EnableFDFrames(); err != nil {
+			return err
+		}
+	}
+	if d.config.OnErrorFrame != nil {
+		if err := conn.EnableErrorFrames(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRawMessage writes message to bus. Messages longer than 8 bytes (and fast-packet PGNs known to
+// DeviceConfig.FastPacketSplitter) are written as multiple fast-packet frames. Messages that DeviceConfig.ISOTPSender
+// considers transport protocol messages are written with ISO 11783-3 transport protocol.
 func (d *Device) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
-	return errors.New("not implemented") // FIXME: implement
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
+	if d.conn == nil {
+		return errors.New("device is not initialized")
+	}
+	if d.config.ISOTPSender != nil && d.config.ISOTPSender.IsTransportMessage(msg) {
+		return d.config.ISOTPSender.Send(ctx, msg, d.sendFrame)
+	}
+	frames, err := d.config.FastPacketSplitter.Split(msg)
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := d.sendFrame(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Device) sendFrame(frame nmea.RawFrame) error {
+	d.connLock.RLock()
+	defer d.connLock.RUnlock()
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
+	return d.conn.SendFrame(frame)
 }
 
 func (d *Device) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
 	msg := nmea.RawMessage{}
+	for {
+		fdFrame, err := d.readFDFrame(ctx)
+		if err != nil {
+			return nmea.RawMessage{}, err
+		}
+		if fdFrame.Length > 8 {
+			return nmea.RawMessage{
+				Time:   fdFrame.Time,
+				Header: fdFrame.Header,
+				Data:   append(nmea.RawData(nil), fdFrame.Data[:fdFrame.Length]...),
+			}, nil
+		}
+		frame := d.toRawFrame(fdFrame)
+		if d.config.FastPacketAssembler != nil {
+			if d.config.FastPacketAssembler.Assemble(frame, &msg) {
+				return msg, nil
+			}
+			continue
+		}
+
+		return nmea.RawMessage{
+			Time:   frame.Time,
+			Header: frame.Header,
+			Data:   frame.Data[:],
+		}, nil
+	}
+}
+
+// ReadRawFrame reads next classic CAN frame from bus. CAN-FD frames with more than 8 bytes of data result
+// nmea.ErrNotRawFrame error.
+func (d *Device) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	fdFrame, err := d.readFDFrame(ctx)
+	if err != nil {
+		return nmea.RawFrame{}, err
+	}
+	if fdFrame.Length > 8 {
+		return nmea.RawFrame{}, nmea.ErrNotRawFrame
+	}
+	return d.toRawFrame(fdFrame), nil
+}
+
+func (d *Device) toRawFrame(fdFrame FDFrame) nmea.RawFrame {
+	frame := nmea.RawFrame{
+		Time:   fdFrame.Time,
+		Header: fdFrame.Header,
+		Length: fdFrame.Length,
+	}
+	copy(frame.Data[:], fdFrame.Data[:frame.Length])
+
+	if d.config.ISOTPSender != nil {
+		d.config.ISOTPSender.HandleFrame(frame)
+	}
+	return frame
+}
+
+// readFDFrame reads next CAN or CAN-FD frame. Error frames are given to DeviceConfig.OnErrorFrame.
+func (d *Device) readFDFrame(ctx context.Context) (FDFrame, error) {
 	start := d.timeNow()
 	for {
 		select {
 		case <-ctx.Done():
-			return nmea.RawMessage{}, ctx.Err()
+			return FDFrame{}, ctx.Err()
 		default:
 		}
-
-		if err := d.conn.SetReadTimeout(50 * time.Millisecond); err != nil { // max 50ms block time for read per iteration
-			return nmea.RawMessage{}, err
+		fdFrame, err := d.readFDFrameOnce()
+		if errors.Is(err, nmea.ErrDeviceClosed) {
+			return FDFrame{}, err
 		}
-		frame, err := d.conn.ReadRawFrame()
 
 		now := d.timeNow()
 		// on read errors we do not return immediately as for:
 		// os.ErrDeadlineExceeded - we set new deadline on next iteration
-		// io.EOF - we check if already read + received is enough to form complete message
 		if err != nil {
 			if errors.Is(err, errReadTimeout) {
 				if now.Sub(start) > d.config.ReceiveDataTimeout {
-					return nmea.RawMessage{}, err
+					return FDFrame{}, err
 				}
 				continue
 			}
-			return nmea.RawMessage{}, err
-		}
-
-		if d.config.FastPacketAssembler != nil {
-			if d.config.FastPacketAssembler.Assemble(frame, &msg) {
-				return msg, err
+			var errorFrame ErrorFrame
+			if d.config.OnErrorFrame != nil && errors.As(err, &errorFrame) {
+				d.config.OnErrorFrame(errorFrame)
+				continue
 			}
-			continue
+			return FDFrame{}, err
 		}
+		return fdFrame, nil
+	}
+}
 
-		return nmea.RawMessage{
-			Time:   frame.Time,
-			Header: frame.Header,
-			Data:   frame.Data[:],
-		}, nil
+// readFDFrameOnce reads frame blocking at most 50ms. Socket is not closed by Close during read.
+func (d *Device) readFDFrameOnce() (FDFrame, error) {
+	d.connLock.RLock()
+	defer d.connLock.RUnlock()
+	if d.closed.Load() {
+		return FDFrame{}, nmea.ErrDeviceClosed
+	}
+
+	if err := d.conn.SetReadTimeout(50 * time.Millisecond); err != nil { // max 50ms block time for read per iteration
+		return FDFrame{}, err
+	}
+	fdFrame, err := d.conn.ReadFDFrame()
+	if err != nil && d.closed.Load() {
+		return FDFrame{}, nmea.ErrDeviceClosed
 	}
+	return fdFrame, err
 }
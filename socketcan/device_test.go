@@ -2,9 +2,13 @@ package socketcan
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"github.com/aldas/go-nmea-client"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
 	"testing"
+	"time"
 )
 
 // sudo ip link set can0 down && sudo /sbin/ip link set can0 up type can bitrate 250000
@@ -43,3 +47,251 @@ func xTestName2(t *testing.T) {
 		fmt.Printf("frame: %+v\n", f)
 	}
 }
+
+func TestDevice_WriteRawMessage(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer unix.Close(fds[1])
+
+	dev := NewDevice(DeviceConfig{})
+	dev.conn = &Connection{socketFD: fds[0]}
+	defer dev.Close()
+
+	header := nmea.CanBusHeader{PGN: 129029, Priority: 3, Source: 10, Destination: 255}
+	data := make([]byte, 43)
+	for i := range data {
+		data[i] = uint8(i)
+	}
+	err = dev.WriteRawMessage(context.Background(), nmea.RawMessage{Header: header, Data: data})
+	assert.NoError(t, err)
+
+	result := make([]byte, 0, len(data))
+	canFrame := make([]byte, 16)
+	for nr := 0; nr < 7; nr++ { // 6 + 6*7 bytes
+		n, err := unix.Read(fds[1], canFrame)
+		if !assert.NoError(t, err) || !assert.Equal(t, 16, n) {
+			return
+		}
+		assert.Equal(t, header.Uint32()|canIDEFFFlag, binary.LittleEndian.Uint32(canFrame[0:4]))
+		assert.Equal(t, uint8(8), canFrame[4])
+		assert.Equal(t, uint8(nr), canFrame[8])
+		if nr == 0 {
+			assert.Equal(t, uint8(43), canFrame[9])
+			result = append(result, canFrame[10:16]...)
+		} else {
+			result = append(result, canFrame[9:16]...)
+		}
+	}
+	assert.Equal(t, data, result[:len(data)])
+}
+
+func TestDevice_WriteRawMessage_notInitialized(t *testing.T) {
+	dev := NewDevice(DeviceConfig{})
+
+	err := dev.WriteRawMessage(context.Background(), nmea.RawMessage{Data: []byte{1}})
+	assert.EqualError(t, err, "device is not initialized")
+
+	dev.Close()
+	err = dev.WriteRawMessage(context.Background(), nmea.RawMessage{Data: []byte{1}})
+	assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+}
+
+func TestDevice_Close_duringRead(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer unix.Close(fds[1])
+
+	dev := NewDevice(DeviceConfig{})
+	dev.conn = &Connection{socketFD: fds[0], timeNow: time.Now}
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := dev.ReadRawMessage(context.Background())
+		readErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond) // let read block on socket
+
+	// Close waits for read in progress so socket is closed only after read has stopped using it
+	assert.NoError(t, dev.Close())
+
+	select {
+	case err := <-readErr:
+		assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+	case <-time.After(1 * time.Second):
+		t.Fatal("read was not unblocked by Close")
+	}
+	_, err = unix.Write(fds[1], make([]byte, canMTU))
+	assert.Error(t, err) // other end of socket pair is closed
+}
+
+func TestDevice_ReadRawMessage_fdAndErrorFrames(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer unix.Close(fds[1])
+
+	errorFrames := make([]ErrorFrame, 0)
+	dev := NewDevice(DeviceConfig{
+		CANFD: true,
+		OnErrorFrame: func(frame ErrorFrame) {
+			errorFrames = append(errorFrames, frame)
+		},
+	})
+	now := time.Unix(1665488842, 0)
+	dev.conn = &Connection{socketFD: fds[0], timeNow: func() time.Time { return now }}
+	defer dev.Close()
+
+	header := nmea.CanBusHeader{PGN: 127250, Priority: 2, Source: 24, Destination: 255}
+
+	classic := make([]byte, canMTU)
+	binary.LittleEndian.PutUint32(classic[0:4], canIDHeading127250Src24)
+	classic[4] = 8
+	copy(classic[8:], []byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	errFrame := make([]byte, canMTU)
+	binary.LittleEndian.PutUint32(errFrame[0:4], uint32(ErrorClassController|ErrorClassCounters)|canIDERRFlag)
+	errFrame[4] = 8
+	errFrame[9] = uint8(ControllerTXPassive)
+	errFrame[14] = 130
+	errFrame[15] = 5
+
+	fd := make([]byte, canFDMTU)
+	binary.LittleEndian.PutUint32(fd[0:4], canIDHeading127250Src24)
+	fd[4] = 12
+	fd[5] = 0x01
+	copy(fd[8:], []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+
+	for _, b := range [][]byte{classic, errFrame, fd} {
+		_, err := unix.Write(fds[1], b)
+		assert.NoError(t, err)
+	}
+
+	msg, err := dev.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{Time: now, Header: header, Data: nmea.RawData{1, 2, 3, 4, 5, 6, 7, 8}}, msg)
+
+	msg, err = dev.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{Time: now, Header: header, Data: nmea.RawData{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}}, msg)
+
+	if assert.Len(t, errorFrames, 1) {
+		assert.Equal(t, "CAN error frame: controller, controller: tx-passive, tx errors: 130, rx errors: 5", errorFrames[0].Error())
+		assert.Equal(t, now, errorFrames[0].Time)
+	}
+}
+
+func TestDevice_ReadRawFrame(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer unix.Close(fds[1])
+
+	dev := NewDevice(DeviceConfig{
+		CANFD:               true,
+		FastPacketAssembler: nmea.NewFastPacketAssembler([]uint32{127250}), // is not used for frame reads
+	})
+	now := time.Unix(1665488842, 0)
+	dev.conn = &Connection{socketFD: fds[0], timeNow: func() time.Time { return now }}
+	defer dev.Close()
+
+	classic := make([]byte, canMTU)
+	binary.LittleEndian.PutUint32(classic[0:4], canIDHeading127250Src24)
+	classic[4] = 3
+	copy(classic[8:], []byte{1, 2, 3})
+
+	fd := make([]byte, canFDMTU)
+	binary.LittleEndian.PutUint32(fd[0:4], canIDHeading127250Src24)
+	fd[4] = 12
+
+	for _, b := range [][]byte{classic, fd} {
+		_, err := unix.Write(fds[1], b)
+		assert.NoError(t, err)
+	}
+
+	frame, err := dev.ReadRawFrame(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawFrame{
+		Time:   now,
+		Header: nmea.CanBusHeader{PGN: 127250, Priority: 2, Source: 24, Destination: 255},
+		Length: 3,
+		Data:   [8]byte{1, 2, 3},
+	}, frame)
+
+	_, err = dev.ReadRawFrame(context.Background())
+	assert.ErrorIs(t, err, nmea.ErrNotRawFrame)
+}
+
+func TestConnection_ReadRawFrame_errors(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		when        []byte
+		expectError string
+	}{
+		{
+			name:        "nok, error frame",
+			when:        []byte{0x40, 0, 0, 0x20, 8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			expectError: "CAN error frame: bus-off",
+		},
+		{
+			name:        "nok, remote transmission request",
+			when:        []byte{0, 0, 0, 0xC0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			expectError: "read CAN remote transmission request frame",
+		},
+		{
+			name:        "nok, CAN-FD frame longer than 8 bytes",
+			when:        append([]byte{0, 0, 0, 0x80, 9}, make([]byte, canFDMTU-5)...),
+			expectError: "read CAN-FD frame longer than 8 bytes",
+		},
+		{
+			name:        "nok, invalid frame size",
+			when:        []byte{0, 0, 0, 0x80, 1, 0, 0, 0, 1},
+			expectError: "read CAN frame with invalid size: 9",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer unix.Close(fds[0])
+			defer unix.Close(fds[1])
+			conn := Connection{socketFD: fds[0], timeNow: time.Now}
+
+			_, err = unix.Write(fds[1], tc.when)
+			assert.NoError(t, err)
+
+			_, err = conn.ReadRawFrame()
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestConnection_SendFDFrame(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+	conn := Connection{socketFD: fds[0], timeNow: time.Now}
+
+	frame := FDFrame{Header: nmea.CanBusHeader{PGN: 127250, Priority: 2, Source: 24}, Flags: 0x01, Length: 12}
+	copy(frame.Data[:], []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	assert.NoError(t, conn.SendFDFrame(frame))
+
+	b := make([]byte, 100)
+	n, err := unix.Read(fds[1], b)
+	assert.NoError(t, err)
+	assert.Equal(t, canFDMTU, n)
+	assert.Equal(t, []byte{0x18, 0x12, 0xF1, 0x89, 12, 0x01, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, b[:20])
+
+	frame.Length = 65
+	assert.EqualError(t, conn.SendFDFrame(frame), "CAN-FD frame data is too long: 65")
+}
@@ -0,0 +1,155 @@
+package socketcan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrorClass is error class bit of CAN error frame (CAN_ERR_* flags in CAN ID, see linux/can/error.h)
+type ErrorClass uint32
+
+const (
+	// ErrorClassTXTimeout means transmission timed out
+	ErrorClassTXTimeout = ErrorClass(0x001)
+	// ErrorClassLostArbitration means arbitration was lost. Data[0] contains bit number.
+	ErrorClassLostArbitration = ErrorClass(0x002)
+	// ErrorClassController means controller problems. Data[1] contains ControllerState flags.
+	ErrorClassController = ErrorClass(0x004)
+	// ErrorClassProtocol means protocol violation. Data[2] contains error type and Data[3] location.
+	ErrorClassProtocol = ErrorClass(0x008)
+	// ErrorClassTransceiver means transceiver status problems. Data[4] contains status.
+	ErrorClassTransceiver = ErrorClass(0x010)
+	// ErrorClassNoAck means no ACK was received for transmitted frame (i.e. no other devices on bus)
+	ErrorClassNoAck = ErrorClass(0x020)
+	// ErrorClassBusOff means controller went to bus-off state and does not take part in bus traffic
+	ErrorClassBusOff = ErrorClass(0x040)
+	// ErrorClassBusError means bus error (may flood the socket)
+	ErrorClassBusError = ErrorClass(0x080)
+	// ErrorClassRestarted means controller was restarted after bus-off
+	ErrorClassRestarted = ErrorClass(0x100)
+	// ErrorClassCounters means TX and RX error counters in Data[6] and Data[7] are valid
+	ErrorClassCounters = ErrorClass(0x200)
+)
+
+var errorClassNames = []struct {
+	class ErrorClass
+	name  string
+}{
+	{class: ErrorClassTXTimeout, name: "tx-timeout"},
+	{class: ErrorClassLostArbitration, name: "lost-arbitration"},
+	{class: ErrorClassController, name: "controller"},
+	{class: ErrorClassProtocol, name: "protocol"},
+	{class: ErrorClassTransceiver, name: "transceiver"},
+	{class: ErrorClassNoAck, name: "no-ack"},
+	{class: ErrorClassBusOff, name: "bus-off"},
+	{class: ErrorClassBusError, name: "bus-error"},
+	{class: ErrorClassRestarted, name: "restarted"},
+}
+
+// ControllerState is controller status flags of CAN error frame (Data[1], CAN_ERR_CRTL_* in linux/can/error.h)
+type ControllerState uint8
+
+const (
+	// ControllerRXOverflow means RX buffer overflow
+	ControllerRXOverflow = ControllerState(0x01)
+	// ControllerTXOverflow means TX buffer overflow
+	ControllerTXOverflow = ControllerState(0x02)
+	// ControllerRXWarning means RX error counter reached warning level (> 96)
+	ControllerRXWarning = ControllerState(0x04)
+	// ControllerTXWarning means TX error counter reached warning level (> 96)
+	ControllerTXWarning = ControllerState(0x08)
+	// ControllerRXPassive means RX error counter reached error passive level (> 127)
+	ControllerRXPassive = ControllerState(0x10)
+	// ControllerTXPassive means TX error counter reached error passive level (> 127)
+	ControllerTXPassive = ControllerState(0x20)
+	// ControllerActive means controller recovered to error active state
+	ControllerActive = ControllerState(0x40)
+)
+
+var controllerStateNames = []struct {
+	state ControllerState
+	name  string
+}{
+	{state: ControllerRXOverflow, name: "rx-overflow"},
+	{state: ControllerTXOverflow, name: "tx-overflow"},
+	{state: ControllerRXWarning, name: "rx-warning"},
+	{state: ControllerTXWarning, name: "tx-warning"},
+	{state: ControllerRXPassive, name: "rx-passive"},
+	{state: ControllerTXPassive, name: "tx-passive"},
+	{state: ControllerActive, name: "active"},
+}
+
+// ErrorFrame is bus error reported by SocketCAN driver as error frame. ErrorFrame implements error interface so
+// Connection.ReadRawFrame returns it as error value.
+type ErrorFrame struct {
+	// Time is when error frame was read
+	Time time.Time
+	// Class is error class bits
+	Class ErrorClass
+	// Data is error class specific data
+	Data [8]byte
+}
+
+func newErrorFrame(t time.Time, canID uint32, data []byte) ErrorFrame {
+	f := ErrorFrame{
+		Time:  t,
+		Class: ErrorClass(canID & canErrClassMask),
+	}
+	copy(f.Data[:], data)
+	return f
+}
+
+// Has checks if error frame has given error class bit set
+func (f ErrorFrame) Has(class ErrorClass) bool {
+	return f.Class&class != 0
+}
+
+// IsBusOff checks if controller went to bus-off state
+func (f ErrorFrame) IsBusOff() bool {
+	return f.Has(ErrorClassBusOff)
+}
+
+// ControllerState returns controller state flags. Valid when frame has ErrorClassController class.
+func (f ErrorFrame) ControllerState() ControllerState {
+	if !f.Has(ErrorClassController) {
+		return 0
+	}
+	return ControllerState(f.Data[1])
+}
+
+// ErrorCounters returns transmit and receive error counters. Counters are valid (ok is true) when frame has
+// ErrorClassCounters class.
+func (f ErrorFrame) ErrorCounters() (tx uint8, rx uint8, ok bool) {
+	if !f.Has(ErrorClassCounters) {
+		return 0, 0, false
+	}
+	return f.Data[6], f.Data[7], true
+}
+
+// Error returns human readable description of error frame
+func (f ErrorFrame) Error() string {
+	classes := make([]string, 0, 2)
+	for _, c := range errorClassNames {
+		if f.Has(c.class) {
+			classes = append(classes, c.name)
+		}
+	}
+	sb := strings.Builder{}
+	sb.WriteString("CAN error frame: ")
+	sb.WriteString(strings.Join(classes, ","))
+	if state := f.ControllerState(); state != 0 {
+		states := make([]string, 0, 2)
+		for _, s := range controllerStateNames {
+			if state&s.state != 0 {
+				states = append(states, s.name)
+			}
+		}
+		sb.WriteString(", controller: ")
+		sb.WriteString(strings.Join(states, ","))
+	}
+	if tx, rx, ok := f.ErrorCounters(); ok {
+		sb.WriteString(fmt.Sprintf(", tx errors: %v, rx errors: %v", tx, rx))
+	}
+	return sb.String()
+}
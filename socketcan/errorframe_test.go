@@ -0,0 +1,57 @@
+package socketcan
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestErrorFrame(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		when            ErrorFrame
+		expectBusOff    bool
+		expectState     ControllerState
+		expectCounters  bool
+		expectTX        uint8
+		expectRX        uint8
+		expectErrString string
+	}{
+		{
+			name:            "ok, bus-off",
+			when:            ErrorFrame{Class: ErrorClassBusOff},
+			expectBusOff:    true,
+			expectErrString: "CAN error frame: bus-off",
+		},
+		{
+			name: "ok, controller warning with counters",
+			when: ErrorFrame{
+				Class: ErrorClassController | ErrorClassCounters,
+				Data:  [8]byte{0, uint8(ControllerRXWarning | ControllerTXWarning), 0, 0, 0, 0, 97, 98},
+			},
+			expectState:     ControllerRXWarning | ControllerTXWarning,
+			expectCounters:  true,
+			expectTX:        97,
+			expectRX:        98,
+			expectErrString: "CAN error frame: controller, controller: rx-warning,tx-warning, tx errors: 97, rx errors: 98",
+		},
+		{
+			name: "ok, controller state ignored without controller class",
+			when: ErrorFrame{
+				Class: ErrorClassNoAck | ErrorClassBusError,
+				Data:  [8]byte{0, uint8(ControllerTXPassive)},
+			},
+			expectErrString: "CAN error frame: no-ack,bus-error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectBusOff, tc.when.IsBusOff())
+			assert.Equal(t, tc.expectState, tc.when.ControllerState())
+			tx, rx, ok := tc.when.ErrorCounters()
+			assert.Equal(t, tc.expectCounters, ok)
+			assert.Equal(t, tc.expectTX, tx)
+			assert.Equal(t, tc.expectRX, rx)
+			assert.EqualError(t, tc.when, tc.expectErrString)
+		})
+	}
+}
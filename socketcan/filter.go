@@ -0,0 +1,81 @@
+package socketcan
+
+import "github.com/aldas/go-nmea-client"
+
+// MaxFilters is maximum number of filters kernel accepts for single socket (CAN_RAW_FILTER_MAX)
+const MaxFilters = 512
+
+const (
+	// filterPDU1PGNMask selects DP, R and PF bits (16-25) of CAN ID. PS bits are part of PGN only for PDU2 (PF >= 240)
+	// messages, for PDU1 messages PS is destination address.
+	filterPDU1PGNMask = uint32(0x3FF) << 16
+	// filterPDU2PGNMask selects DP, R, PF and PS bits (8-25) of CAN ID.
+	filterPDU2PGNMask = uint32(0x3FFFF) << 8
+	// filterSourceMask selects source address bits (0-7) of CAN ID.
+	filterSourceMask = uint32(0xFF)
+	// filterFrameMask makes filter to match only extended data frames (EFF flag set, RTR flag not set)
+	filterFrameMask = canIDEFFFlag | canIDRTRFlag
+)
+
+// Filter is SocketCAN kernel level CAN ID filter. Kernel delivers received frame to socket when
+// `frameCanID & Mask == ID & Mask` is true for any of the socket filters. Frames that do not match are dropped by
+// kernel and do not reach userspace which reduces load on high-traffic buses.
+type Filter struct {
+	ID   uint32
+	Mask uint32
+}
+
+// PGNFilter creates filter that matches frames of given PGN from any source.
+func PGNFilter(pgn uint32) Filter {
+	mask := filterPDU2PGNMask
+	if nmea.IsPDU1PGN(pgn) { // PDU1 message, PS byte is destination address
+		mask = filterPDU1PGNMask
+	}
+	return Filter{
+		ID:   (pgn<<8)&mask | canIDEFFFlag,
+		Mask: mask | filterFrameMask,
+	}
+}
+
+// SourceFilter creates filter that matches frames of any PGN from given source address.
+func SourceFilter(source uint8) Filter {
+	return Filter{
+		ID:   uint32(source) | canIDEFFFlag,
+		Mask: filterSourceMask | filterFrameMask,
+	}
+}
+
+// PGNSourceFilter creates filter that matches frames of given PGN from given source address.
+func PGNSourceFilter(pgn uint32, source uint8) Filter {
+	f := PGNFilter(pgn)
+	f.ID |= uint32(source)
+	f.Mask |= filterSourceMask
+	return f
+}
+
+// AllowListFilters creates filters that match frames having any of given PGNs and any of given sources. When one of
+// the lists is empty only other list is used. When both lists are empty no filters are created (all frames are
+// received).
+//
+// Note: ISO 11783-3 transport protocol frames are sent with their own PGNs (60416 TP.CM and 60160 TP.DT) so these
+// PGNs need to be added to allow-list to receive multi-packet messages.
+func AllowListFilters(pgns []uint32, sources []uint8) []Filter {
+	result := make([]Filter, 0, len(pgns)*len(sources)+len(pgns)+len(sources))
+	switch {
+	case len(pgns) > 0 && len(sources) > 0:
+		for _, pgn := range pgns {
+			for _, src := range sources {
+				result = append(result, PGNSourceFilter(pgn, src))
+			}
+		}
+	case len(pgns) > 0:
+		for _, pgn := range pgns {
+			result = append(result, PGNFilter(pgn))
+		}
+	default:
+		for _, src := range sources {
+			result = append(result, SourceFilter(src))
+		}
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package socketcan
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	canIDHeading127250Src24    = uint32(0x09F11218) | canIDEFFFlag // prio 2, PGN 127250 (PDU2), src 24
+	canIDHeading127250Src25    = uint32(0x09F11219) | canIDEFFFlag // prio 2, PGN 127250 (PDU2), src 25
+	canIDRudder127245Src24     = uint32(0x09F10D18) | canIDEFFFlag // prio 2, PGN 127245 (PDU2), src 24
+	canIDISORequestToAllSrc0   = uint32(0x18EAFF00) | canIDEFFFlag // prio 6, PGN 59904 (PDU1), dst 255, src 0
+	canIDISORequestToSrc24Src0 = uint32(0x18EA1800) | canIDEFFFlag // prio 6, PGN 59904 (PDU1), dst 24, src 0
+)
+
+func filterMatches(f Filter, canID uint32) bool {
+	return canID&f.Mask == f.ID&f.Mask
+}
+
+func TestFilters(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		filter    Filter
+		expect    Filter
+		matches   []uint32
+		noMatches []uint32
+	}{
+		{
+			name:      "ok, PDU2 PGN",
+			filter:    PGNFilter(127250),
+			expect:    Filter{ID: 0x81F11200, Mask: 0xC3FFFF00},
+			matches:   []uint32{canIDHeading127250Src24, canIDHeading127250Src25},
+			noMatches: []uint32{canIDRudder127245Src24, canIDISORequestToAllSrc0, canIDHeading127250Src24 &^ canIDEFFFlag},
+		},
+		{
+			name:      "ok, PDU1 PGN matches any destination",
+			filter:    PGNFilter(59904),
+			expect:    Filter{ID: 0x80EA0000, Mask: 0xC3FF0000},
+			matches:   []uint32{canIDISORequestToAllSrc0, canIDISORequestToSrc24Src0},
+			noMatches: []uint32{canIDHeading127250Src24, canIDISORequestToAllSrc0 | canIDRTRFlag},
+		},
+		{
+			name:      "ok, source",
+			filter:    SourceFilter(24),
+			expect:    Filter{ID: 0x80000018, Mask: 0xC00000FF},
+			matches:   []uint32{canIDHeading127250Src24, canIDRudder127245Src24},
+			noMatches: []uint32{canIDHeading127250Src25, canIDISORequestToSrc24Src0},
+		},
+		{
+			name:      "ok, PGN and source",
+			filter:    PGNSourceFilter(127250, 25),
+			expect:    Filter{ID: 0x81F11219, Mask: 0xC3FFFFFF},
+			matches:   []uint32{canIDHeading127250Src25},
+			noMatches: []uint32{canIDHeading127250Src24, canIDRudder127245Src24},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.filter)
+			for _, canID := range tc.matches {
+				assert.True(t, filterMatches(tc.filter, canID), "expected to match %#x", canID)
+			}
+			for _, canID := range tc.noMatches {
+				assert.False(t, filterMatches(tc.filter, canID), "expected not to match %#x", canID)
+			}
+		})
+	}
+}
+
+func TestAllowListFilters(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		pgns    []uint32
+		sources []uint8
+		expect  []Filter
+	}{
+		{
+			name:    "ok, PGNs and sources",
+			pgns:    []uint32{127250, 59904},
+			sources: []uint8{24, 25},
+			expect: []Filter{
+				PGNSourceFilter(127250, 24),
+				PGNSourceFilter(127250, 25),
+				PGNSourceFilter(59904, 24),
+				PGNSourceFilter(59904, 25),
+			},
+		},
+		{
+			name:   "ok, only PGNs",
+			pgns:   []uint32{127250},
+			expect: []Filter{PGNFilter(127250)},
+		},
+		{
+			name:    "ok, only sources",
+			sources: []uint8{24},
+			expect:  []Filter{SourceFilter(24)},
+		},
+		{
+			name:   "ok, empty lists",
+			expect: []Filter{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, AllowListFilters(tc.pgns, tc.sources))
+		})
+	}
+}
+
+func TestConnection_SetFilters(t *testing.T) {
+	conn := Connection{socketFD: -1}
+
+	assert.NoError(t, conn.SetFilters(nil))
+
+	err := conn.SetFilters(make([]Filter, MaxFilters+1))
+	assert.EqualError(t, err, "too many CAN filters, max 512, got: 513")
+}
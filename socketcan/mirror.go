@@ -0,0 +1,228 @@
+package socketcan
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"sync"
+	"time"
+)
+
+// ErrMirrorSameInterface is returned when mirror source and destination interfaces are same. Mirroring frames into same
+// interface they were read from would retransmit every frame endlessly.
+var ErrMirrorSameInterface = errors.New("mirror source and destination interface can not be same")
+
+// MirrorConfig is configuration for Mirror.
+type MirrorConfig struct {
+	// SourceInterface is SocketCAN interface name where frames are read from.
+	// Defaults to: can0
+	SourceInterface string
+
+	// DestinationInterface is SocketCAN interface name where read frames are retransmitted to.
+	// Defaults to: vcan0
+	DestinationInterface string
+
+	// LoopWindow is time window in which frame read from source that is identical (CAN ID and data) to the frame we
+	// just transmitted to destination is considered as echo and is not retransmitted again. This prevents endless loops
+	// when destination is bridged back to source (i.e. by `cangw`). Frame that comes back from loop arrives practically
+	// immediately, so window should be smaller than the shortest transmission interval of identical frames on the bus.
+	// Negative value disables loop prevention.
+	// Defaults to: 2 milliseconds
+	LoopWindow time.Duration
+
+	// RateInterval is length of interval over which frame rate is calculated for MirrorStats.
+	// Defaults to: 1 second
+	RateInterval time.Duration
+}
+
+// MirrorStats contains statistics of mirrored frames.
+type MirrorStats struct {
+	// Received is number of frames read from source interface
+	Received uint64
+	// Forwarded is number of frames successfully transmitted to destination interface
+	Forwarded uint64
+	// LoopDropped is number of frames that were not transmitted as they were considered as echo of our own transmission
+	LoopDropped uint64
+	// Errors is number of frames that failed to be transmitted to destination interface
+	Errors uint64
+
+	// Started is time when mirroring was started
+	Started time.Time
+	// FramesPerSecond is rate of forwarded frames during last completed rate interval
+	FramesPerSecond float64
+	// AverageFramesPerSecond is rate of forwarded frames since mirroring was started
+	AverageFramesPerSecond float64
+}
+
+type frameConn interface {
+	ReadRawFrame() (nmea.RawFrame, error)
+	SendFrame(raw nmea.RawFrame) error
+	SetReadTimeout(timeout time.Duration) error
+	Close() error
+}
+
+type mirrorFrameKey struct {
+	canID  uint32
+	length uint8
+	data   [8]byte
+}
+
+// Mirror retransmits every frame received on one SocketCAN interface to another interface preserving CAN IDs. This
+// emulates hardware tap and is useful to feed other CAN tools (i.e. can-utils, canboat) with same traffic in parallel
+// on virtual interface (vcan0).
+type Mirror struct {
+	config MirrorConfig
+
+	source      frameConn
+	destination frameConn
+	timeNow     func() time.Time
+
+	mu sync.Mutex
+	// sent holds times when frames were last transmitted to destination. Used to detect echoes coming back to source.
+	sent        map[mirrorFrameKey]time.Time
+	stats       MirrorStats
+	rateStart   time.Time
+	rateCounter uint64
+}
+
+// NewMirror creates new instance of Mirror.
+func NewMirror(config MirrorConfig) *Mirror {
+	if config.SourceInterface == "" {
+		config.SourceInterface = "can0"
+	}
+	if config.DestinationInterface == "" {
+		config.DestinationInterface = "vcan0"
+	}
+	if config.LoopWindow == 0 {
+		config.LoopWindow = 2 * time.Millisecond
+	}
+	if config.RateInterval <= 0 {
+		config.RateInterval = 1 * time.Second
+	}
+	return &Mirror{
+		config:  config,
+		timeNow: time.Now,
+		sent:    map[mirrorFrameKey]time.Time{},
+	}
+}
+
+// Initialize opens connections to source and destination interfaces.
+func (m *Mirror) Initialize() error {
+	if m.config.SourceInterface == m.config.DestinationInterface {
+		return ErrMirrorSameInterface
+	}
+	source, err := NewConnection(m.config.SourceInterface)
+	if err != nil {
+		return err
+	}
+	destination, err := NewConnection(m.config.DestinationInterface)
+	if err != nil {
+		source.Close()
+		return err
+	}
+	m.source = source
+	m.destination = destination
+	return nil
+}
+
+// Close closes connections to source and destination interfaces.
+func (m *Mirror) Close() error {
+	var err error
+	if m.source != nil {
+		err = m.source.Close()
+	}
+	if m.destination != nil {
+		if dErr := m.destination.Close(); err == nil {
+			err = dErr
+		}
+	}
+	return err
+}
+
+// Run reads frames from source interface and retransmits them to destination interface until context is cancelled or
+// reading fails.
+func (m *Mirror) Run(ctx context.Context) error {
+	if m.source == nil || m.destination == nil {
+		return errors.New("mirror is not initialized")
+	}
+	m.mu.Lock()
+	m.stats.Started = m.timeNow()
+	m.rateStart = m.stats.Started
+	m.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := m.source.SetReadTimeout(50 * time.Millisecond); err != nil { // max 50ms block time for read per iteration
+			return err
+		}
+		frame, err := m.source.ReadRawFrame()
+		if err != nil {
+			if errors.Is(err, errReadTimeout) {
+				continue
+			}
+			return err
+		}
+		m.forward(frame)
+	}
+}
+
+func (m *Mirror) forward(frame nmea.RawFrame) {
+	now := m.timeNow()
+	key := mirrorFrameKey{canID: frame.Header.Uint32(), length: frame.Length, data: frame.Data}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Received++
+	if m.config.LoopWindow > 0 {
+		if sentAt, ok := m.sent[key]; ok && now.Sub(sentAt) <= m.config.LoopWindow {
+			delete(m.sent, key)
+			m.stats.LoopDropped++
+			return
+		}
+	}
+
+	if err := m.destination.SendFrame(frame); err != nil {
+		m.stats.Errors++
+		return
+	}
+	m.stats.Forwarded++
+	m.rateCounter++
+
+	if m.config.LoopWindow > 0 {
+		m.sent[key] = now
+		if len(m.sent) > 1024 {
+			m.cleanupSent(now)
+		}
+	}
+	if elapsed := now.Sub(m.rateStart); elapsed >= m.config.RateInterval {
+		m.stats.FramesPerSecond = float64(m.rateCounter) / elapsed.Seconds()
+		m.rateCounter = 0
+		m.rateStart = now
+	}
+}
+
+func (m *Mirror) cleanupSent(now time.Time) {
+	for k, t := range m.sent {
+		if now.Sub(t) > m.config.LoopWindow {
+			delete(m.sent, k)
+		}
+	}
+}
+
+// Stats returns current mirroring statistics.
+func (m *Mirror) Stats() MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	if elapsed := m.timeNow().Sub(stats.Started); !stats.Started.IsZero() && elapsed > 0 {
+		stats.AverageFramesPerSecond = float64(stats.Forwarded) / elapsed.Seconds()
+	}
+	return stats
+}
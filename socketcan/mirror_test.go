@@ -0,0 +1,130 @@
+package socketcan
+
+import (
+	"context"
+	"errors"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeFrameConn struct {
+	frames []nmea.RawFrame
+	sent   []nmea.RawFrame
+	onSend func(frame nmea.RawFrame)
+	closed bool
+}
+
+func (c *fakeFrameConn) ReadRawFrame() (nmea.RawFrame, error) {
+	if len(c.frames) == 0 {
+		return nmea.RawFrame{}, errors.New("no more frames")
+	}
+	f := c.frames[0]
+	c.frames = c.frames[1:]
+	return f, nil
+}
+
+func (c *fakeFrameConn) SendFrame(raw nmea.RawFrame) error {
+	c.sent = append(c.sent, raw)
+	if c.onSend != nil {
+		c.onSend(raw)
+	}
+	return nil
+}
+
+func (c *fakeFrameConn) SetReadTimeout(timeout time.Duration) error {
+	return nil
+}
+
+func (c *fakeFrameConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func testFrame(pgn uint32, data byte) nmea.RawFrame {
+	return nmea.RawFrame{
+		Header: nmea.CanBusHeader{PGN: pgn, Priority: 2, Source: 1, Destination: 255},
+		Length: 8,
+		Data:   [8]byte{data, 1, 2, 3, 4, 5, 6, 7},
+	}
+}
+
+func TestMirror_Run(t *testing.T) {
+	now := time.Date(2023, 5, 14, 10, 0, 0, 0, time.UTC)
+	source := &fakeFrameConn{
+		frames: []nmea.RawFrame{testFrame(127250, 1), testFrame(127251, 2), testFrame(127250, 1)},
+	}
+	destination := &fakeFrameConn{}
+
+	m := NewMirror(MirrorConfig{})
+	m.source = source
+	m.destination = destination
+	m.timeNow = func() time.Time {
+		now = now.Add(100 * time.Millisecond)
+		return now
+	}
+
+	err := m.Run(context.Background())
+	assert.EqualError(t, err, "no more frames")
+
+	assert.Equal(t, []nmea.RawFrame{testFrame(127250, 1), testFrame(127251, 2), testFrame(127250, 1)}, destination.sent)
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(3), stats.Received)
+	assert.Equal(t, uint64(3), stats.Forwarded)
+	assert.Equal(t, uint64(0), stats.LoopDropped)
+	assert.Equal(t, uint64(0), stats.Errors)
+	assert.Equal(t, time.Date(2023, 5, 14, 10, 0, 0, 100_000_000, time.UTC), stats.Started)
+	assert.InDelta(t, 7.5, stats.AverageFramesPerSecond, 0.001) // 3 frames in 400ms
+}
+
+func TestMirror_RunLoopPrevention(t *testing.T) {
+	now := time.Date(2023, 5, 14, 10, 0, 0, 0, time.UTC)
+	source := &fakeFrameConn{
+		frames: []nmea.RawFrame{testFrame(127250, 1), testFrame(127251, 2)},
+	}
+	// destination is bridged back to source, every transmitted frame is echoed back
+	destination := &fakeFrameConn{
+		onSend: func(frame nmea.RawFrame) {
+			source.frames = append(source.frames, frame)
+		},
+	}
+
+	m := NewMirror(MirrorConfig{})
+	m.source = source
+	m.destination = destination
+	m.timeNow = func() time.Time {
+		now = now.Add(1 * time.Millisecond)
+		return now
+	}
+
+	err := m.Run(context.Background())
+	assert.EqualError(t, err, "no more frames")
+
+	assert.Equal(t, []nmea.RawFrame{testFrame(127250, 1), testFrame(127251, 2)}, destination.sent)
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(4), stats.Received)
+	assert.Equal(t, uint64(2), stats.Forwarded)
+	assert.Equal(t, uint64(2), stats.LoopDropped)
+}
+
+func TestMirror_RunContextCancelled(t *testing.T) {
+	m := NewMirror(MirrorConfig{})
+	m.source = &fakeFrameConn{}
+	m.destination = &fakeFrameConn{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Run(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMirror_InitializeSameInterface(t *testing.T) {
+	m := NewMirror(MirrorConfig{SourceInterface: "vcan0", DestinationInterface: "vcan0"})
+
+	err := m.Initialize()
+	assert.ErrorIs(t, err, ErrMirrorSameInterface)
+}
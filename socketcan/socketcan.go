@@ -22,8 +22,31 @@ const (
 	canIDRTRFlag = uint32(1 << 30)
 	// canIDEFFFlag is bit 31 in CAN ID and means EFF extended frame format / IDE identifier extension flag (0 = standard 11 bit, 1 = extended 29 bit)
 	canIDEFFFlag = uint32(1 << 31)
+
+	// canErrClassMask is bitmask to get error class bits from error frame CAN ID (CAN_ERR_MASK)
+	canErrClassMask = uint32(0x1FFFFFFF)
+
+	// canMTU is size of classic CAN frame struct (can_frame)
+	canMTU = 16
+	// canFDMTU is size of CAN-FD frame struct (canfd_frame)
+	canFDMTU = 72
+	// canFDMaxLength is maximum data length of CAN-FD frame
+	canFDMaxLength = 64
 )
 
+// FDFrame is CAN-FD frame with up to 64 data bytes. Classic CAN frames read from CAN-FD enabled socket are also
+// returned as FDFrame with length up to 8 bytes.
+type FDFrame struct {
+	// Time is when frame was read from bus. Filled by this library.
+	Time time.Time
+
+	Header nmea.CanBusHeader
+	// Flags are CAN-FD frame flags (i.e. 0x01 CANFD_BRS bit rate switch)
+	Flags  uint8
+	Length uint8 // 0-64
+	Data   [canFDMaxLength]byte
+}
+
 type Connection struct {
 	socketFD int
 	timeNow  func() time.Time
@@ -79,6 +102,43 @@ func (i Connection) setSocketTimeout(opt int, timeout time.Duration) error {
 	return err
 }
 
+// SetFilters sets kernel level CAN ID filters for socket. Only frames matching any of the filters are received.
+// Empty filter list would drop all frames so it is ignored.
+func (i Connection) SetFilters(filters []Filter) error {
+	if len(filters) == 0 {
+		return nil
+	}
+	if len(filters) > MaxFilters {
+		return fmt.Errorf("too many CAN filters, max %v, got: %v", MaxFilters, len(filters))
+	}
+	canFilters := make([]unix.CanFilter, len(filters))
+	for idx, f := range filters {
+		canFilters[idx] = unix.CanFilter{Id: f.ID, Mask: f.Mask}
+	}
+	if err := unix.SetsockoptCanRawFilter(i.socketFD, unix.SOL_CAN_RAW, unix.CAN_RAW_FILTER, canFilters); err != nil {
+		return fmt.Errorf("could not set CAN filters: %w", err)
+	}
+	return nil
+}
+
+// EnableFDFrames enables receiving and sending CAN-FD frames on socket. Interface must be configured in CAN-FD mode
+// (i.e. `ip link set can0 type can bitrate 250000 dbitrate 2000000 fd on`).
+func (i Connection) EnableFDFrames() error {
+	if err := unix.SetsockoptInt(i.socketFD, unix.SOL_CAN_RAW, unix.CAN_RAW_FD_FRAMES, 1); err != nil {
+		return fmt.Errorf("could not enable CAN-FD frames: %w", err)
+	}
+	return nil
+}
+
+// EnableErrorFrames subscribes socket to error frames of all error classes. Error frames are returned by
+// ReadRawFrame and ReadFDFrame as ErrorFrame error.
+func (i Connection) EnableErrorFrames() error {
+	if err := unix.SetsockoptInt(i.socketFD, unix.SOL_CAN_RAW, unix.CAN_RAW_ERR_FILTER, int(canErrClassMask)); err != nil {
+		return fmt.Errorf("could not enable CAN error frames: %w", err)
+	}
+	return nil
+}
+
 func (i Connection) Close() error {
 	return unix.Close(i.socketFD)
 }
@@ -105,27 +165,78 @@ func (i Connection) SendFrame(raw nmea.RawFrame) error {
 	return err
 }
 
+// SendFDFrame sends CAN-FD frame. Socket must have CAN-FD frames enabled (see EnableFDFrames).
+func (i Connection) SendFDFrame(frame FDFrame) error {
+	if frame.Length > canFDMaxLength {
+		return fmt.Errorf("CAN-FD frame data is too long: %v", frame.Length)
+	}
+	// CAN-FD frame structure: https://github.com/linux-can/can-utils/blob/affdc1b79973c7497bb8607603c24734e11a91aa/include/linux/can.h#L146
+	canFrame := make([]byte, canFDMTU)
+	binary.LittleEndian.PutUint32(canFrame[0:4], frame.Header.Uint32()|canIDEFFFlag)
+	canFrame[4] = frame.Length
+	canFrame[5] = frame.Flags
+	copy(canFrame[8:], frame.Data[:frame.Length])
+
+	_, err := unix.Write(i.socketFD, canFrame)
+	if isContinuableSocketErr(err) {
+		return errWriteTimeout
+	}
+	return err
+}
+
+// ReadRawFrame reads classic CAN frame. Error frames are returned as ErrorFrame error. CAN-FD frames with more than
+// 8 bytes of data result an error.
 func (i Connection) ReadRawFrame() (nmea.RawFrame, error) {
-	canFrame := make([]byte, 16)
-	_, err := unix.Read(i.socketFD, canFrame)
+	frame, err := i.ReadFDFrame()
+	if err != nil {
+		return nmea.RawFrame{}, err
+	}
+	if frame.Length > 8 {
+		return nmea.RawFrame{}, errors.New("read CAN-FD frame longer than 8 bytes")
+	}
+	f := nmea.RawFrame{
+		Time:   frame.Time,
+		Header: frame.Header,
+		Length: frame.Length,
+	}
+	copy(f.Data[:], frame.Data[:f.Length])
+	return f, nil
+}
+
+// ReadFDFrame reads CAN-FD or classic CAN frame. Error frames are returned as ErrorFrame error.
+func (i Connection) ReadFDFrame() (FDFrame, error) {
+	canFrame := make([]byte, canFDMTU)
+	n, err := unix.Read(i.socketFD, canFrame)
 	if err != nil {
 		if isContinuableSocketErr(err) {
-			return nmea.RawFrame{}, errReadTimeout
+			return FDFrame{}, errReadTimeout
 		}
-		return nmea.RawFrame{}, err
+		return FDFrame{}, err
+	}
+	if n != canMTU && n != canFDMTU {
+		return FDFrame{}, fmt.Errorf("read CAN frame with invalid size: %v", n)
 	}
+	now := i.timeNow()
 	canID := binary.LittleEndian.Uint32(canFrame[0:4])
 	if canID&canIDRTRFlag != 0 {
-		return nmea.RawFrame{}, errors.New("read CAN remote transmission request frame")
+		return FDFrame{}, errors.New("read CAN remote transmission request frame")
 	} else if canID&canIDERRFlag != 0 {
-		return nmea.RawFrame{}, errors.New("read CAN error message frame")
+		return FDFrame{}, newErrorFrame(now, canID, canFrame[8:16])
 	}
 
-	f := nmea.RawFrame{
-		Time:   i.timeNow(),
+	f := FDFrame{
+		Time:   now,
 		Header: nmea.ParseCANID(canID ^ canIDMask),
 		Length: canFrame[4],
 	}
+	maxLength := uint8(8)
+	if n == canFDMTU {
+		f.Flags = canFrame[5]
+		maxLength = canFDMaxLength
+	}
+	if f.Length > maxLength {
+		return FDFrame{}, fmt.Errorf("read CAN frame with invalid length: %v", f.Length)
+	}
 	copy(f.Data[:], canFrame[8:8+f.Length])
 
 	return f, nil
@@ -0,0 +1,161 @@
+package nmea
+
+import (
+	"sync"
+	"time"
+)
+
+// SourceGroup defines data type (set of PGNs) and sources that can provide it in order of priority
+type SourceGroup struct {
+	// Name identifies data type (i.e. "position")
+	Name string
+	// PGNs that belong to this data type (i.e. 129025, 129029 for position)
+	PGNs []uint32
+	// Sources is list of source addresses in order of priority. First is primary source. Messages from sources not in
+	// this list are never selected.
+	Sources []uint8
+}
+
+// SelectionChange is event emitted when selected source for data type changes
+type SelectionChange struct {
+	// Group is name of the data type that selection changed for
+	Group string
+	// Time is time of the message that caused selection to change
+	Time time.Time
+	// Previous is previously selected source. Has no meaning when HasPrevious is false.
+	Previous    uint8
+	HasPrevious bool
+	// Current is newly selected source. Has no meaning when HasCurrent is false (all sources went stale).
+	Current    uint8
+	HasCurrent bool
+}
+
+// SourceSelectorConfig configures SourceSelector
+type SourceSelectorConfig struct {
+	Groups []SourceGroup
+
+	// StaleAfter is duration after which source that has not sent any PGN of the group is considered gone and lower
+	// priority source is selected instead.
+	// Defaults to: 5 seconds
+	StaleAfter time.Duration
+
+	// OnChange is called (synchronously) when selected source of group changes. Optional.
+	OnChange func(change SelectionChange)
+}
+
+type sourceGroupState struct {
+	name     string
+	sources  []uint8
+	lastSeen []time.Time
+
+	selected    uint8
+	hasSelected bool
+}
+
+// SourceSelector arbitrates between multiple sources sending same data (i.e. two GPS devices both sending 129025). For
+// every configured data type only messages from single selected source are accepted. Selected source is the highest
+// priority source that has sent data within StaleAfter duration, so when primary source goes silent selection fails
+// over to next source and returns to primary when it starts sending again.
+//
+// SourceSelector is safe for concurrent use.
+type SourceSelector struct {
+	staleAfter time.Duration
+	onChange   func(change SelectionChange)
+
+	lock        sync.Mutex
+	groups      []*sourceGroupState
+	groupsByPGN map[uint32]*sourceGroupState
+}
+
+// NewSourceSelector creates new instance of SourceSelector with given config
+func NewSourceSelector(config SourceSelectorConfig) *SourceSelector {
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 5 * time.Second
+	}
+	s := &SourceSelector{
+		staleAfter:  config.StaleAfter,
+		onChange:    config.OnChange,
+		groups:      make([]*sourceGroupState, 0, len(config.Groups)),
+		groupsByPGN: make(map[uint32]*sourceGroupState),
+	}
+	for _, g := range config.Groups {
+		state := &sourceGroupState{
+			name:     g.Name,
+			sources:  append([]uint8(nil), g.Sources...),
+			lastSeen: make([]time.Time, len(g.Sources)),
+		}
+		s.groups = append(s.groups, state)
+		for _, pgn := range g.PGNs {
+			s.groupsByPGN[pgn] = state
+		}
+	}
+	return s
+}
+
+// Accept checks if message with given header and time should be let through. Messages with PGNs that do not belong to
+// any group are always accepted. Messages of grouped PGNs are accepted only from currently selected source.
+func (s *SourceSelector) Accept(header CanBusHeader, t time.Time) bool {
+	s.lock.Lock()
+	group, ok := s.groupsByPGN[header.PGN]
+	if !ok {
+		s.lock.Unlock()
+		return true
+	}
+
+	for i, src := range group.sources {
+		if src == header.Source {
+			group.lastSeen[i] = t
+			break
+		}
+	}
+	change, changed := s.reselect(group, t)
+	isSelected := group.hasSelected && group.selected == header.Source
+	s.lock.Unlock()
+
+	if changed && s.onChange != nil {
+		s.onChange(change)
+	}
+	return isSelected
+}
+
+func (s *SourceSelector) reselect(group *sourceGroupState, t time.Time) (SelectionChange, bool) {
+	var selected uint8
+	hasSelected := false
+	for i, src := range group.sources {
+		seen := group.lastSeen[i]
+		if seen.IsZero() || t.Sub(seen) > s.staleAfter {
+			continue
+		}
+		selected = src
+		hasSelected = true
+		break
+	}
+	if hasSelected == group.hasSelected && selected == group.selected {
+		return SelectionChange{}, false
+	}
+	change := SelectionChange{
+		Group:       group.name,
+		Time:        t,
+		Previous:    group.selected,
+		HasPrevious: group.hasSelected,
+		Current:     selected,
+		HasCurrent:  hasSelected,
+	}
+	group.selected = selected
+	group.hasSelected = hasSelected
+	return change, true
+}
+
+// Selected returns currently selected source for group with given name. Returns false when group does not exist or
+// has no selected source.
+func (s *SourceSelector) Selected(group string) (uint8, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, g := range s.groups {
+		if g.name == group {
+			return g.selected, g.hasSelected
+		}
+	}
+	return 0, false
+}
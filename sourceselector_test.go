@@ -0,0 +1,113 @@
+package nmea
+
+import (
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSourceSelector_Accept(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	type when struct {
+		header CanBusHeader
+		time   time.Time
+	}
+	var testCases = []struct {
+		name         string
+		when         []when
+		expect       []bool
+		expectEvents []SelectionChange
+	}{
+		{
+			name: "ok, primary source is selected over secondary",
+			when: []when{
+				{header: CanBusHeader{PGN: 129025, Source: 2}, time: now},
+				{header: CanBusHeader{PGN: 129025, Source: 1}, time: now.Add(100 * time.Millisecond)},
+				{header: CanBusHeader{PGN: 129025, Source: 2}, time: now.Add(200 * time.Millisecond)},
+				{header: CanBusHeader{PGN: 129029, Source: 1}, time: now.Add(300 * time.Millisecond)},
+			},
+			expect: []bool{true, true, false, true},
+			expectEvents: []SelectionChange{
+				{Group: "position", Time: now, Current: 2, HasCurrent: true},
+				{Group: "position", Time: now.Add(100 * time.Millisecond), Previous: 2, HasPrevious: true, Current: 1, HasCurrent: true},
+			},
+		},
+		{
+			name: "ok, fails over to secondary when primary goes stale and back when primary returns",
+			when: []when{
+				{header: CanBusHeader{PGN: 129025, Source: 1}, time: now},
+				{header: CanBusHeader{PGN: 129025, Source: 2}, time: now.Add(1 * time.Second)},
+				{header: CanBusHeader{PGN: 129025, Source: 2}, time: now.Add(3 * time.Second)},
+				{header: CanBusHeader{PGN: 129025, Source: 1}, time: now.Add(4 * time.Second)},
+				{header: CanBusHeader{PGN: 129025, Source: 2}, time: now.Add(5 * time.Second)},
+			},
+			expect: []bool{true, false, true, true, false},
+			expectEvents: []SelectionChange{
+				{Group: "position", Time: now, Current: 1, HasCurrent: true},
+				{Group: "position", Time: now.Add(3 * time.Second), Previous: 1, HasPrevious: true, Current: 2, HasCurrent: true},
+				{Group: "position", Time: now.Add(4 * time.Second), Previous: 2, HasPrevious: true, Current: 1, HasCurrent: true},
+			},
+		},
+		{
+			name: "ok, unlisted source is never selected",
+			when: []when{
+				{header: CanBusHeader{PGN: 129025, Source: 3}, time: now},
+			},
+			expect:       []bool{false},
+			expectEvents: nil,
+		},
+		{
+			name: "ok, ungrouped PGN is always accepted",
+			when: []when{
+				{header: CanBusHeader{PGN: 127250, Source: 3}, time: now},
+				{header: CanBusHeader{PGN: 127250, Source: 4}, time: now},
+			},
+			expect:       []bool{true, true},
+			expectEvents: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []SelectionChange
+			selector := NewSourceSelector(SourceSelectorConfig{
+				Groups: []SourceGroup{
+					{Name: "position", PGNs: []uint32{129025, 129029}, Sources: []uint8{1, 2}},
+				},
+				StaleAfter: 2 * time.Second,
+				OnChange: func(change SelectionChange) {
+					events = append(events, change)
+				},
+			})
+
+			result := make([]bool, 0, len(tc.when))
+			for _, w := range tc.when {
+				result = append(result, selector.Accept(w.header, w.time))
+			}
+			assert.Equal(t, tc.expect, result)
+			assert.Equal(t, tc.expectEvents, events)
+		})
+	}
+}
+
+func TestSourceSelector_Selected(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	selector := NewSourceSelector(SourceSelectorConfig{
+		Groups: []SourceGroup{
+			{Name: "heading", PGNs: []uint32{127250}, Sources: []uint8{5, 6}},
+		},
+	})
+
+	_, ok := selector.Selected("heading")
+	assert.False(t, ok)
+
+	selector.Accept(CanBusHeader{PGN: 127250, Source: 6}, now)
+	src, ok := selector.Selected("heading")
+	assert.True(t, ok)
+	assert.Equal(t, uint8(6), src)
+
+	_, ok = selector.Selected("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,248 @@
+// Package storage stores decoded messages into SQL database for queryable history. SQLWriter is written for SQLite
+// (table definitions and queries use SQLite dialect) so small boat computers do not need external database server.
+//
+// Package does not depend on any SQL driver - database is opened by user with driver of their choice (i.e.
+// `modernc.org/sqlite` or `github.com/mattn/go-sqlite3`) and given to NewSQLWriter.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"regexp"
+	"time"
+)
+
+// Layout determines how decoded messages are stored in table
+type Layout uint8
+
+const (
+	// LayoutFields stores one row per field value. Numeric values are in `value` column, textual values (strings,
+	// lookup names) in `text` column. Suitable for time-series queries of single field (i.e. `WHERE pgn=128267 AND
+	// field='depth'`).
+	//
+	// Table: `(time INTEGER, pgn INTEGER, source INTEGER, destination INTEGER, field TEXT, value REAL, text TEXT)`
+	LayoutFields Layout = iota
+	// LayoutMessages stores one row per message with all fields as JSON object (`{"field": value}`) in `fields` column.
+	// Fields can be queried with SQLite JSON functions (i.e. `json_extract(fields, '$.depth')`).
+	//
+	// Table: `(time INTEGER, pgn INTEGER, source INTEGER, destination INTEGER, fields TEXT)`
+	LayoutMessages
+)
+
+const (
+	// DefaultBatchSize is default number of messages written in single transaction
+	DefaultBatchSize = 100
+	// DefaultFlushInterval is default maximum time messages are held in batch before they are written
+	DefaultFlushInterval = 1 * time.Second
+)
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config configures SQLWriter
+type Config struct {
+	// Layout determines table layout
+	Layout Layout
+	// Table is name of table where messages are stored. Table and index on time are created when they do not exist.
+	// Defaults to: `n2k_fields` for LayoutFields and `n2k_messages` for LayoutMessages
+	Table string
+
+	// BatchSize is number of messages written in single transaction. Batching reduces disk writes considerably
+	// (important for SD cards).
+	// Defaults to: DefaultBatchSize
+	BatchSize int
+	// FlushInterval is maximum time (by message time) messages are held in batch before they are written.
+	// Defaults to: DefaultFlushInterval
+	FlushInterval time.Duration
+}
+
+type row struct {
+	raw nmea.RawMessage
+	msg nmea.Message
+}
+
+// SQLWriter writes decoded messages into SQL table in batched transactions. Implements export.Writer.
+//
+// Note: is not go-routine safe
+type SQLWriter struct {
+	db     *sql.DB
+	config Config
+
+	insertQuery string
+	batch       []row
+	batchStart  time.Time
+	timeNow     func() time.Time
+}
+
+// NewSQLWriter creates new instance of SQLWriter and creates table for messages when it does not exist
+func NewSQLWriter(ctx context.Context, db *sql.DB, config Config) (*SQLWriter, error) {
+	if config.Table == "" {
+		config.Table = "n2k_fields"
+		if config.Layout == LayoutMessages {
+			config.Table = "n2k_messages"
+		}
+	}
+	if !validTableName.MatchString(config.Table) {
+		return nil, fmt.Errorf("storage: invalid table name: %v", config.Table)
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+
+	var createQuery string
+	var insertQuery string
+	switch config.Layout {
+	case LayoutFields:
+		createQuery = `CREATE TABLE IF NOT EXISTS ` + config.Table + ` (
+	time INTEGER NOT NULL,
+	pgn INTEGER NOT NULL,
+	source INTEGER NOT NULL,
+	destination INTEGER NOT NULL,
+	field TEXT NOT NULL,
+	value REAL,
+	text TEXT
+)`
+		insertQuery = `INSERT INTO ` + config.Table + ` (time, pgn, source, destination, field, value, text) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	case LayoutMessages:
+		createQuery = `CREATE TABLE IF NOT EXISTS ` + config.Table + ` (
+	time INTEGER NOT NULL,
+	pgn INTEGER NOT NULL,
+	source INTEGER NOT NULL,
+	destination INTEGER NOT NULL,
+	fields TEXT NOT NULL
+)`
+		insertQuery = `INSERT INTO ` + config.Table + ` (time, pgn, source, destination, fields) VALUES (?, ?, ?, ?, ?)`
+	default:
+		return nil, fmt.Errorf("storage: unknown layout: %v", config.Layout)
+	}
+
+	if _, err := db.ExecContext(ctx, createQuery); err != nil {
+		return nil, fmt.Errorf("storage: failed to create table, err: %w", err)
+	}
+	indexQuery := `CREATE INDEX IF NOT EXISTS ` + config.Table + `_pgn_time ON ` + config.Table + ` (pgn, time)`
+	if _, err := db.ExecContext(ctx, indexQuery); err != nil {
+		return nil, fmt.Errorf("storage: failed to create index, err: %w", err)
+	}
+
+	return &SQLWriter{
+		db:          db,
+		config:      config,
+		insertQuery: insertQuery,
+		batch:       make([]row, 0, config.BatchSize),
+		timeNow:     time.Now,
+	}, nil
+}
+
+// WriteMessage adds decoded message to batch. Batch is written when it is full or FlushInterval has passed since
+// first message in batch. Time column is message time in unix milliseconds.
+func (w *SQLWriter) WriteMessage(raw nmea.RawMessage, msg nmea.Message) error {
+	if raw.Time.IsZero() {
+		raw.Time = w.timeNow()
+	}
+	if len(w.batch) == 0 {
+		w.batchStart = raw.Time
+	}
+	w.batch = append(w.batch, row{raw: raw, msg: msg})
+	if len(w.batch) >= w.config.BatchSize || raw.Time.Sub(w.batchStart) >= w.config.FlushInterval {
+		return w.Flush(context.Background())
+	}
+	return nil
+}
+
+// Flush writes batched messages in single transaction
+func (w *SQLWriter) Flush(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storage: failed to begin transaction, err: %w", err)
+	}
+	if err := w.insert(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storage: failed to commit transaction, err: %w", err)
+	}
+	w.batch = w.batch[:0]
+	return nil
+}
+
+func (w *SQLWriter) insert(ctx context.Context, tx *sql.Tx) error {
+	stmt, err := tx.PrepareContext(ctx, w.insertQuery)
+	if err != nil {
+		return fmt.Errorf("storage: failed to prepare insert, err: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range w.batch {
+		h := r.msg.Header
+		t := r.raw.Time.UnixMilli()
+		if w.config.Layout == LayoutMessages {
+			fields, err := fieldsJSON(r.msg.Fields)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.ExecContext(ctx, t, int64(h.PGN), int64(h.Source), int64(h.Destination), fields); err != nil {
+				return fmt.Errorf("storage: failed to insert message, err: %w", err)
+			}
+			continue
+		}
+		for _, f := range r.msg.Fields {
+			value, text := fieldColumns(f)
+			if _, err := stmt.ExecContext(ctx, t, int64(h.PGN), int64(h.Source), int64(h.Destination), f.ID, value, text); err != nil {
+				return fmt.Errorf("storage: failed to insert field, err: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldColumns converts field value to `value` (numeric) and `text` columns. Durations are stored as seconds, lookups
+// have both numeric value and name.
+func fieldColumns(f nmea.FieldValue) (sql.NullFloat64, sql.NullString) {
+	switch v := f.Value.(type) {
+	case string:
+		return sql.NullFloat64{}, sql.NullString{String: v, Valid: true}
+	case []byte:
+		return sql.NullFloat64{}, sql.NullString{String: hex.EncodeToString(v), Valid: true}
+	case time.Duration:
+		return sql.NullFloat64{Float64: v.Seconds(), Valid: true}, sql.NullString{}
+	case nmea.TimeOfDay:
+		return sql.NullFloat64{Float64: v.Duration().Seconds(), Valid: true}, sql.NullString{}
+	case nmea.EnumValue:
+		return sql.NullFloat64{Float64: float64(v.Value), Valid: true}, sql.NullString{String: v.Code, Valid: true}
+	}
+	if n, ok := f.AsFloat64(); ok {
+		return sql.NullFloat64{Float64: n, Valid: true}, sql.NullString{}
+	}
+	b, err := json.Marshal(f.Value) // i.e. repeating field sets, bit lookups
+	if err != nil {
+		return sql.NullFloat64{}, sql.NullString{}
+	}
+	return sql.NullFloat64{}, sql.NullString{String: string(b), Valid: true}
+}
+
+func fieldsJSON(fields nmea.FieldValues) (string, error) {
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.ID] = f.Value
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("storage: failed to marshal fields, err: %w", err)
+	}
+	return string(b), nil
+}
+
+// Close writes remaining batched messages. Database is not closed.
+func (w *SQLWriter) Close() error {
+	return w.Flush(context.Background())
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDriver is fake SQL driver recording executed statements
+type recordingDriver struct {
+	mu      sync.Mutex
+	log     []string
+	execErr error
+}
+
+func (d *recordingDriver) add(entry string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, entry)
+}
+
+func (d *recordingDriver) entries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.log...)
+}
+
+func (d *recordingDriver) Open(_ string) (driver.Conn, error) { return &recordingConn{d: d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.d.add("BEGIN")
+	return &recordingTx{d: c.d}, nil
+}
+
+type recordingTx struct{ d *recordingDriver }
+
+func (t *recordingTx) Commit() error {
+	t.d.add("COMMIT")
+	return nil
+}
+func (t *recordingTx) Rollback() error {
+	t.d.add("ROLLBACK")
+	return nil
+}
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.d.execErr != nil && len(args) > 0 {
+		return nil, s.d.execErr
+	}
+	if len(args) == 0 {
+		s.d.add(s.query)
+	} else {
+		s.d.add(fmt.Sprintf("%v", args))
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *recordingStmt) Query(_ []driver.Value) (driver.Rows, error) { return nil, io.EOF }
+
+var driverCounter = 0
+
+func openRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	d := &recordingDriver{}
+	driverCounter++
+	name := fmt.Sprintf("recording%d", driverCounter)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+var testMessage = nmea.Message{
+	Header: nmea.CanBusHeader{PGN: 127250, Source: 1, Destination: 255},
+	Fields: nmea.FieldValues{
+		{ID: "heading", Value: 1.5},
+		{ID: "sid", Value: uint64(3)},
+		{ID: "reference", Value: nmea.EnumValue{Value: 1, Code: "Magnetic"}},
+		{ID: "name", Value: "boat"},
+		{ID: "elapsed", Value: 1500 * time.Millisecond},
+	},
+}
+
+func TestNewSQLWriter_createsTable(t *testing.T) {
+	db, d := openRecordingDB(t)
+
+	_, err := NewSQLWriter(context.Background(), db, Config{Layout: LayoutMessages})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS n2k_messages (\n\ttime INTEGER NOT NULL,\n\tpgn INTEGER NOT NULL,\n\tsource INTEGER NOT NULL,\n\tdestination INTEGER NOT NULL,\n\tfields TEXT NOT NULL\n)",
+		"CREATE INDEX IF NOT EXISTS n2k_messages_pgn_time ON n2k_messages (pgn, time)",
+	}, d.entries())
+}
+
+func TestNewSQLWriter_errors(t *testing.T) {
+	db, _ := openRecordingDB(t)
+
+	_, err := NewSQLWriter(context.Background(), db, Config{Table: "x; DROP TABLE y"})
+	assert.EqualError(t, err, "storage: invalid table name: x; DROP TABLE y")
+
+	_, err = NewSQLWriter(context.Background(), db, Config{Layout: 99})
+	assert.EqualError(t, err, "storage: unknown layout: 99")
+}
+
+func TestSQLWriter_WriteMessage(t *testing.T) {
+	now := time.Unix(1665488842, 0)
+	var testCases = []struct {
+		name   string
+		config Config
+		expect []string
+	}{
+		{
+			name:   "ok, one row per field",
+			config: Config{Layout: LayoutFields, BatchSize: 2},
+			expect: []string{
+				"BEGIN",
+				"[1665488842000 127250 1 255 heading 1.5 <nil>]",
+				"[1665488842000 127250 1 255 sid 3 <nil>]",
+				"[1665488842000 127250 1 255 reference 1 Magnetic]",
+				"[1665488842000 127250 1 255 name <nil> boat]",
+				"[1665488842000 127250 1 255 elapsed 1.5 <nil>]",
+				"[1665488842500 127250 1 255 heading 1.5 <nil>]",
+				"[1665488842500 127250 1 255 sid 3 <nil>]",
+				"[1665488842500 127250 1 255 reference 1 Magnetic]",
+				"[1665488842500 127250 1 255 name <nil> boat]",
+				"[1665488842500 127250 1 255 elapsed 1.5 <nil>]",
+				"COMMIT",
+			},
+		},
+		{
+			name:   "ok, one row per message, flushed by interval",
+			config: Config{Layout: LayoutMessages, FlushInterval: 500 * time.Millisecond},
+			expect: []string{
+				"BEGIN",
+				`[1665488842000 127250 1 255 {"elapsed":1500000000,"heading":1.5,"name":"boat","reference":{"Value":1,"Code":"Magnetic"},"sid":3}]`,
+				`[1665488842500 127250 1 255 {"elapsed":1500000000,"heading":1.5,"name":"boat","reference":{"Value":1,"Code":"Magnetic"},"sid":3}]`,
+				"COMMIT",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, d := openRecordingDB(t)
+			w, err := NewSQLWriter(context.Background(), db, tc.config)
+			assert.NoError(t, err)
+
+			assert.NoError(t, w.WriteMessage(nmea.RawMessage{Time: now}, testMessage))
+			assert.NoError(t, w.WriteMessage(nmea.RawMessage{Time: now.Add(500 * time.Millisecond)}, testMessage))
+			assert.NoError(t, w.Close())
+
+			assert.Equal(t, tc.expect, d.entries()[2:])
+		})
+	}
+}
+
+func TestSQLWriter_Close_flushesBatch(t *testing.T) {
+	db, d := openRecordingDB(t)
+	w, err := NewSQLWriter(context.Background(), db, Config{Layout: LayoutMessages})
+	assert.NoError(t, err)
+	w.timeNow = func() time.Time {
+		return time.Unix(1665488842, 0)
+	}
+
+	assert.NoError(t, w.WriteMessage(nmea.RawMessage{}, nmea.Message{Header: nmea.CanBusHeader{PGN: 60928}}))
+	assert.Len(t, d.entries(), 2)
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []string{"BEGIN", "[1665488842000 60928 0 0 {}]", "COMMIT"}, d.entries()[2:])
+
+	assert.NoError(t, w.Close())
+	assert.Len(t, d.entries(), 5)
+}
+
+func TestSQLWriter_Flush_errorRollsBack(t *testing.T) {
+	db, d := openRecordingDB(t)
+	w, err := NewSQLWriter(context.Background(), db, Config{Layout: LayoutMessages})
+	assert.NoError(t, err)
+
+	d.execErr = errors.New("disk full")
+	assert.NoError(t, w.WriteMessage(nmea.RawMessage{Time: time.Unix(1665488842, 0)}, testMessage))
+	err = w.Flush(context.Background())
+
+	assert.EqualError(t, err, "storage: failed to insert message, err: disk full")
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, d.entries()[2:])
+	assert.Len(t, w.batch, 1)
+}
@@ -0,0 +1,113 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrStreamWriterClosed is returned when writing to closed StreamWriter
+var ErrStreamWriterClosed = errors.New("stream writer closed")
+
+// ReaderFactory creates format specific message reader (i.e. actisense.NewBinaryDevice) on top of given byte stream.
+type ReaderFactory func(stream io.ReadWriter) RawMessageReader
+
+// StreamWriterConfig configures StreamWriter
+type StreamWriterConfig struct {
+	// OnMessage is called for every message parsed from written bytes. Called from single goroutine.
+	OnMessage func(msg RawMessage)
+	// OnError is called for parsing errors (i.e. malformed message). Parsing continues after error. Optional.
+	OnError func(err error)
+}
+
+// StreamWriter is io.Writer adapter for format parsers. It decouples transport from parsing - users that already own
+// the connection (i.e. custom transport, websocket) can write received bytes into StreamWriter and get parsed messages
+// through callback. Bytes can be written in arbitrary chunks, message boundaries do not need to match Write calls.
+//
+// Write blocks until parser has consumed written bytes. Close must be called to release parser goroutine.
+type StreamWriter struct {
+	config StreamWriterConfig
+
+	pipeWriter *io.PipeWriter
+	cancel     context.CancelFunc
+	done       chan struct{}
+
+	closeOnce sync.Once
+}
+
+// streamReader adapts pipe reader to io.ReadWriter that format readers expect. Writes (i.e. device initialization
+// commands) are discarded. End of stream is reported as ErrStreamWriterClosed so readers that retry on io.EOF (waiting
+// for device to send more data) stop immediately.
+type streamReader struct {
+	reader *io.PipeReader
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		err = ErrStreamWriterClosed
+	}
+	return n, err
+}
+
+func (s *streamReader) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// NewStreamWriter creates new instance of StreamWriter that parses written bytes with reader created by factory.
+func NewStreamWriter(factory ReaderFactory, config StreamWriterConfig) *StreamWriter {
+	pipeReader, pipeWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &StreamWriter{
+		config:     config,
+		pipeWriter: pipeWriter,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	reader := factory(&streamReader{reader: pipeReader})
+	go w.parse(ctx, reader, pipeReader)
+	return w
+}
+
+func (w *StreamWriter) parse(ctx context.Context, reader RawMessageReader, pipeReader *io.PipeReader) {
+	defer close(w.done)
+	// when parser stops all pending and future writes must fail instead of blocking forever
+	defer pipeReader.CloseWithError(ErrStreamWriterClosed)
+
+	for {
+		msg, err := reader.ReadRawMessage(ctx)
+		if err != nil {
+			if errors.Is(err, ErrStreamWriterClosed) || errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+				return
+			}
+			if w.config.OnError != nil {
+				w.config.OnError(err)
+			}
+			continue
+		}
+		if w.config.OnMessage != nil {
+			w.config.OnMessage(msg)
+		}
+	}
+}
+
+// Write writes bytes to the parser. Returns after parser has consumed all given bytes.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	n, err := w.pipeWriter.Write(p)
+	if errors.Is(err, io.ErrClosedPipe) {
+		err = ErrStreamWriterClosed
+	}
+	return n, err
+}
+
+// Close ends the stream and waits until parser has processed all written bytes.
+func (w *StreamWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.pipeWriter.Close()
+		<-w.done
+		w.cancel()
+	})
+	return nil
+}
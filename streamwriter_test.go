@@ -0,0 +1,100 @@
+package nmea_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStreamWriter_Write_canboatLines(t *testing.T) {
+	var messages []nmea.RawMessage
+	var errs []error
+	w := nmea.NewStreamWriter(func(stream io.ReadWriter) nmea.RawMessageReader {
+		return canboat.NewCanBoatReader(stream)
+	}, nmea.StreamWriterConfig{
+		OnMessage: func(msg nmea.RawMessage) { messages = append(messages, msg) },
+		OnError:   func(err error) { errs = append(errs, err) },
+	})
+
+	input := "2023-02-07T11:55:11.002Z,2,127245,13,255,8,ff,07,ff,7f,00,00,ff,ff\n" +
+		"invalid line\n" +
+		"2023-02-07T11:55:11.006Z,2,127250,24,255,8,00,22,00,ff,7f,ff,7f,fc\n"
+	// write in small chunks that do not match line boundaries
+	for _, chunk := range []string{input[0:10], input[10:80], input[80:]} {
+		n, err := w.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.NoError(t, w.Close())
+
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, uint32(127245), messages[0].Header.PGN)
+		assert.Equal(t, uint32(127250), messages[1].Header.PGN)
+	}
+	assert.Len(t, errs, 1)
+
+	_, err := w.Write([]byte("more"))
+	assert.ErrorIs(t, err, nmea.ErrStreamWriterClosed)
+}
+
+func TestStreamWriter_Write_actisenseBinary(t *testing.T) {
+	data, err := os.ReadFile("actisense/testdata/actisense_n2kactisense.bin")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// parse same data directly from reader to know what to expect
+	expected := make([]nmea.RawMessage, 0)
+	device := actisense.NewBinaryDeviceWithConfig(bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(data)), nil), actisense.Config{})
+	for {
+		msg, err := device.ReadRawMessage(context.Background())
+		if err != nil {
+			break
+		}
+		expected = append(expected, msg)
+	}
+
+	var messages []nmea.RawMessage
+	w := nmea.NewStreamWriter(func(stream io.ReadWriter) nmea.RawMessageReader {
+		return actisense.NewBinaryDevice(stream)
+	}, nmea.StreamWriterConfig{
+		OnMessage: func(msg nmea.RawMessage) { messages = append(messages, msg) },
+	})
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := w.Write(data[i:end])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	assert.NotEmpty(t, messages)
+	if assert.Len(t, messages, len(expected)) {
+		for i := range expected {
+			assert.Equal(t, expected[i].Header, messages[i].Header)
+			assert.Equal(t, expected[i].Data, messages[i].Data)
+		}
+	}
+}
+
+func TestStreamWriter_Close(t *testing.T) {
+	w := nmea.NewStreamWriter(func(stream io.ReadWriter) nmea.RawMessageReader {
+		return canboat.NewCanBoatReader(stream)
+	}, nmea.StreamWriterConfig{})
+
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+
+	_, err := io.Copy(w, strings.NewReader("x"))
+	assert.ErrorIs(t, err, nmea.ErrStreamWriterClosed)
+}
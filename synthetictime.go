@@ -0,0 +1,130 @@
+package nmea
+
+import (
+	"context"
+	"time"
+)
+
+// SyntheticTimeMode determines how SyntheticTimeReader assigns time to messages
+type SyntheticTimeMode uint8
+
+const (
+	// SyntheticTimeAnchored keeps time differences between messages as they were read, but shifts them so the first
+	// message has SyntheticTimeConfig.StartTime.
+	SyntheticTimeAnchored SyntheticTimeMode = iota
+	// SyntheticTimeFixedInterval assigns times with fixed SyntheticTimeConfig.Interval between messages.
+	SyntheticTimeFixedInterval
+	// SyntheticTimeRateEstimate estimates original bus message rate from PGNs with known transmission intervals
+	// (SyntheticTimeConfig.ReferenceIntervals) and spreads messages evenly according to that rate.
+	SyntheticTimeRateEstimate
+)
+
+// SyntheticTimeConfig configures SyntheticTimeReader
+type SyntheticTimeConfig struct {
+	Mode SyntheticTimeMode
+
+	// StartTime is time assigned to the first message.
+	// Defaults to: time of the first read message
+	StartTime time.Time
+
+	// Interval is time between messages for SyntheticTimeFixedInterval mode and initial estimate for
+	// SyntheticTimeRateEstimate mode.
+	// Defaults to: 10ms
+	Interval time.Duration
+
+	// ReferenceIntervals maps PGNs to their (known) transmission intervals. Used by SyntheticTimeRateEstimate mode.
+	// Each time PGN is seen again from same source, we know that given interval has passed on the bus and
+	// can estimate how much time each message in between took. See canboat.PGNs.TransmissionIntervals.
+	ReferenceIntervals map[uint32]time.Duration
+}
+
+// SyntheticTimeReader wraps RawMessageReader and replaces message times with synthetic ones. This is useful when
+// replaying files that do not contain timestamps (i.e. NGT binary dumps) as otherwise all messages would have
+// wall clock time of the replay run.
+type SyntheticTimeReader struct {
+	reader RawMessageReader
+	config SyntheticTimeConfig
+
+	isStarted    bool
+	current      time.Time
+	firstOrigin  time.Time
+	estimate     time.Duration
+	messageCount uint64
+	// referenceSeen holds message count when reference PGN was last seen. Key is PGN<<8 | source
+	referenceSeen map[uint64]uint64
+}
+
+// NewSyntheticTimeReader creates new instance of SyntheticTimeReader
+func NewSyntheticTimeReader(reader RawMessageReader, config SyntheticTimeConfig) *SyntheticTimeReader {
+	if config.Interval <= 0 {
+		config.Interval = 10 * time.Millisecond
+	}
+	return &SyntheticTimeReader{
+		reader:        reader,
+		config:        config,
+		estimate:      config.Interval,
+		referenceSeen: make(map[uint64]uint64),
+	}
+}
+
+// ReadRawMessage reads message from wrapped reader and assigns synthetic time to it.
+func (r *SyntheticTimeReader) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	msg, err := r.reader.ReadRawMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	msg.Time = r.nextTime(msg)
+	return msg, nil
+}
+
+func (r *SyntheticTimeReader) nextTime(msg RawMessage) time.Time {
+	r.messageCount++
+	if !r.isStarted {
+		r.isStarted = true
+		r.firstOrigin = msg.Time
+		r.current = r.config.StartTime
+		if r.current.IsZero() {
+			r.current = msg.Time
+		}
+		r.markReference(msg)
+		return r.current
+	}
+
+	switch r.config.Mode {
+	case SyntheticTimeFixedInterval:
+		r.current = r.current.Add(r.config.Interval)
+	case SyntheticTimeRateEstimate:
+		r.current = r.current.Add(r.estimate)
+		r.markReference(msg)
+	default: // SyntheticTimeAnchored
+		return r.current.Add(msg.Time.Sub(r.firstOrigin))
+	}
+	return r.current
+}
+
+func (r *SyntheticTimeReader) markReference(msg RawMessage) {
+	interval, ok := r.config.ReferenceIntervals[msg.Header.PGN]
+	if !ok || interval <= 0 {
+		return
+	}
+	key := uint64(msg.Header.PGN)<<8 | uint64(msg.Header.Source)
+	lastSeenAt, ok := r.referenceSeen[key]
+	r.referenceSeen[key] = r.messageCount
+	if !ok {
+		return
+	}
+	// during `interval` we saw this many messages on the bus. Smooth estimate so single out of order message does
+	// not cause big jumps in time.
+	perMessage := interval / time.Duration(r.messageCount-lastSeenAt)
+	r.estimate = (r.estimate*3 + perMessage) / 4
+}
+
+// Initialize initializes wrapped reader
+func (r *SyntheticTimeReader) Initialize() error {
+	return r.reader.Initialize()
+}
+
+// Close closes wrapped reader
+func (r *SyntheticTimeReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,121 @@
+package nmea
+
+import (
+	"context"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type sliceReader struct {
+	messages []RawMessage
+	index    int
+}
+
+func (r *sliceReader) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	if r.index >= len(r.messages) {
+		return RawMessage{}, io.EOF
+	}
+	msg := r.messages[r.index]
+	r.index++
+	return msg, nil
+}
+
+func (r *sliceReader) Initialize() error {
+	return nil
+}
+
+func (r *sliceReader) Close() error {
+	return nil
+}
+
+func TestSyntheticTimeReader_ReadRawMessage(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	start := test_test.UTCTime(1600000000)
+
+	var testCases = []struct {
+		name        string
+		givenConfig SyntheticTimeConfig
+		when        []RawMessage
+		expect      []time.Time
+	}{
+		{
+			name: "ok, anchored keeps deltas",
+			givenConfig: SyntheticTimeConfig{
+				Mode:      SyntheticTimeAnchored,
+				StartTime: start,
+			},
+			when: []RawMessage{
+				{Time: now, Header: CanBusHeader{PGN: 129025}},
+				{Time: now.Add(15 * time.Millisecond), Header: CanBusHeader{PGN: 129026}},
+				{Time: now.Add(100 * time.Millisecond), Header: CanBusHeader{PGN: 129025}},
+			},
+			expect: []time.Time{
+				start,
+				start.Add(15 * time.Millisecond),
+				start.Add(100 * time.Millisecond),
+			},
+		},
+		{
+			name: "ok, fixed interval starting from first message time",
+			givenConfig: SyntheticTimeConfig{
+				Mode:     SyntheticTimeFixedInterval,
+				Interval: 5 * time.Millisecond,
+			},
+			when: []RawMessage{
+				{Time: now, Header: CanBusHeader{PGN: 129025}},
+				{Time: now, Header: CanBusHeader{PGN: 129026}},
+				{Time: now, Header: CanBusHeader{PGN: 129025}},
+			},
+			expect: []time.Time{
+				now,
+				now.Add(5 * time.Millisecond),
+				now.Add(10 * time.Millisecond),
+			},
+		},
+		{
+			name: "ok, rate estimate from reference PGN",
+			givenConfig: SyntheticTimeConfig{
+				Mode:               SyntheticTimeRateEstimate,
+				StartTime:          start,
+				Interval:           40 * time.Millisecond,
+				ReferenceIntervals: map[uint32]time.Duration{129025: 100 * time.Millisecond},
+			},
+			when: []RawMessage{
+				{Time: now, Header: CanBusHeader{PGN: 129025, Source: 1}},
+				{Time: now, Header: CanBusHeader{PGN: 129026, Source: 1}},
+				{Time: now, Header: CanBusHeader{PGN: 129025, Source: 2}},
+				{Time: now, Header: CanBusHeader{PGN: 127250, Source: 1}},
+				{Time: now, Header: CanBusHeader{PGN: 129025, Source: 1}}, // 4 messages for 100ms => 25ms each
+				{Time: now, Header: CanBusHeader{PGN: 127250, Source: 1}},
+			},
+			expect: []time.Time{
+				start,
+				start.Add(40 * time.Millisecond),
+				start.Add(80 * time.Millisecond),
+				start.Add(120 * time.Millisecond),
+				start.Add(160 * time.Millisecond),
+				start.Add(160*time.Millisecond + 36250*time.Microsecond), // (40*3+25)/4 = 36.25ms
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewSyntheticTimeReader(&sliceReader{messages: tc.when}, tc.givenConfig)
+
+			result := make([]time.Time, 0, len(tc.when))
+			for {
+				msg, err := r.ReadRawMessage(context.Background())
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				result = append(result, msg.Time)
+			}
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package nmea
+
+import (
+	"context"
+	"fmt"
+)
+
+// TeeOutput is single output (sink) of Tee
+type TeeOutput struct {
+	// Writer receives every read message that Filter accepts
+	Writer RawMessageWriter
+	// Filter decides which messages are written to this output. Optional: nil means all messages are written.
+	Filter func(msg RawMessage) bool
+}
+
+// TeeConfig configures Tee
+type TeeConfig struct {
+	Outputs []TeeOutput
+
+	// OnError is called when writing message to output fails. When not set, write errors are returned from
+	// ReadRawMessage (and message is not returned to caller).
+	OnError func(output int, msg RawMessage, err error)
+}
+
+// Tee is RawMessageReader that copies every message read from wrapped reader to configured outputs (i.e. raw
+// recorder) before returning it to caller. This allows single read loop to feed raw recording and decoding pipeline
+// simultaneously. Outputs have their own filters so recording is complete even when caller (decoder) filters
+// only few PGNs.
+type Tee struct {
+	reader  RawMessageReader
+	outputs []TeeOutput
+	onError func(output int, msg RawMessage, err error)
+}
+
+// NewTee creates new instance of Tee writing messages to given outputs
+func NewTee(reader RawMessageReader, outputs ...TeeOutput) *Tee {
+	return NewTeeWithConfig(reader, TeeConfig{Outputs: outputs})
+}
+
+// NewTeeWithConfig creates new instance of Tee with given config
+func NewTeeWithConfig(reader RawMessageReader, config TeeConfig) *Tee {
+	return &Tee{
+		reader:  reader,
+		outputs: config.Outputs,
+		onError: config.OnError,
+	}
+}
+
+// ReadRawMessage reads message from wrapped reader, writes it to outputs and returns it.
+func (t *Tee) ReadRawMessage(ctx context.Context) (RawMessage, error) {
+	msg, err := t.reader.ReadRawMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	for i, o := range t.outputs {
+		if o.Filter != nil && !o.Filter(msg) {
+			continue
+		}
+		if err := o.Writer.WriteRawMessage(ctx, msg); err != nil {
+			if t.onError == nil {
+				return RawMessage{}, fmt.Errorf("tee failed to write to output %v: %w", i, err)
+			}
+			t.onError(i, msg, err)
+		}
+	}
+	return msg, nil
+}
+
+// Initialize initializes wrapped reader
+func (t *Tee) Initialize() error {
+	return t.reader.Initialize()
+}
+
+// Close closes wrapped reader and all outputs. First encountered error is returned.
+func (t *Tee) Close() error {
+	err := t.reader.Close()
+	for _, o := range t.outputs {
+		if oErr := o.Writer.Close(); err == nil {
+			err = oErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+type recordingWriter struct {
+	messages []RawMessage
+	err      error
+	closed   bool
+}
+
+func (w *recordingWriter) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msg)
+	return nil
+}
+
+func (w *recordingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestTee_ReadRawMessage(t *testing.T) {
+	given := []RawMessage{
+		{Header: CanBusHeader{PGN: 127250, Source: 1}},
+		{Header: CanBusHeader{PGN: 129025, Source: 2}},
+		{Header: CanBusHeader{PGN: 127250, Source: 3}},
+	}
+	recorder := &recordingWriter{}
+	headings := &recordingWriter{}
+	tee := NewTee(
+		&sliceReader{messages: given},
+		TeeOutput{Writer: recorder},
+		TeeOutput{Writer: headings, Filter: func(msg RawMessage) bool { return msg.Header.PGN == 127250 }},
+	)
+
+	result := make([]RawMessage, 0)
+	for {
+		msg, err := tee.ReadRawMessage(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+		result = append(result, msg)
+	}
+
+	assert.Equal(t, given, result)
+	assert.Equal(t, given, recorder.messages)
+	assert.Equal(t, []RawMessage{given[0], given[2]}, headings.messages)
+
+	assert.NoError(t, tee.Close())
+	assert.True(t, recorder.closed)
+	assert.True(t, headings.closed)
+}
+
+func TestTee_ReadRawMessage_writeError(t *testing.T) {
+	given := []RawMessage{{Header: CanBusHeader{PGN: 127250, Source: 1}}}
+	tee := NewTee(&sliceReader{messages: given}, TeeOutput{Writer: &recordingWriter{err: errors.New("disk full")}})
+
+	_, err := tee.ReadRawMessage(context.Background())
+	assert.EqualError(t, err, "tee failed to write to output 0: disk full")
+}
+
+func TestTee_ReadRawMessage_onError(t *testing.T) {
+	given := []RawMessage{{Header: CanBusHeader{PGN: 127250, Source: 1}}}
+	var errs []error
+	tee := NewTeeWithConfig(&sliceReader{messages: given}, TeeConfig{
+		Outputs: []TeeOutput{{Writer: &recordingWriter{err: errors.New("disk full")}}},
+		OnError: func(output int, msg RawMessage, err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	msg, err := tee.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, given[0], msg)
+	assert.Len(t, errs, 1)
+}
@@ -0,0 +1,290 @@
+// Package telemetry keeps rolling statistics (latest value and min/max/avg over time windows) of engine and battery
+// values per device instance so dashboards do not need to implement windowing themselves.
+package telemetry
+
+import (
+	"github.com/aldas/go-nmea-client"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// PGNEngineParametersRapidUpdate is PGN 127488 Engine Parameters, Rapid Update
+	PGNEngineParametersRapidUpdate = uint32(127488)
+	// PGNEngineParametersDynamic is PGN 127489 Engine Parameters, Dynamic
+	PGNEngineParametersDynamic = uint32(127489)
+	// PGNDCDetailedStatus is PGN 127506 DC Detailed Status
+	PGNDCDetailedStatus = uint32(127506)
+	// PGNBatteryStatus is PGN 127508 Battery Status
+	PGNBatteryStatus = uint32(127508)
+)
+
+// DefaultFields are PGNs and their field IDs (Canboat field IDs) that Aggregator collects by default
+var DefaultFields = map[uint32][]string{
+	PGNEngineParametersRapidUpdate: {"speed", "boostPressure", "tiltTrim"},
+	PGNEngineParametersDynamic: {"oilPressure", "oilTemperature", "temperature", "alternatorPotential", "fuelRate",
+		"coolantPressure", "fuelPressure", "engineLoad", "engineTorque"},
+	PGNDCDetailedStatus: {"stateOfCharge", "stateOfHealth", "rippleVoltage", "remainingCapacity"},
+	PGNBatteryStatus:    {"voltage", "current", "temperature"},
+}
+
+// DefaultWindows are time windows Aggregator calculates statistics for by default
+var DefaultWindows = []time.Duration{1 * time.Minute, 10 * time.Minute}
+
+// Key identifies single aggregated value
+type Key struct {
+	PGN uint32 `json:"pgn"`
+	// Instance is engine/battery/DC instance (`instance` field of message)
+	Instance uint8  `json:"instance"`
+	Field    string `json:"field"`
+}
+
+// WindowStats contains statistics of values received within time window
+type WindowStats struct {
+	Window time.Duration `json:"window"`
+	// Count is number of values within window
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+// FieldStats contains latest value and window statistics of single field
+type FieldStats struct {
+	Key
+	// Value is latest (instantaneous) value
+	Value float64 `json:"value"`
+	// Source is source address of device that sent latest value
+	Source uint8 `json:"source"`
+	// UpdatedAt is time when latest value was received
+	UpdatedAt time.Time `json:"updated_at"`
+	// Windows contains statistics for each configured window in same order as Config.Windows
+	Windows []WindowStats `json:"windows"`
+}
+
+// Config configures Aggregator instance
+type Config struct {
+	// Fields maps PGNs to field IDs that are aggregated. Only numeric fields are aggregated.
+	// Defaults to: DefaultFields
+	Fields map[uint32][]string
+
+	// Windows are time windows statistics are calculated for. Values older than largest window are discarded.
+	// Defaults to: DefaultWindows (1 and 10 minutes)
+	Windows []time.Duration
+
+	// ReplacementFilter drops legacy PGNs (i.e. J1939 engine PGNs) from sources that also send newer PGN superseding
+	// them, so same value is not aggregated twice when Fields include both.
+	// Optional: when nil all messages are aggregated. Not needed when messages are already filtered (i.e. by
+	// pipeline.NewReplacementFilter stage).
+	ReplacementFilter *nmea.ReplacementFilter
+}
+
+type sample struct {
+	time  time.Time
+	value float64
+}
+
+type series struct {
+	source uint8
+	// samples are ordered by time, oldest first
+	samples []sample
+}
+
+// Aggregator maintains rolling statistics of engine and battery values per instance. Decoded messages are fed to
+// Aggregator with Process and statistics are queried with Stats, Instance or All.
+//
+// Aggregator is safe for concurrent use.
+type Aggregator struct {
+	mutex  sync.Mutex
+	config Config
+	// maxWindow is largest configured window
+	maxWindow time.Duration
+
+	fields map[uint32]map[string]struct{}
+	series map[Key]*series
+
+	now func() time.Time
+}
+
+// NewAggregator creates new instance of Aggregator with default configuration
+func NewAggregator() *Aggregator {
+	return NewAggregatorWithConfig(Config{})
+}
+
+// NewAggregatorWithConfig creates new instance of Aggregator with given configuration
+func NewAggregatorWithConfig(config Config) *Aggregator {
+	if config.Fields == nil {
+		config.Fields = DefaultFields
+	}
+	if len(config.Windows) == 0 {
+		config.Windows = DefaultWindows
+	}
+	a := &Aggregator{
+		config: config,
+		fields: make(map[uint32]map[string]struct{}, len(config.Fields)),
+		series: make(map[Key]*series),
+		now:    time.Now,
+	}
+	for _, w := range config.Windows {
+		if w > a.maxWindow {
+			a.maxWindow = w
+		}
+	}
+	for pgn, IDs := range config.Fields {
+		set := make(map[string]struct{}, len(IDs))
+		for _, ID := range IDs {
+			set[ID] = struct{}{}
+		}
+		a.fields[pgn] = set
+	}
+	return a
+}
+
+// Process adds values of configured fields from decoded message. Returns false when message PGN is not aggregated or
+// message is dropped by Config.ReplacementFilter. Fields without value ("no data") are skipped. Messages without
+// `instance` field are aggregated as instance 0.
+func (a *Aggregator) Process(msg nmea.Message) bool {
+	fields, ok := a.fields[msg.Header.PGN]
+	if !ok {
+		return false
+	}
+	if a.config.ReplacementFilter != nil && !a.config.ReplacementFilter.Accept(msg.Header, a.now()) {
+		return false
+	}
+	instance := uint8(0)
+	if fv, ok := msg.Fields.FindByID("instance"); ok {
+		if v, ok := fv.AsUint64(); ok && v <= 0xff {
+			instance = uint8(v)
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.now()
+	for _, fv := range msg.Fields {
+		if _, ok := fields[fv.ID]; !ok {
+			continue
+		}
+		var value float64
+		switch v := fv.Value.(type) {
+		case float64:
+			value = v
+		case int64:
+			value = float64(v)
+		case uint64:
+			value = float64(v)
+		default:
+			continue
+		}
+		key := Key{PGN: msg.Header.PGN, Instance: instance, Field: fv.ID}
+		s, ok := a.series[key]
+		if !ok {
+			s = &series{}
+			a.series[key] = s
+		}
+		s.source = msg.Header.Source
+		s.samples = append(s.samples, sample{time: now, value: value})
+		s.prune(now.Add(-a.maxWindow))
+	}
+	return true
+}
+
+// prune removes samples older than given time
+func (s *series) prune(oldest time.Time) {
+	i := sort.Search(len(s.samples), func(i int) bool { return !s.samples[i].time.Before(oldest) })
+	if i == 0 {
+		return
+	}
+	s.samples = append(s.samples[:0], s.samples[i:]...)
+}
+
+// Stats returns statistics of single field. Returns false when no values have been received within largest window.
+func (a *Aggregator) Stats(key Key) (FieldStats, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	s, ok := a.series[key]
+	if !ok {
+		return FieldStats{}, false
+	}
+	return a.stats(key, s, a.now())
+}
+
+// Instance returns statistics of all fields of given PGN and instance ordered by field ID.
+func (a *Aggregator) Instance(pgn uint32, instance uint8) []FieldStats {
+	return a.collect(func(k Key) bool { return k.PGN == pgn && k.Instance == instance })
+}
+
+// All returns statistics of all fields ordered by PGN, instance and field ID.
+func (a *Aggregator) All() []FieldStats {
+	return a.collect(func(k Key) bool { return true })
+}
+
+func (a *Aggregator) collect(filter func(k Key) bool) []FieldStats {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.now()
+	result := make([]FieldStats, 0)
+	for key, s := range a.series {
+		if !filter(key) {
+			continue
+		}
+		if fs, ok := a.stats(key, s, now); ok {
+			result = append(result, fs)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ki, kj := result[i].Key, result[j].Key
+		if ki.PGN != kj.PGN {
+			return ki.PGN < kj.PGN
+		}
+		if ki.Instance != kj.Instance {
+			return ki.Instance < kj.Instance
+		}
+		return ki.Field < kj.Field
+	})
+	return result
+}
+
+func (a *Aggregator) stats(key Key, s *series, now time.Time) (FieldStats, bool) {
+	s.prune(now.Add(-a.maxWindow))
+	if len(s.samples) == 0 {
+		delete(a.series, key)
+		return FieldStats{}, false
+	}
+	latest := s.samples[len(s.samples)-1]
+	result := FieldStats{
+		Key:       key,
+		Value:     latest.value,
+		Source:    s.source,
+		UpdatedAt: latest.time,
+		Windows:   make([]WindowStats, len(a.config.Windows)),
+	}
+	for i, w := range a.config.Windows {
+		ws := WindowStats{Window: w}
+		oldest := now.Add(-w)
+		sum := 0.0
+		for j := len(s.samples) - 1; j >= 0; j-- {
+			v := s.samples[j]
+			if v.time.Before(oldest) {
+				break
+			}
+			if ws.Count == 0 || v.value < ws.Min {
+				ws.Min = v.value
+			}
+			if ws.Count == 0 || v.value > ws.Max {
+				ws.Max = v.value
+			}
+			sum += v.value
+			ws.Count++
+		}
+		if ws.Count > 0 {
+			ws.Avg = sum / float64(ws.Count)
+		}
+		result.Windows[i] = ws
+	}
+	return result, true
+}
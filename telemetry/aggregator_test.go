@@ -0,0 +1,117 @@
+package telemetry
+
+import (
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func batteryStatus(instance uint64, voltage float64) nmea.Message {
+	return nmea.Message{
+		Header: nmea.CanBusHeader{PGN: PGNBatteryStatus, Source: 12},
+		Fields: nmea.FieldValues{
+			{ID: "instance", Value: instance},
+			{ID: "voltage", Value: voltage},
+		},
+	}
+}
+
+func TestAggregator_Process(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	start := now
+
+	aggregator := NewAggregatorWithConfig(Config{Windows: []time.Duration{1 * time.Minute, 10 * time.Minute}})
+	aggregator.now = func() time.Time {
+		return now
+	}
+
+	assert.True(t, aggregator.Process(batteryStatus(0, 12.0)))
+	now = now.Add(5 * time.Minute)
+	assert.True(t, aggregator.Process(batteryStatus(0, 13.0)))
+	now = now.Add(30 * time.Second)
+	assert.True(t, aggregator.Process(batteryStatus(0, 12.5)))
+	assert.True(t, aggregator.Process(batteryStatus(1, 24.1)))
+	assert.True(t, aggregator.Process(nmea.Message{
+		Header: nmea.CanBusHeader{PGN: PGNEngineParametersDynamic, Source: 5},
+		Fields: nmea.FieldValues{
+			{ID: "instance", Value: nmea.EnumValue{Value: 1, Code: "Dual Engine Starboard"}},
+			{ID: "oilPressure", Value: 350000.0},
+			{ID: "totalEngineHours", Value: 10 * time.Hour}, // not aggregated
+			{ID: "engineLoad", Value: int64(45)},
+		},
+	}))
+	assert.False(t, aggregator.Process(nmea.Message{Header: nmea.CanBusHeader{PGN: 129025}}))
+
+	result, ok := aggregator.Stats(Key{PGN: PGNBatteryStatus, Instance: 0, Field: "voltage"})
+	assert.True(t, ok)
+	assert.Equal(t, FieldStats{
+		Key:       Key{PGN: PGNBatteryStatus, Instance: 0, Field: "voltage"},
+		Value:     12.5,
+		Source:    12,
+		UpdatedAt: now,
+		Windows: []WindowStats{
+			{Window: 1 * time.Minute, Count: 2, Min: 12.5, Max: 13.0, Avg: 12.75},
+			{Window: 10 * time.Minute, Count: 3, Min: 12.0, Max: 13.0, Avg: 12.5},
+		},
+	}, result)
+
+	engine := aggregator.Instance(PGNEngineParametersDynamic, 1)
+	assert.Len(t, engine, 2)
+	assert.Equal(t, "engineLoad", engine[0].Field)
+	assert.Equal(t, 45.0, engine[0].Value)
+	assert.Equal(t, "oilPressure", engine[1].Field)
+
+	all := aggregator.All()
+	assert.Len(t, all, 4)
+	assert.Equal(t, Key{PGN: PGNEngineParametersDynamic, Instance: 1, Field: "engineLoad"}, all[0].Key)
+	assert.Equal(t, Key{PGN: PGNBatteryStatus, Instance: 1, Field: "voltage"}, all[3].Key)
+
+	// first value falls out of 10 minute window
+	now = start.Add(10*time.Minute + 1*time.Second)
+	result, ok = aggregator.Stats(Key{PGN: PGNBatteryStatus, Instance: 0, Field: "voltage"})
+	assert.True(t, ok)
+	assert.Equal(t, []WindowStats{
+		{Window: 1 * time.Minute, Count: 0},
+		{Window: 10 * time.Minute, Count: 2, Min: 12.5, Max: 13.0, Avg: 12.75},
+	}, result.Windows)
+
+	// all values are out of largest window
+	now = now.Add(10 * time.Minute)
+	_, ok = aggregator.Stats(Key{PGN: PGNBatteryStatus, Instance: 0, Field: "voltage"})
+	assert.False(t, ok)
+	assert.Len(t, aggregator.All(), 0)
+}
+
+func TestAggregator_Process_replacementFilter(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	aggregator := NewAggregatorWithConfig(Config{
+		Fields: map[uint32][]string{
+			PGNEngineParametersDynamic:                     {"oilPressure"},
+			uint32(nmea.PGNJ1939EngineFluidLevelPressure1): {"engineOilPressure"},
+		},
+		ReplacementFilter: nmea.NewReplacementFilter(),
+	})
+	aggregator.now = func() time.Time {
+		return now
+	}
+	legacy := nmea.Message{
+		Header: nmea.CanBusHeader{PGN: uint32(nmea.PGNJ1939EngineFluidLevelPressure1), Source: 5},
+		Fields: nmea.FieldValues{{ID: "engineOilPressure", Value: 352000.0}},
+	}
+
+	assert.True(t, aggregator.Process(legacy)) // source has not sent newer PGN yet
+	now = now.Add(1 * time.Second)
+	assert.True(t, aggregator.Process(nmea.Message{
+		Header: nmea.CanBusHeader{PGN: PGNEngineParametersDynamic, Source: 5},
+		Fields: nmea.FieldValues{{ID: "oilPressure", Value: 350000.0}},
+	}))
+	now = now.Add(1 * time.Second)
+	assert.False(t, aggregator.Process(legacy))
+
+	result, ok := aggregator.Stats(Key{PGN: uint32(nmea.PGNJ1939EngineFluidLevelPressure1), Field: "engineOilPressure"})
+	assert.True(t, ok)
+	assert.Equal(t, 1, result.Windows[0].Count)
+}
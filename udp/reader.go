@@ -0,0 +1,346 @@
+package udp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"github.com/aldas/go-nmea-client/actisense"
+	"github.com/aldas/go-nmea-client/canboat"
+	"github.com/aldas/go-nmea-client/yachtdevices"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// readPollInterval is read deadline used to check context cancellation between datagrams
+const readPollInterval = 250 * time.Millisecond
+
+// ErrReadOnly is returned when writing to Reader
+var ErrReadOnly = errors.New("UDP reader is read-only")
+
+// ErrNoRawFrames is returned by ReadRawFrame when payload format does not contain raw CAN frames
+var ErrNoRawFrames = errors.New("UDP payload format does not contain raw CAN frames")
+
+// Format is payload format of datagrams
+type Format string
+
+const (
+	// FormatYDWGRaw is Yacht Devices RAW format (`17:33:21.107 R 19F51323 01 02`). Lines are CAN frames.
+	FormatYDWGRaw Format = "ydwg-raw"
+	// FormatN2KASCII is Actisense N2K ASCII format (`A173321.107 23FF7 1F513 012F3070002F30709F`). Lines are complete
+	// messages.
+	FormatN2KASCII Format = "n2k-ascii"
+	// FormatCanboatRaw is Canboat plain format (`2021-07-29T10:18:31.758Z,6,126208,36,0,7,02,82,ff,00,10,02,00`).
+	// Lines are complete messages.
+	FormatCanboatRaw Format = "canboat-raw"
+)
+
+// Config is configuration for Reader
+type Config struct {
+	// Address is address to listen on. Unicast or broadcast traffic is received with `:port` or `ip:port` and multicast
+	// traffic with multicast group address (i.e. `239.2.1.1:2000`).
+	Address string
+	// MulticastInterface is name of network interface to join multicast group on.
+	// Optional: by default system chosen interface is used.
+	MulticastInterface string
+
+	// Format is datagram payload format
+	Format Format
+
+	// FastPacketAssembler assembles fast-packet PGN frames to complete messages. Used with FormatYDWGRaw. Frames from
+	// reordered datagrams are assembled as long as they arrive within assembler timeout.
+	// Optional: when not set every frame is returned as separate message
+	FastPacketAssembler nmea.Assembler
+
+	// ReorderTolerance is how much gateway timestamps may go backwards (datagrams arriving out of order) before it is
+	// considered as gateway clock wrap-around or restart.
+	// Optional: defaults to 1 second
+	ReorderTolerance time.Duration
+
+	// DebugLogRawMessageBytes instructs reader to log all received lines
+	DebugLogRawMessageBytes bool
+	// LogFunc callback to output/print debug/log statements. Used when Logger is not set.
+	LogFunc func(format string, a ...any)
+	// Logger outputs debug/log statements. Received and invalid lines are logged on Debug level when
+	// DebugLogRawMessageBytes is set.
+	// Optional: when not set LogFunc is used
+	Logger nmea.Logger
+}
+
+// Stats is statistics of Reader
+type Stats struct {
+	// Datagrams is number of received datagrams
+	Datagrams uint64 `json:"datagrams"`
+	// Lines is number of lines parsed from datagrams
+	Lines uint64 `json:"lines"`
+	// InvalidLines is number of lines that could not be parsed
+	InvalidLines uint64 `json:"invalid_lines"`
+}
+
+// Reader reads NMEA2000 messages from UDP datagrams sent by WiFi gateways (broadcast or multicast). Every datagram is
+// parsed separately and contains one or more complete lines, so lost or truncated datagram does not corrupt following
+// lines. Invalid lines (truncated or corrupted datagrams are common on WiFi links) are skipped and counted in Stats
+// instead of being returned as errors.
+//
+// Note: ReadRawMessage is not go-routine safe
+type Reader struct {
+	config  Config
+	timeNow func() time.Time
+
+	mu   sync.Mutex
+	conn *net.UDPConn
+
+	buf []byte
+	// lines are not yet processed lines of last datagram
+	lines [][]byte
+
+	busClock *nmea.DeviceClock
+
+	datagrams    atomic.Uint64
+	lineCount    atomic.Uint64
+	invalidLines atomic.Uint64
+
+	closed atomic.Bool
+}
+
+// NewReader creates new instance of UDP Reader. Connection is opened by Initialize.
+func NewReader(config Config) *Reader {
+	if config.ReorderTolerance <= 0 {
+		config.ReorderTolerance = 1 * time.Second
+	}
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
+	return &Reader{
+		config:   config,
+		timeNow:  time.Now,
+		buf:      make([]byte, 65536),
+		busClock: nmea.NewDeviceClockWithReorderTolerance(24*time.Hour, config.ReorderTolerance),
+	}
+}
+
+// Initialize starts listening on configured address. Multicast group is joined when address is multicast address.
+func (r *Reader) Initialize() error {
+	switch r.config.Format {
+	case FormatYDWGRaw, FormatN2KASCII, FormatCanboatRaw:
+	default:
+		return fmt.Errorf("unknown UDP payload format: %v", r.config.Format)
+	}
+	addr, err := net.ResolveUDPAddr("udp", r.config.Address)
+	if err != nil {
+		return err
+	}
+
+	var conn *net.UDPConn
+	if addr.IP != nil && addr.IP.IsMulticast() {
+		var ifi *net.Interface
+		if r.config.MulticastInterface != "" {
+			if ifi, err = net.InterfaceByName(r.config.MulticastInterface); err != nil {
+				return err
+			}
+		}
+		conn, err = net.ListenMulticastUDP("udp", ifi, addr)
+	} else {
+		conn, err = net.ListenUDP("udp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed.Load() {
+		_ = conn.Close()
+		return nmea.ErrDeviceClosed
+	}
+	r.conn = conn
+	return nil
+}
+
+// LocalAddr returns local address reader is listening on. Returns nil before Initialize.
+func (r *Reader) LocalAddr() net.Addr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.LocalAddr()
+}
+
+// Stats returns statistics of reader
+func (r *Reader) Stats() Stats {
+	return Stats{
+		Datagrams:    r.datagrams.Load(),
+		Lines:        r.lineCount.Load(),
+		InvalidLines: r.invalidLines.Load(),
+	}
+}
+
+// ReadRawMessage reads next message. Blocks until message is received, context is cancelled or reader is closed.
+func (r *Reader) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if r.config.Format == FormatYDWGRaw {
+		return r.readYDWGMessage(ctx)
+	}
+	for {
+		line, err := r.nextLine(ctx)
+		if err != nil {
+			return nmea.RawMessage{}, err
+		}
+		now := r.timeNow()
+
+		switch r.config.Format {
+		case FormatN2KASCII:
+			m, skip, err := actisense.ParseN2KASCII(line, now, r.busClock)
+			if r.isInvalid(skip, err) {
+				continue
+			}
+			return m, nil
+		case FormatCanboatRaw:
+			if line[0] == '#' {
+				continue
+			}
+			m, err := canboat.UnmarshalString(string(line))
+			if r.isInvalid(false, err) {
+				continue
+			}
+			m.BusTime = m.Time // line time is time when gateway received message
+			m.Time = now
+			return m, nil
+		}
+	}
+}
+
+func (r *Reader) readYDWGMessage(ctx context.Context) (nmea.RawMessage, error) {
+	msg := nmea.RawMessage{}
+	for {
+		frame, busTime, err := r.readFrame(ctx)
+		if err != nil {
+			return nmea.RawMessage{}, err
+		}
+		if r.config.FastPacketAssembler == nil {
+			return nmea.RawMessage{
+				Time:    frame.Time,
+				BusTime: busTime,
+				Header:  frame.Header,
+				Data:    append([]byte(nil), frame.Data[:frame.Length]...),
+			}, nil
+		}
+		if r.config.FastPacketAssembler.Assemble(frame, &msg) {
+			msg.BusTime = busTime
+			return msg, nil
+		}
+	}
+}
+
+// ReadRawFrame reads next CAN frame. Frames are available only with FormatYDWGRaw, other formats contain messages
+// assembled by gateway.
+func (r *Reader) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	if r.config.Format != FormatYDWGRaw {
+		return nmea.RawFrame{}, ErrNoRawFrames
+	}
+	frame, _, err := r.readFrame(ctx)
+	return frame, err
+}
+
+func (r *Reader) readFrame(ctx context.Context) (nmea.RawFrame, time.Time, error) {
+	for {
+		line, err := r.nextLine(ctx)
+		if err != nil {
+			return nmea.RawFrame{}, time.Time{}, err
+		}
+		frame, busTime, skip, err := yachtdevices.ParseRawLine(line, r.timeNow(), r.busClock, false)
+		if r.isInvalid(skip, err) {
+			continue
+		}
+		return frame, busTime, nil
+	}
+}
+
+func (r *Reader) isInvalid(skip bool, err error) bool {
+	if err != nil {
+		r.invalidLines.Add(1)
+		if r.config.DebugLogRawMessageBytes {
+			r.config.Logger.Debug("invalid UDP line", "err", err)
+		}
+		return true
+	}
+	return skip
+}
+
+// nextLine returns next non-empty line from last datagram or reads new datagram
+func (r *Reader) nextLine(ctx context.Context) ([]byte, error) {
+	for {
+		for len(r.lines) > 0 {
+			line := bytes.TrimSpace(r.lines[0])
+			r.lines = r.lines[1:]
+			if len(line) > 0 {
+				r.lineCount.Add(1)
+				if r.config.DebugLogRawMessageBytes {
+					r.config.Logger.Debug("read UDP line", "line", string(line))
+				}
+				return line, nil
+			}
+		}
+		n, err := r.readDatagram(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.datagrams.Add(1)
+		r.lines = bytes.Split(r.buf[:n], []byte{'\n'})
+	}
+}
+
+func (r *Reader) readDatagram(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+	if conn == nil {
+		if r.closed.Load() {
+			return 0, nmea.ErrDeviceClosed
+		}
+		return 0, errors.New("UDP reader is not initialized")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		default:
+		}
+		if r.closed.Load() {
+			return 0, nmea.ErrDeviceClosed
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(readPollInterval)); err != nil {
+			return 0, err
+		}
+		n, _, err := conn.ReadFromUDP(r.buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			if r.closed.Load() {
+				return 0, nmea.ErrDeviceClosed
+			}
+			return 0, err
+		}
+		return n, nil
+	}
+}
+
+// WriteRawMessage returns ErrReadOnly. Gateways broadcasting to UDP do not accept messages over broadcast.
+func (r *Reader) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	return ErrReadOnly
+}
+
+// Close closes connection. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
+func (r *Reader) Close() error {
+	r.closed.Store(true)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
@@ -0,0 +1,171 @@
+package udp
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestReader(t *testing.T, config Config) (*Reader, func(datagrams ...string)) {
+	config.Address = "127.0.0.1:0"
+	reader := NewReader(config)
+	if !assert.NoError(t, reader.Initialize()) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { reader.Close() })
+
+	conn, err := net.DialUDP("udp", nil, reader.LocalAddr().(*net.UDPAddr))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return reader, func(datagrams ...string) {
+		for _, d := range datagrams {
+			_, err := conn.Write([]byte(d))
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestReader_ReadRawMessage_ydwgRaw(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	reader, send := newTestReader(t, Config{
+		Format:              FormatYDWGRaw,
+		FastPacketAssembler: nmea.NewFastPacketAssembler([]uint32{130820}),
+	})
+	reader.timeNow = func() time.Time { return now }
+
+	// second frame of fast-packet arrives before first one, truncated line is skipped
+	send(
+		"00:34:02.803 R 1DFF0400 81 01 FF FF FF FF FF FF\r\n00:34:02.9",
+		"00:34:02.802 R 1DFF0400 80 07 3F 9F 00 40 00 00\r\n00:34:02.810 R 09F11323 3A 9C\r\n",
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := reader.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.CanBusHeader{PGN: 130820, Source: 0, Destination: 255, Priority: 7}, msg.Header)
+	assert.Equal(t, nmea.RawData{0x3F, 0x9F, 0x00, 0x40, 0x00, 0x00, 0x01}, msg.Data)
+	// late frame is not treated as gateway clock wrap-around
+	assert.Equal(t, now.Add(-time.Millisecond), msg.BusTime)
+
+	msg, err = reader.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:    now,
+		BusTime: now,
+		Header:  nmea.CanBusHeader{PGN: 0x1F113, Source: 35, Destination: 255, Priority: 2},
+		Data:    []byte{0x3a, 0x9c},
+	}, msg)
+
+	assert.Equal(t, Stats{Datagrams: 2, Lines: 4, InvalidLines: 1}, reader.Stats())
+}
+
+func TestReader_ReadRawMessage_n2kASCII(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	reader, send := newTestReader(t, Config{Format: FormatN2KASCII})
+	reader.timeNow = func() time.Time { return now }
+
+	send("A173321.107 23FF7 1F513 012F3070002F30709F\r\nA173321.108 23FF7 1F513 0102\r\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := reader.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:       now,
+		BusTime:    now,
+		DeviceTime: 17*time.Hour + 33*time.Minute + 21107*time.Millisecond,
+		Header:     nmea.CanBusHeader{PGN: 0x1F513, Source: 0x23, Destination: 0xFF, Priority: 7},
+		Data:       []byte{0x01, 0x2F, 0x30, 0x70, 0x00, 0x2F, 0x30, 0x70, 0x9F},
+	}, msg)
+
+	msg, err = reader.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawData{0x01, 0x02}, msg.Data)
+}
+
+func TestReader_ReadRawMessage_canboatRaw(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	reader, send := newTestReader(t, Config{Format: FormatCanboatRaw})
+	reader.timeNow = func() time.Time { return now }
+
+	send("2021-07-29T10:18:31.758Z,6,126208,36,0,7,02,82,ff,00,10,02,00")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := reader.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:    now,
+		BusTime: time.Date(2021, 7, 29, 10, 18, 31, 758000000, time.UTC),
+		Header:  nmea.CanBusHeader{PGN: 126208, Source: 36, Destination: 0, Priority: 6},
+		Data:    []byte{0x02, 0x82, 0xff, 0x00, 0x10, 0x02, 0x00},
+	}, msg)
+}
+
+func TestReader_ReadRawMessage_contextCancelled(t *testing.T) {
+	reader, _ := newTestReader(t, Config{Format: FormatCanboatRaw})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := reader.ReadRawMessage(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReader_Close(t *testing.T) {
+	reader, _ := newTestReader(t, Config{Format: FormatCanboatRaw})
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := reader.ReadRawMessage(context.Background())
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, reader.Close())
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("read was not unblocked by Close")
+	}
+	assert.ErrorIs(t, reader.WriteRawMessage(context.Background(), nmea.RawMessage{}), ErrReadOnly)
+}
+
+func TestReader_Initialize_unknownFormat(t *testing.T) {
+	reader := NewReader(Config{Address: "127.0.0.1:0", Format: "xxx"})
+
+	assert.EqualError(t, reader.Initialize(), "unknown UDP payload format: xxx")
+}
+
+func TestReader_ReadRawFrame(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	reader, send := newTestReader(t, Config{
+		Format:              FormatYDWGRaw,
+		FastPacketAssembler: nmea.NewFastPacketAssembler([]uint32{130820}), // is not used for frame reads
+	})
+	reader.timeNow = func() time.Time { return now }
+
+	send("00:34:02.802 R 1DFF0400 80 07 3F 9F 00 40 00 00\r\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	frame, err := reader.ReadRawFrame(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawFrame{
+		Time:   now,
+		Header: nmea.CanBusHeader{PGN: 130820, Source: 0, Destination: 255, Priority: 7},
+		Length: 8,
+		Data:   [8]byte{0x80, 0x07, 0x3F, 0x9F, 0x00, 0x40, 0x00, 0x00},
+	}, frame)
+
+	_, err = NewReader(Config{Format: FormatN2KASCII}).ReadRawFrame(ctx)
+	assert.ErrorIs(t, err, ErrNoRawFrames)
+}
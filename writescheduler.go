@@ -0,0 +1,281 @@
+package nmea
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultFrameInterval is default time between CAN frames written by WriteScheduler. NMEA 2000 devices are expected to
+// leave some time between fast-packet frames so slower receivers do not lose frames.
+const DefaultFrameInterval = 10 * time.Millisecond
+
+// ErrWriteQueueFull is returned by WriteScheduler.WriteRawMessage when queue is full and message is discarded.
+var ErrWriteQueueFull = errors.New("write queue is full")
+
+// WriteSchedulerConfig configures WriteScheduler
+type WriteSchedulerConfig struct {
+	// FrameInterval is minimum time between CAN frames written to device. Fast-packet messages consist of multiple
+	// frames so writing them takes FrameInterval for each frame.
+	// Defaults to: DefaultFrameInterval
+	FrameInterval time.Duration
+
+	// QueueSize is number of messages that can wait in queue for writing. When queue is full WriteRawMessage returns
+	// ErrWriteQueueFull unless queue contains message with lower priority (higher priority value) which is then
+	// discarded instead.
+	// Defaults to: 256
+	QueueSize int
+
+	// Splitter splits messages into frames when wrapped writer implements RawFrameWriter. Frames are then written one
+	// by one with FrameInterval between them. When not set (or writer does not write frames) messages are written
+	// as whole (device splits them) and next message is written after FrameInterval for each frame of written message.
+	Splitter Splitter
+
+	// OnError is called when writing message fails. When not set, write error ends Run with that error.
+	OnError func(msg RawMessage, err error)
+}
+
+// WriteSchedulerStats holds WriteScheduler queue counters
+type WriteSchedulerStats struct {
+	// Written is number of messages written to wrapped writer
+	Written uint64 `json:"written"`
+	// Overflows is number of messages discarded because queue was full
+	Overflows uint64 `json:"overflows"`
+	// Queued is number of messages currently waiting in queue
+	Queued int `json:"queued"`
+	// MaxQueued is highest number of messages that have been in queue at once
+	MaxQueued int `json:"max_queued"`
+}
+
+type writeEntry struct {
+	msg RawMessage
+	// seq is order in which message was queued. Messages with same priority are written in queued order.
+	seq uint64
+}
+
+// writeQueue is priority queue of messages ordered by CAN priority (0 is highest) and queued order
+type writeQueue []writeEntry
+
+func (q writeQueue) Len() int { return len(q) }
+func (q writeQueue) Less(i, j int) bool {
+	if q[i].msg.Header.Priority != q[j].msg.Header.Priority {
+		return q[i].msg.Header.Priority < q[j].msg.Header.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q writeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *writeQueue) Push(x any)   { *q = append(*q, x.(writeEntry)) }
+func (q *writeQueue) Pop() any {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = writeEntry{}
+	*q = old[:n-1]
+	return e
+}
+
+// WriteScheduler queues messages written to it and writes them to wrapped writer from Run. Messages are written in
+// order of CAN priority (lower value first, same priority in queued order) and paced so that bus and device buffers
+// are not flooded - consecutive frames are at least FrameInterval apart.
+//
+// WriteScheduler implements RawMessageWriter so it can be given to AddressMapper and other components that write to
+// bus instead of device.
+//
+// WriteScheduler is safe for concurrent use.
+type WriteScheduler struct {
+	writer RawMessageWriter
+	config WriteSchedulerConfig
+
+	mu     sync.Mutex
+	queue  writeQueue
+	seq    uint64
+	notify chan struct{}
+	stats  WriteSchedulerStats
+
+	// nextWrite is earliest time next frame can be written
+	nextWrite time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewWriteScheduler creates new instance of WriteScheduler
+func NewWriteScheduler(writer RawMessageWriter, config WriteSchedulerConfig) *WriteScheduler {
+	if config.FrameInterval <= 0 {
+		config.FrameInterval = DefaultFrameInterval
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 256
+	}
+	return &WriteScheduler{
+		writer: writer,
+		config: config,
+		queue:  make(writeQueue, 0, config.QueueSize),
+		notify: make(chan struct{}),
+
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+// WriteRawMessage adds message to queue. Returns ErrWriteQueueFull when queue is full and message was discarded.
+func (s *WriteScheduler) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) >= s.config.QueueSize {
+		lowest := s.lowestPriorityIndex()
+		if s.queue[lowest].msg.Header.Priority <= msg.Header.Priority {
+			s.stats.Overflows++
+			return ErrWriteQueueFull
+		}
+		heap.Remove(&s.queue, lowest)
+		s.stats.Overflows++
+	}
+	s.seq++
+	heap.Push(&s.queue, writeEntry{msg: msg, seq: s.seq})
+	if len(s.queue) > s.stats.MaxQueued {
+		s.stats.MaxQueued = len(s.queue)
+	}
+	close(s.notify)
+	s.notify = make(chan struct{})
+	return nil
+}
+
+// lowestPriorityIndex returns index of last queued message with lowest priority. Must be called with lock held.
+func (s *WriteScheduler) lowestPriorityIndex() int {
+	idx := 0
+	for i, e := range s.queue {
+		lowest := s.queue[idx]
+		if e.msg.Header.Priority > lowest.msg.Header.Priority ||
+			(e.msg.Header.Priority == lowest.msg.Header.Priority && e.seq > lowest.seq) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// Run writes queued messages to wrapped writer and blocks until context is cancelled or writing message fails.
+func (s *WriteScheduler) Run(ctx context.Context) error {
+	for {
+		msg, err := s.next(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.write(ctx, msg); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			if s.config.OnError == nil {
+				return err
+			}
+			s.config.OnError(msg, err)
+		}
+	}
+}
+
+// next waits until queue has message and removes message with highest priority from queue
+func (s *WriteScheduler) next(ctx context.Context) (RawMessage, error) {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			e := heap.Pop(&s.queue).(writeEntry)
+			s.mu.Unlock()
+			return e.msg, nil
+		}
+		notify := s.notify
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return RawMessage{}, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+func (s *WriteScheduler) write(ctx context.Context, msg RawMessage) error {
+	frameWriter, ok := s.writer.(RawFrameWriter)
+	if s.config.Splitter == nil || !ok {
+		if err := s.wait(ctx); err != nil {
+			return err
+		}
+		err := s.writer.WriteRawMessage(ctx, msg)
+		s.nextWrite = s.now().Add(time.Duration(frameCount(len(msg.Data))) * s.config.FrameInterval)
+		if err != nil {
+			return err
+		}
+		s.countWritten()
+		return nil
+	}
+
+	frames, err := s.config.Splitter.Split(msg)
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		if err := s.wait(ctx); err != nil {
+			return err
+		}
+		err := frameWriter.WriteRawFrame(ctx, frame)
+		s.nextWrite = s.now().Add(s.config.FrameInterval)
+		if err != nil {
+			return err
+		}
+	}
+	s.countWritten()
+	return nil
+}
+
+// wait blocks until next frame can be written
+func (s *WriteScheduler) wait(ctx context.Context) error {
+	if d := s.nextWrite.Sub(s.now()); d > 0 {
+		return s.sleep(ctx, d)
+	}
+	return ctx.Err()
+}
+
+func (s *WriteScheduler) countWritten() {
+	s.mu.Lock()
+	s.stats.Written++
+	s.mu.Unlock()
+}
+
+// frameCount returns number of CAN frames message with given data length is transmitted as. Messages longer than 8
+// bytes are assumed to be fast-packet messages.
+func frameCount(length int) int {
+	if length <= 8 {
+		return 1
+	}
+	return 1 + (length-6+6)/7
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Stats returns queue counters
+func (s *WriteScheduler) Stats() WriteSchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Queued = len(s.queue)
+	return stats
+}
+
+// Close closes wrapped writer. Messages still in queue are discarded.
+func (s *WriteScheduler) Close() error {
+	s.mu.Lock()
+	s.queue = s.queue[:0]
+	s.mu.Unlock()
+	return s.writer.Close()
+}
@@ -0,0 +1,227 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type timedWrite struct {
+	time  time.Time
+	pgn   uint32
+	frame bool
+}
+
+// pacedWriter records time (from fake clock) of every written message and frame and cancels context after given
+// number of writes
+type pacedWriter struct {
+	now         func() time.Time
+	writes      []timedWrite
+	cancelAfter int
+	cancel      context.CancelFunc
+}
+
+func (w *pacedWriter) WriteRawMessage(ctx context.Context, msg RawMessage) error {
+	return w.record(timedWrite{time: w.now(), pgn: msg.Header.PGN})
+}
+
+func (w *pacedWriter) Close() error {
+	return nil
+}
+
+func (w *pacedWriter) record(tw timedWrite) error {
+	w.writes = append(w.writes, tw)
+	if len(w.writes) >= w.cancelAfter {
+		w.cancel()
+	}
+	return nil
+}
+
+type pacedFrameWriter struct {
+	pacedWriter
+}
+
+func (w *pacedFrameWriter) WriteRawFrame(ctx context.Context, frame RawFrame) error {
+	return w.record(timedWrite{time: w.now(), pgn: frame.Header.PGN, frame: true})
+}
+
+// newTestWriteScheduler creates scheduler with fake clock that is advanced by sleeps
+func newTestWriteScheduler(writer RawMessageWriter, config WriteSchedulerConfig, clock *time.Time) *WriteScheduler {
+	s := NewWriteScheduler(writer, config)
+	s.now = func() time.Time {
+		return *clock
+	}
+	s.sleep = func(ctx context.Context, d time.Duration) error {
+		*clock = clock.Add(d)
+		return ctx.Err()
+	}
+	return s
+}
+
+func TestWriteScheduler_Run_priorityOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	start := clock
+	w := &pacedWriter{now: func() time.Time { return clock }, cancelAfter: 4, cancel: cancel}
+	s := newTestWriteScheduler(w, WriteSchedulerConfig{}, &clock)
+
+	assert.NoError(t, s.WriteRawMessage(ctx, RawMessage{Header: CanBusHeader{PGN: 126993, Priority: 7}}))
+	assert.NoError(t, s.WriteRawMessage(ctx, RawMessage{Header: CanBusHeader{PGN: 59904, Priority: 6}}))
+	assert.NoError(t, s.WriteRawMessage(ctx, RawMessage{Header: CanBusHeader{PGN: 127250, Priority: 2}}))
+	assert.NoError(t, s.WriteRawMessage(ctx, RawMessage{Header: CanBusHeader{PGN: 60928, Priority: 6}}))
+
+	err := s.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []timedWrite{
+		{time: start, pgn: 127250},
+		{time: start.Add(10 * time.Millisecond), pgn: 59904},
+		{time: start.Add(20 * time.Millisecond), pgn: 60928},
+		{time: start.Add(30 * time.Millisecond), pgn: 126993},
+	}, w.writes)
+	assert.Equal(t, WriteSchedulerStats{Written: 4, MaxQueued: 4}, s.Stats())
+}
+
+func TestWriteScheduler_Run_spacing(t *testing.T) {
+	start := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+
+	fastPacket := RawMessage{Header: CanBusHeader{PGN: 129029, Priority: 3}, Data: make([]byte, 20)} // 3 frames
+	single := RawMessage{Header: CanBusHeader{PGN: 127250, Priority: 3}, Data: make([]byte, 8)}
+
+	var testCases = []struct {
+		name        string
+		givenConfig WriteSchedulerConfig
+		whenFrames  bool
+		expect      []timedWrite
+	}{
+		{
+			name:        "ok, whole messages are written after interval for each frame of previous message",
+			givenConfig: WriteSchedulerConfig{FrameInterval: 10 * time.Millisecond},
+			expect: []timedWrite{
+				{time: start, pgn: 129029},
+				{time: start.Add(30 * time.Millisecond), pgn: 127250},
+				{time: start.Add(40 * time.Millisecond), pgn: 129029},
+			},
+		},
+		{
+			name: "ok, frames are written with interval between them",
+			givenConfig: WriteSchedulerConfig{
+				FrameInterval: 10 * time.Millisecond,
+				Splitter:      NewFastPacketSplitter(nil),
+			},
+			whenFrames: true,
+			expect: []timedWrite{
+				{time: start, pgn: 129029, frame: true},
+				{time: start.Add(10 * time.Millisecond), pgn: 129029, frame: true},
+				{time: start.Add(20 * time.Millisecond), pgn: 129029, frame: true},
+				{time: start.Add(30 * time.Millisecond), pgn: 127250, frame: true},
+				{time: start.Add(40 * time.Millisecond), pgn: 129029, frame: true},
+				{time: start.Add(50 * time.Millisecond), pgn: 129029, frame: true},
+				{time: start.Add(60 * time.Millisecond), pgn: 129029, frame: true},
+			},
+		},
+		{
+			name:        "ok, splitter is not used when writer does not write frames",
+			givenConfig: WriteSchedulerConfig{FrameInterval: 5 * time.Millisecond, Splitter: NewFastPacketSplitter(nil)},
+			expect: []timedWrite{
+				{time: start, pgn: 129029},
+				{time: start.Add(15 * time.Millisecond), pgn: 127250},
+				{time: start.Add(20 * time.Millisecond), pgn: 129029},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			clock := start
+			fw := &pacedFrameWriter{pacedWriter{
+				now:         func() time.Time { return clock },
+				cancelAfter: len(tc.expect),
+				cancel:      cancel,
+			}}
+			var writer RawMessageWriter = &fw.pacedWriter
+			if tc.whenFrames {
+				writer = fw
+			}
+			s := newTestWriteScheduler(writer, tc.givenConfig, &clock)
+
+			assert.NoError(t, s.WriteRawMessage(ctx, fastPacket))
+			assert.NoError(t, s.WriteRawMessage(ctx, single))
+			assert.NoError(t, s.WriteRawMessage(ctx, fastPacket))
+
+			err := s.Run(ctx)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, tc.expect, fw.writes)
+		})
+	}
+}
+
+func TestWriteScheduler_WriteRawMessage_overflow(t *testing.T) {
+	s := NewWriteScheduler(&recordingWriter{}, WriteSchedulerConfig{QueueSize: 2})
+
+	assert.NoError(t, s.WriteRawMessage(context.Background(), RawMessage{Header: CanBusHeader{PGN: 1, Priority: 6}}))
+	assert.NoError(t, s.WriteRawMessage(context.Background(), RawMessage{Header: CanBusHeader{PGN: 2, Priority: 6}}))
+
+	err := s.WriteRawMessage(context.Background(), RawMessage{Header: CanBusHeader{PGN: 3, Priority: 6}})
+	assert.ErrorIs(t, err, ErrWriteQueueFull)
+
+	// higher priority message replaces latest queued message with lowest priority
+	err = s.WriteRawMessage(context.Background(), RawMessage{Header: CanBusHeader{PGN: 4, Priority: 2}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, WriteSchedulerStats{Overflows: 2, Queued: 2, MaxQueued: 2}, s.Stats())
+	assert.ElementsMatch(t, []uint32{1, 4}, []uint32{s.queue[0].msg.Header.PGN, s.queue[1].msg.Header.PGN})
+}
+
+func TestWriteScheduler_Run_writeError(t *testing.T) {
+	s := NewWriteScheduler(&recordingWriter{err: errors.New("write failed")}, WriteSchedulerConfig{})
+	assert.NoError(t, s.WriteRawMessage(context.Background(), RawMessage{Header: CanBusHeader{PGN: 1}}))
+
+	err := s.Run(context.Background())
+
+	assert.EqualError(t, err, "write failed")
+}
+
+func TestWriteScheduler_Run_onError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var failed []uint32
+	s := NewWriteScheduler(&recordingWriter{err: errors.New("write failed")}, WriteSchedulerConfig{
+		FrameInterval: time.Millisecond,
+		OnError: func(msg RawMessage, err error) {
+			failed = append(failed, msg.Header.PGN)
+			if len(failed) == 2 {
+				cancel()
+			}
+		},
+	})
+	assert.NoError(t, s.WriteRawMessage(ctx, RawMessage{Header: CanBusHeader{PGN: 1}}))
+	assert.NoError(t, s.WriteRawMessage(ctx, RawMessage{Header: CanBusHeader{PGN: 2}}))
+
+	err := s.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []uint32{1, 2}, failed)
+	assert.Equal(t, uint64(0), s.Stats().Written)
+}
+
+func TestWriteScheduler_Close(t *testing.T) {
+	w := &recordingWriter{}
+	s := NewWriteScheduler(w, WriteSchedulerConfig{})
+	assert.NoError(t, s.WriteRawMessage(context.Background(), RawMessage{Header: CanBusHeader{PGN: 1}}))
+
+	assert.NoError(t, s.Close())
+
+	assert.True(t, w.closed)
+	assert.Equal(t, 0, s.Stats().Queued)
+}
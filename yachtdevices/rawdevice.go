@@ -0,0 +1,296 @@
+package yachtdevices
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aldas/go-nmea-client"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// rawLineMaxSize is longest valid RAW line: `hh:mm:ss.ddd R 1DFF0400 80 07 3F 9F 00 40 00 00\r\n` (49 bytes)
+	rawLineMaxSize = 64
+)
+
+// Config is configuration for Yacht Devices gateway devices
+type Config struct {
+	// DebugLogRawMessageBytes instructs device to log all sent/received raw lines
+	DebugLogRawMessageBytes bool
+	// LogFunc callback to output/print debug/log statements. Used when Logger is not set.
+	LogFunc func(format string, a ...any)
+	// Logger outputs debug/log statements. Raw lines are logged on Debug level when DebugLogRawMessageBytes is set.
+	// Optional: when not set LogFunc is used
+	Logger nmea.Logger
+
+	// FastPacketAssembler assembles fast-packet PGN frames to complete messages. Use nmea.ISOTPAssembler wrapping
+	// nmea.FastPacketAssembler to assemble ISO 11783-3 transport protocol messages as well.
+	// Optional: when not set every frame is returned as separate message
+	FastPacketAssembler nmea.Assembler
+
+	// FastPacketSplitter splits messages to frames for sending.
+	// Optional: defaults to splitter that splits messages longer than 8 bytes into fast-packet frames
+	FastPacketSplitter nmea.Splitter
+
+	// ISOTPSender sends long messages with ISO 11783-3 transport protocol. Handshake frames are received by reading
+	// device, so addressed transfers require reading device concurrently with writing.
+	// Optional: when not set messages are split into fast-packet frames
+	ISOTPSender *nmea.ISOTPSender
+
+	// OutputTransmittedFrames instructs device to return frames that gateway echoes back after sending them to bus
+	// (lines with `T` direction). By default only received (`R`) frames are returned.
+	OutputTransmittedFrames bool
+}
+
+// RawDevice is implementing Yacht Devices YDWG-02/YDEN-02/YDNU-02 gateway RAW protocol over TCP, UDP or serial port.
+//
+// RAW protocol is ordinary CAN frame with 8 bytes of data per line so fast-packet and multi-packet (ISO TP) assembly
+// must be done separately (see Config.FastPacketAssembler). Gateway timestamps (time of day) are used to fill
+// nmea.RawMessage.BusTime.
+//
+// Received line format: `hh:mm:ss.ddd D msgid b0 b1 b2 b3 b4 b5 b6 b7<CR><LF>` where D is direction (`R` received
+// from bus, `T` transmitted to bus). Lines sent to gateway have only `msgid b0 b1 ... b7<CR><LF>` part.
+//
+// Note: is not go-routine safe
+type RawDevice struct {
+	device  io.ReadWriter
+	timeNow func() time.Time
+
+	readBuffer []byte
+	readIndex  int
+
+	config Config
+
+	// busClock converts gateway time of day to bus times
+	busClock *nmea.DeviceClock
+
+	closed atomic.Bool
+}
+
+// NewRawDevice creates new instance of Yacht Devices gateway RAW protocol device
+func NewRawDevice(device io.ReadWriter, config Config) *RawDevice {
+	if config.FastPacketSplitter == nil {
+		config.FastPacketSplitter = nmea.NewFastPacketSplitter(nil)
+	}
+	config.Logger = nmea.ResolveLogger(config.Logger, config.LogFunc)
+	return &RawDevice{
+		device:     device,
+		timeNow:    time.Now,
+		readBuffer: make([]byte, 0, 4096),
+		config:     config,
+		busClock:   nmea.NewDeviceClock(24 * time.Hour),
+	}
+}
+
+// Initialize initializes device. RAW protocol needs no initialization.
+func (d *RawDevice) Initialize() error {
+	return nil
+}
+
+// Close closes device. Blocked ReadRawMessage call is unblocked with nmea.ErrDeviceClosed error.
+func (d *RawDevice) Close() error {
+	d.closed.Store(true)
+	if c, ok := d.device.(io.Closer); ok {
+		return c.Close()
+	}
+	return errors.New("device does not implement Closer interface")
+}
+
+// ReadRawMessage reads next message from device. When Config.FastPacketAssembler is set frames are assembled to
+// complete messages.
+func (d *RawDevice) ReadRawMessage(ctx context.Context) (nmea.RawMessage, error) {
+	if d.config.FastPacketAssembler != nil {
+		msg := nmea.RawMessage{}
+		for {
+			frame, busTime, err := d.readFrame(ctx)
+			if err != nil {
+				return nmea.RawMessage{}, err
+			}
+			if d.config.FastPacketAssembler.Assemble(frame, &msg) {
+				msg.BusTime = busTime
+				return msg, nil
+			}
+		}
+	}
+
+	frame, busTime, err := d.readFrame(ctx)
+	if err != nil {
+		return nmea.RawMessage{}, err
+	}
+	return nmea.RawMessage{
+		Time:    frame.Time,
+		BusTime: busTime,
+		Header:  frame.Header,
+		Data:    frame.Data[:frame.Length],
+	}, nil
+}
+
+// ReadRawFrame reads next CAN frame from device
+func (d *RawDevice) ReadRawFrame(ctx context.Context) (nmea.RawFrame, error) {
+	frame, _, err := d.readFrame(ctx)
+	return frame, err
+}
+
+func (d *RawDevice) readFrame(ctx context.Context) (nmea.RawFrame, time.Time, error) {
+	buf := make([]byte, 2048) // UDP datagrams from gateway contain multiple lines
+	for {
+		select {
+		case <-ctx.Done():
+			return nmea.RawFrame{}, time.Time{}, ctx.Err()
+		default:
+		}
+		if d.closed.Load() {
+			return nmea.RawFrame{}, time.Time{}, nmea.ErrDeviceClosed
+		}
+
+		// process lines that are already buffered before reading more
+		if endIndex := bytes.IndexByte(d.readBuffer, '\n'); endIndex != -1 {
+			line := d.readBuffer[:endIndex+1]
+			if d.config.DebugLogRawMessageBytes {
+				d.config.Logger.Debug("read Yacht Devices RAW line", "line", string(bytes.TrimSpace(line)))
+			}
+			frame, busTime, skip, err := ParseRawLine(line, d.timeNow(), d.busClock, d.config.OutputTransmittedFrames)
+			d.readBuffer = d.readBuffer[:copy(d.readBuffer, d.readBuffer[endIndex+1:])]
+			if skip {
+				continue
+			}
+			if err == nil && d.config.ISOTPSender != nil {
+				d.config.ISOTPSender.HandleFrame(frame)
+			}
+			return frame, busTime, err
+		}
+		if len(d.readBuffer) > rawLineMaxSize { // garbage without line ends
+			d.readBuffer = d.readBuffer[:0]
+		}
+
+		n, err := d.device.Read(buf)
+		if err != nil {
+			if d.closed.Load() {
+				return nmea.RawFrame{}, time.Time{}, nmea.ErrDeviceClosed
+			}
+			return nmea.RawFrame{}, time.Time{}, err
+		}
+		d.readBuffer = append(d.readBuffer, buf[:n]...)
+	}
+}
+
+// ParseRawLine parses single received RAW line. Gateway time of day is converted to bus time with given clock. Returns
+// true for lines that should be skipped (transmitted frames, garbage).
+func ParseRawLine(raw []byte, now time.Time, clock *nmea.DeviceClock, allowTransmitted bool) (nmea.RawFrame, time.Time, bool, error) {
+	// Example: `17:33:21.107 R 19F51323 01 02<CR><LF>`
+	parts := bytes.Fields(raw)
+	if len(parts) < 3 || len(parts) > 11 {
+		return nmea.RawFrame{}, time.Time{}, true, errors.New("invalid Yacht Devices RAW line")
+	}
+	switch parts[1][0] {
+	case 'R':
+	case 'T':
+		if !allowTransmitted {
+			return nmea.RawFrame{}, time.Time{}, true, nil
+		}
+	default:
+		return nmea.RawFrame{}, time.Time{}, true, errors.New("invalid Yacht Devices RAW line direction")
+	}
+
+	canID, err := strconv.ParseUint(string(parts[2]), 16, 29)
+	if err != nil {
+		return nmea.RawFrame{}, time.Time{}, false, fmt.Errorf("invalid Yacht Devices RAW line CAN ID: %w", err)
+	}
+	frame := nmea.RawFrame{
+		Time:   now,
+		Header: nmea.ParseCANID(uint32(canID)),
+		Length: uint8(len(parts) - 3),
+	}
+	for i, p := range parts[3:] {
+		if len(p) != 2 {
+			return nmea.RawFrame{}, time.Time{}, false, errors.New("invalid Yacht Devices RAW line data byte")
+		}
+		if _, err := hex.Decode(frame.Data[i:i+1], p); err != nil {
+			return nmea.RawFrame{}, time.Time{}, false, fmt.Errorf("invalid Yacht Devices RAW line data byte: %w", err)
+		}
+	}
+
+	var busTime time.Time
+	if timeOfDay, ok := parseTimeOfDay(parts[0]); ok {
+		busTime = clock.BusTime(timeOfDay, now)
+	}
+	return frame, busTime, false, nil
+}
+
+// parseTimeOfDay parses `hh:mm:ss.ddd` to duration since midnight
+func parseTimeOfDay(raw []byte) (time.Duration, bool) {
+	if len(raw) != 12 || raw[2] != ':' || raw[5] != ':' || raw[8] != '.' {
+		return 0, false
+	}
+	result := 0
+	for i, c := range raw {
+		if i == 2 || i == 5 || i == 8 {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		result = result*10 + int(c-'0')
+	}
+	// result is now hhmmssddd
+	ms := result % 1000
+	s := (result / 1000) % 100
+	m := (result / 100000) % 100
+	h := result / 10000000
+	return time.Duration(h)*time.Hour +
+		time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second +
+		time.Duration(ms)*time.Millisecond, true
+}
+
+const hextable = "0123456789ABCDEF"
+
+// formatRawLine formats frame as line sent to gateway. Example: `19F51323 01 02<CR><LF>`
+func formatRawLine(frame nmea.RawFrame) []byte {
+	b := make([]byte, 0, 8+3*8+2)
+	b = append(b, fmt.Sprintf("%08X", frame.Header.Uint32())...)
+	for i := uint8(0); i < frame.Length; i++ {
+		v := frame.Data[i]
+		b = append(b, ' ', hextable[v>>4], hextable[v&0x0f])
+	}
+	return append(b, '\r', '\n')
+}
+
+// WriteRawFrame writes single CAN frame to gateway
+func (d *RawDevice) WriteRawFrame(ctx context.Context, frame nmea.RawFrame) error {
+	if d.closed.Load() {
+		return nmea.ErrDeviceClosed
+	}
+	line := formatRawLine(frame)
+	if d.config.DebugLogRawMessageBytes {
+		d.config.Logger.Debug("writing Yacht Devices RAW line", "line", string(bytes.TrimSpace(line)))
+	}
+	_, err := d.device.Write(line)
+	return err
+}
+
+// WriteRawMessage writes message to gateway. Messages longer than 8 bytes (and fast-packet PGNs known to
+// Config.FastPacketSplitter) are written as multiple fast-packet frames. Messages that Config.ISOTPSender considers
+// transport protocol messages are written with ISO 11783-3 transport protocol.
+func (d *RawDevice) WriteRawMessage(ctx context.Context, msg nmea.RawMessage) error {
+	if d.config.ISOTPSender != nil && d.config.ISOTPSender.IsTransportMessage(msg) {
+		return d.config.ISOTPSender.Send(ctx, msg, func(frame nmea.RawFrame) error {
+			return d.WriteRawFrame(ctx, frame)
+		})
+	}
+	frames, err := d.config.FastPacketSplitter.Split(msg)
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		if err := d.WriteRawFrame(ctx, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package yachtdevices
+
+import (
+	"bytes"
+	"context"
+	"github.com/aldas/go-nmea-client"
+	test_test "github.com/aldas/go-nmea-client/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseRawLine(t *testing.T) {
+	now := test_test.UTCTime(1665488842) // Tue Oct 11 2022 11:47:22 GMT+0000
+	var testCases = []struct {
+		name                 string
+		when                 string
+		whenAllowTransmitted bool
+		expect               nmea.RawFrame
+		expectSkip           bool
+		expectError          string
+	}{
+		{
+			name: "ok",
+			when: "17:33:21.107 R 19F51323 01 02\r\n",
+			expect: nmea.RawFrame{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					PGN:         0x1F513, // 1F513 -> 128275 Distance Log
+					Source:      35,      // 0x23
+					Destination: 255,     // 0xff - broadcast
+					Priority:    6,       // 0x06
+				},
+				Length: 2,
+				Data:   [8]byte{0x01, 0x02},
+			},
+		},
+		{
+			name: "ok, 8 bytes without line end",
+			when: "00:34:02.718 R 15FD0800 FF 00 01 CA 6F FF FF FF",
+			expect: nmea.RawFrame{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					PGN:         0x1FD08, // 1FD08 -> 130312 Temperature
+					Source:      0,       // 0x0
+					Destination: 255,     // 0xff - broadcast
+					Priority:    5,       // 0x05
+				},
+				Length: 8,
+				Data:   [8]byte{0xFF, 0x0, 0x01, 0xCA, 0x6F, 0xFF, 0xFF, 0xFF},
+			},
+		},
+		{
+			name: "ok, addressed PGN",
+			when: "00:34:02.718 R 18EA23FE 00 EE 00\r\n",
+			expect: nmea.RawFrame{
+				Time: now,
+				Header: nmea.CanBusHeader{
+					PGN:         uint32(nmea.PGNISORequest),
+					Source:      nmea.AddressNull,
+					Destination: 35,
+					Priority:    6,
+				},
+				Length: 3,
+				Data:   [8]byte{0x00, 0xEE, 0x00},
+			},
+		},
+		{
+			name:       "ok, transmitted frame is skipped",
+			when:       "17:33:21.107 T 19F51323 01 02\r\n",
+			expectSkip: true,
+		},
+		{
+			name:                 "ok, transmitted frame is returned",
+			when:                 "17:33:21.107 T 19F51323 01 02\r\n",
+			whenAllowTransmitted: true,
+			expect: nmea.RawFrame{
+				Time:   now,
+				Header: nmea.CanBusHeader{PGN: 0x1F513, Source: 35, Destination: 255, Priority: 6},
+				Length: 2,
+				Data:   [8]byte{0x01, 0x02},
+			},
+		},
+		{
+			name:        "nok, garbage",
+			when:        "21.107 R\r\n",
+			expectSkip:  true,
+			expectError: "invalid Yacht Devices RAW line",
+		},
+		{
+			name:        "nok, unknown direction",
+			when:        "17:33:21.107 X 19F51323 01 02\r\n",
+			expectSkip:  true,
+			expectError: "invalid Yacht Devices RAW line direction",
+		},
+		{
+			name:        "nok, invalid CAN ID",
+			when:        "17:33:21.107 R 19F5132X 01 02\r\n",
+			expectError: `invalid Yacht Devices RAW line CAN ID: strconv.ParseUint: parsing "19F5132X": invalid syntax`,
+		},
+		{
+			name:        "nok, invalid data byte",
+			when:        "17:33:21.107 R 19F51323 01 0Z\r\n",
+			expectError: "invalid Yacht Devices RAW line data byte: encoding/hex: invalid byte: U+005A 'Z'",
+		},
+		{
+			name:        "nok, invalid data byte length",
+			when:        "17:33:21.107 R 19F51323 01 002\r\n",
+			expectError: "invalid Yacht Devices RAW line data byte",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, skip, err := ParseRawLine([]byte(tc.when), now, nmea.NewDeviceClock(24*time.Hour), tc.whenAllowTransmitted)
+
+			assert.Equal(t, tc.expect, result)
+			assert.Equal(t, tc.expectSkip, skip)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	result, ok := parseTimeOfDay([]byte("17:33:21.107"))
+	assert.True(t, ok)
+	assert.Equal(t, 17*time.Hour+33*time.Minute+21*time.Second+107*time.Millisecond, result)
+
+	_, ok = parseTimeOfDay([]byte("17:33:21"))
+	assert.False(t, ok)
+
+	_, ok = parseTimeOfDay([]byte("17:3a:21.107"))
+	assert.False(t, ok)
+}
+
+func TestFormatRawLine(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		given  nmea.RawFrame
+		expect string
+	}{
+		{
+			name: "ok",
+			given: nmea.RawFrame{
+				Header: nmea.CanBusHeader{PGN: 0x1F113, Source: 35, Destination: 255, Priority: 2},
+				Length: 8,
+				Data:   [8]byte{0x3a, 0x9c, 0x63, 0x01, 0x00, 0xff, 0xff, 0xff},
+			},
+			expect: "09F11323 3A 9C 63 01 00 FF FF FF\r\n",
+		},
+		{
+			name: "ok, ISORequest",
+			given: nmea.RawFrame{
+				Header: nmea.CanBusHeader{
+					PGN:         uint32(nmea.PGNISORequest),
+					Priority:    6,
+					Source:      nmea.AddressNull,
+					Destination: nmea.AddressGlobal,
+				},
+				Length: 3,
+				Data:   [8]byte{0x0, 0xEE, 0x0},
+			},
+			expect: "18EAFFFE 00 EE 00\r\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, string(formatRawLine(tc.given)))
+		})
+	}
+}
+
+type testConn struct {
+	reader io.Reader
+	bytes.Buffer
+}
+
+func (c *testConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func TestRawDevice_ReadRawMessage(t *testing.T) {
+	now := test_test.UTCTime(1665488842)
+	input := "17:33:21.107 R 19F51323 01 02\r\n" +
+		"17:33:21.108 T 19F51323 01 03\r\n" +
+		"garbage\r\n" +
+		"17:33:21.207 R 09F11323 3A 9C 63 01 00 FF FF FF\r\n"
+	device := NewRawDevice(&testConn{reader: bytes.NewReader([]byte(input))}, Config{})
+	device.timeNow = func() time.Time { return now }
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:    now,
+		BusTime: now,
+		Header:  nmea.CanBusHeader{PGN: 0x1F513, Source: 35, Destination: 255, Priority: 6},
+		Data:    []byte{0x01, 0x02},
+	}, msg)
+
+	msg, err = device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawMessage{
+		Time:    now,
+		BusTime: now, // bus time never lies in future compared to local receive time
+		Header:  nmea.CanBusHeader{PGN: 0x1F113, Source: 35, Destination: 255, Priority: 2},
+		Data:    []byte{0x3a, 0x9c, 0x63, 0x01, 0x00, 0xff, 0xff, 0xff},
+	}, msg)
+
+	_, err = device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestRawDevice_ReadRawMessage_fastPacket(t *testing.T) {
+	input := "00:34:02.802 R 1DFF0400 80 07 3F 9F 00 40 00 00\r\n" +
+		"00:34:02.803 R 1DFF0400 81 01 FF FF FF FF FF FF\r\n"
+	device := NewRawDevice(&testConn{reader: bytes.NewReader([]byte(input))}, Config{
+		FastPacketAssembler: nmea.NewFastPacketAssembler([]uint32{130820}),
+	})
+
+	msg, err := device.ReadRawMessage(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.CanBusHeader{PGN: 130820, Source: 0, Destination: 255, Priority: 7}, msg.Header)
+	assert.Equal(t, nmea.RawData{0x3F, 0x9F, 0x00, 0x40, 0x00, 0x00, 0x01}, msg.Data)
+	assert.False(t, msg.BusTime.IsZero())
+}
+
+func TestRawDevice_ReadRawMessage_closed(t *testing.T) {
+	device := NewRawDevice(&testConn{reader: bytes.NewReader(nil)}, Config{})
+	assert.EqualError(t, device.Close(), "device does not implement Closer interface")
+
+	_, err := device.ReadRawMessage(context.Background())
+	assert.ErrorIs(t, err, nmea.ErrDeviceClosed)
+}
+
+func TestRawDevice_WriteRawMessage(t *testing.T) {
+	conn := &testConn{}
+	device := NewRawDevice(conn, Config{})
+
+	header := nmea.CanBusHeader{PGN: 0x1F113, Source: 35, Destination: 255, Priority: 2}
+	err := device.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Header: header,
+		Data:   []byte{0x3a, 0x9c, 0x63, 0x01, 0x00, 0xff, 0xff, 0xff},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "09F11323 3A 9C 63 01 00 FF FF FF\r\n", conn.String())
+
+	conn.Reset()
+	err = device.WriteRawMessage(context.Background(), nmea.RawMessage{
+		Header: header,
+		Data:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+	})
+	assert.NoError(t, err)
+	expect := "09F11323 00 0A 01 02 03 04 05 06\r\n" +
+		"09F11323 01 07 08 09 0A FF FF FF\r\n"
+	assert.Equal(t, expect, conn.String())
+}
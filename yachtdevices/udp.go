@@ -0,0 +1,61 @@
+package yachtdevices
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrNoGatewayAddress is returned when writing to UDP connection that was created without gateway address
+var ErrNoGatewayAddress = errors.New("UDP connection has no gateway address to write to")
+
+// UDPConn is connection to gateway UDP server. Gateway broadcasts RAW lines to its UDP port (default 1456) and
+// receives lines sent to same port. Reads accept datagrams from any address (gateway sends to broadcast address) and
+// writes are sent to gateway address.
+type UDPConn struct {
+	conn    *net.UDPConn
+	gateway *net.UDPAddr
+}
+
+// ListenUDP creates UDP connection listening on listenAddr (i.e. `:1456`). gatewayAddr (i.e. `192.168.4.1:1456`) is
+// where written lines are sent. Empty gatewayAddr creates read-only connection.
+func ListenUDP(listenAddr string, gatewayAddr string) (*UDPConn, error) {
+	lAddr, err := net.ResolveUDPAddr("udp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	var gAddr *net.UDPAddr
+	if gatewayAddr != "" {
+		if gAddr, err = net.ResolveUDPAddr("udp", gatewayAddr); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := net.ListenUDP("udp", lAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &UDPConn{conn: conn, gateway: gAddr}, nil
+}
+
+// Read reads single datagram. Datagrams sent by gateway contain one or more complete lines.
+func (c *UDPConn) Read(p []byte) (int, error) {
+	n, _, err := c.conn.ReadFromUDP(p)
+	return n, err
+}
+
+// Write sends p as single datagram to gateway
+func (c *UDPConn) Write(p []byte) (int, error) {
+	if c.gateway == nil {
+		return 0, ErrNoGatewayAddress
+	}
+	return c.conn.WriteToUDP(p, c.gateway)
+}
+
+// LocalAddr returns local address connection is listening on
+func (c *UDPConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Close closes connection. Blocked Read call is unblocked with error.
+func (c *UDPConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,62 @@
+package yachtdevices
+
+import (
+	"context"
+	"github.com/aldas/go-nmea-client"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPConn(t *testing.T) {
+	gateway, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer gateway.Close()
+
+	conn, err := ListenUDP("127.0.0.1:0", gateway.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	device := NewRawDevice(conn, Config{})
+	defer device.Close()
+
+	// gateway sends multiple lines in single datagram
+	datagram := "17:33:21.107 R 19F51323 01 02\r\n17:33:21.108 R 19F51323 01 03\r\n"
+	_, err = gateway.WriteToUDP([]byte(datagram), conn.LocalAddr().(*net.UDPAddr))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := device.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawData{0x01, 0x02}, msg.Data)
+	msg, err = device.ReadRawMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, nmea.RawData{0x01, 0x03}, msg.Data)
+
+	err = device.WriteRawMessage(ctx, nmea.RawMessage{
+		Header: nmea.CanBusHeader{PGN: 0x1F513, Source: 35, Destination: 255, Priority: 6},
+		Data:   []byte{0x01, 0x02},
+	})
+	assert.NoError(t, err)
+
+	buf := make([]byte, 100)
+	assert.NoError(t, gateway.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, _, err := gateway.ReadFromUDP(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "19F51323 01 02\r\n", string(buf[:n]))
+}
+
+func TestUDPConn_readOnly(t *testing.T) {
+	conn, err := ListenUDP("127.0.0.1:0", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("19F51323 01 02\r\n"))
+	assert.ErrorIs(t, err, ErrNoGatewayAddress)
+}